@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
@@ -58,6 +59,14 @@ func main() {
 		fail(fmt.Sprintf("webhook verifier: %v", err))
 	}
 	webhook := payment.NewAppleWebhookService(catalog, webhookVerifier, tokens, subscriptionDAO)
+	if keyring, err := payment.NewPayloadKeyring(conf.PayloadArchive); err == nil {
+		webhook.WithPayloadArchive(payment.NewPayloadArchive(keyring, dao.NewApplePayloadArchiveDAO(pool), payment.PayloadArchiveConfig{
+			Retention: conf.PayloadArchive.Retention,
+			BatchSize: conf.PayloadArchive.BatchSize,
+		}))
+	} else if !errors.Is(err, payment.ErrNotConfigured) {
+		fail(fmt.Sprintf("payload archive: %v", err))
+	}
 
 	reconciler, err := payment.NewAppleReconciler(catalog)
 	if err != nil {
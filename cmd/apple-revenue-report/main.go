@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/config"
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+func main() {
+	from := flag.String("from", "", "report start time RFC3339, aligned down to the period start (e.g. 2026-01-01T00:00:00Z)")
+	to := flag.String("to", "", "report end time RFC3339, exclusive (default: now)")
+	granularity := flag.String("granularity", payment.RevenueGranularityMonth, "period granularity: day, week or month")
+	envs := flag.String("env", "", "comma-separated Apple environments to include (default: all)")
+	format := flag.String("format", "json", "output format: json or csv")
+	out := flag.String("out", "", "output file (default: stdout)")
+	dsn := flag.String("dsn", os.Getenv("DB_DSN"), "Postgres DSN (default: $DB_DSN)")
+	flag.Parse()
+
+	if *dsn == "" {
+		fail("--dsn or $DB_DSN required")
+	}
+	if *format != "json" && *format != "csv" {
+		fail(fmt.Sprintf("--format must be json or csv, got %q", *format))
+	}
+
+	req := payment.RevenueReportRequest{Granularity: *granularity, To: time.Now().UTC()}
+	if *from == "" {
+		fail("--from required")
+	}
+	t, err := time.Parse(time.RFC3339, *from)
+	if err != nil {
+		fail(fmt.Sprintf("--from invalid: %v", err))
+	}
+	req.From = t
+	if *to != "" {
+		t, err := time.Parse(time.RFC3339, *to)
+		if err != nil {
+			fail(fmt.Sprintf("--to invalid: %v", err))
+		}
+		req.To = t
+	}
+	for _, env := range strings.Split(*envs, ",") {
+		if env = strings.TrimSpace(env); env != "" {
+			req.Environments = append(req.Environments, payment.Environment(env))
+		}
+	}
+
+	startedAt := time.Now()
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		fail(fmt.Sprintf("load config: %v", err))
+	}
+	// 不初始化应用日志：日志走 slog 默认输出（stderr），stdout 只输出报表。
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, *dsn)
+	if err != nil {
+		fail(fmt.Sprintf("connect db: %v", err))
+	}
+	defer pool.Close()
+
+	catalog, err := payment.NewCatalog(conf.AppleIAP, conf.AppEnv)
+	if err != nil {
+		fail(fmt.Sprintf("apple iap not configured: %v", err))
+	}
+	// 与服务端一致，以 apple_products 全表（含已下架商品）中的商品与参考价格为准，表中没有的商品回退到 APPLE_IAP_PRODUCTS。
+	report, err := payment.NewRevenueReportService(catalog, dao.NewRevenueReportDAO(pool)).
+		WithProducts(dao.NewAppleProductDAO(pool)).
+		Report(ctx, req)
+	if err != nil {
+		fail(fmt.Sprintf("report: %v", err))
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fail(fmt.Sprintf("create output: %v", err))
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	if *format == "csv" {
+		err = report.WriteCSV(bw)
+	} else {
+		enc := json.NewEncoder(bw)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report.Response())
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		fail(fmt.Sprintf("write output: %v", err))
+	}
+
+	slog.Info("apple revenue report complete",
+		"from", report.From,
+		"to", report.To,
+		"granularity", report.Granularity,
+		"rows", len(report.Rows),
+		"elapsed", time.Since(startedAt),
+	)
+}
+
+func fail(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -13,16 +14,22 @@ import (
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humachi"
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 
 	chiMw "github.com/go-chi/chi/v5/middleware"
 
 	"github.com/dundunHa/go-serverhttp-template/internal/api"
 	"github.com/dundunHa/go-serverhttp-template/internal/config"
 	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
 	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/admin"
 	"github.com/dundunHa/go-serverhttp-template/internal/service/auth"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/comp"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/credits"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/entitlement"
 	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/referral"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/settings"
 	"github.com/dundunHa/go-serverhttp-template/internal/storage"
 	"github.com/dundunHa/go-serverhttp-template/pkg/cache"
 	logpkg "github.com/dundunHa/go-serverhttp-template/pkg/log"
@@ -38,7 +45,8 @@ func main() {
 	initLogger(conf.AppEnv, conf.Log)
 	initCache(conf.Cache)
 
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	db, err := storage.InitDB(ctx, conf.DB.DSN)
 	if err != nil {
 		slog.Error("init postgres failed", "err", err)
@@ -46,7 +54,8 @@ func main() {
 	}
 	defer db.Close()
 
-	userSvc := initUserService(db)
+	userDAO := dao.NewUserDAO(db)
+	userSvc := service.NewUserService(userDAO)
 	mgr := auth.NewProviderManager()
 	mgr.Register("gmail", auth.NewGmailProvider(conf.Auth.Gmail))
 	mgr.Register("apple", auth.NewAppleProvider(conf.Auth.Apple))
@@ -61,22 +70,149 @@ func main() {
 		slog.Error("init jwt service failed", "err", err)
 		os.Exit(1)
 	}
-	authSvc := auth.NewAuthService(mgr, userSvc, tokenSvc)
+	authSvc := auth.NewAuthService(mgr, userSvc, tokenSvc).WithAccountStates(userDAO)
 
 	subscriptionDAO := dao.NewSubscriptionDAO(db)
 	paymentTokens := payment.NewTokenService(subscriptionDAO)
 	iapCatalog, catalogErr := payment.NewCatalog(conf.AppleIAP, conf.AppEnv)
 	if catalogErr != nil {
-		slog.Warn("apple iap catalog unavailable; verify endpoint and /users/me subscription will return NONE", "err", catalogErr)
+		slog.Warn("apple iap catalog unavailable; verify endpoint is disabled and /users/me only reflects comp entitlements", "err", catalogErr)
+	}
+	productCatalog := buildProductCatalogService(ctx, iapCatalog, dao.NewAppleProductDAO(db))
+	if productCatalog != nil {
+		startBackgroundJob(ctx, "apple-product-catalog-refresh", conf.AppleIAP.CatalogRefreshInterval, productCatalog.Run)
+	}
+	payloadArchive, err := buildPayloadArchive(dao.NewApplePayloadArchiveDAO(db), conf.PayloadArchive)
+	if err != nil {
+		slog.Error("invalid payload archive config", "err", err)
+		os.Exit(1)
+	}
+	if payloadArchive != nil {
+		startBackgroundJob(ctx, "apple-payload-archive-maintenance", conf.PayloadArchive.PurgeInterval, payloadArchive.RunMaintenance)
+	}
+	paymentIAP := buildPaymentIAPService(iapCatalog, subscriptionDAO, paymentTokens, payloadArchive)
+	appleWebhook := buildPaymentWebhookService(iapCatalog, subscriptionDAO, paymentTokens, payloadArchive)
+	if appleWebhook != nil {
+		paymentTokens.WithBindingHook(appleWebhook.RedrivePendingForToken)
+		startBackgroundJob(ctx, "apple-pending-event-redrive", conf.AppleIAP.PendingRedriveInterval, appleWebhook.RunPendingRedrive)
+	}
+	appleOffers := buildAppleOfferService(iapCatalog, subscriptionDAO, paymentTokens, dao.NewAppleOfferDAO(db))
+	if statusSync := buildAppleStatusSyncService(iapCatalog, subscriptionDAO, dao.NewAppleStatusSyncDAO(db), conf.AppleStatusSync); statusSync != nil {
+		startBackgroundJob(ctx, "apple-subscription-status-sync", conf.AppleStatusSync.Interval, statusSync.RunSync)
+	}
+	if consumption := buildAppleConsumptionService(iapCatalog, dao.NewAppleConsumptionDAO(db), conf.AppleConsumption); consumption != nil {
+		startBackgroundJob(ctx, "apple-consumption-response", conf.AppleConsumption.Interval, consumption.Run)
+	}
+
+	googlePlayDAO := dao.NewGooglePlayDAO(db)
+	googlePlayCatalog, googleCatalogErr := payment.NewGooglePlayCatalog(conf.GooglePlay, conf.AppEnv)
+	if googleCatalogErr != nil {
+		slog.Warn("google play catalog unavailable; google play verify and webhook endpoints are disabled", "err", googleCatalogErr)
+	}
+	googlePlay := buildGooglePlayService(googlePlayCatalog, googlePlayDAO, paymentTokens)
+
+	stripeDAO := dao.NewStripeDAO(db)
+	stripeCatalog, stripeCatalogErr := payment.NewStripeCatalog(conf.Stripe, conf.AppEnv)
+	if stripeCatalogErr != nil {
+		slog.Warn("stripe catalog unavailable; stripe checkout, portal and webhook endpoints are disabled", "err", stripeCatalogErr)
+	}
+	stripeSvc := buildStripeService(stripeCatalog, stripeDAO)
+	featureSets, err := entitlement.ParseFeatureSets(conf.Entitlement.FeatureSets)
+	if err != nil {
+		slog.Error("invalid entitlement feature sets", "err", err)
+		os.Exit(1)
 	}
-	paymentIAP := buildPaymentIAPService(iapCatalog, subscriptionDAO, paymentTokens)
-	subscriptionReader := buildSubscriptionReader(iapCatalog, subscriptionDAO)
-	paymentWebhook := buildPaymentWebhookService(iapCatalog, subscriptionDAO, paymentTokens)
+	compDAO := dao.NewCompEntitlementDAO(db)
+	entitlements := entitlement.NewEngine(featureSets,
+		payment.NewAppleEntitlementSource(subscriptionDAO, iapCatalog),
+		payment.NewGooglePlayEntitlementSource(googlePlayDAO),
+		payment.NewStripeEntitlementSource(stripeDAO),
+		entitlement.NewCompSource(compDAO),
+	)
+	entitlementWebhooks := entitlement.NewWebhookService(dao.NewEntitlementWebhookDAO(db), entitlement.WebhookConfig{
+		BatchSize:       conf.Entitlement.Webhook.BatchSize,
+		MaxAttempts:     conf.Entitlement.Webhook.MaxAttempts,
+		RetryBackoff:    conf.Entitlement.Webhook.RetryBackoff,
+		MaxRetryBackoff: conf.Entitlement.Webhook.MaxRetryBackoff,
+		Timeout:         conf.Entitlement.Webhook.Timeout,
+		RequireHTTPS:    conf.AppEnv == payment.AppEnvProd,
+	})
+	startBackgroundJob(ctx, "entitlement-webhook-delivery", conf.Entitlement.Webhook.Interval, entitlementWebhooks.Run)
 
-	srv := newHTTPServer(conf.Server.Port, userSvc, authSvc, paymentTokens, paymentIAP, subscriptionReader, paymentWebhook)
+	paywallExperiments, err := payment.ParsePaywallExperiments(conf.Paywall.Experiments)
+	if err != nil {
+		slog.Error("invalid paywall experiments", "err", err)
+		os.Exit(1)
+	}
+	paywallExperimentSvc := payment.NewPaywallExperimentService(iapCatalog, paywallExperiments, dao.NewPaywallExperimentDAO(db))
+
+	creditSvc := credits.NewService(dao.NewCreditDAO(db), conf.Credits.SweepBatchSize)
+	startBackgroundJob(ctx, "credit-expiry-sweep", conf.Credits.SweepInterval, creditSvc.RunSweeper)
+
+	settingsSvc := settings.NewService(dao.NewSettingsDAO(db), settings.DefaultRegistry(), settings.RedisCache{}, conf.Settings.CacheTTL)
+
+	referralSvc := referral.NewService(dao.NewReferralDAO(db), referral.Config{
+		RedeemWindow: conf.Referral.RedeemWindow,
+		InviterCap:   conf.Referral.InviterCap,
+		InviterReward: model.ReferralRewards{
+			Credits:  conf.Referral.InviterRewardCredits,
+			CompDays: conf.Referral.InviterRewardCompDays,
+		},
+		InviteeReward: model.ReferralRewards{
+			Credits:  conf.Referral.InviteeRewardCredits,
+			CompDays: conf.Referral.InviteeRewardCompDays,
+		},
+		CreditsTTL: conf.Referral.RewardCreditsTTL,
+		CompPlanID: conf.Referral.CompPlanID,
+		CompLevel:  conf.Referral.CompLevel,
+	})
+
+	compSvc := comp.NewService(compDAO, dao.NewPromoCodeDAO(db))
+
+	adminSvc := admin.NewService(dao.NewAdminDAO(db), conf.Auth.AdminUserIDs)
+	if payloadArchive != nil {
+		adminSvc.WithPayloadReader(payloadArchive)
+	}
+
+	srv := newHTTPServer(conf.Server.Port, api.UserDeps{
+		Users:        userSvc,
+		Auth:         authSvc,
+		Entitlements: entitlements,
+		Credits:      creditSvc,
+		Settings:     settingsSvc,
+		Referrals:    referralSvc,
+		PromoCodes:   compSvc,
+		Purchases:    buildPurchaseHistoryService(iapCatalog, dao.NewPurchaseHistoryDAO(db)),
+	}, api.PaymentDeps{
+		Auth:    authSvc,
+		Tokens:  paymentTokens,
+		IAP:     paymentIAP,
+		Webhook: paymentWebhookDeps(appleWebhook),
+
+		AppleOffers:  appleOffers,
+		AppleRestore: buildAppleRestoreService(iapCatalog, subscriptionDAO, paymentTokens, payloadArchive),
+
+		Paywall:      buildPaywallService(iapCatalog, googlePlayCatalog, stripeCatalog, paywallExperimentSvc),
+		Entitlements: entitlements,
+
+		GooglePlay:        googlePlayVerifyDeps(googlePlay),
+		GooglePlayWebhook: googlePlayWebhookDeps(googlePlay, googlePlayCatalog),
+
+		Stripe:        stripeDeps(stripeSvc),
+		StripeWebhook: stripeWebhookDeps(stripeSvc, stripeCatalog),
+	}, api.AdminDeps{
+		Auth:               authSvc,
+		Users:              adminSvc,
+		Products:           adminProductDeps(productCatalog),
+		PaywallExperiments: paywallExperimentSvc,
+		Revenue:            buildRevenueReportService(iapCatalog, dao.NewRevenueReportDAO(db), dao.NewAppleProductDAO(db)),
+		Webhooks:           entitlementWebhooks,
+		Comps:              compSvc,
+	})
 	startServer(srv)
 
 	waitForShutdown(srv, 10*time.Second)
+	cancel()
 }
 
 func initLogger(appEnv string, cfg logpkg.Config) {
@@ -98,13 +234,9 @@ func initCache(cfg config.CacheConfig) {
 	slog.Info("Cache initialized")
 }
 
-func initUserService(db *pgxpool.Pool) service.UserService {
-	return service.NewUserService(dao.NewUserDAO(db))
-}
-
 // buildPaymentIAPService 在 catalog 配置齐全时构造 verify 路径所需的 service；
 // 配置缺失时返回 nil，路由层会把 nil 映射为 503 让客户端提示“尚未开放”。
-func buildPaymentIAPService(catalog *payment.Catalog, subscriptionDAO dao.SubscriptionDAO, tokens *payment.TokenService) api.PaymentIAPService {
+func buildPaymentIAPService(catalog *payment.Catalog, subscriptionDAO dao.SubscriptionDAO, tokens *payment.TokenService, archive *payment.PayloadArchive) api.PaymentIAPService {
 	if catalog == nil {
 		return nil
 	}
@@ -113,23 +245,39 @@ func buildPaymentIAPService(catalog *payment.Catalog, subscriptionDAO dao.Subscr
 		slog.Warn("apple iap verifier unavailable; verify endpoint will return 503", "err", err)
 		return nil
 	}
-	return payment.NewAppleIAPService(catalog, verifier, tokens, subscriptionDAO)
+	return payment.NewAppleIAPService(catalog, verifier, tokens, subscriptionDAO).WithPayloadArchive(archive)
 }
 
-// buildSubscriptionReader 把 catalog + DAO 包装成 /users/me 用的 reader。
-//
-// catalog 缺失时返回 nil，user.go 退化为 SubscriptionInfo{Status:"NONE"}。
-func buildSubscriptionReader(catalog *payment.Catalog, subscriptionDAO dao.SubscriptionDAO) api.SubscriptionReader {
+// buildAppleRestoreService 在 catalog 配置齐全时构造恢复购买 service；否则返回 nil，路由返回 503。
+func buildAppleRestoreService(catalog *payment.Catalog, subscriptionDAO dao.SubscriptionDAO, tokens *payment.TokenService, archive *payment.PayloadArchive) api.PaymentAppleRestoreService {
 	if catalog == nil {
 		return nil
 	}
-	return payment.NewSubscriptionReader(subscriptionDAO, catalog)
+	verifier, err := payment.NewAppleTransactionVerifier(catalog)
+	if err != nil {
+		slog.Warn("apple iap verifier unavailable; restore endpoint will return 503", "err", err)
+		return nil
+	}
+	reconciler, err := payment.NewAppleReconciler(catalog)
+	if err != nil {
+		slog.Warn("apple reconciler unavailable; restore endpoint will return 503", "err", err)
+		return nil
+	}
+	return payment.NewAppleRestoreService(catalog, verifier, reconciler, tokens, subscriptionDAO).WithPayloadArchive(archive)
+}
+
+// buildPaywallService 在任一商店 catalog 可用时构造付费墙 service；全部缺失时返回 nil，路由返回 503。
+func buildPaywallService(apple *payment.Catalog, googlePlay *payment.GooglePlayCatalog, stripe *payment.StripeCatalog, experiments *payment.PaywallExperimentService) api.PaymentPaywallService {
+	if apple == nil && googlePlay == nil && stripe == nil {
+		return nil
+	}
+	return payment.NewPaywallService(apple, googlePlay, stripe).WithExperiments(experiments)
 }
 
 // buildPaymentWebhookService 在 catalog 配置齐全时构造 webhook service。
 //
 // catalog 缺失时返回 nil，路由层会把 nil 映射为 500（让 Apple 在配置恢复后自动重试）。
-func buildPaymentWebhookService(catalog *payment.Catalog, subscriptionDAO dao.SubscriptionDAO, tokens *payment.TokenService) api.PaymentWebhookService {
+func buildPaymentWebhookService(catalog *payment.Catalog, subscriptionDAO dao.SubscriptionDAO, tokens *payment.TokenService, archive *payment.PayloadArchive) *payment.AppleWebhookService {
 	if catalog == nil {
 		return nil
 	}
@@ -138,11 +286,216 @@ func buildPaymentWebhookService(catalog *payment.Catalog, subscriptionDAO dao.Su
 		slog.Warn("apple iap webhook verifier unavailable; webhook endpoint will return 500", "err", err)
 		return nil
 	}
-	return payment.NewAppleWebhookService(catalog, verifier, tokens, subscriptionDAO)
+	return payment.NewAppleWebhookService(catalog, verifier, tokens, subscriptionDAO).WithPayloadArchive(archive)
+}
+
+// buildProductCatalogService 在 catalog 配置齐全时构造数据库商品目录同步 service，并在启动时完成初始化与首次加载；
+// catalog 缺失时返回 nil，商品目录后台返回 503。初始化或加载失败只记录日志，catalog 继续使用 APPLE_IAP_PRODUCTS。
+func buildProductCatalogService(ctx context.Context, catalog *payment.Catalog, productDAO dao.AppleProductDAO) *payment.ProductCatalogService {
+	if catalog == nil {
+		return nil
+	}
+	svc := payment.NewProductCatalogService(catalog, productDAO)
+	if err := svc.Seed(ctx); err != nil {
+		slog.Warn("apple product catalog seed failed", "err", err)
+	}
+	if err := svc.Refresh(ctx); err != nil {
+		slog.Warn("apple product catalog load failed; using APPLE_IAP_PRODUCTS", "err", err)
+	}
+	return svc
+}
+
+// adminProductDeps 避免把 nil *ProductCatalogService 包成非 nil 接口，路由层据此返回 503。
+func adminProductDeps(svc *payment.ProductCatalogService) api.AdminAppleProductService {
+	if svc == nil {
+		return nil
+	}
+	return svc
+}
+
+// buildRevenueReportService 在 catalog 配置齐全时构造收入报表 service；否则返回 nil，路由返回 503。
+func buildRevenueReportService(catalog *payment.Catalog, reportDAO dao.RevenueReportDAO, productDAO dao.AppleProductDAO) api.AdminRevenueReportService {
+	if catalog == nil {
+		return nil
+	}
+	return payment.NewRevenueReportService(catalog, reportDAO).WithProducts(productDAO)
+}
+
+// buildPayloadArchive 在配置了 PAYLOAD_ARCHIVE_KEYS 时构造加密归档；未配置时返回 nil（不归档），
+// key 格式错误时返回 error，避免静默丢失归档。
+func buildPayloadArchive(archiveDAO dao.ApplePayloadArchiveDAO, cfg config.PayloadArchiveConfig) (*payment.PayloadArchive, error) {
+	keyring, err := payment.NewPayloadKeyring(cfg)
+	if errors.Is(err, payment.ErrNotConfigured) {
+		slog.Info("apple payload archive disabled; PAYLOAD_ARCHIVE_KEYS is not set")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return payment.NewPayloadArchive(keyring, archiveDAO, payment.PayloadArchiveConfig{
+		Retention: cfg.Retention,
+		BatchSize: cfg.BatchSize,
+	}), nil
+}
+
+// paymentWebhookDeps 避免把 nil *AppleWebhookService 包成非 nil 接口，路由层据此返回 500。
+func paymentWebhookDeps(svc *payment.AppleWebhookService) api.PaymentWebhookService {
+	if svc == nil {
+		return nil
+	}
+	return svc
+}
+
+// buildAppleOfferService 在 catalog 配置齐全且私钥可用于签名时构造优惠签名 service；否则返回 nil，路由层返回 503。
+func buildAppleOfferService(catalog *payment.Catalog, subscriptionDAO dao.SubscriptionDAO, tokens *payment.TokenService, offerDAO dao.AppleOfferDAO) api.PaymentAppleOfferService {
+	if catalog == nil {
+		return nil
+	}
+	svc, err := payment.NewAppleOfferService(catalog, tokens, subscriptionDAO, offerDAO)
+	if err != nil {
+		slog.Warn("apple offer signer unavailable; offer-signature endpoint will return 503", "err", err)
+		return nil
+	}
+	return svc
+}
+
+// buildPurchaseHistoryService 在 catalog 配置齐全时构造购买记录 service；否则返回 nil，路由返回 503。
+func buildPurchaseHistoryService(catalog *payment.Catalog, historyDAO dao.PurchaseHistoryDAO) api.PurchaseHistoryService {
+	if catalog == nil {
+		return nil
+	}
+	return payment.NewPurchaseHistoryService(catalog, historyDAO)
+}
+
+// buildAppleStatusSyncService 在 catalog 配置齐全时构造订阅状态同步任务；否则返回 nil，任务不启动。
+func buildAppleStatusSyncService(catalog *payment.Catalog, subscriptionDAO dao.SubscriptionDAO, syncDAO dao.AppleStatusSyncDAO, cfg config.AppleStatusSyncConfig) *payment.AppleStatusSyncService {
+	if catalog == nil {
+		return nil
+	}
+	reconciler, err := payment.NewAppleReconciler(catalog)
+	if err != nil {
+		slog.Warn("apple reconciler unavailable; subscription status sync is disabled", "err", err)
+		return nil
+	}
+	return payment.NewAppleStatusSyncService(catalog, reconciler, subscriptionDAO, syncDAO, payment.AppleStatusSyncConfig{
+		ExpiryWindow:  cfg.ExpiryWindow,
+		StaleAfter:    cfg.StaleAfter,
+		ResyncAfter:   cfg.ResyncAfter,
+		BatchSize:     cfg.BatchSize,
+		Concurrency:   cfg.Concurrency,
+		RatePerSecond: cfg.RatePerSecond,
+	})
+}
+
+// buildAppleConsumptionService 在 catalog 配置齐全时构造 CONSUMPTION_REQUEST 回复任务；否则返回 nil，任务不启动。
+func buildAppleConsumptionService(catalog *payment.Catalog, consumptionDAO dao.AppleConsumptionDAO, cfg config.AppleConsumptionConfig) *payment.AppleConsumptionService {
+	if catalog == nil {
+		return nil
+	}
+	sender, err := payment.NewAppleConsumptionSender(catalog)
+	if err != nil {
+		slog.Warn("apple consumption sender unavailable; consumption requests are recorded but not answered", "err", err)
+		return nil
+	}
+	return payment.NewAppleConsumptionService(consumptionDAO, sender, payment.AppleConsumptionConfig{
+		Timeout:           catalog.AppleAPITimeout(),
+		BatchSize:         cfg.BatchSize,
+		MaxAttempts:       cfg.MaxAttempts,
+		RetryBackoff:      cfg.RetryBackoff,
+		MaxRetryBackoff:   cfg.MaxRetryBackoff,
+		CustomerConsented: cfg.CustomerConsented,
+		RefundPreference:  cfg.RefundPreference,
+	})
+}
+
+// buildGooglePlayService 在 Google Play catalog 配置齐全时构造 verify / RTDN 共用的 service；否则返回 nil。
+func buildGooglePlayService(catalog *payment.GooglePlayCatalog, googlePlayDAO dao.GooglePlayDAO, tokens *payment.TokenService) *payment.GooglePlayService {
+	if catalog == nil {
+		return nil
+	}
+	publisher, err := payment.NewGooglePlayPublisher(catalog)
+	if err != nil {
+		slog.Warn("google play publisher unavailable; google play endpoints are disabled", "err", err)
+		return nil
+	}
+	return payment.NewGooglePlayService(catalog, publisher, tokens, googlePlayDAO)
+}
+
+// googlePlayVerifyDeps 避免把 nil *GooglePlayService 包成非 nil 接口，路由层据此返回 503。
+func googlePlayVerifyDeps(svc *payment.GooglePlayService) api.PaymentGooglePlayService {
+	if svc == nil {
+		return nil
+	}
+	return svc
+}
+
+// googlePlayWebhookDeps 仅在配置了 GOOGLE_PLAY_WEBHOOK_TOKEN 时开放 RTDN webhook；未开放时路由返回 500，
+// Pub/Sub 会在配置恢复后继续重投。
+func googlePlayWebhookDeps(svc *payment.GooglePlayService, catalog *payment.GooglePlayCatalog) api.PaymentGooglePlayWebhookService {
+	if svc == nil || catalog.WebhookToken() == "" {
+		return nil
+	}
+	return svc
+}
+
+// buildStripeService 在 Stripe catalog 配置齐全时构造 checkout / portal / webhook 共用的 service；否则返回 nil。
+func buildStripeService(catalog *payment.StripeCatalog, stripeDAO dao.StripeDAO) *payment.StripeService {
+	if catalog == nil {
+		return nil
+	}
+	stripeAPI, err := payment.NewStripeAPI(catalog)
+	if err != nil {
+		slog.Warn("stripe api client unavailable; stripe endpoints are disabled", "err", err)
+		return nil
+	}
+	return payment.NewStripeService(catalog, stripeAPI, stripeDAO)
+}
+
+// stripeDeps 避免把 nil *StripeService 包成非 nil 接口，路由层据此返回 503。
+func stripeDeps(svc *payment.StripeService) api.PaymentStripeService {
+	if svc == nil {
+		return nil
+	}
+	return svc
+}
+
+// stripeWebhookDeps 仅在配置了 STRIPE_WEBHOOK_SECRET 时开放 webhook；未开放时路由返回 500，Stripe 会持续重试。
+func stripeWebhookDeps(svc *payment.StripeService, catalog *payment.StripeCatalog) api.PaymentStripeWebhookService {
+	if svc == nil || catalog.WebhookSecret() == "" {
+		return nil
+	}
+	return svc
+}
+
+// startBackgroundJob 在独立 goroutine 中每隔 interval 调用一次 tick，ctx 取消（服务关机）时任务退出。
+// 单次失败只记录日志，不中断循环。
+//
+// interval <= 0 视为关闭该任务，只打一条日志。
+func startBackgroundJob(ctx context.Context, name string, interval time.Duration, tick func(context.Context) error) {
+	if interval <= 0 {
+		slog.Info("background job disabled", "job", name)
+		return
+	}
+	go func() {
+		slog.Info("background job started", "job", name, "interval", interval)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("background job stopped", "job", name)
+				return
+			case <-ticker.C:
+				if err := tick(ctx); err != nil && !errors.Is(err, context.Canceled) {
+					slog.Error("background job failed", "job", name, "err", err)
+				}
+			}
+		}
+	}()
 }
 
 // 构建一个带中间件和路由的 HTTP Server
-func newHTTPServer(port int, userSvc service.UserService, authSvc auth.Service, paymentTokens *payment.TokenService, paymentIAP api.PaymentIAPService, subscriptions api.SubscriptionReader, paymentWebhook api.PaymentWebhookService) *http.Server {
+func newHTTPServer(port int, userDeps api.UserDeps, paymentDeps api.PaymentDeps, adminDeps api.AdminDeps) *http.Server {
 	r := chi.NewRouter()
 	r.Use(
 		chiMw.RequestID,
@@ -174,17 +527,9 @@ func newHTTPServer(port int, userSvc service.UserService, authSvc auth.Service,
 		},
 	}
 	humaAPI := humachi.New(r, humaConfig)
-	api.RegisterUserRoutes(humaAPI, api.UserDeps{
-		Users:         userSvc,
-		Auth:          authSvc,
-		Subscriptions: subscriptions,
-	})
-	api.RegisterPaymentRoutes(humaAPI, api.PaymentDeps{
-		Auth:    authSvc,
-		Tokens:  paymentTokens,
-		IAP:     paymentIAP,
-		Webhook: paymentWebhook,
-	})
+	api.RegisterUserRoutes(humaAPI, userDeps)
+	api.RegisterPaymentRoutes(humaAPI, paymentDeps)
+	api.RegisterAdminRoutes(humaAPI, adminDeps)
 
 	addr := fmt.Sprintf(":%d", port)
 	return &http.Server{
@@ -0,0 +1,39 @@
+-- Migration: 003_credits
+-- Purpose: Store user credits as expiring buckets with an append-only ledger.
+--   * credit_buckets: one row per grant (purchase / promo / subscription allowance) with its own optional expiry.
+--   * credit_ledger:  audit trail of every balance change (GRANT / SPEND / EXPIRE), one row per touched bucket.
+-- Spending drains buckets FIFO by expires_at (NULL = never expires, drained last).
+-- Idempotent: uses CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS credit_buckets (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    remaining BIGINT NOT NULL CHECK (remaining >= 0),
+    expires_at TIMESTAMPTZ,
+    reference TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS credit_buckets_user_spendable_idx
+    ON credit_buckets(user_id, expires_at ASC NULLS LAST, id)
+    WHERE remaining > 0;
+
+CREATE INDEX IF NOT EXISTS credit_buckets_expiry_idx
+    ON credit_buckets(expires_at)
+    WHERE remaining > 0 AND expires_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS credit_ledger (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    bucket_id BIGINT REFERENCES credit_buckets(id) ON DELETE SET NULL,
+    entry_type TEXT NOT NULL,
+    delta BIGINT NOT NULL,
+    reference TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS credit_ledger_user_idx
+    ON credit_ledger(user_id, created_at DESC);
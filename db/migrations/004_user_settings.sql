@@ -0,0 +1,15 @@
+-- Migration: 004_user_settings
+-- Purpose: Per-user preference overrides stored as key/value pairs.
+--   * Allowed keys, value types and defaults live in the server-side registry
+--     (internal/service/settings); only values that differ from the default need a row.
+--   * value is the JSON encoding of the setting, validated by the registry before write.
+-- Idempotent: uses CREATE TABLE IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    value JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, key)
+);
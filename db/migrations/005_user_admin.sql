@@ -0,0 +1,21 @@
+-- Migration: 005_user_admin
+-- Purpose: Support staff user management.
+--   * users.status / status_reason / status_updated_at: account state set by admins (ACTIVE / SUSPENDED).
+--   * users.sessions_revoked_at: access tokens issued at or before this instant are rejected (force logout).
+--   * Lookup indexes for admin search by email, provider subject and per-user event history.
+-- Idempotent: uses ADD COLUMN / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'ACTIVE';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_updated_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS sessions_revoked_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS auth_identities_email_lower_idx
+    ON auth_identities(lower(email))
+    WHERE email <> '';
+
+CREATE INDEX IF NOT EXISTS auth_identities_provider_subject_idx
+    ON auth_identities(provider_subject);
+
+CREATE INDEX IF NOT EXISTS apple_events_user_created_idx
+    ON apple_events(user_id, created_at DESC);
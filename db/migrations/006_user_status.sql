@@ -0,0 +1,12 @@
+-- Migration: 006_user_status
+-- Purpose: Enforce account status at authentication.
+--   * users.status gains BANNED / DELETED alongside ACTIVE / SUSPENDED and is now CHECK-constrained.
+--   * users.status_expires_at: optional end of a SUSPENDED / BANNED period; once passed the account is
+--     treated as ACTIVE again without a write. DELETED never expires.
+-- Idempotent: ADD COLUMN IF NOT EXISTS + DROP CONSTRAINT IF EXISTS before re-adding the check.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_expires_at TIMESTAMPTZ;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users ADD CONSTRAINT users_status_check
+    CHECK (status IN ('ACTIVE', 'SUSPENDED', 'BANNED', 'DELETED'));
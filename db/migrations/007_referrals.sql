@@ -0,0 +1,51 @@
+-- Migration: 007_referrals
+-- Purpose: Referral codes with two-sided rewards.
+--   * referral_codes: one stable, human-typable code per user (generated lazily on first read).
+--   * referral_redemptions: one row per invitee; the rewards granted to each side are recorded for stats/audit.
+--     device_key is the invitee's guest subject / client device id and may redeem at most once.
+--   * comp_entitlements: complimentary entitlement periods (referral rewards etc.) that count as an active
+--     subscription for /users/me; consecutive grants for the same user are stacked end-to-end.
+-- Idempotent: uses CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS referral_codes (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    code TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS referral_redemptions (
+    id BIGSERIAL PRIMARY KEY,
+    inviter_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invitee_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code TEXT NOT NULL,
+    device_key TEXT NOT NULL DEFAULT '',
+    inviter_reward_credits BIGINT NOT NULL DEFAULT 0,
+    inviter_reward_comp_days INT NOT NULL DEFAULT 0,
+    invitee_reward_credits BIGINT NOT NULL DEFAULT 0,
+    invitee_reward_comp_days INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT referral_redemptions_invitee_key UNIQUE (invitee_user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS referral_redemptions_device_key_uidx
+    ON referral_redemptions(device_key)
+    WHERE device_key <> '';
+
+CREATE INDEX IF NOT EXISTS referral_redemptions_inviter_idx
+    ON referral_redemptions(inviter_user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS comp_entitlements (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    plan_id TEXT NOT NULL,
+    level INT NOT NULL,
+    source TEXT NOT NULL,
+    reference TEXT NOT NULL DEFAULT '',
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS comp_entitlements_user_ends_idx
+    ON comp_entitlements(user_id, ends_at DESC);
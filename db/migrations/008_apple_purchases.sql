@@ -0,0 +1,34 @@
+-- Migration: 008_apple_purchases
+-- Purpose: Record Apple one-time purchases (consumables / non-consumables) keyed by transaction.
+--   * apple_purchases: one row per (transaction_id, environment); inserting the row is the exactly-once gate for
+--     crediting consumables, credit_bucket_id points at the bucket granted for it so REFUND can reverse the grant.
+--     Active non-consumable rows count as a lifetime entitlement for /users/me.
+-- Idempotent: uses CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS apple_purchases (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    environment TEXT NOT NULL,
+    transaction_id TEXT NOT NULL,
+    original_transaction_id TEXT NOT NULL DEFAULT '',
+    plan_id TEXT NOT NULL,
+    provider_product_id TEXT NOT NULL,
+    product_type TEXT NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 1,
+    level INTEGER NOT NULL DEFAULT 0,
+    credits BIGINT NOT NULL DEFAULT 0,
+    credit_bucket_id BIGINT REFERENCES credit_buckets(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'ACTIVE',
+    purchased_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (transaction_id, environment)
+);
+
+CREATE INDEX IF NOT EXISTS apple_purchases_user_idx
+    ON apple_purchases(user_id, purchased_at DESC);
+
+CREATE INDEX IF NOT EXISTS apple_purchases_user_lifetime_idx
+    ON apple_purchases(user_id, level DESC)
+    WHERE product_type = 'non_consumable' AND status = 'ACTIVE';
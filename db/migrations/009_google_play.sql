@@ -0,0 +1,80 @@
+-- Migration: 009_google_play
+-- Purpose: Add Google Play Billing tables next to the Apple IAP ones.
+--   * google_play_subscriptions: current state of a Play subscription per purchase_token. linked_purchase_token points at the
+--     token it replaced (upgrade / downgrade / resubscribe).
+--   * google_play_purchases:     one-time products (consumables / non-consumables) per purchase_token; inserting the row is the
+--     exactly-once gate for crediting consumables, credit_bucket_id lets a voided purchase reverse the grant.
+--   * google_play_events:        idempotent audit log of Real-Time Developer Notifications keyed by the Pub/Sub message id.
+-- Idempotent: uses CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS google_play_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    package_name TEXT NOT NULL,
+    purchase_token TEXT NOT NULL,
+    linked_purchase_token TEXT NOT NULL DEFAULT '',
+    latest_order_id TEXT NOT NULL DEFAULT '',
+    plan_id TEXT NOT NULL,
+    provider_product_id TEXT NOT NULL,
+    level INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'ACTIVE',
+    auto_renew_status TEXT NOT NULL DEFAULT 'UNKNOWN',
+    test_purchase BOOLEAN NOT NULL DEFAULT false,
+    current_period_start TIMESTAMPTZ NOT NULL,
+    current_period_end TIMESTAMPTZ NOT NULL,
+    last_event_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (purchase_token)
+);
+
+CREATE INDEX IF NOT EXISTS google_play_subscriptions_user_entitlement_idx
+    ON google_play_subscriptions(user_id, level DESC, current_period_end DESC)
+    WHERE status IN ('ACTIVE', 'CANCELED');
+
+CREATE TABLE IF NOT EXISTS google_play_purchases (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    package_name TEXT NOT NULL,
+    purchase_token TEXT NOT NULL,
+    order_id TEXT NOT NULL DEFAULT '',
+    plan_id TEXT NOT NULL,
+    provider_product_id TEXT NOT NULL,
+    product_type TEXT NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 1,
+    level INTEGER NOT NULL DEFAULT 0,
+    credits BIGINT NOT NULL DEFAULT 0,
+    credit_bucket_id BIGINT REFERENCES credit_buckets(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'ACTIVE',
+    test_purchase BOOLEAN NOT NULL DEFAULT false,
+    purchased_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (purchase_token)
+);
+
+CREATE INDEX IF NOT EXISTS google_play_purchases_user_lifetime_idx
+    ON google_play_purchases(user_id, level DESC)
+    WHERE product_type = 'non_consumable' AND status = 'ACTIVE';
+
+CREATE TABLE IF NOT EXISTS google_play_events (
+    id BIGSERIAL PRIMARY KEY,
+    message_id TEXT NOT NULL UNIQUE,
+    notification_kind TEXT NOT NULL,
+    notification_type INTEGER NOT NULL DEFAULT 0,
+    package_name TEXT NOT NULL DEFAULT '',
+    purchase_token TEXT NOT NULL DEFAULT '',
+    product_id TEXT NOT NULL DEFAULT '',
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    processing_status TEXT NOT NULL DEFAULT 'PROCESSED',
+    processing_error TEXT NOT NULL DEFAULT '',
+    event_time TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS google_play_events_purchase_token_idx
+    ON google_play_events(purchase_token);
+
+CREATE INDEX IF NOT EXISTS google_play_events_status_idx
+    ON google_play_events(processing_status, created_at);
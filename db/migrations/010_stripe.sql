@@ -0,0 +1,59 @@
+-- Migration: 010_stripe
+-- Purpose: Add Stripe web billing tables.
+--   * stripe_customers:     one Stripe customer per user, created lazily on the first checkout; the reverse lookup lets
+--                           webhooks resolve the user from the event's customer id.
+--   * stripe_subscriptions: current state of a Stripe subscription per subscription id, projected into the same
+--                           status / level / period columns as apple_subscriptions.
+--   * stripe_events:        idempotent audit log of webhook events keyed by the Stripe event id.
+-- Idempotent: uses CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS stripe_customers (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    customer_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id),
+    UNIQUE (customer_id)
+);
+
+CREATE TABLE IF NOT EXISTS stripe_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    customer_id TEXT NOT NULL,
+    subscription_id TEXT NOT NULL,
+    price_id TEXT NOT NULL,
+    plan_id TEXT NOT NULL,
+    level INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'ACTIVE',
+    stripe_status TEXT NOT NULL DEFAULT '',
+    auto_renew_status TEXT NOT NULL DEFAULT 'UNKNOWN',
+    current_period_start TIMESTAMPTZ NOT NULL,
+    current_period_end TIMESTAMPTZ NOT NULL,
+    last_event_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (subscription_id)
+);
+
+CREATE INDEX IF NOT EXISTS stripe_subscriptions_user_entitlement_idx
+    ON stripe_subscriptions(user_id, level DESC, current_period_end DESC)
+    WHERE status IN ('ACTIVE', 'CANCELED');
+
+CREATE TABLE IF NOT EXISTS stripe_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_id TEXT NOT NULL UNIQUE,
+    event_type TEXT NOT NULL,
+    customer_id TEXT NOT NULL DEFAULT '',
+    subscription_id TEXT NOT NULL DEFAULT '',
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    processing_status TEXT NOT NULL DEFAULT 'PROCESSED',
+    processing_error TEXT NOT NULL DEFAULT '',
+    event_created_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS stripe_events_subscription_idx
+    ON stripe_events(subscription_id);
+
+CREATE INDEX IF NOT EXISTS stripe_events_status_idx
+    ON stripe_events(processing_status, created_at);
@@ -0,0 +1,28 @@
+-- Migration: 011_apple_family_shares
+-- Purpose: Track Apple Family Sharing entitlements separately from the purchaser's subscription.
+--   * apple_family_shares: one row per family member's shared subscription, keyed by the family member's own
+--     (original_transaction_id, environment). Family-shared transactions carry no appAccountToken, so the row is
+--     created when the family member claims the transaction through verify; the first claimer owns it.
+--     REVOKE notifications (the purchaser stopped sharing or left the family) flip status to REVOKED.
+-- Idempotent: uses CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS apple_family_shares (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    environment TEXT NOT NULL,
+    original_transaction_id TEXT NOT NULL,
+    last_transaction_id TEXT NOT NULL,
+    plan_id TEXT NOT NULL,
+    provider_product_id TEXT NOT NULL,
+    level INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL,
+    current_period_end TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    last_event_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (original_transaction_id, environment)
+);
+
+CREATE INDEX IF NOT EXISTS apple_family_shares_user_idx
+    ON apple_family_shares(user_id, level DESC, current_period_end DESC);
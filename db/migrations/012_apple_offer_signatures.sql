@@ -0,0 +1,21 @@
+-- Migration: 012_apple_offer_signatures
+-- Purpose: Audit log of StoreKit promotional / win-back offer signatures issued by POST /payment/apple/offer-signature.
+--   * apple_offer_signatures: one row per issued signature. The nonce is unique so a signature can be traced back
+--     to the user and offer it was generated for; per-user counts enforce the offer's max_per_user rule.
+-- Idempotent: uses CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS apple_offer_signatures (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    offer_id TEXT NOT NULL,
+    offer_type TEXT NOT NULL,
+    product_id TEXT NOT NULL,
+    app_account_token TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+    signed_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (nonce)
+);
+
+CREATE INDEX IF NOT EXISTS apple_offer_signatures_user_offer_idx
+    ON apple_offer_signatures(user_id, offer_id);
@@ -0,0 +1,18 @@
+-- Migration: 013_apple_subscription_status_sync
+-- Purpose: Bookkeeping for the scheduled job that reconciles apple_subscriptions against Apple's
+--   Get All Subscription Statuses API to catch missed App Store Server Notifications.
+--   * apple_subscription_status_syncs: one row per apple_subscriptions row that has been synced at least once.
+--     synced_at drives the re-sync back-off; apple_status / last_error record the latest outcome for operators.
+--     Kept out of apple_subscriptions so a sync never touches last_event_at or the reducer's snapshot columns.
+-- Idempotent: uses CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS apple_subscription_status_syncs (
+    subscription_id BIGINT PRIMARY KEY REFERENCES apple_subscriptions(id) ON DELETE CASCADE,
+    synced_at TIMESTAMPTZ NOT NULL,
+    apple_status INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS apple_subscription_status_syncs_synced_at_idx
+    ON apple_subscription_status_syncs(synced_at);
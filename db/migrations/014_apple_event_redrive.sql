@@ -0,0 +1,11 @@
+-- Migration: 014_apple_event_redrive
+-- Purpose: Support re-driving App Store Server Notifications parked as PENDING_USER_BINDING.
+--   * apple_events.decoded_payload is now populated for PENDING_USER_BINDING events so they can be reprocessed
+--     without Apple once the appAccountToken is bound to a user.
+--   * apple_events_pending_token_idx: partial index used to find parked events by appAccountToken when a binding
+--     is created or verify succeeds, and by the periodic sweep.
+-- Idempotent: uses CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE INDEX IF NOT EXISTS apple_events_pending_token_idx
+    ON apple_events(app_account_token)
+    WHERE processing_status = 'PENDING_USER_BINDING';
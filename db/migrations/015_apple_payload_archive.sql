@@ -0,0 +1,42 @@
+-- Migration: 015_apple_payload_archive
+-- Purpose: Encrypted archive of decoded App Store Server Notifications and transactions for dispute investigation.
+--   * apple_payload_archives: one row per archived payload. The payload is sealed with a per-row data key (AES-256-GCM);
+--     the data key is wrapped by the master key named in key_id, so master keys can be rotated by re-wrapping
+--     wrapped_key without touching ciphertext. Plaintext payloads are never stored.
+--   * source: NOTIFICATION rows reference apple_events(id); TRANSACTION rows come from POST /payment/apple/verify
+--     and are unique per (environment, transaction_id).
+--   * expires_at drives the retention job that purges archives after the configured retention period.
+-- Idempotent: uses CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS apple_payload_archives (
+    id BIGSERIAL PRIMARY KEY,
+    source TEXT NOT NULL,
+    event_id BIGINT REFERENCES apple_events(id) ON DELETE CASCADE,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    environment TEXT NOT NULL,
+    original_transaction_id TEXT NOT NULL DEFAULT '',
+    transaction_id TEXT NOT NULL DEFAULT '',
+    key_id TEXT NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    nonce BYTEA NOT NULL,
+    ciphertext BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS apple_payload_archives_event_uq
+    ON apple_payload_archives(event_id)
+    WHERE event_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS apple_payload_archives_transaction_uq
+    ON apple_payload_archives(environment, transaction_id)
+    WHERE source = 'TRANSACTION';
+
+CREATE INDEX IF NOT EXISTS apple_payload_archives_original_tx_idx
+    ON apple_payload_archives(original_transaction_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS apple_payload_archives_expires_at_idx
+    ON apple_payload_archives(expires_at);
+
+CREATE INDEX IF NOT EXISTS apple_payload_archives_key_id_idx
+    ON apple_payload_archives(key_id);
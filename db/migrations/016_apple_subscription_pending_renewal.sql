@@ -0,0 +1,11 @@
+-- Migration: 016_apple_subscription_pending_renewal
+-- Purpose: Track the product a subscription will renew into after an in-group change.
+--   * pending_product_id / pending_plan_id / pending_level come from renewal info's autoRenewProductId when it
+--     differs from the current product (downgrade or crossgrade scheduled for the next renewal).
+--     Empty / 0 means no pending change. The current plan_id / level stay in effect until the renewal
+--     transaction for the pending product arrives; upgrades take effect immediately and never leave a pending row.
+-- Idempotent: uses ADD COLUMN IF NOT EXISTS so re-running this migration is safe.
+
+ALTER TABLE apple_subscriptions ADD COLUMN IF NOT EXISTS pending_product_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE apple_subscriptions ADD COLUMN IF NOT EXISTS pending_plan_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE apple_subscriptions ADD COLUMN IF NOT EXISTS pending_level INTEGER NOT NULL DEFAULT 0;
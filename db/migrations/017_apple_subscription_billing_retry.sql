@@ -0,0 +1,10 @@
+-- Migration: 017_apple_subscription_billing_retry
+-- Purpose: Keep the renewal-failure details Apple sends in renewal info so /users/me can tell a lapsed payment
+--   apart from a plain expiry.
+--   * billing_retry: renewal info isInBillingRetryPeriod; Apple is still trying to charge the user.
+--   * expiration_intent: renewal info expirationIntent (1 customer canceled, 2 billing error, 3 price increase
+--     declined, 4 product unavailable, 5 other); 0 means not expired / unknown.
+-- Idempotent: uses ADD COLUMN IF NOT EXISTS so re-running this migration is safe.
+
+ALTER TABLE apple_subscriptions ADD COLUMN IF NOT EXISTS billing_retry BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE apple_subscriptions ADD COLUMN IF NOT EXISTS expiration_intent INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,33 @@
+-- Migration: 018_apple_consumption_requests
+-- Purpose: Track App Store CONSUMPTION_REQUEST notifications and our Send Consumption Information responses.
+--   * apple_consumption_requests: one row per CONSUMPTION_REQUEST event. Apple only accepts the response within 12 hours
+--     of the notification (deadline_at); a background job sends PENDING rows when next_attempt_at is due and backs off
+--     on failure. status: PENDING -> SENT, or FAILED once attempts are exhausted / the deadline has passed.
+--     sent_payload keeps the exact body sent to Apple for support follow-ups.
+-- Idempotent: uses CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS apple_consumption_requests (
+    id BIGSERIAL PRIMARY KEY,
+    event_id BIGINT NOT NULL UNIQUE REFERENCES apple_events(id) ON DELETE CASCADE,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    environment TEXT NOT NULL,
+    original_transaction_id TEXT NOT NULL DEFAULT '',
+    transaction_id TEXT NOT NULL,
+    app_account_token UUID,
+    status TEXT NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deadline_at TIMESTAMPTZ NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_payload JSONB,
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS apple_consumption_requests_due_idx
+    ON apple_consumption_requests(next_attempt_at)
+    WHERE status = 'PENDING';
+
+CREATE INDEX IF NOT EXISTS apple_consumption_requests_transaction_idx
+    ON apple_consumption_requests(transaction_id, environment);
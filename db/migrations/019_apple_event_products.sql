@@ -0,0 +1,15 @@
+-- Migration: 019_apple_event_products
+-- Purpose: Keep the product of each App Store notification so users can see their purchase history.
+--   * apple_events.product_id: productId of the notification's transaction.
+--   * apple_events.renewal_product_id: autoRenewProductId from the renewal info; for DID_CHANGE_RENEWAL_PREF this is
+--     the plan the user switches to.
+--   Rows written before this migration keep '' and fall back to the subscription / purchase row when read.
+--   * apple_events_user_timeline_idx: serves GET /users/me/purchases (newest first, per user).
+-- Idempotent: uses ADD COLUMN / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+ALTER TABLE apple_events ADD COLUMN IF NOT EXISTS product_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE apple_events ADD COLUMN IF NOT EXISTS renewal_product_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS apple_events_user_timeline_idx
+    ON apple_events(user_id, (COALESCE(notification_created_at, created_at)) DESC, id DESC)
+    WHERE processing_status = 'PROCESSED';
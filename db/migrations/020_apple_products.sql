@@ -0,0 +1,30 @@
+-- Migration: 020_apple_products
+-- Purpose: Manage the Apple IAP product catalog in the database instead of the APPLE_IAP_PRODUCTS env var.
+--   * apple_products: one row per (product_id, environment). Columns mirror the APPLE_IAP_PRODUCTS JSON fields plus
+--     display metadata (display_name, description, sort_order) for client paywalls. Rows are deactivated rather than
+--     deleted so historical subscriptions / purchases can still be resolved by support tooling.
+--   * Every instance reloads active rows periodically and swaps its in-memory catalog atomically; when the table is
+--     empty it is seeded from APPLE_IAP_PRODUCTS, which also remains the fallback if no active rows exist.
+-- Idempotent: uses CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS apple_products (
+    id BIGSERIAL PRIMARY KEY,
+    plan_id TEXT NOT NULL,
+    product_id TEXT NOT NULL,
+    environment TEXT NOT NULL,
+    type TEXT NOT NULL DEFAULT 'subscription',
+    level INTEGER NOT NULL DEFAULT 0,
+    credits BIGINT NOT NULL DEFAULT 0,
+    subscription_group_id TEXT NOT NULL DEFAULT '',
+    display_name TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (product_id, environment)
+);
+
+CREATE INDEX IF NOT EXISTS apple_products_active_idx
+    ON apple_products(sort_order, id)
+    WHERE active;
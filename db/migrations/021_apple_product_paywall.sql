@@ -0,0 +1,10 @@
+-- Migration: 021_apple_product_paywall
+-- Purpose: Serve the client paywall (GET /payment/products) from the product catalog.
+--   * apple_products.localizations: per-locale paywall copy, a JSON object keyed by BCP 47 locale
+--     ({"zh-Hans": {"title": "...", "description": "...", "features": ["..."]}}). display_name / description
+--     remain the fallback for locales without an entry.
+--   * apple_products.recommended: highlight this plan on the paywall.
+-- Idempotent: uses ADD COLUMN IF NOT EXISTS so re-running this migration is safe.
+
+ALTER TABLE apple_products ADD COLUMN IF NOT EXISTS localizations JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE apple_products ADD COLUMN IF NOT EXISTS recommended BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,22 @@
+-- Migration: 022_paywall_experiments
+-- Purpose: Persist paywall A/B experiment exposures.
+--   * paywall_exposures: one row per (experiment, user). The variant is chosen deterministically from
+--     hash(user_id, experiment_id) on first exposure and never changes afterwards, so re-weighting an
+--     experiment only affects users who have not seen it yet.
+--   * first_exposed_at anchors conversion attribution: an apple_subscriptions row created at or after it
+--     counts as a conversion of the exposed variant.
+-- Idempotent: uses IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS paywall_exposures (
+    id BIGSERIAL PRIMARY KEY,
+    experiment_id TEXT NOT NULL,
+    variant_id TEXT NOT NULL,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform TEXT NOT NULL,
+    first_exposed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_exposed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (experiment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_paywall_exposures_experiment_variant
+    ON paywall_exposures (experiment_id, variant_id);
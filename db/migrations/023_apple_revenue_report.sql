@@ -0,0 +1,19 @@
+-- Migration: 023_apple_revenue_report
+-- Purpose: Support the subscription revenue / churn report, which is computed by replaying apple_events.
+--   * apple_events.offer_discount_type: offerDiscountType of the notification's transaction (FREE_TRIAL,
+--     PAY_AS_YOU_GO, PAY_UP_FRONT), used for trial conversion. Rows written before this migration keep ''
+--     and are reported as paid subscriptions.
+--   * apple_products.price_milliunits / currency / billing_period: reference price table for MRR. The price is
+--     in milliunits of currency (Apple's convention), billing_period an ISO 8601 duration (P1W, P1M, P1Y, ...).
+--     Products without a price contribute 0 to MRR.
+--   * apple_events_occurred_idx: serves the report's chronological scan.
+-- Idempotent: uses ADD COLUMN / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+ALTER TABLE apple_events ADD COLUMN IF NOT EXISTS offer_discount_type TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE apple_products ADD COLUMN IF NOT EXISTS price_milliunits BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE apple_products ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE apple_products ADD COLUMN IF NOT EXISTS billing_period TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS apple_events_occurred_idx
+    ON apple_events((COALESCE(notification_created_at, created_at)), id);
@@ -0,0 +1,52 @@
+-- Migration: 024_entitlement_webhooks
+-- Purpose: Push subscription state changes to downstream services instead of having them poll /users/me.
+--   * entitlement_webhook_subscribers: registry of callback URLs, the HMAC secret used to sign each request and the
+--     event types (subscription.activated / subscription.deactivated / subscription.updated) each one receives.
+--     Subscribers are deactivated rather than deleted so their delivery history stays queryable.
+--   * entitlement_webhook_events: one row per committed change of an Apple / Google Play / Stripe subscription row,
+--     written in the same transaction as the change. Only written when at least one active subscriber wants it.
+--   * entitlement_webhook_deliveries: one row per (event, subscriber). A background job claims due PENDING rows,
+--     POSTs the signed event and backs off exponentially on failure. status: PENDING -> DELIVERED, or DEAD once
+--     attempts are exhausted; admins can reset any row back to PENDING to replay it.
+-- Idempotent: uses CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+CREATE TABLE IF NOT EXISTS entitlement_webhook_subscribers (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS entitlement_webhook_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS entitlement_webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    event_id BIGINT NOT NULL REFERENCES entitlement_webhook_events(id) ON DELETE CASCADE,
+    subscriber_id BIGINT NOT NULL REFERENCES entitlement_webhook_subscribers(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (event_id, subscriber_id)
+);
+
+CREATE INDEX IF NOT EXISTS entitlement_webhook_deliveries_due_idx
+    ON entitlement_webhook_deliveries(next_attempt_at, id)
+    WHERE status = 'PENDING';
+
+CREATE INDEX IF NOT EXISTS entitlement_webhook_deliveries_subscriber_idx
+    ON entitlement_webhook_deliveries(subscriber_id, status, id);
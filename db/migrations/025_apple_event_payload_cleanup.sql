@@ -0,0 +1,11 @@
+-- Migration: 025_apple_event_payload_cleanup
+-- Purpose: Stop keeping re-drive copies of App Store Server Notifications once they are no longer needed.
+--   * apple_events.decoded_payload is only kept while an event is PENDING_USER_BINDING and now holds the minimal
+--     fields needed to re-drive it; UpdateAppleEventProcessing clears it when the event reaches a final status.
+--   * Clears copies left on events that were re-driven before this migration.
+-- Idempotent: the UPDATE only touches rows that still have a payload, so re-running this migration is safe.
+
+UPDATE apple_events
+SET decoded_payload = NULL
+WHERE processing_status <> 'PENDING_USER_BINDING'
+  AND decoded_payload IS NOT NULL;
@@ -0,0 +1,39 @@
+-- Migration: 026_comp_grants_promo_codes
+-- Purpose: Let admins and promo codes grant complimentary entitlements alongside referral rewards.
+--   * comp_entitlements.note / revoked_at: admins grant comps directly (source ADMIN) with a free-form note and can
+--     revoke any comp. Revoked rows no longer count as an entitlement nor extend the queue new comps are appended to.
+--   * promo_codes: admin-managed codes granting comp_days of plan_id / level on redemption, with an optional
+--     redemption cap (max_redemptions, 0 = unlimited) and expiry. Codes are deactivated rather than deleted.
+--   * promo_code_redemptions: one row per (code, user), linked to the comp it granted (source PROMO_CODE).
+-- Idempotent: uses ADD COLUMN / CREATE TABLE / CREATE INDEX IF NOT EXISTS so re-running this migration is safe.
+
+ALTER TABLE comp_entitlements ADD COLUMN IF NOT EXISTS note TEXT NOT NULL DEFAULT '';
+ALTER TABLE comp_entitlements ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS promo_codes (
+    code TEXT PRIMARY KEY,
+    plan_id TEXT NOT NULL,
+    level INT NOT NULL,
+    comp_days INT NOT NULL,
+    max_redemptions INT NOT NULL DEFAULT 0,
+    redeemed_count INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (comp_days > 0),
+    CHECK (level > 0),
+    CHECK (max_redemptions >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS promo_code_redemptions (
+    id BIGSERIAL PRIMARY KEY,
+    code TEXT NOT NULL REFERENCES promo_codes(code),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    comp_entitlement_id BIGINT NOT NULL REFERENCES comp_entitlements(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT promo_code_redemptions_code_user_key UNIQUE (code, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS promo_code_redemptions_user_idx
+    ON promo_code_redemptions(user_id, created_at DESC);
@@ -0,0 +1,62 @@
+-- Admin user search. Every variant pages by ascending users.id:
+-- after_id is the last id of the previous page (0 for the first page).
+
+-- name: SearchUsersByID :many
+SELECT id, name, created_at, updated_at, status, status_reason, status_updated_at, sessions_revoked_at, status_expires_at
+FROM users
+WHERE id = sqlc.arg(user_id)
+  AND id > sqlc.arg(after_id)
+ORDER BY id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: SearchUsersByEmail :many
+SELECT u.id, u.name, u.created_at, u.updated_at, u.status, u.status_reason, u.status_updated_at, u.sessions_revoked_at, u.status_expires_at
+FROM users u
+WHERE u.id > sqlc.arg(after_id)
+  AND EXISTS (
+      SELECT 1
+      FROM auth_identities ai
+      WHERE ai.user_id = u.id
+        AND lower(ai.email) = lower(sqlc.arg(email))
+  )
+ORDER BY u.id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: SearchUsersByProviderSubject :many
+SELECT u.id, u.name, u.created_at, u.updated_at, u.status, u.status_reason, u.status_updated_at, u.sessions_revoked_at, u.status_expires_at
+FROM users u
+WHERE u.id > sqlc.arg(after_id)
+  AND EXISTS (
+      SELECT 1
+      FROM auth_identities ai
+      WHERE ai.user_id = u.id
+        AND ai.provider_subject = sqlc.arg(provider_subject)
+  )
+ORDER BY u.id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: SearchUsersByAppleAccountToken :many
+SELECT u.id, u.name, u.created_at, u.updated_at, u.status, u.status_reason, u.status_updated_at, u.sessions_revoked_at, u.status_expires_at
+FROM users u
+WHERE u.id > sqlc.arg(after_id)
+  AND EXISTS (
+      SELECT 1
+      FROM apple_account_tokens t
+      WHERE t.user_id = u.id
+        AND t.token = sqlc.arg(token)
+  )
+ORDER BY u.id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: SearchUsersByOriginalTransactionID :many
+SELECT u.id, u.name, u.created_at, u.updated_at, u.status, u.status_reason, u.status_updated_at, u.sessions_revoked_at, u.status_expires_at
+FROM users u
+WHERE u.id > sqlc.arg(after_id)
+  AND EXISTS (
+      SELECT 1
+      FROM apple_subscriptions s
+      WHERE s.user_id = u.id
+        AND s.original_transaction_id = sqlc.arg(original_transaction_id)
+  )
+ORDER BY u.id ASC
+LIMIT sqlc.arg(page_size);
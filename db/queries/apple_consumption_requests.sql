@@ -0,0 +1,73 @@
+-- Apple CONSUMPTION_REQUEST replies.
+-- ClaimDueAppleConsumptionRequests leases due requests by pushing next_attempt_at to lease_until, so concurrent
+-- instances do not answer the same request twice; a crashed sender's lease simply expires.
+
+-- name: InsertAppleConsumptionRequest :exec
+INSERT INTO apple_consumption_requests (
+    event_id,
+    user_id,
+    environment,
+    original_transaction_id,
+    transaction_id,
+    app_account_token,
+    deadline_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (event_id) DO NOTHING;
+
+-- name: ClaimDueAppleConsumptionRequests :many
+UPDATE apple_consumption_requests
+SET next_attempt_at = sqlc.arg(lease_until),
+    updated_at      = now()
+WHERE id IN (
+    SELECT id
+    FROM apple_consumption_requests
+    WHERE status = 'PENDING'
+      AND next_attempt_at <= sqlc.arg(now)
+    ORDER BY deadline_at ASC, id ASC
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkAppleConsumptionRequestSent :exec
+UPDATE apple_consumption_requests
+SET status       = 'SENT',
+    attempts     = attempts + 1,
+    last_error   = '',
+    sent_payload = sqlc.arg(sent_payload),
+    sent_at      = sqlc.arg(sent_at),
+    updated_at   = now()
+WHERE id = sqlc.arg(id)
+  AND status = 'PENDING';
+
+-- name: MarkAppleConsumptionRequestFailed :exec
+UPDATE apple_consumption_requests
+SET status          = sqlc.arg(status),
+    attempts        = attempts + 1,
+    last_error      = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    updated_at      = now()
+WHERE id = sqlc.arg(id)
+  AND status = 'PENDING';
+
+-- name: GetAppleConsumptionSnapshot :one
+SELECT u.created_at AS account_created_at,
+       u.status AS account_status,
+       COALESCE(b.amount, 0)::bigint AS credits_granted,
+       COALESCE(b.remaining, 0)::bigint AS credits_remaining,
+       (p.id IS NOT NULL OR EXISTS (
+           SELECT 1
+           FROM apple_subscriptions s
+           WHERE s.user_id = u.id
+             AND s.original_transaction_id = sqlc.arg(original_transaction_id)
+             AND s.environment = sqlc.arg(environment)
+       ))::boolean AS delivered
+FROM users u
+LEFT JOIN apple_purchases p
+       ON p.user_id = u.id
+      AND p.transaction_id = sqlc.arg(transaction_id)
+      AND p.environment = sqlc.arg(environment)
+LEFT JOIN credit_buckets b ON b.id = p.credit_bucket_id
+WHERE u.id = sqlc.arg(user_id);
@@ -1,3 +1,11 @@
+-- Apple App Store Server Notifications and the read models built on them.
+-- ListApplePurchaseTimelineByUser merges processed notifications with apple_purchases / apple_subscriptions rows
+-- that have no matching processed notification (verified before the notification arrived, or never notified), so
+-- a purchase appears once: one-time purchases are matched by transaction id against ONE_TIME_CHARGE, subscriptions
+-- by original transaction id against SUBSCRIBED. Rows are keyed by (occurred_at, source, id).
+-- ListAppleRevenueEvents keeps IGNORED_UNKNOWN_TYPE rows: notifications for products that were missing from the
+-- catalog at the time still belong to the subscription lifecycle replayed by the revenue report.
+
 -- name: InsertAppleEventIfNotExists :one
 INSERT INTO apple_events (
     notification_uuid,
@@ -13,9 +21,12 @@ INSERT INTO apple_events (
     processing_error,
     raw_jws_sha256,
     decoded_payload,
-    notification_created_at
+    notification_created_at,
+    product_id,
+    renewal_product_id,
+    offer_discount_type
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
 )
 ON CONFLICT (notification_uuid) DO NOTHING
 RETURNING id;
@@ -31,3 +42,139 @@ FROM apple_events
 WHERE processing_status = $1
 ORDER BY created_at ASC
 LIMIT $2;
+
+-- name: ListRecentAppleEventsByUser :many
+SELECT *
+FROM apple_events
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: ListRedrivableAppleEvents :many
+SELECT e.*
+FROM apple_events e
+JOIN apple_account_tokens t ON t.token = e.app_account_token
+WHERE e.processing_status = 'PENDING_USER_BINDING'
+  AND (
+      e.decoded_payload IS NOT NULL
+      OR EXISTS (
+          SELECT 1
+          FROM apple_payload_archives a
+          WHERE a.event_id = e.id
+            AND a.source = 'NOTIFICATION'
+      )
+  )
+  AND (sqlc.narg(app_account_token)::uuid IS NULL OR e.app_account_token = sqlc.narg(app_account_token)::uuid)
+ORDER BY e.notification_created_at ASC NULLS LAST, e.id ASC
+LIMIT sqlc.arg(batch_size);
+
+-- name: LockPendingAppleEvent :one
+SELECT *
+FROM apple_events
+WHERE id = $1
+  AND processing_status = 'PENDING_USER_BINDING'
+FOR UPDATE SKIP LOCKED;
+
+-- name: UpdateAppleEventProcessing :exec
+UPDATE apple_events
+SET processing_status = $2,
+    processing_error  = $3,
+    user_id           = $4,
+    decoded_payload   = NULL
+WHERE id = $1;
+
+-- name: ListApplePurchaseTimelineByUser :many
+SELECT t.source,
+       t.id,
+       t.notification_type,
+       t.subtype,
+       t.environment,
+       t.occurred_at,
+       t.product_id,
+       t.renewal_product_id
+FROM (
+    SELECT 'EVENT'::text AS source,
+           e.id,
+           e.notification_type,
+           e.subtype,
+           e.environment,
+           COALESCE(e.notification_created_at, e.created_at)::timestamptz AS occurred_at,
+           COALESCE(NULLIF(e.product_id, ''), p.provider_product_id, s.provider_product_id, '')::text AS product_id,
+           e.renewal_product_id
+    FROM apple_events e
+    LEFT JOIN apple_purchases p
+           ON p.transaction_id = e.transaction_id
+          AND p.environment = e.environment
+          AND p.user_id = e.user_id
+    LEFT JOIN apple_subscriptions s
+           ON s.original_transaction_id = e.original_transaction_id
+          AND s.environment = e.environment
+          AND s.user_id = e.user_id
+    WHERE e.user_id = sqlc.arg(user_id)::bigint
+      AND e.processing_status = 'PROCESSED'
+    UNION ALL
+    SELECT 'PURCHASE'::text,
+           p.id,
+           'ONE_TIME_CHARGE'::text,
+           ''::text,
+           p.environment,
+           p.purchased_at,
+           p.provider_product_id,
+           ''::text
+    FROM apple_purchases p
+    WHERE p.user_id = sqlc.arg(user_id)::bigint
+      AND NOT EXISTS (
+          SELECT 1
+          FROM apple_events e
+          WHERE e.user_id = p.user_id
+            AND e.environment = p.environment
+            AND e.transaction_id = p.transaction_id
+            AND e.processing_status = 'PROCESSED'
+            AND e.notification_type = 'ONE_TIME_CHARGE'
+      )
+    UNION ALL
+    SELECT 'SUBSCRIPTION'::text,
+           s.id,
+           'SUBSCRIBED'::text,
+           ''::text,
+           s.environment,
+           s.created_at,
+           s.provider_product_id,
+           ''::text
+    FROM apple_subscriptions s
+    WHERE s.user_id = sqlc.arg(user_id)::bigint
+      AND NOT EXISTS (
+          SELECT 1
+          FROM apple_events e
+          WHERE e.user_id = s.user_id
+            AND e.environment = s.environment
+            AND e.original_transaction_id = s.original_transaction_id
+            AND e.processing_status = 'PROCESSED'
+            AND e.notification_type = 'SUBSCRIBED'
+      )
+) t
+WHERE t.environment = ANY(sqlc.arg(environments)::text[])
+  AND t.notification_type = ANY(sqlc.arg(notification_types)::text[])
+  AND (t.occurred_at, t.source, t.id) < (sqlc.arg(before_at)::timestamptz, sqlc.arg(before_source)::text, sqlc.arg(before_id)::bigint)
+ORDER BY t.occurred_at DESC, t.source DESC, t.id DESC
+LIMIT sqlc.arg(batch_size);
+
+-- name: ListAppleRevenueEvents :many
+SELECT e.id,
+       e.notification_type,
+       e.subtype,
+       e.environment,
+       e.original_transaction_id,
+       COALESCE(e.notification_created_at, e.created_at)::timestamptz AS occurred_at,
+       COALESCE(NULLIF(e.product_id, ''), s.provider_product_id, '')::text AS product_id,
+       e.offer_discount_type
+FROM apple_events e
+LEFT JOIN apple_subscriptions s
+       ON s.original_transaction_id = e.original_transaction_id
+      AND s.environment = e.environment
+WHERE e.original_transaction_id <> ''
+  AND e.processing_status <> 'IGNORED_DUPLICATE'
+  AND COALESCE(e.notification_created_at, e.created_at) < sqlc.arg(until)::timestamptz
+  AND (COALESCE(e.notification_created_at, e.created_at), e.id) > (sqlc.arg(after_at)::timestamptz, sqlc.arg(after_id)::bigint)
+ORDER BY COALESCE(e.notification_created_at, e.created_at), e.id
+LIMIT sqlc.arg(batch_size);
@@ -0,0 +1,41 @@
+-- name: LockAppleFamilyShare :one
+SELECT *
+FROM apple_family_shares
+WHERE original_transaction_id = $1
+  AND environment = $2
+FOR UPDATE;
+
+-- name: UpsertAppleFamilyShare :one
+INSERT INTO apple_family_shares (
+    user_id,
+    environment,
+    original_transaction_id,
+    last_transaction_id,
+    plan_id,
+    provider_product_id,
+    level,
+    status,
+    current_period_end,
+    revoked_at,
+    last_event_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+ON CONFLICT (original_transaction_id, environment) DO UPDATE SET
+    last_transaction_id = EXCLUDED.last_transaction_id,
+    plan_id             = EXCLUDED.plan_id,
+    provider_product_id = EXCLUDED.provider_product_id,
+    level               = EXCLUDED.level,
+    status              = EXCLUDED.status,
+    current_period_end  = EXCLUDED.current_period_end,
+    revoked_at          = EXCLUDED.revoked_at,
+    last_event_at       = EXCLUDED.last_event_at,
+    updated_at          = now()
+RETURNING *;
+
+-- name: ListAppleFamilySharesForUserEntitlement :many
+SELECT *
+FROM apple_family_shares
+WHERE user_id = $1
+  AND environment = ANY($2::text[])
+ORDER BY level DESC, current_period_end DESC;
@@ -0,0 +1,22 @@
+-- name: InsertAppleOfferSignature :one
+INSERT INTO apple_offer_signatures (
+    user_id,
+    offer_id,
+    offer_type,
+    product_id,
+    app_account_token,
+    nonce,
+    signed_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: CountAppleOfferSignatures :one
+SELECT count(*)
+FROM apple_offer_signatures
+WHERE user_id = $1
+  AND offer_id = $2;
+
+-- name: LockAppleOfferSignatureQuota :exec
+SELECT pg_advisory_xact_lock(hashtextextended(sqlc.arg(offer_id)::text, sqlc.arg(user_id)::bigint));
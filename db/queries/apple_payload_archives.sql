@@ -0,0 +1,54 @@
+-- name: InsertApplePayloadArchive :exec
+INSERT INTO apple_payload_archives (
+    source,
+    event_id,
+    user_id,
+    environment,
+    original_transaction_id,
+    transaction_id,
+    key_id,
+    wrapped_key,
+    nonce,
+    ciphertext,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetApplePayloadArchiveByEvent :one
+SELECT *
+FROM apple_payload_archives
+WHERE event_id = sqlc.arg(event_id)
+  AND source = 'NOTIFICATION';
+
+-- name: ListApplePayloadArchivesByOriginalTx :many
+SELECT *
+FROM apple_payload_archives
+WHERE original_transaction_id = sqlc.arg(original_transaction_id)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(batch_size);
+
+-- name: DeleteExpiredApplePayloadArchives :execrows
+DELETE FROM apple_payload_archives
+WHERE id IN (
+    SELECT id
+    FROM apple_payload_archives
+    WHERE expires_at <= sqlc.arg(expires_before)
+    ORDER BY expires_at ASC
+    LIMIT sqlc.arg(batch_size)
+);
+
+-- name: ListApplePayloadArchivesNotUsingKey :many
+SELECT *
+FROM apple_payload_archives
+WHERE key_id <> sqlc.arg(key_id)
+ORDER BY id ASC
+LIMIT sqlc.arg(batch_size);
+
+-- name: UpdateApplePayloadArchiveKey :exec
+UPDATE apple_payload_archives
+SET key_id = sqlc.arg(key_id),
+    wrapped_key = sqlc.arg(wrapped_key)
+WHERE id = sqlc.arg(id)
+  AND key_id = sqlc.arg(previous_key_id);
@@ -0,0 +1,84 @@
+-- name: ListAppleProducts :many
+SELECT *
+FROM apple_products
+ORDER BY sort_order, id;
+
+-- name: ListActiveAppleProducts :many
+SELECT *
+FROM apple_products
+WHERE active
+ORDER BY sort_order, id;
+
+-- name: CountAppleProducts :one
+SELECT count(*)
+FROM apple_products;
+
+-- name: InsertAppleProduct :one
+INSERT INTO apple_products (
+    plan_id,
+    product_id,
+    environment,
+    type,
+    level,
+    credits,
+    subscription_group_id,
+    display_name,
+    description,
+    sort_order,
+    active,
+    localizations,
+    recommended,
+    price_milliunits,
+    currency,
+    billing_period
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
+)
+RETURNING *;
+
+-- name: InsertAppleProductIfNotExists :execrows
+INSERT INTO apple_products (
+    plan_id,
+    product_id,
+    environment,
+    type,
+    level,
+    credits,
+    subscription_group_id,
+    display_name,
+    description,
+    sort_order,
+    active,
+    localizations,
+    recommended,
+    price_milliunits,
+    currency,
+    billing_period
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
+)
+ON CONFLICT (product_id, environment) DO NOTHING;
+
+-- name: GetAppleProduct :one
+SELECT *
+FROM apple_products
+WHERE id = $1;
+
+-- name: UpdateAppleProduct :one
+UPDATE apple_products
+SET plan_id               = $2,
+    level                 = $3,
+    credits               = $4,
+    subscription_group_id = $5,
+    display_name          = $6,
+    description           = $7,
+    sort_order            = $8,
+    active                = $9,
+    localizations         = $10,
+    recommended           = $11,
+    price_milliunits      = $12,
+    currency              = $13,
+    billing_period        = $14,
+    updated_at            = now()
+WHERE id = $1
+RETURNING *;
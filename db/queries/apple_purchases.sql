@@ -0,0 +1,49 @@
+-- name: InsertApplePurchaseIfNotExists :one
+INSERT INTO apple_purchases (
+    user_id,
+    environment,
+    transaction_id,
+    original_transaction_id,
+    plan_id,
+    provider_product_id,
+    product_type,
+    quantity,
+    level,
+    credits,
+    purchased_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+ON CONFLICT (transaction_id, environment) DO NOTHING
+RETURNING *;
+
+-- name: LockApplePurchaseByTransaction :one
+SELECT *
+FROM apple_purchases
+WHERE transaction_id = $1
+  AND environment = $2
+FOR UPDATE;
+
+-- name: SetApplePurchaseCreditBucket :exec
+UPDATE apple_purchases
+SET credit_bucket_id = $2,
+    updated_at = now()
+WHERE id = $1;
+
+-- name: MarkApplePurchaseRefunded :one
+UPDATE apple_purchases
+SET status = 'REFUNDED',
+    revoked_at = $2,
+    updated_at = now()
+WHERE id = $1
+  AND status <> 'REFUNDED'
+RETURNING *;
+
+-- name: ListActiveLifetimePurchasesByUser :many
+SELECT *
+FROM apple_purchases
+WHERE user_id = $1
+  AND environment = ANY($2::text[])
+  AND product_type = 'non_consumable'
+  AND status = 'ACTIVE'
+ORDER BY level DESC, purchased_at ASC;
@@ -0,0 +1,25 @@
+-- name: ListAppleSubscriptionsDueForStatusSync :many
+SELECT s.*
+FROM apple_subscriptions s
+LEFT JOIN apple_subscription_status_syncs y ON y.subscription_id = s.id
+WHERE s.status IN ('ACTIVE', 'CANCELED')
+  AND s.environment = ANY(sqlc.arg(environments)::text[])
+  AND (s.current_period_end < sqlc.arg(period_end_before) OR s.last_event_at < sqlc.arg(last_event_before))
+  AND (y.synced_at IS NULL OR y.synced_at < sqlc.arg(synced_before))
+ORDER BY y.synced_at ASC NULLS FIRST, s.current_period_end ASC, s.id ASC
+LIMIT sqlc.arg(batch_size);
+
+-- name: UpsertAppleSubscriptionStatusSync :exec
+INSERT INTO apple_subscription_status_syncs (
+    subscription_id,
+    synced_at,
+    apple_status,
+    last_error
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (subscription_id) DO UPDATE SET
+    synced_at    = EXCLUDED.synced_at,
+    apple_status = EXCLUDED.apple_status,
+    last_error   = EXCLUDED.last_error,
+    updated_at   = now();
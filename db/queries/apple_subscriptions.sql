@@ -4,6 +4,16 @@ FROM apple_subscriptions
 WHERE original_transaction_id = $1
   AND environment = $2;
 
+-- name: GetSubscriptionByOriginalTxForUpdate :one
+SELECT *
+FROM apple_subscriptions
+WHERE original_transaction_id = $1
+  AND environment = $2
+FOR UPDATE;
+
+-- name: LockSubscriptionOriginalTx :exec
+SELECT pg_advisory_xact_lock(hashtextextended('apple_subscriptions:' || sqlc.arg(environment)::text, hashtext(sqlc.arg(original_transaction_id)::text)));
+
 -- name: ListSubscriptionsForUserEntitlement :many
 SELECT *
 FROM apple_subscriptions
@@ -37,10 +47,16 @@ INSERT INTO apple_subscriptions (
     last_event_at,
     last_notification_created_at,
     last_payload_hash,
-    last_transaction_snapshot
+    last_transaction_snapshot,
+    pending_product_id,
+    pending_plan_id,
+    pending_level,
+    billing_retry,
+    expiration_intent
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
-    $11, $12, $13, $14, $15, $16, $17, $18, $19
+    $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+    $21, $22, $23, $24
 )
 ON CONFLICT (original_transaction_id, environment) DO UPDATE SET
     last_transaction_id          = EXCLUDED.last_transaction_id,
@@ -58,5 +74,30 @@ ON CONFLICT (original_transaction_id, environment) DO UPDATE SET
     last_notification_created_at = EXCLUDED.last_notification_created_at,
     last_payload_hash            = EXCLUDED.last_payload_hash,
     last_transaction_snapshot    = EXCLUDED.last_transaction_snapshot,
+    pending_product_id           = EXCLUDED.pending_product_id,
+    pending_plan_id              = EXCLUDED.pending_plan_id,
+    pending_level                = EXCLUDED.pending_level,
+    billing_retry                = EXCLUDED.billing_retry,
+    expiration_intent            = EXCLUDED.expiration_intent,
     updated_at                   = now()
 RETURNING *;
+
+-- name: ListSubscriptionsByUser :many
+SELECT *
+FROM apple_subscriptions
+WHERE user_id = $1
+ORDER BY last_event_at DESC, id DESC;
+
+-- name: ReassignSubscriptionOwner :one
+UPDATE apple_subscriptions
+SET user_id           = sqlc.arg(user_id),
+    app_account_token = sqlc.arg(app_account_token),
+    updated_at        = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: LockSubscriptionByID :one
+SELECT *
+FROM apple_subscriptions
+WHERE id = $1
+FOR UPDATE;
@@ -0,0 +1,45 @@
+-- name: InsertCompEntitlement :one
+INSERT INTO comp_entitlements (
+    user_id,
+    plan_id,
+    level,
+    source,
+    reference,
+    note,
+    starts_at,
+    ends_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: LockCompEntitlementQueue :exec
+SELECT pg_advisory_xact_lock(hashtextextended('comp_entitlements', sqlc.arg(user_id)::bigint));
+
+-- name: GetLatestCompEntitlementEnd :one
+SELECT max(ends_at)::timestamptz AS ends_at
+FROM comp_entitlements
+WHERE user_id = $1
+  AND revoked_at IS NULL;
+
+-- name: ListUnexpiredCompEntitlements :many
+SELECT *
+FROM comp_entitlements
+WHERE user_id = sqlc.arg(user_id)
+  AND ends_at > sqlc.arg(now)
+  AND revoked_at IS NULL
+ORDER BY starts_at ASC, id ASC;
+
+-- name: ListCompEntitlementsByUser :many
+SELECT *
+FROM comp_entitlements
+WHERE user_id = $1
+ORDER BY starts_at DESC, id DESC;
+
+-- name: RevokeCompEntitlement :one
+UPDATE comp_entitlements
+SET revoked_at = sqlc.arg(now)
+WHERE id = sqlc.arg(id)
+  AND user_id = sqlc.arg(user_id)
+  AND revoked_at IS NULL
+RETURNING *;
@@ -0,0 +1,63 @@
+-- name: InsertCreditBucket :one
+INSERT INTO credit_buckets (
+    user_id,
+    source,
+    amount,
+    remaining,
+    expires_at,
+    reference
+) VALUES (
+    $1, $2, $3, $3, $4, $5
+)
+RETURNING *;
+
+-- name: ListActiveCreditBuckets :many
+SELECT *
+FROM credit_buckets
+WHERE user_id = $1
+  AND remaining > 0
+  AND (expires_at IS NULL OR expires_at > $2)
+ORDER BY expires_at ASC NULLS LAST, id ASC;
+
+-- name: LockSpendableCreditBuckets :many
+SELECT *
+FROM credit_buckets
+WHERE user_id = $1
+  AND remaining > 0
+  AND (expires_at IS NULL OR expires_at > $2)
+ORDER BY expires_at ASC NULLS LAST, id ASC
+FOR UPDATE;
+
+-- name: LockExpiredCreditBuckets :many
+SELECT *
+FROM credit_buckets
+WHERE remaining > 0
+  AND expires_at IS NOT NULL
+  AND expires_at <= $1
+ORDER BY expires_at ASC, id ASC
+LIMIT $2
+FOR UPDATE SKIP LOCKED;
+
+-- name: SetCreditBucketRemaining :exec
+UPDATE credit_buckets
+SET remaining = $2,
+    updated_at = now()
+WHERE id = $1;
+
+-- name: InsertCreditLedgerEntry :one
+INSERT INTO credit_ledger (
+    user_id,
+    bucket_id,
+    entry_type,
+    delta,
+    reference
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+RETURNING id;
+
+-- name: LockCreditBucket :one
+SELECT *
+FROM credit_buckets
+WHERE id = $1
+FOR UPDATE;
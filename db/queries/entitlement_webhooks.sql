@@ -0,0 +1,131 @@
+-- Outbound entitlement webhooks.
+-- UpdateEntitlementWebhookSubscriber keeps the current secret when secret is empty.
+-- InsertEntitlementWebhookEvent records a change event and fans it out to every active subscriber of its type in one
+-- statement, so it commits or rolls back with the subscription write; nothing is written when no subscriber wants it.
+-- ClaimDueEntitlementWebhookDeliveries leases due deliveries of active subscribers by pushing next_attempt_at to
+-- lease_until, so concurrent instances do not send the same delivery twice; a crashed sender's lease simply expires.
+-- ReplayEntitlementWebhookDelivery resets a delivery of any status with a fresh attempt budget.
+
+-- name: ListEntitlementWebhookSubscribers :many
+SELECT *
+FROM entitlement_webhook_subscribers
+ORDER BY id;
+
+-- name: InsertEntitlementWebhookSubscriber :one
+INSERT INTO entitlement_webhook_subscribers (
+    name,
+    url,
+    secret,
+    event_types,
+    active
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: UpdateEntitlementWebhookSubscriber :one
+UPDATE entitlement_webhook_subscribers
+SET name        = sqlc.arg(name),
+    url         = sqlc.arg(url),
+    secret      = COALESCE(NULLIF(sqlc.arg(secret)::text, ''), secret),
+    event_types = sqlc.arg(event_types),
+    active      = sqlc.arg(active),
+    updated_at  = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: InsertEntitlementWebhookEvent :one
+WITH event AS (
+    INSERT INTO entitlement_webhook_events (event_type, user_id, payload)
+    SELECT sqlc.arg(event_type)::text, sqlc.arg(user_id)::bigint, sqlc.arg(payload)::jsonb
+    WHERE EXISTS (
+        SELECT 1
+        FROM entitlement_webhook_subscribers
+        WHERE active
+          AND sqlc.arg(event_type)::text = ANY (event_types)
+    )
+    RETURNING id, event_type
+), deliveries AS (
+    INSERT INTO entitlement_webhook_deliveries (event_id, subscriber_id)
+    SELECT event.id, s.id
+    FROM event
+    JOIN entitlement_webhook_subscribers s
+      ON s.active
+     AND event.event_type = ANY (s.event_types)
+    RETURNING id
+)
+SELECT count(*)
+FROM deliveries;
+
+-- name: ClaimDueEntitlementWebhookDeliveries :many
+UPDATE entitlement_webhook_deliveries d
+SET next_attempt_at = sqlc.arg(lease_until),
+    updated_at      = now()
+FROM entitlement_webhook_events e, entitlement_webhook_subscribers s
+WHERE d.id IN (
+    SELECT due.id
+    FROM entitlement_webhook_deliveries due
+    JOIN entitlement_webhook_subscribers sub ON sub.id = due.subscriber_id AND sub.active
+    WHERE due.status = 'PENDING'
+      AND due.next_attempt_at <= sqlc.arg(now)
+    ORDER BY due.next_attempt_at, due.id
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE OF due SKIP LOCKED
+)
+  AND e.id = d.event_id
+  AND s.id = d.subscriber_id
+RETURNING d.id, d.event_id, d.subscriber_id, d.attempts, e.event_type, e.user_id, e.payload, e.created_at, s.url, s.secret;
+
+-- name: MarkEntitlementWebhookDeliveryDelivered :exec
+UPDATE entitlement_webhook_deliveries
+SET status           = 'DELIVERED',
+    attempts         = attempts + 1,
+    last_status_code = sqlc.arg(last_status_code),
+    last_error       = '',
+    delivered_at     = sqlc.arg(delivered_at),
+    updated_at       = now()
+WHERE id = sqlc.arg(id)
+  AND status = 'PENDING';
+
+-- name: MarkEntitlementWebhookDeliveryFailed :exec
+UPDATE entitlement_webhook_deliveries
+SET status           = sqlc.arg(status),
+    attempts         = attempts + 1,
+    last_status_code = sqlc.arg(last_status_code),
+    last_error       = sqlc.arg(last_error),
+    next_attempt_at  = sqlc.arg(next_attempt_at),
+    updated_at       = now()
+WHERE id = sqlc.arg(id)
+  AND status = 'PENDING';
+
+-- name: ListEntitlementWebhookDeliveries :many
+SELECT d.id, d.event_id, d.subscriber_id, e.event_type, e.user_id, d.status, d.attempts, d.next_attempt_at,
+       d.last_status_code, d.last_error, d.delivered_at, d.created_at, d.updated_at
+FROM entitlement_webhook_deliveries d
+JOIN entitlement_webhook_events e ON e.id = d.event_id
+WHERE (sqlc.arg(subscriber_id)::bigint = 0 OR d.subscriber_id = sqlc.arg(subscriber_id))
+  AND (sqlc.arg(status)::text = '' OR d.status = sqlc.arg(status))
+  AND (sqlc.arg(before_id)::bigint = 0 OR d.id < sqlc.arg(before_id))
+ORDER BY d.id DESC
+LIMIT sqlc.arg(batch_size);
+
+-- name: ReplayEntitlementWebhookDelivery :one
+UPDATE entitlement_webhook_deliveries
+SET status          = 'PENDING',
+    attempts        = 0,
+    next_attempt_at = sqlc.arg(now),
+    last_error      = '',
+    delivered_at    = NULL,
+    updated_at      = now()
+WHERE id = sqlc.arg(id)
+RETURNING id;
+
+-- name: ReplayDeadEntitlementWebhookDeliveries :execrows
+UPDATE entitlement_webhook_deliveries
+SET status          = 'PENDING',
+    attempts        = 0,
+    next_attempt_at = sqlc.arg(now),
+    last_error      = '',
+    updated_at      = now()
+WHERE subscriber_id = sqlc.arg(subscriber_id)
+  AND status = 'DEAD';
@@ -0,0 +1,125 @@
+-- name: InsertGooglePlayEventIfNotExists :one
+INSERT INTO google_play_events (
+    message_id,
+    notification_kind,
+    notification_type,
+    package_name,
+    purchase_token,
+    product_id,
+    user_id,
+    processing_status,
+    processing_error,
+    event_time
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT (message_id) DO NOTHING
+RETURNING id;
+
+-- name: LockGooglePlaySubscriptionByToken :one
+SELECT *
+FROM google_play_subscriptions
+WHERE purchase_token = $1
+FOR UPDATE;
+
+-- name: UpsertGooglePlaySubscription :one
+INSERT INTO google_play_subscriptions (
+    user_id,
+    package_name,
+    purchase_token,
+    linked_purchase_token,
+    latest_order_id,
+    plan_id,
+    provider_product_id,
+    level,
+    status,
+    auto_renew_status,
+    test_purchase,
+    current_period_start,
+    current_period_end,
+    last_event_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+ON CONFLICT (purchase_token) DO UPDATE SET
+    linked_purchase_token = EXCLUDED.linked_purchase_token,
+    latest_order_id       = EXCLUDED.latest_order_id,
+    plan_id               = EXCLUDED.plan_id,
+    provider_product_id   = EXCLUDED.provider_product_id,
+    level                 = EXCLUDED.level,
+    status                = EXCLUDED.status,
+    auto_renew_status     = EXCLUDED.auto_renew_status,
+    test_purchase         = EXCLUDED.test_purchase,
+    current_period_start  = EXCLUDED.current_period_start,
+    current_period_end    = EXCLUDED.current_period_end,
+    last_event_at         = EXCLUDED.last_event_at,
+    updated_at            = now()
+RETURNING *;
+
+-- name: RevokeGooglePlaySubscription :one
+UPDATE google_play_subscriptions
+SET status = 'REVOKED',
+    last_event_at = $2,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListGooglePlaySubscriptionsForUserEntitlement :many
+SELECT *
+FROM google_play_subscriptions
+WHERE user_id = $1
+ORDER BY
+    CASE WHEN status IN ('ACTIVE', 'CANCELED') AND current_period_end > now()
+         THEN 0 ELSE 1 END,
+    level DESC,
+    current_period_end DESC,
+    last_event_at DESC;
+
+-- name: InsertGooglePlayPurchaseIfNotExists :one
+INSERT INTO google_play_purchases (
+    user_id,
+    package_name,
+    purchase_token,
+    order_id,
+    plan_id,
+    provider_product_id,
+    product_type,
+    quantity,
+    level,
+    credits,
+    test_purchase,
+    purchased_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+ON CONFLICT (purchase_token) DO NOTHING
+RETURNING *;
+
+-- name: LockGooglePlayPurchaseByToken :one
+SELECT *
+FROM google_play_purchases
+WHERE purchase_token = $1
+FOR UPDATE;
+
+-- name: SetGooglePlayPurchaseCreditBucket :exec
+UPDATE google_play_purchases
+SET credit_bucket_id = $2,
+    updated_at = now()
+WHERE id = $1;
+
+-- name: MarkGooglePlayPurchaseRefunded :one
+UPDATE google_play_purchases
+SET status = 'REFUNDED',
+    revoked_at = $2,
+    updated_at = now()
+WHERE id = $1
+  AND status <> 'REFUNDED'
+RETURNING *;
+
+-- name: ListActiveGooglePlayLifetimePurchasesByUser :many
+SELECT *
+FROM google_play_purchases
+WHERE user_id = $1
+  AND product_type = 'non_consumable'
+  AND status = 'ACTIVE'
+ORDER BY level DESC, purchased_at ASC;
@@ -0,0 +1,34 @@
+-- Paywall experiment exposures. The upsert keeps the variant of the first exposure (sticky assignment);
+-- a user converts when an apple_subscriptions row in an entitlement environment is created at or after
+-- their first exposure.
+
+-- name: UpsertPaywallExposure :one
+INSERT INTO paywall_exposures (
+    experiment_id,
+    variant_id,
+    user_id,
+    platform,
+    first_exposed_at,
+    last_exposed_at
+) VALUES (
+    $1, $2, $3, $4, $5, $5
+)
+ON CONFLICT (experiment_id, user_id) DO UPDATE
+SET last_exposed_at = GREATEST(paywall_exposures.last_exposed_at, EXCLUDED.last_exposed_at)
+RETURNING *;
+
+-- name: ListPaywallExperimentResults :many
+SELECT
+    e.variant_id,
+    count(*)::bigint AS exposures,
+    (count(*) FILTER (WHERE EXISTS (
+        SELECT 1
+        FROM apple_subscriptions s
+        WHERE s.user_id = e.user_id
+          AND s.environment = ANY(sqlc.arg(environments)::text[])
+          AND s.created_at >= e.first_exposed_at
+    )))::bigint AS conversions
+FROM paywall_exposures e
+WHERE e.experiment_id = sqlc.arg(experiment_id)
+GROUP BY e.variant_id
+ORDER BY e.variant_id;
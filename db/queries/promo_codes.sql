@@ -0,0 +1,46 @@
+-- name: InsertPromoCode :one
+INSERT INTO promo_codes (
+    code,
+    plan_id,
+    level,
+    comp_days,
+    max_redemptions,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: ListPromoCodes :many
+SELECT *
+FROM promo_codes
+ORDER BY created_at DESC, code ASC;
+
+-- name: DeactivatePromoCode :one
+UPDATE promo_codes
+SET active     = FALSE,
+    updated_at = now()
+WHERE code = $1
+RETURNING *;
+
+-- name: LockPromoCode :one
+SELECT *
+FROM promo_codes
+WHERE code = $1
+FOR UPDATE;
+
+-- name: IncrementPromoCodeRedemptions :exec
+UPDATE promo_codes
+SET redeemed_count = redeemed_count + 1,
+    updated_at     = now()
+WHERE code = $1;
+
+-- name: InsertPromoCodeRedemption :one
+INSERT INTO promo_code_redemptions (
+    code,
+    user_id,
+    comp_entitlement_id
+) VALUES (
+    $1, $2, $3
+)
+RETURNING *;
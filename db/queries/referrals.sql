@@ -0,0 +1,49 @@
+-- name: GetReferralCodeByUser :one
+SELECT *
+FROM referral_codes
+WHERE user_id = $1;
+
+-- name: InsertReferralCode :one
+INSERT INTO referral_codes (user_id, code)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO NOTHING
+RETURNING *;
+
+-- name: LockReferralCode :one
+SELECT *
+FROM referral_codes
+WHERE code = $1
+FOR UPDATE;
+
+-- name: GetReferralRedemptionByInvitee :one
+SELECT *
+FROM referral_redemptions
+WHERE invitee_user_id = $1;
+
+-- name: CountReferralRedemptionsByInviter :one
+SELECT count(*)
+FROM referral_redemptions
+WHERE inviter_user_id = $1;
+
+-- name: InsertReferralRedemption :one
+INSERT INTO referral_redemptions (
+    inviter_user_id,
+    invitee_user_id,
+    code,
+    device_key,
+    inviter_reward_credits,
+    inviter_reward_comp_days,
+    invitee_reward_credits,
+    invitee_reward_comp_days
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: GetReferralStatsByInviter :one
+SELECT
+    count(*) AS invited_count,
+    COALESCE(sum(inviter_reward_credits), 0)::bigint AS earned_credits,
+    COALESCE(sum(inviter_reward_comp_days), 0)::bigint AS earned_comp_days
+FROM referral_redemptions
+WHERE inviter_user_id = $1;
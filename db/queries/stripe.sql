@@ -0,0 +1,83 @@
+-- name: GetStripeCustomerByUser :one
+SELECT *
+FROM stripe_customers
+WHERE user_id = $1;
+
+-- name: GetStripeCustomerByCustomerID :one
+SELECT *
+FROM stripe_customers
+WHERE customer_id = $1;
+
+-- name: InsertStripeCustomerIfNotExists :one
+INSERT INTO stripe_customers (
+    user_id,
+    customer_id
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (user_id) DO NOTHING
+RETURNING *;
+
+-- name: InsertStripeEventIfNotExists :one
+INSERT INTO stripe_events (
+    event_id,
+    event_type,
+    customer_id,
+    subscription_id,
+    user_id,
+    processing_status,
+    processing_error,
+    event_created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+ON CONFLICT (event_id) DO NOTHING
+RETURNING id;
+
+-- name: LockStripeSubscriptionBySubscriptionID :one
+SELECT *
+FROM stripe_subscriptions
+WHERE subscription_id = $1
+FOR UPDATE;
+
+-- name: UpsertStripeSubscription :one
+INSERT INTO stripe_subscriptions (
+    user_id,
+    customer_id,
+    subscription_id,
+    price_id,
+    plan_id,
+    level,
+    status,
+    stripe_status,
+    auto_renew_status,
+    current_period_start,
+    current_period_end,
+    last_event_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+ON CONFLICT (subscription_id) DO UPDATE SET
+    price_id             = EXCLUDED.price_id,
+    plan_id              = EXCLUDED.plan_id,
+    level                = EXCLUDED.level,
+    status               = EXCLUDED.status,
+    stripe_status        = EXCLUDED.stripe_status,
+    auto_renew_status    = EXCLUDED.auto_renew_status,
+    current_period_start = EXCLUDED.current_period_start,
+    current_period_end   = EXCLUDED.current_period_end,
+    last_event_at        = EXCLUDED.last_event_at,
+    updated_at           = now()
+WHERE stripe_subscriptions.last_event_at <= EXCLUDED.last_event_at
+RETURNING *;
+
+-- name: ListStripeSubscriptionsForUserEntitlement :many
+SELECT *
+FROM stripe_subscriptions
+WHERE user_id = $1
+ORDER BY
+    CASE WHEN status IN ('ACTIVE', 'CANCELED') AND current_period_end > now()
+         THEN 0 ELSE 1 END,
+    level DESC,
+    current_period_end DESC,
+    last_event_at DESC;
@@ -0,0 +1,22 @@
+-- name: ListUserSettings :many
+SELECT user_id, key, value, created_at, updated_at
+FROM user_settings
+WHERE user_id = $1
+ORDER BY key ASC;
+
+-- name: UpsertUserSetting :exec
+INSERT INTO user_settings (
+    user_id,
+    key,
+    value
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (user_id, key) DO UPDATE
+SET value = EXCLUDED.value,
+    updated_at = now();
+
+-- name: DeleteUserSetting :exec
+DELETE FROM user_settings
+WHERE user_id = $1
+  AND key = $2;
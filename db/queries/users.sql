@@ -13,7 +13,10 @@ SELECT
     u.id,
     ai.email,
     ai.provider,
-    ai.provider_subject
+    ai.provider_subject,
+    u.status,
+    u.status_reason,
+    u.status_expires_at
 FROM auth_identities ai
 JOIN users u ON u.id = ai.user_id
 WHERE ai.provider = $1
@@ -32,3 +35,37 @@ INSERT INTO auth_identities (
     $4
 )
 RETURNING provider, provider_subject, user_id, email;
+
+-- name: GetUserWithAccountState :one
+SELECT id, name, created_at, updated_at, status, status_reason, status_updated_at, sessions_revoked_at, status_expires_at
+FROM users
+WHERE id = $1;
+
+-- name: GetUserAccountState :one
+SELECT id, status, status_reason, status_updated_at, sessions_revoked_at, status_expires_at
+FROM users
+WHERE id = $1;
+
+-- name: SetUserStatus :one
+UPDATE users
+SET status = sqlc.arg(status),
+    status_reason = sqlc.arg(status_reason),
+    status_expires_at = sqlc.narg(status_expires_at),
+    status_updated_at = now(),
+    sessions_revoked_at = CASE WHEN sqlc.arg(revoke_sessions)::boolean THEN now() ELSE sessions_revoked_at END,
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING id, status, status_reason, status_updated_at, sessions_revoked_at, status_expires_at;
+
+-- name: RevokeUserSessions :one
+UPDATE users
+SET sessions_revoked_at = now(),
+    updated_at = now()
+WHERE id = $1
+RETURNING id, status, status_reason, status_updated_at, sessions_revoked_at, status_expires_at;
+
+-- name: ListAuthIdentitiesByUser :many
+SELECT provider, provider_subject, user_id, email, created_at, updated_at
+FROM auth_identities
+WHERE user_id = $1
+ORDER BY created_at ASC;
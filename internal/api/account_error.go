@@ -0,0 +1,62 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/auth"
+)
+
+// accountError 是账号被拒绝访问时的错误体：在 huma 默认的 problem+json 字段之外增加 code，
+// 客户端据此区分暂停 / 封禁 / 注销 / 强制下线并展示对应文案，而不是笼统的 401。
+type accountError struct {
+	*huma.ErrorModel
+	Code      string `json:"code" doc:"账号错误码" enum:"ACCOUNT_SUSPENDED,ACCOUNT_BANNED,ACCOUNT_DELETED,SESSION_REVOKED"`
+	Reason    string `json:"reason,omitempty" doc:"管理员填写的原因"`
+	ExpiresAt string `json:"expires_at,omitempty" doc:"暂停 / 封禁的到期时间（RFC3339），为空表示不过期" format:"date-time"`
+}
+
+// accountAccessError 把认证链路上的账号状态错误映射为带 code 的响应；其它错误返回 nil，由调用方按原逻辑处理。
+//
+//   - 账号 SUSPENDED / BANNED / DELETED：403 + ACCOUNT_*
+//   - token 签发于强制下线之前：401 + SESSION_REVOKED
+func accountAccessError(err error) error {
+	var blocked *model.AccountBlockedError
+	if errors.As(err, &blocked) {
+		return &accountError{
+			ErrorModel: &huma.ErrorModel{
+				Status: http.StatusForbidden,
+				Title:  http.StatusText(http.StatusForbidden),
+				Detail: accountBlockedMessage(blocked.Status),
+			},
+			Code:      blocked.Code(),
+			Reason:    blocked.Reason,
+			ExpiresAt: formatOptionalTime(blocked.ExpiresAt),
+		}
+	}
+	if errors.Is(err, auth.ErrSessionRevoked) {
+		return &accountError{
+			ErrorModel: &huma.ErrorModel{
+				Status: http.StatusUnauthorized,
+				Title:  http.StatusText(http.StatusUnauthorized),
+				Detail: "登录状态已失效，请重新登录",
+			},
+			Code: model.AccountErrorSessionRevoked,
+		}
+	}
+	return nil
+}
+
+func accountBlockedMessage(status string) string {
+	switch status {
+	case model.UserStatusBanned:
+		return "账号已被封禁"
+	case model.UserStatusDeleted:
+		return "账号已注销"
+	default:
+		return "账号已被暂停使用"
+	}
+}
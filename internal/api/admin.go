@@ -0,0 +1,389 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/admin"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/auth"
+)
+
+// AdminUserService 是 /admin/users/* 路由所需的最小服务接口。
+type AdminUserService interface {
+	IsAdmin(userID int64) bool
+	SearchUsers(ctx context.Context, actorID int64, field model.AdminSearchField, query, cursor string, limit int) (admin.SearchPage, error)
+	GetUserDetail(ctx context.Context, actorID, userID int64) (model.AdminUserDetail, error)
+	Suspend(ctx context.Context, actorID, userID int64, reason string, expiresAt *time.Time) (model.AccountState, error)
+	Ban(ctx context.Context, actorID, userID int64, reason string, expiresAt *time.Time) (model.AccountState, error)
+	Unsuspend(ctx context.Context, actorID, userID int64, reason string) (model.AccountState, error)
+	ForceLogout(ctx context.Context, actorID, userID int64, reason string) (model.AccountState, error)
+	ListApplePayloads(ctx context.Context, actorID int64, originalTxID string) ([]model.ApplePayload, error)
+}
+
+// AdminDeps 聚合管理后台路由的依赖。
+type AdminDeps struct {
+	Auth  auth.Service
+	Users AdminUserService
+	// Products 为 nil 时（Apple IAP 未配置）商品目录路由返回 503。
+	Products AdminAppleProductService
+	// PaywallExperiments 为 nil 时付费墙实验报表路由返回 503。
+	PaywallExperiments AdminPaywallExperimentService
+	// Revenue 为 nil 时（Apple IAP 未配置）收入报表路由返回 503。
+	Revenue AdminRevenueReportService
+	// Webhooks 为 nil 时权益 webhook 管理路由返回 503。
+	Webhooks AdminEntitlementWebhookService
+	// Comps 为 nil 时赠送会员与兑换码管理路由返回 503。
+	Comps AdminCompService
+}
+
+// RegisterAdminRoutes 注册 /admin/* 路由。所有路由都要求 Bearer token 且调用方在管理员白名单中。
+func RegisterAdminRoutes(api huma.API, deps AdminDeps) {
+	registerAdminDocMetadata(api)
+	registerAdminSearchUsersRoute(api, deps)
+	registerAdminUserDetailRoute(api, deps)
+	registerAdminUserActionRoute(api, deps, "suspend", "暂停账号", "把账号置为 SUSPENDED 并立即强制下线：此前签发的 access token 全部失效。可通过 expires_at 设置到期自动恢复。", deps.suspend)
+	registerAdminUserActionRoute(api, deps, "ban", "封禁账号", "把账号置为 BANNED 并立即强制下线。可通过 expires_at 设置到期自动恢复，省略则永久封禁。", deps.ban)
+	registerAdminUserActionRoute(api, deps, "unsuspend", "恢复账号", "把 SUSPENDED / BANNED 账号恢复为 ACTIVE。已失效的 access token 不会恢复，用户需要重新登录。", deps.unsuspend)
+	registerAdminUserActionRoute(api, deps, "force-logout", "强制下线", "使该用户此前签发的所有 access token 失效，不改变账号状态。", deps.forceLogout)
+	registerAdminApplePayloadsRoute(api, deps)
+	registerAdminAppleProductRoutes(api, deps)
+	registerAdminPaywallExperimentRoutes(api, deps)
+	registerAdminRevenueReportRoutes(api, deps)
+	registerAdminEntitlementWebhookRoutes(api, deps)
+	registerAdminCompRoutes(api, deps)
+}
+
+func registerAdminDocMetadata(api huma.API) {
+	openapi := api.OpenAPI()
+	for _, t := range openapi.Tags {
+		if t.Name == "admin" {
+			return
+		}
+	}
+	openapi.Tags = append(openapi.Tags, &huma.Tag{
+		Name:        "admin",
+		Description: "客服后台：用户搜索、详情与账号操作，Apple 商品目录管理、付费墙实验、订阅收入报表、权益变更 webhook、赠送会员与兑换码管理。仅限管理员白名单中的用户调用。",
+	})
+}
+
+func registerAdminSearchUsersRoute(api huma.API, deps AdminDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-search-users",
+		Method:      http.MethodGet,
+		Path:        "/admin/users",
+		Summary:     "搜索用户",
+		Description: "按指定维度精确查找用户：用户 ID、登录邮箱（不区分大小写）、登录提供方 subject、Apple appAccountToken 或 Apple originalTransactionId。\n\n结果按用户 ID 升序分页；响应中的 next_cursor 非空时，把它作为 cursor 参数传回即可获取下一页。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Field         string `query:"field" enum:"id,email,provider_subject,app_account_token,original_transaction_id" default:"email" doc:"搜索维度"`
+		Q             string `query:"q" required:"true" minLength:"1" doc:"搜索值（精确匹配）" example:"ada@example.com"`
+		Cursor        string `query:"cursor" doc:"上一页返回的 next_cursor；首页留空"`
+		Limit         int    `query:"limit" minimum:"1" maximum:"100" default:"20" doc:"每页数量"`
+	}) (*struct {
+		Body model.Response[model.AdminUserSearchResponse]
+	}, error) {
+		actorID, err := adminActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		page, err := deps.Users.SearchUsers(ctx, actorID, model.AdminSearchField(input.Field), input.Q, input.Cursor, input.Limit)
+		if err != nil {
+			return nil, mapAdminError(err)
+		}
+		out := model.AdminUserSearchResponse{
+			Users:      make([]model.AdminUserSummaryView, 0, len(page.Users)),
+			NextCursor: page.NextCursor,
+		}
+		for _, u := range page.Users {
+			out.Users = append(out.Users, adminUserView(u))
+		}
+		return &struct {
+			Body model.Response[model.AdminUserSearchResponse]
+		}{
+			Body: model.Success(out),
+		}, nil
+	})
+}
+
+func registerAdminUserDetailRoute(api huma.API, deps AdminDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-get-user",
+		Method:      http.MethodGet,
+		Path:        "/admin/users/{id}",
+		Summary:     "查看用户详情",
+		Description: "聚合用户的登录身份、Apple appAccountToken、全部环境下的 Apple 订阅以及最近 20 条 Apple 通知，便于客服排查购买问题。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusNotFound,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		ID            int64  `path:"id" minimum:"1" doc:"用户 ID" example:"1"`
+	}) (*struct {
+		Body model.Response[model.AdminUserDetailResponse]
+	}, error) {
+		actorID, err := adminActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		detail, err := deps.Users.GetUserDetail(ctx, actorID, input.ID)
+		if err != nil {
+			return nil, mapAdminError(err)
+		}
+		return &struct {
+			Body model.Response[model.AdminUserDetailResponse]
+		}{
+			Body: model.Success(adminUserDetailView(detail)),
+		}, nil
+	})
+}
+
+func registerAdminApplePayloadsRoute(api huma.API, deps AdminDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-list-apple-payloads",
+		Method:      http.MethodGet,
+		Path:        "/admin/apple/payloads",
+		Summary:     "查看 Apple 原始数据归档",
+		Description: "解密并返回该 originalTransactionId 下最近的 Apple 通知与 verify 交易归档（解码后的 JSON），用于争议排查。每次调用都会记录审计日志。\n\n未配置 PAYLOAD_ARCHIVE_KEYS 时返回 503；超过保留期的归档已被删除。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization         string `header:"Authorization" hidden:"true"`
+		OriginalTransactionID string `query:"original_transaction_id" required:"true" minLength:"1" doc:"Apple originalTransactionId" example:"200000123456789"`
+	}) (*struct {
+		Body model.Response[model.AdminApplePayloadsResponse]
+	}, error) {
+		actorID, err := adminActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		payloads, err := deps.Users.ListApplePayloads(ctx, actorID, input.OriginalTransactionID)
+		if err != nil {
+			return nil, mapAdminError(err)
+		}
+		out := model.AdminApplePayloadsResponse{Payloads: make([]model.AdminApplePayloadView, 0, len(payloads))}
+		for _, p := range payloads {
+			view := model.AdminApplePayloadView{
+				ID:                    strconv.FormatInt(p.ID, 10),
+				Source:                p.Source,
+				Environment:           string(p.Environment),
+				OriginalTransactionID: p.OriginalTransactionID,
+				TransactionID:         p.TransactionID,
+				Payload:               p.Payload,
+				CreatedAt:             formatTime(p.CreatedAt),
+				ExpiresAt:             formatTime(p.ExpiresAt),
+			}
+			if p.UserID > 0 {
+				view.UserID = strconv.FormatInt(p.UserID, 10)
+			}
+			out.Payloads = append(out.Payloads, view)
+		}
+		return &struct {
+			Body model.Response[model.AdminApplePayloadsResponse]
+		}{
+			Body: model.Success(out),
+		}, nil
+	})
+}
+
+type adminUserAction func(ctx context.Context, actorID, userID int64, req model.AdminUserActionRequest) (model.AccountState, error)
+
+func (d AdminDeps) suspend(ctx context.Context, actorID, userID int64, req model.AdminUserActionRequest) (model.AccountState, error) {
+	return d.Users.Suspend(ctx, actorID, userID, req.Reason, req.ExpiresAt)
+}
+
+func (d AdminDeps) ban(ctx context.Context, actorID, userID int64, req model.AdminUserActionRequest) (model.AccountState, error) {
+	return d.Users.Ban(ctx, actorID, userID, req.Reason, req.ExpiresAt)
+}
+
+func (d AdminDeps) unsuspend(ctx context.Context, actorID, userID int64, req model.AdminUserActionRequest) (model.AccountState, error) {
+	return d.Users.Unsuspend(ctx, actorID, userID, req.Reason)
+}
+
+func (d AdminDeps) forceLogout(ctx context.Context, actorID, userID int64, req model.AdminUserActionRequest) (model.AccountState, error) {
+	return d.Users.ForceLogout(ctx, actorID, userID, req.Reason)
+}
+
+func registerAdminUserActionRoute(api huma.API, deps AdminDeps, action, summary, description string, run adminUserAction) {
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-" + action + "-user",
+		Method:      http.MethodPost,
+		Path:        "/admin/users/{id}/" + action,
+		Summary:     summary,
+		Description: description,
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusNotFound,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		ID            int64  `path:"id" minimum:"1" doc:"用户 ID" example:"1"`
+		Body          model.AdminUserActionRequest
+	}) (*struct {
+		Body model.Response[model.AdminAccountStateView]
+	}, error) {
+		actorID, err := adminActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		state, err := run(ctx, actorID, input.ID, input.Body)
+		if err != nil {
+			return nil, mapAdminError(err)
+		}
+		return &struct {
+			Body model.Response[model.AdminAccountStateView]
+		}{
+			Body: model.Success(model.AdminAccountStateView{
+				UserID:            strconv.FormatInt(state.UserID, 10),
+				Status:            state.Status,
+				StatusReason:      state.StatusReason,
+				StatusUpdatedAt:   formatOptionalTime(state.StatusUpdatedAt),
+				StatusExpiresAt:   formatOptionalTime(state.StatusExpiresAt),
+				SessionsRevokedAt: formatOptionalTime(state.SessionsRevokedAt),
+			}),
+		}, nil
+	})
+}
+
+// adminActorID 校验 Bearer token 并确认调用方是管理员，返回调用方用户 ID。
+func adminActorID(ctx context.Context, deps AdminDeps, authHeader string) (int64, error) {
+	authedUser, err := validateUserBearerToken(ctx, deps.Auth, authHeader)
+	if err != nil {
+		return 0, err
+	}
+	actorID, perr := strconv.ParseInt(authedUser.ID, 10, 64)
+	if perr != nil || actorID <= 0 {
+		return 0, huma.Error401Unauthorized("access token 无效")
+	}
+	if deps.Users == nil {
+		return 0, huma.Error503ServiceUnavailable("管理后台未配置")
+	}
+	if !deps.Users.IsAdmin(actorID) {
+		return 0, huma.Error403Forbidden("无管理员权限")
+	}
+	return actorID, nil
+}
+
+func mapAdminError(err error) error {
+	switch {
+	case errors.Is(err, admin.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("管理后台未配置")
+	case errors.Is(err, admin.ErrForbidden):
+		return huma.Error403Forbidden("无管理员权限")
+	case errors.Is(err, admin.ErrInvalidQuery),
+		errors.Is(err, admin.ErrInvalidCursor),
+		errors.Is(err, admin.ErrInvalidExpiry):
+		return huma.Error400BadRequest(err.Error())
+	case errors.Is(err, admin.ErrUserNotFound):
+		return huma.Error404NotFound("用户不存在")
+	default:
+		return huma.Error500InternalServerError("管理操作失败")
+	}
+}
+
+func adminUserView(u model.AdminUser) model.AdminUserSummaryView {
+	return model.AdminUserSummaryView{
+		ID:                strconv.FormatInt(u.ID, 10),
+		Name:              u.Name,
+		Status:            u.Status,
+		StatusReason:      u.StatusReason,
+		StatusUpdatedAt:   formatOptionalTime(u.StatusUpdatedAt),
+		StatusExpiresAt:   formatOptionalTime(u.StatusExpiresAt),
+		SessionsRevokedAt: formatOptionalTime(u.SessionsRevokedAt),
+		CreatedAt:         formatTime(u.CreatedAt),
+	}
+}
+
+func adminUserDetailView(d model.AdminUserDetail) model.AdminUserDetailResponse {
+	out := model.AdminUserDetailResponse{
+		User:            adminUserView(d.User),
+		Identities:      make([]model.AdminIdentityView, 0, len(d.Identities)),
+		AppAccountToken: d.AppAccountToken,
+		Subscriptions:   make([]model.AdminSubscriptionView, 0, len(d.Subscriptions)),
+		RecentEvents:    make([]model.AdminAppleEventView, 0, len(d.RecentEvents)),
+	}
+	for _, ai := range d.Identities {
+		out.Identities = append(out.Identities, model.AdminIdentityView{
+			Provider:        ai.Provider,
+			ProviderSubject: ai.ProviderSubject,
+			Email:           ai.Email,
+			CreatedAt:       formatTime(ai.CreatedAt),
+		})
+	}
+	for _, s := range d.Subscriptions {
+		out.Subscriptions = append(out.Subscriptions, model.AdminSubscriptionView{
+			Environment:           string(s.Environment),
+			OriginalTransactionID: s.OriginalTransactionID,
+			LastTransactionID:     s.LastTransactionID,
+			ProductID:             s.ProviderProductID,
+			PlanID:                s.PlanID,
+			Level:                 s.Level,
+			Status:                s.Status,
+			AutoRenewStatus:       s.AutoRenewStatus,
+			CurrentPeriodEnd:      formatTime(s.CurrentPeriodEnd),
+			LastEventAt:           formatTime(s.LastEventAt),
+		})
+	}
+	for _, e := range d.RecentEvents {
+		out.RecentEvents = append(out.RecentEvents, model.AdminAppleEventView{
+			NotificationUUID:      e.NotificationUUID,
+			NotificationType:      e.NotificationType,
+			Subtype:               e.Subtype,
+			Environment:           string(e.Environment),
+			OriginalTransactionID: e.OriginalTransactionID,
+			TransactionID:         e.TransactionID,
+			ProcessingStatus:      e.ProcessingStatus,
+			ProcessingError:       e.ProcessingError,
+			NotificationCreatedAt: formatOptionalTime(e.NotificationCreatedAt),
+			CreatedAt:             formatTime(e.CreatedAt),
+		})
+	}
+	return out
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
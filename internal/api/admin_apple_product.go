@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+// AdminAppleProductService 是 /admin/apple/products 路由所需的最小服务接口。
+//
+// 生产实现由 internal/service/payment.ProductCatalogService 提供；为 nil 时路由返回 503。
+type AdminAppleProductService interface {
+	ListProducts(ctx context.Context) ([]model.AppleProduct, error)
+	CreateProduct(ctx context.Context, actorID int64, in model.AppleProductInput) (model.AppleProduct, error)
+	UpdateProduct(ctx context.Context, actorID, id int64, in model.AppleProductInput) (model.AppleProduct, error)
+}
+
+func registerAdminAppleProductRoutes(api huma.API, deps AdminDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-list-apple-products",
+		Method:      http.MethodGet,
+		Path:        "/admin/apple/products",
+		Summary:     "查看 Apple 商品目录",
+		Description: "返回数据库管理的全部 Apple IAP 商品，包括已下架的商品。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+	}) (*struct {
+		Body model.Response[model.AdminAppleProductsResponse]
+	}, error) {
+		if _, err := adminProductActorID(ctx, deps, input.Authorization); err != nil {
+			return nil, err
+		}
+		products, err := deps.Products.ListProducts(ctx)
+		if err != nil {
+			return nil, mapAdminAppleProductError(err)
+		}
+		out := model.AdminAppleProductsResponse{Products: make([]model.AdminAppleProductView, 0, len(products))}
+		for _, p := range products {
+			out.Products = append(out.Products, adminAppleProductView(p))
+		}
+		return &struct {
+			Body model.Response[model.AdminAppleProductsResponse]
+		}{
+			Body: model.Success(out),
+		}, nil
+	})
+
+	writeErrors := []int{
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusConflict,
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+	}
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-create-apple-product",
+		Method:      http.MethodPost,
+		Path:        "/admin/apple/products",
+		Summary:     "新增 Apple 商品",
+		Description: "新增一条 Apple IAP 商品。本实例立即生效，其他实例在 APPLE_IAP_CATALOG_REFRESH_INTERVAL 内生效，无需重启。\n\n(product_id, environment) 已存在时返回 409。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors:      writeErrors,
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Body          model.AdminAppleProductRequest
+	}) (*struct {
+		Body model.Response[model.AdminAppleProductView]
+	}, error) {
+		actorID, err := adminProductActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		row, err := deps.Products.CreateProduct(ctx, actorID, appleProductInput(input.Body))
+		if err != nil {
+			return nil, mapAdminAppleProductError(err)
+		}
+		return &struct {
+			Body model.Response[model.AdminAppleProductView]
+		}{
+			Body: model.Success(adminAppleProductView(row)),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-update-apple-product",
+		Method:      http.MethodPut,
+		Path:        "/admin/apple/products/{id}",
+		Summary:     "修改 Apple 商品",
+		Description: "整体覆盖一条 Apple IAP 商品的可变字段。product_id、environment 与 type 创建后不可修改，与现有值不同时返回 400。商品不能删除，下架请设置 active=false：下架后商品不再出现在 paywall，verify 与通知仍按该商品处理，已有订阅的续期、到期与退款照常生效。\n\n生效时机同新增。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors:      append(writeErrors, http.StatusNotFound),
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		ID            int64  `path:"id" minimum:"1" doc:"商品记录 ID" example:"1"`
+		Body          model.AdminAppleProductRequest
+	}) (*struct {
+		Body model.Response[model.AdminAppleProductView]
+	}, error) {
+		actorID, err := adminProductActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		row, err := deps.Products.UpdateProduct(ctx, actorID, input.ID, appleProductInput(input.Body))
+		if err != nil {
+			return nil, mapAdminAppleProductError(err)
+		}
+		return &struct {
+			Body model.Response[model.AdminAppleProductView]
+		}{
+			Body: model.Success(adminAppleProductView(row)),
+		}, nil
+	})
+}
+
+// adminProductActorID 在 adminActorID 的基础上确认商品目录已配置。
+func adminProductActorID(ctx context.Context, deps AdminDeps, authHeader string) (int64, error) {
+	actorID, err := adminActorID(ctx, deps, authHeader)
+	if err != nil {
+		return 0, err
+	}
+	if deps.Products == nil {
+		return 0, huma.Error503ServiceUnavailable("Apple IAP 未配置")
+	}
+	return actorID, nil
+}
+
+func appleProductInput(req model.AdminAppleProductRequest) model.AppleProductInput {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return model.AppleProductInput{
+		PlanID:              req.PlanID,
+		ProductID:           req.ProductID,
+		Environment:         model.AppleEnvironment(req.Environment),
+		Type:                req.Type,
+		Level:               req.Level,
+		Credits:             req.Credits,
+		SubscriptionGroupID: req.SubscriptionGroupID,
+		DisplayName:         req.DisplayName,
+		Description:         req.Description,
+		SortOrder:           req.SortOrder,
+		Active:              active,
+		Localizations:       req.Localizations,
+		Recommended:         req.Recommended,
+		PriceMilliunits:     req.PriceMilliunits,
+		Currency:            req.Currency,
+		BillingPeriod:       req.BillingPeriod,
+	}
+}
+
+func adminAppleProductView(p model.AppleProduct) model.AdminAppleProductView {
+	localizations := p.Localizations
+	if localizations == nil {
+		localizations = map[string]model.ProductLocalization{}
+	}
+	return model.AdminAppleProductView{
+		ID:                  strconv.FormatInt(p.ID, 10),
+		PlanID:              p.PlanID,
+		ProductID:           p.ProductID,
+		Environment:         string(p.Environment),
+		Type:                p.Type,
+		Level:               p.Level,
+		Credits:             p.Credits,
+		SubscriptionGroupID: p.SubscriptionGroupID,
+		DisplayName:         p.DisplayName,
+		Description:         p.Description,
+		SortOrder:           p.SortOrder,
+		Active:              p.Active,
+		Localizations:       localizations,
+		Recommended:         p.Recommended,
+		PriceMilliunits:     p.PriceMilliunits,
+		Currency:            p.Currency,
+		BillingPeriod:       p.BillingPeriod,
+		CreatedAt:           formatTime(p.CreatedAt),
+		UpdatedAt:           formatTime(p.UpdatedAt),
+	}
+}
+
+func mapAdminAppleProductError(err error) error {
+	switch {
+	case errors.Is(err, payment.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("Apple IAP 未配置")
+	case errors.Is(err, payment.ErrInvalidProduct):
+		return huma.Error400BadRequest(err.Error())
+	case errors.Is(err, payment.ErrAppleProductConflict):
+		return huma.Error409Conflict("该环境下 product_id 已存在")
+	case errors.Is(err, payment.ErrAppleProductNotFound):
+		return huma.Error404NotFound("商品不存在")
+	default:
+		return huma.Error500InternalServerError("管理操作失败")
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/admin"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+type stubAdminAppleProductSvc struct {
+	actorID int64
+	id      int64
+	in      model.AppleProductInput
+	err     error
+}
+
+func (s *stubAdminAppleProductSvc) ListProducts(context.Context) ([]model.AppleProduct, error) {
+	return []model.AppleProduct{{ID: 1, PlanID: "pro_monthly", ProductID: "com.app.pro.monthly", Environment: model.AppleEnvProduction, Type: "subscription", Level: 1, Active: true}}, nil
+}
+
+func (s *stubAdminAppleProductSvc) CreateProduct(_ context.Context, actorID int64, in model.AppleProductInput) (model.AppleProduct, error) {
+	s.actorID, s.in = actorID, in
+	if s.err != nil {
+		return model.AppleProduct{}, s.err
+	}
+	return model.AppleProduct{ID: 2, PlanID: in.PlanID, ProductID: in.ProductID, Environment: in.Environment, Type: in.Type, Active: in.Active}, nil
+}
+
+func (s *stubAdminAppleProductSvc) UpdateProduct(_ context.Context, actorID, id int64, in model.AppleProductInput) (model.AppleProduct, error) {
+	s.actorID, s.id, s.in = actorID, id, in
+	if s.err != nil {
+		return model.AppleProduct{}, s.err
+	}
+	return model.AppleProduct{ID: id, PlanID: in.PlanID, ProductID: in.ProductID, Environment: in.Environment, Type: in.Type, Active: in.Active}, nil
+}
+
+func newAdminAppleProductTestRouter(t testing.TB, products AdminAppleProductService, adminIDs ...int64) http.Handler {
+	t.Helper()
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterAdminRoutes(api, AdminDeps{
+		Auth:     newTestAuthService(t, service.NewMemoryUserService()),
+		Users:    admin.NewService(&memoryAdminDAO{}, adminIDs),
+		Products: products,
+	})
+	return router
+}
+
+func TestAdminAppleProductRoutes(t *testing.T) {
+	do := func(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+		var req *http.Request
+		if body == "" {
+			req = newAuthorizedUserRequest(t, method, path, nil)
+		} else {
+			req = newAuthorizedUserRequest(t, method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	body := `{"plan_id":"pro_yearly","product_id":"com.app.pro.yearly","environment":"Production","level":1}`
+
+	if rec := do(newAdminAppleProductTestRouter(t, &stubAdminAppleProductSvc{}, 42), http.MethodGet, "/admin/apple/products", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin status = %d, want 403; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := do(newAdminAppleProductTestRouter(t, nil, 1), http.MethodGet, "/admin/apple/products", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("nil service status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+
+	svc := &stubAdminAppleProductSvc{}
+	router := newAdminAppleProductTestRouter(t, svc, 1)
+	rec := do(router, http.MethodGet, "/admin/apple/products", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"product_id":"com.app.pro.monthly"`) {
+		t.Fatalf("list status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = do(router, http.MethodPost, "/admin/apple/products", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if svc.actorID != 1 || !svc.in.Active || svc.in.Environment != model.AppleEnvProduction {
+		t.Fatalf("create must default active=true: %+v", svc)
+	}
+	var got struct {
+		Data model.AdminAppleProductView `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Data.ID != "2" || got.Data.ProductID != "com.app.pro.yearly" {
+		t.Fatalf("unexpected product: %+v", got.Data)
+	}
+
+	rec = do(router, http.MethodPut, "/admin/apple/products/7", strings.Replace(body, `"level":1`, `"level":1,"active":false`, 1))
+	if rec.Code != http.StatusOK || svc.id != 7 || svc.in.Active {
+		t.Fatalf("deactivate status = %d, call=%+v; body=%s", rec.Code, svc, rec.Body.String())
+	}
+
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{payment.ErrInvalidProduct, http.StatusBadRequest},
+		{payment.ErrAppleProductConflict, http.StatusConflict},
+		{payment.ErrAppleProductNotFound, http.StatusNotFound},
+	} {
+		router := newAdminAppleProductTestRouter(t, &stubAdminAppleProductSvc{err: tc.err}, 1)
+		if rec := do(router, http.MethodPut, "/admin/apple/products/7", body); rec.Code != tc.want {
+			t.Fatalf("%v status = %d, want %d; body=%s", tc.err, rec.Code, tc.want, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/comp"
+)
+
+// AdminCompService 是 /admin/users/{id}/comps 与 /admin/promo-codes 路由所需的最小服务接口。
+//
+// 生产实现由 internal/service/comp.Service 提供；为 nil 时路由返回 503。
+type AdminCompService interface {
+	ListComps(ctx context.Context, userID int64) ([]model.CompEntitlement, error)
+	GrantComp(ctx context.Context, actorID, userID int64, planID string, level, days int, note string) (model.CompEntitlement, error)
+	RevokeComp(ctx context.Context, actorID, userID, compID int64) (model.CompEntitlement, error)
+	ListPromoCodes(ctx context.Context) ([]model.PromoCode, error)
+	CreatePromoCode(ctx context.Context, actorID int64, in model.PromoCodeInput) (model.PromoCode, error)
+	DeactivatePromoCode(ctx context.Context, actorID int64, code string) (model.PromoCode, error)
+}
+
+func registerAdminCompRoutes(api huma.API, deps AdminDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-list-user-comps",
+		Method:      http.MethodGet,
+		Path:        "/admin/users/{id}/comps",
+		Summary:     "查看用户的赠送会员",
+		Description: "返回该用户全部来源（邀请奖励、后台赠送、兑换码）的赠送会员权益，包括已结束与已撤销的，按开始时间倒序。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		ID            int64  `path:"id" minimum:"1" doc:"用户 ID" example:"1"`
+	}) (*struct {
+		Body model.Response[model.AdminCompEntitlementsResponse]
+	}, error) {
+		if _, err := adminCompActorID(ctx, deps, input.Authorization); err != nil {
+			return nil, err
+		}
+		comps, err := deps.Comps.ListComps(ctx, input.ID)
+		if err != nil {
+			return nil, mapAdminCompError(err)
+		}
+		out := model.AdminCompEntitlementsResponse{Comps: make([]model.AdminCompEntitlementView, 0, len(comps))}
+		for _, c := range comps {
+			out.Comps = append(out.Comps, adminCompEntitlementView(c))
+		}
+		return &struct {
+			Body model.Response[model.AdminCompEntitlementsResponse]
+		}{
+			Body: model.Success(out),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-grant-user-comp",
+		Method:      http.MethodPost,
+		Path:        "/admin/users/{id}/comps",
+		Summary:     "赠送会员",
+		Description: "给用户发放一段赠送会员权益（来源 ADMIN），排在该用户已有赠送权益之后，立即计入订阅视图。note 必填，随权益保存并记录审计日志。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusNotFound,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		ID            int64  `path:"id" minimum:"1" doc:"用户 ID" example:"1"`
+		Body          model.AdminCompGrantRequest
+	}) (*struct {
+		Body model.Response[model.AdminCompEntitlementView]
+	}, error) {
+		actorID, err := adminCompActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		granted, err := deps.Comps.GrantComp(ctx, actorID, input.ID, input.Body.PlanID, input.Body.Level, input.Body.CompDays, input.Body.Note)
+		if err != nil {
+			return nil, mapAdminCompError(err)
+		}
+		return &struct {
+			Body model.Response[model.AdminCompEntitlementView]
+		}{
+			Body: model.Success(adminCompEntitlementView(granted)),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-revoke-user-comp",
+		Method:      http.MethodPost,
+		Path:        "/admin/users/{id}/comps/{comp_id}/revoke",
+		Summary:     "撤销赠送会员",
+		Description: "撤销用户的一段赠送会员权益（任意来源），撤销后立即不再计入订阅视图。排在其后的赠送权益保持原有起止时间，不会前移。\n\n权益不存在、不属于该用户或已撤销时返回 404。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusNotFound,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		ID            int64  `path:"id" minimum:"1" doc:"用户 ID" example:"1"`
+		CompID        int64  `path:"comp_id" minimum:"1" doc:"赠送权益 ID" example:"42"`
+	}) (*struct {
+		Body model.Response[model.AdminCompEntitlementView]
+	}, error) {
+		actorID, err := adminCompActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		revoked, err := deps.Comps.RevokeComp(ctx, actorID, input.ID, input.CompID)
+		if err != nil {
+			return nil, mapAdminCompError(err)
+		}
+		return &struct {
+			Body model.Response[model.AdminCompEntitlementView]
+		}{
+			Body: model.Success(adminCompEntitlementView(revoked)),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-list-promo-codes",
+		Method:      http.MethodGet,
+		Path:        "/admin/promo-codes",
+		Summary:     "查看兑换码",
+		Description: "返回全部兑换码及其兑换次数，包括已停用的兑换码。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+	}) (*struct {
+		Body model.Response[model.AdminPromoCodesResponse]
+	}, error) {
+		if _, err := adminCompActorID(ctx, deps, input.Authorization); err != nil {
+			return nil, err
+		}
+		codes, err := deps.Comps.ListPromoCodes(ctx)
+		if err != nil {
+			return nil, mapAdminCompError(err)
+		}
+		out := model.AdminPromoCodesResponse{PromoCodes: make([]model.AdminPromoCodeView, 0, len(codes))}
+		for _, c := range codes {
+			out.PromoCodes = append(out.PromoCodes, adminPromoCodeView(c))
+		}
+		return &struct {
+			Body model.Response[model.AdminPromoCodesResponse]
+		}{
+			Body: model.Success(out),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-create-promo-code",
+		Method:      http.MethodPost,
+		Path:        "/admin/promo-codes",
+		Summary:     "新增兑换码",
+		Description: "新增一个兑换码。用户兑换后获得 comp_days 天 plan_id / level 的赠送会员（来源 PROMO_CODE），每个用户每个兑换码只能兑换一次。\n\n兑换码已存在时返回 409。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusConflict,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Body          model.AdminPromoCodeRequest
+	}) (*struct {
+		Body model.Response[model.AdminPromoCodeView]
+	}, error) {
+		actorID, err := adminCompActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		code, err := deps.Comps.CreatePromoCode(ctx, actorID, model.PromoCodeInput{
+			Code:           input.Body.Code,
+			PlanID:         input.Body.PlanID,
+			Level:          input.Body.Level,
+			CompDays:       input.Body.CompDays,
+			MaxRedemptions: input.Body.MaxRedemptions,
+			ExpiresAt:      input.Body.ExpiresAt,
+		})
+		if err != nil {
+			return nil, mapAdminCompError(err)
+		}
+		return &struct {
+			Body model.Response[model.AdminPromoCodeView]
+		}{
+			Body: model.Success(adminPromoCodeView(code)),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-deactivate-promo-code",
+		Method:      http.MethodPost,
+		Path:        "/admin/promo-codes/{code}/deactivate",
+		Summary:     "停用兑换码",
+		Description: "停用后兑换码不再可兑换，且不能恢复。已兑换的赠送会员不受影响，需要时通过撤销赠送会员逐个处理。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusNotFound,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Code          string `path:"code" minLength:"1" maxLength:"32" doc:"兑换码（不区分大小写）" example:"SPRING2026"`
+	}) (*struct {
+		Body model.Response[model.AdminPromoCodeView]
+	}, error) {
+		actorID, err := adminCompActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		code, err := deps.Comps.DeactivatePromoCode(ctx, actorID, input.Code)
+		if err != nil {
+			return nil, mapAdminCompError(err)
+		}
+		return &struct {
+			Body model.Response[model.AdminPromoCodeView]
+		}{
+			Body: model.Success(adminPromoCodeView(code)),
+		}, nil
+	})
+}
+
+// adminCompActorID 在 adminActorID 的基础上确认赠送权益服务已配置。
+func adminCompActorID(ctx context.Context, deps AdminDeps, authHeader string) (int64, error) {
+	actorID, err := adminActorID(ctx, deps, authHeader)
+	if err != nil {
+		return 0, err
+	}
+	if deps.Comps == nil {
+		return 0, huma.Error503ServiceUnavailable("赠送会员未配置")
+	}
+	return actorID, nil
+}
+
+func adminCompEntitlementView(c model.CompEntitlement) model.AdminCompEntitlementView {
+	return model.AdminCompEntitlementView{
+		ID:        strconv.FormatInt(c.ID, 10),
+		PlanID:    c.PlanID,
+		Level:     c.Level,
+		Source:    c.Source,
+		Reference: c.Reference,
+		Note:      c.Note,
+		StartsAt:  formatTime(c.StartsAt),
+		EndsAt:    formatTime(c.EndsAt),
+		RevokedAt: formatOptionalTime(c.RevokedAt),
+	}
+}
+
+func adminPromoCodeView(c model.PromoCode) model.AdminPromoCodeView {
+	return model.AdminPromoCodeView{
+		Code:           c.Code,
+		PlanID:         c.PlanID,
+		Level:          c.Level,
+		CompDays:       c.CompDays,
+		MaxRedemptions: c.MaxRedemptions,
+		RedeemedCount:  c.RedeemedCount,
+		ExpiresAt:      formatOptionalTime(c.ExpiresAt),
+		Active:         c.Active,
+		CreatedAt:      formatTime(c.CreatedAt),
+		UpdatedAt:      formatTime(c.UpdatedAt),
+	}
+}
+
+func mapAdminCompError(err error) error {
+	switch {
+	case errors.Is(err, comp.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("赠送会员未配置")
+	case errors.Is(err, comp.ErrInvalidGrant),
+		errors.Is(err, comp.ErrInvalidPromoCode):
+		return huma.Error400BadRequest(err.Error())
+	case errors.Is(err, comp.ErrUserNotFound):
+		return huma.Error404NotFound("用户不存在")
+	case errors.Is(err, comp.ErrCompNotFound):
+		return huma.Error404NotFound("赠送会员不存在或已撤销")
+	case errors.Is(err, comp.ErrPromoCodeNotFound):
+		return huma.Error404NotFound("兑换码不存在")
+	case errors.Is(err, comp.ErrPromoCodeConflict):
+		return huma.Error409Conflict("兑换码已存在")
+	default:
+		return huma.Error500InternalServerError("管理操作失败")
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/admin"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/comp"
+)
+
+type stubAdminCompSvc struct {
+	actorID int64
+	userID  int64
+	compID  int64
+	planID  string
+	days    int
+	note    string
+	code    string
+	promoIn model.PromoCodeInput
+	err     error
+}
+
+func (s *stubAdminCompSvc) ListComps(_ context.Context, userID int64) ([]model.CompEntitlement, error) {
+	s.userID = userID
+	revokedAt := time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC)
+	return []model.CompEntitlement{
+		{ID: 3, UserID: userID, PlanID: "pro", Level: 1, Source: model.CompSourcePromoCode, Reference: "promo:SPRING"},
+		{ID: 2, UserID: userID, PlanID: "pro", Level: 1, Source: model.CompSourceAdmin, Reference: "admin:1", RevokedAt: &revokedAt},
+	}, s.err
+}
+
+func (s *stubAdminCompSvc) GrantComp(_ context.Context, actorID, userID int64, planID string, _ int, days int, note string) (model.CompEntitlement, error) {
+	s.actorID, s.userID, s.planID, s.days, s.note = actorID, userID, planID, days, note
+	if s.err != nil {
+		return model.CompEntitlement{}, s.err
+	}
+	return model.CompEntitlement{ID: 4, UserID: userID, PlanID: planID, Level: 1, Source: model.CompSourceAdmin, Note: note}, nil
+}
+
+func (s *stubAdminCompSvc) RevokeComp(_ context.Context, actorID, userID, compID int64) (model.CompEntitlement, error) {
+	s.actorID, s.userID, s.compID = actorID, userID, compID
+	if s.err != nil {
+		return model.CompEntitlement{}, s.err
+	}
+	now := time.Now()
+	return model.CompEntitlement{ID: compID, UserID: userID, RevokedAt: &now}, nil
+}
+
+func (s *stubAdminCompSvc) ListPromoCodes(context.Context) ([]model.PromoCode, error) {
+	return []model.PromoCode{{Code: "SPRING", PlanID: "pro", Level: 1, CompDays: 30, Active: true}}, s.err
+}
+
+func (s *stubAdminCompSvc) CreatePromoCode(_ context.Context, actorID int64, in model.PromoCodeInput) (model.PromoCode, error) {
+	s.actorID, s.promoIn = actorID, in
+	if s.err != nil {
+		return model.PromoCode{}, s.err
+	}
+	return model.PromoCode{Code: in.Code, PlanID: in.PlanID, Level: in.Level, CompDays: in.CompDays, MaxRedemptions: in.MaxRedemptions, Active: true}, nil
+}
+
+func (s *stubAdminCompSvc) DeactivatePromoCode(_ context.Context, actorID int64, code string) (model.PromoCode, error) {
+	s.actorID, s.code = actorID, code
+	if s.err != nil {
+		return model.PromoCode{}, s.err
+	}
+	return model.PromoCode{Code: code}, nil
+}
+
+func newAdminCompTestRouter(t testing.TB, comps AdminCompService, adminIDs ...int64) http.Handler {
+	t.Helper()
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterAdminRoutes(api, AdminDeps{
+		Auth:  newTestAuthService(t, service.NewMemoryUserService()),
+		Users: admin.NewService(&memoryAdminDAO{}, adminIDs),
+		Comps: comps,
+	})
+	return router
+}
+
+func TestAdminCompRoutes(t *testing.T) {
+	do := func(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+		var req *http.Request
+		if body == "" {
+			req = newAuthorizedUserRequest(t, method, path, nil)
+		} else {
+			req = newAuthorizedUserRequest(t, method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	grantBody := `{"plan_id":"pro","level":1,"comp_days":30,"note":"outage credit"}`
+
+	if rec := do(newAdminCompTestRouter(t, &stubAdminCompSvc{}, 42), http.MethodGet, "/admin/users/7/comps", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin status = %d, want 403; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := do(newAdminCompTestRouter(t, nil, 1), http.MethodGet, "/admin/promo-codes", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("nil service status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+
+	svc := &stubAdminCompSvc{}
+	router := newAdminCompTestRouter(t, svc, 1)
+	rec := do(router, http.MethodGet, "/admin/users/7/comps", "")
+	if rec.Code != http.StatusOK || svc.userID != 7 {
+		t.Fatalf("list status = %d, call=%+v; body=%s", rec.Code, svc, rec.Body.String())
+	}
+	var list struct {
+		Data model.AdminCompEntitlementsResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(list.Data.Comps) != 2 || list.Data.Comps[0].Source != model.CompSourcePromoCode || list.Data.Comps[0].RevokedAt != "" || list.Data.Comps[1].RevokedAt != "2026-09-02T00:00:00Z" {
+		t.Fatalf("unexpected comps: %+v", list.Data.Comps)
+	}
+
+	rec = do(router, http.MethodPost, "/admin/users/7/comps", grantBody)
+	if rec.Code != http.StatusOK || svc.actorID != 1 || svc.userID != 7 || svc.days != 30 || svc.note != "outage credit" {
+		t.Fatalf("grant status = %d, call=%+v; body=%s", rec.Code, svc, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"source":"ADMIN"`) {
+		t.Fatalf("grant response missing source: %s", rec.Body.String())
+	}
+
+	rec = do(router, http.MethodPost, "/admin/users/7/comps/3/revoke", "")
+	if rec.Code != http.StatusOK || svc.userID != 7 || svc.compID != 3 {
+		t.Fatalf("revoke status = %d, call=%+v; body=%s", rec.Code, svc, rec.Body.String())
+	}
+
+	rec = do(router, http.MethodPost, "/admin/promo-codes", `{"code":"spring","plan_id":"pro","level":1,"comp_days":30,"max_redemptions":100}`)
+	if rec.Code != http.StatusOK || svc.promoIn.Code != "spring" || svc.promoIn.MaxRedemptions != 100 {
+		t.Fatalf("create promo status = %d, call=%+v; body=%s", rec.Code, svc, rec.Body.String())
+	}
+
+	rec = do(router, http.MethodPost, "/admin/promo-codes/spring/deactivate", "")
+	if rec.Code != http.StatusOK || svc.code != "spring" {
+		t.Fatalf("deactivate status = %d, call=%+v; body=%s", rec.Code, svc, rec.Body.String())
+	}
+
+	for _, tc := range []struct {
+		method, path, body string
+		err                error
+		want               int
+	}{
+		{http.MethodPost, "/admin/users/7/comps", grantBody, comp.ErrInvalidGrant, http.StatusBadRequest},
+		{http.MethodPost, "/admin/users/7/comps", grantBody, comp.ErrUserNotFound, http.StatusNotFound},
+		{http.MethodPost, "/admin/users/7/comps/3/revoke", "", comp.ErrCompNotFound, http.StatusNotFound},
+		{http.MethodPost, "/admin/promo-codes", `{"code":"X","plan_id":"pro","level":1,"comp_days":1}`, comp.ErrPromoCodeConflict, http.StatusConflict},
+		{http.MethodPost, "/admin/promo-codes/X/deactivate", "", comp.ErrPromoCodeNotFound, http.StatusNotFound},
+	} {
+		router := newAdminCompTestRouter(t, &stubAdminCompSvc{err: tc.err}, 1)
+		if rec := do(router, tc.method, tc.path, tc.body); rec.Code != tc.want {
+			t.Fatalf("%s %s %v status = %d, want %d; body=%s", tc.method, tc.path, tc.err, rec.Code, tc.want, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/entitlement"
+	logpkg "github.com/dundunHa/go-serverhttp-template/pkg/log"
+)
+
+// AdminEntitlementWebhookService 是 /admin/webhooks/* 路由所需的最小服务接口。
+//
+// 生产实现由 internal/service/entitlement.WebhookService 提供；为 nil 时路由返回 503。
+type AdminEntitlementWebhookService interface {
+	ListSubscribers(ctx context.Context) ([]model.EntitlementWebhookSubscriber, error)
+	CreateSubscriber(ctx context.Context, actorID int64, in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriber, error)
+	UpdateSubscriber(ctx context.Context, actorID, id int64, in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriber, error)
+	ListDeliveries(ctx context.Context, filter entitlement.WebhookDeliveryFilter, cursor string, limit int) (entitlement.WebhookDeliveryPage, error)
+	ReplayDelivery(ctx context.Context, actorID, id int64) error
+	ReplayDeadDeliveries(ctx context.Context, actorID, subscriberID int64) (int64, error)
+}
+
+const adminWebhookSubscriberDescription = "订阅方在 Apple / Google Play / Stripe 订阅状态变更提交后收到 POST 回调，请求体为 {\"id\",\"type\",\"created_at\",\"data\"}，data 是变更后的订阅快照。\n\n" +
+	"请求头 X-Entitlement-Webhook-Signature 形如 t=<unix 秒>,v1=<签名>，签名为 hex(HMAC-SHA256(secret, \"<t>.<请求体>\"))，订阅方应校验签名并拒绝时间戳过旧的请求；" +
+	"X-Entitlement-Webhook-Id 为事件 ID，重试与重放时不变，可用于去重。返回 2xx 视为投递成功，其余按 ENTITLEMENT_WEBHOOK_RETRY_BACKOFF 指数退避重试，次数用尽后记为 DEAD。"
+
+func registerAdminEntitlementWebhookRoutes(api huma.API, deps AdminDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-list-webhook-subscribers",
+		Method:      http.MethodGet,
+		Path:        "/admin/webhooks/subscribers",
+		Summary:     "查看权益 webhook 订阅方",
+		Description: "返回全部权益变更 webhook 订阅方，包括已停用的订阅方。不返回签名密钥。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+	}) (*struct {
+		Body model.Response[model.AdminEntitlementWebhookSubscribersResponse]
+	}, error) {
+		if _, err := adminWebhookActorID(ctx, deps, input.Authorization); err != nil {
+			return nil, err
+		}
+		subs, err := deps.Webhooks.ListSubscribers(ctx)
+		if err != nil {
+			return nil, mapAdminWebhookError(ctx, err)
+		}
+		out := model.AdminEntitlementWebhookSubscribersResponse{Subscribers: make([]model.AdminEntitlementWebhookSubscriberView, 0, len(subs))}
+		for _, sub := range subs {
+			out.Subscribers = append(out.Subscribers, adminWebhookSubscriberView(sub, false))
+		}
+		return &struct {
+			Body model.Response[model.AdminEntitlementWebhookSubscribersResponse]
+		}{
+			Body: model.Success(out),
+		}, nil
+	})
+
+	writeErrors := []int{
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+	}
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-create-webhook-subscriber",
+		Method:      http.MethodPost,
+		Path:        "/admin/webhooks/subscribers",
+		Summary:     "新增权益 webhook 订阅方",
+		Description: adminWebhookSubscriberDescription + "\n\n响应中的 secret 只返回这一次。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors:      writeErrors,
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Body          model.AdminEntitlementWebhookSubscriberRequest
+	}) (*struct {
+		Body model.Response[model.AdminEntitlementWebhookSubscriberView]
+	}, error) {
+		actorID, err := adminWebhookActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		sub, err := deps.Webhooks.CreateSubscriber(ctx, actorID, webhookSubscriberInput(input.Body))
+		if err != nil {
+			return nil, mapAdminWebhookError(ctx, err)
+		}
+		return &struct {
+			Body model.Response[model.AdminEntitlementWebhookSubscriberView]
+		}{
+			Body: model.Success(adminWebhookSubscriberView(sub, true)),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-update-webhook-subscriber",
+		Method:      http.MethodPut,
+		Path:        "/admin/webhooks/subscribers/{id}",
+		Summary:     "修改权益 webhook 订阅方",
+		Description: "整体覆盖订阅方配置；省略 secret 保留原密钥，传入新 secret 即轮换密钥。订阅方不能删除，停用请设置 active=false：停用后不再产生新投递，待发送的投递暂停到重新启用。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors:      append(writeErrors, http.StatusNotFound),
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		ID            int64  `path:"id" minimum:"1" doc:"订阅方 ID" example:"1"`
+		Body          model.AdminEntitlementWebhookSubscriberRequest
+	}) (*struct {
+		Body model.Response[model.AdminEntitlementWebhookSubscriberView]
+	}, error) {
+		actorID, err := adminWebhookActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		sub, err := deps.Webhooks.UpdateSubscriber(ctx, actorID, input.ID, webhookSubscriberInput(input.Body))
+		if err != nil {
+			return nil, mapAdminWebhookError(ctx, err)
+		}
+		return &struct {
+			Body model.Response[model.AdminEntitlementWebhookSubscriberView]
+		}{
+			Body: model.Success(adminWebhookSubscriberView(sub, false)),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-list-webhook-deliveries",
+		Method:      http.MethodGet,
+		Path:        "/admin/webhooks/deliveries",
+		Summary:     "查看权益 webhook 投递记录",
+		Description: "按投递 ID 倒序分页返回投递记录，可按订阅方与状态过滤；响应中的 next_cursor 非空时，把它作为 cursor 参数传回即可获取下一页。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		SubscriberID  int64  `query:"subscriber_id" minimum:"0" doc:"只返回该订阅方的投递；省略为全部" example:"1"`
+		Status        string `query:"status" enum:"PENDING,DELIVERED,DEAD" doc:"只返回该状态的投递；省略为全部"`
+		Cursor        string `query:"cursor" doc:"上一页响应中的 next_cursor；首页省略"`
+		Limit         int    `query:"limit" minimum:"1" maximum:"100" default:"20" doc:"每页条数"`
+	}) (*struct {
+		Body model.Response[model.AdminEntitlementWebhookDeliveriesResponse]
+	}, error) {
+		if _, err := adminWebhookActorID(ctx, deps, input.Authorization); err != nil {
+			return nil, err
+		}
+		page, err := deps.Webhooks.ListDeliveries(ctx, entitlement.WebhookDeliveryFilter{
+			SubscriberID: input.SubscriberID,
+			Status:       input.Status,
+		}, input.Cursor, input.Limit)
+		if err != nil {
+			return nil, mapAdminWebhookError(ctx, err)
+		}
+		out := model.AdminEntitlementWebhookDeliveriesResponse{
+			Deliveries: make([]model.AdminEntitlementWebhookDeliveryView, 0, len(page.Deliveries)),
+			NextCursor: page.NextCursor,
+		}
+		for _, d := range page.Deliveries {
+			out.Deliveries = append(out.Deliveries, adminWebhookDeliveryView(d))
+		}
+		return &struct {
+			Body model.Response[model.AdminEntitlementWebhookDeliveriesResponse]
+		}{
+			Body: model.Success(out),
+		}, nil
+	})
+
+	replayErrors := []int{
+		http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+	}
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-replay-webhook-delivery",
+		Method:      http.MethodPost,
+		Path:        "/admin/webhooks/deliveries/{id}/replay",
+		Summary:     "重放一条权益 webhook 投递",
+		Description: "把投递重新排入队列并清零尝试次数，下一轮投递任务立即发送。任意状态都可以重放，包括已成功的投递；请求体与事件 ID 与首次投递相同，签名时间戳为发送时间。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors:      append(replayErrors, http.StatusNotFound),
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		ID            int64  `path:"id" minimum:"1" doc:"投递 ID" example:"1"`
+	}) (*struct {
+		Body model.Response[model.AdminEntitlementWebhookReplayResponse]
+	}, error) {
+		actorID, err := adminWebhookActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		if err := deps.Webhooks.ReplayDelivery(ctx, actorID, input.ID); err != nil {
+			return nil, mapAdminWebhookError(ctx, err)
+		}
+		return &struct {
+			Body model.Response[model.AdminEntitlementWebhookReplayResponse]
+		}{
+			Body: model.Success(model.AdminEntitlementWebhookReplayResponse{Replayed: 1}),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-replay-dead-webhook-deliveries",
+		Method:      http.MethodPost,
+		Path:        "/admin/webhooks/subscribers/{id}/replay-dead",
+		Summary:     "重放订阅方的全部死信投递",
+		Description: "把该订阅方全部 DEAD 状态的投递重新排入队列并清零尝试次数，通常在订阅方故障恢复后调用。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors:      replayErrors,
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		ID            int64  `path:"id" minimum:"1" doc:"订阅方 ID" example:"1"`
+	}) (*struct {
+		Body model.Response[model.AdminEntitlementWebhookReplayResponse]
+	}, error) {
+		actorID, err := adminWebhookActorID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		n, err := deps.Webhooks.ReplayDeadDeliveries(ctx, actorID, input.ID)
+		if err != nil {
+			return nil, mapAdminWebhookError(ctx, err)
+		}
+		return &struct {
+			Body model.Response[model.AdminEntitlementWebhookReplayResponse]
+		}{
+			Body: model.Success(model.AdminEntitlementWebhookReplayResponse{Replayed: n}),
+		}, nil
+	})
+}
+
+// adminWebhookActorID 在 adminActorID 的基础上确认 webhook 服务已配置。
+func adminWebhookActorID(ctx context.Context, deps AdminDeps, authHeader string) (int64, error) {
+	actorID, err := adminActorID(ctx, deps, authHeader)
+	if err != nil {
+		return 0, err
+	}
+	if deps.Webhooks == nil {
+		return 0, huma.Error503ServiceUnavailable("权益 webhook 未配置")
+	}
+	return actorID, nil
+}
+
+func webhookSubscriberInput(req model.AdminEntitlementWebhookSubscriberRequest) model.EntitlementWebhookSubscriberInput {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return model.EntitlementWebhookSubscriberInput{
+		Name:       req.Name,
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+		Active:     active,
+	}
+}
+
+func adminWebhookSubscriberView(sub model.EntitlementWebhookSubscriber, withSecret bool) model.AdminEntitlementWebhookSubscriberView {
+	view := model.AdminEntitlementWebhookSubscriberView{
+		ID:         strconv.FormatInt(sub.ID, 10),
+		Name:       sub.Name,
+		URL:        sub.URL,
+		EventTypes: sub.EventTypes,
+		Active:     sub.Active,
+		CreatedAt:  formatTime(sub.CreatedAt),
+		UpdatedAt:  formatTime(sub.UpdatedAt),
+	}
+	if withSecret {
+		view.Secret = sub.Secret
+	}
+	return view
+}
+
+func adminWebhookDeliveryView(d model.EntitlementWebhookDelivery) model.AdminEntitlementWebhookDeliveryView {
+	view := model.AdminEntitlementWebhookDeliveryView{
+		ID:             strconv.FormatInt(d.ID, 10),
+		EventID:        strconv.FormatInt(d.EventID, 10),
+		SubscriberID:   strconv.FormatInt(d.SubscriberID, 10),
+		EventType:      d.EventType,
+		UserID:         strconv.FormatInt(d.UserID, 10),
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      formatTime(d.CreatedAt),
+		UpdatedAt:      formatTime(d.UpdatedAt),
+	}
+	if d.Status == model.WebhookDeliveryPending {
+		view.NextAttemptAt = formatTime(d.NextAttemptAt)
+	}
+	if d.DeliveredAt != nil {
+		view.DeliveredAt = formatTime(*d.DeliveredAt)
+	}
+	return view
+}
+
+func mapAdminWebhookError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, entitlement.ErrWebhookNotConfigured):
+		return huma.Error503ServiceUnavailable("权益 webhook 未配置")
+	case errors.Is(err, entitlement.ErrInvalidWebhookSubscriber):
+		return huma.Error400BadRequest(err.Error())
+	case errors.Is(err, entitlement.ErrInvalidWebhookCursor):
+		return huma.Error400BadRequest("cursor 无效")
+	case errors.Is(err, entitlement.ErrWebhookSubscriberNotFound):
+		return huma.Error404NotFound("订阅方不存在")
+	case errors.Is(err, entitlement.ErrWebhookDeliveryNotFound):
+		return huma.Error404NotFound("投递记录不存在")
+	default:
+		logpkg.FromContext(ctx).ErrorContext(ctx, "admin entitlement webhook failed", "err", err)
+		return huma.Error500InternalServerError("操作失败")
+	}
+}
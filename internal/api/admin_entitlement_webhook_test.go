@@ -0,0 +1,163 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/admin"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/entitlement"
+)
+
+type stubWebhookSvc struct {
+	created  model.EntitlementWebhookSubscriberInput
+	filter   entitlement.WebhookDeliveryFilter
+	cursor   string
+	replayed []int64
+	actorID  int64
+}
+
+func (s *stubWebhookSvc) ListSubscribers(context.Context) ([]model.EntitlementWebhookSubscriber, error) {
+	return []model.EntitlementWebhookSubscriber{{ID: 1, Name: "billing", URL: "https://billing.example.com", Secret: "whsec_hidden", EventTypes: []string{model.EntitlementEventActivated}, Active: true}}, nil
+}
+
+func (s *stubWebhookSvc) CreateSubscriber(_ context.Context, actorID int64, in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriber, error) {
+	s.actorID, s.created = actorID, in
+	return model.EntitlementWebhookSubscriber{ID: 2, Name: in.Name, URL: in.URL, Secret: "whsec_generated", EventTypes: in.EventTypes, Active: in.Active}, nil
+}
+
+func (s *stubWebhookSvc) UpdateSubscriber(_ context.Context, _, id int64, in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriber, error) {
+	if id != 1 {
+		return model.EntitlementWebhookSubscriber{}, entitlement.ErrWebhookSubscriberNotFound
+	}
+	return model.EntitlementWebhookSubscriber{ID: id, Name: in.Name, URL: in.URL, Secret: "whsec_hidden", EventTypes: in.EventTypes, Active: in.Active}, nil
+}
+
+func (s *stubWebhookSvc) ListDeliveries(_ context.Context, filter entitlement.WebhookDeliveryFilter, cursor string, _ int) (entitlement.WebhookDeliveryPage, error) {
+	s.filter, s.cursor = filter, cursor
+	if cursor == "bad" {
+		return entitlement.WebhookDeliveryPage{}, entitlement.ErrInvalidWebhookCursor
+	}
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	return entitlement.WebhookDeliveryPage{
+		Deliveries: []model.EntitlementWebhookDelivery{{
+			ID: 5, EventID: 4, SubscriberID: 1, EventType: model.EntitlementEventDeactivated, UserID: 42,
+			Status: model.WebhookDeliveryDead, Attempts: 10, NextAttemptAt: at, LastStatusCode: 500, LastError: "unexpected status 500",
+			CreatedAt: at, UpdatedAt: at,
+		}},
+		NextCursor: "next",
+	}, nil
+}
+
+func (s *stubWebhookSvc) ReplayDelivery(_ context.Context, _, id int64) error {
+	if id != 5 {
+		return entitlement.ErrWebhookDeliveryNotFound
+	}
+	s.replayed = append(s.replayed, id)
+	return nil
+}
+
+func (s *stubWebhookSvc) ReplayDeadDeliveries(context.Context, int64, int64) (int64, error) {
+	return 3, nil
+}
+
+func newAdminWebhookTestRouter(t testing.TB, webhooks AdminEntitlementWebhookService, adminIDs ...int64) http.Handler {
+	t.Helper()
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterAdminRoutes(api, AdminDeps{
+		Auth:     newTestAuthService(t, service.NewMemoryUserService()),
+		Users:    admin.NewService(&memoryAdminDAO{}, adminIDs),
+		Webhooks: webhooks,
+	})
+	return router
+}
+
+func TestAdminEntitlementWebhookRoutes(t *testing.T) {
+	do := func(router http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+		var raw []byte
+		if body != nil {
+			var err error
+			if raw, err = json.Marshal(body); err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+		}
+		req := newAuthorizedUserRequest(t, method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(newAdminWebhookTestRouter(t, &stubWebhookSvc{}, 42), http.MethodGet, "/admin/webhooks/subscribers", nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin status = %d, want 403; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := do(newAdminWebhookTestRouter(t, nil, 1), http.MethodGet, "/admin/webhooks/subscribers", nil); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("nil service status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+
+	svc := &stubWebhookSvc{}
+	router := newAdminWebhookTestRouter(t, svc, 1)
+	rec := do(router, http.MethodGet, "/admin/webhooks/subscribers", nil)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "whsec_hidden") || !strings.Contains(rec.Body.String(), `"event_types":["subscription.activated"]`) {
+		t.Fatalf("list status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+
+	req := map[string]any{"name": "billing", "url": "https://billing.example.com", "event_types": []string{"subscription.deactivated"}}
+	rec = do(router, http.MethodPost, "/admin/webhooks/subscribers", req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"secret":"whsec_generated"`) || !svc.created.Active || svc.actorID != 1 {
+		t.Fatalf("create status = %d, in=%+v; body=%s", rec.Code, svc.created, rec.Body.String())
+	}
+	bad := map[string]any{"name": "billing", "url": "https://billing.example.com", "event_types": []string{"subscription.created"}}
+	if rec := do(router, http.MethodPost, "/admin/webhooks/subscribers", bad); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown event type status = %d, want 422; body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = do(router, http.MethodPut, "/admin/webhooks/subscribers/1", req)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "whsec_hidden") {
+		t.Fatalf("update status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := do(router, http.MethodPut, "/admin/webhooks/subscribers/9", req); rec.Code != http.StatusNotFound {
+		t.Fatalf("update missing status = %d, want 404; body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = do(router, http.MethodGet, "/admin/webhooks/deliveries?subscriber_id=1&status=DEAD&cursor=abc", nil)
+	if rec.Code != http.StatusOK || svc.filter.SubscriberID != 1 || svc.filter.Status != model.WebhookDeliveryDead || svc.cursor != "abc" {
+		t.Fatalf("deliveries status = %d, filter=%+v; body=%s", rec.Code, svc.filter, rec.Body.String())
+	}
+	got := rec.Body.String()
+	for _, want := range []string{`"id":"5","event_id":"4","subscriber_id":"1"`, `"user_id":"42","status":"DEAD","attempts":10`, `"next_cursor":"next"`} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("deliveries body missing %s: %s", want, got)
+		}
+	}
+	if strings.Contains(got, "next_attempt_at") {
+		t.Fatalf("dead delivery should not expose next_attempt_at: %s", got)
+	}
+	if rec := do(router, http.MethodGet, "/admin/webhooks/deliveries?cursor=bad", nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad cursor status = %d, want 400; body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = do(router, http.MethodPost, "/admin/webhooks/deliveries/5/replay", nil)
+	if rec.Code != http.StatusOK || len(svc.replayed) != 1 || !strings.Contains(rec.Body.String(), `"replayed":1`) {
+		t.Fatalf("replay status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := do(router, http.MethodPost, "/admin/webhooks/deliveries/6/replay", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("replay missing status = %d, want 404; body=%s", rec.Code, rec.Body.String())
+	}
+	rec = do(router, http.MethodPost, "/admin/webhooks/subscribers/1/replay-dead", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"replayed":3`) {
+		t.Fatalf("replay dead status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+// AdminPaywallExperimentService 是 /admin/paywall/experiments 路由所需的最小服务接口。
+//
+// 生产实现由 internal/service/payment.PaywallExperimentService 提供；为 nil 时路由返回 503。
+type AdminPaywallExperimentService interface {
+	Results(ctx context.Context, experimentID string) (payment.PaywallExperimentResults, error)
+}
+
+func registerAdminPaywallExperimentRoutes(api huma.API, deps AdminDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-get-paywall-experiment-results",
+		Method:      http.MethodGet,
+		Path:        "/admin/paywall/experiments/{id}/results",
+		Summary:     "查看付费墙实验结果",
+		Description: "按分组返回付费墙实验的曝光人数与转化人数。曝光按用户去重，同一用户始终留在首次曝光时的分组；曝光后在 APPLE_IAP_ENTITLEMENT_ENVIRONMENTS 内创建了 Apple 订阅的用户记为转化。\n\n已从 PAYWALL_EXPERIMENTS 中移除的实验或分组仍可查询历史数据；实验既不在配置中也没有曝光记录时返回 404。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusNotFound,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		ID            string `path:"id" doc:"实验 ID" example:"annual_first"`
+	}) (*struct {
+		Body model.Response[model.AdminPaywallExperimentResultsResponse]
+	}, error) {
+		if _, err := adminActorID(ctx, deps, input.Authorization); err != nil {
+			return nil, err
+		}
+		if deps.PaywallExperiments == nil {
+			return nil, huma.Error503ServiceUnavailable("付费墙实验未配置")
+		}
+		res, err := deps.PaywallExperiments.Results(ctx, input.ID)
+		if err != nil {
+			return nil, mapAdminPaywallExperimentError(err)
+		}
+		out := model.AdminPaywallExperimentResultsResponse{
+			ExperimentID: res.ExperimentID,
+			Active:       res.Active,
+			Variants:     make([]model.AdminPaywallVariantResultView, 0, len(res.Variants)),
+		}
+		for _, v := range res.Variants {
+			view := model.AdminPaywallVariantResultView{
+				VariantID:   v.VariantID,
+				Weight:      v.Weight,
+				Exposures:   v.Exposures,
+				Conversions: v.Conversions,
+			}
+			if v.Exposures > 0 {
+				view.ConversionRate = float64(v.Conversions) / float64(v.Exposures)
+			}
+			out.Variants = append(out.Variants, view)
+		}
+		return &struct {
+			Body model.Response[model.AdminPaywallExperimentResultsResponse]
+		}{
+			Body: model.Success(out),
+		}, nil
+	})
+}
+
+func mapAdminPaywallExperimentError(err error) error {
+	switch {
+	case errors.Is(err, payment.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("付费墙实验未配置")
+	case errors.Is(err, payment.ErrPaywallExperimentNotFound):
+		return huma.Error404NotFound("实验不存在")
+	default:
+		return huma.Error500InternalServerError("获取实验结果失败")
+	}
+}
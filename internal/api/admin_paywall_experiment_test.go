@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/admin"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+type stubPaywallExperimentSvc struct {
+	experimentID string
+	err          error
+}
+
+func (s *stubPaywallExperimentSvc) Results(_ context.Context, experimentID string) (payment.PaywallExperimentResults, error) {
+	s.experimentID = experimentID
+	if s.err != nil {
+		return payment.PaywallExperimentResults{}, s.err
+	}
+	return payment.PaywallExperimentResults{ExperimentID: experimentID, Active: true, Variants: []payment.PaywallVariantResult{
+		{VariantID: "control", Weight: 50, Exposures: 200, Conversions: 10},
+		{VariantID: "annual_first", Weight: 50},
+	}}, nil
+}
+
+func newAdminPaywallExperimentTestRouter(t testing.TB, experiments AdminPaywallExperimentService, adminIDs ...int64) http.Handler {
+	t.Helper()
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterAdminRoutes(api, AdminDeps{
+		Auth:               newTestAuthService(t, service.NewMemoryUserService()),
+		Users:              admin.NewService(&memoryAdminDAO{}, adminIDs),
+		PaywallExperiments: experiments,
+	})
+	return router
+}
+
+func TestAdminPaywallExperimentResultsRoute(t *testing.T) {
+	const path = "/admin/paywall/experiments/annual_first/results"
+	do := func(router http.Handler) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := do(newAdminPaywallExperimentTestRouter(t, &stubPaywallExperimentSvc{}, 42)); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin status = %d, want 403; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := do(newAdminPaywallExperimentTestRouter(t, nil, 1)); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("nil service status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := do(newAdminPaywallExperimentTestRouter(t, &stubPaywallExperimentSvc{err: payment.ErrPaywallExperimentNotFound}, 1)); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing experiment status = %d, want 404; body=%s", rec.Code, rec.Body.String())
+	}
+
+	svc := &stubPaywallExperimentSvc{}
+	rec := do(newAdminPaywallExperimentTestRouter(t, svc, 1))
+	if rec.Code != http.StatusOK || svc.experimentID != "annual_first" {
+		t.Fatalf("status = %d, call=%+v; body=%s", rec.Code, svc, rec.Body.String())
+	}
+	got := rec.Body.String()
+	for _, want := range []string{
+		`"active":true`,
+		`"variant_id":"control","weight":50,"exposures":200,"conversions":10,"conversion_rate":0.05`,
+		`"variant_id":"annual_first","weight":50,"exposures":0,"conversions":0,"conversion_rate":0`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("body missing %s: %s", want, got)
+		}
+	}
+}
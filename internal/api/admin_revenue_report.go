@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+	logpkg "github.com/dundunHa/go-serverhttp-template/pkg/log"
+)
+
+// AdminRevenueReportService 是 /admin/reports/revenue 路由所需的最小服务接口。
+//
+// 生产实现由 internal/service/payment.RevenueReportService 提供；为 nil 时路由返回 503。
+type AdminRevenueReportService interface {
+	Report(ctx context.Context, req payment.RevenueReportRequest) (payment.RevenueReport, error)
+}
+
+type adminRevenueReportInput struct {
+	Authorization string `header:"Authorization" hidden:"true"`
+	From          string `query:"from" required:"true" doc:"报表开始时间（RFC3339），向下对齐到周期开始" example:"2026-01-01T00:00:00Z"`
+	To            string `query:"to" doc:"报表结束时间（RFC3339，不含），向上对齐到周期结束；省略为当前时间" example:"2026-07-01T00:00:00Z"`
+	Granularity   string `query:"granularity" enum:"day,week,month" default:"month" doc:"周期粒度，按 UTC 切分，周从周一开始"`
+	Environment   string `query:"environment" doc:"只统计这些 Apple 环境，逗号分隔；省略为全部" example:"Production"`
+}
+
+const adminRevenueReportDescription = "按周期、环境、plan 统计订阅指标：周期结束时的有效订阅数与 MRR，以及周期内的新订阅、续期、流失、退款、重新激活与免费试用转化。\n\n" +
+	"指标由 apple_events 通知历史按时间顺序重放得出，同一时间范围重复查询结果一致。MRR 按 apple_products 全表（含已下架商品）中的参考价格（price_milliunits / currency / billing_period）折算为月金额，试用中的订阅不计入；消耗型与非消耗型商品不参与统计。"
+
+func registerAdminRevenueReportRoutes(api huma.API, deps AdminDeps) {
+	errs := []int{
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-get-revenue-report",
+		Method:      http.MethodGet,
+		Path:        "/admin/reports/revenue",
+		Summary:     "订阅收入与流失报表",
+		Description: adminRevenueReportDescription,
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors:      errs,
+	}, func(ctx context.Context, input *adminRevenueReportInput) (*struct {
+		Body model.Response[model.RevenueReportResponse]
+	}, error) {
+		report, err := adminRevenueReport(ctx, deps, input, "json")
+		if err != nil {
+			return nil, err
+		}
+		return &struct {
+			Body model.Response[model.RevenueReportResponse]
+		}{
+			Body: model.Success(report.Response()),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-get-revenue-report-csv",
+		Method:      http.MethodGet,
+		Path:        "/admin/reports/revenue.csv",
+		Summary:     "订阅收入与流失报表（CSV）",
+		Description: adminRevenueReportDescription + "\n\n与 /admin/reports/revenue 参数相同，以 CSV 下载，列与 JSON 的 rows 字段一致。",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors:      errs,
+	}, func(ctx context.Context, input *adminRevenueReportInput) (*struct {
+		ContentType        string `header:"Content-Type"`
+		ContentDisposition string `header:"Content-Disposition"`
+		Body               []byte
+	}, error) {
+		report, err := adminRevenueReport(ctx, deps, input, "csv")
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := report.WriteCSV(&buf); err != nil {
+			return nil, huma.Error500InternalServerError("生成报表失败")
+		}
+		return &struct {
+			ContentType        string `header:"Content-Type"`
+			ContentDisposition string `header:"Content-Disposition"`
+			Body               []byte
+		}{
+			ContentType:        "text/csv; charset=utf-8",
+			ContentDisposition: `attachment; filename="revenue-` + report.Granularity + "-" + report.From.Format("20060102") + "-" + report.To.Format("20060102") + `.csv"`,
+			Body:               buf.Bytes(),
+		}, nil
+	})
+}
+
+// adminRevenueReport 校验管理员身份与参数后生成报表，并记录一条审计日志。
+func adminRevenueReport(ctx context.Context, deps AdminDeps, input *adminRevenueReportInput, format string) (payment.RevenueReport, error) {
+	actorID, err := adminActorID(ctx, deps, input.Authorization)
+	if err != nil {
+		return payment.RevenueReport{}, err
+	}
+	if deps.Revenue == nil {
+		return payment.RevenueReport{}, huma.Error503ServiceUnavailable("收入报表未配置")
+	}
+	req := payment.RevenueReportRequest{Granularity: input.Granularity, To: time.Now().UTC()}
+	if req.From, err = time.Parse(time.RFC3339, input.From); err != nil {
+		return payment.RevenueReport{}, huma.Error400BadRequest("from 必须是 RFC3339 时间")
+	}
+	if input.To != "" {
+		if req.To, err = time.Parse(time.RFC3339, input.To); err != nil {
+			return payment.RevenueReport{}, huma.Error400BadRequest("to 必须是 RFC3339 时间")
+		}
+	}
+	for _, env := range strings.Split(input.Environment, ",") {
+		if env = strings.TrimSpace(env); env != "" {
+			req.Environments = append(req.Environments, payment.Environment(env))
+		}
+	}
+
+	report, err := deps.Revenue.Report(ctx, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrInvalidReportRange):
+			return payment.RevenueReport{}, huma.Error400BadRequest("时间范围不合法：from 必须早于 to，且周期数不超过 1000")
+		case errors.Is(err, payment.ErrNotConfigured):
+			return payment.RevenueReport{}, huma.Error503ServiceUnavailable("收入报表未配置")
+		default:
+			logpkg.FromContext(ctx).ErrorContext(ctx, "admin revenue report failed", "actor_user_id", actorID, "err", err)
+			return payment.RevenueReport{}, huma.Error500InternalServerError("生成报表失败")
+		}
+	}
+	logpkg.FromContext(ctx).InfoContext(ctx, "admin revenue report",
+		"actor_user_id", actorID,
+		"from", report.From,
+		"to", report.To,
+		"granularity", report.Granularity,
+		"environments", req.Environments,
+		"format", format,
+		"rows", len(report.Rows),
+	)
+	return report, nil
+}
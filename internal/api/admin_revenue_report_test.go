@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/admin"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+type stubRevenueReportSvc struct {
+	req payment.RevenueReportRequest
+	err error
+}
+
+func (s *stubRevenueReportSvc) Report(_ context.Context, req payment.RevenueReportRequest) (payment.RevenueReport, error) {
+	s.req = req
+	if s.err != nil {
+		return payment.RevenueReport{}, s.err
+	}
+	oct, nov := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	return payment.RevenueReport{From: oct, To: nov, Granularity: req.Granularity, Rows: []payment.RevenueRow{
+		{PeriodStart: oct, PeriodEnd: nov, Environment: "Production", PlanID: "pro_yearly",
+			Active: 10, New: 4, TrialConverted: 1, TrialExpired: 3, MRRMilliunits: 83325, Currency: "USD"},
+	}}, nil
+}
+
+func newAdminRevenueReportTestRouter(t testing.TB, revenue AdminRevenueReportService, adminIDs ...int64) http.Handler {
+	t.Helper()
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterAdminRoutes(api, AdminDeps{
+		Auth:    newTestAuthService(t, service.NewMemoryUserService()),
+		Users:   admin.NewService(&memoryAdminDAO{}, adminIDs),
+		Revenue: revenue,
+	})
+	return router
+}
+
+func TestAdminRevenueReportRoute(t *testing.T) {
+	const query = "?from=2026-10-01T00:00:00Z&to=2026-11-01T00:00:00Z&environment=Production,%20Sandbox"
+	do := func(router http.Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := do(newAdminRevenueReportTestRouter(t, &stubRevenueReportSvc{}, 42), "/admin/reports/revenue"+query); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin status = %d, want 403; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := do(newAdminRevenueReportTestRouter(t, nil, 1), "/admin/reports/revenue"+query); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("nil service status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := do(newAdminRevenueReportTestRouter(t, &stubRevenueReportSvc{}, 1), "/admin/reports/revenue?from=yesterday"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad from status = %d, want 400; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := do(newAdminRevenueReportTestRouter(t, &stubRevenueReportSvc{err: payment.ErrInvalidReportRange}, 1), "/admin/reports/revenue"+query); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid range status = %d, want 400; body=%s", rec.Code, rec.Body.String())
+	}
+
+	svc := &stubRevenueReportSvc{}
+	rec := do(newAdminRevenueReportTestRouter(t, svc, 1), "/admin/reports/revenue"+query)
+	if rec.Code != http.StatusOK || svc.req.Granularity != "month" || len(svc.req.Environments) != 2 || svc.req.Environments[1] != "Sandbox" ||
+		!svc.req.To.Equal(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("status = %d, req=%+v; body=%s", rec.Code, svc.req, rec.Body.String())
+	}
+	got := rec.Body.String()
+	for _, want := range []string{
+		`"granularity":"month"`,
+		`"plan_id":"pro_yearly","active":10,"new":4`,
+		`"trial_conversion_rate":0.25,"mrr_milliunits":83325,"currency":"USD"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("body missing %s: %s", want, got)
+		}
+	}
+
+	rec = do(newAdminRevenueReportTestRouter(t, &stubRevenueReportSvc{}, 1), "/admin/reports/revenue.csv"+query+"&granularity=week")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") ||
+		!strings.Contains(rec.Header().Get("Content-Disposition"), `revenue-week-20261001-20261101.csv`) {
+		t.Fatalf("csv status = %d, headers=%v; body=%s", rec.Code, rec.Header(), rec.Body.String())
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "period_start,") ||
+		lines[1] != "2026-10-01T00:00:00Z,2026-11-01T00:00:00Z,Production,pro_yearly,10,4,0,0,0,0,0,1,3,0.2500,83325,USD" {
+		t.Fatalf("csv body = %q", rec.Body.String())
+	}
+}
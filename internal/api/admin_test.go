@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/admin"
+)
+
+type memoryAdminDAO struct {
+	users map[int64]model.AdminUser
+}
+
+func (d *memoryAdminDAO) SearchUsers(_ context.Context, field model.AdminSearchField, query string, afterID int64, limit int) ([]model.AdminUser, error) {
+	out := []model.AdminUser{}
+	for _, u := range d.users {
+		if field == model.AdminSearchByID && query == "1" && u.ID == 1 && u.ID > afterID && len(out) < limit {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+func (d *memoryAdminDAO) GetUserDetail(_ context.Context, userID int64, _ int) (model.AdminUserDetail, error) {
+	u, ok := d.users[userID]
+	if !ok {
+		return model.AdminUserDetail{}, admin.ErrUserNotFound
+	}
+	return model.AdminUserDetail{User: u}, nil
+}
+
+func (d *memoryAdminDAO) SetUserStatus(_ context.Context, userID int64, status, reason string, expiresAt *time.Time, revokeSessions bool) (model.AccountState, error) {
+	u, ok := d.users[userID]
+	if !ok {
+		return model.AccountState{}, admin.ErrUserNotFound
+	}
+	u.Status, u.StatusReason, u.StatusExpiresAt = status, reason, expiresAt
+	if revokeSessions {
+		now := time.Now()
+		u.SessionsRevokedAt = &now
+	}
+	d.users[userID] = u
+	return model.AccountState{UserID: userID, Status: u.Status, StatusReason: u.StatusReason, StatusExpiresAt: u.StatusExpiresAt, SessionsRevokedAt: u.SessionsRevokedAt}, nil
+}
+
+func (d *memoryAdminDAO) RevokeUserSessions(_ context.Context, userID int64) (model.AccountState, error) {
+	u := d.users[userID]
+	return d.SetUserStatus(context.Background(), userID, u.Status, u.StatusReason, u.StatusExpiresAt, true)
+}
+
+func newAdminTestRouter(t testing.TB, adminIDs ...int64) (http.Handler, *memoryAdminDAO) {
+	t.Helper()
+	dao := &memoryAdminDAO{users: map[int64]model.AdminUser{
+		1: {ID: 1, Name: "Ada", Status: model.UserStatusActive, CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}}
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterAdminRoutes(api, AdminDeps{
+		Auth:  newTestAuthService(t, service.NewMemoryUserService()),
+		Users: admin.NewService(dao, adminIDs),
+	})
+	return router, dao
+}
+
+func TestAdminRoutesRejectNonAdmin(t *testing.T) {
+	router, _ := newAdminTestRouter(t, 42)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/admin/users?field=id&q=1", nil))
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403; body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAdminRoutesRequireBearerToken(t *testing.T) {
+	router, _ := newAdminTestRouter(t, 1)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/users?field=id&q=1", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401; body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAdminRoutesSearchUsers(t *testing.T) {
+	router, _ := newAdminTestRouter(t, 1)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/admin/users?field=id&q=1", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	var got struct {
+		Data model.AdminUserSearchResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got.Data.Users) != 1 || got.Data.Users[0].ID != "1" || got.Data.Users[0].CreatedAt != "2026-01-02T03:04:05Z" {
+		t.Fatalf("unexpected users: %+v", got.Data.Users)
+	}
+}
+
+func TestAdminRoutesUserDetailNotFound(t *testing.T) {
+	router, _ := newAdminTestRouter(t, 1)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/admin/users/404", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404; body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAdminRoutesSuspendUser(t *testing.T) {
+	router, dao := newAdminTestRouter(t, 1)
+	req := newAuthorizedUserRequest(t, http.MethodPost, "/admin/users/1/suspend", strings.NewReader(`{"reason":"chargeback fraud"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	var got struct {
+		Data model.AdminAccountStateView `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Data.Status != model.UserStatusSuspended || got.Data.SessionsRevokedAt == "" {
+		t.Fatalf("unexpected state: %+v", got.Data)
+	}
+	if dao.users[1].StatusReason != "chargeback fraud" {
+		t.Fatalf("reason not stored: %+v", dao.users[1])
+	}
+}
+
+func TestAdminRoutesApplePayloadsNotConfigured(t *testing.T) {
+	router, _ := newAdminTestRouter(t, 1)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/admin/apple/payloads?original_transaction_id=ot-1", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+}
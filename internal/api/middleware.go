@@ -6,6 +6,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"runtime/debug"
 	"strings"
 	"time"
@@ -119,8 +120,8 @@ func Logging() func(http.Handler) http.Handler {
 				"Started Request",
 				"phase", "start",
 				"method", r.Method,
-				"uri", r.RequestURI,
-				"query", r.URL.Query(),
+				"uri", redactRequestURI(r),
+				"query", redactSensitiveQuery(r.URL.Query()),
 				"body", requestBody,
 			)
 
@@ -194,6 +195,28 @@ func readRequestBodyForLog(r *http.Request) string {
 	return redactSensitiveJSON(string(bodyBytes))
 }
 
+// redactSensitiveQuery 返回脱敏后的 query 拷贝（如 Google Play webhook 的 ?token=）。
+func redactSensitiveQuery(query url.Values) url.Values {
+	out := make(url.Values, len(query))
+	for key, values := range query {
+		if isSensitiveField(key) {
+			out[key] = []string{"[REDACTED]"}
+			continue
+		}
+		out[key] = values
+	}
+	return out
+}
+
+func redactRequestURI(r *http.Request) string {
+	if r.URL.RawQuery == "" {
+		return r.RequestURI
+	}
+	redacted := *r.URL
+	redacted.RawQuery = redactSensitiveQuery(r.URL.Query()).Encode()
+	return redacted.RequestURI()
+}
+
 func redactSensitiveJSON(value string) string {
 	if value == "" {
 		return ""
@@ -239,6 +262,7 @@ func isSensitiveField(field string) bool {
 		"raw_payload", "rawpayload",
 		"decoded_payload", "decodedpayload",
 		"appaccounttoken", "app_account_token",
+		"purchasetoken", "purchase_token",
 		"private_key", "privatekey",
 		"apple_iap_private_key",
 		"p8", "p8_path", "p8path",
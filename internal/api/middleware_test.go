@@ -173,6 +173,36 @@ func TestLoggingRestoresRequestBodyAndRedactsLogs(t *testing.T) {
 	t.Fatalf("request log was not emitted: %s", logs.String())
 }
 
+func TestLoggingRedactsSensitiveQueryParams(t *testing.T) {
+	var logs bytes.Buffer
+	root := slog.New(slog.NewJSONHandler(&logs, nil))
+	handler := chiMw.RequestID(InjectRootLogger(root)(Logging()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "rtdn-secret" {
+			t.Fatalf("handler saw token %q", r.URL.Query().Get("token"))
+		}
+		w.WriteHeader(http.StatusOK)
+	}))))
+
+	handler.ServeHTTP(
+		httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodPost, "/webhooks/google-play?token=rtdn-secret&v=1", strings.NewReader(`{}`)),
+	)
+
+	if strings.Contains(logs.String(), "rtdn-secret") {
+		t.Fatalf("query token leaked into logs: %s", logs.String())
+	}
+	for _, entry := range jsonLogEntries(t, logs.String()) {
+		if entry["msg"] == "Started Request" {
+			uri, _ := entry["uri"].(string)
+			if !strings.Contains(uri, "/webhooks/google-play?") || !strings.Contains(uri, "v=1") {
+				t.Fatalf("unexpected uri: %q", uri)
+			}
+			return
+		}
+	}
+	t.Fatalf("request log was not emitted: %s", logs.String())
+}
+
 func TestLoggingDoesNotLogLargeRequestOrResponseBodies(t *testing.T) {
 	var logs bytes.Buffer
 	root := slog.New(slog.NewJSONHandler(&logs, nil))
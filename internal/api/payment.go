@@ -20,7 +20,7 @@ type PaymentTokenService interface {
 
 // PaymentIAPService 是 verify 路由所需的最小服务接口。
 type PaymentIAPService interface {
-	VerifyTransaction(ctx context.Context, userID int64, transactionID string) (*model.SubscriptionInfo, error)
+	VerifyTransactionWithProof(ctx context.Context, userID int64, transactionID string, proof payment.FamilyShareProof) (*payment.VerifyResult, error)
 }
 
 // PaymentWebhookService 是 webhook 路由所需的最小服务接口。
@@ -35,14 +35,35 @@ type PaymentDeps struct {
 	Tokens  PaymentTokenService
 	IAP     PaymentIAPService
 	Webhook PaymentWebhookService
+
+	AppleOffers  PaymentAppleOfferService
+	AppleRestore PaymentAppleRestoreService
+
+	// Paywall 为 nil 时 /payment/products 返回 503；Entitlements 为 nil 时不标记 current。
+	Paywall      PaymentPaywallService
+	Entitlements EntitlementReader
+
+	GooglePlay        PaymentGooglePlayService
+	GooglePlayWebhook PaymentGooglePlayWebhookService
+
+	Stripe        PaymentStripeService
+	StripeWebhook PaymentStripeWebhookService
 }
 
-// RegisterPaymentRoutes 注册 /payment/* 与 /webhooks/apple 路由。
+// RegisterPaymentRoutes 注册 /payment/* 与 /webhooks/{apple,google-play,stripe} 路由。
 func RegisterPaymentRoutes(api huma.API, deps PaymentDeps) {
 	registerPaymentDocMetadata(api)
 	registerAccountTokenRoute(api, deps)
 	registerVerifyRoute(api, deps)
+	registerAppleOfferSignatureRoute(api, deps)
+	registerAppleRestoreRoute(api, deps)
+	registerPaywallRoute(api, deps)
 	registerWebhookRoute(api, deps)
+	registerGooglePlayVerifyRoute(api, deps)
+	registerGooglePlayWebhookRoute(api, deps)
+	registerStripeCheckoutRoute(api, deps)
+	registerStripePortalRoute(api, deps)
+	registerStripeWebhookRoute(api, deps)
 }
 
 func registerPaymentDocMetadata(api huma.API) {
@@ -54,7 +75,7 @@ func registerPaymentDocMetadata(api huma.API) {
 	}
 	openapi.Tags = append(openapi.Tags, &huma.Tag{
 		Name:        "payment",
-		Description: "Apple In-App Purchase 订阅相关接口（账号 token / 校验购买 / Webhook）。",
+		Description: "Apple In-App Purchase、Google Play Billing 与 Stripe web 支付相关接口（付费墙商品 / 账号 token / 校验订阅与一次性购买 / 恢复购买 / 优惠签名 / Webhook）。",
 	})
 }
 
@@ -102,12 +123,15 @@ func registerAccountTokenRoute(api huma.API, deps PaymentDeps) {
 
 // VerifyAppleTransactionRequest 是 POST /payment/apple/verify 的请求体。
 type VerifyAppleTransactionRequest struct {
-	TransactionID string `json:"transaction_id" doc:"Apple StoreKit purchase 完成时返回的 transactionId" required:"true" minLength:"1" example:"200000123456789"`
+	TransactionID     string `json:"transaction_id" doc:"Apple StoreKit purchase 完成时返回的 transactionId" required:"true" minLength:"1" example:"200000123456789"`
+	SignedTransaction string `json:"signed_transaction,omitempty" doc:"认领家庭共享订阅时必填：设备上 StoreKit 返回的 Transaction.jwsRepresentation"`
+	DeviceID          string `json:"device_id,omitempty" doc:"认领家庭共享订阅时必填：本机 identifierForVendor（UUID）" example:"9B1DEB4D-3B7D-4BAD-9BDD-2B0D7B3DCB6D"`
 }
 
 // VerifyAppleTransactionResponse 是 POST /payment/apple/verify 的响应负载。
 type VerifyAppleTransactionResponse struct {
-	SubscriptionInfo model.SubscriptionInfo `json:"subscription_info" doc:"刚刚校验完成的订阅状态，已经写入 apple_subscriptions"`
+	SubscriptionInfo *model.SubscriptionInfo `json:"subscription_info,omitempty" doc:"刚刚校验完成的订阅状态，已经写入 apple_subscriptions；非消耗型买断返回终身权益（无到期时间），消耗型商品省略"`
+	Purchase         *model.PurchaseInfo     `json:"purchase,omitempty" doc:"一次性购买（消耗型 / 非消耗型）的入账结果；自动续期订阅省略"`
 }
 
 func registerVerifyRoute(api huma.API, deps PaymentDeps) {
@@ -115,8 +139,8 @@ func registerVerifyRoute(api huma.API, deps PaymentDeps) {
 		OperationID: "verify-apple-transaction",
 		Method:      http.MethodPost,
 		Path:        "/payment/apple/verify",
-		Summary:     "校验 Apple IAP 购买并写入订阅状态",
-		Description: "客户端在 StoreKit 购买成功后立即调用本接口提交 transactionId。服务端通过 App Store Server API 拉取并验证签名后的 transaction，校验 bundle、产品 catalog、appAccountToken 与当前用户的绑定关系，并幂等写入 apple_subscriptions。\n\n返回值与 GET /users/me 中的 SubscriptionInfo 字段同形：客户端可以据此立即刷新本地订阅 UI，而不需要再发起一次 /users/me。",
+		Summary:     "校验 Apple IAP 购买并写入订阅 / 购买记录",
+		Description: "客户端在 StoreKit 购买成功后立即调用本接口提交 transactionId。服务端通过 App Store Server API 拉取并验证签名后的 transaction，校验 bundle、产品 catalog、appAccountToken 与当前用户的绑定关系，并幂等写入 apple_subscriptions（自动续期订阅）或 apple_purchases（消耗型 / 非消耗型）。\n\nFamily Sharing 获得的订阅不带 appAccountToken：家庭成员需同时提交设备上的 signed_transaction 与本机 identifierForVendor（device_id），服务端校验 JWS 签名与其中的 deviceVerification 后才认领该共享订阅，缺少或不匹配返回 400；权益单独记录在 apple_family_shares，同一共享订阅只能被一个用户认领，其他用户提交返回 409。\n\n返回值中的 subscription_info 与 GET /users/me 中的 SubscriptionInfo 字段同形：客户端可以据此立即刷新本地订阅 UI，而不需要再发起一次 /users/me。消耗型商品按 catalog 配置发放积分，同一 transactionId 无论提交多少次（包括与 webhook 并发到达）只入账一次。",
 		Tags:        []string{"payment"},
 		Security:    []map[string][]string{{"bearerAuth": {}}},
 		Errors: []int{
@@ -146,14 +170,20 @@ func registerVerifyRoute(api huma.API, deps PaymentDeps) {
 		if deps.IAP == nil {
 			return nil, huma.Error503ServiceUnavailable("Apple IAP 未配置")
 		}
-		info, err := deps.IAP.VerifyTransaction(ctx, userID, input.Body.TransactionID)
+		result, err := deps.IAP.VerifyTransactionWithProof(ctx, userID, input.Body.TransactionID, payment.FamilyShareProof{
+			SignedTransaction: input.Body.SignedTransaction,
+			DeviceID:          input.Body.DeviceID,
+		})
 		if err != nil {
 			return nil, mapVerifyError(err)
 		}
 		return &struct {
 			Body model.Response[VerifyAppleTransactionResponse]
 		}{
-			Body: model.Success(VerifyAppleTransactionResponse{SubscriptionInfo: *info}),
+			Body: model.Success(VerifyAppleTransactionResponse{
+				SubscriptionInfo: result.Subscription,
+				Purchase:         result.Purchase,
+			}),
 		}, nil
 	})
 }
@@ -168,9 +198,14 @@ func mapVerifyError(err error) error {
 		errors.Is(err, payment.ErrUnsupportedProductType),
 		errors.Is(err, payment.ErrTransactionRevoked),
 		errors.Is(err, payment.ErrAppleTransactionNotFound),
+		errors.Is(err, payment.ErrInvalidSignedTransaction),
+		errors.Is(err, payment.ErrFamilyShareProofRequired),
+		errors.Is(err, payment.ErrFamilyShareProofMismatch),
 		errors.Is(err, payment.ErrInvalidConfig):
 		return huma.Error400BadRequest(err.Error())
-	case errors.Is(err, payment.ErrSubscriptionOwnershipConflict):
+	case errors.Is(err, payment.ErrSubscriptionOwnershipConflict),
+		errors.Is(err, payment.ErrPurchaseOwnershipConflict),
+		errors.Is(err, payment.ErrFamilyShareOwnershipConflict):
 		return huma.Error409Conflict(err.Error())
 	case errors.Is(err, payment.ErrAppleAuthRejected):
 		return huma.Error500InternalServerError("Apple API 鉴权失败")
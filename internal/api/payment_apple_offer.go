@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+// PaymentAppleOfferService 是 offer-signature 路由所需的最小服务接口。
+type PaymentAppleOfferService interface {
+	SignOffer(ctx context.Context, userID int64, productID, offerID string) (*payment.OfferSignature, error)
+}
+
+// AppleOfferSignatureRequest 是 POST /payment/apple/offer-signature 的请求体。
+type AppleOfferSignatureRequest struct {
+	ProductID string `json:"product_id" doc:"要兑换优惠的 Apple 订阅 product id" required:"true" minLength:"1" example:"com.app.pro.monthly"`
+	OfferID   string `json:"offer_id" doc:"App Store Connect 中配置的优惠 identifier" required:"true" minLength:"1" example:"winback_50"`
+}
+
+// AppleOfferSignatureResponse 是 POST /payment/apple/offer-signature 的响应负载，字段与 StoreKit
+// Product.SubscriptionOffer.Signature / SKPaymentDiscount 的参数一一对应。
+type AppleOfferSignatureResponse struct {
+	ProductID       string `json:"product_id" doc:"Apple 订阅 product id" example:"com.app.pro.monthly"`
+	OfferID         string `json:"offer_id" doc:"优惠 identifier" example:"winback_50"`
+	KeyID           string `json:"key_id" doc:"签名使用的 App Store key id" example:"ABC123DEFG"`
+	AppAccountToken string `json:"app_account_token" doc:"参与签名的 appAccountToken，购买时必须原样提交" example:"00000000-0000-4000-8000-000000000042"`
+	Nonce           string `json:"nonce" doc:"一次性 UUID（小写）" example:"6f1c7c1e-3c0b-4b8e-9d7a-2f0a8f7c9e11"`
+	Timestamp       int64  `json:"timestamp" doc:"签名时间（Unix 毫秒）" example:"1767225600000"`
+	Signature       string `json:"signature" doc:"base64 编码的 ECDSA 签名" example:"MEUCIQ..."`
+}
+
+func registerAppleOfferSignatureRoute(api huma.API, deps PaymentDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "create-apple-offer-signature",
+		Method:      http.MethodPost,
+		Path:        "/payment/apple/offer-signature",
+		Summary:     "生成 Apple 订阅促销 / 挽回优惠签名",
+		Description: "StoreKit 展示促销优惠（promotional offer）或挽回优惠（win-back offer）前调用。服务端按 APPLE_IAP_OFFERS 中的规则校验当前用户的资格：promotional 要求当前或曾经订阅过同一订阅组，win_back 要求曾经订阅且当前没有有效订阅，配置了 max_per_user 时超过签发次数也视为不符合资格（403）。\n\n签名覆盖 bundle id、key id、product id、offer id、appAccountToken、nonce 与 timestamp，使用 App Store key 生成。每次签发都会记录到 apple_offer_signatures 用于审计。",
+		Tags:        []string{"payment"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusNotFound,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Body          AppleOfferSignatureRequest
+	}) (*struct {
+		Body model.Response[AppleOfferSignatureResponse]
+	}, error) {
+		authedUser, err := validateUserBearerToken(ctx, deps.Auth, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		userID, perr := strconv.ParseInt(authedUser.ID, 10, 64)
+		if perr != nil || userID <= 0 {
+			return nil, huma.Error401Unauthorized("access token 无效")
+		}
+		if input.Body.ProductID == "" || input.Body.OfferID == "" {
+			return nil, huma.Error400BadRequest("product_id 与 offer_id 不能为空")
+		}
+		if deps.AppleOffers == nil {
+			return nil, huma.Error503ServiceUnavailable("Apple IAP 未配置")
+		}
+		sig, err := deps.AppleOffers.SignOffer(ctx, userID, input.Body.ProductID, input.Body.OfferID)
+		if err != nil {
+			return nil, mapAppleOfferError(err)
+		}
+		return &struct {
+			Body model.Response[AppleOfferSignatureResponse]
+		}{
+			Body: model.Success(AppleOfferSignatureResponse{
+				ProductID:       sig.ProductID,
+				OfferID:         sig.OfferID,
+				KeyID:           sig.KeyID,
+				AppAccountToken: sig.AppAccountToken,
+				Nonce:           sig.Nonce,
+				Timestamp:       sig.Timestamp,
+				Signature:       sig.Signature,
+			}),
+		}, nil
+	})
+}
+
+func mapAppleOfferError(err error) error {
+	switch {
+	case errors.Is(err, payment.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("Apple IAP 未配置")
+	case errors.Is(err, payment.ErrUnknownOffer):
+		return huma.Error404NotFound("优惠不存在")
+	case errors.Is(err, payment.ErrOfferNotEligible):
+		return huma.Error403Forbidden("当前用户不符合该优惠的资格")
+	default:
+		return huma.Error500InternalServerError("生成优惠签名失败")
+	}
+}
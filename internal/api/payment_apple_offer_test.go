@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+type stubAppleOfferSvc struct {
+	userID             int64
+	productID, offerID string
+	err                error
+}
+
+func (s *stubAppleOfferSvc) SignOffer(_ context.Context, userID int64, productID, offerID string) (*payment.OfferSignature, error) {
+	s.userID, s.productID, s.offerID = userID, productID, offerID
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &payment.OfferSignature{ProductID: productID, OfferID: offerID, KeyID: "ABC123", Nonce: "n-1", Timestamp: 1700000000000, Signature: "c2ln"}, nil
+}
+
+func newAppleOfferTestRouter(t testing.TB, svc PaymentAppleOfferService) http.Handler {
+	t.Helper()
+	authSvc := newTestAuthService(t, service.NewMemoryUserService())
+	router := chi.NewRouter()
+	humaAPI := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterPaymentRoutes(humaAPI, PaymentDeps{Auth: authSvc, AppleOffers: svc})
+	return router
+}
+
+func TestAppleOfferSignatureRoute(t *testing.T) {
+	body := `{"product_id":"com.app.pro.monthly","offer_id":"winback_50"}`
+	do := func(svc PaymentAppleOfferService) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newAppleOfferTestRouter(t, svc).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodPost, "/payment/apple/offer-signature", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do(nil); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("nil service status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+
+	svc := &stubAppleOfferSvc{}
+	rec := do(svc)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if svc.userID != 1 || svc.productID != "com.app.pro.monthly" || svc.offerID != "winback_50" {
+		t.Fatalf("unexpected call: %+v", svc)
+	}
+	if !strings.Contains(rec.Body.String(), `"signature":"c2ln"`) || !strings.Contains(rec.Body.String(), `"key_id":"ABC123"`) {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+
+	if rec := do(&stubAppleOfferSvc{err: payment.ErrUnknownOffer}); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown offer status = %d, want 404; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := do(&stubAppleOfferSvc{err: payment.ErrOfferNotEligible}); rec.Code != http.StatusForbidden {
+		t.Fatalf("ineligible status = %d, want 403; body=%s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+// PaymentAppleRestoreService 是 restore 路由所需的最小服务接口。
+type PaymentAppleRestoreService interface {
+	RestorePurchases(ctx context.Context, userID int64, transactionIDs, signedTransactions []string) (*payment.RestoreResult, error)
+}
+
+// RestoreAppleTransactionsRequest 是 POST /payment/apple/restore 的请求体。
+type RestoreAppleTransactionsRequest struct {
+	TransactionIDs     []string `json:"transaction_ids,omitempty" doc:"StoreKit Transaction.currentEntitlements / latest(for:) 返回的 transactionId" maxItems:"50"`
+	SignedTransactions []string `json:"signed_transactions,omitempty" doc:"StoreKit VerificationResult 的 jwsRepresentation；服务端校验签名后取出 transactionId" maxItems:"50"`
+}
+
+// RestoreAppleTransactionItem 是一条提交的交易的恢复结果。
+type RestoreAppleTransactionItem struct {
+	TransactionID    string                  `json:"transaction_id" doc:"提交的 transactionId（signed transaction 为解出的 transactionId）" example:"200000123456789"`
+	Status           string                  `json:"status" doc:"RESTORED 已恢复；CONFLICT 属于其他账号，未做任何改动；SKIPPED 不需要或无法恢复；NOT_FOUND Apple 查不到该交易" enum:"RESTORED,CONFLICT,SKIPPED,NOT_FOUND" example:"RESTORED"`
+	Reason           string                  `json:"reason,omitempty" doc:"补充说明：REBOUND 表示订阅已从其他账号改绑到当前账号；OWNED_BY_ANOTHER_ACCOUNT 为冲突原因；其余为跳过原因" enum:"REBOUND,OWNED_BY_ANOTHER_ACCOUNT,UNKNOWN_APP_ACCOUNT_TOKEN,MISSING_APP_ACCOUNT_TOKEN,FAMILY_SHARED,CONSUMABLE,UNKNOWN_PRODUCT,DUPLICATE" example:"REBOUND"`
+	SubscriptionInfo *model.SubscriptionInfo `json:"subscription_info,omitempty" doc:"恢复后的订阅状态，与 verify 响应同形；非消耗型买断返回终身权益"`
+	Purchase         *model.PurchaseInfo     `json:"purchase,omitempty" doc:"恢复的非消耗型买断"`
+}
+
+// RestoreAppleTransactionsResponse 是 POST /payment/apple/restore 的响应负载。
+type RestoreAppleTransactionsResponse struct {
+	Items     []RestoreAppleTransactionItem `json:"items" doc:"按提交顺序（先 transaction_ids，后 signed_transactions）的恢复结果"`
+	Conflicts int                           `json:"conflicts" doc:"属于其他账号的交易数；大于 0 时客户端应提示用户切换到购买时登录的账号" example:"0" minimum:"0"`
+}
+
+func registerAppleRestoreRoute(api huma.API, deps PaymentDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-apple-transactions",
+		Method:      http.MethodPost,
+		Path:        "/payment/apple/restore",
+		Summary:     "恢复 Apple IAP 购买",
+		Description: "客户端在“恢复购买”或换设备 / 重装后调用，提交本机 StoreKit 当前权益的 transactionId 或 signed transaction。服务端通过 App Store Server API 拉取交易，对自动续期订阅再查询 original transaction 的最新状态，重建 apple_subscriptions；非消耗型买断幂等写入 apple_purchases，消耗型商品不参与恢复。\n\n归属以 Apple 返回的最新交易的 appAccountToken 为准：属于当前用户时恢复（订阅若记在其他账号下则改绑到当前账号，reason 为 REBOUND）；属于其他账号时不做任何改动，返回 CONFLICT，响应中不包含其他账号的任何信息。",
+		Tags:        []string{"payment"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Body          RestoreAppleTransactionsRequest
+	}) (*struct {
+		Body model.Response[RestoreAppleTransactionsResponse]
+	}, error) {
+		authedUser, err := validateUserBearerToken(ctx, deps.Auth, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		userID, perr := strconv.ParseInt(authedUser.ID, 10, 64)
+		if perr != nil || userID <= 0 {
+			return nil, huma.Error401Unauthorized("access token 无效")
+		}
+		if len(input.Body.TransactionIDs) == 0 && len(input.Body.SignedTransactions) == 0 {
+			return nil, huma.Error400BadRequest("transaction_ids 与 signed_transactions 不能同时为空")
+		}
+		if deps.AppleRestore == nil {
+			return nil, huma.Error503ServiceUnavailable("Apple IAP 未配置")
+		}
+		result, err := deps.AppleRestore.RestorePurchases(ctx, userID, input.Body.TransactionIDs, input.Body.SignedTransactions)
+		if err != nil {
+			return nil, mapAppleRestoreError(err)
+		}
+		out := RestoreAppleTransactionsResponse{Items: make([]RestoreAppleTransactionItem, 0, len(result.Items))}
+		for _, item := range result.Items {
+			if item.Status == payment.RestoreStatusConflict {
+				out.Conflicts++
+			}
+			out.Items = append(out.Items, RestoreAppleTransactionItem{
+				TransactionID:    item.TransactionID,
+				Status:           item.Status,
+				Reason:           item.Reason,
+				SubscriptionInfo: item.Subscription,
+				Purchase:         item.Purchase,
+			})
+		}
+		return &struct {
+			Body model.Response[RestoreAppleTransactionsResponse]
+		}{
+			Body: model.Success(out),
+		}, nil
+	})
+}
+
+func mapAppleRestoreError(err error) error {
+	switch {
+	case errors.Is(err, payment.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("Apple IAP 未配置")
+	case errors.Is(err, payment.ErrInvalidSignedTransaction):
+		return huma.Error400BadRequest("signed transaction 无效")
+	case errors.Is(err, payment.ErrAppleAuthRejected):
+		return huma.Error500InternalServerError("Apple API 鉴权失败")
+	default:
+		return huma.Error500InternalServerError("恢复购买失败")
+	}
+}
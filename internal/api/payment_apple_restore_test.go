@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+type stubAppleRestoreSvc struct {
+	userID      int64
+	ids, signed []string
+	err         error
+}
+
+func (s *stubAppleRestoreSvc) RestorePurchases(_ context.Context, userID int64, transactionIDs, signedTransactions []string) (*payment.RestoreResult, error) {
+	s.userID, s.ids, s.signed = userID, transactionIDs, signedTransactions
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &payment.RestoreResult{Items: []payment.RestoreItem{
+		{TransactionID: "tx-1", Status: payment.RestoreStatusRestored, Reason: payment.RestoreReasonRebound,
+			Subscription: &model.SubscriptionInfo{ProductID: "pro_monthly", Status: "ACTIVE", SubscribeLevel: 1}},
+		{TransactionID: "tx-2", Status: payment.RestoreStatusConflict, Reason: payment.RestoreReasonOwnedByAnotherAccount},
+	}}, nil
+}
+
+func newAppleRestoreTestRouter(t testing.TB, svc PaymentAppleRestoreService) http.Handler {
+	t.Helper()
+	authSvc := newTestAuthService(t, service.NewMemoryUserService())
+	router := chi.NewRouter()
+	humaAPI := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterPaymentRoutes(humaAPI, PaymentDeps{Auth: authSvc, AppleRestore: svc})
+	return router
+}
+
+func TestAppleRestoreRoute(t *testing.T) {
+	do := func(svc PaymentAppleRestoreService, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newAppleRestoreTestRouter(t, svc).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodPost, "/payment/apple/restore", strings.NewReader(body)))
+		return rec
+	}
+	body := `{"transaction_ids":["tx-1","tx-2"],"signed_transactions":["a.b.c"]}`
+
+	if rec := do(nil, body); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("nil service status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := do(&stubAppleRestoreSvc{}, `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("empty body status = %d, want 400; body=%s", rec.Code, rec.Body.String())
+	}
+
+	svc := &stubAppleRestoreSvc{}
+	rec := do(svc, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if svc.userID != 1 || len(svc.ids) != 2 || len(svc.signed) != 1 {
+		t.Fatalf("unexpected call: %+v", svc)
+	}
+	got := rec.Body.String()
+	for _, want := range []string{`"conflicts":1`, `"reason":"REBOUND"`, `"status":"CONFLICT"`, `"reason":"OWNED_BY_ANOTHER_ACCOUNT"`, `"subscribe_level":1`} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("body missing %s: %s", want, got)
+		}
+	}
+
+	if rec := do(&stubAppleRestoreSvc{err: payment.ErrInvalidSignedTransaction}, body); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid signed transaction status = %d, want 400; body=%s", rec.Code, rec.Body.String())
+	}
+}
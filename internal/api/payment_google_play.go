@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+// PaymentGooglePlayService 是 Google Play verify 路由所需的最小服务接口。
+type PaymentGooglePlayService interface {
+	VerifyPurchase(ctx context.Context, userID int64, productID, purchaseToken string) (*payment.VerifyResult, error)
+}
+
+// PaymentGooglePlayWebhookService 是 Google Play RTDN webhook 路由所需的最小服务接口。
+type PaymentGooglePlayWebhookService interface {
+	HandlePushMessage(ctx context.Context, token string, msg payment.GooglePlayPushMessage) error
+	WebhookMaxBodyBytes() int
+}
+
+// VerifyGooglePlayPurchaseRequest 是 POST /payment/google-play/verify 的请求体。
+type VerifyGooglePlayPurchaseRequest struct {
+	ProductID     string `json:"product_id" doc:"Play Console 中的商品 / 订阅 productId" required:"true" minLength:"1" example:"pro.monthly"`
+	PurchaseToken string `json:"purchase_token" doc:"Play Billing Library 购买完成时返回的 purchaseToken" required:"true" minLength:"1"`
+}
+
+func registerGooglePlayVerifyRoute(api huma.API, deps PaymentDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "verify-google-play-purchase",
+		Method:      http.MethodPost,
+		Path:        "/payment/google-play/verify",
+		Summary:     "校验 Google Play 购买并写入订阅 / 购买记录",
+		Description: "客户端在 Play Billing 购买成功后调用本接口提交 productId 与 purchaseToken。服务端通过 Android Publisher API 查询购买状态，校验 catalog、obfuscatedAccountId（即 /payment/apple/account-token 返回的 UUID，未携带时返回 400）与当前用户的绑定关系，幂等写入 google_play_subscriptions 或 google_play_purchases，并在写入成功后 acknowledge 该购买。\n\n返回值与 POST /payment/apple/verify 同形：subscription_info 可直接刷新本地订阅 UI，消耗型商品同一 purchaseToken 只入账一次。",
+		Tags:        []string{"payment"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusConflict,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Body          VerifyGooglePlayPurchaseRequest
+	}) (*struct {
+		Body model.Response[VerifyAppleTransactionResponse]
+	}, error) {
+		authedUser, err := validateUserBearerToken(ctx, deps.Auth, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		userID, perr := strconv.ParseInt(authedUser.ID, 10, 64)
+		if perr != nil || userID <= 0 {
+			return nil, huma.Error401Unauthorized("access token 无效")
+		}
+		if input.Body.ProductID == "" || input.Body.PurchaseToken == "" {
+			return nil, huma.Error400BadRequest("product_id 与 purchase_token 不能为空")
+		}
+		if deps.GooglePlay == nil {
+			return nil, huma.Error503ServiceUnavailable("Google Play 未配置")
+		}
+		result, err := deps.GooglePlay.VerifyPurchase(ctx, userID, input.Body.ProductID, input.Body.PurchaseToken)
+		if err != nil {
+			return nil, mapGooglePlayVerifyError(err)
+		}
+		return &struct {
+			Body model.Response[VerifyAppleTransactionResponse]
+		}{
+			Body: model.Success(VerifyAppleTransactionResponse{
+				SubscriptionInfo: result.Subscription,
+				Purchase:         result.Purchase,
+			}),
+		}, nil
+	})
+}
+
+func mapGooglePlayVerifyError(err error) error {
+	switch {
+	case errors.Is(err, payment.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("Google Play 未配置")
+	case errors.Is(err, payment.ErrGooglePlayUnknownProduct),
+		errors.Is(err, payment.ErrGooglePlayProductMismatch),
+		errors.Is(err, payment.ErrGooglePlayPurchasePending),
+		errors.Is(err, payment.ErrGooglePlayPurchaseCanceled),
+		errors.Is(err, payment.ErrGooglePlayTestPurchase),
+		errors.Is(err, payment.ErrGooglePlayAccountMismatch),
+		errors.Is(err, payment.ErrGooglePlayAccountMissing),
+		errors.Is(err, payment.ErrGooglePlayPurchaseNotFound):
+		return huma.Error400BadRequest(err.Error())
+	case errors.Is(err, payment.ErrGooglePlayOwnershipConflict):
+		return huma.Error409Conflict(err.Error())
+	case errors.Is(err, payment.ErrGooglePlayAuthRejected):
+		return huma.Error500InternalServerError("Google Play API 鉴权失败")
+	default:
+		return huma.Error500InternalServerError("verify 失败")
+	}
+}
+
+// GooglePlayPushRequest 是 Cloud Pub/Sub push subscription 投递的请求体。
+type GooglePlayPushRequest struct {
+	Message struct {
+		Data        string            `json:"data" doc:"base64 编码的 DeveloperNotification" required:"true"`
+		MessageID   string            `json:"messageId" doc:"Pub/Sub message id，作为幂等键" required:"true"`
+		PublishTime string            `json:"publishTime,omitempty" doc:"Pub/Sub 发布时间" required:"false"`
+		Attributes  map[string]string `json:"attributes,omitempty" doc:"Pub/Sub 消息属性" required:"false"`
+	} `json:"message" required:"true"`
+	Subscription string `json:"subscription,omitempty" doc:"Pub/Sub subscription 名称" required:"false"`
+}
+
+func registerGooglePlayWebhookRoute(api huma.API, deps PaymentDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "google-play-rtdn-webhook",
+		Method:      http.MethodPost,
+		Path:        "/webhooks/google-play",
+		Summary:     "Google Play Real-Time Developer Notifications 入口",
+		Description: "公共 endpoint：接收 Cloud Pub/Sub push 投递的 RTDN。push endpoint 需配置为 `/webhooks/google-play?token=<GOOGLE_PLAY_WEBHOOK_TOKEN>`，token 不匹配返回 401。通知只作为“某个 purchaseToken 有变化”的信号，服务端会向 Android Publisher API 拉取最新状态后幂等写入 google_play_events 并更新订阅 / 购买。\n\n返回 2xx 即 ack；任何下游瞬态错误（DB / Google API）返回 500，由 Pub/Sub 按退避策略重投。日志中 token 查询参数会被脱敏。",
+		Tags:        []string{"payment"},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusInternalServerError,
+		},
+	}, func(ctx context.Context, input *struct {
+		Token string `query:"token" doc:"与 GOOGLE_PLAY_WEBHOOK_TOKEN 一致的共享密钥"`
+		Body  GooglePlayPushRequest
+	}) (*struct {
+		Body model.Response[AppleWebhookAck]
+	}, error) {
+		if deps.GooglePlayWebhook == nil {
+			return nil, huma.Error500InternalServerError("webhook 未配置")
+		}
+		max := deps.GooglePlayWebhook.WebhookMaxBodyBytes()
+		if max <= 0 {
+			max = fallbackWebhookMaxBodyBytes
+		}
+		if input.Body.Message.MessageID == "" || input.Body.Message.Data == "" {
+			return nil, huma.Error400BadRequest("message.messageId 与 message.data 不能为空")
+		}
+		if len(input.Body.Message.Data) > max {
+			return nil, huma.Error400BadRequest("message.data 超出长度限制")
+		}
+		err := deps.GooglePlayWebhook.HandlePushMessage(ctx, input.Token, payment.GooglePlayPushMessage{
+			MessageID: input.Body.Message.MessageID,
+			Data:      input.Body.Message.Data,
+		})
+		if err != nil {
+			return nil, mapGooglePlayWebhookError(err)
+		}
+		return &struct {
+			Body model.Response[AppleWebhookAck]
+		}{
+			Body: model.Success(AppleWebhookAck{Success: true, Message: "ok"}),
+		}, nil
+	})
+}
+
+func mapGooglePlayWebhookError(err error) error {
+	switch {
+	case errors.Is(err, payment.ErrNotConfigured):
+		return huma.Error500InternalServerError("google play not configured")
+	case errors.Is(err, payment.ErrGooglePlayWebhookUnauthorized):
+		return huma.Error401Unauthorized("invalid webhook token")
+	case errors.Is(err, payment.ErrGooglePlayInvalidNotification):
+		return huma.Error400BadRequest("invalid notification")
+	default:
+		return huma.Error500InternalServerError("google play webhook processing failed")
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+type stubGooglePlaySvc struct {
+	userID        int64
+	productID     string
+	purchaseToken string
+	result        *payment.VerifyResult
+	err           error
+}
+
+func (s *stubGooglePlaySvc) VerifyPurchase(_ context.Context, userID int64, productID, purchaseToken string) (*payment.VerifyResult, error) {
+	s.userID, s.productID, s.purchaseToken = userID, productID, purchaseToken
+	return s.result, s.err
+}
+
+type stubGooglePlayWebhookSvc struct {
+	token string
+	msg   payment.GooglePlayPushMessage
+	err   error
+}
+
+func (s *stubGooglePlayWebhookSvc) HandlePushMessage(_ context.Context, token string, msg payment.GooglePlayPushMessage) error {
+	s.token, s.msg = token, msg
+	return s.err
+}
+
+func (s *stubGooglePlayWebhookSvc) WebhookMaxBodyBytes() int { return 1024 }
+
+func newGooglePlayTestRouter(t testing.TB, verify PaymentGooglePlayService, webhook PaymentGooglePlayWebhookService) http.Handler {
+	t.Helper()
+	authSvc := newTestAuthService(t, service.NewMemoryUserService())
+	router := chi.NewRouter()
+	humaAPI := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterPaymentRoutes(humaAPI, PaymentDeps{Auth: authSvc, GooglePlay: verify, GooglePlayWebhook: webhook})
+	return router
+}
+
+func TestGooglePlayVerifyRoute(t *testing.T) {
+	body := `{"product_id":"pro.monthly","purchase_token":"tok-1"}`
+
+	rec := httptest.NewRecorder()
+	newGooglePlayTestRouter(t, nil, nil).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodPost, "/payment/google-play/verify", strings.NewReader(body)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("nil service status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+
+	svc := &stubGooglePlaySvc{result: &payment.VerifyResult{Subscription: &model.SubscriptionInfo{ProductID: "pro_monthly", Status: "ACTIVE", SubscribeLevel: 1}}}
+	rec = httptest.NewRecorder()
+	newGooglePlayTestRouter(t, svc, nil).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodPost, "/payment/google-play/verify", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if svc.userID != 1 || svc.productID != "pro.monthly" || svc.purchaseToken != "tok-1" {
+		t.Fatalf("unexpected call: %+v", svc)
+	}
+	if !strings.Contains(rec.Body.String(), `"product_id":"pro_monthly"`) {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+
+	svc = &stubGooglePlaySvc{err: payment.ErrGooglePlayOwnershipConflict}
+	rec = httptest.NewRecorder()
+	newGooglePlayTestRouter(t, svc, nil).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodPost, "/payment/google-play/verify", strings.NewReader(body)))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("conflict status = %d, want 409; body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGooglePlayWebhookRoute(t *testing.T) {
+	body := `{"message":{"data":"e30=","messageId":"m-1","publishTime":"2026-01-01T00:00:00Z"},"subscription":"projects/p/subscriptions/s"}`
+
+	rec := httptest.NewRecorder()
+	newGooglePlayTestRouter(t, nil, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks/google-play?token=x", strings.NewReader(body)))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("nil service status = %d, want 500; body=%s", rec.Code, rec.Body.String())
+	}
+
+	svc := &stubGooglePlayWebhookSvc{}
+	rec = httptest.NewRecorder()
+	newGooglePlayTestRouter(t, nil, svc).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks/google-play?token=secret", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if svc.token != "secret" || svc.msg.MessageID != "m-1" || svc.msg.Data != "e30=" {
+		t.Fatalf("unexpected call: %+v", svc)
+	}
+
+	svc = &stubGooglePlayWebhookSvc{err: payment.ErrGooglePlayWebhookUnauthorized}
+	rec = httptest.NewRecorder()
+	newGooglePlayTestRouter(t, nil, svc).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks/google-play?token=bad", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad token status = %d, want 401; body=%s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+// PaymentPaywallService 是 /payment/products 路由所需的最小服务接口。
+type PaymentPaywallService interface {
+	Paywall(ctx context.Context, userID int64, platform string, locales []string) (payment.Paywall, error)
+}
+
+// PaywallProduct 是付费墙上的一个方案。
+type PaywallProduct struct {
+	PlanID      string   `json:"plan_id" doc:"内部套餐 ID，与 subscription_info / entitlements 中的 plan_id 一致" example:"pro_monthly"`
+	Type        string   `json:"type" doc:"商品类型" enum:"subscription,consumable,non_consumable" example:"subscription"`
+	Level       int      `json:"level" doc:"权益等级" example:"1"`
+	ProductIDs  []string `json:"product_ids" doc:"当前平台商店中的商品 ID：iOS 为 App Store productId，Android 为 Google Play 商品 ID，Web 为 Stripe price id"`
+	Title       string   `json:"title" doc:"本地化标题" example:"Pro 月度会员"`
+	Description string   `json:"description,omitempty" doc:"本地化描述"`
+	Features    []string `json:"features" doc:"本地化权益要点，按展示顺序"`
+	Recommended bool     `json:"recommended" doc:"是否推荐方案"`
+	Current     bool     `json:"current" doc:"调用方当前是否持有该 plan 的有效权益；未携带 token 时恒为 false"`
+}
+
+// PaywallResponse 是 GET /payment/products 的响应负载。
+type PaywallResponse struct {
+	Platform     string           `json:"platform" doc:"请求的平台" enum:"ios,android,web" example:"ios"`
+	Locale       string           `json:"locale" doc:"实际采用的语言标签；为空表示没有匹配的翻译，使用默认文案" example:"zh-Hans"`
+	ExperimentID string           `json:"experiment_id,omitempty" doc:"调用方所在的付费墙实验；未登录或没有进行中的实验时省略" example:"annual_first"`
+	VariantID    string           `json:"variant_id,omitempty" doc:"调用方在实验中的分组，同一用户始终相同" example:"control"`
+	Products     []PaywallProduct `json:"products" doc:"按展示顺序排列的方案"`
+}
+
+func registerPaywallRoute(api huma.API, deps PaymentDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-payment-products",
+		Method:      http.MethodGet,
+		Path:        "/payment/products",
+		Summary:     "获取付费墙商品",
+		Description: "按平台与语言返回当前上架的方案：plan、商店商品 ID、权益等级、本地化标题 / 描述 / 权益要点与推荐标记，数据来自商品目录，后台修改后无需发版即可生效。\n\n语言取 locale 参数，未传时取 Accept-Language；没有匹配的翻译时使用默认文案。携带 Bearer token 时标记调用方当前持有的方案（current），token 可省略。\n\n有进行中的付费墙实验时，登录用户按用户 ID 稳定分组，返回所在分组的方案组合与推荐方案，并在 experiment_id / variant_id 中标明分组；未登录用户看到默认付费墙。\n\n响应带 ETag，客户端用 If-None-Match 重新请求时内容未变返回 304。",
+		Tags:        []string{"payment"},
+		Errors: []int{
+			http.StatusUnauthorized,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization  string `header:"Authorization" hidden:"true"`
+		AcceptLanguage string `header:"Accept-Language" doc:"未传 locale 时用于选择语言"`
+		IfNoneMatch    string `header:"If-None-Match" doc:"上次响应的 ETag"`
+		Platform       string `query:"platform" enum:"ios,android,web" default:"ios" doc:"客户端平台，决定返回哪个商店的商品 ID"`
+		Locale         string `query:"locale" doc:"BCP 47 语言标签，优先于 Accept-Language" example:"zh-Hans"`
+	}) (*struct {
+		Status       int
+		ETag         string `header:"ETag"`
+		CacheControl string `header:"Cache-Control"`
+		Vary         string `header:"Vary"`
+		Body         model.Response[PaywallResponse]
+	}, error) {
+		userID, err := paywallUserID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		current, err := paywallCurrentPlans(ctx, deps, userID)
+		if err != nil {
+			return nil, err
+		}
+		if deps.Paywall == nil {
+			return nil, huma.Error503ServiceUnavailable("商品目录未配置")
+		}
+		locales := parseAcceptLanguage(input.AcceptLanguage)
+		if locale := strings.TrimSpace(input.Locale); locale != "" {
+			locales = []string{locale}
+		}
+		paywall, err := deps.Paywall.Paywall(ctx, userID, input.Platform, locales)
+		if err != nil {
+			return nil, mapPaywallError(err)
+		}
+
+		out := PaywallResponse{
+			Platform:     paywall.Platform,
+			Locale:       paywall.Locale,
+			ExperimentID: paywall.ExperimentID,
+			VariantID:    paywall.VariantID,
+			Products:     make([]PaywallProduct, 0, len(paywall.Plans)),
+		}
+		for _, p := range paywall.Plans {
+			features := p.Features
+			if features == nil {
+				features = []string{}
+			}
+			_, isCurrent := current[p.PlanID]
+			out.Products = append(out.Products, PaywallProduct{
+				PlanID:      p.PlanID,
+				Type:        p.Type,
+				Level:       p.Level,
+				ProductIDs:  p.ProductIDs,
+				Title:       p.Title,
+				Description: p.Description,
+				Features:    features,
+				Recommended: p.Recommended,
+				Current:     isCurrent,
+			})
+		}
+		body := model.Success(out)
+		etag, err := paywallETag(body)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("获取商品失败")
+		}
+
+		resp := &struct {
+			Status       int
+			ETag         string `header:"ETag"`
+			CacheControl string `header:"Cache-Control"`
+			Vary         string `header:"Vary"`
+			Body         model.Response[PaywallResponse]
+		}{
+			Status:       http.StatusOK,
+			ETag:         etag,
+			CacheControl: "private, no-cache",
+			Vary:         "Authorization, Accept-Language",
+			Body:         body,
+		}
+		if etagMatches(input.IfNoneMatch, etag) {
+			resp.Status = http.StatusNotModified
+		}
+		return resp, nil
+	})
+}
+
+// paywallUserID 在携带 Bearer token 时返回调用方的用户 ID；未携带 token 时返回 0，token 无效返回 401。
+func paywallUserID(ctx context.Context, deps PaymentDeps, authHeader string) (int64, error) {
+	if strings.TrimSpace(authHeader) == "" {
+		return 0, nil
+	}
+	authedUser, err := validateUserBearerToken(ctx, deps.Auth, authHeader)
+	if err != nil {
+		return 0, err
+	}
+	userID, perr := strconv.ParseInt(authedUser.ID, 10, 64)
+	if perr != nil || userID <= 0 {
+		return 0, huma.Error401Unauthorized("access token 无效")
+	}
+	return userID, nil
+}
+
+// paywallCurrentPlans 返回 userID 持有有效权益的 plan；未登录（userID 为 0）时返回 nil。
+func paywallCurrentPlans(ctx context.Context, deps PaymentDeps, userID int64) (map[string]struct{}, error) {
+	if userID <= 0 || deps.Entitlements == nil {
+		return nil, nil
+	}
+	summary, err := deps.Entitlements.LoadEntitlements(ctx, userID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("获取商品失败")
+	}
+	current := make(map[string]struct{}, len(summary.Entitlements))
+	for _, e := range summary.Entitlements {
+		current[e.PlanID] = struct{}{}
+	}
+	return current, nil
+}
+
+// paywallETag 对响应体取 SHA-256 作为强 ETag；current 标记也参与计算，不同用户的响应互不命中。
+func paywallETag(body any) (string, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// etagMatches 判断 If-None-Match 是否包含 etag（支持逗号分隔的多个值、弱校验前缀 W/ 与 *）。
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAcceptLanguage 按 q 值从高到低返回 Accept-Language 中的语言标签，忽略 * 与 q=0。
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag: tag, q: q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		out = append(out, t.tag)
+	}
+	return out
+}
+
+func mapPaywallError(err error) error {
+	switch {
+	case errors.Is(err, payment.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("该平台未配置商品目录")
+	default:
+		return huma.Error500InternalServerError("获取商品失败")
+	}
+}
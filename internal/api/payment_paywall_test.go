@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+type stubPaywallSvc struct {
+	userID   int64
+	platform string
+	locales  []string
+}
+
+func (s *stubPaywallSvc) Paywall(_ context.Context, userID int64, platform string, locales []string) (payment.Paywall, error) {
+	s.userID, s.platform, s.locales = userID, platform, locales
+	out := payment.Paywall{Platform: platform, Locale: "zh-Hans", Plans: []payment.PaywallPlan{
+		{PlanID: "pro_yearly", Type: "subscription", Level: 1, ProductIDs: []string{"com.app.pro.yearly"}, Title: "Pro 年度", Recommended: true},
+		{PlanID: "pro_monthly", Type: "subscription", Level: 1, ProductIDs: []string{"com.app.pro.monthly"}, Title: "Pro 月度", Features: []string{"去除广告"}},
+	}}
+	if userID > 0 {
+		out.ExperimentID, out.VariantID = "annual_first", "control"
+	}
+	return out, nil
+}
+
+func newPaywallTestRouter(t testing.TB, svc PaymentPaywallService) http.Handler {
+	t.Helper()
+	router := chi.NewRouter()
+	humaAPI := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterPaymentRoutes(humaAPI, PaymentDeps{
+		Auth:    newTestAuthService(t, service.NewMemoryUserService()),
+		Paywall: svc,
+		Entitlements: stubEntitlementReader{summary: model.EntitlementSummary{
+			Entitlements: []model.Entitlement{{FeatureSet: "premium", PlanID: "pro_monthly", Status: "ACTIVE", Level: 1}},
+		}},
+	})
+	return router
+}
+
+func TestPaywallRoute(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newPaywallTestRouter(t, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/payment/products", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("nil service status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+
+	svc := &stubPaywallSvc{}
+	router := newPaywallTestRouter(t, svc)
+
+	// 匿名请求：按 Accept-Language 的 q 值排序，不标记 current。
+	req := httptest.NewRequest(http.MethodGet, "/payment/products", nil)
+	req.Header.Set("Accept-Language", "en;q=0.5, zh-Hans-CN, *;q=0.1")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("anonymous status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if svc.userID != 0 || svc.platform != payment.PaywallPlatformIOS || strings.Join(svc.locales, ",") != "zh-Hans-CN,en" {
+		t.Fatalf("unexpected call: %+v", svc)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`"locale":"zh-Hans"`, `"recommended":true`, `"features":[]`, `"features":["去除广告"]`} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("body missing %s: %s", want, body)
+		}
+	}
+	if strings.Contains(body, `"current":true`) || strings.Contains(body, `"experiment_id"`) {
+		t.Fatalf("anonymous response must not mark current plan or experiment: %s", body)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/payment/products", nil)
+	req.Header.Set("Accept-Language", "en;q=0.5, zh-Hans-CN, *;q=0.1")
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("conditional status = %d, want 304; body=%s", rec.Code, rec.Body.String())
+	}
+
+	// 登录用户：locale 参数优先，标记当前 plan，ETag 随之变化。
+	req = newAuthorizedUserRequest(t, http.MethodGet, "/payment/products?platform=web&locale=ja", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("authorized status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if svc.userID != 1 || svc.platform != payment.PaywallPlatformWeb || strings.Join(svc.locales, ",") != "ja" {
+		t.Fatalf("unexpected call: %+v", svc)
+	}
+	if !strings.Contains(rec.Body.String(), `"plan_id":"pro_monthly","type":"subscription","level":1,"product_ids":["com.app.pro.monthly"],"title":"Pro 月度","features":["去除广告"],"recommended":false,"current":true`) {
+		t.Fatalf("current plan not marked: %s", rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"experiment_id":"annual_first","variant_id":"control"`) {
+		t.Fatalf("experiment assignment missing: %s", rec.Body.String())
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Fatal("personalized response must not reuse the anonymous ETag")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/payment/products", nil)
+	req.Header.Set("Authorization", "Bearer invalid")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("invalid token status = %d, want 401; body=%s", rec.Code, rec.Body.String())
+	}
+}
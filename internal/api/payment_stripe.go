@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+// PaymentStripeService 是 Stripe checkout / portal 路由所需的最小服务接口。
+type PaymentStripeService interface {
+	CreateCheckoutSession(ctx context.Context, userID int64, planID string) (model.StripeCheckoutResponse, error)
+	CreatePortalSession(ctx context.Context, userID int64) (model.StripePortalResponse, error)
+}
+
+// PaymentStripeWebhookService 是 Stripe webhook 路由所需的最小服务接口。
+type PaymentStripeWebhookService interface {
+	HandleWebhook(ctx context.Context, payload []byte, signature string) error
+	WebhookMaxBodyBytes() int
+}
+
+const fallbackStripeWebhookMaxBodyBytes = 262144
+
+func registerStripeCheckoutRoute(api huma.API, deps PaymentDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "create-stripe-checkout-session",
+		Method:      http.MethodPost,
+		Path:        "/payment/stripe/checkout",
+		Summary:     "创建 Stripe Checkout Session",
+		Description: "为当前用户创建订阅模式的 Stripe Checkout Session，返回 Stripe 托管支付页面的跳转链接。首次调用时为用户创建 Stripe customer 并持久化映射，后续 webhook 通过 customer 反查用户。支付完成后订阅状态由 /webhooks/stripe 异步写入，并体现在 GET /users/me 的 subscription_info 中。",
+		Tags:        []string{"payment"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Body          model.StripeCheckoutRequest
+	}) (*struct {
+		Body model.Response[model.StripeCheckoutResponse]
+	}, error) {
+		userID, err := stripeRouteUserID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		if input.Body.PlanID == "" {
+			return nil, huma.Error400BadRequest("plan_id 不能为空")
+		}
+		if deps.Stripe == nil {
+			return nil, huma.Error503ServiceUnavailable("Stripe 未配置")
+		}
+		out, err := deps.Stripe.CreateCheckoutSession(ctx, userID, input.Body.PlanID)
+		if err != nil {
+			return nil, mapStripeError(err)
+		}
+		return &struct {
+			Body model.Response[model.StripeCheckoutResponse]
+		}{Body: model.Success(out)}, nil
+	})
+}
+
+func registerStripePortalRoute(api huma.API, deps PaymentDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "create-stripe-portal-session",
+		Method:      http.MethodPost,
+		Path:        "/payment/stripe/portal",
+		Summary:     "获取 Stripe customer portal 链接",
+		Description: "返回当前用户的 Stripe customer portal 链接，用于取消 / 更换订阅与管理付款方式。从未通过 Stripe 购买过的用户返回 404。",
+		Tags:        []string{"payment"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Errors: []int{
+			http.StatusUnauthorized,
+			http.StatusNotFound,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+	}) (*struct {
+		Body model.Response[model.StripePortalResponse]
+	}, error) {
+		userID, err := stripeRouteUserID(ctx, deps, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		if deps.Stripe == nil {
+			return nil, huma.Error503ServiceUnavailable("Stripe 未配置")
+		}
+		out, err := deps.Stripe.CreatePortalSession(ctx, userID)
+		if err != nil {
+			return nil, mapStripeError(err)
+		}
+		return &struct {
+			Body model.Response[model.StripePortalResponse]
+		}{Body: model.Success(out)}, nil
+	})
+}
+
+func stripeRouteUserID(ctx context.Context, deps PaymentDeps, authorization string) (int64, error) {
+	authedUser, err := validateUserBearerToken(ctx, deps.Auth, authorization)
+	if err != nil {
+		return 0, err
+	}
+	userID, perr := strconv.ParseInt(authedUser.ID, 10, 64)
+	if perr != nil || userID <= 0 {
+		return 0, huma.Error401Unauthorized("access token 无效")
+	}
+	return userID, nil
+}
+
+func mapStripeError(err error) error {
+	switch {
+	case errors.Is(err, payment.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("Stripe 未配置")
+	case errors.Is(err, payment.ErrStripeUnknownPlan):
+		return huma.Error400BadRequest(err.Error())
+	case errors.Is(err, payment.ErrStripeNoCustomer):
+		return huma.Error404NotFound("尚未通过 Stripe 购买")
+	case errors.Is(err, payment.ErrStripeAuthRejected):
+		return huma.Error500InternalServerError("Stripe API 鉴权失败")
+	default:
+		return huma.Error500InternalServerError("Stripe 请求失败")
+	}
+}
+
+func registerStripeWebhookRoute(api huma.API, deps PaymentDeps) {
+	huma.Register(api, huma.Operation{
+		OperationID: "stripe-webhook",
+		Method:      http.MethodPost,
+		Path:        "/webhooks/stripe",
+		Summary:     "Stripe webhook 入口",
+		Description: "公共 endpoint：接收 Stripe 推送的事件。服务端用 STRIPE_WEBHOOK_SECRET 校验 `Stripe-Signature`（HMAC-SHA256，时间戳偏差超过 STRIPE_WEBHOOK_TOLERANCE 拒绝），然后以 event id 幂等写入 stripe_events，并按 customer.subscription.created / updated / deleted 更新订阅状态；其余事件只记录不处理。\n\n签名错误返回 400；下游瞬态错误返回 500，由 Stripe 按退避策略重试。",
+		Tags:        []string{"payment"},
+		// 签名基于原始字节计算，body 交给 service 解码，不做 schema 校验。
+		SkipValidateBody: true,
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusInternalServerError,
+		},
+	}, func(ctx context.Context, input *struct {
+		Signature string `header:"Stripe-Signature" doc:"Stripe webhook 签名头"`
+		RawBody   []byte `contentType:"application/json"`
+	}) (*struct {
+		Body model.Response[AppleWebhookAck]
+	}, error) {
+		if deps.StripeWebhook == nil {
+			return nil, huma.Error500InternalServerError("webhook 未配置")
+		}
+		max := deps.StripeWebhook.WebhookMaxBodyBytes()
+		if max <= 0 {
+			max = fallbackStripeWebhookMaxBodyBytes
+		}
+		if len(input.RawBody) == 0 {
+			return nil, huma.Error400BadRequest("请求体不能为空")
+		}
+		if len(input.RawBody) > max {
+			return nil, huma.Error400BadRequest("请求体超出长度限制")
+		}
+		if input.Signature == "" {
+			return nil, huma.Error400BadRequest("缺少 Stripe-Signature")
+		}
+		if err := deps.StripeWebhook.HandleWebhook(ctx, input.RawBody, input.Signature); err != nil {
+			return nil, mapStripeWebhookError(err)
+		}
+		return &struct {
+			Body model.Response[AppleWebhookAck]
+		}{
+			Body: model.Success(AppleWebhookAck{Success: true, Message: "ok"}),
+		}, nil
+	})
+}
+
+func mapStripeWebhookError(err error) error {
+	switch {
+	case errors.Is(err, payment.ErrNotConfigured):
+		return huma.Error500InternalServerError("stripe not configured")
+	case errors.Is(err, payment.ErrStripeInvalidSignature):
+		return huma.Error400BadRequest("invalid signature")
+	case errors.Is(err, payment.ErrStripeInvalidEvent):
+		return huma.Error400BadRequest("invalid event")
+	default:
+		return huma.Error500InternalServerError("stripe webhook processing failed")
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+type stubStripeSvc struct {
+	userID int64
+	planID string
+	err    error
+}
+
+func (s *stubStripeSvc) CreateCheckoutSession(_ context.Context, userID int64, planID string) (model.StripeCheckoutResponse, error) {
+	s.userID, s.planID = userID, planID
+	if s.err != nil {
+		return model.StripeCheckoutResponse{}, s.err
+	}
+	return model.StripeCheckoutResponse{SessionID: "cs_1", URL: "https://checkout.example/cs_1"}, nil
+}
+
+func (s *stubStripeSvc) CreatePortalSession(_ context.Context, userID int64) (model.StripePortalResponse, error) {
+	s.userID = userID
+	if s.err != nil {
+		return model.StripePortalResponse{}, s.err
+	}
+	return model.StripePortalResponse{URL: "https://portal.example/p_1"}, nil
+}
+
+type stubStripeWebhookSvc struct {
+	payload   string
+	signature string
+	err       error
+}
+
+func (s *stubStripeWebhookSvc) HandleWebhook(_ context.Context, payload []byte, signature string) error {
+	s.payload, s.signature = string(payload), signature
+	return s.err
+}
+
+func (s *stubStripeWebhookSvc) WebhookMaxBodyBytes() int { return 1024 }
+
+func newStripeTestRouter(t testing.TB, svc PaymentStripeService, webhook PaymentStripeWebhookService) http.Handler {
+	t.Helper()
+	authSvc := newTestAuthService(t, service.NewMemoryUserService())
+	router := chi.NewRouter()
+	humaAPI := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterPaymentRoutes(humaAPI, PaymentDeps{Auth: authSvc, Stripe: svc, StripeWebhook: webhook})
+	return router
+}
+
+func TestStripeCheckoutRoute(t *testing.T) {
+	body := `{"plan_id":"pro_monthly"}`
+
+	rec := httptest.NewRecorder()
+	newStripeTestRouter(t, nil, nil).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodPost, "/payment/stripe/checkout", strings.NewReader(body)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("nil service status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+
+	svc := &stubStripeSvc{}
+	rec = httptest.NewRecorder()
+	newStripeTestRouter(t, svc, nil).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodPost, "/payment/stripe/checkout", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if svc.userID != 1 || svc.planID != "pro_monthly" {
+		t.Fatalf("unexpected call: %+v", svc)
+	}
+	if !strings.Contains(rec.Body.String(), `"session_id":"cs_1"`) {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+
+	svc = &stubStripeSvc{err: payment.ErrStripeUnknownPlan}
+	rec = httptest.NewRecorder()
+	newStripeTestRouter(t, svc, nil).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodPost, "/payment/stripe/checkout", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown plan status = %d, want 400; body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	newStripeTestRouter(t, &stubStripeSvc{}, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/payment/stripe/checkout", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous status = %d, want 401; body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStripePortalRoute(t *testing.T) {
+	svc := &stubStripeSvc{}
+	rec := httptest.NewRecorder()
+	newStripeTestRouter(t, svc, nil).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodPost, "/payment/stripe/portal", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if svc.userID != 1 || !strings.Contains(rec.Body.String(), `"url":"https://portal.example/p_1"`) {
+		t.Fatalf("unexpected call/body: %+v %s", svc, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	newStripeTestRouter(t, &stubStripeSvc{err: payment.ErrStripeNoCustomer}, nil).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodPost, "/payment/stripe/portal", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("no customer status = %d, want 404; body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStripeWebhookRoute(t *testing.T) {
+	body := `{"id":"evt_1","type":"customer.subscription.updated"}`
+	newReq := func(sig string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if sig != "" {
+			req.Header.Set("Stripe-Signature", sig)
+		}
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	newStripeTestRouter(t, nil, nil).ServeHTTP(rec, newReq("t=1,v1=abc"))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("nil service status = %d, want 500; body=%s", rec.Code, rec.Body.String())
+	}
+
+	svc := &stubStripeWebhookSvc{}
+	rec = httptest.NewRecorder()
+	newStripeTestRouter(t, nil, svc).ServeHTTP(rec, newReq("t=1,v1=abc"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if svc.payload != body || svc.signature != "t=1,v1=abc" {
+		t.Fatalf("unexpected call: %+v", svc)
+	}
+
+	rec = httptest.NewRecorder()
+	newStripeTestRouter(t, nil, &stubStripeWebhookSvc{}).ServeHTTP(rec, newReq(""))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("missing signature status = %d, want 400; body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	newStripeTestRouter(t, nil, &stubStripeWebhookSvc{err: payment.ErrStripeInvalidSignature}).ServeHTTP(rec, newReq("t=1,v1=bad"))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad signature status = %d, want 400; body=%s", rec.Code, rec.Body.String())
+	}
+}
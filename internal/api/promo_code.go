@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/auth"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/comp"
+)
+
+// PromoCodeService 是 /users/me/promo-codes 路由所需的最小服务接口。
+//
+// 生产实现由 internal/service/comp.Service 提供；为 nil 时路由返回 503。
+type PromoCodeService interface {
+	RedeemPromoCode(ctx context.Context, userID int64, code string) (model.CompEntitlement, error)
+}
+
+func registerUserPromoCodeRoutes(api huma.API, authSvc auth.Service, promoSvc PromoCodeService) {
+	huma.Register(api, huma.Operation{
+		OperationID: "redeem-promo-code",
+		Method:      http.MethodPost,
+		Path:        "/users/me/promo-codes/redeem",
+		Summary:     "兑换兑换码",
+		Description: "兑换运营发放的兑换码，获得一段赠送会员，排在已有赠送会员之后。\n\n- 每个用户每个兑换码只能兑换一次\n- 已停用、已过期或达到兑换上限的兑换码不可兑换",
+		Tags:        []string{"users"},
+		Security:    []map[string][]string{{"bearerAuth": []string{}}},
+		Errors: []int{
+			http.StatusUnauthorized,
+			http.StatusNotFound,
+			http.StatusConflict,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Body          model.RedeemPromoCodeRequest
+	}) (*struct {
+		Body model.Response[model.RedeemPromoCodeResponse]
+	}, error) {
+		userID, _, err := referralUser(ctx, authSvc, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		if promoSvc == nil {
+			return nil, huma.Error503ServiceUnavailable("兑换码未配置")
+		}
+		granted, err := promoSvc.RedeemPromoCode(ctx, userID, input.Body.Code)
+		if err != nil {
+			return nil, mapPromoCodeError(err)
+		}
+		return &struct {
+			Body model.Response[model.RedeemPromoCodeResponse]
+		}{
+			Body: model.Success(model.RedeemPromoCodeResponse{
+				PlanID:     granted.PlanID,
+				CompDays:   int(granted.EndsAt.Sub(granted.StartsAt) / (24 * time.Hour)),
+				CompEndsAt: formatTime(granted.EndsAt),
+			}),
+		}, nil
+	})
+}
+
+func mapPromoCodeError(err error) error {
+	switch {
+	case errors.Is(err, comp.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("兑换码未配置")
+	case errors.Is(err, comp.ErrPromoCodeNotFound):
+		return huma.Error404NotFound("兑换码不存在")
+	case errors.Is(err, comp.ErrPromoCodeInactive),
+		errors.Is(err, comp.ErrPromoCodeExpired):
+		return huma.Error409Conflict("兑换码已失效")
+	case errors.Is(err, comp.ErrPromoCodeExhausted):
+		return huma.Error409Conflict("兑换码已达到兑换上限")
+	case errors.Is(err, comp.ErrAlreadyRedeemed):
+		return huma.Error409Conflict("已经兑换过该兑换码")
+	default:
+		return huma.Error500InternalServerError("兑换失败")
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/comp"
+)
+
+type stubPromoCodeService struct {
+	userID int64
+	code   string
+	comp   model.CompEntitlement
+	err    error
+}
+
+func (s *stubPromoCodeService) RedeemPromoCode(_ context.Context, userID int64, code string) (model.CompEntitlement, error) {
+	s.userID, s.code = userID, code
+	return s.comp, s.err
+}
+
+func newPromoCodeTestRouter(t testing.TB, promoSvc PromoCodeService) http.Handler {
+	t.Helper()
+	userSvc := service.NewMemoryUserService()
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterUserRoutes(api, UserDeps{
+		Users:      userSvc,
+		Auth:       newTestAuthService(t, userSvc),
+		PromoCodes: promoSvc,
+	})
+	return router
+}
+
+func redeemPromoCodeRequest(t testing.TB) *http.Request {
+	req := newAuthorizedUserRequest(t, http.MethodPost, "/users/me/promo-codes/redeem", strings.NewReader(`{"code":"spring"}`))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestPromoCodeRoutesRedeem(t *testing.T) {
+	startsAt := time.Date(2026, 10, 8, 0, 0, 0, 0, time.UTC)
+	svc := &stubPromoCodeService{comp: model.CompEntitlement{PlanID: "pro", StartsAt: startsAt, EndsAt: startsAt.Add(30 * 24 * time.Hour)}}
+	rec := httptest.NewRecorder()
+	newPromoCodeTestRouter(t, svc).ServeHTTP(rec, redeemPromoCodeRequest(t))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if svc.userID != 1 || svc.code != "spring" {
+		t.Fatalf("unexpected call: %+v", svc)
+	}
+	if !strings.Contains(rec.Body.String(), `"comp_days":30`) || !strings.Contains(rec.Body.String(), `"comp_ends_at":"2026-11-07T00:00:00Z"`) {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+}
+
+func TestPromoCodeRoutesRedeemErrorMapping(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{comp.ErrNotConfigured, http.StatusServiceUnavailable},
+		{comp.ErrPromoCodeNotFound, http.StatusNotFound},
+		{comp.ErrPromoCodeInactive, http.StatusConflict},
+		{comp.ErrPromoCodeExpired, http.StatusConflict},
+		{comp.ErrPromoCodeExhausted, http.StatusConflict},
+		{comp.ErrAlreadyRedeemed, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newPromoCodeTestRouter(t, &stubPromoCodeService{err: tt.err}).ServeHTTP(rec, redeemPromoCodeRequest(t))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body=%s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	newPromoCodeTestRouter(t, nil).ServeHTTP(rec, redeemPromoCodeRequest(t))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("nil service status = %d, want 503", rec.Code)
+	}
+}
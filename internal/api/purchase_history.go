@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/auth"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+// PurchaseHistoryService 是 /users/me/purchases 路由所需的最小服务接口。
+//
+// 生产实现由 internal/service/payment.PurchaseHistoryService 提供；为 nil 时路由返回 503。
+type PurchaseHistoryService interface {
+	ListPurchaseHistory(ctx context.Context, userID int64, cursor string, limit int) (payment.PurchaseHistoryPage, error)
+}
+
+func registerUserPurchaseHistoryRoute(api huma.API, authSvc auth.Service, historySvc PurchaseHistoryService) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-current-user-purchases",
+		Method:      http.MethodGet,
+		Path:        "/users/me/purchases",
+		Summary:     "获取我的购买记录",
+		Description: "按时间倒序返回当前用户的 App Store 购买记录：首次购买、续期、退款、订阅换档与到期，附带发生时间与商品。\n\n记录来自已处理的 App Store 通知；只经过 verify、尚无对应通知的一次性购买与订阅也会作为首次购买列出，同一笔交易只出现一次。订阅的续期、退款与到期只能来自通知。\n\n响应不包含交易号等 Apple 标识。next_cursor 非空时，把它作为 cursor 参数传回即可获取下一页。",
+		Tags:        []string{"users"},
+		Security:    []map[string][]string{{"bearerAuth": []string{}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Cursor        string `query:"cursor" doc:"上一页返回的 next_cursor；首页留空"`
+		Limit         int    `query:"limit" minimum:"1" maximum:"100" default:"20" doc:"每页数量"`
+	}) (*struct {
+		Body model.Response[model.PurchaseHistoryResponse]
+	}, error) {
+		userID, err := purchaseHistoryUserID(ctx, authSvc, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		if historySvc == nil {
+			return nil, huma.Error503ServiceUnavailable("Apple IAP 未配置")
+		}
+		page, err := historySvc.ListPurchaseHistory(ctx, userID, input.Cursor, input.Limit)
+		if err != nil {
+			return nil, mapPurchaseHistoryError(err)
+		}
+		out := model.PurchaseHistoryResponse{
+			Items:      make([]model.PurchaseHistoryItem, 0, len(page.Entries)),
+			NextCursor: page.NextCursor,
+		}
+		for _, e := range page.Entries {
+			out.Items = append(out.Items, model.PurchaseHistoryItem{
+				Type:        e.Type,
+				OccurredAt:  formatTime(e.OccurredAt),
+				PlanID:      e.PlanID,
+				ProductID:   e.ProductID,
+				ToPlanID:    e.ToPlanID,
+				ToProductID: e.ToProductID,
+				Reason:      e.Reason,
+			})
+		}
+		return &struct {
+			Body model.Response[model.PurchaseHistoryResponse]
+		}{
+			Body: model.Success(out),
+		}, nil
+	})
+}
+
+func purchaseHistoryUserID(ctx context.Context, authSvc auth.Service, authHeader string) (int64, error) {
+	authedUser, err := validateUserBearerToken(ctx, authSvc, authHeader)
+	if err != nil {
+		return 0, err
+	}
+	userID, perr := strconv.ParseInt(authedUser.ID, 10, 64)
+	if perr != nil || userID <= 0 {
+		return 0, huma.Error401Unauthorized("access token 无效")
+	}
+	return userID, nil
+}
+
+func mapPurchaseHistoryError(err error) error {
+	switch {
+	case errors.Is(err, payment.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("Apple IAP 未配置")
+	case errors.Is(err, payment.ErrInvalidHistoryCursor):
+		return huma.Error400BadRequest("cursor 无效")
+	default:
+		return huma.Error500InternalServerError("获取购买记录失败")
+	}
+}
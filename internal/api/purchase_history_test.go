@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/payment"
+)
+
+type stubPurchaseHistoryService struct {
+	page       payment.PurchaseHistoryPage
+	err        error
+	lastCursor string
+	lastLimit  int
+}
+
+func (s *stubPurchaseHistoryService) ListPurchaseHistory(_ context.Context, _ int64, cursor string, limit int) (payment.PurchaseHistoryPage, error) {
+	s.lastCursor, s.lastLimit = cursor, limit
+	return s.page, s.err
+}
+
+func newPurchaseHistoryTestRouter(t testing.TB, historySvc PurchaseHistoryService) http.Handler {
+	t.Helper()
+	userSvc := service.NewMemoryUserService()
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterUserRoutes(api, UserDeps{
+		Users:     userSvc,
+		Auth:      newTestAuthService(t, userSvc),
+		Purchases: historySvc,
+	})
+	return router
+}
+
+func TestPurchaseHistoryRouteListsEntries(t *testing.T) {
+	svc := &stubPurchaseHistoryService{page: payment.PurchaseHistoryPage{
+		Entries: []payment.PurchaseHistoryEntry{{
+			Type: model.PurchaseHistoryPlanChange, OccurredAt: time.Date(2026, 2, 23, 12, 0, 0, 0, time.UTC),
+			PlanID: "pro_monthly", ProductID: "com.app.pro.monthly",
+			ToPlanID: "basic_monthly", ToProductID: "com.app.basic.monthly", Reason: "DOWNGRADE",
+		}},
+		NextCursor: "next",
+	}}
+	rec := httptest.NewRecorder()
+	newPurchaseHistoryTestRouter(t, svc).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/users/me/purchases?cursor=abc&limit=5", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	var got struct {
+		Data model.PurchaseHistoryResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if svc.lastCursor != "abc" || svc.lastLimit != 5 {
+		t.Fatalf("cursor=%q limit=%d not passed through", svc.lastCursor, svc.lastLimit)
+	}
+	if len(got.Data.Items) != 1 || got.Data.NextCursor != "next" {
+		t.Fatalf("unexpected response: %+v", got.Data)
+	}
+	item := got.Data.Items[0]
+	if item.Type != "PLAN_CHANGE" || item.OccurredAt != "2026-02-23T12:00:00Z" || item.ToPlanID != "basic_monthly" {
+		t.Fatalf("unexpected item: %+v", item)
+	}
+}
+
+func TestPurchaseHistoryRouteErrors(t *testing.T) {
+	cases := map[string]struct {
+		svc  PurchaseHistoryService
+		want int
+	}{
+		"not configured": {svc: nil, want: http.StatusServiceUnavailable},
+		"invalid cursor": {svc: &stubPurchaseHistoryService{err: payment.ErrInvalidHistoryCursor}, want: http.StatusBadRequest},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newPurchaseHistoryTestRouter(t, tc.svc).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/users/me/purchases", nil))
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d; body=%s", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
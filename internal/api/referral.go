@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/auth"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/referral"
+)
+
+// ReferralService 是 /users/me/referral 路由所需的最小服务接口。
+//
+// 生产实现由 internal/service/referral.Service 提供；为 nil 时路由返回 503。
+type ReferralService interface {
+	GetInfo(ctx context.Context, userID int64) (referral.Info, error)
+	Redeem(ctx context.Context, in referral.RedeemInput) (referral.RedeemResult, error)
+}
+
+func registerUserReferralRoutes(api huma.API, authSvc auth.Service, referralSvc ReferralService) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-current-user-referral",
+		Method:      http.MethodGet,
+		Path:        "/users/me/referral",
+		Summary:     "获取我的邀请码与邀请统计",
+		Description: "返回当前用户长期不变的邀请码（首次访问时生成）、已成功邀请人数、累计获得的奖励，以及当前的邀请规则（双方奖励、邀请上限、兑换期限）。",
+		Tags:        []string{"users"},
+		Security:    []map[string][]string{{"bearerAuth": []string{}}},
+		Errors:      []int{http.StatusUnauthorized, http.StatusInternalServerError, http.StatusServiceUnavailable},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+	}) (*struct {
+		Body model.Response[model.ReferralInfoResponse]
+	}, error) {
+		userID, _, err := referralUser(ctx, authSvc, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		if referralSvc == nil {
+			return nil, huma.Error503ServiceUnavailable("邀请功能未配置")
+		}
+		info, err := referralSvc.GetInfo(ctx, userID)
+		if err != nil {
+			return nil, mapReferralError(err)
+		}
+		return &struct {
+			Body model.Response[model.ReferralInfoResponse]
+		}{
+			Body: model.Success(referralInfoView(info)),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "redeem-referral-code",
+		Method:      http.MethodPost,
+		Path:        "/users/me/referral/redeem",
+		Summary:     "兑换邀请码",
+		Description: "新用户在注册后的有限天数内可以兑换一次邀请码，兑换成功后邀请人与被邀请人同时获得奖励（积分和 / 或赠送会员天数）。\n\n- 不能兑换自己的邀请码\n- 每个用户、每台设备（游客身份）只能兑换一次；非游客账号必须上报 device_id\n- 邀请人达到邀请上限后该邀请码不再可用",
+		Tags:        []string{"users"},
+		Security:    []map[string][]string{{"bearerAuth": []string{}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusNotFound,
+			http.StatusConflict,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Body          model.RedeemReferralRequest
+	}) (*struct {
+		Body model.Response[model.RedeemReferralResponse]
+	}, error) {
+		userID, authedUser, err := referralUser(ctx, authSvc, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		if referralSvc == nil {
+			return nil, huma.Error503ServiceUnavailable("邀请功能未配置")
+		}
+		result, err := referralSvc.Redeem(ctx, referral.RedeemInput{
+			UserID:          userID,
+			Provider:        authedUser.Provider,
+			ProviderSubject: authedUser.ProviderSubject,
+			Code:            input.Body.Code,
+			DeviceID:        input.Body.DeviceID,
+		})
+		if err != nil {
+			return nil, mapReferralError(err)
+		}
+		return &struct {
+			Body model.Response[model.RedeemReferralResponse]
+		}{
+			Body: model.Success(model.RedeemReferralResponse{
+				Reward:     referralRewardView(result.Reward),
+				CompEndsAt: formatOptionalTime(result.CompEndsAt),
+			}),
+		}, nil
+	})
+}
+
+func referralUser(ctx context.Context, authSvc auth.Service, authHeader string) (int64, *model.UserInfo, error) {
+	authedUser, err := validateUserBearerToken(ctx, authSvc, authHeader)
+	if err != nil {
+		return 0, nil, err
+	}
+	userID, perr := strconv.ParseInt(authedUser.ID, 10, 64)
+	if perr != nil || userID <= 0 {
+		return 0, nil, huma.Error401Unauthorized("access token 无效")
+	}
+	return userID, authedUser, nil
+}
+
+func mapReferralError(err error) error {
+	switch {
+	case errors.Is(err, referral.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("邀请功能未配置")
+	case errors.Is(err, referral.ErrInvalidCode),
+		errors.Is(err, referral.ErrCodeNotFound):
+		return huma.Error404NotFound("邀请码不存在")
+	case errors.Is(err, referral.ErrSelfReferral):
+		return huma.Error400BadRequest("不能兑换自己的邀请码")
+	case errors.Is(err, referral.ErrDeviceRequired):
+		return huma.Error400BadRequest("缺少 device_id")
+	case errors.Is(err, referral.ErrRedeemWindowClosed):
+		return huma.Error400BadRequest("已超过邀请码兑换期限")
+	case errors.Is(err, referral.ErrAlreadyRedeemed):
+		return huma.Error409Conflict("已经兑换过邀请码")
+	case errors.Is(err, referral.ErrDeviceUsed):
+		return huma.Error409Conflict("该设备已经兑换过邀请码")
+	case errors.Is(err, referral.ErrInviterLimitReached):
+		return huma.Error409Conflict("该邀请码已达到邀请上限")
+	default:
+		return huma.Error500InternalServerError("邀请操作失败")
+	}
+}
+
+func referralInfoView(info referral.Info) model.ReferralInfoResponse {
+	remaining := -1
+	if info.Config.InviterCap > 0 {
+		remaining = max(info.Config.InviterCap-info.Stats.InvitedCount, 0)
+	}
+	return model.ReferralInfoResponse{
+		Code:             info.Code,
+		InvitedCount:     info.Stats.InvitedCount,
+		InviteLimit:      max(info.Config.InviterCap, 0),
+		RemainingInvites: remaining,
+		EarnedCredits:    info.Stats.EarnedCredits,
+		EarnedCompDays:   info.Stats.EarnedCompDays,
+		InviterReward:    referralRewardView(info.Config.InviterReward),
+		InviteeReward:    referralRewardView(info.Config.InviteeReward),
+		RedeemWindowDays: int(info.Config.RedeemWindow.Hours() / 24),
+		RedeemedCode:     info.RedeemedCode,
+	}
+}
+
+func referralRewardView(r model.ReferralRewards) model.ReferralRewardView {
+	return model.ReferralRewardView{Credits: r.Credits, CompDays: r.CompDays}
+}
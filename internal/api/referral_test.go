@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/referral"
+)
+
+type stubReferralService struct {
+	info      referral.Info
+	result    referral.RedeemResult
+	err       error
+	lastInput referral.RedeemInput
+}
+
+func (s *stubReferralService) GetInfo(context.Context, int64) (referral.Info, error) {
+	return s.info, s.err
+}
+
+func (s *stubReferralService) Redeem(_ context.Context, in referral.RedeemInput) (referral.RedeemResult, error) {
+	s.lastInput = in
+	return s.result, s.err
+}
+
+func newReferralTestRouter(t testing.TB, referralSvc ReferralService) http.Handler {
+	t.Helper()
+	userSvc := service.NewMemoryUserService()
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterUserRoutes(api, UserDeps{
+		Users:     userSvc,
+		Auth:      newTestAuthService(t, userSvc),
+		Referrals: referralSvc,
+	})
+	return router
+}
+
+func TestReferralRoutesGetInfo(t *testing.T) {
+	svc := &stubReferralService{info: referral.Info{
+		Code:  "K7M2Q9XA",
+		Stats: model.ReferralStats{InvitedCount: 3, EarnedCredits: 300},
+		Config: referral.Config{
+			RedeemWindow:  7 * 24 * time.Hour,
+			InviterCap:    50,
+			InviterReward: model.ReferralRewards{Credits: 100},
+			InviteeReward: model.ReferralRewards{Credits: 100, CompDays: 7},
+		},
+	}}
+	rec := httptest.NewRecorder()
+	newReferralTestRouter(t, svc).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/users/me/referral", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	var got struct {
+		Data model.ReferralInfoResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Data.Code != "K7M2Q9XA" || got.Data.RemainingInvites != 47 || got.Data.RedeemWindowDays != 7 || got.Data.InviteeReward.CompDays != 7 {
+		t.Fatalf("unexpected info: %+v", got.Data)
+	}
+}
+
+func TestReferralRoutesRedeemPassesGuestIdentity(t *testing.T) {
+	endsAt := time.Date(2026, 10, 8, 0, 0, 0, 0, time.UTC)
+	svc := &stubReferralService{result: referral.RedeemResult{
+		Reward:     model.ReferralRewards{Credits: 100, CompDays: 7},
+		CompEndsAt: &endsAt,
+	}}
+	rec := httptest.NewRecorder()
+	req := newAuthorizedUserRequest(t, http.MethodPost, "/users/me/referral/redeem", strings.NewReader(`{"code":"k7m2q9xa"}`))
+	req.Header.Set("Content-Type", "application/json")
+	newReferralTestRouter(t, svc).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if svc.lastInput.UserID != 1 || svc.lastInput.Provider != "guest" || svc.lastInput.ProviderSubject != "tester" || svc.lastInput.Code != "k7m2q9xa" {
+		t.Fatalf("unexpected redeem input: %+v", svc.lastInput)
+	}
+	if !strings.Contains(rec.Body.String(), `"comp_ends_at":"2026-10-08T00:00:00Z"`) {
+		t.Fatalf("missing comp_ends_at: %s", rec.Body.String())
+	}
+}
+
+func TestReferralRoutesRedeemErrorMapping(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{referral.ErrCodeNotFound, http.StatusNotFound},
+		{referral.ErrSelfReferral, http.StatusBadRequest},
+		{referral.ErrRedeemWindowClosed, http.StatusBadRequest},
+		{referral.ErrAlreadyRedeemed, http.StatusConflict},
+		{referral.ErrDeviceUsed, http.StatusConflict},
+		{referral.ErrInviterLimitReached, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := newAuthorizedUserRequest(t, http.MethodPost, "/users/me/referral/redeem", strings.NewReader(`{"code":"ABC"}`))
+			req.Header.Set("Content-Type", "application/json")
+			newReferralTestRouter(t, &stubReferralService{err: tt.err}).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body=%s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestReferralRoutesNotConfigured(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newReferralTestRouter(t, nil).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/users/me/referral", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/auth"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/settings"
+)
+
+// SettingsService 是 /users/me/settings 路由所需的最小服务接口。
+//
+// 生产实现由 internal/service/settings.Service 提供；为 nil 时两个路由都返回 503。
+type SettingsService interface {
+	Get(ctx context.Context, userID int64) (map[string]any, error)
+	Update(ctx context.Context, userID int64, patch map[string]json.RawMessage) (map[string]any, error)
+}
+
+func registerUserSettingsRoutes(api huma.API, authSvc auth.Service, settingsSvc SettingsService) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-current-user-settings",
+		Method:      http.MethodGet,
+		Path:        "/users/me/settings",
+		Summary:     "获取当前用户的偏好设置",
+		Description: "返回服务端登记的全部配置项及其当前值。用户未修改过的配置项返回服务端默认值，因此客户端不需要内置默认值。",
+		Tags:        []string{"users"},
+		Security:    []map[string][]string{{"bearerAuth": []string{}}},
+		Errors:      []int{http.StatusUnauthorized, http.StatusInternalServerError, http.StatusServiceUnavailable},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+	}) (*struct {
+		Body model.Response[model.UserSettings]
+	}, error) {
+		userID, err := settingsUserID(ctx, authSvc, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		if settingsSvc == nil {
+			return nil, huma.Error503ServiceUnavailable("用户设置未配置")
+		}
+		values, err := settingsSvc.Get(ctx, userID)
+		if err != nil {
+			return nil, mapSettingsError(err)
+		}
+		return &struct {
+			Body model.Response[model.UserSettings]
+		}{
+			Body: model.Success(model.UserSettings{Settings: values}),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "update-current-user-settings",
+		Method:      http.MethodPut,
+		Path:        "/users/me/settings",
+		Summary:     "部分更新当前用户的偏好设置",
+		Description: "只需提交要修改的配置项，未提交的保持不变；值为 null 表示恢复默认值。\n\n任一 key 未在服务端登记或值类型不符时返回 400，且本次请求中的所有修改都不会生效。成功时返回更新后的完整设置。",
+		Tags:        []string{"users"},
+		Security:    []map[string][]string{{"bearerAuth": []string{}}},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+		},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization" hidden:"true"`
+		Body          model.UpdateUserSettingsRequest
+	}) (*struct {
+		Body model.Response[model.UserSettings]
+	}, error) {
+		userID, err := settingsUserID(ctx, authSvc, input.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		if settingsSvc == nil {
+			return nil, huma.Error503ServiceUnavailable("用户设置未配置")
+		}
+		values, err := settingsSvc.Update(ctx, userID, input.Body.Settings)
+		if err != nil {
+			return nil, mapSettingsError(err)
+		}
+		return &struct {
+			Body model.Response[model.UserSettings]
+		}{
+			Body: model.Success(model.UserSettings{Settings: values}),
+		}, nil
+	})
+}
+
+func settingsUserID(ctx context.Context, authSvc auth.Service, authHeader string) (int64, error) {
+	authedUser, err := validateUserBearerToken(ctx, authSvc, authHeader)
+	if err != nil {
+		return 0, err
+	}
+	userID, perr := strconv.ParseInt(authedUser.ID, 10, 64)
+	if perr != nil || userID <= 0 {
+		return 0, huma.Error401Unauthorized("access token 无效")
+	}
+	return userID, nil
+}
+
+func mapSettingsError(err error) error {
+	switch {
+	case errors.Is(err, settings.ErrNotConfigured):
+		return huma.Error503ServiceUnavailable("用户设置未配置")
+	case errors.Is(err, settings.ErrUnknownKey),
+		errors.Is(err, settings.ErrInvalidValue),
+		errors.Is(err, settings.ErrEmptyPatch):
+		return huma.Error400BadRequest(err.Error())
+	default:
+		return huma.Error500InternalServerError("读写用户设置失败")
+	}
+}
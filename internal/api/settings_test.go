@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/service"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/settings"
+)
+
+type memorySettingsDAO struct {
+	rows map[string]json.RawMessage
+}
+
+func (d *memorySettingsDAO) ListSettings(context.Context, int64) (map[string]json.RawMessage, error) {
+	out := map[string]json.RawMessage{}
+	for k, v := range d.rows {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (d *memorySettingsDAO) UpdateSettings(_ context.Context, _ int64, set map[string]json.RawMessage, reset []string) error {
+	for k, v := range set {
+		d.rows[k] = v
+	}
+	for _, k := range reset {
+		delete(d.rows, k)
+	}
+	return nil
+}
+
+func newSettingsTestRouter(t testing.TB, settingsSvc SettingsService) http.Handler {
+	t.Helper()
+	userSvc := service.NewMemoryUserService()
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterUserRoutes(api, UserDeps{
+		Users:    userSvc,
+		Auth:     newTestAuthService(t, userSvc),
+		Settings: settingsSvc,
+	})
+	return router
+}
+
+type settingsEnvelope struct {
+	Data struct {
+		Settings map[string]any `json:"settings"`
+	} `json:"data"`
+}
+
+func TestSettingsRoutesGetReturnsDefaults(t *testing.T) {
+	svc := settings.NewService(&memorySettingsDAO{rows: map[string]json.RawMessage{}}, nil, nil, 0)
+	rec := httptest.NewRecorder()
+	newSettingsTestRouter(t, svc).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/users/me/settings", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	var got settingsEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Data.Settings["notifications.push_enabled"] != true || got.Data.Settings["content.filter_level"] != "moderate" {
+		t.Fatalf("unexpected settings: %+v", got.Data.Settings)
+	}
+}
+
+func TestSettingsRoutesPartialUpdate(t *testing.T) {
+	dao := &memorySettingsDAO{rows: map[string]json.RawMessage{}}
+	svc := settings.NewService(dao, nil, nil, 0)
+	router := newSettingsTestRouter(t, svc)
+
+	req := newAuthorizedUserRequest(t, http.MethodPut, "/users/me/settings",
+		strings.NewReader(`{"settings":{"notifications.email_enabled":true}}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	var got settingsEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Data.Settings["notifications.email_enabled"] != true || got.Data.Settings["notifications.push_enabled"] != true {
+		t.Fatalf("unexpected settings: %+v", got.Data.Settings)
+	}
+	if len(dao.rows) != 1 {
+		t.Fatalf("only the submitted key should be stored: %+v", dao.rows)
+	}
+}
+
+func TestSettingsRoutesRejectUnknownKey(t *testing.T) {
+	dao := &memorySettingsDAO{rows: map[string]json.RawMessage{}}
+	svc := settings.NewService(dao, nil, nil, 0)
+
+	req := newAuthorizedUserRequest(t, http.MethodPut, "/users/me/settings",
+		strings.NewReader(`{"settings":{"notifications.email_enabled":true,"ui.theme":"dark"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	newSettingsTestRouter(t, svc).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400; body=%s", rec.Code, rec.Body.String())
+	}
+	if len(dao.rows) != 0 {
+		t.Fatalf("rejected request must not write: %+v", dao.rows)
+	}
+}
+
+func TestSettingsRoutesUnavailableWithoutService(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newSettingsTestRouter(t, nil).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/users/me/settings", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+}
@@ -12,27 +12,45 @@ import (
 	"github.com/dundunHa/go-serverhttp-template/internal/model"
 	"github.com/dundunHa/go-serverhttp-template/internal/service"
 	"github.com/dundunHa/go-serverhttp-template/internal/service/auth"
+	logpkg "github.com/dundunHa/go-serverhttp-template/pkg/log"
 )
 
 type UserDeps struct {
-	Users         service.UserService
-	Auth          auth.Service
-	Subscriptions SubscriptionReader
+	Users        service.UserService
+	Auth         auth.Service
+	Entitlements EntitlementReader
+	Credits      CreditsReader
+	Settings     SettingsService
+	Referrals    ReferralService
+	PromoCodes   PromoCodeService
+	Purchases    PurchaseHistoryService
 }
 
-// SubscriptionReader 是 /users/me 用来获取 provider-neutral 订阅状态的依赖。
+// EntitlementReader 是 /users/me 用来获取 provider-neutral 权益视图的依赖。
 //
-// 生产实现由 internal/service/payment.SubscriptionReader 提供；测试可以注入空实现，
-// 此时 loadCurrentUser 返回 SubscriptionInfo{Status: "NONE"}。
-type SubscriptionReader interface {
-	LoadSubscriptionInfo(ctx context.Context, userID int64) (model.SubscriptionInfo, error)
+// 生产实现由 internal/service/entitlement.Engine 提供；为 nil 时 loadCurrentUser 返回
+// SubscriptionInfo{Status: "NONE"} 与空的 entitlements。
+type EntitlementReader interface {
+	LoadEntitlements(ctx context.Context, userID int64) (model.EntitlementSummary, error)
+}
+
+// CreditsReader 是 /users/me 用来获取积分余额视图的依赖。
+//
+// 生产实现由 internal/service/credits.Service 提供；为 nil 时 loadCurrentUser 返回余额 0、
+// breakdown 为空数组。
+type CreditsReader interface {
+	LoadCredits(ctx context.Context, userID int64) (model.Credits, error)
 }
 
 func RegisterUserRoutes(api huma.API, deps UserDeps) {
 	registerUserBearerAuth(api)
 	registerAPIDocMetadata(api)
 	registerUserHelloRoute(api)
-	registerUserRoutes(api, deps.Users, deps.Auth, deps.Subscriptions)
+	registerUserRoutes(api, deps.Users, deps.Auth, deps.Entitlements, deps.Credits)
+	registerUserSettingsRoutes(api, deps.Auth, deps.Settings)
+	registerUserReferralRoutes(api, deps.Auth, deps.Referrals)
+	registerUserPromoCodeRoutes(api, deps.Auth, deps.PromoCodes)
+	registerUserPurchaseHistoryRoute(api, deps.Auth, deps.Purchases)
 	registerUserAuthRoutes(api, deps.Auth)
 }
 
@@ -100,16 +118,16 @@ func registerUserHelloRoute(api huma.API) {
 	})
 }
 
-func registerUserRoutes(api huma.API, userSvc service.UserService, authSvc auth.Service, subscriptions SubscriptionReader) {
+func registerUserRoutes(api huma.API, userSvc service.UserService, authSvc auth.Service, entitlements EntitlementReader, credits CreditsReader) {
 	huma.Register(api, huma.Operation{
 		OperationID: "get-current-user",
 		Method:      http.MethodGet,
 		Path:        "/users/me",
 		Summary:     "获取当前登录用户信息",
-		Description: "根据 Authorization 请求头中的 Bearer access token 解析出当前用户身份，并返回该用户的基础信息、积分余额以及订阅状态。不接收任何路径或查询参数。",
+		Description: "根据 Authorization 请求头中的 Bearer access token 解析出当前用户身份，并返回该用户的基础信息、积分余额、订阅状态以及按 feature set 合并后的权益（Apple、Google Play、Stripe 与赠送权益取等级最高、到期最晚的一条）。不接收任何路径或查询参数。",
 		Tags:        []string{"users"},
 		Security:    []map[string][]string{{"bearerAuth": []string{}}},
-		Errors:      []int{http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError},
+		Errors:      []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError},
 	}, func(ctx context.Context, input *struct {
 		Authorization string `header:"Authorization" hidden:"true"`
 	}) (*struct {
@@ -120,7 +138,7 @@ func registerUserRoutes(api huma.API, userSvc service.UserService, authSvc auth.
 			return nil, err
 		}
 
-		me, err := loadCurrentUser(ctx, userSvc, subscriptions, authedUser)
+		me, err := loadCurrentUser(ctx, userSvc, entitlements, credits, authedUser)
 		if err != nil {
 			return nil, err
 		}
@@ -135,9 +153,9 @@ func registerUserRoutes(api huma.API, userSvc service.UserService, authSvc auth.
 
 // loadCurrentUser 根据 JWT 中的用户标识，组装 /users/me 的返回数据。
 //
-// Credits.Balance 仍然保持 0：plan U7 明确不引入 credits/wallet。
-// SubscriptionInfo 由注入的 SubscriptionReader 给出；reader 为 nil 时退化为 Status="NONE"。
-func loadCurrentUser(ctx context.Context, userSvc service.UserService, subscriptions SubscriptionReader, authedUser *model.UserInfo) (*model.MeData, error) {
+// Credits 由注入的 CreditsReader 给出；reader 为 nil 时余额为 0、breakdown 为空数组。
+// SubscriptionInfo 与 Entitlements 由注入的 EntitlementReader 给出；reader 为 nil 时退化为 Status="NONE"。
+func loadCurrentUser(ctx context.Context, userSvc service.UserService, entitlements EntitlementReader, credits CreditsReader, authedUser *model.UserInfo) (*model.MeData, error) {
 	id, err := strconv.Atoi(authedUser.ID)
 	if err != nil || id <= 0 {
 		return nil, huma.Error401Unauthorized("access token 无效")
@@ -152,20 +170,37 @@ func loadCurrentUser(ctx context.Context, userSvc service.UserService, subscript
 	}
 
 	subInfo := model.SubscriptionInfo{Status: "NONE"}
-	if subscriptions != nil {
-		info, err := subscriptions.LoadSubscriptionInfo(ctx, int64(user.ID))
+	granted := []model.Entitlement{}
+	if entitlements != nil {
+		summary, err := entitlements.LoadEntitlements(ctx, int64(user.ID))
 		if err != nil {
 			return nil, huma.Error500InternalServerError("获取订阅状态失败")
 		}
-		subInfo = info
+		subInfo = summary.Subscription
 		if subInfo.Status == "" {
 			subInfo.Status = "NONE"
 		}
+		if summary.Entitlements != nil {
+			granted = summary.Entitlements
+		}
+	}
+
+	creditsInfo := model.Credits{Breakdown: []model.CreditBreakdown{}}
+	if credits != nil {
+		info, err := credits.LoadCredits(ctx, int64(user.ID))
+		if err != nil {
+			return nil, huma.Error500InternalServerError("获取积分余额失败")
+		}
+		creditsInfo = info
+		if creditsInfo.Breakdown == nil {
+			creditsInfo.Breakdown = []model.CreditBreakdown{}
+		}
 	}
 
 	return &model.MeData{
-		Credits:          model.Credits{},
+		Credits:          creditsInfo,
 		SubscriptionInfo: subInfo,
+		Entitlements:     granted,
 		User: model.UserSummary{
 			ID:   strconv.Itoa(user.ID),
 			Name: user.Name,
@@ -186,6 +221,13 @@ func validateUserBearerToken(ctx context.Context, authSvc auth.Service, authHead
 	}
 	user, err := authSvc.AuthenticateAccessToken(ctx, authFields[1])
 	if err != nil {
+		if accountErr := accountAccessError(err); accountErr != nil {
+			return nil, accountErr
+		}
+		if errors.Is(err, auth.ErrAccountStateUnavailable) {
+			logpkg.FromContext(ctx).ErrorContext(ctx, "check account state failed", "err", err)
+			return nil, huma.Error503ServiceUnavailable("账号状态暂不可用，请稍后重试")
+		}
 		return nil, huma.Error401Unauthorized("access token 无效")
 	}
 	return user, nil
@@ -197,11 +239,12 @@ func registerUserAuthRoutes(api huma.API, authSvc auth.Service) {
 		Method:      http.MethodPost,
 		Path:        "/auth/{provider}",
 		Summary:     "校验第三方登录凭证并颁发 access token",
-		Description: "校验指定 provider（gmail / apple / guest）的登录凭证，成功后会颁发本服务的 JWT access token，后续业务接口可使用该 token 作为 Bearer 身份。\n\n- gmail：Google ID Token\n- apple：Sign in with Apple identityToken\n- guest：客户端生成的设备 ID",
+		Description: "校验指定 provider（gmail / apple / guest）的登录凭证，成功后会颁发本服务的 JWT access token，后续业务接口可使用该 token 作为 Bearer 身份。\n\n- gmail：Google ID Token\n- apple：Sign in with Apple identityToken\n- guest：客户端生成的设备 ID\n\n已被暂停、封禁或注销的账号返回 403，响应体中的 code 为 ACCOUNT_SUSPENDED / ACCOUNT_BANNED / ACCOUNT_DELETED。",
 		Tags:        []string{"auth"},
 		Errors: []int{
 			http.StatusBadRequest,
 			http.StatusUnauthorized,
+			http.StatusForbidden,
 			http.StatusNotFound,
 			http.StatusUnprocessableEntity,
 			http.StatusInternalServerError,
@@ -223,6 +266,9 @@ func registerUserAuthRoutes(api huma.API, authSvc auth.Service) {
 			if errors.Is(err, auth.ErrIdentityUnavailable) || errors.Is(err, service.ErrAuthIdentityUnsupported) {
 				return nil, huma.Error500InternalServerError("身份解析服务不可用")
 			}
+			if accountErr := accountAccessError(err); accountErr != nil {
+				return nil, accountErr
+			}
 			return nil, huma.Error401Unauthorized(err.Error())
 		}
 		accessToken, expiresIn, err := authSvc.IssueAccessToken(ctx, *user)
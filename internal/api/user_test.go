@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -152,6 +153,126 @@ func TestUserRoutesGetCurrentUser(t *testing.T) {
 	}
 }
 
+type stubCreditsReader struct {
+	credits model.Credits
+	err     error
+}
+
+func (s stubCreditsReader) LoadCredits(context.Context, int64) (model.Credits, error) {
+	return s.credits, s.err
+}
+
+func newUserTestRouterWithCredits(t testing.TB, credits CreditsReader) http.Handler {
+	t.Helper()
+	userSvc := service.NewMemoryUserService()
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("Test API", "0.1.0"))
+	RegisterUserRoutes(api, UserDeps{
+		Users:   userSvc,
+		Auth:    newTestAuthService(t, userSvc),
+		Credits: credits,
+	})
+	return router
+}
+
+func TestUserRoutesGetCurrentUserIncludesCreditsBreakdown(t *testing.T) {
+	req := newAuthorizedUserRequest(t, http.MethodGet, "/users/me", nil)
+	rec := httptest.NewRecorder()
+
+	newUserTestRouterWithCredits(t, stubCreditsReader{credits: model.Credits{
+		Balance: 30,
+		Breakdown: []model.CreditBreakdown{
+			{Source: model.CreditSourcePromo, Balance: 10},
+			{Source: model.CreditSourcePurchase, Balance: 20},
+		},
+		NextExpiresAt:      "2026-06-01T00:00:00Z",
+		NextExpiringAmount: 10,
+	}}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var got struct {
+		Data struct {
+			Credits model.Credits `json:"credits"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	c := got.Data.Credits
+	if c.Balance != 30 || len(c.Breakdown) != 2 || c.NextExpiresAt != "2026-06-01T00:00:00Z" || c.NextExpiringAmount != 10 {
+		t.Fatalf("unexpected credits: %+v", c)
+	}
+}
+
+func TestUserRoutesGetCurrentUserEmptyCreditsBreakdownIsArray(t *testing.T) {
+	req := newAuthorizedUserRequest(t, http.MethodGet, "/users/me", nil)
+	rec := httptest.NewRecorder()
+
+	newUserTestRouter(t).ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), `"breakdown":[]`) {
+		t.Fatalf("expected empty breakdown array; body=%s", rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "next_expires_at") {
+		t.Fatalf("next_expires_at should be omitted without expiring credits; body=%s", rec.Body.String())
+	}
+}
+
+type stubEntitlementReader struct {
+	summary model.EntitlementSummary
+}
+
+func (s stubEntitlementReader) LoadEntitlements(context.Context, int64) (model.EntitlementSummary, error) {
+	return s.summary, nil
+}
+
+func TestUserRoutesGetCurrentUserIncludesEntitlements(t *testing.T) {
+	userSvc := service.NewMemoryUserService()
+	router := chi.NewRouter()
+	RegisterUserRoutes(humachi.New(router, huma.DefaultConfig("Test API", "0.1.0")), UserDeps{
+		Users: userSvc,
+		Auth:  newTestAuthService(t, userSvc),
+		Entitlements: stubEntitlementReader{summary: model.EntitlementSummary{
+			Subscription: model.SubscriptionInfo{ProductID: "pro_monthly", Status: "ACTIVE", SubscribeLevel: 2},
+			Entitlements: []model.Entitlement{{FeatureSet: "pro", PlanID: "pro_monthly", Source: model.EntitlementSourceStripe, Status: "ACTIVE", Level: 2}},
+		}},
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/users/me", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var got struct {
+		Data model.MeData `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Data.SubscriptionInfo.ProductID != "pro_monthly" || len(got.Data.Entitlements) != 1 || got.Data.Entitlements[0].FeatureSet != "pro" {
+		t.Fatalf("unexpected data: %+v", got.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	newUserTestRouter(t).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/users/me", nil))
+	if !strings.Contains(rec.Body.String(), `"entitlements":[]`) {
+		t.Fatalf("expected empty entitlements array; body=%s", rec.Body.String())
+	}
+}
+
+func TestUserRoutesGetCurrentUserCreditsError(t *testing.T) {
+	req := newAuthorizedUserRequest(t, http.MethodGet, "/users/me", nil)
+	rec := httptest.NewRecorder()
+
+	newUserTestRouterWithCredits(t, stubCreditsReader{err: errors.New("db down")}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d; body=%s", rec.Code, http.StatusInternalServerError, rec.Body.String())
+	}
+}
+
 func TestUserRoutesGetCurrentUserReturnNotFound(t *testing.T) {
 	req := newAuthorizedUserRequestForUser(t, model.UserInfo{
 		ID:              "404",
@@ -311,3 +432,103 @@ func TestUserRoutesOpenAPI(t *testing.T) {
 		t.Fatalf("openapi response missing bearer auth scheme: %s", rec.Body.String())
 	}
 }
+
+type stubAccountStates map[int64]model.AccountState
+
+func (s stubAccountStates) GetAccountState(_ context.Context, userID int64) (model.AccountState, error) {
+	return s[userID], nil
+}
+
+type blockedIdentityResolver struct {
+	err error
+}
+
+func (r blockedIdentityResolver) ResolveAuthIdentity(context.Context, model.AuthIdentity) (*model.UserInfo, error) {
+	return nil, r.err
+}
+
+type accountErrorBody struct {
+	Status    int    `json:"status"`
+	Code      string `json:"code"`
+	Reason    string `json:"reason"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+func TestUserRoutesBlockedAccountReturnsAccountCode(t *testing.T) {
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	userSvc := service.NewMemoryUserService()
+	authSvc := newTestAuthService(t, userSvc).WithAccountStates(stubAccountStates{
+		1: {UserID: 1, Status: model.UserStatusBanned, StatusReason: "spam", StatusExpiresAt: &until},
+	})
+	rec := httptest.NewRecorder()
+	newUserTestRouterWithDeps(t, userSvc, authSvc).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/users/me", nil))
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403; body=%s", rec.Code, rec.Body.String())
+	}
+	var got accountErrorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Code != model.AccountErrorBanned || got.Reason != "spam" || got.ExpiresAt != "2030-01-01T00:00:00Z" {
+		t.Fatalf("unexpected error body: %+v", got)
+	}
+}
+
+func TestUserRoutesRevokedSessionReturnsSessionCode(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	userSvc := service.NewMemoryUserService()
+	authSvc := newTestAuthService(t, userSvc).WithAccountStates(stubAccountStates{
+		1: {UserID: 1, Status: model.UserStatusActive, SessionsRevokedAt: &future},
+	})
+	rec := httptest.NewRecorder()
+	newUserTestRouterWithDeps(t, userSvc, authSvc).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/users/me", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401; body=%s", rec.Code, rec.Body.String())
+	}
+	var got accountErrorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Code != model.AccountErrorSessionRevoked {
+		t.Fatalf("code = %q, want %q", got.Code, model.AccountErrorSessionRevoked)
+	}
+}
+
+type unavailableAccountStates struct{}
+
+func (unavailableAccountStates) GetAccountState(context.Context, int64) (model.AccountState, error) {
+	return model.AccountState{}, errors.New("connection refused")
+}
+
+func TestUserRoutesAccountStateFailureReturns503(t *testing.T) {
+	userSvc := service.NewMemoryUserService()
+	authSvc := newTestAuthService(t, userSvc).WithAccountStates(unavailableAccountStates{})
+	rec := httptest.NewRecorder()
+	newUserTestRouterWithDeps(t, userSvc, authSvc).ServeHTTP(rec, newAuthorizedUserRequest(t, http.MethodGet, "/users/me", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503; body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestUserRoutesAuthRejectsBlockedAccount(t *testing.T) {
+	userSvc := service.NewMemoryUserService()
+	authSvc := newTestAuthService(t, blockedIdentityResolver{err: &model.AccountBlockedError{Status: model.UserStatusSuspended, Reason: "review"}})
+	req := httptest.NewRequest(http.MethodPost, "/auth/guest", strings.NewReader(`{"token":"device-1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	newUserTestRouterWithDeps(t, userSvc, authSvc).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403; body=%s", rec.Code, rec.Body.String())
+	}
+	var got accountErrorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Code != model.AccountErrorSuspended || got.Status != http.StatusForbidden {
+		t.Fatalf("unexpected error body: %+v", got)
+	}
+}
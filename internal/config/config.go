@@ -28,6 +28,133 @@ type Config struct {
 	Auth AuthConfig `envconfig:"AUTH"`
 
 	AppleIAP AppleIAPConfig `envconfig:"APPLE_IAP"`
+
+	AppleStatusSync AppleStatusSyncConfig `envconfig:"APPLE_STATUS_SYNC"`
+
+	AppleConsumption AppleConsumptionConfig `envconfig:"APPLE_CONSUMPTION"`
+
+	PayloadArchive PayloadArchiveConfig `envconfig:"PAYLOAD_ARCHIVE"`
+
+	GooglePlay GooglePlayConfig `envconfig:"GOOGLE_PLAY"`
+
+	Stripe StripeConfig `envconfig:"STRIPE"`
+
+	Entitlement EntitlementConfig `envconfig:"ENTITLEMENT"`
+
+	Credits CreditsConfig `envconfig:"CREDITS"`
+
+	Settings SettingsConfig `envconfig:"SETTINGS"`
+
+	Referral ReferralConfig `envconfig:"REFERRAL"`
+
+	Paywall PaywallConfig `envconfig:"PAYWALL"`
+}
+
+// PaywallConfig 描述付费墙 A/B 实验，环境变量以 PAYWALL_ 为前缀。
+//
+// Experiments 为实验的 JSON 数组，例如
+// [{"id":"annual_first","platform":"ios","variants":[{"id":"control","weight":50},
+// {"id":"annual_first","weight":50,"plan_ids":["pro_yearly","pro_monthly"],"recommended_plan_id":"pro_yearly"}]}]；
+// plan_ids 为空的分组为对照组，platform 省略表示所有平台。为空时不做实验。
+type PaywallConfig struct {
+	Experiments string `envconfig:"EXPERIMENTS"`
+}
+
+// ReferralConfig 描述邀请码兑换规则与奖励，环境变量以 REFERRAL_ 为前缀。
+//
+// 奖励可以是积分、赠送会员天数或两者兼有；某一项为 0 表示不发放该项。
+type ReferralConfig struct {
+	RedeemWindow          time.Duration `envconfig:"REDEEM_WINDOW" default:"168h"`
+	InviterCap            int           `envconfig:"INVITER_CAP" default:"50"`
+	InviterRewardCredits  int64         `envconfig:"INVITER_REWARD_CREDITS" default:"100"`
+	InviterRewardCompDays int           `envconfig:"INVITER_REWARD_COMP_DAYS" default:"0"`
+	InviteeRewardCredits  int64         `envconfig:"INVITEE_REWARD_CREDITS" default:"100"`
+	InviteeRewardCompDays int           `envconfig:"INVITEE_REWARD_COMP_DAYS" default:"0"`
+	RewardCreditsTTL      time.Duration `envconfig:"REWARD_CREDITS_TTL" default:"0"`
+	CompPlanID            string        `envconfig:"COMP_PLAN_ID" default:"referral"`
+	CompLevel             int           `envconfig:"COMP_LEVEL" default:"1"`
+}
+
+// EntitlementConfig 描述权益合并规则，环境变量以 ENTITLEMENT_ 为前缀。
+//
+// FeatureSets 为 plan_id 到 feature set 的 JSON 映射，例如 {"pro_monthly":"pro","lifetime":"pro"}；
+// 未声明的 plan 归入默认 feature set。
+type EntitlementConfig struct {
+	FeatureSets string `envconfig:"FEATURE_SETS"`
+
+	Webhook EntitlementWebhookConfig `envconfig:"WEBHOOK"`
+}
+
+// EntitlementWebhookConfig 描述出站权益 webhook 的投递任务，环境变量以 ENTITLEMENT_WEBHOOK_ 为前缀。
+//
+// 任务每隔 Interval 领取最多 BatchSize 条到期的投递，每个请求最多等待 Timeout；失败后按 RetryBackoff 指数退避
+// （单次间隔不超过 MaxRetryBackoff），累计 MaxAttempts 次后记为 DEAD，由管理员重放。订阅方在 /admin/webhooks 下管理。Interval <= 0 时不启动任务。
+type EntitlementWebhookConfig struct {
+	Interval        time.Duration `envconfig:"INTERVAL" default:"10s"`
+	BatchSize       int           `envconfig:"BATCH_SIZE" default:"50"`
+	MaxAttempts     int           `envconfig:"MAX_ATTEMPTS" default:"10"`
+	RetryBackoff    time.Duration `envconfig:"RETRY_BACKOFF" default:"30s"`
+	MaxRetryBackoff time.Duration `envconfig:"MAX_RETRY_BACKOFF" default:"1h"`
+	Timeout         time.Duration `envconfig:"TIMEOUT" default:"10s"`
+}
+
+// SettingsConfig 描述用户偏好设置的缓存配置，环境变量以 SETTINGS_ 为前缀。
+type SettingsConfig struct {
+	CacheTTL time.Duration `envconfig:"CACHE_TTL" default:"10m"`
+}
+
+// CreditsConfig 描述积分过期清理任务的配置，环境变量以 CREDITS_ 为前缀。
+//
+// SweepInterval <= 0 时不启动后台清理；过期 bucket 在读路径上仍然不计入余额，
+// 只是不会写 EXPIRE ledger。
+type CreditsConfig struct {
+	SweepInterval  time.Duration `envconfig:"SWEEP_INTERVAL" default:"5m"`
+	SweepBatchSize int           `envconfig:"SWEEP_BATCH_SIZE" default:"500"`
+}
+
+// AppleStatusSyncConfig 描述 Apple 订阅状态定时同步任务，环境变量以 APPLE_STATUS_SYNC_ 为前缀。
+//
+// 任务每隔 Interval 挑选 current_period_end 落在 ExpiryWindow 内（或已过期）、或超过 StaleAfter 没有收到通知的
+// ACTIVE / CANCELED 订阅，调用 Apple Get All Subscription Statuses 修正本地状态；同一订阅在 ResyncAfter 内不重复查询。
+// Concurrency 限制同时在途的 Apple 请求数，RatePerSecond 限制每秒发起的请求数。Interval <= 0 时不启动任务。
+type AppleStatusSyncConfig struct {
+	Interval      time.Duration `envconfig:"INTERVAL" default:"1h"`
+	ExpiryWindow  time.Duration `envconfig:"EXPIRY_WINDOW" default:"24h"`
+	StaleAfter    time.Duration `envconfig:"STALE_AFTER" default:"168h"`
+	ResyncAfter   time.Duration `envconfig:"RESYNC_AFTER" default:"6h"`
+	BatchSize     int           `envconfig:"BATCH_SIZE" default:"200"`
+	Concurrency   int           `envconfig:"CONCURRENCY" default:"4"`
+	RatePerSecond int           `envconfig:"RATE_PER_SECOND" default:"10"`
+}
+
+// AppleConsumptionConfig 描述 CONSUMPTION_REQUEST 的回复任务，环境变量以 APPLE_CONSUMPTION_ 为前缀。
+//
+// 任务每隔 Interval 取出最多 BatchSize 条到期的请求，调用 Send Consumption Information；失败后按 RetryBackoff
+// 指数退避（单次间隔不超过 MaxRetryBackoff），累计 MaxAttempts 次或超过 Apple 的 12 小时回复期限后记为 FAILED。CustomerConsented 表示用户已同意
+// 向 Apple 提供消费数据（Apple 只接受已同意的回复），为 false 时请求直接记为 FAILED 而不发送。RefundPreference 取
+// Apple 的 refundPreference 枚举（0 未声明、1 同意退款、2 拒绝退款、3 无偏好）。Interval <= 0 时不启动任务。
+type AppleConsumptionConfig struct {
+	Interval          time.Duration `envconfig:"INTERVAL" default:"1m"`
+	BatchSize         int           `envconfig:"BATCH_SIZE" default:"100"`
+	MaxAttempts       int           `envconfig:"MAX_ATTEMPTS" default:"5"`
+	RetryBackoff      time.Duration `envconfig:"RETRY_BACKOFF" default:"1m"`
+	MaxRetryBackoff   time.Duration `envconfig:"MAX_RETRY_BACKOFF" default:"1h"`
+	CustomerConsented bool          `envconfig:"CUSTOMER_CONSENTED" default:"false"`
+	RefundPreference  int           `envconfig:"REFUND_PREFERENCE" default:"0"`
+}
+
+// PayloadArchiveConfig 描述解码后 Apple 通知 / transaction 的加密归档，环境变量以 PAYLOAD_ARCHIVE_ 为前缀。
+//
+// Keys 为逗号分隔的 "<key id>:<base64 编码的 32 字节 key>"，ActiveKeyID 指定新归档使用的 master key
+// （只有一把 key 时可省略）。轮换时追加新 key 并切换 ActiveKeyID，旧 key 保留到后台任务把存量归档重新包裹完毕。
+// Keys 属于凭证，不设 default；未配置时不归档。归档在 Retention 后由每隔 PurgeInterval 运行的任务删除，
+// 每批最多 BatchSize 条；PurgeInterval <= 0 时不启动任务。
+type PayloadArchiveConfig struct {
+	Keys          string        `envconfig:"KEYS"`
+	ActiveKeyID   string        `envconfig:"ACTIVE_KEY_ID"`
+	Retention     time.Duration `envconfig:"RETENTION" default:"2160h"`
+	PurgeInterval time.Duration `envconfig:"PURGE_INTERVAL" default:"1h"`
+	BatchSize     int           `envconfig:"BATCH_SIZE" default:"500"`
 }
 
 // AppleIAPConfig 描述 Apple In-App Purchase 订阅相关配置。
@@ -48,12 +175,58 @@ type AppleIAPConfig struct {
 	PrivateKey string `envconfig:"PRIVATE_KEY"`
 	P8Path     string `envconfig:"P8_PATH"`
 	Products   string `envconfig:"PRODUCTS"`
+	// Offers 是 StoreKit 促销 / 挽回优惠规则（JSON），未配置时 offer-signature 路由对所有优惠返回 404。
+	Offers string `envconfig:"OFFERS"`
 
 	EntitlementEnvironments string        `envconfig:"ENTITLEMENT_ENVIRONMENTS" default:"Production"`
 	EnableSandboxFallback   bool          `envconfig:"ENABLE_SANDBOX_FALLBACK" default:"false"`
 	WebhookMaxBodyBytes     int           `envconfig:"WEBHOOK_MAX_BODY_BYTES" default:"65536"`
 	StoreRawPayloads        bool          `envconfig:"STORE_RAW_PAYLOADS" default:"false"`
 	AppleAPITimeout         time.Duration `envconfig:"APPLE_API_TIMEOUT" default:"10s"`
+	// PendingRedriveInterval 是重放 PENDING_USER_BINDING 通知的兜底周期，<= 0 时只在 token 绑定 / verify 成功时重放。
+	PendingRedriveInterval time.Duration `envconfig:"PENDING_REDRIVE_INTERVAL" default:"10m"`
+	// CatalogRefreshInterval 是从 apple_products 重新加载商品目录的周期，<= 0 时只在启动与后台修改后加载。
+	CatalogRefreshInterval time.Duration `envconfig:"CATALOG_REFRESH_INTERVAL" default:"30s"`
+}
+
+// GooglePlayConfig 描述 Google Play Billing 相关配置，环境变量以 GOOGLE_PLAY_ 为前缀。
+//
+// 与 AppleIAPConfig 相同的安全契约：凭证字段（ServiceAccountJSON / WebhookToken / Products）不设 default，
+// 缺失即视为未配置；校验位置在 internal/service/payment/google_play_catalog.go。
+//
+// APIBaseURL / TokenURL 为空时使用 Google 官方地址；本地联调或测试时可以指向 fake server。
+// WebhookToken 是 Pub/Sub push 订阅 URL 上携带的共享密钥（?token=...），用于校验 RTDN 来源。
+type GooglePlayConfig struct {
+	PackageName        string `envconfig:"PACKAGE_NAME"`
+	ServiceAccountJSON string `envconfig:"SERVICE_ACCOUNT_JSON"`
+	Products           string `envconfig:"PRODUCTS"`
+	WebhookToken       string `envconfig:"WEBHOOK_TOKEN"`
+
+	APIBaseURL          string        `envconfig:"API_BASE_URL"`
+	TokenURL            string        `envconfig:"TOKEN_URL"`
+	APITimeout          time.Duration `envconfig:"API_TIMEOUT" default:"10s"`
+	AllowTestPurchases  bool          `envconfig:"ALLOW_TEST_PURCHASES" default:"false"`
+	WebhookMaxBodyBytes int           `envconfig:"WEBHOOK_MAX_BODY_BYTES" default:"65536"`
+}
+
+// StripeConfig 描述 Stripe web billing 相关配置，环境变量以 STRIPE_ 为前缀。
+//
+// SecretKey / WebhookSecret / Products 不设 default，缺失即视为未配置；校验位置在 internal/service/payment/stripe_catalog.go。
+// APIBaseURL 为空时使用 https://api.stripe.com，测试时可以指向本地 stand-in。
+// SuccessURL / CancelURL 是 Checkout 完成或取消后跳回的页面，PortalReturnURL 是 customer portal 的返回页面。
+type StripeConfig struct {
+	SecretKey     string `envconfig:"SECRET_KEY"`
+	WebhookSecret string `envconfig:"WEBHOOK_SECRET"`
+	Products      string `envconfig:"PRODUCTS"`
+
+	SuccessURL      string `envconfig:"SUCCESS_URL"`
+	CancelURL       string `envconfig:"CANCEL_URL"`
+	PortalReturnURL string `envconfig:"PORTAL_RETURN_URL"`
+
+	APIBaseURL          string        `envconfig:"API_BASE_URL"`
+	APITimeout          time.Duration `envconfig:"API_TIMEOUT" default:"10s"`
+	WebhookTolerance    time.Duration `envconfig:"WEBHOOK_TOLERANCE" default:"5m"`
+	WebhookMaxBodyBytes int           `envconfig:"WEBHOOK_MAX_BODY_BYTES" default:"262144"`
 }
 
 // CacheConfig 定义 Redis 缓存相关配置
@@ -73,6 +246,8 @@ type AuthConfig struct {
 	Gmail GmailConfig `envconfig:"GMAIL"`
 	Apple AppleConfig `envconfig:"APPLE"`
 	JWT   JWTConfig   `envconfig:"JWT"`
+	// AdminUserIDs 是允许调用 /admin/* 的用户 ID 白名单（逗号分隔），为空时后台接口全部返回 403。
+	AdminUserIDs []int64 `envconfig:"ADMIN_USER_IDS"`
 }
 
 // GmailConfig Gmail认证相关配置
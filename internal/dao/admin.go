@@ -0,0 +1,222 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// ErrAdminUserNotFound 表示管理操作的目标用户不存在。
+var ErrAdminUserNotFound = errors.New("dao: user not found")
+
+// AdminDAO 暴露客服后台使用的查询与账号状态写入。
+//
+// 搜索统一按 users.id 升序做 keyset 分页：afterID 为上一页最后一个用户 ID（首页传 0）。
+type AdminDAO interface {
+	SearchUsers(ctx context.Context, field model.AdminSearchField, query string, afterID int64, limit int) ([]model.AdminUser, error)
+	GetUserDetail(ctx context.Context, userID int64, eventLimit int) (model.AdminUserDetail, error)
+	SetUserStatus(ctx context.Context, userID int64, status, reason string, expiresAt *time.Time, revokeSessions bool) (model.AccountState, error)
+	RevokeUserSessions(ctx context.Context, userID int64) (model.AccountState, error)
+}
+
+type adminDAO struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewAdminDAO 构造一个面向 PostgreSQL 的 AdminDAO。
+func NewAdminDAO(pool *pgxpool.Pool) AdminDAO {
+	return &adminDAO{
+		pool:    pool,
+		queries: db.New(pool),
+	}
+}
+
+// SearchUsers 按 field 精确匹配 query。email 不区分大小写；app_account_token 必须是合法 UUID，
+// 否则直接返回空结果（而不是错误），与“搜不到”的语义一致。
+func (d *adminDAO) SearchUsers(ctx context.Context, field model.AdminSearchField, query string, afterID int64, limit int) ([]model.AdminUser, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("admin dao: invalid limit %d", limit)
+	}
+	pageSize := int32(limit)
+
+	var (
+		rows []db.User
+		err  error
+	)
+	switch field {
+	case model.AdminSearchByID:
+		id, perr := strconv.ParseInt(query, 10, 64)
+		if perr != nil || id <= 0 {
+			return []model.AdminUser{}, nil
+		}
+		rows, err = d.queries.SearchUsersByID(ctx, db.SearchUsersByIDParams{UserID: id, AfterID: afterID, PageSize: pageSize})
+	case model.AdminSearchByEmail:
+		rows, err = d.queries.SearchUsersByEmail(ctx, db.SearchUsersByEmailParams{AfterID: afterID, Email: query, PageSize: pageSize})
+	case model.AdminSearchByProviderSubject:
+		rows, err = d.queries.SearchUsersByProviderSubject(ctx, db.SearchUsersByProviderSubjectParams{AfterID: afterID, ProviderSubject: query, PageSize: pageSize})
+	case model.AdminSearchByAppAccountToken:
+		token, uerr := uuidStringToPg(query)
+		if uerr != nil {
+			return []model.AdminUser{}, nil
+		}
+		rows, err = d.queries.SearchUsersByAppleAccountToken(ctx, db.SearchUsersByAppleAccountTokenParams{AfterID: afterID, Token: token, PageSize: pageSize})
+	case model.AdminSearchByOriginalTransactionID:
+		rows, err = d.queries.SearchUsersByOriginalTransactionID(ctx, db.SearchUsersByOriginalTransactionIDParams{AfterID: afterID, OriginalTransactionID: query, PageSize: pageSize})
+	default:
+		return nil, fmt.Errorf("admin dao: unsupported search field %q", field)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("admin dao: search by %s: %w", field, err)
+	}
+	out := make([]model.AdminUser, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapAdminUserRow(r))
+	}
+	return out, nil
+}
+
+// GetUserDetail 聚合用户的身份、appAccountToken、订阅与最近 eventLimit 条 Apple 通知。
+func (d *adminDAO) GetUserDetail(ctx context.Context, userID int64, eventLimit int) (model.AdminUserDetail, error) {
+	user, err := d.queries.GetUserWithAccountState(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.AdminUserDetail{}, ErrAdminUserNotFound
+		}
+		return model.AdminUserDetail{}, fmt.Errorf("admin dao: get user: %w", err)
+	}
+	out := model.AdminUserDetail{User: mapAdminUserRow(user)}
+
+	identities, err := d.queries.ListAuthIdentitiesByUser(ctx, userID)
+	if err != nil {
+		return model.AdminUserDetail{}, fmt.Errorf("admin dao: list identities: %w", err)
+	}
+	out.Identities = make([]model.AdminIdentity, 0, len(identities))
+	for _, ai := range identities {
+		out.Identities = append(out.Identities, model.AdminIdentity{
+			Provider:        ai.Provider,
+			ProviderSubject: ai.ProviderSubject,
+			Email:           ai.Email,
+			CreatedAt:       ai.CreatedAt.Time,
+		})
+	}
+
+	token, err := d.queries.GetAppleAccountTokenByUser(ctx, userID)
+	switch {
+	case err == nil:
+		out.AppAccountToken = pgUUIDToString(token.Token)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return model.AdminUserDetail{}, fmt.Errorf("admin dao: get account token: %w", err)
+	}
+
+	subs, err := d.queries.ListSubscriptionsByUser(ctx, userID)
+	if err != nil {
+		return model.AdminUserDetail{}, fmt.Errorf("admin dao: list subscriptions: %w", err)
+	}
+	out.Subscriptions = make([]model.Subscription, 0, len(subs))
+	for _, s := range subs {
+		out.Subscriptions = append(out.Subscriptions, mapSubscriptionRow(s))
+	}
+
+	if eventLimit > 0 {
+		events, err := d.queries.ListRecentAppleEventsByUser(ctx, db.ListRecentAppleEventsByUserParams{
+			UserID: int64ToPgInt8(userID),
+			Limit:  int32(eventLimit),
+		})
+		if err != nil {
+			return model.AdminUserDetail{}, fmt.Errorf("admin dao: list events: %w", err)
+		}
+		out.RecentEvents = make([]model.AdminAppleEvent, 0, len(events))
+		for _, e := range events {
+			out.RecentEvents = append(out.RecentEvents, mapAdminAppleEventRow(e))
+		}
+	}
+	return out, nil
+}
+
+// SetUserStatus 更新账号状态；expiresAt 为 nil 表示不过期。revokeSessions 为 true 时同一条 UPDATE 内
+// 把 sessions_revoked_at 置为 now()。
+func (d *adminDAO) SetUserStatus(ctx context.Context, userID int64, status, reason string, expiresAt *time.Time, revokeSessions bool) (model.AccountState, error) {
+	row, err := d.queries.SetUserStatus(ctx, db.SetUserStatusParams{
+		Status:          status,
+		StatusReason:    reason,
+		StatusExpiresAt: optionalTimePg(expiresAt),
+		RevokeSessions:  revokeSessions,
+		ID:              userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.AccountState{}, ErrAdminUserNotFound
+		}
+		return model.AccountState{}, fmt.Errorf("admin dao: set status: %w", err)
+	}
+	return accountStateFromColumns(row.ID, row.Status, row.StatusReason, row.StatusUpdatedAt, row.StatusExpiresAt, row.SessionsRevokedAt), nil
+}
+
+// RevokeUserSessions 把 sessions_revoked_at 置为 now()，使此前签发的 access token 全部失效。
+func (d *adminDAO) RevokeUserSessions(ctx context.Context, userID int64) (model.AccountState, error) {
+	row, err := d.queries.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.AccountState{}, ErrAdminUserNotFound
+		}
+		return model.AccountState{}, fmt.Errorf("admin dao: revoke sessions: %w", err)
+	}
+	return accountStateFromColumns(row.ID, row.Status, row.StatusReason, row.StatusUpdatedAt, row.StatusExpiresAt, row.SessionsRevokedAt), nil
+}
+
+func mapAdminUserRow(row db.User) model.AdminUser {
+	return model.AdminUser{
+		ID:                row.ID,
+		Name:              row.Name,
+		Status:            row.Status,
+		StatusReason:      row.StatusReason,
+		StatusUpdatedAt:   pgTimestamptzPtr(row.StatusUpdatedAt),
+		StatusExpiresAt:   pgTimestamptzPtr(row.StatusExpiresAt),
+		SessionsRevokedAt: pgTimestamptzPtr(row.SessionsRevokedAt),
+		CreatedAt:         row.CreatedAt.Time,
+	}
+}
+
+func mapAdminAppleEventRow(row db.AppleEvent) model.AdminAppleEvent {
+	return model.AdminAppleEvent{
+		NotificationUUID:      row.NotificationUuid,
+		NotificationType:      row.NotificationType,
+		Subtype:               row.Subtype,
+		Environment:           model.AppleEnvironment(row.Environment),
+		OriginalTransactionID: row.OriginalTransactionID,
+		TransactionID:         row.TransactionID,
+		ProcessingStatus:      row.ProcessingStatus,
+		ProcessingError:       row.ProcessingError,
+		NotificationCreatedAt: pgTimestamptzPtr(row.NotificationCreatedAt),
+		CreatedAt:             row.CreatedAt.Time,
+	}
+}
+
+func accountStateFromColumns(id int64, status, reason string, updatedAt, expiresAt, revokedAt pgtype.Timestamptz) model.AccountState {
+	return model.AccountState{
+		UserID:            id,
+		Status:            status,
+		StatusReason:      reason,
+		StatusUpdatedAt:   pgTimestamptzPtr(updatedAt),
+		StatusExpiresAt:   pgTimestamptzPtr(expiresAt),
+		SessionsRevokedAt: pgTimestamptzPtr(revokedAt),
+	}
+}
+
+func pgTimestamptzPtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
@@ -0,0 +1,98 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_AdminDAO_SearchByEmailAndSuspend(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+	ctx := context.Background()
+
+	email := fmt.Sprintf("Admin-Search-%d@Example.com", userID)
+	if _, err := pool.Exec(ctx,
+		"INSERT INTO auth_identities (user_id, provider, provider_subject, email) VALUES ($1, 'guest', $2, $3)",
+		userID, fmt.Sprintf("admin-search-%d", userID), email); err != nil {
+		t.Fatalf("insert identity: %v", err)
+	}
+	defer func() { _, _ = pool.Exec(ctx, "DELETE FROM auth_identities WHERE user_id = $1", userID) }()
+
+	d := NewAdminDAO(pool)
+	users, err := d.SearchUsers(ctx, model.AdminSearchByEmail, fmt.Sprintf("admin-search-%d@example.com", userID), 0, 10)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != userID || users[0].Status != model.UserStatusActive {
+		t.Fatalf("unexpected search result: %+v", users)
+	}
+
+	state, err := d.SetUserStatus(ctx, userID, model.UserStatusSuspended, "fraud", nil, true)
+	if err != nil {
+		t.Fatalf("suspend: %v", err)
+	}
+	if state.Status != model.UserStatusSuspended || state.SessionsRevokedAt == nil || state.StatusUpdatedAt == nil {
+		t.Fatalf("unexpected state after suspend: %+v", state)
+	}
+
+	got, err := NewUserDAO(pool).GetAccountState(ctx, userID)
+	if err != nil {
+		t.Fatalf("get account state: %v", err)
+	}
+	if got.Status != model.UserStatusSuspended || got.StatusReason != "fraud" {
+		t.Fatalf("unexpected account state: %+v", got)
+	}
+
+	if _, err := d.SetUserStatus(ctx, -1, model.UserStatusActive, "", nil, false); err != ErrAdminUserNotFound {
+		t.Fatalf("missing user err = %v, want ErrAdminUserNotFound", err)
+	}
+}
+
+func TestIntegration_UserDAO_ResolveAuthIdentityRefusesBlockedAccount(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+	ctx := context.Background()
+
+	identity := model.AuthIdentity{Provider: "guest", Subject: fmt.Sprintf("blocked-%d", userID)}
+	if _, err := pool.Exec(ctx,
+		"INSERT INTO auth_identities (user_id, provider, provider_subject) VALUES ($1, $2, $3)",
+		userID, identity.Provider, identity.Subject); err != nil {
+		t.Fatalf("insert identity: %v", err)
+	}
+	defer func() { _, _ = pool.Exec(ctx, "DELETE FROM auth_identities WHERE user_id = $1", userID) }()
+
+	users := NewUserDAO(pool)
+	admin := NewAdminDAO(pool)
+	if _, err := admin.SetUserStatus(ctx, userID, model.UserStatusBanned, "spam", nil, true); err != nil {
+		t.Fatalf("ban: %v", err)
+	}
+	_, err := users.ResolveAuthIdentity(ctx, identity)
+	var blocked *model.AccountBlockedError
+	if !errors.As(err, &blocked) || blocked.Status != model.UserStatusBanned || blocked.Reason != "spam" {
+		t.Fatalf("resolve err = %v, want banned AccountBlockedError", err)
+	}
+
+	// 已到期的封禁不再阻止登录。
+	expired := time.Now().Add(-time.Minute)
+	if _, err := admin.SetUserStatus(ctx, userID, model.UserStatusBanned, "spam", &expired, false); err != nil {
+		t.Fatalf("set expired ban: %v", err)
+	}
+	info, err := users.ResolveAuthIdentity(ctx, identity)
+	if err != nil {
+		t.Fatalf("resolve after expiry: %v", err)
+	}
+	if info.ID != fmt.Sprint(userID) {
+		t.Fatalf("resolved user = %s, want %d", info.ID, userID)
+	}
+}
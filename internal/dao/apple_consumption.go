@@ -0,0 +1,163 @@
+package dao
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// ErrConsumptionUserNotFound 表示 consumption 请求关联的用户已不存在。
+var ErrConsumptionUserNotFound = errors.New("dao: consumption request user not found")
+
+// AppleConsumptionDAO 读取待回复的 CONSUMPTION_REQUEST 并记录投递结果。
+//
+// 请求的写入走 SubscriptionTx.InsertConsumptionRequest，与 apple_events 在同一事务内。
+type AppleConsumptionDAO interface {
+	ClaimDueConsumptionRequests(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.AppleConsumptionRequest, error)
+	GetConsumptionSnapshot(ctx context.Context, req model.AppleConsumptionRequest) (model.ConsumptionSnapshot, error)
+	MarkConsumptionRequestSent(ctx context.Context, id int64, payload []byte, sentAt time.Time) error
+	MarkConsumptionRequestFailed(ctx context.Context, id int64, status, lastError string, nextAttemptAt time.Time) error
+}
+
+type appleConsumptionDAO struct {
+	queries *db.Queries
+}
+
+// NewAppleConsumptionDAO 构造一个面向 PostgreSQL 的 AppleConsumptionDAO。
+func NewAppleConsumptionDAO(pool *pgxpool.Pool) AppleConsumptionDAO {
+	return &appleConsumptionDAO{queries: db.New(pool)}
+}
+
+// InsertConsumptionRequest 记录一条 CONSUMPTION_REQUEST；同一通知（event_id）重复写入时忽略。
+// UserID 为 0 时写为 NULL，AppAccountToken 为空或不是 UUID 时写为 NULL。
+func (s *subscriptionTxQueries) InsertConsumptionRequest(ctx context.Context, in model.AppleConsumptionRequest) error {
+	if in.EventID <= 0 || in.TransactionID == "" {
+		return errors.New("subscription dao: consumption request requires event id and transaction id")
+	}
+	if err := s.queries.InsertAppleConsumptionRequest(ctx, db.InsertAppleConsumptionRequestParams{
+		EventID:               in.EventID,
+		UserID:                int64ToPgInt8(in.UserID),
+		Environment:           string(in.Environment),
+		OriginalTransactionID: in.OriginalTransactionID,
+		TransactionID:         in.TransactionID,
+		AppAccountToken:       optionalUUIDStringToPg(in.AppAccountToken),
+		DeadlineAt:            timeToPgTimestamptz(in.DeadlineAt),
+	}); err != nil {
+		return fmt.Errorf("subscription dao: insert consumption request: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueConsumptionRequests 领取 next_attempt_at 已到的 PENDING 请求并把 next_attempt_at 推到 leaseUntil，
+// 多实例不会重复回复同一请求。截止时间最早的优先，最多 limit 条。
+func (d *appleConsumptionDAO) ClaimDueConsumptionRequests(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.AppleConsumptionRequest, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("consumption dao: invalid limit %d", limit)
+	}
+	rows, err := d.queries.ClaimDueAppleConsumptionRequests(ctx, db.ClaimDueAppleConsumptionRequestsParams{
+		LeaseUntil: timeToPgTimestamptz(leaseUntil),
+		Now:        timeToPgTimestamptz(now),
+		BatchSize:  int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("consumption dao: claim due: %w", err)
+	}
+	out := make([]model.AppleConsumptionRequest, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapConsumptionRequestRow(r))
+	}
+	// UPDATE ... RETURNING 不保证顺序；按截止时间、ID 升序返回。
+	slices.SortFunc(out, func(a, b model.AppleConsumptionRequest) int {
+		if c := a.DeadlineAt.Compare(b.DeadlineAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return out, nil
+}
+
+// GetConsumptionSnapshot 汇总该请求用户的账户信息与该交易的积分发放 / 交付情况；用户不存在时返回 ErrConsumptionUserNotFound。
+func (d *appleConsumptionDAO) GetConsumptionSnapshot(ctx context.Context, req model.AppleConsumptionRequest) (model.ConsumptionSnapshot, error) {
+	row, err := d.queries.GetAppleConsumptionSnapshot(ctx, db.GetAppleConsumptionSnapshotParams{
+		OriginalTransactionID: req.OriginalTransactionID,
+		Environment:           string(req.Environment),
+		TransactionID:         req.TransactionID,
+		UserID:                req.UserID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.ConsumptionSnapshot{}, ErrConsumptionUserNotFound
+		}
+		return model.ConsumptionSnapshot{}, fmt.Errorf("consumption dao: snapshot: %w", err)
+	}
+	return model.ConsumptionSnapshot{
+		AccountCreatedAt: row.AccountCreatedAt.Time,
+		AccountStatus:    row.AccountStatus,
+		CreditsGranted:   row.CreditsGranted,
+		CreditsRemaining: row.CreditsRemaining,
+		Delivered:        row.Delivered,
+	}, nil
+}
+
+// MarkConsumptionRequestSent 把请求标记为 SENT 并保存发送给 Apple 的 body；仅对 PENDING 行生效。
+func (d *appleConsumptionDAO) MarkConsumptionRequestSent(ctx context.Context, id int64, payload []byte, sentAt time.Time) error {
+	if err := d.queries.MarkAppleConsumptionRequestSent(ctx, db.MarkAppleConsumptionRequestSentParams{
+		SentPayload: payload,
+		SentAt:      timeToPgTimestamptz(sentAt),
+		ID:          id,
+	}); err != nil {
+		return fmt.Errorf("consumption dao: mark sent: %w", err)
+	}
+	return nil
+}
+
+// MarkConsumptionRequestFailed 记录一次失败：status 为 PENDING 时在 nextAttemptAt 重试，为 FAILED 时不再重试。
+func (d *appleConsumptionDAO) MarkConsumptionRequestFailed(ctx context.Context, id int64, status, lastError string, nextAttemptAt time.Time) error {
+	if status != model.ConsumptionRequestPending && status != model.ConsumptionRequestFailed {
+		return fmt.Errorf("consumption dao: invalid status %q", status)
+	}
+	if err := d.queries.MarkAppleConsumptionRequestFailed(ctx, db.MarkAppleConsumptionRequestFailedParams{
+		Status:        status,
+		LastError:     lastError,
+		NextAttemptAt: timeToPgTimestamptz(nextAttemptAt),
+		ID:            id,
+	}); err != nil {
+		return fmt.Errorf("consumption dao: mark failed: %w", err)
+	}
+	return nil
+}
+
+func mapConsumptionRequestRow(r db.AppleConsumptionRequest) model.AppleConsumptionRequest {
+	out := model.AppleConsumptionRequest{
+		ID:                    r.ID,
+		EventID:               r.EventID,
+		Environment:           model.AppleEnvironment(r.Environment),
+		OriginalTransactionID: r.OriginalTransactionID,
+		TransactionID:         r.TransactionID,
+		AppAccountToken:       pgUUIDToString(r.AppAccountToken),
+		Status:                r.Status,
+		Attempts:              int(r.Attempts),
+		NextAttemptAt:         r.NextAttemptAt.Time,
+		DeadlineAt:            r.DeadlineAt.Time,
+		LastError:             r.LastError,
+		SentPayload:           r.SentPayload,
+		CreatedAt:             r.CreatedAt.Time,
+	}
+	if r.UserID.Valid {
+		out.UserID = r.UserID.Int64
+	}
+	if r.SentAt.Valid {
+		t := r.SentAt.Time
+		out.SentAt = &t
+	}
+	return out
+}
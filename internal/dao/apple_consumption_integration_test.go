@@ -0,0 +1,107 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_AppleConsumptionDAO_Lifecycle(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	subs := NewSubscriptionDAO(pool)
+	consumption := NewAppleConsumptionDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	notifUUID := "it-consumption-" + t.Name()
+	txID := "it-consumption-tx-" + t.Name()
+	defer func() {
+		_, _ = pool.Exec(ctx, "DELETE FROM apple_events WHERE notification_uuid = $1", notifUUID)
+	}()
+
+	for i := 0; i < 2; i++ { // 同一通知重复投递只记录一次
+		if err := subs.InTx(ctx, func(qtx SubscriptionTx) error {
+			_, eventID, err := qtx.InsertAppleEventIfNotExists(ctx, model.AppleEventInsert{
+				NotificationUUID:      notifUUID,
+				NotificationType:      "CONSUMPTION_REQUEST",
+				Environment:           model.AppleEnvSandbox,
+				TransactionID:         txID,
+				ProcessingStatus:      model.EventStatusProcessed,
+				RawJWSSHA256:          "sha",
+				NotificationCreatedAt: &now,
+			})
+			if err != nil {
+				return err
+			}
+			return qtx.InsertConsumptionRequest(ctx, model.AppleConsumptionRequest{
+				EventID:               eventID,
+				UserID:                userID,
+				Environment:           model.AppleEnvSandbox,
+				OriginalTransactionID: txID,
+				TransactionID:         txID,
+				DeadlineAt:            now.Add(12 * time.Hour),
+			})
+		}); err != nil {
+			t.Fatalf("insert #%d: %v", i, err)
+		}
+	}
+
+	claimDue := func(at time.Time) []model.AppleConsumptionRequest {
+		rows, err := consumption.ClaimDueConsumptionRequests(ctx, at, at.Add(10*time.Minute), 1000)
+		if err != nil {
+			t.Fatalf("claim due: %v", err)
+		}
+		var out []model.AppleConsumptionRequest
+		for _, r := range rows {
+			if r.TransactionID == txID {
+				out = append(out, r)
+			}
+		}
+		return out
+	}
+	due := claimDue(now.Add(time.Second))
+	if len(due) != 1 || due[0].UserID != userID || due[0].Status != model.ConsumptionRequestPending {
+		t.Fatalf("unexpected due requests: %+v", due)
+	}
+	req := due[0]
+	// 租约内不会被再次领取；租约过期（发送方崩溃）后重新可领取。
+	if again := claimDue(now.Add(2 * time.Second)); len(again) != 0 {
+		t.Fatalf("request claimed twice: %+v", again)
+	}
+	if again := claimDue(now.Add(11 * time.Minute)); len(again) != 1 || again[0].ID != req.ID {
+		t.Fatalf("expired lease must be reclaimable: %+v", again)
+	}
+
+	snap, err := consumption.GetConsumptionSnapshot(ctx, req)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if snap.AccountStatus != model.UserStatusActive || snap.Delivered || snap.CreditsGranted != 0 {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+
+	if err := consumption.MarkConsumptionRequestFailed(ctx, req.ID, model.ConsumptionRequestPending, "boom", now.Add(time.Hour)); err != nil {
+		t.Fatalf("mark failed: %v", err)
+	}
+	if due := claimDue(now.Add(time.Minute)); len(due) != 0 {
+		t.Fatalf("request must wait for next attempt: %+v", due)
+	}
+	if err := consumption.MarkConsumptionRequestSent(ctx, req.ID, []byte(`{"platform":1}`), now); err != nil {
+		t.Fatalf("mark sent: %v", err)
+	}
+	var status string
+	var attempts int
+	if err := pool.QueryRow(ctx, "SELECT status, attempts FROM apple_consumption_requests WHERE id = $1", req.ID).Scan(&status, &attempts); err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	if status != model.ConsumptionRequestSent || attempts != 2 {
+		t.Fatalf("status=%s attempts=%d, want SENT after 2 attempts", status, attempts)
+	}
+}
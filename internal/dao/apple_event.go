@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// ErrEventNotPending 表示事件已不是 PENDING_USER_BINDING（已被其他重放处理），或正被其他事务锁定。
+var ErrEventNotPending = errors.New("dao: apple event not pending user binding")
+
+// ListRedrivableEvents 返回已能绑定到 user 的 PENDING_USER_BINDING 事件：appAccountToken 已有映射，且保留了
+// decoded payload 或仍有该通知的加密归档。token 非空时只返回该 token 的事件。按通知时间升序，最多 limit 条。
+func (s *subscriptionTxQueries) ListRedrivableEvents(ctx context.Context, token string, limit int) ([]model.AppleEvent, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("subscription dao: invalid limit %d", limit)
+	}
+	var pgToken pgtype.UUID
+	if token != "" {
+		var err error
+		if pgToken, err = uuidStringToPg(token); err != nil {
+			return nil, fmt.Errorf("subscription dao: encode app_account_token: %w", err)
+		}
+	}
+	rows, err := s.queries.ListRedrivableAppleEvents(ctx, db.ListRedrivableAppleEventsParams{
+		AppAccountToken: pgToken,
+		BatchSize:       int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("subscription dao: list redrivable events: %w", err)
+	}
+	out := make([]model.AppleEvent, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapAppleEventRow(r))
+	}
+	return out, nil
+}
+
+// LockPendingEvent 以 FOR UPDATE SKIP LOCKED 锁住仍处于 PENDING_USER_BINDING 的事件；
+// 已被处理或被并发重放锁住时返回 ErrEventNotPending。
+func (s *subscriptionTxQueries) LockPendingEvent(ctx context.Context, eventID int64) (model.AppleEvent, error) {
+	row, err := s.queries.LockPendingAppleEvent(ctx, eventID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.AppleEvent{}, ErrEventNotPending
+		}
+		return model.AppleEvent{}, fmt.Errorf("subscription dao: lock pending event: %w", err)
+	}
+	return mapAppleEventRow(row), nil
+}
+
+// UpdateEventProcessing 写入重放后的 processing_status / user_id / processing_error。userID == 0 写为 NULL。
+// 事件由此离开 PENDING_USER_BINDING，为重放保留的 decoded_payload 同时清空。
+func (s *subscriptionTxQueries) UpdateEventProcessing(ctx context.Context, eventID int64, status string, userID int64, processingError string) error {
+	if err := s.queries.UpdateAppleEventProcessing(ctx, db.UpdateAppleEventProcessingParams{
+		ID:               eventID,
+		ProcessingStatus: status,
+		ProcessingError:  processingError,
+		UserID:           int64ToPgInt8(userID),
+	}); err != nil {
+		return fmt.Errorf("subscription dao: update event processing: %w", err)
+	}
+	return nil
+}
+
+func mapAppleEventRow(row db.AppleEvent) model.AppleEvent {
+	out := model.AppleEvent{
+		ID:                    row.ID,
+		NotificationUUID:      row.NotificationUuid,
+		NotificationType:      row.NotificationType,
+		Environment:           model.AppleEnvironment(row.Environment),
+		AppAccountToken:       pgUUIDToString(row.AppAccountToken),
+		OriginalTransactionID: row.OriginalTransactionID,
+		ProcessingStatus:      row.ProcessingStatus,
+		ProcessingError:       row.ProcessingError,
+		RawJWSSHA256:          row.RawJwsSha256,
+		DecodedPayload:        row.DecodedPayload,
+		CreatedAt:             row.CreatedAt.Time,
+	}
+	if row.UserID.Valid {
+		out.UserID = row.UserID.Int64
+	}
+	if row.NotificationCreatedAt.Valid {
+		t := row.NotificationCreatedAt.Time
+		out.NotificationCreatedAt = &t
+	}
+	return out
+}
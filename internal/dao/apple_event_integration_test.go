@@ -0,0 +1,112 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_AppleEvent_RedrivePendingBinding(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	d := NewSubscriptionDAO(pool)
+	ctx := context.Background()
+	token, _, err := d.GetOrCreateAccountToken(ctx, userID)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	unbound := "00000000-0000-4000-8000-0000000fffff"
+	uuidPrefix := "it-redrive-" + t.Name() + "-"
+	defer func() {
+		_, _ = pool.Exec(ctx, "DELETE FROM apple_events WHERE notification_uuid LIKE $1", uuidPrefix+"%")
+	}()
+
+	now := time.Now().UTC()
+	for _, ev := range []struct {
+		uuid, token        string
+		retained, archived bool
+	}{{"bound", token, true, false}, {"unbound", unbound, true, false}, {"archived", token, false, true}, {"purged", token, false, false}} {
+		if err := d.InTx(ctx, func(tx SubscriptionTx) error {
+			in := model.AppleEventInsert{
+				NotificationUUID:      uuidPrefix + ev.uuid,
+				NotificationType:      "SUBSCRIBED",
+				Environment:           model.AppleEnvSandbox,
+				AppAccountToken:       ev.token,
+				ProcessingStatus:      model.EventStatusPendingUserBinding,
+				RawJWSSHA256:          "sha",
+				NotificationCreatedAt: &now,
+			}
+			// 开启加密归档时不写明文副本；既无副本也无归档的事件（归档已过期清理）不可重放。
+			if ev.retained {
+				in.DecodedPayload = []byte(`{"NotificationUUID":"` + uuidPrefix + ev.uuid + `"}`)
+			}
+			_, eventID, err := tx.InsertAppleEventIfNotExists(ctx, in)
+			if err != nil || !ev.archived {
+				return err
+			}
+			return tx.InsertPayloadArchive(ctx, model.ApplePayloadArchive{
+				Source:      model.PayloadSourceNotification,
+				EventID:     eventID,
+				Environment: model.AppleEnvSandbox,
+				KeyID:       "it",
+				WrappedKey:  []byte("wrapped"),
+				Nonce:       []byte("nonce"),
+				Ciphertext:  []byte("ciphertext"),
+				ExpiresAt:   now.Add(time.Hour),
+			})
+		}); err != nil {
+			t.Fatalf("insert %s: %v", ev.uuid, err)
+		}
+	}
+
+	var listed []model.AppleEvent
+	if err := d.InTx(ctx, func(tx SubscriptionTx) error {
+		var err error
+		listed, err = tx.ListRedrivableEvents(ctx, token, 10)
+		return err
+	}); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(listed) != 2 || listed[0].NotificationUUID != uuidPrefix+"bound" || len(listed[0].DecodedPayload) == 0 ||
+		listed[1].NotificationUUID != uuidPrefix+"archived" || listed[1].RawJWSSHA256 != "sha" {
+		t.Fatalf("only bound events with a re-drive copy or archive should be redrivable: %+v", listed)
+	}
+	if err := d.InTx(ctx, func(tx SubscriptionTx) error {
+		archive, err := tx.GetPayloadArchiveByEvent(ctx, listed[1].ID)
+		if err != nil || archive.EventID != listed[1].ID || string(archive.Ciphertext) != "ciphertext" {
+			t.Fatalf("archive by event: %+v %v", archive, err)
+		}
+		_, err = tx.GetPayloadArchiveByEvent(ctx, listed[0].ID)
+		return err
+	}); !errors.Is(err, ErrPayloadArchiveNotFound) {
+		t.Fatalf("missing archive err = %v, want ErrPayloadArchiveNotFound", err)
+	}
+
+	if err := d.InTx(ctx, func(tx SubscriptionTx) error {
+		ev, err := tx.LockPendingEvent(ctx, listed[0].ID)
+		if err != nil {
+			return err
+		}
+		return tx.UpdateEventProcessing(ctx, ev.ID, model.EventStatusProcessed, userID, "")
+	}); err != nil {
+		t.Fatalf("redrive: %v", err)
+	}
+	if err := d.InTx(ctx, func(tx SubscriptionTx) error {
+		_, err := tx.LockPendingEvent(ctx, listed[0].ID)
+		return err
+	}); !errors.Is(err, ErrEventNotPending) {
+		t.Fatalf("second lock err = %v, want ErrEventNotPending", err)
+	}
+	var cleared bool
+	if err := pool.QueryRow(ctx, "SELECT decoded_payload IS NULL FROM apple_events WHERE id = $1", listed[0].ID).Scan(&cleared); err != nil || !cleared {
+		t.Fatalf("re-drive copy must be cleared at final status: cleared=%v err=%v", cleared, err)
+	}
+}
@@ -0,0 +1,86 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// ErrOfferSignatureLimitReached 表示用户对该 offer 的签发次数已达 max_per_user。
+var ErrOfferSignatureLimitReached = errors.New("dao: offer signature limit reached")
+
+// AppleOfferDAO 记录签发过的 Apple 订阅优惠签名，供审计与按用户限次。
+type AppleOfferDAO interface {
+	InsertOfferSignature(ctx context.Context, in model.AppleOfferSignatureInsert, maxPerUser int) (model.AppleOfferSignature, error)
+}
+
+type appleOfferDAO struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewAppleOfferDAO 构造一个面向 PostgreSQL 的 AppleOfferDAO。
+func NewAppleOfferDAO(pool *pgxpool.Pool) AppleOfferDAO {
+	return &appleOfferDAO{pool: pool, queries: db.New(pool)}
+}
+
+// InsertOfferSignature 记录一次签发。maxPerUser > 0 时在同一事务内按 (user, offer) 加 advisory lock
+// 后计数再写入，已达上限返回 ErrOfferSignatureLimitReached，避免并发请求同时通过计数检查。
+func (d *appleOfferDAO) InsertOfferSignature(ctx context.Context, in model.AppleOfferSignatureInsert, maxPerUser int) (model.AppleOfferSignature, error) {
+	if in.UserID <= 0 {
+		return model.AppleOfferSignature{}, fmt.Errorf("apple offer dao: invalid user id %d", in.UserID)
+	}
+	if in.OfferID == "" || in.Nonce == "" {
+		return model.AppleOfferSignature{}, errors.New("apple offer dao: offer id and nonce required")
+	}
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return model.AppleOfferSignature{}, fmt.Errorf("apple offer dao: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	q := d.queries.WithTx(tx)
+
+	if maxPerUser > 0 {
+		if err := q.LockAppleOfferSignatureQuota(ctx, db.LockAppleOfferSignatureQuotaParams{OfferID: in.OfferID, UserID: in.UserID}); err != nil {
+			return model.AppleOfferSignature{}, fmt.Errorf("apple offer dao: lock quota: %w", err)
+		}
+		n, err := q.CountAppleOfferSignatures(ctx, db.CountAppleOfferSignaturesParams{UserID: in.UserID, OfferID: in.OfferID})
+		if err != nil {
+			return model.AppleOfferSignature{}, fmt.Errorf("apple offer dao: count: %w", err)
+		}
+		if n >= int64(maxPerUser) {
+			return model.AppleOfferSignature{}, ErrOfferSignatureLimitReached
+		}
+	}
+	row, err := q.InsertAppleOfferSignature(ctx, db.InsertAppleOfferSignatureParams{
+		UserID:          in.UserID,
+		OfferID:         in.OfferID,
+		OfferType:       in.OfferType,
+		ProductID:       in.ProductID,
+		AppAccountToken: in.AppAccountToken,
+		Nonce:           in.Nonce,
+		SignedAt:        timeToPgTimestamptz(in.SignedAt),
+	})
+	if err != nil {
+		return model.AppleOfferSignature{}, fmt.Errorf("apple offer dao: insert: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return model.AppleOfferSignature{}, fmt.Errorf("apple offer dao: commit: %w", err)
+	}
+	return model.AppleOfferSignature{
+		ID:              row.ID,
+		UserID:          row.UserID,
+		OfferID:         row.OfferID,
+		OfferType:       row.OfferType,
+		ProductID:       row.ProductID,
+		AppAccountToken: row.AppAccountToken,
+		Nonce:           row.Nonce,
+		SignedAt:        row.SignedAt.Time,
+		CreatedAt:       row.CreatedAt.Time,
+	}, nil
+}
@@ -0,0 +1,106 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_AppleOfferDAO_LogAndCount(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	d := NewAppleOfferDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i, nonce := range []string{"it-nonce-1-" + t.Name(), "it-nonce-2-" + t.Name()} {
+		row, err := d.InsertOfferSignature(ctx, model.AppleOfferSignatureInsert{
+			UserID:          userID,
+			OfferID:         "winback_50",
+			OfferType:       model.OfferTypeWinBack,
+			ProductID:       "com.app.pro.monthly",
+			AppAccountToken: "00000000-0000-4000-8000-000000000001",
+			Nonce:           nonce,
+			SignedAt:        now,
+		}, 0)
+		if err != nil || row.ID == 0 || !row.SignedAt.Equal(now) {
+			t.Fatalf("insert %d: %+v %v", i, row, err)
+		}
+	}
+	if n := countOfferSignatures(t, pool, userID, "winback_50"); n != 2 {
+		t.Fatalf("count = %d, want 2", n)
+	}
+	if n := countOfferSignatures(t, pool, userID, "promo_3m"); n != 0 {
+		t.Fatalf("other offer count = %d, want 0", n)
+	}
+}
+
+func TestIntegration_AppleOfferDAO_MaxPerUserIsAtomic(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	d := NewAppleOfferDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// 并发签发同一 offer，max_per_user=1 时只能有一次成功。
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := d.InsertOfferSignature(ctx, model.AppleOfferSignatureInsert{
+				UserID:          userID,
+				OfferID:         "promo_3m",
+				OfferType:       model.OfferTypePromotional,
+				ProductID:       "com.app.pro.monthly",
+				AppAccountToken: "00000000-0000-4000-8000-000000000001",
+				Nonce:           t.Name() + "-" + string(rune('a'+i)),
+				SignedAt:        now,
+			}, 1)
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	var ok int
+	for err := range errs {
+		switch {
+		case err == nil:
+			ok++
+		case !errors.Is(err, ErrOfferSignatureLimitReached):
+			t.Fatalf("insert err = %v", err)
+		}
+	}
+	if ok != 1 {
+		t.Fatalf("successful inserts = %d, want 1", ok)
+	}
+	if n := countOfferSignatures(t, pool, userID, "promo_3m"); n != 1 {
+		t.Fatalf("count = %d, want 1", n)
+	}
+}
+
+func countOfferSignatures(t *testing.T, pool *pgxpool.Pool, userID int64, offerID string) int64 {
+	t.Helper()
+	var n int64
+	if err := pool.QueryRow(context.Background(), "SELECT count(*) FROM apple_offer_signatures WHERE user_id = $1 AND offer_id = $2", userID, offerID).Scan(&n); err != nil {
+		t.Fatalf("count offer signatures: %v", err)
+	}
+	return n
+}
@@ -0,0 +1,163 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// ErrPayloadArchiveNotFound 表示该通知没有加密归档（未开启归档或已过保留期被清理）。
+var ErrPayloadArchiveNotFound = errors.New("dao: apple payload archive not found")
+
+// ApplePayloadArchiveDAO 读取与维护 apple_payload_archives：客服查询、保留期清理与 master key 轮换。
+//
+// 归档的写入走 SubscriptionTx.InsertPayloadArchive，与 apple_events / 订阅写入在同一事务内。
+type ApplePayloadArchiveDAO interface {
+	ListArchivesByOriginalTx(ctx context.Context, originalTxID string, limit int) ([]model.ApplePayloadArchive, error)
+	DeleteExpiredArchives(ctx context.Context, before time.Time, limit int) (int64, error)
+	ListArchivesNotUsingKey(ctx context.Context, keyID string, limit int) ([]model.ApplePayloadArchive, error)
+	UpdateArchiveKey(ctx context.Context, id int64, previousKeyID, keyID string, wrappedKey []byte) error
+}
+
+type applePayloadArchiveDAO struct {
+	queries *db.Queries
+}
+
+// NewApplePayloadArchiveDAO 构造一个面向 PostgreSQL 的 ApplePayloadArchiveDAO。
+func NewApplePayloadArchiveDAO(pool *pgxpool.Pool) ApplePayloadArchiveDAO {
+	return &applePayloadArchiveDAO{queries: db.New(pool)}
+}
+
+// InsertPayloadArchive 写入一条密文归档；同一通知（event_id）或同一 verify transaction 重复写入时忽略。
+// in.ID / in.CreatedAt 被忽略，EventID / UserID 为 0 时写为 NULL。
+func (s *subscriptionTxQueries) InsertPayloadArchive(ctx context.Context, in model.ApplePayloadArchive) error {
+	if in.KeyID == "" || len(in.WrappedKey) == 0 || len(in.Ciphertext) == 0 {
+		return errors.New("subscription dao: payload archive must be sealed")
+	}
+	if err := s.queries.InsertApplePayloadArchive(ctx, db.InsertApplePayloadArchiveParams{
+		Source:                in.Source,
+		EventID:               int64ToPgInt8(in.EventID),
+		UserID:                int64ToPgInt8(in.UserID),
+		Environment:           string(in.Environment),
+		OriginalTransactionID: in.OriginalTransactionID,
+		TransactionID:         in.TransactionID,
+		KeyID:                 in.KeyID,
+		WrappedKey:            in.WrappedKey,
+		Nonce:                 in.Nonce,
+		Ciphertext:            in.Ciphertext,
+		ExpiresAt:             timeToPgTimestamptz(in.ExpiresAt),
+	}); err != nil {
+		return fmt.Errorf("subscription dao: insert payload archive: %w", err)
+	}
+	return nil
+}
+
+// GetPayloadArchiveByEvent 返回该通知（event_id）的 NOTIFICATION 归档，供 PENDING_USER_BINDING 重放解密；
+// 不存在时返回 ErrPayloadArchiveNotFound。
+func (s *subscriptionTxQueries) GetPayloadArchiveByEvent(ctx context.Context, eventID int64) (model.ApplePayloadArchive, error) {
+	row, err := s.queries.GetApplePayloadArchiveByEvent(ctx, int64ToPgInt8(eventID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.ApplePayloadArchive{}, ErrPayloadArchiveNotFound
+		}
+		return model.ApplePayloadArchive{}, fmt.Errorf("subscription dao: get payload archive by event: %w", err)
+	}
+	return mapPayloadArchiveRow(row), nil
+}
+
+// ListArchivesByOriginalTx 返回该 originalTransactionId 下的归档，按写入时间倒序，最多 limit 条。
+func (d *applePayloadArchiveDAO) ListArchivesByOriginalTx(ctx context.Context, originalTxID string, limit int) ([]model.ApplePayloadArchive, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("payload archive dao: invalid limit %d", limit)
+	}
+	rows, err := d.queries.ListApplePayloadArchivesByOriginalTx(ctx, db.ListApplePayloadArchivesByOriginalTxParams{
+		OriginalTransactionID: originalTxID,
+		BatchSize:             int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("payload archive dao: list by original tx: %w", err)
+	}
+	return mapPayloadArchiveRows(rows), nil
+}
+
+// DeleteExpiredArchives 删除 expires_at <= before 的归档，单次最多 limit 条，返回删除行数。
+func (d *applePayloadArchiveDAO) DeleteExpiredArchives(ctx context.Context, before time.Time, limit int) (int64, error) {
+	if limit <= 0 {
+		return 0, fmt.Errorf("payload archive dao: invalid limit %d", limit)
+	}
+	n, err := d.queries.DeleteExpiredApplePayloadArchives(ctx, db.DeleteExpiredApplePayloadArchivesParams{
+		ExpiresBefore: timeToPgTimestamptz(before),
+		BatchSize:     int32(limit),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("payload archive dao: delete expired: %w", err)
+	}
+	return n, nil
+}
+
+// ListArchivesNotUsingKey 返回仍由其他 master key 包裹的归档，供轮换后重新包裹。
+func (d *applePayloadArchiveDAO) ListArchivesNotUsingKey(ctx context.Context, keyID string, limit int) ([]model.ApplePayloadArchive, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("payload archive dao: invalid limit %d", limit)
+	}
+	rows, err := d.queries.ListApplePayloadArchivesNotUsingKey(ctx, db.ListApplePayloadArchivesNotUsingKeyParams{
+		KeyID:     keyID,
+		BatchSize: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("payload archive dao: list not using key: %w", err)
+	}
+	return mapPayloadArchiveRows(rows), nil
+}
+
+// UpdateArchiveKey 改写归档的 key_id / wrapped_key；仅当当前 key_id 仍为 previousKeyID 时生效，
+// 并发轮换不会互相覆盖。
+func (d *applePayloadArchiveDAO) UpdateArchiveKey(ctx context.Context, id int64, previousKeyID, keyID string, wrappedKey []byte) error {
+	if err := d.queries.UpdateApplePayloadArchiveKey(ctx, db.UpdateApplePayloadArchiveKeyParams{
+		KeyID:         keyID,
+		WrappedKey:    wrappedKey,
+		ID:            id,
+		PreviousKeyID: previousKeyID,
+	}); err != nil {
+		return fmt.Errorf("payload archive dao: update key: %w", err)
+	}
+	return nil
+}
+
+func mapPayloadArchiveRows(rows []db.ApplePayloadArchive) []model.ApplePayloadArchive {
+	out := make([]model.ApplePayloadArchive, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapPayloadArchiveRow(r))
+	}
+	return out
+}
+
+func mapPayloadArchiveRow(r db.ApplePayloadArchive) model.ApplePayloadArchive {
+	a := model.ApplePayloadArchive{
+		ID:                    r.ID,
+		Source:                r.Source,
+		Environment:           model.AppleEnvironment(r.Environment),
+		OriginalTransactionID: r.OriginalTransactionID,
+		TransactionID:         r.TransactionID,
+		KeyID:                 r.KeyID,
+		WrappedKey:            r.WrappedKey,
+		Nonce:                 r.Nonce,
+		Ciphertext:            r.Ciphertext,
+		CreatedAt:             r.CreatedAt.Time,
+		ExpiresAt:             r.ExpiresAt.Time,
+	}
+	if r.EventID.Valid {
+		a.EventID = r.EventID.Int64
+	}
+	if r.UserID.Valid {
+		a.UserID = r.UserID.Int64
+	}
+	return a
+}
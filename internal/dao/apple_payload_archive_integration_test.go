@@ -0,0 +1,81 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_ApplePayloadArchiveDAO_InsertPurgeAndRekey(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	subs := NewSubscriptionDAO(pool)
+	archives := NewApplePayloadArchiveDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	otID := "it-archive-" + t.Name()
+
+	insert := func(txID string, expiresAt time.Time) {
+		if err := subs.InTx(ctx, func(qtx SubscriptionTx) error {
+			return qtx.InsertPayloadArchive(ctx, model.ApplePayloadArchive{
+				Source:                model.PayloadSourceTransaction,
+				UserID:                userID,
+				Environment:           model.AppleEnvSandbox,
+				OriginalTransactionID: otID,
+				TransactionID:         txID,
+				KeyID:                 "it-old",
+				WrappedKey:            []byte("wrapped"),
+				Nonce:                 []byte("nonce"),
+				Ciphertext:            []byte("ciphertext"),
+				ExpiresAt:             expiresAt,
+			})
+		}); err != nil {
+			t.Fatalf("insert %s: %v", txID, err)
+		}
+	}
+	insert(otID+"-expired", now.Add(-time.Hour))
+	insert(otID+"-live", now.Add(time.Hour))
+	insert(otID+"-live", now.Add(time.Hour)) // verify 重复提交同一 transaction 只归档一次
+
+	rows, err := archives.ListArchivesByOriginalTx(ctx, otID, 10)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(rows) != 2 || rows[0].UserID != userID || string(rows[0].Ciphertext) != "ciphertext" {
+		t.Fatalf("unexpected archives: %+v", rows)
+	}
+
+	if _, err := archives.DeleteExpiredArchives(ctx, now, 1000); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	rows, err = archives.ListArchivesByOriginalTx(ctx, otID, 10)
+	if err != nil {
+		t.Fatalf("list after purge: %v", err)
+	}
+	if len(rows) != 1 || rows[0].TransactionID != otID+"-live" {
+		t.Fatalf("archives after purge: %+v", rows)
+	}
+
+	live := rows[0]
+	if err := archives.UpdateArchiveKey(ctx, live.ID, "it-old", "it-new", []byte("rewrapped")); err != nil {
+		t.Fatalf("rekey: %v", err)
+	}
+	// previous key 不匹配时不覆盖（并发轮换）。
+	if err := archives.UpdateArchiveKey(ctx, live.ID, "it-old", "it-other", []byte("stale")); err != nil {
+		t.Fatalf("stale rekey: %v", err)
+	}
+	rows, err = archives.ListArchivesByOriginalTx(ctx, otID, 10)
+	if err != nil {
+		t.Fatalf("list after rekey: %v", err)
+	}
+	if rows[0].KeyID != "it-new" || string(rows[0].WrappedKey) != "rewrapped" {
+		t.Fatalf("archive after rekey: %+v", rows[0])
+	}
+}
@@ -0,0 +1,230 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// ErrAppleProductNotFound 表示 apple_products 中不存在该 ID。
+var ErrAppleProductNotFound = errors.New("dao: apple product not found")
+
+// ErrDuplicateAppleProduct 表示 (product_id, environment) 已被其他行占用。
+var ErrDuplicateAppleProduct = errors.New("dao: duplicate apple product")
+
+// AppleProductDAO 读写数据库管理的 Apple IAP 商品目录。
+type AppleProductDAO interface {
+	ListProducts(ctx context.Context, activeOnly bool) ([]model.AppleProduct, error)
+	CountProducts(ctx context.Context) (int64, error)
+	// SeedProducts 逐条插入尚不存在的商品，已存在的 (product_id, environment) 跳过；返回实际插入的行数。
+	SeedProducts(ctx context.Context, in []model.AppleProductInput) (int64, error)
+	CreateProduct(ctx context.Context, in model.AppleProductInput) (model.AppleProduct, error)
+	GetProduct(ctx context.Context, id int64) (model.AppleProduct, error)
+	UpdateProduct(ctx context.Context, id int64, in model.AppleProductInput) (model.AppleProduct, error)
+}
+
+type appleProductDAO struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewAppleProductDAO 构造一个面向 PostgreSQL 的 AppleProductDAO。
+func NewAppleProductDAO(pool *pgxpool.Pool) AppleProductDAO {
+	return &appleProductDAO{pool: pool, queries: db.New(pool)}
+}
+
+// ListProducts 按 sort_order、id 升序返回商品；activeOnly 为 true 时只返回上架商品。
+func (d *appleProductDAO) ListProducts(ctx context.Context, activeOnly bool) ([]model.AppleProduct, error) {
+	var (
+		rows []db.AppleProduct
+		err  error
+	)
+	if activeOnly {
+		rows, err = d.queries.ListActiveAppleProducts(ctx)
+	} else {
+		rows, err = d.queries.ListAppleProducts(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("apple product dao: list: %w", err)
+	}
+	out := make([]model.AppleProduct, 0, len(rows))
+	for _, row := range rows {
+		p, err := appleProductFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// CountProducts 返回 apple_products 的总行数（含已下架）。
+func (d *appleProductDAO) CountProducts(ctx context.Context) (int64, error) {
+	n, err := d.queries.CountAppleProducts(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("apple product dao: count: %w", err)
+	}
+	return n, nil
+}
+
+// SeedProducts 在一个事务内插入 in；多个实例同时启动时由唯一约束保证不会重复写入。
+func (d *appleProductDAO) SeedProducts(ctx context.Context, in []model.AppleProductInput) (int64, error) {
+	if len(in) == 0 {
+		return 0, nil
+	}
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("apple product dao: begin tx: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	q := d.queries.WithTx(tx)
+	var inserted int64
+	for _, p := range in {
+		params, err := appleProductParams(p)
+		if err != nil {
+			return 0, err
+		}
+		n, err := q.InsertAppleProductIfNotExists(ctx, db.InsertAppleProductIfNotExistsParams(params))
+		if err != nil {
+			return 0, fmt.Errorf("apple product dao: seed %s/%s: %w", p.ProductID, p.Environment, err)
+		}
+		inserted += n
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("apple product dao: commit: %w", err)
+	}
+	committed = true
+	return inserted, nil
+}
+
+// CreateProduct 新增一条商品；(product_id, environment) 已存在时返回 ErrDuplicateAppleProduct。
+func (d *appleProductDAO) CreateProduct(ctx context.Context, in model.AppleProductInput) (model.AppleProduct, error) {
+	params, err := appleProductParams(in)
+	if err != nil {
+		return model.AppleProduct{}, err
+	}
+	row, err := d.queries.InsertAppleProduct(ctx, params)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.AppleProduct{}, ErrDuplicateAppleProduct
+		}
+		return model.AppleProduct{}, fmt.Errorf("apple product dao: insert: %w", err)
+	}
+	return appleProductFromRow(row)
+}
+
+// GetProduct 按 ID 读取商品；不存在返回 ErrAppleProductNotFound。
+func (d *appleProductDAO) GetProduct(ctx context.Context, id int64) (model.AppleProduct, error) {
+	row, err := d.queries.GetAppleProduct(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.AppleProduct{}, ErrAppleProductNotFound
+		}
+		return model.AppleProduct{}, fmt.Errorf("apple product dao: get: %w", err)
+	}
+	return appleProductFromRow(row)
+}
+
+// UpdateProduct 覆盖 id 对应商品的可变字段；商品标识 (product_id, environment) 与 type 保持不变。
+// 不存在返回 ErrAppleProductNotFound。
+func (d *appleProductDAO) UpdateProduct(ctx context.Context, id int64, in model.AppleProductInput) (model.AppleProduct, error) {
+	p, err := appleProductParams(in)
+	if err != nil {
+		return model.AppleProduct{}, err
+	}
+	row, err := d.queries.UpdateAppleProduct(ctx, db.UpdateAppleProductParams{
+		ID:                  id,
+		PlanID:              p.PlanID,
+		Level:               p.Level,
+		Credits:             p.Credits,
+		SubscriptionGroupID: p.SubscriptionGroupID,
+		DisplayName:         p.DisplayName,
+		Description:         p.Description,
+		SortOrder:           p.SortOrder,
+		Active:              p.Active,
+		Localizations:       p.Localizations,
+		Recommended:         p.Recommended,
+		PriceMilliunits:     p.PriceMilliunits,
+		Currency:            p.Currency,
+		BillingPeriod:       p.BillingPeriod,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.AppleProduct{}, ErrAppleProductNotFound
+		}
+		return model.AppleProduct{}, fmt.Errorf("apple product dao: update: %w", err)
+	}
+	return appleProductFromRow(row)
+}
+
+func appleProductParams(in model.AppleProductInput) (db.InsertAppleProductParams, error) {
+	localizations := in.Localizations
+	if localizations == nil {
+		localizations = map[string]model.ProductLocalization{}
+	}
+	raw, err := json.Marshal(localizations)
+	if err != nil {
+		return db.InsertAppleProductParams{}, fmt.Errorf("apple product dao: marshal localizations: %w", err)
+	}
+	return db.InsertAppleProductParams{
+		PlanID:              in.PlanID,
+		ProductID:           in.ProductID,
+		Environment:         string(in.Environment),
+		Type:                in.Type,
+		Level:               int32(in.Level),
+		Credits:             in.Credits,
+		SubscriptionGroupID: in.SubscriptionGroupID,
+		DisplayName:         in.DisplayName,
+		Description:         in.Description,
+		SortOrder:           int32(in.SortOrder),
+		Active:              in.Active,
+		Localizations:       raw,
+		Recommended:         in.Recommended,
+		PriceMilliunits:     in.PriceMilliunits,
+		Currency:            in.Currency,
+		BillingPeriod:       in.BillingPeriod,
+	}, nil
+}
+
+func appleProductFromRow(row db.AppleProduct) (model.AppleProduct, error) {
+	var localizations map[string]model.ProductLocalization
+	if len(row.Localizations) > 0 {
+		if err := json.Unmarshal(row.Localizations, &localizations); err != nil {
+			return model.AppleProduct{}, fmt.Errorf("apple product dao: decode localizations of %d: %w", row.ID, err)
+		}
+	}
+	return model.AppleProduct{
+		ID:                  row.ID,
+		PlanID:              row.PlanID,
+		ProductID:           row.ProductID,
+		Environment:         model.AppleEnvironment(row.Environment),
+		Type:                row.Type,
+		Level:               int(row.Level),
+		Credits:             row.Credits,
+		SubscriptionGroupID: row.SubscriptionGroupID,
+		DisplayName:         row.DisplayName,
+		Description:         row.Description,
+		SortOrder:           int(row.SortOrder),
+		Active:              row.Active,
+		Localizations:       localizations,
+		Recommended:         row.Recommended,
+		PriceMilliunits:     row.PriceMilliunits,
+		Currency:            row.Currency,
+		BillingPeriod:       row.BillingPeriod,
+		CreatedAt:           row.CreatedAt.Time,
+		UpdatedAt:           row.UpdatedAt.Time,
+	}, nil
+}
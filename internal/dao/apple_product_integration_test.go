@@ -0,0 +1,82 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_AppleProductDAO_CreateUpdateSeed(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	ctx := context.Background()
+	productID := "com.it.product." + t.Name()
+	defer func() {
+		_, _ = pool.Exec(ctx, "DELETE FROM apple_products WHERE product_id LIKE $1", productID+"%")
+	}()
+
+	d := NewAppleProductDAO(pool)
+	in := model.AppleProductInput{
+		PlanID: "it_plan", ProductID: productID, Environment: model.AppleEnvSandbox,
+		Type: model.ProductTypeSubscription, Level: 1, DisplayName: "IT", SortOrder: 5, Active: true, Recommended: true,
+		Localizations: map[string]model.ProductLocalization{"zh-Hans": {Title: "集成测试", Features: []string{"a", "b"}}},
+	}
+	created, err := d.CreateProduct(ctx, in)
+	if err != nil || created.ID == 0 || !created.Active || created.CreatedAt.IsZero() {
+		t.Fatalf("create: %+v %v", created, err)
+	}
+	if l := created.Localizations["zh-Hans"]; !created.Recommended || l.Title != "集成测试" || len(l.Features) != 2 {
+		t.Fatalf("paywall metadata not stored: %+v", created)
+	}
+	if _, err := d.CreateProduct(ctx, in); !errors.Is(err, ErrDuplicateAppleProduct) {
+		t.Fatalf("duplicate create err = %v, want ErrDuplicateAppleProduct", err)
+	}
+
+	in.Active = false
+	in.Level = 2
+	updated, err := d.UpdateProduct(ctx, created.ID, in)
+	if err != nil || updated.Active || updated.Level != 2 {
+		t.Fatalf("update: %+v %v", updated, err)
+	}
+	if _, err := d.UpdateProduct(ctx, -1, in); !errors.Is(err, ErrAppleProductNotFound) {
+		t.Fatalf("missing update err = %v, want ErrAppleProductNotFound", err)
+	}
+	// UPDATE 不触碰商品标识与类型。
+	renamed := in
+	renamed.ProductID, renamed.Type = productID+".renamed", model.ProductTypeNonConsumable
+	if _, err := d.UpdateProduct(ctx, created.ID, renamed); err != nil {
+		t.Fatalf("update with identity fields: %v", err)
+	}
+	got, err := d.GetProduct(ctx, created.ID)
+	if err != nil || got.ProductID != productID || got.Type != model.ProductTypeSubscription {
+		t.Fatalf("identity fields must stay unchanged: %+v %v", got, err)
+	}
+	if _, err := d.GetProduct(ctx, -1); !errors.Is(err, ErrAppleProductNotFound) {
+		t.Fatalf("missing get err = %v, want ErrAppleProductNotFound", err)
+	}
+
+	active, err := d.ListProducts(ctx, true)
+	if err != nil {
+		t.Fatalf("list active: %v", err)
+	}
+	for _, p := range active {
+		if p.ID == created.ID {
+			t.Fatalf("inactive product must not be listed as active: %+v", p)
+		}
+	}
+
+	// 已存在的 (product_id, environment) 被跳过，只插入新行。
+	second := in
+	second.ProductID = productID + ".yearly"
+	n, err := d.SeedProducts(ctx, []model.AppleProductInput{in, second})
+	if err != nil || n != 1 {
+		t.Fatalf("seed inserted = %d, err = %v, want 1", n, err)
+	}
+	if count, err := d.CountProducts(ctx); err != nil || count < 2 {
+		t.Fatalf("count = %d, err = %v", count, err)
+	}
+}
@@ -0,0 +1,70 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// AppleStatusSyncDAO 为订阅状态同步任务挑选待对账的 apple_subscriptions 行并记录同步结果。
+//
+// 订阅本身的写入仍然走 SubscriptionDAO.InTx + UpsertSubscriptionWithOwnershipCheck。
+type AppleStatusSyncDAO interface {
+	ListSubscriptionsDueForStatusSync(ctx context.Context, in model.SubscriptionStatusSyncFilter) ([]model.Subscription, error)
+	MarkSubscriptionStatusSynced(ctx context.Context, in model.SubscriptionStatusSync) error
+}
+
+type appleStatusSyncDAO struct {
+	queries *db.Queries
+}
+
+// NewAppleStatusSyncDAO 构造一个面向 PostgreSQL 的 AppleStatusSyncDAO。
+func NewAppleStatusSyncDAO(pool *pgxpool.Pool) AppleStatusSyncDAO {
+	return &appleStatusSyncDAO{queries: db.New(pool)}
+}
+
+// ListSubscriptionsDueForStatusSync 按“从未同步优先、其次 current_period_end 最早”的顺序返回至多 Limit 行。
+func (d *appleStatusSyncDAO) ListSubscriptionsDueForStatusSync(ctx context.Context, in model.SubscriptionStatusSyncFilter) ([]model.Subscription, error) {
+	if in.Limit <= 0 {
+		return nil, fmt.Errorf("apple status sync dao: invalid limit %d", in.Limit)
+	}
+	envs := make([]string, 0, len(in.Environments))
+	for _, e := range in.Environments {
+		envs = append(envs, string(e))
+	}
+	rows, err := d.queries.ListAppleSubscriptionsDueForStatusSync(ctx, db.ListAppleSubscriptionsDueForStatusSyncParams{
+		Environments:    envs,
+		PeriodEndBefore: timeToPgTimestamptz(in.PeriodEndBefore),
+		LastEventBefore: timeToPgTimestamptz(in.LastEventBefore),
+		SyncedBefore:    timeToPgTimestamptz(in.SyncedBefore),
+		BatchSize:       int32(in.Limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("apple status sync dao: list due: %w", err)
+	}
+	out := make([]model.Subscription, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapSubscriptionRow(r))
+	}
+	return out, nil
+}
+
+// MarkSubscriptionStatusSynced 写入（或覆盖）该订阅最近一次同步的时间与结果。
+func (d *appleStatusSyncDAO) MarkSubscriptionStatusSynced(ctx context.Context, in model.SubscriptionStatusSync) error {
+	if in.SubscriptionID <= 0 {
+		return fmt.Errorf("apple status sync dao: invalid subscription id %d", in.SubscriptionID)
+	}
+	if err := d.queries.UpsertAppleSubscriptionStatusSync(ctx, db.UpsertAppleSubscriptionStatusSyncParams{
+		SubscriptionID: in.SubscriptionID,
+		SyncedAt:       timeToPgTimestamptz(in.SyncedAt),
+		AppleStatus:    int32(in.AppleStatus),
+		LastError:      in.LastError,
+	}); err != nil {
+		return fmt.Errorf("apple status sync dao: mark synced: %w", err)
+	}
+	return nil
+}
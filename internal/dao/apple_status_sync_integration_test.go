@@ -0,0 +1,92 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_AppleStatusSyncDAO_DueSelectionAndBackoff(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	subs := NewSubscriptionDAO(pool)
+	syncs := NewAppleStatusSyncDAO(pool)
+	ctx := context.Background()
+	token, _, err := subs.GetOrCreateAccountToken(ctx, userID)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	insert := func(otID, status string, periodEnd, lastEvent time.Time) model.Subscription {
+		var row model.Subscription
+		if err := subs.InTx(ctx, func(qtx SubscriptionTx) error {
+			var err error
+			row, err = qtx.UpsertSubscriptionWithOwnershipCheck(ctx, model.SubscriptionUpsert{
+				UserID:                userID,
+				AppAccountToken:       token,
+				Environment:           model.AppleEnvSandbox,
+				OriginalTransactionID: otID,
+				LastTransactionID:     otID,
+				PlanID:                "pro_monthly",
+				ProviderProductID:     "com.app.pro.monthly",
+				Level:                 1,
+				Status:                status,
+				CurrentPeriodStart:    periodEnd.Add(-30 * 24 * time.Hour),
+				CurrentPeriodEnd:      periodEnd,
+				LastEventAt:           lastEvent,
+			})
+			return err
+		}); err != nil {
+			t.Fatalf("insert %s: %v", otID, err)
+		}
+		return row
+	}
+	prefix := "it-status-sync-" + t.Name() + "-"
+	expiring := insert(prefix+"expiring", model.SubscriptionStatusActive, now.Add(time.Hour), now)
+	stale := insert(prefix+"stale", model.SubscriptionStatusCanceled, now.Add(20*24*time.Hour), now.Add(-10*24*time.Hour))
+	insert(prefix+"fresh", model.SubscriptionStatusActive, now.Add(20*24*time.Hour), now)
+	insert(prefix+"expired", model.SubscriptionStatusExpired, now.Add(-time.Hour), now.Add(-10*24*time.Hour))
+
+	filter := model.SubscriptionStatusSyncFilter{
+		Environments:    []model.AppleEnvironment{model.AppleEnvSandbox},
+		PeriodEndBefore: now.Add(24 * time.Hour),
+		LastEventBefore: now.Add(-7 * 24 * time.Hour),
+		SyncedBefore:    now.Add(-6 * time.Hour),
+		Limit:           100,
+	}
+	due := func() map[int64]bool {
+		rows, err := syncs.ListSubscriptionsDueForStatusSync(ctx, filter)
+		if err != nil {
+			t.Fatalf("list due: %v", err)
+		}
+		out := map[int64]bool{}
+		for _, r := range rows {
+			if r.UserID == userID {
+				out[r.ID] = true
+			}
+		}
+		return out
+	}
+
+	if got := due(); len(got) != 2 || !got[expiring.ID] || !got[stale.ID] {
+		t.Fatalf("due before sync = %v, want expiring %d and stale %d", got, expiring.ID, stale.ID)
+	}
+
+	if err := syncs.MarkSubscriptionStatusSynced(ctx, model.SubscriptionStatusSync{SubscriptionID: expiring.ID, SyncedAt: now, AppleStatus: 1}); err != nil {
+		t.Fatalf("mark: %v", err)
+	}
+	if err := syncs.MarkSubscriptionStatusSynced(ctx, model.SubscriptionStatusSync{SubscriptionID: stale.ID, SyncedAt: now.Add(-7 * time.Hour), LastError: "boom"}); err != nil {
+		t.Fatalf("mark stale: %v", err)
+	}
+	if got := due(); len(got) != 1 || !got[stale.ID] {
+		t.Fatalf("due after sync = %v, want only stale %d", got, stale.ID)
+	}
+}
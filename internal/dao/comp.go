@@ -0,0 +1,166 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+var (
+	// ErrCompEntitlementNotFound 表示赠送权益不存在、不属于该用户或已被撤销。
+	ErrCompEntitlementNotFound = errors.New("dao: comp entitlement not found")
+	// ErrCompUserNotFound 表示发放赠送权益的目标用户不存在。
+	ErrCompUserNotFound = errors.New("dao: comp user not found")
+)
+
+// CompEntitlementDAO 读写赠送权益：订阅视图合并只读未撤销的权益，后台可直接发放与撤销。
+// 邀请、兑换码等业务发放在各自的事务内进行（见 grantCompTx）。
+type CompEntitlementDAO interface {
+	ListUnexpiredCompEntitlements(ctx context.Context, userID int64, now time.Time) ([]model.CompEntitlement, error)
+	ListCompEntitlements(ctx context.Context, userID int64) ([]model.CompEntitlement, error)
+	GrantComp(ctx context.Context, in model.CompGrant, now time.Time) (model.CompEntitlement, error)
+	RevokeComp(ctx context.Context, userID, id int64, now time.Time) (model.CompEntitlement, error)
+}
+
+type compEntitlementDAO struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewCompEntitlementDAO 构造一个面向 PostgreSQL 的 CompEntitlementDAO。
+func NewCompEntitlementDAO(pool *pgxpool.Pool) CompEntitlementDAO {
+	return &compEntitlementDAO{pool: pool, queries: db.New(pool)}
+}
+
+// ListUnexpiredCompEntitlements 返回 ends_at 晚于 now 的赠送权益（包括尚未开始的排队权益），按开始时间升序。
+func (d *compEntitlementDAO) ListUnexpiredCompEntitlements(ctx context.Context, userID int64, now time.Time) ([]model.CompEntitlement, error) {
+	rows, err := d.queries.ListUnexpiredCompEntitlements(ctx, db.ListUnexpiredCompEntitlementsParams{
+		UserID: userID,
+		Now:    timeToPgTimestamptz(now),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("comp dao: list: %w", err)
+	}
+	out := make([]model.CompEntitlement, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapCompEntitlementRow(r))
+	}
+	return out, nil
+}
+
+// ListCompEntitlements 返回用户的全部赠送权益（含已结束与已撤销的），按开始时间倒序。
+func (d *compEntitlementDAO) ListCompEntitlements(ctx context.Context, userID int64) ([]model.CompEntitlement, error) {
+	rows, err := d.queries.ListCompEntitlementsByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("comp dao: list by user: %w", err)
+	}
+	out := make([]model.CompEntitlement, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapCompEntitlementRow(r))
+	}
+	return out, nil
+}
+
+// GrantComp 在独立事务内发放一段赠送权益，排队规则同 grantCompTx。
+func (d *compEntitlementDAO) GrantComp(ctx context.Context, in model.CompGrant, now time.Time) (model.CompEntitlement, error) {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return model.CompEntitlement{}, fmt.Errorf("comp dao: begin tx: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	comp, err := grantCompTx(ctx, d.queries.WithTx(tx), in, now)
+	if err != nil {
+		return model.CompEntitlement{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return model.CompEntitlement{}, fmt.Errorf("comp dao: commit: %w", err)
+	}
+	committed = true
+	return comp, nil
+}
+
+// RevokeComp 撤销用户的一段赠送权益。撤销不会前移其后排队的权益；不存在或已撤销时返回 ErrCompEntitlementNotFound。
+func (d *compEntitlementDAO) RevokeComp(ctx context.Context, userID, id int64, now time.Time) (model.CompEntitlement, error) {
+	row, err := d.queries.RevokeCompEntitlement(ctx, db.RevokeCompEntitlementParams{
+		Now:    timeToPgTimestamptz(now),
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.CompEntitlement{}, ErrCompEntitlementNotFound
+		}
+		return model.CompEntitlement{}, fmt.Errorf("comp dao: revoke: %w", err)
+	}
+	return mapCompEntitlementRow(row), nil
+}
+
+// grantCompTx 在调用方事务内发放一段赠送权益：起点为 max(now, 该用户现有未撤销赠送权益的最晚结束时间)。
+// 同一用户的发放按 advisory lock 串行，并发发放不会排到同一起点。
+func grantCompTx(ctx context.Context, q *db.Queries, in model.CompGrant, now time.Time) (model.CompEntitlement, error) {
+	if in.UserID <= 0 {
+		return model.CompEntitlement{}, fmt.Errorf("comp dao: invalid user id %d", in.UserID)
+	}
+	if in.Duration <= 0 {
+		return model.CompEntitlement{}, fmt.Errorf("comp dao: invalid duration %s", in.Duration)
+	}
+	if in.PlanID == "" || in.Source == "" {
+		return model.CompEntitlement{}, errors.New("comp dao: plan id and source required")
+	}
+	if err := q.LockCompEntitlementQueue(ctx, in.UserID); err != nil {
+		return model.CompEntitlement{}, fmt.Errorf("comp dao: lock queue: %w", err)
+	}
+	start := now
+	latest, err := q.GetLatestCompEntitlementEnd(ctx, in.UserID)
+	if err != nil {
+		return model.CompEntitlement{}, fmt.Errorf("comp dao: latest end: %w", err)
+	}
+	if latest.Valid && latest.Time.After(start) {
+		start = latest.Time
+	}
+	row, err := q.InsertCompEntitlement(ctx, db.InsertCompEntitlementParams{
+		UserID:    in.UserID,
+		PlanID:    in.PlanID,
+		Level:     int32(in.Level),
+		Source:    in.Source,
+		Reference: in.Reference,
+		Note:      in.Note,
+		StartsAt:  timeToPgTimestamptz(start),
+		EndsAt:    timeToPgTimestamptz(start.Add(in.Duration)),
+	})
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return model.CompEntitlement{}, ErrCompUserNotFound
+		}
+		return model.CompEntitlement{}, fmt.Errorf("comp dao: insert: %w", err)
+	}
+	return mapCompEntitlementRow(row), nil
+}
+
+func mapCompEntitlementRow(row db.CompEntitlement) model.CompEntitlement {
+	return model.CompEntitlement{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		PlanID:    row.PlanID,
+		Level:     int(row.Level),
+		Source:    row.Source,
+		Reference: row.Reference,
+		Note:      row.Note,
+		StartsAt:  row.StartsAt.Time,
+		EndsAt:    row.EndsAt.Time,
+		RevokedAt: pgTimestamptzPtr(row.RevokedAt),
+	}
+}
@@ -0,0 +1,257 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// ErrInsufficientCredits 表示未过期 bucket 的剩余额度之和不足以支付本次消费。
+//
+// 该错误会触发 InTx 回滚，因此不会出现“扣了一半”的情况。
+var ErrInsufficientCredits = errors.New("dao: insufficient credits")
+
+// CreditDAO 暴露积分 bucket / ledger 的持久化操作。
+//
+// 与 SubscriptionDAO 一致：所有写入都在 InTx 作用域内完成，bucket 余额与 ledger 同事务提交；
+// 读路径不开事务。
+type CreditDAO interface {
+	ListActiveBuckets(ctx context.Context, userID int64, now time.Time) ([]model.CreditBucket, error)
+
+	InTx(ctx context.Context, fn func(CreditTx) error) error
+}
+
+// CreditTx 暴露事务作用域的积分写入操作。仅在 CreditDAO.InTx 回调里使用。
+type CreditTx interface {
+	GrantCredits(ctx context.Context, in model.CreditGrant) (model.CreditBucket, error)
+	SpendCredits(ctx context.Context, userID int64, amount int64, reference string, now time.Time) error
+	ExpireDueBuckets(ctx context.Context, now time.Time, limit int) (int, error)
+}
+
+type creditDAO struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewCreditDAO 构造一个面向 PostgreSQL 的 CreditDAO。
+func NewCreditDAO(pool *pgxpool.Pool) CreditDAO {
+	return &creditDAO{
+		pool:    pool,
+		queries: db.New(pool),
+	}
+}
+
+// ListActiveBuckets 返回 user 在 now 时刻仍可用的 bucket，按消费顺序（最早过期优先，永不过期最后）排列。
+func (d *creditDAO) ListActiveBuckets(ctx context.Context, userID int64, now time.Time) ([]model.CreditBucket, error) {
+	if userID <= 0 {
+		return nil, fmt.Errorf("credit dao: invalid user id %d", userID)
+	}
+	rows, err := d.queries.ListActiveCreditBuckets(ctx, db.ListActiveCreditBucketsParams{
+		UserID:    userID,
+		ExpiresAt: timeToPgTimestamptz(now),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("credit dao: list buckets: %w", err)
+	}
+	out := make([]model.CreditBucket, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapCreditBucketRow(r))
+	}
+	return out, nil
+}
+
+// InTx 在数据库事务内执行 fn，提交或回滚由 fn 的返回值驱动。
+func (d *creditDAO) InTx(ctx context.Context, fn func(CreditTx) error) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("credit dao: begin tx: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err := fn(&creditTxQueries{queries: d.queries.WithTx(tx)}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("credit dao: commit: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+type creditTxQueries struct {
+	queries *db.Queries
+}
+
+// GrantCredits 新建一个 bucket 并写入 GRANT ledger。
+func (c *creditTxQueries) GrantCredits(ctx context.Context, in model.CreditGrant) (model.CreditBucket, error) {
+	return grantCreditsTx(ctx, c.queries, in)
+}
+
+// SpendCredits 按 FIFO（最早过期优先）从 bucket 中扣减 amount，每个被扣减的 bucket 写一条 SPEND ledger。
+//
+// 所有候选 bucket 先 SELECT ... FOR UPDATE 锁定，再判断余额是否充足；不足时返回
+// ErrInsufficientCredits，由 InTx 回滚。
+func (c *creditTxQueries) SpendCredits(ctx context.Context, userID int64, amount int64, reference string, now time.Time) error {
+	if userID <= 0 {
+		return fmt.Errorf("credit dao: invalid user id %d", userID)
+	}
+	if amount <= 0 {
+		return fmt.Errorf("credit dao: invalid spend amount %d", amount)
+	}
+
+	buckets, err := c.queries.LockSpendableCreditBuckets(ctx, db.LockSpendableCreditBucketsParams{
+		UserID:    userID,
+		ExpiresAt: timeToPgTimestamptz(now),
+	})
+	if err != nil {
+		return fmt.Errorf("credit dao: lock buckets: %w", err)
+	}
+	var available int64
+	for _, b := range buckets {
+		available += b.Remaining
+	}
+	if available < amount {
+		return ErrInsufficientCredits
+	}
+
+	left := amount
+	for _, b := range buckets {
+		if left == 0 {
+			break
+		}
+		take := min(b.Remaining, left)
+		if err := c.queries.SetCreditBucketRemaining(ctx, db.SetCreditBucketRemainingParams{
+			ID:        b.ID,
+			Remaining: b.Remaining - take,
+		}); err != nil {
+			return fmt.Errorf("credit dao: debit bucket %d: %w", b.ID, err)
+		}
+		if err := insertCreditLedger(ctx, c.queries, userID, b.ID, model.CreditEntrySpend, -take, reference); err != nil {
+			return err
+		}
+		left -= take
+	}
+	return nil
+}
+
+// ExpireDueBuckets 把 expires_at <= now 且仍有剩余的 bucket 清零，并为每个 bucket 写一条 EXPIRE ledger。
+//
+// 单次最多处理 limit 个 bucket；使用 SKIP LOCKED，多实例并发 sweep 不会互相阻塞或重复过期。
+// 返回本次过期的 bucket 数量，调用方可据此判断是否需要继续下一批。
+func (c *creditTxQueries) ExpireDueBuckets(ctx context.Context, now time.Time, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+	buckets, err := c.queries.LockExpiredCreditBuckets(ctx, db.LockExpiredCreditBucketsParams{
+		ExpiresAt: timeToPgTimestamptz(now),
+		Limit:     int32(limit),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("credit dao: lock expired buckets: %w", err)
+	}
+	for _, b := range buckets {
+		if err := c.queries.SetCreditBucketRemaining(ctx, db.SetCreditBucketRemainingParams{
+			ID:        b.ID,
+			Remaining: 0,
+		}); err != nil {
+			return 0, fmt.Errorf("credit dao: expire bucket %d: %w", b.ID, err)
+		}
+		if err := insertCreditLedger(ctx, c.queries, b.UserID, b.ID, model.CreditEntryExpire, -b.Remaining, b.Reference); err != nil {
+			return 0, err
+		}
+	}
+	return len(buckets), nil
+}
+
+// grantCreditsTx 是所有发放路径共用的事务内实现；其他 DAO（例如需要在同一事务里发积分的业务）直接复用。
+func grantCreditsTx(ctx context.Context, q *db.Queries, in model.CreditGrant) (model.CreditBucket, error) {
+	if in.UserID <= 0 {
+		return model.CreditBucket{}, fmt.Errorf("credit dao: invalid user id %d", in.UserID)
+	}
+	if in.Amount <= 0 {
+		return model.CreditBucket{}, fmt.Errorf("credit dao: invalid grant amount %d", in.Amount)
+	}
+	if in.Source == "" {
+		return model.CreditBucket{}, errors.New("credit dao: source required")
+	}
+	row, err := q.InsertCreditBucket(ctx, db.InsertCreditBucketParams{
+		UserID:    in.UserID,
+		Source:    in.Source,
+		Amount:    in.Amount,
+		ExpiresAt: optionalTimePg(in.ExpiresAt),
+		Reference: in.Reference,
+	})
+	if err != nil {
+		return model.CreditBucket{}, fmt.Errorf("credit dao: insert bucket: %w", err)
+	}
+	if err := insertCreditLedger(ctx, q, in.UserID, row.ID, model.CreditEntryGrant, in.Amount, in.Reference); err != nil {
+		return model.CreditBucket{}, err
+	}
+	return mapCreditBucketRow(row), nil
+}
+
+// revokeCreditBucketTx 在调用方事务内收回一个 bucket 的剩余额度，写一条 REVOKE ledger，返回收回的数量。
+//
+// 已经消费掉的部分无法收回；bucket 已清零时不写 ledger。
+func revokeCreditBucketTx(ctx context.Context, q *db.Queries, bucketID int64, reference string) (int64, error) {
+	b, err := q.LockCreditBucket(ctx, bucketID)
+	if err != nil {
+		return 0, fmt.Errorf("credit dao: lock bucket %d: %w", bucketID, err)
+	}
+	if b.Remaining == 0 {
+		return 0, nil
+	}
+	if err := q.SetCreditBucketRemaining(ctx, db.SetCreditBucketRemainingParams{
+		ID:        b.ID,
+		Remaining: 0,
+	}); err != nil {
+		return 0, fmt.Errorf("credit dao: revoke bucket %d: %w", b.ID, err)
+	}
+	if err := insertCreditLedger(ctx, q, b.UserID, b.ID, model.CreditEntryRevoke, -b.Remaining, reference); err != nil {
+		return 0, err
+	}
+	return b.Remaining, nil
+}
+
+func insertCreditLedger(ctx context.Context, q *db.Queries, userID, bucketID int64, entryType string, delta int64, reference string) error {
+	_, err := q.InsertCreditLedgerEntry(ctx, db.InsertCreditLedgerEntryParams{
+		UserID:    userID,
+		BucketID:  pgtype.Int8{Int64: bucketID, Valid: true},
+		EntryType: entryType,
+		Delta:     delta,
+		Reference: reference,
+	})
+	if err != nil {
+		return fmt.Errorf("credit dao: insert ledger: %w", err)
+	}
+	return nil
+}
+
+func mapCreditBucketRow(row db.CreditBucket) model.CreditBucket {
+	out := model.CreditBucket{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		Source:    row.Source,
+		Amount:    row.Amount,
+		Remaining: row.Remaining,
+		Reference: row.Reference,
+		CreatedAt: row.CreatedAt.Time,
+	}
+	if row.ExpiresAt.Valid {
+		t := row.ExpiresAt.Time
+		out.ExpiresAt = &t
+	}
+	return out
+}
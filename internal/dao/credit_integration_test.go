@@ -0,0 +1,97 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_CreditDAO_FIFOSpendAndExpiry(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	d := NewCreditDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	soon := now.Add(time.Hour)
+	later := now.Add(48 * time.Hour)
+
+	var soonID, laterID, permanentID int64
+	if err := d.InTx(ctx, func(tx CreditTx) error {
+		grants := []struct {
+			id *int64
+			in model.CreditGrant
+		}{
+			{&permanentID, model.CreditGrant{UserID: userID, Source: model.CreditSourcePurchase, Amount: 100}},
+			{&laterID, model.CreditGrant{UserID: userID, Source: model.CreditSourcePromo, Amount: 50, ExpiresAt: &later}},
+			{&soonID, model.CreditGrant{UserID: userID, Source: model.CreditSourcePromo, Amount: 30, ExpiresAt: &soon}},
+		}
+		for _, g := range grants {
+			b, err := tx.GrantCredits(ctx, g.in)
+			if err != nil {
+				return err
+			}
+			*g.id = b.ID
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+
+	if err := d.InTx(ctx, func(tx CreditTx) error {
+		return tx.SpendCredits(ctx, userID, 40, "job-1", now)
+	}); err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+
+	buckets, err := d.ListActiveBuckets(ctx, userID, now)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	remaining := map[int64]int64{}
+	for _, b := range buckets {
+		remaining[b.ID] = b.Remaining
+	}
+	if _, ok := remaining[soonID]; ok {
+		t.Fatalf("soon bucket should be drained first: %+v", buckets)
+	}
+	if remaining[laterID] != 40 || remaining[permanentID] != 100 {
+		t.Fatalf("unexpected remaining after spend: %+v", remaining)
+	}
+
+	err = d.InTx(ctx, func(tx CreditTx) error {
+		return tx.SpendCredits(ctx, userID, 1000, "job-2", now)
+	})
+	if !errors.Is(err, ErrInsufficientCredits) {
+		t.Fatalf("err = %v, want ErrInsufficientCredits", err)
+	}
+
+	var expired int
+	if err := d.InTx(ctx, func(tx CreditTx) error {
+		n, err := tx.ExpireDueBuckets(ctx, later.Add(time.Second), 100)
+		expired = n
+		return err
+	}); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if expired < 1 {
+		t.Fatalf("expired = %d, want at least the later bucket", expired)
+	}
+	var expireDelta int64
+	if err := pool.QueryRow(ctx,
+		"SELECT COALESCE(SUM(delta), 0) FROM credit_ledger WHERE user_id = $1 AND entry_type = $2",
+		userID, model.CreditEntryExpire,
+	).Scan(&expireDelta); err != nil {
+		t.Fatalf("sum expire ledger: %v", err)
+	}
+	if expireDelta != -40 {
+		t.Fatalf("expire ledger delta = %d, want -40", expireDelta)
+	}
+}
@@ -0,0 +1,363 @@
+package dao
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// ErrWebhookSubscriberNotFound 表示 entitlement_webhook_subscribers 中不存在该 ID。
+var ErrWebhookSubscriberNotFound = errors.New("dao: entitlement webhook subscriber not found")
+
+// ErrWebhookDeliveryNotFound 表示 entitlement_webhook_deliveries 中不存在该 ID。
+var ErrWebhookDeliveryNotFound = errors.New("dao: entitlement webhook delivery not found")
+
+// EntitlementWebhookDAO 管理出站权益 webhook 的订阅方与投递记录。
+//
+// 事件的写入不在这里：各渠道的订阅写方法（Apple / Google Play / Stripe 的 Tx）在同一事务内调用
+// recordEntitlementChange，保证只有提交的变更才会产生投递。
+type EntitlementWebhookDAO interface {
+	ListSubscribers(ctx context.Context) ([]model.EntitlementWebhookSubscriber, error)
+	CreateSubscriber(ctx context.Context, in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriber, error)
+	UpdateSubscriber(ctx context.Context, id int64, in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriber, error)
+	// ClaimDueDeliveries 领取到期的投递并把 next_attempt_at 推到 leaseUntil，租约内其他实例不会重复领取。
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.EntitlementWebhookDispatch, error)
+	MarkDeliveryDelivered(ctx context.Context, id int64, statusCode int, deliveredAt time.Time) error
+	MarkDeliveryFailed(ctx context.Context, id int64, status string, statusCode int, lastError string, nextAttemptAt time.Time) error
+	ListDeliveries(ctx context.Context, q model.EntitlementWebhookDeliveryQuery) ([]model.EntitlementWebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, id int64, now time.Time) error
+	// ReplayDeadDeliveries 把该订阅方全部 DEAD 投递重新排入队列，返回受影响的行数。
+	ReplayDeadDeliveries(ctx context.Context, subscriberID int64, now time.Time) (int64, error)
+}
+
+type entitlementWebhookDAO struct {
+	queries *db.Queries
+}
+
+// NewEntitlementWebhookDAO 构造一个面向 PostgreSQL 的 EntitlementWebhookDAO。
+func NewEntitlementWebhookDAO(pool *pgxpool.Pool) EntitlementWebhookDAO {
+	return &entitlementWebhookDAO{queries: db.New(pool)}
+}
+
+// ListSubscribers 按 id 升序返回全部订阅方（含已停用）。
+func (d *entitlementWebhookDAO) ListSubscribers(ctx context.Context) ([]model.EntitlementWebhookSubscriber, error) {
+	rows, err := d.queries.ListEntitlementWebhookSubscribers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("entitlement webhook dao: list subscribers: %w", err)
+	}
+	out := make([]model.EntitlementWebhookSubscriber, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, mapEntitlementWebhookSubscriberRow(row))
+	}
+	return out, nil
+}
+
+// CreateSubscriber 新增一个订阅方；Secret 必须由调用方生成好。
+func (d *entitlementWebhookDAO) CreateSubscriber(ctx context.Context, in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriber, error) {
+	if in.Secret == "" {
+		return model.EntitlementWebhookSubscriber{}, errors.New("entitlement webhook dao: secret required")
+	}
+	row, err := d.queries.InsertEntitlementWebhookSubscriber(ctx, db.InsertEntitlementWebhookSubscriberParams{
+		Name:       in.Name,
+		Url:        in.URL,
+		Secret:     in.Secret,
+		EventTypes: in.EventTypes,
+		Active:     in.Active,
+	})
+	if err != nil {
+		return model.EntitlementWebhookSubscriber{}, fmt.Errorf("entitlement webhook dao: insert subscriber: %w", err)
+	}
+	return mapEntitlementWebhookSubscriberRow(row), nil
+}
+
+// UpdateSubscriber 整体替换订阅方配置；in.Secret 为空时保留原密钥。
+func (d *entitlementWebhookDAO) UpdateSubscriber(ctx context.Context, id int64, in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriber, error) {
+	row, err := d.queries.UpdateEntitlementWebhookSubscriber(ctx, db.UpdateEntitlementWebhookSubscriberParams{
+		Name:       in.Name,
+		Url:        in.URL,
+		Secret:     in.Secret,
+		EventTypes: in.EventTypes,
+		Active:     in.Active,
+		ID:         id,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.EntitlementWebhookSubscriber{}, ErrWebhookSubscriberNotFound
+		}
+		return model.EntitlementWebhookSubscriber{}, fmt.Errorf("entitlement webhook dao: update subscriber: %w", err)
+	}
+	return mapEntitlementWebhookSubscriberRow(row), nil
+}
+
+// ClaimDueDeliveries 只领取启用中订阅方的投递，next_attempt_at 最早的优先，最多 limit 条。
+func (d *entitlementWebhookDAO) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.EntitlementWebhookDispatch, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("entitlement webhook dao: invalid limit %d", limit)
+	}
+	rows, err := d.queries.ClaimDueEntitlementWebhookDeliveries(ctx, db.ClaimDueEntitlementWebhookDeliveriesParams{
+		LeaseUntil: timeToPgTimestamptz(leaseUntil),
+		Now:        timeToPgTimestamptz(now),
+		BatchSize:  int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("entitlement webhook dao: claim due: %w", err)
+	}
+	out := make([]model.EntitlementWebhookDispatch, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, model.EntitlementWebhookDispatch{
+			DeliveryID:     r.ID,
+			EventID:        r.EventID,
+			SubscriberID:   r.SubscriberID,
+			Attempts:       int(r.Attempts),
+			EventType:      r.EventType,
+			UserID:         r.UserID,
+			Payload:        r.Payload,
+			EventCreatedAt: r.CreatedAt.Time,
+			URL:            r.Url,
+			Secret:         r.Secret,
+		})
+	}
+	// UPDATE ... RETURNING 不保证顺序；按投递 ID 升序返回，同一批内先发生的事件先发送。
+	slices.SortFunc(out, func(a, b model.EntitlementWebhookDispatch) int { return cmp.Compare(a.DeliveryID, b.DeliveryID) })
+	return out, nil
+}
+
+// MarkDeliveryDelivered 把投递标记为 DELIVERED；仅对 PENDING 行生效。
+func (d *entitlementWebhookDAO) MarkDeliveryDelivered(ctx context.Context, id int64, statusCode int, deliveredAt time.Time) error {
+	if err := d.queries.MarkEntitlementWebhookDeliveryDelivered(ctx, db.MarkEntitlementWebhookDeliveryDeliveredParams{
+		LastStatusCode: int32(statusCode),
+		DeliveredAt:    timeToPgTimestamptz(deliveredAt),
+		ID:             id,
+	}); err != nil {
+		return fmt.Errorf("entitlement webhook dao: mark delivered: %w", err)
+	}
+	return nil
+}
+
+// MarkDeliveryFailed 记录一次失败：status 为 PENDING 时在 nextAttemptAt 重试，为 DEAD 时不再重试。
+func (d *entitlementWebhookDAO) MarkDeliveryFailed(ctx context.Context, id int64, status string, statusCode int, lastError string, nextAttemptAt time.Time) error {
+	if status != model.WebhookDeliveryPending && status != model.WebhookDeliveryDead {
+		return fmt.Errorf("entitlement webhook dao: invalid status %q", status)
+	}
+	if err := d.queries.MarkEntitlementWebhookDeliveryFailed(ctx, db.MarkEntitlementWebhookDeliveryFailedParams{
+		Status:         status,
+		LastStatusCode: int32(statusCode),
+		LastError:      lastError,
+		NextAttemptAt:  timeToPgTimestamptz(nextAttemptAt),
+		ID:             id,
+	}); err != nil {
+		return fmt.Errorf("entitlement webhook dao: mark failed: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries 按 id 倒序分页返回投递记录。
+func (d *entitlementWebhookDAO) ListDeliveries(ctx context.Context, q model.EntitlementWebhookDeliveryQuery) ([]model.EntitlementWebhookDelivery, error) {
+	if q.Limit <= 0 {
+		return nil, fmt.Errorf("entitlement webhook dao: invalid limit %d", q.Limit)
+	}
+	rows, err := d.queries.ListEntitlementWebhookDeliveries(ctx, db.ListEntitlementWebhookDeliveriesParams{
+		SubscriberID: q.SubscriberID,
+		Status:       q.Status,
+		BeforeID:     q.BeforeID,
+		BatchSize:    int32(q.Limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("entitlement webhook dao: list deliveries: %w", err)
+	}
+	out := make([]model.EntitlementWebhookDelivery, 0, len(rows))
+	for _, r := range rows {
+		item := model.EntitlementWebhookDelivery{
+			ID:             r.ID,
+			EventID:        r.EventID,
+			SubscriberID:   r.SubscriberID,
+			EventType:      r.EventType,
+			UserID:         r.UserID,
+			Status:         r.Status,
+			Attempts:       int(r.Attempts),
+			NextAttemptAt:  r.NextAttemptAt.Time,
+			LastStatusCode: int(r.LastStatusCode),
+			LastError:      r.LastError,
+			CreatedAt:      r.CreatedAt.Time,
+			UpdatedAt:      r.UpdatedAt.Time,
+		}
+		if r.DeliveredAt.Valid {
+			t := r.DeliveredAt.Time
+			item.DeliveredAt = &t
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+// ReplayDelivery 把任意状态的投递重置为 PENDING 并清零尝试次数，在 now 立即重发。
+func (d *entitlementWebhookDAO) ReplayDelivery(ctx context.Context, id int64, now time.Time) error {
+	if _, err := d.queries.ReplayEntitlementWebhookDelivery(ctx, db.ReplayEntitlementWebhookDeliveryParams{
+		Now: timeToPgTimestamptz(now),
+		ID:  id,
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWebhookDeliveryNotFound
+		}
+		return fmt.Errorf("entitlement webhook dao: replay delivery: %w", err)
+	}
+	return nil
+}
+
+// ReplayDeadDeliveries 不检查订阅方是否存在，不存在时返回 0。
+func (d *entitlementWebhookDAO) ReplayDeadDeliveries(ctx context.Context, subscriberID int64, now time.Time) (int64, error) {
+	n, err := d.queries.ReplayDeadEntitlementWebhookDeliveries(ctx, db.ReplayDeadEntitlementWebhookDeliveriesParams{
+		Now:          timeToPgTimestamptz(now),
+		SubscriberID: subscriberID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("entitlement webhook dao: replay dead deliveries: %w", err)
+	}
+	return n, nil
+}
+
+func mapEntitlementWebhookSubscriberRow(row db.EntitlementWebhookSubscriber) model.EntitlementWebhookSubscriber {
+	return model.EntitlementWebhookSubscriber{
+		ID:         row.ID,
+		Name:       row.Name,
+		URL:        row.Url,
+		Secret:     row.Secret,
+		EventTypes: row.EventTypes,
+		Active:     row.Active,
+		CreatedAt:  row.CreatedAt.Time,
+		UpdatedAt:  row.UpdatedAt.Time,
+	}
+}
+
+// ───────── 变更事件 ─────────
+
+// recordEntitlementChange 比较订阅行写入前后的快照，有变化时写入一条事件并扇出到订阅了该类型的订阅方。
+// before 为 nil 表示新建的订阅行。必须与订阅写入处于同一事务。
+func recordEntitlementChange(ctx context.Context, q *db.Queries, before *model.EntitlementChange, after model.EntitlementChange) error {
+	eventType := classifyEntitlementChange(before, after)
+	if eventType == "" {
+		return nil
+	}
+	if before != nil {
+		after.PreviousStatus = before.Status
+	}
+	return insertEntitlementEvent(ctx, q, eventType, after)
+}
+
+func insertEntitlementEvent(ctx context.Context, q *db.Queries, eventType string, change model.EntitlementChange) error {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("entitlement webhook dao: encode event: %w", err)
+	}
+	if _, err := q.InsertEntitlementWebhookEvent(ctx, db.InsertEntitlementWebhookEventParams{
+		EventType: eventType,
+		UserID:    change.UserID,
+		Payload:   payload,
+	}); err != nil {
+		return fmt.Errorf("entitlement webhook dao: insert event: %w", err)
+	}
+	return nil
+}
+
+// classifyEntitlementChange 返回事件类型；无需通知时返回空串。
+//
+// ACTIVE / CANCELED（已关闭续期但未到期）视为有权益：获得权益为 activated，失去权益为 deactivated，
+// 其余影响下游判断的字段变化为 updated。新建但已无权益的行（如恢复出的历史订阅）不通知。
+func classifyEntitlementChange(before *model.EntitlementChange, after model.EntitlementChange) string {
+	entitled := func(c model.EntitlementChange) bool {
+		return c.Status == model.SubscriptionStatusActive || c.Status == model.SubscriptionStatusCanceled
+	}
+	switch {
+	case before == nil:
+		if entitled(after) {
+			return model.EntitlementEventActivated
+		}
+		return ""
+	case !entitled(*before) && entitled(after):
+		return model.EntitlementEventActivated
+	case entitled(*before) && !entitled(after):
+		return model.EntitlementEventDeactivated
+	}
+	if before.UserID != after.UserID || before.PlanID != after.PlanID || before.Level != after.Level ||
+		before.Status != after.Status || before.AutoRenewStatus != after.AutoRenewStatus ||
+		!before.CurrentPeriodEnd.Equal(after.CurrentPeriodEnd) || !equalOptionalTime(before.GracePeriodExpiresAt, after.GracePeriodExpiresAt) ||
+		before.BillingRetry != after.BillingRetry || before.PendingPlanID != after.PendingPlanID {
+		return model.EntitlementEventUpdated
+	}
+	return ""
+}
+
+func equalOptionalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+func appleEntitlementChange(s model.Subscription) model.EntitlementChange {
+	return model.EntitlementChange{
+		UserID:               s.UserID,
+		Source:               model.EntitlementSourceApple,
+		SubscriptionID:       s.ID,
+		Environment:          string(s.Environment),
+		PlanID:               s.PlanID,
+		Level:                s.Level,
+		Status:               s.Status,
+		AutoRenewStatus:      s.AutoRenewStatus,
+		CurrentPeriodEnd:     s.CurrentPeriodEnd,
+		GracePeriodExpiresAt: s.GracePeriodExpiresAt,
+		BillingRetry:         s.BillingRetry,
+		PendingPlanID:        s.PendingPlanID,
+	}
+}
+
+// appleFamilyEntitlementChange 把家庭共享权益映射为快照；成员一侧没有自动续期信息。
+func appleFamilyEntitlementChange(s model.AppleFamilyShare) model.EntitlementChange {
+	return model.EntitlementChange{
+		UserID:           s.UserID,
+		Source:           model.EntitlementSourceAppleFamily,
+		SubscriptionID:   s.ID,
+		Environment:      string(s.Environment),
+		PlanID:           s.PlanID,
+		Level:            s.Level,
+		Status:           s.Status,
+		AutoRenewStatus:  model.AutoRenewStatusUnknown,
+		CurrentPeriodEnd: s.CurrentPeriodEnd,
+	}
+}
+
+func googlePlayEntitlementChange(s model.GooglePlaySubscription) model.EntitlementChange {
+	return model.EntitlementChange{
+		UserID:           s.UserID,
+		Source:           model.EntitlementSourceGooglePlay,
+		SubscriptionID:   s.ID,
+		PlanID:           s.PlanID,
+		Level:            s.Level,
+		Status:           s.Status,
+		AutoRenewStatus:  s.AutoRenewStatus,
+		CurrentPeriodEnd: s.CurrentPeriodEnd,
+	}
+}
+
+func stripeEntitlementChange(s model.StripeSubscription) model.EntitlementChange {
+	return model.EntitlementChange{
+		UserID:           s.UserID,
+		Source:           model.EntitlementSourceStripe,
+		SubscriptionID:   s.ID,
+		PlanID:           s.PlanID,
+		Level:            s.Level,
+		Status:           s.Status,
+		AutoRenewStatus:  s.AutoRenewStatus,
+		CurrentPeriodEnd: s.CurrentPeriodEnd,
+	}
+}
@@ -0,0 +1,181 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_EntitlementWebhookDAO_ChangeEventsAndDeliveries(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	hooks := NewEntitlementWebhookDAO(pool)
+	stripe := NewStripeDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	sub, err := hooks.CreateSubscriber(ctx, model.EntitlementWebhookSubscriberInput{
+		Name:       "it-" + t.Name(),
+		URL:        "https://example.com/hooks",
+		Secret:     "it-secret-0123456789",
+		EventTypes: []string{model.EntitlementEventActivated, model.EntitlementEventDeactivated},
+		Active:     true,
+	})
+	if err != nil {
+		t.Fatalf("create subscriber: %v", err)
+	}
+	defer func() {
+		_, _ = pool.Exec(ctx, "DELETE FROM entitlement_webhook_subscribers WHERE id = $1", sub.ID)
+	}()
+
+	in := model.StripeSubscriptionUpsert{
+		UserID:             userID,
+		CustomerID:         "cus_it_" + t.Name(),
+		SubscriptionID:     "sub_it_" + t.Name(),
+		PriceID:            "price_it",
+		PlanID:             "pro_monthly",
+		Level:              1,
+		Status:             model.SubscriptionStatusActive,
+		StripeStatus:       "active",
+		AutoRenewStatus:    model.AutoRenewStatusOn,
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   now.Add(30 * 24 * time.Hour),
+		LastEventAt:        now,
+	}
+	upsert := func(in model.StripeSubscriptionUpsert) {
+		t.Helper()
+		if err := stripe.InTx(ctx, func(tx StripeTx) error {
+			_, err := tx.UpsertSubscriptionWithOwnershipCheck(ctx, in)
+			return err
+		}); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+	upsert(in) // 新订阅 → activated
+	upsert(in) // 无变化 → 不产生事件
+	renewOff := in
+	renewOff.AutoRenewStatus = model.AutoRenewStatusOff
+	upsert(renewOff) // updated，但订阅方未订阅该类型
+	expired := renewOff
+	expired.Status = model.SubscriptionStatusExpired
+	upsert(expired) // → deactivated
+
+	// 订阅写入失败回滚时，事件一并回滚。
+	rollback := errors.New("rollback")
+	if err := stripe.InTx(ctx, func(tx StripeTx) error {
+		if _, err := tx.UpsertSubscriptionWithOwnershipCheck(ctx, in); err != nil {
+			return err
+		}
+		return rollback
+	}); !errors.Is(err, rollback) {
+		t.Fatalf("rollback tx err = %v", err)
+	}
+
+	list, err := hooks.ListDeliveries(ctx, model.EntitlementWebhookDeliveryQuery{SubscriberID: sub.ID, Limit: 10})
+	if err != nil {
+		t.Fatalf("list deliveries: %v", err)
+	}
+	if len(list) != 2 || list[0].EventType != model.EntitlementEventDeactivated || list[1].EventType != model.EntitlementEventActivated ||
+		list[0].UserID != userID || list[0].Status != model.WebhookDeliveryPending {
+		t.Fatalf("unexpected deliveries: %+v", list)
+	}
+
+	claimed, err := hooks.ClaimDueDeliveries(ctx, now.Add(time.Minute), now.Add(time.Hour), 100)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	var mine []model.EntitlementWebhookDispatch
+	for _, d := range claimed {
+		if d.SubscriberID == sub.ID {
+			mine = append(mine, d)
+		}
+	}
+	if len(mine) != 2 || mine[0].URL != sub.URL || mine[0].Secret != sub.Secret {
+		t.Fatalf("unexpected claim: %+v", mine)
+	}
+	var data model.EntitlementChange
+	if err := json.Unmarshal(mine[1].Payload, &data); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if mine[1].EventType != model.EntitlementEventDeactivated || data.Source != model.EntitlementSourceStripe ||
+		data.Status != model.SubscriptionStatusExpired || data.PreviousStatus != model.SubscriptionStatusActive || data.UserID != userID {
+		t.Fatalf("unexpected deactivated event: %s %+v", mine[1].EventType, data)
+	}
+	// 租约内不会被再次领取。
+	again, err := hooks.ClaimDueDeliveries(ctx, now.Add(2*time.Minute), now.Add(time.Hour), 100)
+	if err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+	for _, d := range again {
+		if d.SubscriberID == sub.ID {
+			t.Fatalf("delivery claimed twice: %+v", d)
+		}
+	}
+
+	if err := hooks.MarkDeliveryDelivered(ctx, mine[0].DeliveryID, 204, now); err != nil {
+		t.Fatalf("mark delivered: %v", err)
+	}
+	if err := hooks.MarkDeliveryFailed(ctx, mine[1].DeliveryID, model.WebhookDeliveryDead, 500, "unexpected status 500", now); err != nil {
+		t.Fatalf("mark dead: %v", err)
+	}
+	dead, err := hooks.ListDeliveries(ctx, model.EntitlementWebhookDeliveryQuery{SubscriberID: sub.ID, Status: model.WebhookDeliveryDead, Limit: 10})
+	if err != nil || len(dead) != 1 || dead[0].ID != mine[1].DeliveryID || dead[0].Attempts != 1 || dead[0].LastStatusCode != 500 {
+		t.Fatalf("dead deliveries: %+v %v", dead, err)
+	}
+
+	n, err := hooks.ReplayDeadDeliveries(ctx, sub.ID, now)
+	if err != nil || n != 1 {
+		t.Fatalf("replay dead: n=%d err=%v", n, err)
+	}
+	if err := hooks.ReplayDelivery(ctx, mine[0].DeliveryID, now); err != nil {
+		t.Fatalf("replay delivered: %v", err)
+	}
+	if err := hooks.ReplayDelivery(ctx, -1, now); !errors.Is(err, ErrWebhookDeliveryNotFound) {
+		t.Fatalf("replay missing err = %v, want ErrWebhookDeliveryNotFound", err)
+	}
+	pending, err := hooks.ListDeliveries(ctx, model.EntitlementWebhookDeliveryQuery{SubscriberID: sub.ID, Status: model.WebhookDeliveryPending, Limit: 1})
+	if err != nil || len(pending) != 1 || pending[0].Attempts != 0 || pending[0].DeliveredAt != nil {
+		t.Fatalf("pending after replay: %+v %v", pending, err)
+	}
+	next, err := hooks.ListDeliveries(ctx, model.EntitlementWebhookDeliveryQuery{SubscriberID: sub.ID, BeforeID: pending[0].ID, Limit: 10})
+	if err != nil || len(next) != 1 {
+		t.Fatalf("second page: %+v %v", next, err)
+	}
+
+	// 停用的订阅方不再产生投递，也不领取已有投递；更新时省略 secret 保留原密钥。
+	updated, err := hooks.UpdateSubscriber(ctx, sub.ID, model.EntitlementWebhookSubscriberInput{
+		Name:       sub.Name,
+		URL:        sub.URL,
+		EventTypes: sub.EventTypes,
+		Active:     false,
+	})
+	if err != nil || updated.Active || updated.Secret != sub.Secret {
+		t.Fatalf("update subscriber: %+v %v", updated, err)
+	}
+	upsert(in)
+	claimed, err = hooks.ClaimDueDeliveries(ctx, now.Add(time.Minute), now.Add(time.Hour), 100)
+	if err != nil {
+		t.Fatalf("claim after deactivate: %v", err)
+	}
+	for _, d := range claimed {
+		if d.SubscriberID == sub.ID {
+			t.Fatalf("inactive subscriber delivery claimed: %+v", d)
+		}
+	}
+	all, err := hooks.ListDeliveries(ctx, model.EntitlementWebhookDeliveryQuery{SubscriberID: sub.ID, Limit: 10})
+	if err != nil || len(all) != 2 {
+		t.Fatalf("deliveries after deactivate: %+v %v", all, err)
+	}
+	if _, err := hooks.UpdateSubscriber(ctx, -1, model.EntitlementWebhookSubscriberInput{Name: "x", URL: sub.URL, EventTypes: sub.EventTypes}); !errors.Is(err, ErrWebhookSubscriberNotFound) {
+		t.Fatalf("update missing err = %v, want ErrWebhookSubscriberNotFound", err)
+	}
+}
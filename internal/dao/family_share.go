@@ -0,0 +1,125 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// ErrFamilyShareNotFound 表示按 (original_transaction_id, environment) 查找家庭共享权益时未命中。
+var ErrFamilyShareNotFound = errors.New("dao: apple family share not found")
+
+// ErrFamilyShareOwnershipConflict 表示该家庭共享 transaction 已被其他 user 认领。
+var ErrFamilyShareOwnershipConflict = errors.New("dao: apple family share owned by another user")
+
+// ListFamilySharesForUserEntitlement 返回 user 在 allowedEnvs 内认领的家庭共享权益（含已撤销 / 过期的），
+// 按 level DESC、current_period_end DESC 排序。
+func (d *subscriptionDAO) ListFamilySharesForUserEntitlement(ctx context.Context, userID int64, allowedEnvs []model.AppleEnvironment) ([]model.AppleFamilyShare, error) {
+	if userID <= 0 {
+		return nil, fmt.Errorf("subscription dao: invalid user id %d", userID)
+	}
+	envs := make([]string, 0, len(allowedEnvs))
+	for _, e := range allowedEnvs {
+		envs = append(envs, string(e))
+	}
+	rows, err := d.queries.ListAppleFamilySharesForUserEntitlement(ctx, db.ListAppleFamilySharesForUserEntitlementParams{
+		UserID:  userID,
+		Column2: envs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("subscription dao: list family shares: %w", err)
+	}
+	out := make([]model.AppleFamilyShare, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapFamilyShareRow(r))
+	}
+	return out, nil
+}
+
+// GetFamilyShare 在事务内加锁读取家庭共享权益。
+func (s *subscriptionTxQueries) GetFamilyShare(ctx context.Context, originalTxID string, env model.AppleEnvironment) (model.AppleFamilyShare, error) {
+	row, err := s.queries.LockAppleFamilyShare(ctx, db.LockAppleFamilyShareParams{
+		OriginalTransactionID: originalTxID,
+		Environment:           string(env),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.AppleFamilyShare{}, ErrFamilyShareNotFound
+		}
+		return model.AppleFamilyShare{}, fmt.Errorf("subscription dao: lock family share: %w", err)
+	}
+	return mapFamilyShareRow(row), nil
+}
+
+// UpsertFamilyShareWithOwnershipCheck 写入或更新家庭共享权益；已被其他 user 认领时返回
+// ErrFamilyShareOwnershipConflict，ON CONFLICT DO UPDATE 不触碰 user_id。权益变化在同一事务内
+// 以 APPLE_FAMILY_SHARING 来源写入权益变更事件。
+func (s *subscriptionTxQueries) UpsertFamilyShareWithOwnershipCheck(ctx context.Context, in model.AppleFamilyShareUpsert) (model.AppleFamilyShare, error) {
+	if in.UserID <= 0 {
+		return model.AppleFamilyShare{}, fmt.Errorf("subscription dao: invalid user id %d", in.UserID)
+	}
+	if in.OriginalTransactionID == "" || in.Environment == "" {
+		return model.AppleFamilyShare{}, errors.New("subscription dao: original transaction id and environment required")
+	}
+	existing, err := s.GetFamilyShare(ctx, in.OriginalTransactionID, in.Environment)
+	if err != nil && !errors.Is(err, ErrFamilyShareNotFound) {
+		return model.AppleFamilyShare{}, err
+	}
+	if err == nil && existing.UserID != in.UserID {
+		return model.AppleFamilyShare{}, ErrFamilyShareOwnershipConflict
+	}
+	row, err := s.queries.UpsertAppleFamilyShare(ctx, db.UpsertAppleFamilyShareParams{
+		UserID:                in.UserID,
+		Environment:           string(in.Environment),
+		OriginalTransactionID: in.OriginalTransactionID,
+		LastTransactionID:     in.LastTransactionID,
+		PlanID:                in.PlanID,
+		ProviderProductID:     in.ProviderProductID,
+		Level:                 int32(in.Level),
+		Status:                defaultIfEmpty(in.Status, model.SubscriptionStatusActive),
+		CurrentPeriodEnd:      timeToPgTimestamptz(in.CurrentPeriodEnd),
+		RevokedAt:             optionalTimePg(in.RevokedAt),
+		LastEventAt:           timeToPgTimestamptz(in.LastEventAt),
+	})
+	if err != nil {
+		return model.AppleFamilyShare{}, fmt.Errorf("subscription dao: upsert family share: %w", err)
+	}
+	share := mapFamilyShareRow(row)
+	var before *model.EntitlementChange
+	if existing.ID != 0 {
+		c := appleFamilyEntitlementChange(existing)
+		before = &c
+	}
+	if err := recordEntitlementChange(ctx, s.queries, before, appleFamilyEntitlementChange(share)); err != nil {
+		return model.AppleFamilyShare{}, err
+	}
+	return share, nil
+}
+
+func mapFamilyShareRow(row db.AppleFamilyShare) model.AppleFamilyShare {
+	out := model.AppleFamilyShare{
+		ID:                    row.ID,
+		UserID:                row.UserID,
+		Environment:           model.AppleEnvironment(row.Environment),
+		OriginalTransactionID: row.OriginalTransactionID,
+		LastTransactionID:     row.LastTransactionID,
+		PlanID:                row.PlanID,
+		ProviderProductID:     row.ProviderProductID,
+		Level:                 int(row.Level),
+		Status:                row.Status,
+		CurrentPeriodEnd:      row.CurrentPeriodEnd.Time,
+		LastEventAt:           row.LastEventAt.Time,
+		CreatedAt:             row.CreatedAt.Time,
+		UpdatedAt:             row.UpdatedAt.Time,
+	}
+	if row.RevokedAt.Valid {
+		t := row.RevokedAt.Time
+		out.RevokedAt = &t
+	}
+	return out
+}
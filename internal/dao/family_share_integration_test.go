@@ -0,0 +1,103 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_FamilyShare_ClaimOwnershipAndRevoke(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+	otherID, cleanupOther := withTestUser(t, pool)
+	defer cleanupOther()
+
+	d := NewSubscriptionDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	otID := "it-family-" + t.Name()
+
+	in := model.AppleFamilyShareUpsert{
+		UserID:                userID,
+		Environment:           model.AppleEnvSandbox,
+		OriginalTransactionID: otID,
+		LastTransactionID:     otID,
+		PlanID:                "pro_monthly",
+		ProviderProductID:     "com.app.pro.monthly",
+		Level:                 1,
+		Status:                model.SubscriptionStatusActive,
+		CurrentPeriodEnd:      now.Add(30 * 24 * time.Hour),
+		LastEventAt:           now,
+	}
+	if err := d.InTx(ctx, func(tx SubscriptionTx) error {
+		if _, err := tx.GetFamilyShare(ctx, otID, model.AppleEnvSandbox); !errors.Is(err, ErrFamilyShareNotFound) {
+			t.Fatalf("get before claim err = %v, want ErrFamilyShareNotFound", err)
+		}
+		_, err := tx.UpsertFamilyShareWithOwnershipCheck(ctx, in)
+		return err
+	}); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	other := in
+	other.UserID = otherID
+	if err := d.InTx(ctx, func(tx SubscriptionTx) error {
+		_, err := tx.UpsertFamilyShareWithOwnershipCheck(ctx, other)
+		return err
+	}); !errors.Is(err, ErrFamilyShareOwnershipConflict) {
+		t.Fatalf("other user err = %v, want ErrFamilyShareOwnershipConflict", err)
+	}
+
+	revoked := in
+	revoked.Status = model.SubscriptionStatusRevoked
+	revoked.RevokedAt = &now
+	if err := d.InTx(ctx, func(tx SubscriptionTx) error {
+		_, err := tx.UpsertFamilyShareWithOwnershipCheck(ctx, revoked)
+		return err
+	}); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+
+	rows, err := d.ListFamilySharesForUserEntitlement(ctx, userID, []model.AppleEnvironment{model.AppleEnvSandbox})
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("list: %+v %v", rows, err)
+	}
+	if rows[0].Status != model.SubscriptionStatusRevoked || rows[0].RevokedAt == nil || !rows[0].RevokedAt.Equal(now) {
+		t.Fatalf("unexpected row after revoke: %+v", rows[0])
+	}
+	if rows, err := d.ListFamilySharesForUserEntitlement(ctx, userID, []model.AppleEnvironment{model.AppleEnvProduction}); err != nil || len(rows) != 0 {
+		t.Fatalf("production list should be empty: %+v %v", rows, err)
+	}
+	// 认领与撤销各产生一条权益变更事件，冲突的写入不产生事件。
+	events, err := pool.Query(ctx, `SELECT event_type, payload->>'source', payload->>'status'
+		FROM entitlement_webhook_events WHERE user_id = ANY($1::bigint[]) ORDER BY id`, []int64{userID, otherID})
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+	defer events.Close()
+	var got []string
+	for events.Next() {
+		var eventType, source, status string
+		if err := events.Scan(&eventType, &source, &status); err != nil {
+			t.Fatalf("scan event: %v", err)
+		}
+		got = append(got, eventType+"/"+source+"/"+status)
+	}
+	if err := events.Err(); err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+	want := []string{
+		model.EntitlementEventActivated + "/" + model.EntitlementSourceAppleFamily + "/" + model.SubscriptionStatusActive,
+		model.EntitlementEventDeactivated + "/" + model.EntitlementSourceAppleFamily + "/" + model.SubscriptionStatusRevoked,
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("entitlement events = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,360 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// ErrGooglePlaySubscriptionNotFound 表示按 purchase_token 查找 Google Play 订阅时未命中。
+var ErrGooglePlaySubscriptionNotFound = errors.New("dao: google play subscription not found")
+
+// ErrGooglePlayPurchaseNotFound 表示按 purchase_token 查找 Google Play 一次性购买时未命中。
+var ErrGooglePlayPurchaseNotFound = errors.New("dao: google play purchase not found")
+
+// ErrGooglePlayOwnershipConflict 表示同一 purchase_token 已经记在其他 user 名下。
+var ErrGooglePlayOwnershipConflict = errors.New("dao: google play purchase token owned by another user")
+
+// GooglePlayDAO 暴露 Google Play Billing 的持久化操作。
+//
+// 与 SubscriptionDAO 相同：写路径（RTDN 事件 + 订阅 / 购买）都在 InTx 内完成，读路径不开事务。
+type GooglePlayDAO interface {
+	ListSubscriptionsForUserEntitlement(ctx context.Context, userID int64) ([]model.GooglePlaySubscription, error)
+	ListActiveLifetimePurchases(ctx context.Context, userID int64) ([]model.GooglePlayPurchase, error)
+
+	InTx(ctx context.Context, fn func(GooglePlayTx) error) error
+}
+
+// GooglePlayTx 暴露事务作用域的写入操作。仅在 GooglePlayDAO.InTx 回调里使用。
+type GooglePlayTx interface {
+	InsertEventIfNotExists(ctx context.Context, in model.GooglePlayEventInsert) (bool, error)
+
+	GetSubscriptionByToken(ctx context.Context, purchaseToken string) (model.GooglePlaySubscription, error)
+	UpsertSubscriptionWithOwnershipCheck(ctx context.Context, in model.GooglePlaySubscriptionUpsert) (model.GooglePlaySubscription, error)
+	RevokeSubscription(ctx context.Context, sub model.GooglePlaySubscription, revokedAt time.Time) (model.GooglePlaySubscription, error)
+
+	InsertPurchaseIfNotExists(ctx context.Context, in model.GooglePlayPurchaseInsert) (model.GooglePlayPurchase, bool, error)
+	GetPurchaseByToken(ctx context.Context, purchaseToken string) (model.GooglePlayPurchase, error)
+	GrantPurchaseCredits(ctx context.Context, purchaseID int64, in model.CreditGrant) (model.CreditBucket, error)
+	RefundPurchase(ctx context.Context, p model.GooglePlayPurchase, revokedAt time.Time) (model.GooglePlayPurchase, error)
+}
+
+type googlePlayDAO struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewGooglePlayDAO 构造一个面向 PostgreSQL 的 GooglePlayDAO。
+func NewGooglePlayDAO(pool *pgxpool.Pool) GooglePlayDAO {
+	return &googlePlayDAO{
+		pool:    pool,
+		queries: db.New(pool),
+	}
+}
+
+// ListSubscriptionsForUserEntitlement 返回 user 的 Google Play 订阅，排序规则与 Apple 订阅一致：
+// 仍有效的行优先，其次 level DESC、current_period_end DESC、last_event_at DESC。
+func (d *googlePlayDAO) ListSubscriptionsForUserEntitlement(ctx context.Context, userID int64) ([]model.GooglePlaySubscription, error) {
+	if userID <= 0 {
+		return nil, fmt.Errorf("google play dao: invalid user id %d", userID)
+	}
+	rows, err := d.queries.ListGooglePlaySubscriptionsForUserEntitlement(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("google play dao: list subscriptions: %w", err)
+	}
+	out := make([]model.GooglePlaySubscription, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapGooglePlaySubscriptionRow(r))
+	}
+	return out, nil
+}
+
+// ListActiveLifetimePurchases 返回 user 未退款的非消耗型购买，按 level DESC 排序。
+func (d *googlePlayDAO) ListActiveLifetimePurchases(ctx context.Context, userID int64) ([]model.GooglePlayPurchase, error) {
+	if userID <= 0 {
+		return nil, fmt.Errorf("google play dao: invalid user id %d", userID)
+	}
+	rows, err := d.queries.ListActiveGooglePlayLifetimePurchasesByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("google play dao: list lifetime purchases: %w", err)
+	}
+	out := make([]model.GooglePlayPurchase, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapGooglePlayPurchaseRow(r))
+	}
+	return out, nil
+}
+
+// InTx 在数据库事务内执行 fn，提交或回滚由 fn 的返回值驱动。
+func (d *googlePlayDAO) InTx(ctx context.Context, fn func(GooglePlayTx) error) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("google play dao: begin tx: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err := fn(&googlePlayTxQueries{queries: d.queries.WithTx(tx)}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("google play dao: commit: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+type googlePlayTxQueries struct {
+	queries *db.Queries
+}
+
+// InsertEventIfNotExists 把 RTDN 幂等地写入 google_play_events；返回 false 表示 message_id 已存在（重复投递）。
+func (s *googlePlayTxQueries) InsertEventIfNotExists(ctx context.Context, in model.GooglePlayEventInsert) (bool, error) {
+	if in.MessageID == "" {
+		return false, errors.New("google play dao: message id required")
+	}
+	_, err := s.queries.InsertGooglePlayEventIfNotExists(ctx, db.InsertGooglePlayEventIfNotExistsParams{
+		MessageID:        in.MessageID,
+		NotificationKind: in.NotificationKind,
+		NotificationType: int32(in.NotificationType),
+		PackageName:      in.PackageName,
+		PurchaseToken:    in.PurchaseToken,
+		ProductID:        in.ProductID,
+		UserID:           int64ToPgInt8(in.UserID),
+		ProcessingStatus: defaultIfEmpty(in.ProcessingStatus, model.EventStatusProcessed),
+		ProcessingError:  in.ProcessingError,
+		EventTime:        optionalTimePg(in.EventTime),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("google play dao: insert event: %w", err)
+	}
+	return true, nil
+}
+
+// GetSubscriptionByToken 在事务内按 purchase_token 读取订阅并加行锁；未命中返回 ErrGooglePlaySubscriptionNotFound。
+func (s *googlePlayTxQueries) GetSubscriptionByToken(ctx context.Context, purchaseToken string) (model.GooglePlaySubscription, error) {
+	row, err := s.queries.LockGooglePlaySubscriptionByToken(ctx, purchaseToken)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.GooglePlaySubscription{}, ErrGooglePlaySubscriptionNotFound
+		}
+		return model.GooglePlaySubscription{}, fmt.Errorf("google play dao: lock subscription: %w", err)
+	}
+	return mapGooglePlaySubscriptionRow(row), nil
+}
+
+// UpsertSubscriptionWithOwnershipCheck 在事务内 upsert 订阅；现有行属于其他 user 时返回 ErrGooglePlayOwnershipConflict。
+func (s *googlePlayTxQueries) UpsertSubscriptionWithOwnershipCheck(ctx context.Context, in model.GooglePlaySubscriptionUpsert) (model.GooglePlaySubscription, error) {
+	if in.UserID <= 0 {
+		return model.GooglePlaySubscription{}, fmt.Errorf("google play dao: invalid user id %d", in.UserID)
+	}
+	if in.PurchaseToken == "" {
+		return model.GooglePlaySubscription{}, errors.New("google play dao: purchase token required")
+	}
+	existing, err := s.GetSubscriptionByToken(ctx, in.PurchaseToken)
+	if err != nil && !errors.Is(err, ErrGooglePlaySubscriptionNotFound) {
+		return model.GooglePlaySubscription{}, err
+	}
+	if err == nil && existing.UserID != in.UserID {
+		return model.GooglePlaySubscription{}, ErrGooglePlayOwnershipConflict
+	}
+
+	row, err := s.queries.UpsertGooglePlaySubscription(ctx, db.UpsertGooglePlaySubscriptionParams{
+		UserID:              in.UserID,
+		PackageName:         in.PackageName,
+		PurchaseToken:       in.PurchaseToken,
+		LinkedPurchaseToken: in.LinkedPurchaseToken,
+		LatestOrderID:       in.LatestOrderID,
+		PlanID:              in.PlanID,
+		ProviderProductID:   in.ProviderProductID,
+		Level:               int32(in.Level),
+		Status:              defaultIfEmpty(in.Status, model.SubscriptionStatusActive),
+		AutoRenewStatus:     defaultIfEmpty(in.AutoRenewStatus, model.AutoRenewStatusUnknown),
+		TestPurchase:        in.TestPurchase,
+		CurrentPeriodStart:  timeToPgTimestamptz(in.CurrentPeriodStart),
+		CurrentPeriodEnd:    timeToPgTimestamptz(in.CurrentPeriodEnd),
+		LastEventAt:         timeToPgTimestamptz(in.LastEventAt),
+	})
+	if err != nil {
+		return model.GooglePlaySubscription{}, fmt.Errorf("google play dao: upsert subscription: %w", err)
+	}
+	sub := mapGooglePlaySubscriptionRow(row)
+	var before *model.EntitlementChange
+	if existing.ID != 0 {
+		c := googlePlayEntitlementChange(existing)
+		before = &c
+	}
+	if err := recordEntitlementChange(ctx, s.queries, before, googlePlayEntitlementChange(sub)); err != nil {
+		return model.GooglePlaySubscription{}, err
+	}
+	return sub, nil
+}
+
+// RevokeSubscription 把订阅标记为 REVOKED（退款 / 作废）；sub 是调用方在同一事务内读到的当前行。
+func (s *googlePlayTxQueries) RevokeSubscription(ctx context.Context, sub model.GooglePlaySubscription, revokedAt time.Time) (model.GooglePlaySubscription, error) {
+	row, err := s.queries.RevokeGooglePlaySubscription(ctx, db.RevokeGooglePlaySubscriptionParams{
+		ID:          sub.ID,
+		LastEventAt: timeToPgTimestamptz(revokedAt),
+	})
+	if err != nil {
+		return model.GooglePlaySubscription{}, fmt.Errorf("google play dao: revoke subscription: %w", err)
+	}
+	revoked := mapGooglePlaySubscriptionRow(row)
+	before := googlePlayEntitlementChange(sub)
+	if err := recordEntitlementChange(ctx, s.queries, &before, googlePlayEntitlementChange(revoked)); err != nil {
+		return model.GooglePlaySubscription{}, err
+	}
+	return revoked, nil
+}
+
+// InsertPurchaseIfNotExists 幂等写入一次性购买，语义与 Apple 的同名方法一致：
+// created==true 是“恰好一次”入账的唯一闸门；现有行属于其他 user 时返回 ErrGooglePlayOwnershipConflict。
+func (s *googlePlayTxQueries) InsertPurchaseIfNotExists(ctx context.Context, in model.GooglePlayPurchaseInsert) (model.GooglePlayPurchase, bool, error) {
+	if in.UserID <= 0 {
+		return model.GooglePlayPurchase{}, false, fmt.Errorf("google play dao: invalid user id %d", in.UserID)
+	}
+	if in.PurchaseToken == "" {
+		return model.GooglePlayPurchase{}, false, errors.New("google play dao: purchase token required")
+	}
+	row, err := s.queries.InsertGooglePlayPurchaseIfNotExists(ctx, db.InsertGooglePlayPurchaseIfNotExistsParams{
+		UserID:            in.UserID,
+		PackageName:       in.PackageName,
+		PurchaseToken:     in.PurchaseToken,
+		OrderID:           in.OrderID,
+		PlanID:            in.PlanID,
+		ProviderProductID: in.ProviderProductID,
+		ProductType:       in.ProductType,
+		Quantity:          int32(max(in.Quantity, 1)),
+		Level:             int32(in.Level),
+		Credits:           in.Credits,
+		TestPurchase:      in.TestPurchase,
+		PurchasedAt:       timeToPgTimestamptz(in.PurchasedAt),
+	})
+	if err == nil {
+		return mapGooglePlayPurchaseRow(row), true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return model.GooglePlayPurchase{}, false, fmt.Errorf("google play dao: insert purchase: %w", err)
+	}
+	existing, err := s.GetPurchaseByToken(ctx, in.PurchaseToken)
+	if err != nil {
+		return model.GooglePlayPurchase{}, false, err
+	}
+	if existing.UserID != in.UserID {
+		return model.GooglePlayPurchase{}, false, ErrGooglePlayOwnershipConflict
+	}
+	return existing, false, nil
+}
+
+// GetPurchaseByToken 在事务内按 purchase_token 读取一次性购买并加行锁；未命中返回 ErrGooglePlayPurchaseNotFound。
+func (s *googlePlayTxQueries) GetPurchaseByToken(ctx context.Context, purchaseToken string) (model.GooglePlayPurchase, error) {
+	row, err := s.queries.LockGooglePlayPurchaseByToken(ctx, purchaseToken)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.GooglePlayPurchase{}, ErrGooglePlayPurchaseNotFound
+		}
+		return model.GooglePlayPurchase{}, fmt.Errorf("google play dao: lock purchase: %w", err)
+	}
+	return mapGooglePlayPurchaseRow(row), nil
+}
+
+// GrantPurchaseCredits 为一次性购买发放积分并把 bucket 关联回购买记录，供作废时收回。
+func (s *googlePlayTxQueries) GrantPurchaseCredits(ctx context.Context, purchaseID int64, in model.CreditGrant) (model.CreditBucket, error) {
+	bucket, err := grantCreditsTx(ctx, s.queries, in)
+	if err != nil {
+		return model.CreditBucket{}, err
+	}
+	if err := s.queries.SetGooglePlayPurchaseCreditBucket(ctx, db.SetGooglePlayPurchaseCreditBucketParams{
+		ID:             purchaseID,
+		CreditBucketID: pgtype.Int8{Int64: bucket.ID, Valid: true},
+	}); err != nil {
+		return model.CreditBucket{}, fmt.Errorf("google play dao: link credit bucket: %w", err)
+	}
+	return bucket, nil
+}
+
+// RefundPurchase 把购买标记为 REFUNDED，并收回关联 bucket 的剩余积分。重复退款是 no-op，返回当前行。
+func (s *googlePlayTxQueries) RefundPurchase(ctx context.Context, p model.GooglePlayPurchase, revokedAt time.Time) (model.GooglePlayPurchase, error) {
+	row, err := s.queries.MarkGooglePlayPurchaseRefunded(ctx, db.MarkGooglePlayPurchaseRefundedParams{
+		ID:        p.ID,
+		RevokedAt: timeToPgTimestamptz(revokedAt),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return s.GetPurchaseByToken(ctx, p.PurchaseToken)
+		}
+		return model.GooglePlayPurchase{}, fmt.Errorf("google play dao: refund purchase: %w", err)
+	}
+	if row.CreditBucketID.Valid {
+		if _, err := revokeCreditBucketTx(ctx, s.queries, row.CreditBucketID.Int64, "google-play-refund:"+row.OrderID); err != nil {
+			return model.GooglePlayPurchase{}, err
+		}
+	}
+	return mapGooglePlayPurchaseRow(row), nil
+}
+
+func mapGooglePlaySubscriptionRow(row db.GooglePlaySubscription) model.GooglePlaySubscription {
+	return model.GooglePlaySubscription{
+		ID:                  row.ID,
+		UserID:              row.UserID,
+		PackageName:         row.PackageName,
+		PurchaseToken:       row.PurchaseToken,
+		LinkedPurchaseToken: row.LinkedPurchaseToken,
+		LatestOrderID:       row.LatestOrderID,
+		PlanID:              row.PlanID,
+		ProviderProductID:   row.ProviderProductID,
+		Level:               int(row.Level),
+		Status:              row.Status,
+		AutoRenewStatus:     row.AutoRenewStatus,
+		TestPurchase:        row.TestPurchase,
+		CurrentPeriodStart:  row.CurrentPeriodStart.Time,
+		CurrentPeriodEnd:    row.CurrentPeriodEnd.Time,
+		LastEventAt:         row.LastEventAt.Time,
+		CreatedAt:           row.CreatedAt.Time,
+		UpdatedAt:           row.UpdatedAt.Time,
+	}
+}
+
+func mapGooglePlayPurchaseRow(row db.GooglePlayPurchase) model.GooglePlayPurchase {
+	out := model.GooglePlayPurchase{
+		ID:                row.ID,
+		UserID:            row.UserID,
+		PackageName:       row.PackageName,
+		PurchaseToken:     row.PurchaseToken,
+		OrderID:           row.OrderID,
+		PlanID:            row.PlanID,
+		ProviderProductID: row.ProviderProductID,
+		ProductType:       row.ProductType,
+		Quantity:          int(row.Quantity),
+		Level:             int(row.Level),
+		Credits:           row.Credits,
+		Status:            row.Status,
+		TestPurchase:      row.TestPurchase,
+		PurchasedAt:       row.PurchasedAt.Time,
+		CreatedAt:         row.CreatedAt.Time,
+		UpdatedAt:         row.UpdatedAt.Time,
+	}
+	if row.CreditBucketID.Valid {
+		out.CreditBucketID = row.CreditBucketID.Int64
+	}
+	if row.RevokedAt.Valid {
+		t := row.RevokedAt.Time
+		out.RevokedAt = &t
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_GooglePlayDAO_SubscriptionOwnershipAndEntitlement(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+	otherID, cleanupOther := withTestUser(t, pool)
+	defer cleanupOther()
+
+	d := NewGooglePlayDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	token := "it-gp-sub-" + t.Name()
+
+	in := model.GooglePlaySubscriptionUpsert{
+		UserID:             userID,
+		PackageName:        "com.app.example",
+		PurchaseToken:      token,
+		LatestOrderID:      "GPA.it-1",
+		PlanID:             "pro_monthly",
+		ProviderProductID:  "pro.monthly",
+		Level:              1,
+		Status:             model.SubscriptionStatusActive,
+		AutoRenewStatus:    model.AutoRenewStatusOn,
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   now.Add(30 * 24 * time.Hour),
+		LastEventAt:        now,
+	}
+	var sub model.GooglePlaySubscription
+	if err := d.InTx(ctx, func(tx GooglePlayTx) error {
+		created, err := tx.InsertEventIfNotExists(ctx, model.GooglePlayEventInsert{
+			MessageID: "it-msg-" + t.Name(), NotificationKind: model.GooglePlayNotificationSubscription,
+			PurchaseToken: token, ProcessingStatus: model.EventStatusProcessed, UserID: userID,
+		})
+		if err != nil || !created {
+			t.Fatalf("insert event: created=%v err=%v", created, err)
+		}
+		sub, err = tx.UpsertSubscriptionWithOwnershipCheck(ctx, in)
+		return err
+	}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	other := in
+	other.UserID = otherID
+	if err := d.InTx(ctx, func(tx GooglePlayTx) error {
+		_, err := tx.UpsertSubscriptionWithOwnershipCheck(ctx, other)
+		return err
+	}); !errors.Is(err, ErrGooglePlayOwnershipConflict) {
+		t.Fatalf("other user err = %v, want ErrGooglePlayOwnershipConflict", err)
+	}
+
+	rows, err := d.ListSubscriptionsForUserEntitlement(ctx, userID)
+	if err != nil || len(rows) != 1 || rows[0].ID != sub.ID {
+		t.Fatalf("entitlement rows = %+v, err = %v", rows, err)
+	}
+
+	if err := d.InTx(ctx, func(tx GooglePlayTx) error {
+		_, err := tx.RevokeSubscription(ctx, sub, now)
+		return err
+	}); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if err := d.InTx(ctx, func(tx GooglePlayTx) error {
+		got, err := tx.GetSubscriptionByToken(ctx, token)
+		if err != nil {
+			return err
+		}
+		if got.Status != model.SubscriptionStatusRevoked {
+			t.Fatalf("status = %q, want REVOKED", got.Status)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// PaywallExperimentDAO 记录付费墙实验曝光并统计转化。
+type PaywallExperimentDAO interface {
+	// RecordExposure 记录一次曝光；用户已在该实验中时保留原分组，只刷新 last_exposed_at。返回持久化的分组。
+	RecordExposure(ctx context.Context, in model.PaywallExposureInsert) (model.PaywallExposure, error)
+	// ListVariantStats 按 variant_id 升序返回实验各分组的曝光人数与转化人数；只有 allowedEnvs 内的订阅计为转化。
+	ListVariantStats(ctx context.Context, experimentID string, allowedEnvs []model.AppleEnvironment) ([]model.PaywallVariantStats, error)
+}
+
+type paywallExperimentDAO struct {
+	queries *db.Queries
+}
+
+// NewPaywallExperimentDAO 构造一个面向 PostgreSQL 的 PaywallExperimentDAO。
+func NewPaywallExperimentDAO(pool *pgxpool.Pool) PaywallExperimentDAO {
+	return &paywallExperimentDAO{queries: db.New(pool)}
+}
+
+func (d *paywallExperimentDAO) RecordExposure(ctx context.Context, in model.PaywallExposureInsert) (model.PaywallExposure, error) {
+	row, err := d.queries.UpsertPaywallExposure(ctx, db.UpsertPaywallExposureParams{
+		ExperimentID:   in.ExperimentID,
+		VariantID:      in.VariantID,
+		UserID:         in.UserID,
+		Platform:       in.Platform,
+		FirstExposedAt: timeToPgTimestamptz(in.ExposedAt),
+	})
+	if err != nil {
+		return model.PaywallExposure{}, fmt.Errorf("paywall experiment dao: record exposure: %w", err)
+	}
+	return model.PaywallExposure{
+		ID:             row.ID,
+		ExperimentID:   row.ExperimentID,
+		VariantID:      row.VariantID,
+		UserID:         row.UserID,
+		Platform:       row.Platform,
+		FirstExposedAt: row.FirstExposedAt.Time,
+		LastExposedAt:  row.LastExposedAt.Time,
+	}, nil
+}
+
+// ListVariantStats 中的转化以 apple_subscriptions.created_at 不早于首次曝光为准，每个用户最多计一次。
+func (d *paywallExperimentDAO) ListVariantStats(ctx context.Context, experimentID string, allowedEnvs []model.AppleEnvironment) ([]model.PaywallVariantStats, error) {
+	envs := make([]string, 0, len(allowedEnvs))
+	for _, e := range allowedEnvs {
+		envs = append(envs, string(e))
+	}
+	rows, err := d.queries.ListPaywallExperimentResults(ctx, db.ListPaywallExperimentResultsParams{
+		Environments: envs,
+		ExperimentID: experimentID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("paywall experiment dao: list results: %w", err)
+	}
+	out := make([]model.PaywallVariantStats, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, model.PaywallVariantStats{
+			VariantID:   row.VariantID,
+			Exposures:   row.Exposures,
+			Conversions: row.Conversions,
+		})
+	}
+	return out, nil
+}
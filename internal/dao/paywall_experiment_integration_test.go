@@ -0,0 +1,88 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_PaywallExperimentDAO_StickyExposureAndConversion(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	converted, cleanupA := withTestUser(t, pool)
+	defer cleanupA()
+	browsed, cleanupB := withTestUser(t, pool)
+	defer cleanupB()
+
+	d := NewPaywallExperimentDAO(pool)
+	subs := NewSubscriptionDAO(pool)
+	ctx := context.Background()
+	experimentID := "it-" + t.Name()
+	exposedAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	first, err := d.RecordExposure(ctx, model.PaywallExposureInsert{
+		ExperimentID: experimentID, VariantID: "annual_first", UserID: converted, Platform: "ios", ExposedAt: exposedAt,
+	})
+	if err != nil || first.VariantID != "annual_first" || !first.FirstExposedAt.Equal(exposedAt) {
+		t.Fatalf("first exposure: %+v %v", first, err)
+	}
+	// 再次曝光保留原分组，只刷新 last_exposed_at。
+	again, err := d.RecordExposure(ctx, model.PaywallExposureInsert{
+		ExperimentID: experimentID, VariantID: "control", UserID: converted, Platform: "ios", ExposedAt: exposedAt.Add(time.Minute),
+	})
+	if err != nil || again.ID != first.ID || again.VariantID != "annual_first" || !again.FirstExposedAt.Equal(exposedAt) || !again.LastExposedAt.After(exposedAt) {
+		t.Fatalf("repeat exposure must be sticky: %+v %v", again, err)
+	}
+	if _, err := d.RecordExposure(ctx, model.PaywallExposureInsert{
+		ExperimentID: experimentID, VariantID: "control", UserID: browsed, Platform: "ios", ExposedAt: exposedAt,
+	}); err != nil {
+		t.Fatalf("second user exposure: %v", err)
+	}
+
+	token, _, err := subs.GetOrCreateAccountToken(ctx, converted)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	now := time.Now().UTC()
+	if err := subs.InTx(ctx, func(qtx SubscriptionTx) error {
+		_, err := qtx.UpsertSubscriptionWithOwnershipCheck(ctx, model.SubscriptionUpsert{
+			UserID:                converted,
+			AppAccountToken:       token,
+			Environment:           model.AppleEnvSandbox,
+			OriginalTransactionID: "it-paywall-" + t.Name(),
+			LastTransactionID:     "it-paywall-tx-" + t.Name(),
+			PlanID:                "pro_yearly",
+			ProviderProductID:     "com.app.pro.yearly",
+			Level:                 1,
+			Status:                model.SubscriptionStatusActive,
+			AutoRenewStatus:       model.AutoRenewStatusOn,
+			CurrentPeriodStart:    now,
+			CurrentPeriodEnd:      now.Add(365 * 24 * time.Hour),
+			LastEventAt:           now,
+		})
+		return err
+	}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	// 订阅在 Sandbox：只统计 Production 时不计为转化。
+	prodOnly, err := d.ListVariantStats(ctx, experimentID, []model.AppleEnvironment{model.AppleEnvProduction})
+	if err != nil || len(prodOnly) != 2 || prodOnly[0].Conversions != 0 {
+		t.Fatalf("production-only stats = %+v %v", prodOnly, err)
+	}
+	stats, err := d.ListVariantStats(ctx, experimentID, []model.AppleEnvironment{model.AppleEnvSandbox})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	want := []model.PaywallVariantStats{
+		{VariantID: "annual_first", Exposures: 1, Conversions: 1},
+		{VariantID: "control", Exposures: 1, Conversions: 0},
+	}
+	if len(stats) != len(want) || stats[0] != want[0] || stats[1] != want[1] {
+		t.Fatalf("stats = %+v, want %+v", stats, want)
+	}
+}
@@ -0,0 +1,177 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+var (
+	// ErrPromoCodeNotFound 表示兑换码不存在。
+	ErrPromoCodeNotFound = errors.New("dao: promo code not found")
+	// ErrPromoCodeConflict 表示创建的兑换码已存在。
+	ErrPromoCodeConflict = errors.New("dao: promo code already exists")
+	// ErrPromoCodeAlreadyRedeemed 表示该用户已经兑换过这个兑换码（每人每码只能兑换一次）。
+	ErrPromoCodeAlreadyRedeemed = errors.New("dao: promo code already redeemed")
+)
+
+// PromoCodeDAO 暴露兑换码的后台管理与兑换事务。兑换记录、计数与赠送权益在 InTx 内同事务提交。
+type PromoCodeDAO interface {
+	CreateCode(ctx context.Context, in model.PromoCodeInput) (model.PromoCode, error)
+	ListCodes(ctx context.Context) ([]model.PromoCode, error)
+	DeactivateCode(ctx context.Context, code string) (model.PromoCode, error)
+
+	InTx(ctx context.Context, fn func(PromoCodeTx) error) error
+}
+
+// PromoCodeTx 暴露事务作用域的兑换操作。仅在 PromoCodeDAO.InTx 回调里使用。
+type PromoCodeTx interface {
+	// LockCode 锁住兑换码所在行，使同一兑换码的兑换串行化，兑换上限计数准确。
+	LockCode(ctx context.Context, code string) (model.PromoCode, error)
+	GrantComp(ctx context.Context, in model.CompGrant, now time.Time) (model.CompEntitlement, error)
+	// InsertRedemption 写入兑换记录并累加兑换次数；该用户已兑换过时返回 ErrPromoCodeAlreadyRedeemed。
+	InsertRedemption(ctx context.Context, code string, userID, compID int64) error
+}
+
+type promoCodeDAO struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewPromoCodeDAO 构造一个面向 PostgreSQL 的 PromoCodeDAO。
+func NewPromoCodeDAO(pool *pgxpool.Pool) PromoCodeDAO {
+	return &promoCodeDAO{
+		pool:    pool,
+		queries: db.New(pool),
+	}
+}
+
+// CreateCode 新增兑换码；code 已存在时返回 ErrPromoCodeConflict。
+func (d *promoCodeDAO) CreateCode(ctx context.Context, in model.PromoCodeInput) (model.PromoCode, error) {
+	var expiresAt pgtype.Timestamptz
+	if in.ExpiresAt != nil {
+		expiresAt = timeToPgTimestamptz(*in.ExpiresAt)
+	}
+	row, err := d.queries.InsertPromoCode(ctx, db.InsertPromoCodeParams{
+		Code:           in.Code,
+		PlanID:         in.PlanID,
+		Level:          int32(in.Level),
+		CompDays:       int32(in.CompDays),
+		MaxRedemptions: int32(in.MaxRedemptions),
+		ExpiresAt:      expiresAt,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.PromoCode{}, ErrPromoCodeConflict
+		}
+		return model.PromoCode{}, fmt.Errorf("promo code dao: insert: %w", err)
+	}
+	return mapPromoCodeRow(row), nil
+}
+
+// ListCodes 返回全部兑换码（含已停用的），按创建时间倒序。
+func (d *promoCodeDAO) ListCodes(ctx context.Context) ([]model.PromoCode, error) {
+	rows, err := d.queries.ListPromoCodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("promo code dao: list: %w", err)
+	}
+	out := make([]model.PromoCode, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapPromoCodeRow(r))
+	}
+	return out, nil
+}
+
+// DeactivateCode 停用兑换码；已发放的赠送权益不受影响。
+func (d *promoCodeDAO) DeactivateCode(ctx context.Context, code string) (model.PromoCode, error) {
+	row, err := d.queries.DeactivatePromoCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.PromoCode{}, ErrPromoCodeNotFound
+		}
+		return model.PromoCode{}, fmt.Errorf("promo code dao: deactivate: %w", err)
+	}
+	return mapPromoCodeRow(row), nil
+}
+
+// InTx 在数据库事务内执行 fn，提交或回滚由 fn 的返回值驱动。
+func (d *promoCodeDAO) InTx(ctx context.Context, fn func(PromoCodeTx) error) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("promo code dao: begin tx: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err := fn(&promoCodeTxQueries{queries: d.queries.WithTx(tx)}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("promo code dao: commit: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+type promoCodeTxQueries struct {
+	queries *db.Queries
+}
+
+func (r *promoCodeTxQueries) LockCode(ctx context.Context, code string) (model.PromoCode, error) {
+	row, err := r.queries.LockPromoCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.PromoCode{}, ErrPromoCodeNotFound
+		}
+		return model.PromoCode{}, fmt.Errorf("promo code dao: lock code: %w", err)
+	}
+	return mapPromoCodeRow(row), nil
+}
+
+func (r *promoCodeTxQueries) GrantComp(ctx context.Context, in model.CompGrant, now time.Time) (model.CompEntitlement, error) {
+	return grantCompTx(ctx, r.queries, in, now)
+}
+
+func (r *promoCodeTxQueries) InsertRedemption(ctx context.Context, code string, userID, compID int64) error {
+	if _, err := r.queries.InsertPromoCodeRedemption(ctx, db.InsertPromoCodeRedemptionParams{
+		Code:              code,
+		UserID:            userID,
+		CompEntitlementID: compID,
+	}); err != nil {
+		if isUniqueViolation(err) {
+			return ErrPromoCodeAlreadyRedeemed
+		}
+		return fmt.Errorf("promo code dao: insert redemption: %w", err)
+	}
+	if err := r.queries.IncrementPromoCodeRedemptions(ctx, code); err != nil {
+		return fmt.Errorf("promo code dao: increment redemptions: %w", err)
+	}
+	return nil
+}
+
+func mapPromoCodeRow(row db.PromoCode) model.PromoCode {
+	return model.PromoCode{
+		Code:           row.Code,
+		PlanID:         row.PlanID,
+		Level:          int(row.Level),
+		CompDays:       int(row.CompDays),
+		MaxRedemptions: int(row.MaxRedemptions),
+		RedeemedCount:  int(row.RedeemedCount),
+		ExpiresAt:      pgTimestamptzPtr(row.ExpiresAt),
+		Active:         row.Active,
+		CreatedAt:      row.CreatedAt.Time,
+		UpdatedAt:      row.UpdatedAt.Time,
+	}
+}
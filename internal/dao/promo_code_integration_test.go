@@ -0,0 +1,132 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_PromoCodeDAO_RedeemOncePerUser(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanupUser := withTestUser(t, pool)
+	defer cleanupUser()
+
+	d := NewPromoCodeDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	code := fmt.Sprintf("IT%d", userID)
+	defer func() {
+		_, _ = pool.Exec(ctx, `DELETE FROM promo_code_redemptions WHERE code = $1`, code)
+		_, _ = pool.Exec(ctx, `DELETE FROM promo_codes WHERE code = $1`, code)
+	}()
+
+	created, err := d.CreateCode(ctx, model.PromoCodeInput{Code: code, PlanID: "pro", Level: 2, CompDays: 14, MaxRedemptions: 10})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !created.Active || created.RedeemedCount != 0 || created.ExpiresAt != nil {
+		t.Fatalf("unexpected code: %+v", created)
+	}
+	if _, err := d.CreateCode(ctx, model.PromoCodeInput{Code: code, PlanID: "pro", Level: 1, CompDays: 1}); !errors.Is(err, ErrPromoCodeConflict) {
+		t.Fatalf("duplicate err = %v, want ErrPromoCodeConflict", err)
+	}
+
+	redeem := func() error {
+		return d.InTx(ctx, func(tx PromoCodeTx) error {
+			promo, err := tx.LockCode(ctx, code)
+			if err != nil {
+				return err
+			}
+			comp, err := tx.GrantComp(ctx, model.CompGrant{UserID: userID, PlanID: promo.PlanID, Level: promo.Level, Source: model.CompSourcePromoCode, Reference: "promo:" + code, Duration: 14 * 24 * time.Hour}, now)
+			if err != nil {
+				return err
+			}
+			return tx.InsertRedemption(ctx, code, userID, comp.ID)
+		})
+	}
+	if err := redeem(); err != nil {
+		t.Fatalf("redeem: %v", err)
+	}
+	if err := redeem(); !errors.Is(err, ErrPromoCodeAlreadyRedeemed) {
+		t.Fatalf("second redeem err = %v, want ErrPromoCodeAlreadyRedeemed", err)
+	}
+
+	comps, err := NewCompEntitlementDAO(pool).ListCompEntitlements(ctx, userID)
+	if err != nil {
+		t.Fatalf("list comps: %v", err)
+	}
+	if len(comps) != 1 || comps[0].Source != model.CompSourcePromoCode || !comps[0].EndsAt.Equal(now.Add(14*24*time.Hour)) {
+		t.Fatalf("rolled back redemption must not leave a comp: %+v", comps)
+	}
+
+	deactivated, err := d.DeactivateCode(ctx, code)
+	if err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	if deactivated.Active || deactivated.RedeemedCount != 1 {
+		t.Fatalf("unexpected deactivated code: %+v", deactivated)
+	}
+	if _, err := d.DeactivateCode(ctx, code+"-missing"); !errors.Is(err, ErrPromoCodeNotFound) {
+		t.Fatalf("missing code err = %v, want ErrPromoCodeNotFound", err)
+	}
+}
+
+func TestIntegration_CompEntitlementDAO_AdminGrantAndRevoke(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanupUser := withTestUser(t, pool)
+	defer cleanupUser()
+
+	d := NewCompEntitlementDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	grant := model.CompGrant{UserID: userID, PlanID: "pro", Level: 1, Source: model.CompSourceAdmin, Reference: "admin:1", Note: "outage", Duration: 24 * time.Hour}
+
+	first, err := d.GrantComp(ctx, grant, now)
+	if err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	second, err := d.GrantComp(ctx, grant, now)
+	if err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	if first.Note != "outage" || !second.StartsAt.Equal(first.EndsAt) {
+		t.Fatalf("second comp must queue after the first: %+v / %+v", first, second)
+	}
+
+	revoked, err := d.RevokeComp(ctx, userID, second.ID, now)
+	if err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if revoked.RevokedAt == nil {
+		t.Fatalf("revoked comp missing revoked_at: %+v", revoked)
+	}
+	if _, err := d.RevokeComp(ctx, userID, second.ID, now); !errors.Is(err, ErrCompEntitlementNotFound) {
+		t.Fatalf("second revoke err = %v, want ErrCompEntitlementNotFound", err)
+	}
+	active, err := d.ListUnexpiredCompEntitlements(ctx, userID, now)
+	if err != nil {
+		t.Fatalf("list unexpired: %v", err)
+	}
+	if len(active) != 1 || active[0].ID != first.ID {
+		t.Fatalf("revoked comp must not count: %+v", active)
+	}
+	third, err := d.GrantComp(ctx, grant, now)
+	if err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	if !third.StartsAt.Equal(first.EndsAt) {
+		t.Fatalf("revoked comp must not extend the queue: %+v", third)
+	}
+
+	if _, err := d.GrantComp(ctx, model.CompGrant{UserID: userID + 1_000_000_000, PlanID: "pro", Level: 1, Source: model.CompSourceAdmin, Duration: time.Hour}, now); !errors.Is(err, ErrCompUserNotFound) {
+		t.Fatalf("missing user err = %v, want ErrCompUserNotFound", err)
+	}
+}
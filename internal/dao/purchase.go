@@ -0,0 +1,166 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// ErrPurchaseNotFound 表示按 (transaction_id, environment) 查找一次性购买时未命中。
+var ErrPurchaseNotFound = errors.New("dao: apple purchase not found")
+
+// ErrPurchaseOwnershipConflict 表示同一 transaction 已经记在其他 user 名下。
+var ErrPurchaseOwnershipConflict = errors.New("dao: apple purchase owned by another user")
+
+// ListActiveLifetimePurchases 返回 user 在 allowedEnvs 内未退款的非消耗型购买，按 level DESC 排序。
+func (d *subscriptionDAO) ListActiveLifetimePurchases(ctx context.Context, userID int64, allowedEnvs []model.AppleEnvironment) ([]model.Purchase, error) {
+	if userID <= 0 {
+		return nil, fmt.Errorf("subscription dao: invalid user id %d", userID)
+	}
+	envs := make([]string, 0, len(allowedEnvs))
+	for _, e := range allowedEnvs {
+		envs = append(envs, string(e))
+	}
+	rows, err := d.queries.ListActiveLifetimePurchasesByUser(ctx, db.ListActiveLifetimePurchasesByUserParams{
+		UserID:  userID,
+		Column2: envs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("subscription dao: list lifetime purchases: %w", err)
+	}
+	out := make([]model.Purchase, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapPurchaseRow(r))
+	}
+	return out, nil
+}
+
+// InsertPurchaseIfNotExists 幂等写入一次性购买。
+//
+// 返回 created==true 表示本次调用首次写入，调用方据此决定是否发放积分；这是“恰好一次”入账的唯一闸门。
+// 已存在时返回加锁后的现有行；现有行属于其他 user 时返回 ErrPurchaseOwnershipConflict。
+func (s *subscriptionTxQueries) InsertPurchaseIfNotExists(ctx context.Context, in model.PurchaseInsert) (model.Purchase, bool, error) {
+	if in.UserID <= 0 {
+		return model.Purchase{}, false, fmt.Errorf("subscription dao: invalid user id %d", in.UserID)
+	}
+	if in.TransactionID == "" || in.Environment == "" {
+		return model.Purchase{}, false, errors.New("subscription dao: transaction id and environment required")
+	}
+	row, err := s.queries.InsertApplePurchaseIfNotExists(ctx, db.InsertApplePurchaseIfNotExistsParams{
+		UserID:                in.UserID,
+		Environment:           string(in.Environment),
+		TransactionID:         in.TransactionID,
+		OriginalTransactionID: in.OriginalTransactionID,
+		PlanID:                in.PlanID,
+		ProviderProductID:     in.ProviderProductID,
+		ProductType:           in.ProductType,
+		Quantity:              int32(max(in.Quantity, 1)),
+		Level:                 int32(in.Level),
+		Credits:               in.Credits,
+		PurchasedAt:           timeToPgTimestamptz(in.PurchasedAt),
+	})
+	if err == nil {
+		return mapPurchaseRow(row), true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return model.Purchase{}, false, fmt.Errorf("subscription dao: insert purchase: %w", err)
+	}
+	existing, err := s.lockPurchase(ctx, in.TransactionID, in.Environment)
+	if err != nil {
+		return model.Purchase{}, false, err
+	}
+	if existing.UserID != in.UserID {
+		return model.Purchase{}, false, ErrPurchaseOwnershipConflict
+	}
+	return existing, false, nil
+}
+
+// GetPurchaseByTransaction 在事务内按 natural key 读取一次性购买并加行锁；未命中返回 ErrPurchaseNotFound。
+func (s *subscriptionTxQueries) GetPurchaseByTransaction(ctx context.Context, transactionID string, env model.AppleEnvironment) (model.Purchase, error) {
+	return s.lockPurchase(ctx, transactionID, env)
+}
+
+// GrantPurchaseCredits 为一次性购买发放积分并把 bucket 关联回购买记录，供退款时收回。
+func (s *subscriptionTxQueries) GrantPurchaseCredits(ctx context.Context, purchaseID int64, in model.CreditGrant) (model.CreditBucket, error) {
+	bucket, err := grantCreditsTx(ctx, s.queries, in)
+	if err != nil {
+		return model.CreditBucket{}, err
+	}
+	if err := s.queries.SetApplePurchaseCreditBucket(ctx, db.SetApplePurchaseCreditBucketParams{
+		ID:             purchaseID,
+		CreditBucketID: pgtype.Int8{Int64: bucket.ID, Valid: true},
+	}); err != nil {
+		return model.CreditBucket{}, fmt.Errorf("subscription dao: link credit bucket: %w", err)
+	}
+	return bucket, nil
+}
+
+// RefundPurchase 把购买标记为 REFUNDED，并收回关联 bucket 的剩余积分。重复退款是 no-op，返回当前行。
+// 一次性购买（含买断）不是订阅行，有意不产生权益变更事件：事件快照以订阅行为单位，下游需以 /users/me 为准。
+func (s *subscriptionTxQueries) RefundPurchase(ctx context.Context, p model.Purchase, revokedAt time.Time) (model.Purchase, error) {
+	row, err := s.queries.MarkApplePurchaseRefunded(ctx, db.MarkApplePurchaseRefundedParams{
+		ID:        p.ID,
+		RevokedAt: timeToPgTimestamptz(revokedAt),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return s.lockPurchase(ctx, p.TransactionID, p.Environment)
+		}
+		return model.Purchase{}, fmt.Errorf("subscription dao: refund purchase: %w", err)
+	}
+	if row.CreditBucketID.Valid {
+		if _, err := revokeCreditBucketTx(ctx, s.queries, row.CreditBucketID.Int64, "apple-refund:"+row.TransactionID); err != nil {
+			return model.Purchase{}, err
+		}
+	}
+	return mapPurchaseRow(row), nil
+}
+
+func (s *subscriptionTxQueries) lockPurchase(ctx context.Context, transactionID string, env model.AppleEnvironment) (model.Purchase, error) {
+	row, err := s.queries.LockApplePurchaseByTransaction(ctx, db.LockApplePurchaseByTransactionParams{
+		TransactionID: transactionID,
+		Environment:   string(env),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Purchase{}, ErrPurchaseNotFound
+		}
+		return model.Purchase{}, fmt.Errorf("subscription dao: lock purchase: %w", err)
+	}
+	return mapPurchaseRow(row), nil
+}
+
+func mapPurchaseRow(row db.ApplePurchase) model.Purchase {
+	out := model.Purchase{
+		ID:                    row.ID,
+		UserID:                row.UserID,
+		Environment:           model.AppleEnvironment(row.Environment),
+		TransactionID:         row.TransactionID,
+		OriginalTransactionID: row.OriginalTransactionID,
+		PlanID:                row.PlanID,
+		ProviderProductID:     row.ProviderProductID,
+		ProductType:           row.ProductType,
+		Quantity:              int(row.Quantity),
+		Level:                 int(row.Level),
+		Credits:               row.Credits,
+		Status:                row.Status,
+		PurchasedAt:           row.PurchasedAt.Time,
+		CreatedAt:             row.CreatedAt.Time,
+		UpdatedAt:             row.UpdatedAt.Time,
+	}
+	if row.CreditBucketID.Valid {
+		out.CreditBucketID = row.CreditBucketID.Int64
+	}
+	if row.RevokedAt.Valid {
+		t := row.RevokedAt.Time
+		out.RevokedAt = &t
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// PurchaseHistoryDAO 读取用户的 Apple 购买时间线，数据来自 apple_events 中已处理的通知，
+// 以及没有对应通知的 apple_purchases / apple_subscriptions 行。
+type PurchaseHistoryDAO interface {
+	ListPurchaseTimeline(ctx context.Context, in model.PurchaseTimelineQuery) ([]model.PurchaseTimelineEvent, error)
+}
+
+type purchaseHistoryDAO struct {
+	queries *db.Queries
+}
+
+// NewPurchaseHistoryDAO 构造一个面向 PostgreSQL 的 PurchaseHistoryDAO。
+func NewPurchaseHistoryDAO(pool *pgxpool.Pool) PurchaseHistoryDAO {
+	return &purchaseHistoryDAO{queries: db.New(pool)}
+}
+
+// ListPurchaseTimeline 按 (发生时间, 来源, id) 倒序返回至多 Limit 条记录；BeforeAt 为零值时从最新一条开始。
+func (d *purchaseHistoryDAO) ListPurchaseTimeline(ctx context.Context, in model.PurchaseTimelineQuery) ([]model.PurchaseTimelineEvent, error) {
+	if in.Limit <= 0 {
+		return nil, fmt.Errorf("purchase history dao: invalid limit %d", in.Limit)
+	}
+	envs := make([]string, 0, len(in.Environments))
+	for _, e := range in.Environments {
+		envs = append(envs, string(e))
+	}
+	before := pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true}
+	beforeSource, beforeID := "", int64(math.MaxInt64)
+	if !in.BeforeAt.IsZero() {
+		before = timeToPgTimestamptz(in.BeforeAt)
+		beforeSource, beforeID = in.BeforeSource, in.BeforeID
+	}
+	rows, err := d.queries.ListApplePurchaseTimelineByUser(ctx, db.ListApplePurchaseTimelineByUserParams{
+		UserID:            in.UserID,
+		Environments:      envs,
+		NotificationTypes: in.NotificationTypes,
+		BeforeAt:          before,
+		BeforeSource:      beforeSource,
+		BeforeID:          beforeID,
+		BatchSize:         int32(in.Limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("purchase history dao: list timeline: %w", err)
+	}
+	out := make([]model.PurchaseTimelineEvent, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, model.PurchaseTimelineEvent{
+			Source:           r.Source,
+			ID:               r.ID,
+			NotificationType: r.NotificationType,
+			Subtype:          r.Subtype,
+			Environment:      model.AppleEnvironment(r.Environment),
+			OccurredAt:       r.OccurredAt.Time,
+			ProductID:        r.ProductID,
+			RenewalProductID: r.RenewalProductID,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,131 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_PurchaseHistoryDAO_Timeline(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	subs := NewSubscriptionDAO(pool)
+	history := NewPurchaseHistoryDAO(pool)
+	ctx := context.Background()
+	uuidPrefix := "it-history-" + t.Name() + "-"
+	defer func() {
+		_, _ = pool.Exec(ctx, "DELETE FROM apple_events WHERE notification_uuid LIKE $1", uuidPrefix+"%")
+	}()
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	originalTxID, chargedTxID, verifiedTxID := uuidPrefix+"ot", uuidPrefix+"charged", uuidPrefix+"verified"
+	for i, ev := range []struct {
+		uuid, notifType, status, originalTxID, txID string
+	}{
+		{"buy", "SUBSCRIBED", model.EventStatusProcessed, originalTxID, ""},
+		{"renew", "DID_RENEW", model.EventStatusProcessed, originalTxID, ""},
+		{"toggle", "DID_CHANGE_RENEWAL_STATUS", model.EventStatusProcessed, originalTxID, ""},
+		{"pending", "DID_RENEW", model.EventStatusPendingUserBinding, originalTxID, ""},
+		{"refund", "REFUND", model.EventStatusProcessed, originalTxID, ""},
+		{"charge", "ONE_TIME_CHARGE", model.EventStatusProcessed, chargedTxID, chargedTxID},
+	} {
+		at := base.Add(time.Duration(i) * time.Minute)
+		if err := subs.InTx(ctx, func(qtx SubscriptionTx) error {
+			_, _, err := qtx.InsertAppleEventIfNotExists(ctx, model.AppleEventInsert{
+				NotificationUUID:      uuidPrefix + ev.uuid,
+				NotificationType:      ev.notifType,
+				Environment:           model.AppleEnvSandbox,
+				UserID:                userID,
+				OriginalTransactionID: ev.originalTxID,
+				TransactionID:         ev.txID,
+				ProcessingStatus:      ev.status,
+				RawJWSSHA256:          "sha",
+				NotificationCreatedAt: &at,
+				ProductID:             "com.app.pro.monthly",
+			})
+			return err
+		}); err != nil {
+			t.Fatalf("insert %s: %v", ev.uuid, err)
+		}
+	}
+
+	// 有对应已处理通知的购买 / 订阅行只出现一次；只经过 verify 的购买以 PURCHASE 来源补入时间线。
+	token, _, err := subs.GetOrCreateAccountToken(ctx, userID)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	if err := subs.InTx(ctx, func(qtx SubscriptionTx) error {
+		for i, txID := range []string{chargedTxID, verifiedTxID} {
+			if _, _, err := qtx.InsertPurchaseIfNotExists(ctx, model.PurchaseInsert{
+				UserID:            userID,
+				Environment:       model.AppleEnvSandbox,
+				TransactionID:     txID,
+				PlanID:            "credits_100",
+				ProviderProductID: "com.app.credits.100",
+				ProductType:       model.ProductTypeConsumable,
+				Quantity:          1,
+				PurchasedAt:       base.Add(time.Duration(6+i) * time.Minute),
+			}); err != nil {
+				return err
+			}
+		}
+		_, err := qtx.UpsertSubscriptionWithOwnershipCheck(ctx, model.SubscriptionUpsert{
+			UserID:                userID,
+			AppAccountToken:       token,
+			Environment:           model.AppleEnvSandbox,
+			OriginalTransactionID: originalTxID,
+			PlanID:                "pro_monthly",
+			ProviderProductID:     "com.app.pro.monthly",
+			Level:                 1,
+			Status:                model.SubscriptionStatusActive,
+			AutoRenewStatus:       model.AutoRenewStatusOn,
+			CurrentPeriodStart:    base,
+			CurrentPeriodEnd:      base.Add(30 * 24 * time.Hour),
+			LastEventAt:           base,
+		})
+		return err
+	}); err != nil {
+		t.Fatalf("insert purchases: %v", err)
+	}
+
+	query := model.PurchaseTimelineQuery{
+		UserID:            userID,
+		Environments:      []model.AppleEnvironment{model.AppleEnvSandbox},
+		NotificationTypes: []string{"SUBSCRIBED", "ONE_TIME_CHARGE", "DID_RENEW", "REFUND"},
+		Limit:             2,
+	}
+	var pages [][]model.PurchaseTimelineEvent
+	for {
+		page, err := history.ListPurchaseTimeline(ctx, query)
+		if err != nil {
+			t.Fatalf("page %d: %v", len(pages), err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		pages = append(pages, page)
+		last := page[len(page)-1]
+		query.BeforeAt, query.BeforeSource, query.BeforeID = last.OccurredAt, last.Source, last.ID
+	}
+	if len(pages) != 3 || len(pages[2]) != 1 {
+		t.Fatalf("unexpected pages: %+v", pages)
+	}
+	first := pages[0]
+	if first[0].Source != model.PurchaseTimelineSourcePurchase || first[0].NotificationType != "ONE_TIME_CHARGE" || first[0].ProductID != "com.app.credits.100" ||
+		first[1].Source != model.PurchaseTimelineSourceEvent || first[1].NotificationType != "ONE_TIME_CHARGE" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+	if second := pages[1]; second[0].NotificationType != "REFUND" || second[1].NotificationType != "DID_RENEW" || second[0].ProductID != "com.app.pro.monthly" {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+	if third := pages[2]; third[0].Source != model.PurchaseTimelineSourceEvent || third[0].NotificationType != "SUBSCRIBED" {
+		t.Fatalf("unexpected third page: %+v", third)
+	}
+}
@@ -0,0 +1,115 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_PurchaseDAO_InsertOnceAndRefundRevokesCredits(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+	otherID, cleanupOther := withTestUser(t, pool)
+	defer cleanupOther()
+
+	d := NewSubscriptionDAO(pool)
+	credits := NewCreditDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	txID := "it-purchase-" + t.Name()
+
+	in := model.PurchaseInsert{
+		UserID:            userID,
+		Environment:       model.AppleEnvSandbox,
+		TransactionID:     txID,
+		PlanID:            "credits_100",
+		ProviderProductID: "com.app.credits.100",
+		ProductType:       model.ProductTypeConsumable,
+		Quantity:          1,
+		Credits:           100,
+		PurchasedAt:       now,
+	}
+	var first model.Purchase
+	if err := d.InTx(ctx, func(tx SubscriptionTx) error {
+		p, created, err := tx.InsertPurchaseIfNotExists(ctx, in)
+		if err != nil {
+			return err
+		}
+		if !created {
+			t.Fatal("first insert should create")
+		}
+		first = p
+		_, err = tx.GrantPurchaseCredits(ctx, p.ID, model.CreditGrant{UserID: userID, Source: model.CreditSourcePurchase, Amount: 100, Reference: "apple:" + txID})
+		return err
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	if err := d.InTx(ctx, func(tx SubscriptionTx) error {
+		p, created, err := tx.InsertPurchaseIfNotExists(ctx, in)
+		if err != nil {
+			return err
+		}
+		if created || p.ID != first.ID || p.CreditBucketID == 0 {
+			t.Fatalf("replay should return existing row with bucket: created=%v %+v", created, p)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	other := in
+	other.UserID = otherID
+	if err := d.InTx(ctx, func(tx SubscriptionTx) error {
+		_, _, err := tx.InsertPurchaseIfNotExists(ctx, other)
+		return err
+	}); !errors.Is(err, ErrPurchaseOwnershipConflict) {
+		t.Fatalf("other user err = %v, want ErrPurchaseOwnershipConflict", err)
+	}
+
+	if err := credits.InTx(ctx, func(tx CreditTx) error {
+		return tx.SpendCredits(ctx, userID, 30, "job", now)
+	}); err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := d.InTx(ctx, func(tx SubscriptionTx) error {
+			p, err := tx.GetPurchaseByTransaction(ctx, txID, model.AppleEnvSandbox)
+			if err != nil {
+				return err
+			}
+			refunded, err := tx.RefundPurchase(ctx, p, now)
+			if err != nil {
+				return err
+			}
+			if refunded.Status != model.PurchaseStatusRefunded {
+				t.Fatalf("status = %s, want REFUNDED", refunded.Status)
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("refund #%d: %v", i, err)
+		}
+	}
+
+	buckets, err := credits.ListActiveBuckets(ctx, userID, now)
+	if err != nil {
+		t.Fatalf("list buckets: %v", err)
+	}
+	if len(buckets) != 0 {
+		t.Fatalf("refund should revoke the remaining credits, got %+v", buckets)
+	}
+	var revoked int64
+	if err := pool.QueryRow(ctx, "SELECT COALESCE(SUM(delta), 0) FROM credit_ledger WHERE user_id = $1 AND entry_type = 'REVOKE'", userID).Scan(&revoked); err != nil {
+		t.Fatalf("sum revoke: %v", err)
+	}
+	if revoked != -70 {
+		t.Fatalf("revoked delta = %d, want -70 (only the unspent remainder, once)", revoked)
+	}
+}
@@ -0,0 +1,269 @@
+package dao
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+var (
+	// ErrReferralCodeNotFound 表示兑换的邀请码不存在。
+	ErrReferralCodeNotFound = errors.New("dao: referral code not found")
+	// ErrReferralAlreadyRedeemed 表示被邀请人已经兑换过邀请码（每个用户只能兑换一次）。
+	ErrReferralAlreadyRedeemed = errors.New("dao: referral already redeemed")
+	// ErrReferralDeviceUsed 表示该设备 / 游客身份已经参与过一次兑换。
+	ErrReferralDeviceUsed = errors.New("dao: referral device already used")
+)
+
+const (
+	referralRedemptionInviteeConstraint = "referral_redemptions_invitee_key"
+	referralRedemptionDeviceConstraint  = "referral_redemptions_device_key_uidx"
+
+	// 邀请码字母表去掉了容易混淆的 0/O、1/I/L，便于口头或手动输入。
+	referralCodeAlphabet  = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+	referralCodeLength    = 8
+	referralCodeMaxTrials = 5
+)
+
+// ReferralDAO 暴露邀请码与兑换记录的持久化操作。兑换相关的写入都在 InTx 作用域内完成，
+// 兑换记录与双方奖励（积分 bucket、赠送权益）同事务提交。
+type ReferralDAO interface {
+	GetOrCreateCode(ctx context.Context, userID int64) (string, error)
+	GetStats(ctx context.Context, inviterUserID int64) (model.ReferralStats, error)
+	GetRedemptionByInvitee(ctx context.Context, inviteeUserID int64) (model.ReferralRedemption, error)
+
+	InTx(ctx context.Context, fn func(ReferralTx) error) error
+}
+
+// ReferralTx 暴露事务作用域的兑换操作。仅在 ReferralDAO.InTx 回调里使用。
+type ReferralTx interface {
+	// LockCode 锁住邀请码所在行并返回邀请人 user id，使同一邀请人的兑换串行化。
+	LockCode(ctx context.Context, code string) (int64, error)
+	GetUserCreatedAt(ctx context.Context, userID int64) (time.Time, error)
+	HasRedeemed(ctx context.Context, inviteeUserID int64) (bool, error)
+	ListGuestSubjects(ctx context.Context, userID int64) ([]string, error)
+	CountRedemptions(ctx context.Context, inviterUserID int64) (int, error)
+	InsertRedemption(ctx context.Context, in model.ReferralRedemption) (model.ReferralRedemption, error)
+	GrantCredits(ctx context.Context, in model.CreditGrant) (model.CreditBucket, error)
+	GrantComp(ctx context.Context, in model.CompGrant, now time.Time) (model.CompEntitlement, error)
+}
+
+type referralDAO struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewReferralDAO 构造一个面向 PostgreSQL 的 ReferralDAO。
+func NewReferralDAO(pool *pgxpool.Pool) ReferralDAO {
+	return &referralDAO{
+		pool:    pool,
+		queries: db.New(pool),
+	}
+}
+
+// GetOrCreateCode 返回用户的邀请码，首次读取时生成。code 撞车时换一个重试，并发首次读取只会保留一条。
+func (d *referralDAO) GetOrCreateCode(ctx context.Context, userID int64) (string, error) {
+	if userID <= 0 {
+		return "", fmt.Errorf("referral dao: invalid user id %d", userID)
+	}
+	existing, err := d.queries.GetReferralCodeByUser(ctx, userID)
+	if err == nil {
+		return existing.Code, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("referral dao: lookup code: %w", err)
+	}
+
+	for range referralCodeMaxTrials {
+		code, err := newReferralCode()
+		if err != nil {
+			return "", fmt.Errorf("referral dao: generate code: %w", err)
+		}
+		row, err := d.queries.InsertReferralCode(ctx, db.InsertReferralCodeParams{UserID: userID, Code: code})
+		switch {
+		case err == nil:
+			return row.Code, nil
+		case errors.Is(err, pgx.ErrNoRows):
+			// 并发请求已经为该用户生成了 code。
+			existing, err := d.queries.GetReferralCodeByUser(ctx, userID)
+			if err != nil {
+				return "", fmt.Errorf("referral dao: reload code: %w", err)
+			}
+			return existing.Code, nil
+		case isUniqueViolation(err):
+			continue
+		default:
+			return "", fmt.Errorf("referral dao: insert code: %w", err)
+		}
+	}
+	return "", fmt.Errorf("referral dao: no free code after %d attempts", referralCodeMaxTrials)
+}
+
+// GetStats 汇总邀请人已成功邀请的人数与累计获得的奖励。
+func (d *referralDAO) GetStats(ctx context.Context, inviterUserID int64) (model.ReferralStats, error) {
+	row, err := d.queries.GetReferralStatsByInviter(ctx, inviterUserID)
+	if err != nil {
+		return model.ReferralStats{}, fmt.Errorf("referral dao: stats: %w", err)
+	}
+	return model.ReferralStats{
+		InvitedCount:   int(row.InvitedCount),
+		EarnedCredits:  row.EarnedCredits,
+		EarnedCompDays: int(row.EarnedCompDays),
+	}, nil
+}
+
+// GetRedemptionByInvitee 返回用户作为被邀请人的兑换记录；未兑换时返回 pgx.ErrNoRows。
+func (d *referralDAO) GetRedemptionByInvitee(ctx context.Context, inviteeUserID int64) (model.ReferralRedemption, error) {
+	row, err := d.queries.GetReferralRedemptionByInvitee(ctx, inviteeUserID)
+	if err != nil {
+		return model.ReferralRedemption{}, err
+	}
+	return mapReferralRedemptionRow(row), nil
+}
+
+// InTx 在数据库事务内执行 fn，提交或回滚由 fn 的返回值驱动。
+func (d *referralDAO) InTx(ctx context.Context, fn func(ReferralTx) error) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("referral dao: begin tx: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err := fn(&referralTxQueries{queries: d.queries.WithTx(tx)}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("referral dao: commit: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+type referralTxQueries struct {
+	queries *db.Queries
+}
+
+func (r *referralTxQueries) LockCode(ctx context.Context, code string) (int64, error) {
+	row, err := r.queries.LockReferralCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrReferralCodeNotFound
+		}
+		return 0, fmt.Errorf("referral dao: lock code: %w", err)
+	}
+	return row.UserID, nil
+}
+
+func (r *referralTxQueries) GetUserCreatedAt(ctx context.Context, userID int64) (time.Time, error) {
+	row, err := r.queries.GetUserWithAccountState(ctx, userID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("referral dao: get user: %w", err)
+	}
+	return row.CreatedAt.Time, nil
+}
+
+func (r *referralTxQueries) HasRedeemed(ctx context.Context, inviteeUserID int64) (bool, error) {
+	_, err := r.queries.GetReferralRedemptionByInvitee(ctx, inviteeUserID)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return false, fmt.Errorf("referral dao: lookup redemption: %w", err)
+}
+
+func (r *referralTxQueries) ListGuestSubjects(ctx context.Context, userID int64) ([]string, error) {
+	identities, err := r.queries.ListAuthIdentitiesByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("referral dao: list identities: %w", err)
+	}
+	var out []string
+	for _, ai := range identities {
+		if ai.Provider == "guest" {
+			out = append(out, ai.ProviderSubject)
+		}
+	}
+	return out, nil
+}
+
+func (r *referralTxQueries) CountRedemptions(ctx context.Context, inviterUserID int64) (int, error) {
+	n, err := r.queries.CountReferralRedemptionsByInviter(ctx, inviterUserID)
+	if err != nil {
+		return 0, fmt.Errorf("referral dao: count redemptions: %w", err)
+	}
+	return int(n), nil
+}
+
+// InsertRedemption 写入兑换记录；被邀请人或设备重复时分别返回 ErrReferralAlreadyRedeemed / ErrReferralDeviceUsed。
+func (r *referralTxQueries) InsertRedemption(ctx context.Context, in model.ReferralRedemption) (model.ReferralRedemption, error) {
+	row, err := r.queries.InsertReferralRedemption(ctx, db.InsertReferralRedemptionParams{
+		InviterUserID:         in.InviterUserID,
+		InviteeUserID:         in.InviteeUserID,
+		Code:                  in.Code,
+		DeviceKey:             in.DeviceKey,
+		InviterRewardCredits:  in.InviterReward.Credits,
+		InviterRewardCompDays: int32(in.InviterReward.CompDays),
+		InviteeRewardCredits:  in.InviteeReward.Credits,
+		InviteeRewardCompDays: int32(in.InviteeReward.CompDays),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			switch pgErr.ConstraintName {
+			case referralRedemptionInviteeConstraint:
+				return model.ReferralRedemption{}, ErrReferralAlreadyRedeemed
+			case referralRedemptionDeviceConstraint:
+				return model.ReferralRedemption{}, ErrReferralDeviceUsed
+			}
+		}
+		return model.ReferralRedemption{}, fmt.Errorf("referral dao: insert redemption: %w", err)
+	}
+	return mapReferralRedemptionRow(row), nil
+}
+
+func (r *referralTxQueries) GrantCredits(ctx context.Context, in model.CreditGrant) (model.CreditBucket, error) {
+	return grantCreditsTx(ctx, r.queries, in)
+}
+
+func (r *referralTxQueries) GrantComp(ctx context.Context, in model.CompGrant, now time.Time) (model.CompEntitlement, error) {
+	return grantCompTx(ctx, r.queries, in, now)
+}
+
+func mapReferralRedemptionRow(row db.ReferralRedemption) model.ReferralRedemption {
+	return model.ReferralRedemption{
+		ID:            row.ID,
+		InviterUserID: row.InviterUserID,
+		InviteeUserID: row.InviteeUserID,
+		Code:          row.Code,
+		DeviceKey:     row.DeviceKey,
+		InviterReward: model.ReferralRewards{Credits: row.InviterRewardCredits, CompDays: int(row.InviterRewardCompDays)},
+		InviteeReward: model.ReferralRewards{Credits: row.InviteeRewardCredits, CompDays: int(row.InviteeRewardCompDays)},
+		CreatedAt:     row.CreatedAt.Time,
+	}
+}
+
+func newReferralCode() (string, error) {
+	var b [referralCodeLength]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = referralCodeAlphabet[int(b[i])%len(referralCodeAlphabet)]
+	}
+	return string(b[:]), nil
+}
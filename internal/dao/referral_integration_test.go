@@ -0,0 +1,87 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_ReferralDAO_CodeStableAndRedeemOnce(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	inviterID, cleanupInviter := withTestUser(t, pool)
+	defer cleanupInviter()
+	inviteeID, cleanupInvitee := withTestUser(t, pool)
+	defer cleanupInvitee()
+	otherID, cleanupOther := withTestUser(t, pool)
+	defer cleanupOther()
+
+	d := NewReferralDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	code, err := d.GetOrCreateCode(ctx, inviterID)
+	if err != nil {
+		t.Fatalf("create code: %v", err)
+	}
+	again, err := d.GetOrCreateCode(ctx, inviterID)
+	if err != nil || again != code {
+		t.Fatalf("code not stable: %q vs %q (err=%v)", code, again, err)
+	}
+
+	deviceKey := fmt.Sprintf("device-%d", inviteeID)
+	redeem := func(invitee int64) error {
+		return d.InTx(ctx, func(tx ReferralTx) error {
+			inviter, err := tx.LockCode(ctx, code)
+			if err != nil {
+				return err
+			}
+			r, err := tx.InsertRedemption(ctx, model.ReferralRedemption{
+				InviterUserID: inviter,
+				InviteeUserID: invitee,
+				Code:          code,
+				DeviceKey:     deviceKey,
+				InviterReward: model.ReferralRewards{Credits: 100},
+				InviteeReward: model.ReferralRewards{CompDays: 7},
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := tx.GrantCredits(ctx, model.CreditGrant{UserID: inviter, Source: model.CreditSourceReferral, Amount: 100, Reference: fmt.Sprintf("referral:%d", r.ID)}); err != nil {
+				return err
+			}
+			_, err = tx.GrantComp(ctx, model.CompGrant{UserID: invitee, PlanID: "referral", Level: 1, Source: model.CompSourceReferral, Duration: 7 * 24 * time.Hour}, now)
+			return err
+		})
+	}
+	if err := redeem(inviteeID); err != nil {
+		t.Fatalf("redeem: %v", err)
+	}
+	if err := redeem(inviteeID); !errors.Is(err, ErrReferralAlreadyRedeemed) {
+		t.Fatalf("second redeem err = %v, want ErrReferralAlreadyRedeemed", err)
+	}
+	if err := redeem(otherID); !errors.Is(err, ErrReferralDeviceUsed) {
+		t.Fatalf("same device err = %v, want ErrReferralDeviceUsed", err)
+	}
+
+	stats, err := d.GetStats(ctx, inviterID)
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if stats.InvitedCount != 1 || stats.EarnedCredits != 100 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	comps, err := NewCompEntitlementDAO(pool).ListUnexpiredCompEntitlements(ctx, inviteeID, now)
+	if err != nil {
+		t.Fatalf("list comps: %v", err)
+	}
+	if len(comps) != 1 || !comps[0].EndsAt.Equal(now.Add(7*24*time.Hour)) {
+		t.Fatalf("unexpected comps: %+v", comps)
+	}
+}
@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// RevenueReportDAO 按时间顺序读取 apple_events，供收入报表重放订阅生命周期。
+type RevenueReportDAO interface {
+	ListRevenueEvents(ctx context.Context, in model.RevenueEventQuery) ([]model.RevenueEvent, error)
+}
+
+type revenueReportDAO struct {
+	queries *db.Queries
+}
+
+// NewRevenueReportDAO 构造一个面向 PostgreSQL 的 RevenueReportDAO。
+func NewRevenueReportDAO(pool *pgxpool.Pool) RevenueReportDAO {
+	return &revenueReportDAO{queries: db.New(pool)}
+}
+
+// ListRevenueEvents 按 (发生时间, id) 升序返回至多 Limit 条带 original transaction 的事件，
+// 跳过重复通知；未知类型（含当时不在目录中的商品）的通知仍会返回，由报表按商品目录重新映射。AfterAt 为零值时从最早一条开始。
+func (d *revenueReportDAO) ListRevenueEvents(ctx context.Context, in model.RevenueEventQuery) ([]model.RevenueEvent, error) {
+	if in.Limit <= 0 {
+		return nil, fmt.Errorf("revenue report dao: invalid limit %d", in.Limit)
+	}
+	rows, err := d.queries.ListAppleRevenueEvents(ctx, db.ListAppleRevenueEventsParams{
+		Until:     timeToPgTimestamptz(in.Until),
+		AfterAt:   timeToPgTimestamptz(in.AfterAt),
+		AfterID:   in.AfterID,
+		BatchSize: int32(in.Limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("revenue report dao: list events: %w", err)
+	}
+	out := make([]model.RevenueEvent, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, model.RevenueEvent{
+			ID:                    r.ID,
+			NotificationType:      r.NotificationType,
+			Subtype:               r.Subtype,
+			Environment:           model.AppleEnvironment(r.Environment),
+			OriginalTransactionID: r.OriginalTransactionID,
+			OccurredAt:            r.OccurredAt.Time,
+			ProductID:             r.ProductID,
+			OfferDiscountType:     r.OfferDiscountType,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,82 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_RevenueReportDAO_ListRevenueEvents(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	subs := NewSubscriptionDAO(pool)
+	report := NewRevenueReportDAO(pool)
+	ctx := context.Background()
+	uuidPrefix := "it-revenue-" + t.Name() + "-"
+	defer func() {
+		_, _ = pool.Exec(ctx, "DELETE FROM apple_events WHERE notification_uuid LIKE $1", uuidPrefix+"%")
+	}()
+
+	// 用远早于其他测试数据的时间段隔离本测试的事件。
+	base := time.Date(2001, 3, 1, 0, 0, 0, 0, time.UTC)
+	originalTx := "it-revenue-" + time.Now().Format("150405.000000")
+	for i, ev := range []struct {
+		uuid, notifType, status, originalTx, offer string
+	}{
+		{"buy", "SUBSCRIBED", model.EventStatusProcessed, originalTx, "FREE_TRIAL"},
+		{"dup", "SUBSCRIBED", model.EventStatusIgnoredDuplicate, originalTx, ""},
+		{"no-tx", "TEST", model.EventStatusProcessed, "", ""},
+		{"renew", "DID_RENEW", model.EventStatusPendingUserBinding, originalTx, ""},
+		{"expire", "EXPIRED", model.EventStatusIgnoredUnknownType, originalTx, ""},
+		{"later", "DID_RENEW", model.EventStatusProcessed, originalTx, ""},
+	} {
+		at := base.Add(time.Duration(i) * 24 * time.Hour)
+		if err := subs.InTx(ctx, func(qtx SubscriptionTx) error {
+			_, _, err := qtx.InsertAppleEventIfNotExists(ctx, model.AppleEventInsert{
+				NotificationUUID:      uuidPrefix + ev.uuid,
+				NotificationType:      ev.notifType,
+				Environment:           model.AppleEnvSandbox,
+				UserID:                userID,
+				OriginalTransactionID: ev.originalTx,
+				ProcessingStatus:      ev.status,
+				RawJWSSHA256:          "sha",
+				NotificationCreatedAt: &at,
+				ProductID:             "com.app.pro.monthly",
+				OfferDiscountType:     ev.offer,
+			})
+			return err
+		}); err != nil {
+			t.Fatalf("insert %s: %v", ev.uuid, err)
+		}
+	}
+
+	query := model.RevenueEventQuery{
+		Until:   base.Add(5 * 24 * time.Hour),
+		AfterAt: base.Add(-time.Second),
+		Limit:   2,
+	}
+	first, err := report.ListRevenueEvents(ctx, query)
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	if len(first) != 2 || first[0].NotificationType != "SUBSCRIBED" || first[0].OfferDiscountType != "FREE_TRIAL" ||
+		first[0].OriginalTransactionID != originalTx || !first[0].OccurredAt.Equal(base) || first[1].NotificationType != "DID_RENEW" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+	query.AfterAt, query.AfterID = first[1].OccurredAt, first[1].ID
+	second, err := report.ListRevenueEvents(ctx, query)
+	if err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	// 跳过重复 / 无 original transaction 的通知，当时商品未知而忽略的通知仍返回，Until 之后的事件不返回。
+	if len(second) != 1 || second[0].NotificationType != "EXPIRED" || second[0].ProductID != "com.app.pro.monthly" {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+}
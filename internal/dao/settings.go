@@ -0,0 +1,95 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+)
+
+// SettingsDAO 持久化用户偏好覆盖值。
+//
+// DAO 只存取原始 JSON，不关心 key 是否合法、类型是否正确；这些约束由
+// internal/service/settings 的 registry 负责，DAO 层保持 schema 无关，新增配置项不需要迁移。
+type SettingsDAO interface {
+	// ListSettings 返回 user 所有已覆盖的配置项；没有覆盖时返回空 map。
+	ListSettings(ctx context.Context, userID int64) (map[string]json.RawMessage, error)
+	// UpdateSettings 在单个事务内写入 set 中的覆盖值并删除 reset 中的 key（恢复默认值）。
+	UpdateSettings(ctx context.Context, userID int64, set map[string]json.RawMessage, reset []string) error
+}
+
+type settingsDAO struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewSettingsDAO 构造一个面向 PostgreSQL 的 SettingsDAO。
+func NewSettingsDAO(pool *pgxpool.Pool) SettingsDAO {
+	return &settingsDAO{
+		pool:    pool,
+		queries: db.New(pool),
+	}
+}
+
+func (d *settingsDAO) ListSettings(ctx context.Context, userID int64) (map[string]json.RawMessage, error) {
+	if userID <= 0 {
+		return nil, fmt.Errorf("settings dao: invalid user id %d", userID)
+	}
+	rows, err := d.queries.ListUserSettings(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("settings dao: list: %w", err)
+	}
+	out := make(map[string]json.RawMessage, len(rows))
+	for _, r := range rows {
+		out[r.Key] = json.RawMessage(r.Value)
+	}
+	return out, nil
+}
+
+func (d *settingsDAO) UpdateSettings(ctx context.Context, userID int64, set map[string]json.RawMessage, reset []string) error {
+	if userID <= 0 {
+		return fmt.Errorf("settings dao: invalid user id %d", userID)
+	}
+	if len(set) == 0 && len(reset) == 0 {
+		return nil
+	}
+
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("settings dao: begin tx: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	q := d.queries.WithTx(tx)
+	for key, value := range set {
+		if err := q.UpsertUserSetting(ctx, db.UpsertUserSettingParams{
+			UserID: userID,
+			Key:    key,
+			Value:  value,
+		}); err != nil {
+			return fmt.Errorf("settings dao: upsert %q: %w", key, err)
+		}
+	}
+	for _, key := range reset {
+		if err := q.DeleteUserSetting(ctx, db.DeleteUserSettingParams{
+			UserID: userID,
+			Key:    key,
+		}); err != nil {
+			return fmt.Errorf("settings dao: delete %q: %w", key, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("settings dao: commit: %w", err)
+	}
+	committed = true
+	return nil
+}
@@ -0,0 +1,39 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestIntegration_SettingsDAO_UpsertAndReset(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	d := NewSettingsDAO(pool)
+	ctx := context.Background()
+
+	if err := d.UpdateSettings(ctx, userID, map[string]json.RawMessage{
+		"notifications.push_enabled": json.RawMessage(`false`),
+		"content.filter_level":       json.RawMessage(`"strict"`),
+	}, nil); err != nil {
+		t.Fatalf("first update: %v", err)
+	}
+	if err := d.UpdateSettings(ctx, userID, map[string]json.RawMessage{
+		"notifications.push_enabled": json.RawMessage(`true`),
+	}, []string{"content.filter_level"}); err != nil {
+		t.Fatalf("second update: %v", err)
+	}
+
+	got, err := d.ListSettings(ctx, userID)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 1 || string(got["notifications.push_enabled"]) != "true" {
+		t.Fatalf("unexpected settings: %+v", got)
+	}
+}
@@ -0,0 +1,258 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/db"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// ErrStripeCustomerNotFound 表示 user 或 customer id 尚未建立 Stripe customer 映射。
+var ErrStripeCustomerNotFound = errors.New("dao: stripe customer not found")
+
+// ErrStripeSubscriptionNotFound 表示按 subscription id 查找 Stripe 订阅时未命中。
+var ErrStripeSubscriptionNotFound = errors.New("dao: stripe subscription not found")
+
+// ErrStripeOwnershipConflict 表示同一 Stripe subscription 已经记在其他 user 名下。
+var ErrStripeOwnershipConflict = errors.New("dao: stripe subscription owned by another user")
+
+// StripeDAO 暴露 Stripe web billing 的持久化操作。
+//
+// 与 GooglePlayDAO 相同：webhook 写路径（事件 + 订阅）在 InTx 内完成，读路径不开事务。
+type StripeDAO interface {
+	GetCustomerByUser(ctx context.Context, userID int64) (model.StripeCustomer, error)
+	// BindCustomer 为 user 记录 customer id；user 已有映射时返回已有行（并发 checkout 只保留先写入者）。
+	BindCustomer(ctx context.Context, userID int64, customerID string) (model.StripeCustomer, error)
+	ListSubscriptionsForUserEntitlement(ctx context.Context, userID int64) ([]model.StripeSubscription, error)
+
+	InTx(ctx context.Context, fn func(StripeTx) error) error
+}
+
+// StripeTx 暴露事务作用域的写入操作。仅在 StripeDAO.InTx 回调里使用。
+type StripeTx interface {
+	InsertEventIfNotExists(ctx context.Context, in model.StripeEventInsert) (bool, error)
+	GetCustomerByCustomerID(ctx context.Context, customerID string) (model.StripeCustomer, error)
+	GetSubscription(ctx context.Context, subscriptionID string) (model.StripeSubscription, error)
+	UpsertSubscriptionWithOwnershipCheck(ctx context.Context, in model.StripeSubscriptionUpsert) (model.StripeSubscription, error)
+}
+
+type stripeDAO struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewStripeDAO 构造一个面向 PostgreSQL 的 StripeDAO。
+func NewStripeDAO(pool *pgxpool.Pool) StripeDAO {
+	return &stripeDAO{
+		pool:    pool,
+		queries: db.New(pool),
+	}
+}
+
+// GetCustomerByUser 返回 user 的 Stripe customer；未建立映射返回 ErrStripeCustomerNotFound。
+func (d *stripeDAO) GetCustomerByUser(ctx context.Context, userID int64) (model.StripeCustomer, error) {
+	row, err := d.queries.GetStripeCustomerByUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.StripeCustomer{}, ErrStripeCustomerNotFound
+		}
+		return model.StripeCustomer{}, fmt.Errorf("stripe dao: get customer: %w", err)
+	}
+	return mapStripeCustomerRow(row), nil
+}
+
+// BindCustomer 以 user_id 唯一约束幂等写入 customer 映射。
+func (d *stripeDAO) BindCustomer(ctx context.Context, userID int64, customerID string) (model.StripeCustomer, error) {
+	if userID <= 0 {
+		return model.StripeCustomer{}, fmt.Errorf("stripe dao: invalid user id %d", userID)
+	}
+	if customerID == "" {
+		return model.StripeCustomer{}, errors.New("stripe dao: customer id required")
+	}
+	row, err := d.queries.InsertStripeCustomerIfNotExists(ctx, db.InsertStripeCustomerIfNotExistsParams{
+		UserID:     userID,
+		CustomerID: customerID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return d.GetCustomerByUser(ctx, userID)
+		}
+		return model.StripeCustomer{}, fmt.Errorf("stripe dao: bind customer: %w", err)
+	}
+	return mapStripeCustomerRow(row), nil
+}
+
+// ListSubscriptionsForUserEntitlement 返回 user 的 Stripe 订阅，排序规则与 Apple 订阅一致。
+func (d *stripeDAO) ListSubscriptionsForUserEntitlement(ctx context.Context, userID int64) ([]model.StripeSubscription, error) {
+	if userID <= 0 {
+		return nil, fmt.Errorf("stripe dao: invalid user id %d", userID)
+	}
+	rows, err := d.queries.ListStripeSubscriptionsForUserEntitlement(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("stripe dao: list subscriptions: %w", err)
+	}
+	out := make([]model.StripeSubscription, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, mapStripeSubscriptionRow(r))
+	}
+	return out, nil
+}
+
+// InTx 在数据库事务内执行 fn，提交或回滚由 fn 的返回值驱动。
+func (d *stripeDAO) InTx(ctx context.Context, fn func(StripeTx) error) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("stripe dao: begin tx: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err := fn(&stripeTxQueries{queries: d.queries.WithTx(tx)}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("stripe dao: commit: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+type stripeTxQueries struct {
+	queries *db.Queries
+}
+
+// InsertEventIfNotExists 把 webhook 事件幂等地写入 stripe_events；返回 false 表示 event id 已存在（重复投递）。
+func (s *stripeTxQueries) InsertEventIfNotExists(ctx context.Context, in model.StripeEventInsert) (bool, error) {
+	if in.EventID == "" {
+		return false, errors.New("stripe dao: event id required")
+	}
+	_, err := s.queries.InsertStripeEventIfNotExists(ctx, db.InsertStripeEventIfNotExistsParams{
+		EventID:          in.EventID,
+		EventType:        in.EventType,
+		CustomerID:       in.CustomerID,
+		SubscriptionID:   in.SubscriptionID,
+		UserID:           int64ToPgInt8(in.UserID),
+		ProcessingStatus: defaultIfEmpty(in.ProcessingStatus, model.EventStatusProcessed),
+		ProcessingError:  in.ProcessingError,
+		EventCreatedAt:   optionalTimePg(in.EventCreatedAt),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("stripe dao: insert event: %w", err)
+	}
+	return true, nil
+}
+
+// GetCustomerByCustomerID 按 Stripe customer id 反查 user；未命中返回 ErrStripeCustomerNotFound。
+func (s *stripeTxQueries) GetCustomerByCustomerID(ctx context.Context, customerID string) (model.StripeCustomer, error) {
+	row, err := s.queries.GetStripeCustomerByCustomerID(ctx, customerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.StripeCustomer{}, ErrStripeCustomerNotFound
+		}
+		return model.StripeCustomer{}, fmt.Errorf("stripe dao: get customer by id: %w", err)
+	}
+	return mapStripeCustomerRow(row), nil
+}
+
+// GetSubscription 在事务内按 subscription id 读取订阅并加行锁；未命中返回 ErrStripeSubscriptionNotFound。
+func (s *stripeTxQueries) GetSubscription(ctx context.Context, subscriptionID string) (model.StripeSubscription, error) {
+	row, err := s.queries.LockStripeSubscriptionBySubscriptionID(ctx, subscriptionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.StripeSubscription{}, ErrStripeSubscriptionNotFound
+		}
+		return model.StripeSubscription{}, fmt.Errorf("stripe dao: lock subscription: %w", err)
+	}
+	return mapStripeSubscriptionRow(row), nil
+}
+
+// UpsertSubscriptionWithOwnershipCheck 在事务内 upsert 订阅；现有行属于其他 user 时返回 ErrStripeOwnershipConflict。
+//
+// Stripe 不保证事件按序投递：in.LastEventAt 早于已写入的 last_event_at 时不覆盖，原样返回现有行。
+func (s *stripeTxQueries) UpsertSubscriptionWithOwnershipCheck(ctx context.Context, in model.StripeSubscriptionUpsert) (model.StripeSubscription, error) {
+	if in.UserID <= 0 {
+		return model.StripeSubscription{}, fmt.Errorf("stripe dao: invalid user id %d", in.UserID)
+	}
+	if in.SubscriptionID == "" {
+		return model.StripeSubscription{}, errors.New("stripe dao: subscription id required")
+	}
+	existing, err := s.GetSubscription(ctx, in.SubscriptionID)
+	if err != nil && !errors.Is(err, ErrStripeSubscriptionNotFound) {
+		return model.StripeSubscription{}, err
+	}
+	if err == nil && existing.UserID != in.UserID {
+		return model.StripeSubscription{}, ErrStripeOwnershipConflict
+	}
+
+	row, err := s.queries.UpsertStripeSubscription(ctx, db.UpsertStripeSubscriptionParams{
+		UserID:             in.UserID,
+		CustomerID:         in.CustomerID,
+		SubscriptionID:     in.SubscriptionID,
+		PriceID:            in.PriceID,
+		PlanID:             in.PlanID,
+		Level:              int32(in.Level),
+		Status:             defaultIfEmpty(in.Status, model.SubscriptionStatusActive),
+		StripeStatus:       in.StripeStatus,
+		AutoRenewStatus:    defaultIfEmpty(in.AutoRenewStatus, model.AutoRenewStatusUnknown),
+		CurrentPeriodStart: timeToPgTimestamptz(in.CurrentPeriodStart),
+		CurrentPeriodEnd:   timeToPgTimestamptz(in.CurrentPeriodEnd),
+		LastEventAt:        timeToPgTimestamptz(in.LastEventAt),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return existing, nil
+	}
+	if err != nil {
+		return model.StripeSubscription{}, fmt.Errorf("stripe dao: upsert subscription: %w", err)
+	}
+	sub := mapStripeSubscriptionRow(row)
+	var before *model.EntitlementChange
+	if existing.ID != 0 {
+		c := stripeEntitlementChange(existing)
+		before = &c
+	}
+	if err := recordEntitlementChange(ctx, s.queries, before, stripeEntitlementChange(sub)); err != nil {
+		return model.StripeSubscription{}, err
+	}
+	return sub, nil
+}
+
+func mapStripeCustomerRow(row db.StripeCustomer) model.StripeCustomer {
+	return model.StripeCustomer{
+		ID:         row.ID,
+		UserID:     row.UserID,
+		CustomerID: row.CustomerID,
+		CreatedAt:  row.CreatedAt.Time,
+	}
+}
+
+func mapStripeSubscriptionRow(row db.StripeSubscription) model.StripeSubscription {
+	return model.StripeSubscription{
+		ID:                 row.ID,
+		UserID:             row.UserID,
+		CustomerID:         row.CustomerID,
+		SubscriptionID:     row.SubscriptionID,
+		PriceID:            row.PriceID,
+		PlanID:             row.PlanID,
+		Level:              int(row.Level),
+		Status:             row.Status,
+		StripeStatus:       row.StripeStatus,
+		AutoRenewStatus:    row.AutoRenewStatus,
+		CurrentPeriodStart: row.CurrentPeriodStart.Time,
+		CurrentPeriodEnd:   row.CurrentPeriodEnd.Time,
+		LastEventAt:        row.LastEventAt.Time,
+		CreatedAt:          row.CreatedAt.Time,
+		UpdatedAt:          row.UpdatedAt.Time,
+	}
+}
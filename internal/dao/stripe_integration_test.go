@@ -0,0 +1,104 @@
+//go:build integration
+
+package dao
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestIntegration_StripeDAO_CustomerBindingAndSubscription(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+	otherID, cleanupOther := withTestUser(t, pool)
+	defer cleanupOther()
+
+	d := NewStripeDAO(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	customerID := "cus_it_" + t.Name()
+	subscriptionID := "sub_it_" + t.Name()
+
+	if _, err := d.GetCustomerByUser(ctx, userID); !errors.Is(err, ErrStripeCustomerNotFound) {
+		t.Fatalf("get before bind err = %v, want ErrStripeCustomerNotFound", err)
+	}
+	first, err := d.BindCustomer(ctx, userID, customerID)
+	if err != nil || first.CustomerID != customerID {
+		t.Fatalf("bind: %+v %v", first, err)
+	}
+	// 并发 checkout 的第二次 bind 返回已存在的映射而不是覆盖。
+	second, err := d.BindCustomer(ctx, userID, customerID+"_dup")
+	if err != nil || second.CustomerID != customerID {
+		t.Fatalf("rebind: %+v %v", second, err)
+	}
+
+	in := model.StripeSubscriptionUpsert{
+		UserID:             userID,
+		CustomerID:         customerID,
+		SubscriptionID:     subscriptionID,
+		PriceID:            "price_it",
+		PlanID:             "pro_monthly",
+		Level:              1,
+		Status:             model.SubscriptionStatusActive,
+		StripeStatus:       "active",
+		AutoRenewStatus:    model.AutoRenewStatusOn,
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   now.Add(30 * 24 * time.Hour),
+		LastEventAt:        now,
+	}
+	var sub model.StripeSubscription
+	if err := d.InTx(ctx, func(tx StripeTx) error {
+		c, err := tx.GetCustomerByCustomerID(ctx, customerID)
+		if err != nil || c.UserID != userID {
+			t.Fatalf("customer lookup: %+v %v", c, err)
+		}
+		created, err := tx.InsertEventIfNotExists(ctx, model.StripeEventInsert{
+			EventID: "evt_it_" + t.Name(), EventType: "customer.subscription.created",
+			CustomerID: customerID, SubscriptionID: subscriptionID, UserID: userID,
+			ProcessingStatus: model.EventStatusProcessed,
+		})
+		if err != nil || !created {
+			t.Fatalf("insert event: created=%v err=%v", created, err)
+		}
+		sub, err = tx.UpsertSubscriptionWithOwnershipCheck(ctx, in)
+		return err
+	}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	other := in
+	other.UserID = otherID
+	if err := d.InTx(ctx, func(tx StripeTx) error {
+		_, err := tx.UpsertSubscriptionWithOwnershipCheck(ctx, other)
+		return err
+	}); !errors.Is(err, ErrStripeOwnershipConflict) {
+		t.Fatalf("other user err = %v, want ErrStripeOwnershipConflict", err)
+	}
+
+	// 乱序到达的旧事件不覆盖已有状态。
+	stale := in
+	stale.Status = model.SubscriptionStatusExpired
+	stale.LastEventAt = now.Add(-time.Minute)
+	var kept model.StripeSubscription
+	if err := d.InTx(ctx, func(tx StripeTx) error {
+		var err error
+		kept, err = tx.UpsertSubscriptionWithOwnershipCheck(ctx, stale)
+		return err
+	}); err != nil {
+		t.Fatalf("stale upsert: %v", err)
+	}
+	if kept.ID != sub.ID || kept.Status != model.SubscriptionStatusActive || !kept.LastEventAt.Equal(now) {
+		t.Fatalf("stale event overwrote subscription: %+v", kept)
+	}
+
+	rows, err := d.ListSubscriptionsForUserEntitlement(ctx, userID)
+	if err != nil || len(rows) != 1 || rows[0].ID != sub.ID {
+		t.Fatalf("entitlement rows = %+v, err = %v", rows, err)
+	}
+}
@@ -30,6 +30,12 @@ var ErrSubscriptionNotFound = errors.New("dao: apple subscription not found")
 // 该错误是 webhook OWNERSHIP_CONFLICT 路径与 verify 409 路径的唯一区分点。
 var ErrSubscriptionOwnershipConflict = errors.New("dao: apple subscription owned by another user")
 
+// ErrStaleSubscriptionUpdate 表示 upsert 入参比现有行更旧（通知时间或交易时间更早，或试图把已过期 / 撤销的
+// 订阅改回有效而没有更新的周期），DAO 拒绝写入以防乱序通知回退状态。
+//
+// webhook 路径据此把事件记为 IGNORED_STALE；verify 路径返回现有行。
+var ErrStaleSubscriptionUpdate = errors.New("dao: apple subscription update is older than current state")
+
 // SubscriptionDAO 暴露 Apple IAP 订阅领域的持久化操作。
 //
 // 单元划分：
@@ -41,11 +47,13 @@ var ErrSubscriptionOwnershipConflict = errors.New("dao: apple subscription owned
 // 事务内既写 apple_events 幂等键又 reduce apple_subscriptions，从而保证 atomic
 // idempotency。读路径不开事务以减少锁竞争。
 type SubscriptionDAO interface {
-	GetOrCreateAccountToken(ctx context.Context, userID int64) (string, error)
+	GetOrCreateAccountToken(ctx context.Context, userID int64) (string, bool, error)
 	GetAccountTokenByToken(ctx context.Context, token string) (model.AppleAccountToken, error)
 
 	GetSubscriptionByOriginalTx(ctx context.Context, originalTxID string, env model.AppleEnvironment) (model.Subscription, error)
 	ListSubscriptionsForUserEntitlement(ctx context.Context, userID int64, allowedEnvs []model.AppleEnvironment) ([]model.Subscription, error)
+	ListActiveLifetimePurchases(ctx context.Context, userID int64, allowedEnvs []model.AppleEnvironment) ([]model.Purchase, error)
+	ListFamilySharesForUserEntitlement(ctx context.Context, userID int64, allowedEnvs []model.AppleEnvironment) ([]model.AppleFamilyShare, error)
 
 	InTx(ctx context.Context, fn func(SubscriptionTx) error) error
 }
@@ -55,6 +63,29 @@ type SubscriptionTx interface {
 	InsertAppleEventIfNotExists(ctx context.Context, in model.AppleEventInsert) (created bool, eventID int64, err error)
 	UpsertSubscriptionWithOwnershipCheck(ctx context.Context, in model.SubscriptionUpsert) (model.Subscription, error)
 	GetSubscriptionByOriginalTx(ctx context.Context, originalTxID string, env model.AppleEnvironment) (model.Subscription, error)
+	ReassignSubscriptionOwner(ctx context.Context, subscriptionID, userID int64, appAccountToken string) (model.Subscription, error)
+
+	// 一次性购买（消耗型 / 非消耗型），实现见 purchase.go。
+	InsertPurchaseIfNotExists(ctx context.Context, in model.PurchaseInsert) (model.Purchase, bool, error)
+	GetPurchaseByTransaction(ctx context.Context, transactionID string, env model.AppleEnvironment) (model.Purchase, error)
+	GrantPurchaseCredits(ctx context.Context, purchaseID int64, in model.CreditGrant) (model.CreditBucket, error)
+	RefundPurchase(ctx context.Context, p model.Purchase, revokedAt time.Time) (model.Purchase, error)
+
+	// Family Sharing 家庭成员的权益，实现见 family_share.go。
+	GetFamilyShare(ctx context.Context, originalTxID string, env model.AppleEnvironment) (model.AppleFamilyShare, error)
+	UpsertFamilyShareWithOwnershipCheck(ctx context.Context, in model.AppleFamilyShareUpsert) (model.AppleFamilyShare, error)
+
+	// PENDING_USER_BINDING 事件重放，实现见 apple_event.go。
+	ListRedrivableEvents(ctx context.Context, token string, limit int) ([]model.AppleEvent, error)
+	LockPendingEvent(ctx context.Context, eventID int64) (model.AppleEvent, error)
+	UpdateEventProcessing(ctx context.Context, eventID int64, status string, userID int64, processingError string) error
+
+	// 加密归档，实现见 apple_payload_archive.go。
+	InsertPayloadArchive(ctx context.Context, in model.ApplePayloadArchive) error
+	GetPayloadArchiveByEvent(ctx context.Context, eventID int64) (model.ApplePayloadArchive, error)
+
+	// CONSUMPTION_REQUEST 记录，实现见 apple_consumption.go。
+	InsertConsumptionRequest(ctx context.Context, in model.AppleConsumptionRequest) error
 }
 
 type subscriptionDAO struct {
@@ -70,18 +101,18 @@ func NewSubscriptionDAO(pool *pgxpool.Pool) SubscriptionDAO {
 	}
 }
 
-// GetOrCreateAccountToken 返回 userID 对应的 appAccountToken UUID。
+// GetOrCreateAccountToken 返回 userID 对应的 appAccountToken UUID，created 表示本次调用新建了映射。
 //
 // 同一用户多次调用返回同一个 UUID。实现策略：在事务里先 select；未命中再生成 UUID v4
 // 并 insert。借助 apple_account_tokens.UNIQUE(user_id) 约束兜底并发首调写入场景。
-func (d *subscriptionDAO) GetOrCreateAccountToken(ctx context.Context, userID int64) (string, error) {
+func (d *subscriptionDAO) GetOrCreateAccountToken(ctx context.Context, userID int64) (string, bool, error) {
 	if userID <= 0 {
-		return "", fmt.Errorf("subscription dao: invalid user id %d", userID)
+		return "", false, fmt.Errorf("subscription dao: invalid user id %d", userID)
 	}
 
 	tx, err := d.pool.Begin(ctx)
 	if err != nil {
-		return "", fmt.Errorf("subscription dao: begin tx: %w", err)
+		return "", false, fmt.Errorf("subscription dao: begin tx: %w", err)
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
@@ -89,33 +120,33 @@ func (d *subscriptionDAO) GetOrCreateAccountToken(ctx context.Context, userID in
 	existing, err := qtx.GetAppleAccountTokenByUser(ctx, userID)
 	if err == nil {
 		if commitErr := tx.Commit(ctx); commitErr != nil {
-			return "", fmt.Errorf("subscription dao: commit: %w", commitErr)
+			return "", false, fmt.Errorf("subscription dao: commit: %w", commitErr)
 		}
-		return pgUUIDToString(existing.Token), nil
+		return pgUUIDToString(existing.Token), false, nil
 	}
 	if !errors.Is(err, pgx.ErrNoRows) {
-		return "", fmt.Errorf("subscription dao: lookup account token: %w", err)
+		return "", false, fmt.Errorf("subscription dao: lookup account token: %w", err)
 	}
 
 	token, err := newUUIDv4()
 	if err != nil {
-		return "", fmt.Errorf("subscription dao: generate uuid: %w", err)
+		return "", false, fmt.Errorf("subscription dao: generate uuid: %w", err)
 	}
 	pg, err := uuidStringToPg(token)
 	if err != nil {
-		return "", fmt.Errorf("subscription dao: encode uuid: %w", err)
+		return "", false, fmt.Errorf("subscription dao: encode uuid: %w", err)
 	}
 	inserted, err := qtx.InsertAppleAccountToken(ctx, db.InsertAppleAccountTokenParams{
 		UserID: userID,
 		Token:  pg,
 	})
 	if err != nil {
-		return "", fmt.Errorf("subscription dao: insert account token: %w", err)
+		return "", false, fmt.Errorf("subscription dao: insert account token: %w", err)
 	}
 	if commitErr := tx.Commit(ctx); commitErr != nil {
-		return "", fmt.Errorf("subscription dao: commit: %w", commitErr)
+		return "", false, fmt.Errorf("subscription dao: commit: %w", commitErr)
 	}
-	return pgUUIDToString(inserted.Token), nil
+	return pgUUIDToString(inserted.Token), true, nil
 }
 
 // GetAccountTokenByToken 通过 UUID 反查映射；未命中或 UUID 无效都返回 ErrAccountTokenNotFound。
@@ -246,6 +277,9 @@ func (s *subscriptionTxQueries) InsertAppleEventIfNotExists(ctx context.Context,
 		RawJwsSha256:          in.RawJWSSHA256,
 		DecodedPayload:        in.DecodedPayload,
 		NotificationCreatedAt: optionalTimePg(in.NotificationCreatedAt),
+		ProductID:             in.ProductID,
+		RenewalProductID:      in.RenewalProductID,
+		OfferDiscountType:     in.OfferDiscountType,
 	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -259,7 +293,8 @@ func (s *subscriptionTxQueries) InsertAppleEventIfNotExists(ctx context.Context,
 // UpsertSubscriptionWithOwnershipCheck 在事务内 upsert subscription 并保证 ownership 不漂移。
 //
 // 流程：
-//  1. SELECT 现有 (original_transaction_id, environment) 行；
+//  1. SELECT ... FOR UPDATE 锁住现有 (original_transaction_id, environment) 行，并发投递在此串行，
+//     乱序判断和权益变更的 before 快照都基于锁内读到的状态；
 //  2. 若存在但 user_id 与入参不同 → 返回 ErrSubscriptionOwnershipConflict 触发回滚；
 //  3. 否则调用 sqlc UpsertSubscription（ON CONFLICT DO UPDATE 不触碰 user_id 与 app_account_token）。
 //
@@ -275,16 +310,16 @@ func (s *subscriptionTxQueries) UpsertSubscriptionWithOwnershipCheck(ctx context
 		return model.Subscription{}, errors.New("subscription dao: environment required")
 	}
 
-	existing, err := s.queries.GetSubscriptionByOriginalTx(ctx, db.GetSubscriptionByOriginalTxParams{
-		OriginalTransactionID: in.OriginalTransactionID,
-		Environment:           string(in.Environment),
-	})
+	existing, err := s.lockSubscriptionForUpsert(ctx, in.OriginalTransactionID, in.Environment)
 	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		return model.Subscription{}, fmt.Errorf("subscription dao: lookup before upsert: %w", err)
+		return model.Subscription{}, fmt.Errorf("subscription dao: lock before upsert: %w", err)
 	}
 	if err == nil && existing.UserID != in.UserID {
 		return model.Subscription{}, ErrSubscriptionOwnershipConflict
 	}
+	if err == nil && isStaleSubscriptionUpsert(mapSubscriptionRow(existing), in) {
+		return model.Subscription{}, ErrStaleSubscriptionUpdate
+	}
 
 	pg, err := uuidStringToPg(in.AppAccountToken)
 	if err != nil {
@@ -311,11 +346,63 @@ func (s *subscriptionTxQueries) UpsertSubscriptionWithOwnershipCheck(ctx context
 		LastNotificationCreatedAt: optionalTimePg(in.LastNotificationCreatedAt),
 		LastPayloadHash:           in.LastPayloadHash,
 		LastTransactionSnapshot:   in.LastTransactionSnapshot,
+		PendingProductID:          in.PendingProductID,
+		PendingPlanID:             in.PendingPlanID,
+		PendingLevel:              int32(in.PendingLevel),
+		BillingRetry:              in.BillingRetry,
+		ExpirationIntent:          int32(in.ExpirationIntent),
 	})
 	if err != nil {
 		return model.Subscription{}, fmt.Errorf("subscription dao: upsert: %w", err)
 	}
-	return mapSubscriptionRow(row), nil
+	sub := mapSubscriptionRow(row)
+	var before *model.EntitlementChange
+	if existing.ID != 0 {
+		c := appleEntitlementChange(mapSubscriptionRow(existing))
+		before = &c
+	}
+	if err := recordEntitlementChange(ctx, s.queries, before, appleEntitlementChange(sub)); err != nil {
+		return model.Subscription{}, err
+	}
+	return sub, nil
+}
+
+// lockSubscriptionForUpsert 锁住待 upsert 的订阅行。行不存在时 FOR UPDATE 锁不到任何东西，
+// 改为按 (environment, original_transaction_id) 取事务级 advisory lock 后重读，避免两条首投同时插入。
+func (s *subscriptionTxQueries) lockSubscriptionForUpsert(ctx context.Context, originalTxID string, env model.AppleEnvironment) (db.AppleSubscription, error) {
+	params := db.GetSubscriptionByOriginalTxForUpdateParams{
+		OriginalTransactionID: originalTxID,
+		Environment:           string(env),
+	}
+	row, err := s.queries.GetSubscriptionByOriginalTxForUpdate(ctx, params)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return row, err
+	}
+	if err := s.queries.LockSubscriptionOriginalTx(ctx, db.LockSubscriptionOriginalTxParams{
+		Environment:           string(env),
+		OriginalTransactionID: originalTxID,
+	}); err != nil {
+		return db.AppleSubscription{}, err
+	}
+	return s.queries.GetSubscriptionByOriginalTxForUpdate(ctx, params)
+}
+
+// isStaleSubscriptionUpsert 判断入参是否比现有行更旧。Apple 不保证通知顺序，reconcile 重放也会带来旧事件：
+//  1. 双方都带通知时间（signedDate）且入参更早；
+//  2. 入参的 transaction 购买时间（current_period_start）早于现有行，撤销（REVOKED）除外；
+//  3. 现有行已 EXPIRED / REVOKED，入参要改回 ACTIVE / CANCELED 但 current_period_end 并不更晚。
+func isStaleSubscriptionUpsert(existing model.Subscription, in model.SubscriptionUpsert) bool {
+	if existing.LastNotificationCreatedAt != nil && in.LastNotificationCreatedAt != nil &&
+		in.LastNotificationCreatedAt.Before(*existing.LastNotificationCreatedAt) {
+		return true
+	}
+	if in.Status != model.SubscriptionStatusRevoked && !in.CurrentPeriodStart.IsZero() &&
+		in.CurrentPeriodStart.Before(existing.CurrentPeriodStart) {
+		return true
+	}
+	terminal := existing.Status == model.SubscriptionStatusExpired || existing.Status == model.SubscriptionStatusRevoked
+	reviving := in.Status == model.SubscriptionStatusActive || in.Status == model.SubscriptionStatusCanceled
+	return terminal && reviving && !in.CurrentPeriodEnd.After(existing.CurrentPeriodEnd)
 }
 
 // GetSubscriptionByOriginalTx 在事务内提供一致读，便于 service 在 reducer 中预读现有快照。
@@ -333,6 +420,57 @@ func (s *subscriptionTxQueries) GetSubscriptionByOriginalTx(ctx context.Context,
 	return mapSubscriptionRow(row), nil
 }
 
+// ReassignSubscriptionOwner 把订阅行改绑到 userID / appAccountToken，仅供恢复购买使用：
+// 调用方必须已确认 Apple 返回的最新 transaction 携带的正是该用户的 appAccountToken。
+// 改绑时原用户收到 subscription.deactivated（data.user_id 为原用户），新用户收到 subscription.activated。
+func (s *subscriptionTxQueries) ReassignSubscriptionOwner(ctx context.Context, subscriptionID, userID int64, appAccountToken string) (model.Subscription, error) {
+	if userID <= 0 {
+		return model.Subscription{}, fmt.Errorf("subscription dao: invalid user id %d", userID)
+	}
+	pg, err := uuidStringToPg(appAccountToken)
+	if err != nil {
+		return model.Subscription{}, fmt.Errorf("subscription dao: encode app_account_token: %w", err)
+	}
+	existing, err := s.queries.LockSubscriptionByID(ctx, subscriptionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Subscription{}, ErrSubscriptionNotFound
+		}
+		return model.Subscription{}, fmt.Errorf("subscription dao: lock before reassign: %w", err)
+	}
+	row, err := s.queries.ReassignSubscriptionOwner(ctx, db.ReassignSubscriptionOwnerParams{
+		UserID:          userID,
+		AppAccountToken: pg,
+		ID:              subscriptionID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Subscription{}, ErrSubscriptionNotFound
+		}
+		return model.Subscription{}, fmt.Errorf("subscription dao: reassign owner: %w", err)
+	}
+	sub := mapSubscriptionRow(row)
+	before := appleEntitlementChange(mapSubscriptionRow(existing))
+	if existing.UserID == userID {
+		if err := recordEntitlementChange(ctx, s.queries, &before, appleEntitlementChange(sub)); err != nil {
+			return model.Subscription{}, err
+		}
+		return sub, nil
+	}
+	// 换了主人：原用户失去该订阅带来的权益，新用户视为新获得。
+	if classifyEntitlementChange(nil, before) == model.EntitlementEventActivated {
+		lost := appleEntitlementChange(sub)
+		lost.UserID, lost.PreviousStatus = existing.UserID, existing.Status
+		if err := insertEntitlementEvent(ctx, s.queries, model.EntitlementEventDeactivated, lost); err != nil {
+			return model.Subscription{}, err
+		}
+	}
+	if err := recordEntitlementChange(ctx, s.queries, nil, appleEntitlementChange(sub)); err != nil {
+		return model.Subscription{}, err
+	}
+	return sub, nil
+}
+
 func mapSubscriptionRow(row db.AppleSubscription) model.Subscription {
 	out := model.Subscription{
 		ID:                      row.ID,
@@ -353,6 +491,11 @@ func mapSubscriptionRow(row db.AppleSubscription) model.Subscription {
 		LastEventAt:             row.LastEventAt.Time,
 		LastPayloadHash:         row.LastPayloadHash,
 		LastTransactionSnapshot: row.LastTransactionSnapshot,
+		PendingProductID:        row.PendingProductID,
+		PendingPlanID:           row.PendingPlanID,
+		PendingLevel:            int(row.PendingLevel),
+		BillingRetry:            row.BillingRetry,
+		ExpirationIntent:        int(row.ExpirationIntent),
 		CreatedAt:               row.CreatedAt.Time,
 		UpdatedAt:               row.UpdatedAt.Time,
 	}
@@ -5,6 +5,7 @@ package dao
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"sync"
@@ -65,13 +66,13 @@ func TestIntegration_SubscriptionDAO_AccountTokenUniqueAndStable(t *testing.T) {
 	dao := NewSubscriptionDAO(pool)
 	ctx := context.Background()
 
-	first, err := dao.GetOrCreateAccountToken(ctx, userID)
-	if err != nil {
-		t.Fatalf("first call: %v", err)
+	first, created, err := dao.GetOrCreateAccountToken(ctx, userID)
+	if err != nil || !created {
+		t.Fatalf("first call: created=%v err=%v", created, err)
 	}
-	second, err := dao.GetOrCreateAccountToken(ctx, userID)
-	if err != nil {
-		t.Fatalf("second call: %v", err)
+	second, created, err := dao.GetOrCreateAccountToken(ctx, userID)
+	if err != nil || created {
+		t.Fatalf("second call: created=%v err=%v", created, err)
 	}
 	if first != second {
 		t.Fatalf("token not stable: %s vs %s", first, second)
@@ -98,7 +99,7 @@ func TestIntegration_SubscriptionDAO_AccountTokenConcurrentFirstCall(t *testing.
 	for i := 0; i < workers; i++ {
 		go func(idx int) {
 			defer wg.Done()
-			tok, err := dao.GetOrCreateAccountToken(ctx, userID)
+			tok, _, err := dao.GetOrCreateAccountToken(ctx, userID)
 			results[idx] = tok
 			errs[idx] = err
 		}(i)
@@ -138,11 +139,11 @@ func TestIntegration_SubscriptionDAO_OwnershipConflict(t *testing.T) {
 	dao := NewSubscriptionDAO(pool)
 	ctx := context.Background()
 
-	tokenA, err := dao.GetOrCreateAccountToken(ctx, userA)
+	tokenA, _, err := dao.GetOrCreateAccountToken(ctx, userA)
 	if err != nil {
 		t.Fatalf("token A: %v", err)
 	}
-	tokenB, err := dao.GetOrCreateAccountToken(ctx, userB)
+	tokenB, _, err := dao.GetOrCreateAccountToken(ctx, userB)
 	if err != nil {
 		t.Fatalf("token B: %v", err)
 	}
@@ -177,6 +178,182 @@ func TestIntegration_SubscriptionDAO_OwnershipConflict(t *testing.T) {
 	if !errors.Is(err, ErrSubscriptionOwnershipConflict) {
 		t.Fatalf("user B should conflict, got %v", err)
 	}
+
+	// 恢复购买确认最新交易属于 B 之后改绑，B 随后可以正常写入。
+	if err := dao.InTx(ctx, func(qtx SubscriptionTx) error {
+		current, err := qtx.GetSubscriptionByOriginalTx(ctx, "shared-original-tx", model.AppleEnvProduction)
+		if err != nil {
+			return err
+		}
+		moved, err := qtx.ReassignSubscriptionOwner(ctx, current.ID, userB, tokenB)
+		if err != nil {
+			return err
+		}
+		if moved.UserID != userB || moved.AppAccountToken != tokenB {
+			t.Errorf("unexpected reassigned row: %+v", moved)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("reassign: %v", err)
+	}
+	if err := upsert(userB, tokenB); err != nil {
+		t.Fatalf("user B after reassign: %v", err)
+	}
+}
+
+func TestIntegration_SubscriptionDAO_RejectsOutOfOrderUpdates(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	dao := NewSubscriptionDAO(pool)
+	ctx := context.Background()
+	token, _, err := dao.GetOrCreateAccountToken(ctx, userID)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	start, end := now.Add(-24*time.Hour), now.Add(29*24*time.Hour)
+	upsert := func(status string, periodStart, periodEnd, signedAt time.Time) error {
+		return dao.InTx(ctx, func(qtx SubscriptionTx) error {
+			_, err := qtx.UpsertSubscriptionWithOwnershipCheck(ctx, model.SubscriptionUpsert{
+				UserID:                    userID,
+				AppAccountToken:           token,
+				Environment:               model.AppleEnvProduction,
+				OriginalTransactionID:     "it-stale-" + t.Name(),
+				LastTransactionID:         "txn-" + periodStart.Format(time.RFC3339),
+				PlanID:                    "pro_monthly",
+				ProviderProductID:         "com.app.pro.monthly",
+				Level:                     1,
+				Status:                    status,
+				AutoRenewStatus:           model.AutoRenewStatusOn,
+				CurrentPeriodStart:        periodStart,
+				CurrentPeriodEnd:          periodEnd,
+				LastEventAt:               signedAt,
+				LastNotificationCreatedAt: &signedAt,
+			})
+			return err
+		})
+	}
+
+	if err := upsert(model.SubscriptionStatusActive, start, end, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("initial: %v", err)
+	}
+	if err := upsert(model.SubscriptionStatusRevoked, start, end, now); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	// 延迟到达的 DID_RENEW：通知时间更早。
+	if err := upsert(model.SubscriptionStatusActive, start, end, now.Add(-30*time.Minute)); !errors.Is(err, ErrStaleSubscriptionUpdate) {
+		t.Fatalf("delayed renew err = %v, want ErrStaleSubscriptionUpdate", err)
+	}
+	// reconcile 重放：通知时间更晚但周期没有前进，不能把 REVOKED 改回有效。
+	if err := upsert(model.SubscriptionStatusActive, start, end, now.Add(time.Minute)); !errors.Is(err, ErrStaleSubscriptionUpdate) {
+		t.Fatalf("replayed renew err = %v, want ErrStaleSubscriptionUpdate", err)
+	}
+	// 真正的新周期（重新订阅）可以覆盖终态。
+	if err := upsert(model.SubscriptionStatusActive, end, end.Add(30*24*time.Hour), now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("new period: %v", err)
+	}
+	sub, err := dao.GetSubscriptionByOriginalTx(ctx, "it-stale-"+t.Name(), model.AppleEnvProduction)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if sub.Status != model.SubscriptionStatusActive || !sub.CurrentPeriodStart.Equal(end) {
+		t.Fatalf("unexpected row after new period: %+v", sub)
+	}
+}
+
+func TestIntegration_SubscriptionDAO_ConcurrentUpsertsSerializeStaleCheck(t *testing.T) {
+	pool := mustOpenIntegrationPool(t)
+	defer pool.Close()
+	userID, cleanup := withTestUser(t, pool)
+	defer cleanup()
+
+	dao := NewSubscriptionDAO(pool)
+	ctx := context.Background()
+	token, _, err := dao.GetOrCreateAccountToken(ctx, userID)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	start, end := now.Add(-24*time.Hour), now.Add(29*24*time.Hour)
+	upsertIn := func(qtx SubscriptionTx, originalTxID, status string, signedAt time.Time) error {
+		_, err := qtx.UpsertSubscriptionWithOwnershipCheck(ctx, model.SubscriptionUpsert{
+			UserID:                    userID,
+			AppAccountToken:           token,
+			Environment:               model.AppleEnvProduction,
+			OriginalTransactionID:     originalTxID,
+			LastTransactionID:         "txn-" + originalTxID,
+			PlanID:                    "pro_monthly",
+			ProviderProductID:         "com.app.pro.monthly",
+			Level:                     1,
+			Status:                    status,
+			AutoRenewStatus:           model.AutoRenewStatusOn,
+			CurrentPeriodStart:        start,
+			CurrentPeriodEnd:          end,
+			LastEventAt:               signedAt,
+			LastNotificationCreatedAt: &signedAt,
+		})
+		return err
+	}
+
+	// seeded=false 覆盖首投并发（行尚不存在），seeded=true 覆盖已有行上的并发投递。
+	for _, seeded := range []bool{false, true} {
+		originalTxID := fmt.Sprintf("it-race-%s-%v", t.Name(), seeded)
+		if seeded {
+			if err := dao.InTx(ctx, func(qtx SubscriptionTx) error {
+				return upsertIn(qtx, originalTxID, model.SubscriptionStatusActive, now.Add(-time.Hour))
+			}); err != nil {
+				t.Fatalf("seed: %v", err)
+			}
+		}
+
+		// 事务 A 写入较新的 REVOKED 后暂不提交；事务 B 带着较旧的通知并发到达，必须等 A 提交后再做乱序判断。
+		locked, release := make(chan struct{}), make(chan struct{})
+		errA := make(chan error, 1)
+		go func() {
+			errA <- dao.InTx(ctx, func(qtx SubscriptionTx) error {
+				if err := upsertIn(qtx, originalTxID, model.SubscriptionStatusRevoked, now); err != nil {
+					close(locked)
+					return err
+				}
+				close(locked)
+				<-release
+				return nil
+			})
+		}()
+		<-locked
+
+		errB := make(chan error, 1)
+		go func() {
+			errB <- dao.InTx(ctx, func(qtx SubscriptionTx) error {
+				return upsertIn(qtx, originalTxID, model.SubscriptionStatusActive, now.Add(-30*time.Minute))
+			})
+		}()
+		select {
+		case err := <-errB:
+			t.Fatalf("seeded=%v: concurrent upsert must wait for the row lock, got %v", seeded, err)
+		case <-time.After(200 * time.Millisecond):
+		}
+		close(release)
+
+		if err := <-errA; err != nil {
+			t.Fatalf("seeded=%v: tx A: %v", seeded, err)
+		}
+		if err := <-errB; !errors.Is(err, ErrStaleSubscriptionUpdate) {
+			t.Fatalf("seeded=%v: tx B err = %v, want ErrStaleSubscriptionUpdate", seeded, err)
+		}
+		sub, err := dao.GetSubscriptionByOriginalTx(ctx, originalTxID, model.AppleEnvProduction)
+		if err != nil {
+			t.Fatalf("seeded=%v: get: %v", seeded, err)
+		}
+		if sub.Status != model.SubscriptionStatusRevoked {
+			t.Fatalf("seeded=%v: status = %s, want REVOKED", seeded, sub.Status)
+		}
+	}
 }
 
 func TestIntegration_SubscriptionDAO_EventIdempotencyAndAtomicRollback(t *testing.T) {
@@ -188,7 +365,7 @@ func TestIntegration_SubscriptionDAO_EventIdempotencyAndAtomicRollback(t *testin
 	dao := NewSubscriptionDAO(pool)
 	ctx := context.Background()
 
-	tok, err := dao.GetOrCreateAccountToken(ctx, userID)
+	tok, _, err := dao.GetOrCreateAccountToken(ctx, userID)
 	if err != nil {
 		t.Fatalf("seed token: %v", err)
 	}
@@ -272,7 +449,7 @@ func TestIntegration_SubscriptionDAO_ListEntitlementOrderingAndEnvFilter(t *test
 	dao := NewSubscriptionDAO(pool)
 	ctx := context.Background()
 
-	tok, err := dao.GetOrCreateAccountToken(ctx, userID)
+	tok, _, err := dao.GetOrCreateAccountToken(ctx, userID)
 	if err != nil {
 		t.Fatalf("token: %v", err)
 	}
@@ -3,7 +3,9 @@ package dao
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -18,6 +20,7 @@ var ErrUserNotFound = pgx.ErrNoRows
 type UserDAO interface {
 	FindByID(ctx context.Context, id int) (*model.User, error)
 	ResolveAuthIdentity(ctx context.Context, identity model.AuthIdentity) (*model.UserInfo, error)
+	GetAccountState(ctx context.Context, userID int64) (model.AccountState, error)
 }
 
 type userDAO struct {
@@ -95,6 +98,19 @@ func (d *userDAO) ResolveAuthIdentity(ctx context.Context, identity model.AuthId
 	}, nil
 }
 
+// GetAccountState 读取账号状态与强制下线时间，供 access token 鉴权时校验；用户不存在时返回 model.ErrAccountNotFound。
+func (d *userDAO) GetAccountState(ctx context.Context, userID int64) (model.AccountState, error) {
+	row, err := d.queries.GetUserAccountState(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.AccountState{}, model.ErrAccountNotFound
+	}
+	if err != nil {
+		return model.AccountState{}, fmt.Errorf("user dao: get account state: %w", err)
+	}
+	return accountStateFromColumns(row.ID, row.Status, row.StatusReason, row.StatusUpdatedAt, row.StatusExpiresAt, row.SessionsRevokedAt), nil
+}
+
+// getUserInfoByAuthIdentity 查找已绑定的用户；账号处于非 ACTIVE 状态时返回 *model.AccountBlockedError，拒绝登录。
 func getUserInfoByAuthIdentity(ctx context.Context, q *db.Queries, identity model.AuthIdentity) (*model.UserInfo, error) {
 	user, err := q.GetUserInfoByAuthIdentity(ctx, db.GetUserInfoByAuthIdentityParams{
 		Provider:        identity.Provider,
@@ -103,6 +119,15 @@ func getUserInfoByAuthIdentity(ctx context.Context, q *db.Queries, identity mode
 	if err != nil {
 		return nil, err
 	}
+	state := model.AccountState{
+		UserID:          user.ID,
+		Status:          user.Status,
+		StatusReason:    user.StatusReason,
+		StatusExpiresAt: pgTimestamptzPtr(user.StatusExpiresAt),
+	}
+	if err := state.BlockedError(time.Now()); err != nil {
+		return nil, err
+	}
 
 	return &model.UserInfo{
 		ID:              strconv.Itoa(int(user.ID)),
@@ -123,3 +148,8 @@ func isUniqueViolation(err error) bool {
 	var pgErr *pgconn.PgError
 	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
@@ -12,7 +12,7 @@ import (
 )
 
 const getAppleEventByUUID = `-- name: GetAppleEventByUUID :one
-SELECT id, notification_uuid, notification_type, subtype, environment, user_id, app_account_token, original_transaction_id, transaction_id, web_order_line_item_id, processing_status, processing_error, raw_jws_sha256, decoded_payload, notification_created_at, created_at
+SELECT id, notification_uuid, notification_type, subtype, environment, user_id, app_account_token, original_transaction_id, transaction_id, web_order_line_item_id, processing_status, processing_error, raw_jws_sha256, decoded_payload, notification_created_at, created_at, product_id, renewal_product_id, offer_discount_type
 FROM apple_events
 WHERE notification_uuid = $1
 `
@@ -37,6 +37,9 @@ func (q *Queries) GetAppleEventByUUID(ctx context.Context, notificationUuid stri
 		&i.DecodedPayload,
 		&i.NotificationCreatedAt,
 		&i.CreatedAt,
+		&i.ProductID,
+		&i.RenewalProductID,
+		&i.OfferDiscountType,
 	)
 	return i, err
 }
@@ -56,9 +59,12 @@ INSERT INTO apple_events (
     processing_error,
     raw_jws_sha256,
     decoded_payload,
-    notification_created_at
+    notification_created_at,
+    product_id,
+    renewal_product_id,
+    offer_discount_type
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
 )
 ON CONFLICT (notification_uuid) DO NOTHING
 RETURNING id
@@ -79,6 +85,9 @@ type InsertAppleEventIfNotExistsParams struct {
 	RawJwsSha256          string
 	DecodedPayload        []byte
 	NotificationCreatedAt pgtype.Timestamptz
+	ProductID             string
+	RenewalProductID      string
+	OfferDiscountType     string
 }
 
 func (q *Queries) InsertAppleEventIfNotExists(ctx context.Context, arg InsertAppleEventIfNotExistsParams) (int64, error) {
@@ -97,14 +106,225 @@ func (q *Queries) InsertAppleEventIfNotExists(ctx context.Context, arg InsertApp
 		arg.RawJwsSha256,
 		arg.DecodedPayload,
 		arg.NotificationCreatedAt,
+		arg.ProductID,
+		arg.RenewalProductID,
+		arg.OfferDiscountType,
 	)
 	var id int64
 	err := row.Scan(&id)
 	return id, err
 }
 
+const listApplePurchaseTimelineByUser = `-- name: ListApplePurchaseTimelineByUser :many
+SELECT t.source,
+       t.id,
+       t.notification_type,
+       t.subtype,
+       t.environment,
+       t.occurred_at,
+       t.product_id,
+       t.renewal_product_id
+FROM (
+    SELECT 'EVENT'::text AS source,
+           e.id,
+           e.notification_type,
+           e.subtype,
+           e.environment,
+           COALESCE(e.notification_created_at, e.created_at)::timestamptz AS occurred_at,
+           COALESCE(NULLIF(e.product_id, ''), p.provider_product_id, s.provider_product_id, '')::text AS product_id,
+           e.renewal_product_id
+    FROM apple_events e
+    LEFT JOIN apple_purchases p
+           ON p.transaction_id = e.transaction_id
+          AND p.environment = e.environment
+          AND p.user_id = e.user_id
+    LEFT JOIN apple_subscriptions s
+           ON s.original_transaction_id = e.original_transaction_id
+          AND s.environment = e.environment
+          AND s.user_id = e.user_id
+    WHERE e.user_id = $1::bigint
+      AND e.processing_status = 'PROCESSED'
+    UNION ALL
+    SELECT 'PURCHASE'::text,
+           p.id,
+           'ONE_TIME_CHARGE'::text,
+           ''::text,
+           p.environment,
+           p.purchased_at,
+           p.provider_product_id,
+           ''::text
+    FROM apple_purchases p
+    WHERE p.user_id = $1::bigint
+      AND NOT EXISTS (
+          SELECT 1
+          FROM apple_events e
+          WHERE e.user_id = p.user_id
+            AND e.environment = p.environment
+            AND e.transaction_id = p.transaction_id
+            AND e.processing_status = 'PROCESSED'
+            AND e.notification_type = 'ONE_TIME_CHARGE'
+      )
+    UNION ALL
+    SELECT 'SUBSCRIPTION'::text,
+           s.id,
+           'SUBSCRIBED'::text,
+           ''::text,
+           s.environment,
+           s.created_at,
+           s.provider_product_id,
+           ''::text
+    FROM apple_subscriptions s
+    WHERE s.user_id = $1::bigint
+      AND NOT EXISTS (
+          SELECT 1
+          FROM apple_events e
+          WHERE e.user_id = s.user_id
+            AND e.environment = s.environment
+            AND e.original_transaction_id = s.original_transaction_id
+            AND e.processing_status = 'PROCESSED'
+            AND e.notification_type = 'SUBSCRIBED'
+      )
+) t
+WHERE t.environment = ANY($2::text[])
+  AND t.notification_type = ANY($3::text[])
+  AND (t.occurred_at, t.source, t.id) < ($4::timestamptz, $5::text, $6::bigint)
+ORDER BY t.occurred_at DESC, t.source DESC, t.id DESC
+LIMIT $7
+`
+
+type ListApplePurchaseTimelineByUserParams struct {
+	UserID            int64
+	Environments      []string
+	NotificationTypes []string
+	BeforeAt          pgtype.Timestamptz
+	BeforeSource      string
+	BeforeID          int64
+	BatchSize         int32
+}
+
+type ListApplePurchaseTimelineByUserRow struct {
+	Source           string
+	ID               int64
+	NotificationType string
+	Subtype          string
+	Environment      string
+	OccurredAt       pgtype.Timestamptz
+	ProductID        string
+	RenewalProductID string
+}
+
+func (q *Queries) ListApplePurchaseTimelineByUser(ctx context.Context, arg ListApplePurchaseTimelineByUserParams) ([]ListApplePurchaseTimelineByUserRow, error) {
+	rows, err := q.db.Query(ctx, listApplePurchaseTimelineByUser,
+		arg.UserID,
+		arg.Environments,
+		arg.NotificationTypes,
+		arg.BeforeAt,
+		arg.BeforeSource,
+		arg.BeforeID,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListApplePurchaseTimelineByUserRow
+	for rows.Next() {
+		var i ListApplePurchaseTimelineByUserRow
+		if err := rows.Scan(
+			&i.Source,
+			&i.ID,
+			&i.NotificationType,
+			&i.Subtype,
+			&i.Environment,
+			&i.OccurredAt,
+			&i.ProductID,
+			&i.RenewalProductID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAppleRevenueEvents = `-- name: ListAppleRevenueEvents :many
+SELECT e.id,
+       e.notification_type,
+       e.subtype,
+       e.environment,
+       e.original_transaction_id,
+       COALESCE(e.notification_created_at, e.created_at)::timestamptz AS occurred_at,
+       COALESCE(NULLIF(e.product_id, ''), s.provider_product_id, '')::text AS product_id,
+       e.offer_discount_type
+FROM apple_events e
+LEFT JOIN apple_subscriptions s
+       ON s.original_transaction_id = e.original_transaction_id
+      AND s.environment = e.environment
+WHERE e.original_transaction_id <> ''
+  AND e.processing_status <> 'IGNORED_DUPLICATE'
+  AND COALESCE(e.notification_created_at, e.created_at) < $1::timestamptz
+  AND (COALESCE(e.notification_created_at, e.created_at), e.id) > ($2::timestamptz, $3::bigint)
+ORDER BY COALESCE(e.notification_created_at, e.created_at), e.id
+LIMIT $4
+`
+
+type ListAppleRevenueEventsParams struct {
+	Until     pgtype.Timestamptz
+	AfterAt   pgtype.Timestamptz
+	AfterID   int64
+	BatchSize int32
+}
+
+type ListAppleRevenueEventsRow struct {
+	ID                    int64
+	NotificationType      string
+	Subtype               string
+	Environment           string
+	OriginalTransactionID string
+	OccurredAt            pgtype.Timestamptz
+	ProductID             string
+	OfferDiscountType     string
+}
+
+func (q *Queries) ListAppleRevenueEvents(ctx context.Context, arg ListAppleRevenueEventsParams) ([]ListAppleRevenueEventsRow, error) {
+	rows, err := q.db.Query(ctx, listAppleRevenueEvents,
+		arg.Until,
+		arg.AfterAt,
+		arg.AfterID,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAppleRevenueEventsRow
+	for rows.Next() {
+		var i ListAppleRevenueEventsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.NotificationType,
+			&i.Subtype,
+			&i.Environment,
+			&i.OriginalTransactionID,
+			&i.OccurredAt,
+			&i.ProductID,
+			&i.OfferDiscountType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listPendingAppleEvents = `-- name: ListPendingAppleEvents :many
-SELECT id, notification_uuid, notification_type, subtype, environment, user_id, app_account_token, original_transaction_id, transaction_id, web_order_line_item_id, processing_status, processing_error, raw_jws_sha256, decoded_payload, notification_created_at, created_at
+SELECT id, notification_uuid, notification_type, subtype, environment, user_id, app_account_token, original_transaction_id, transaction_id, web_order_line_item_id, processing_status, processing_error, raw_jws_sha256, decoded_payload, notification_created_at, created_at, product_id, renewal_product_id, offer_discount_type
 FROM apple_events
 WHERE processing_status = $1
 ORDER BY created_at ASC
@@ -142,6 +362,9 @@ func (q *Queries) ListPendingAppleEvents(ctx context.Context, arg ListPendingApp
 			&i.DecodedPayload,
 			&i.NotificationCreatedAt,
 			&i.CreatedAt,
+			&i.ProductID,
+			&i.RenewalProductID,
+			&i.OfferDiscountType,
 		); err != nil {
 			return nil, err
 		}
@@ -152,3 +375,181 @@ func (q *Queries) ListPendingAppleEvents(ctx context.Context, arg ListPendingApp
 	}
 	return items, nil
 }
+
+const listRecentAppleEventsByUser = `-- name: ListRecentAppleEventsByUser :many
+SELECT id, notification_uuid, notification_type, subtype, environment, user_id, app_account_token, original_transaction_id, transaction_id, web_order_line_item_id, processing_status, processing_error, raw_jws_sha256, decoded_payload, notification_created_at, created_at, product_id, renewal_product_id, offer_discount_type
+FROM apple_events
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2
+`
+
+type ListRecentAppleEventsByUserParams struct {
+	UserID pgtype.Int8
+	Limit  int32
+}
+
+func (q *Queries) ListRecentAppleEventsByUser(ctx context.Context, arg ListRecentAppleEventsByUserParams) ([]AppleEvent, error) {
+	rows, err := q.db.Query(ctx, listRecentAppleEventsByUser, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AppleEvent
+	for rows.Next() {
+		var i AppleEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.NotificationUuid,
+			&i.NotificationType,
+			&i.Subtype,
+			&i.Environment,
+			&i.UserID,
+			&i.AppAccountToken,
+			&i.OriginalTransactionID,
+			&i.TransactionID,
+			&i.WebOrderLineItemID,
+			&i.ProcessingStatus,
+			&i.ProcessingError,
+			&i.RawJwsSha256,
+			&i.DecodedPayload,
+			&i.NotificationCreatedAt,
+			&i.CreatedAt,
+			&i.ProductID,
+			&i.RenewalProductID,
+			&i.OfferDiscountType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRedrivableAppleEvents = `-- name: ListRedrivableAppleEvents :many
+SELECT e.id, e.notification_uuid, e.notification_type, e.subtype, e.environment, e.user_id, e.app_account_token, e.original_transaction_id, e.transaction_id, e.web_order_line_item_id, e.processing_status, e.processing_error, e.raw_jws_sha256, e.decoded_payload, e.notification_created_at, e.created_at, e.product_id, e.renewal_product_id, e.offer_discount_type
+FROM apple_events e
+JOIN apple_account_tokens t ON t.token = e.app_account_token
+WHERE e.processing_status = 'PENDING_USER_BINDING'
+  AND (
+      e.decoded_payload IS NOT NULL
+      OR EXISTS (
+          SELECT 1
+          FROM apple_payload_archives a
+          WHERE a.event_id = e.id
+            AND a.source = 'NOTIFICATION'
+      )
+  )
+  AND ($1::uuid IS NULL OR e.app_account_token = $1::uuid)
+ORDER BY e.notification_created_at ASC NULLS LAST, e.id ASC
+LIMIT $2
+`
+
+type ListRedrivableAppleEventsParams struct {
+	AppAccountToken pgtype.UUID
+	BatchSize       int32
+}
+
+func (q *Queries) ListRedrivableAppleEvents(ctx context.Context, arg ListRedrivableAppleEventsParams) ([]AppleEvent, error) {
+	rows, err := q.db.Query(ctx, listRedrivableAppleEvents, arg.AppAccountToken, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AppleEvent
+	for rows.Next() {
+		var i AppleEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.NotificationUuid,
+			&i.NotificationType,
+			&i.Subtype,
+			&i.Environment,
+			&i.UserID,
+			&i.AppAccountToken,
+			&i.OriginalTransactionID,
+			&i.TransactionID,
+			&i.WebOrderLineItemID,
+			&i.ProcessingStatus,
+			&i.ProcessingError,
+			&i.RawJwsSha256,
+			&i.DecodedPayload,
+			&i.NotificationCreatedAt,
+			&i.CreatedAt,
+			&i.ProductID,
+			&i.RenewalProductID,
+			&i.OfferDiscountType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockPendingAppleEvent = `-- name: LockPendingAppleEvent :one
+SELECT id, notification_uuid, notification_type, subtype, environment, user_id, app_account_token, original_transaction_id, transaction_id, web_order_line_item_id, processing_status, processing_error, raw_jws_sha256, decoded_payload, notification_created_at, created_at, product_id, renewal_product_id, offer_discount_type
+FROM apple_events
+WHERE id = $1
+  AND processing_status = 'PENDING_USER_BINDING'
+FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) LockPendingAppleEvent(ctx context.Context, id int64) (AppleEvent, error) {
+	row := q.db.QueryRow(ctx, lockPendingAppleEvent, id)
+	var i AppleEvent
+	err := row.Scan(
+		&i.ID,
+		&i.NotificationUuid,
+		&i.NotificationType,
+		&i.Subtype,
+		&i.Environment,
+		&i.UserID,
+		&i.AppAccountToken,
+		&i.OriginalTransactionID,
+		&i.TransactionID,
+		&i.WebOrderLineItemID,
+		&i.ProcessingStatus,
+		&i.ProcessingError,
+		&i.RawJwsSha256,
+		&i.DecodedPayload,
+		&i.NotificationCreatedAt,
+		&i.CreatedAt,
+		&i.ProductID,
+		&i.RenewalProductID,
+		&i.OfferDiscountType,
+	)
+	return i, err
+}
+
+const updateAppleEventProcessing = `-- name: UpdateAppleEventProcessing :exec
+UPDATE apple_events
+SET processing_status = $2,
+    processing_error  = $3,
+    user_id           = $4,
+    decoded_payload   = NULL
+WHERE id = $1
+`
+
+type UpdateAppleEventProcessingParams struct {
+	ID               int64
+	ProcessingStatus string
+	ProcessingError  string
+	UserID           pgtype.Int8
+}
+
+func (q *Queries) UpdateAppleEventProcessing(ctx context.Context, arg UpdateAppleEventProcessingParams) error {
+	_, err := q.db.Exec(ctx, updateAppleEventProcessing,
+		arg.ID,
+		arg.ProcessingStatus,
+		arg.ProcessingError,
+		arg.UserID,
+	)
+	return err
+}
@@ -12,7 +12,7 @@ import (
 )
 
 const getSubscriptionByOriginalTx = `-- name: GetSubscriptionByOriginalTx :one
-SELECT id, user_id, app_account_token, environment, original_transaction_id, last_transaction_id, web_order_line_item_id, plan_id, provider_product_id, subscription_group_id, level, status, auto_renew_status, current_period_start, current_period_end, grace_period_expires_at, last_event_at, last_notification_created_at, last_payload_hash, last_transaction_snapshot, created_at, updated_at
+SELECT id, user_id, app_account_token, environment, original_transaction_id, last_transaction_id, web_order_line_item_id, plan_id, provider_product_id, subscription_group_id, level, status, auto_renew_status, current_period_start, current_period_end, grace_period_expires_at, last_event_at, last_notification_created_at, last_payload_hash, last_transaction_snapshot, created_at, updated_at, pending_product_id, pending_plan_id, pending_level, billing_retry, expiration_intent
 FROM apple_subscriptions
 WHERE original_transaction_id = $1
   AND environment = $2
@@ -49,12 +49,134 @@ func (q *Queries) GetSubscriptionByOriginalTx(ctx context.Context, arg GetSubscr
 		&i.LastTransactionSnapshot,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.PendingProductID,
+		&i.PendingPlanID,
+		&i.PendingLevel,
+		&i.BillingRetry,
+		&i.ExpirationIntent,
 	)
 	return i, err
 }
 
+const getSubscriptionByOriginalTxForUpdate = `-- name: GetSubscriptionByOriginalTxForUpdate :one
+SELECT id, user_id, app_account_token, environment, original_transaction_id, last_transaction_id, web_order_line_item_id, plan_id, provider_product_id, subscription_group_id, level, status, auto_renew_status, current_period_start, current_period_end, grace_period_expires_at, last_event_at, last_notification_created_at, last_payload_hash, last_transaction_snapshot, created_at, updated_at, pending_product_id, pending_plan_id, pending_level, billing_retry, expiration_intent
+FROM apple_subscriptions
+WHERE original_transaction_id = $1
+  AND environment = $2
+FOR UPDATE
+`
+
+type GetSubscriptionByOriginalTxForUpdateParams struct {
+	OriginalTransactionID string
+	Environment           string
+}
+
+func (q *Queries) GetSubscriptionByOriginalTxForUpdate(ctx context.Context, arg GetSubscriptionByOriginalTxForUpdateParams) (AppleSubscription, error) {
+	row := q.db.QueryRow(ctx, getSubscriptionByOriginalTxForUpdate, arg.OriginalTransactionID, arg.Environment)
+	var i AppleSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.AppAccountToken,
+		&i.Environment,
+		&i.OriginalTransactionID,
+		&i.LastTransactionID,
+		&i.WebOrderLineItemID,
+		&i.PlanID,
+		&i.ProviderProductID,
+		&i.SubscriptionGroupID,
+		&i.Level,
+		&i.Status,
+		&i.AutoRenewStatus,
+		&i.CurrentPeriodStart,
+		&i.CurrentPeriodEnd,
+		&i.GracePeriodExpiresAt,
+		&i.LastEventAt,
+		&i.LastNotificationCreatedAt,
+		&i.LastPayloadHash,
+		&i.LastTransactionSnapshot,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.PendingProductID,
+		&i.PendingPlanID,
+		&i.PendingLevel,
+		&i.BillingRetry,
+		&i.ExpirationIntent,
+	)
+	return i, err
+}
+
+const lockSubscriptionOriginalTx = `-- name: LockSubscriptionOriginalTx :exec
+SELECT pg_advisory_xact_lock(hashtextextended('apple_subscriptions:' || $1::text, hashtext($2::text)))
+`
+
+type LockSubscriptionOriginalTxParams struct {
+	Environment           string
+	OriginalTransactionID string
+}
+
+func (q *Queries) LockSubscriptionOriginalTx(ctx context.Context, arg LockSubscriptionOriginalTxParams) error {
+	_, err := q.db.Exec(ctx, lockSubscriptionOriginalTx, arg.Environment, arg.OriginalTransactionID)
+	return err
+}
+
+const listSubscriptionsByUser = `-- name: ListSubscriptionsByUser :many
+SELECT id, user_id, app_account_token, environment, original_transaction_id, last_transaction_id, web_order_line_item_id, plan_id, provider_product_id, subscription_group_id, level, status, auto_renew_status, current_period_start, current_period_end, grace_period_expires_at, last_event_at, last_notification_created_at, last_payload_hash, last_transaction_snapshot, created_at, updated_at, pending_product_id, pending_plan_id, pending_level, billing_retry, expiration_intent
+FROM apple_subscriptions
+WHERE user_id = $1
+ORDER BY last_event_at DESC, id DESC
+`
+
+func (q *Queries) ListSubscriptionsByUser(ctx context.Context, userID int64) ([]AppleSubscription, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AppleSubscription
+	for rows.Next() {
+		var i AppleSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.AppAccountToken,
+			&i.Environment,
+			&i.OriginalTransactionID,
+			&i.LastTransactionID,
+			&i.WebOrderLineItemID,
+			&i.PlanID,
+			&i.ProviderProductID,
+			&i.SubscriptionGroupID,
+			&i.Level,
+			&i.Status,
+			&i.AutoRenewStatus,
+			&i.CurrentPeriodStart,
+			&i.CurrentPeriodEnd,
+			&i.GracePeriodExpiresAt,
+			&i.LastEventAt,
+			&i.LastNotificationCreatedAt,
+			&i.LastPayloadHash,
+			&i.LastTransactionSnapshot,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.PendingProductID,
+			&i.PendingPlanID,
+			&i.PendingLevel,
+			&i.BillingRetry,
+			&i.ExpirationIntent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listSubscriptionsForUserEntitlement = `-- name: ListSubscriptionsForUserEntitlement :many
-SELECT id, user_id, app_account_token, environment, original_transaction_id, last_transaction_id, web_order_line_item_id, plan_id, provider_product_id, subscription_group_id, level, status, auto_renew_status, current_period_start, current_period_end, grace_period_expires_at, last_event_at, last_notification_created_at, last_payload_hash, last_transaction_snapshot, created_at, updated_at
+SELECT id, user_id, app_account_token, environment, original_transaction_id, last_transaction_id, web_order_line_item_id, plan_id, provider_product_id, subscription_group_id, level, status, auto_renew_status, current_period_start, current_period_end, grace_period_expires_at, last_event_at, last_notification_created_at, last_payload_hash, last_transaction_snapshot, created_at, updated_at, pending_product_id, pending_plan_id, pending_level, billing_retry, expiration_intent
 FROM apple_subscriptions
 WHERE user_id = $1
   AND environment = ANY($2::text[])
@@ -104,6 +226,11 @@ func (q *Queries) ListSubscriptionsForUserEntitlement(ctx context.Context, arg L
 			&i.LastTransactionSnapshot,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.PendingProductID,
+			&i.PendingPlanID,
+			&i.PendingLevel,
+			&i.BillingRetry,
+			&i.ExpirationIntent,
 		); err != nil {
 			return nil, err
 		}
@@ -115,6 +242,98 @@ func (q *Queries) ListSubscriptionsForUserEntitlement(ctx context.Context, arg L
 	return items, nil
 }
 
+const lockSubscriptionByID = `-- name: LockSubscriptionByID :one
+SELECT id, user_id, app_account_token, environment, original_transaction_id, last_transaction_id, web_order_line_item_id, plan_id, provider_product_id, subscription_group_id, level, status, auto_renew_status, current_period_start, current_period_end, grace_period_expires_at, last_event_at, last_notification_created_at, last_payload_hash, last_transaction_snapshot, created_at, updated_at, pending_product_id, pending_plan_id, pending_level, billing_retry, expiration_intent
+FROM apple_subscriptions
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockSubscriptionByID(ctx context.Context, id int64) (AppleSubscription, error) {
+	row := q.db.QueryRow(ctx, lockSubscriptionByID, id)
+	var i AppleSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.AppAccountToken,
+		&i.Environment,
+		&i.OriginalTransactionID,
+		&i.LastTransactionID,
+		&i.WebOrderLineItemID,
+		&i.PlanID,
+		&i.ProviderProductID,
+		&i.SubscriptionGroupID,
+		&i.Level,
+		&i.Status,
+		&i.AutoRenewStatus,
+		&i.CurrentPeriodStart,
+		&i.CurrentPeriodEnd,
+		&i.GracePeriodExpiresAt,
+		&i.LastEventAt,
+		&i.LastNotificationCreatedAt,
+		&i.LastPayloadHash,
+		&i.LastTransactionSnapshot,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.PendingProductID,
+		&i.PendingPlanID,
+		&i.PendingLevel,
+		&i.BillingRetry,
+		&i.ExpirationIntent,
+	)
+	return i, err
+}
+
+const reassignSubscriptionOwner = `-- name: ReassignSubscriptionOwner :one
+UPDATE apple_subscriptions
+SET user_id           = $1,
+    app_account_token = $2,
+    updated_at        = now()
+WHERE id = $3
+RETURNING id, user_id, app_account_token, environment, original_transaction_id, last_transaction_id, web_order_line_item_id, plan_id, provider_product_id, subscription_group_id, level, status, auto_renew_status, current_period_start, current_period_end, grace_period_expires_at, last_event_at, last_notification_created_at, last_payload_hash, last_transaction_snapshot, created_at, updated_at, pending_product_id, pending_plan_id, pending_level, billing_retry, expiration_intent
+`
+
+type ReassignSubscriptionOwnerParams struct {
+	UserID          int64
+	AppAccountToken pgtype.UUID
+	ID              int64
+}
+
+func (q *Queries) ReassignSubscriptionOwner(ctx context.Context, arg ReassignSubscriptionOwnerParams) (AppleSubscription, error) {
+	row := q.db.QueryRow(ctx, reassignSubscriptionOwner, arg.UserID, arg.AppAccountToken, arg.ID)
+	var i AppleSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.AppAccountToken,
+		&i.Environment,
+		&i.OriginalTransactionID,
+		&i.LastTransactionID,
+		&i.WebOrderLineItemID,
+		&i.PlanID,
+		&i.ProviderProductID,
+		&i.SubscriptionGroupID,
+		&i.Level,
+		&i.Status,
+		&i.AutoRenewStatus,
+		&i.CurrentPeriodStart,
+		&i.CurrentPeriodEnd,
+		&i.GracePeriodExpiresAt,
+		&i.LastEventAt,
+		&i.LastNotificationCreatedAt,
+		&i.LastPayloadHash,
+		&i.LastTransactionSnapshot,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.PendingProductID,
+		&i.PendingPlanID,
+		&i.PendingLevel,
+		&i.BillingRetry,
+		&i.ExpirationIntent,
+	)
+	return i, err
+}
+
 const upsertSubscription = `-- name: UpsertSubscription :one
 INSERT INTO apple_subscriptions (
     user_id,
@@ -135,10 +354,16 @@ INSERT INTO apple_subscriptions (
     last_event_at,
     last_notification_created_at,
     last_payload_hash,
-    last_transaction_snapshot
+    last_transaction_snapshot,
+    pending_product_id,
+    pending_plan_id,
+    pending_level,
+    billing_retry,
+    expiration_intent
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
-    $11, $12, $13, $14, $15, $16, $17, $18, $19
+    $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+    $21, $22, $23, $24
 )
 ON CONFLICT (original_transaction_id, environment) DO UPDATE SET
     last_transaction_id          = EXCLUDED.last_transaction_id,
@@ -156,8 +381,13 @@ ON CONFLICT (original_transaction_id, environment) DO UPDATE SET
     last_notification_created_at = EXCLUDED.last_notification_created_at,
     last_payload_hash            = EXCLUDED.last_payload_hash,
     last_transaction_snapshot    = EXCLUDED.last_transaction_snapshot,
+    pending_product_id           = EXCLUDED.pending_product_id,
+    pending_plan_id              = EXCLUDED.pending_plan_id,
+    pending_level                = EXCLUDED.pending_level,
+    billing_retry                = EXCLUDED.billing_retry,
+    expiration_intent            = EXCLUDED.expiration_intent,
     updated_at                   = now()
-RETURNING id, user_id, app_account_token, environment, original_transaction_id, last_transaction_id, web_order_line_item_id, plan_id, provider_product_id, subscription_group_id, level, status, auto_renew_status, current_period_start, current_period_end, grace_period_expires_at, last_event_at, last_notification_created_at, last_payload_hash, last_transaction_snapshot, created_at, updated_at
+RETURNING id, user_id, app_account_token, environment, original_transaction_id, last_transaction_id, web_order_line_item_id, plan_id, provider_product_id, subscription_group_id, level, status, auto_renew_status, current_period_start, current_period_end, grace_period_expires_at, last_event_at, last_notification_created_at, last_payload_hash, last_transaction_snapshot, created_at, updated_at, pending_product_id, pending_plan_id, pending_level, billing_retry, expiration_intent
 `
 
 type UpsertSubscriptionParams struct {
@@ -180,6 +410,11 @@ type UpsertSubscriptionParams struct {
 	LastNotificationCreatedAt pgtype.Timestamptz
 	LastPayloadHash           string
 	LastTransactionSnapshot   []byte
+	PendingProductID          string
+	PendingPlanID             string
+	PendingLevel              int32
+	BillingRetry              bool
+	ExpirationIntent          int32
 }
 
 func (q *Queries) UpsertSubscription(ctx context.Context, arg UpsertSubscriptionParams) (AppleSubscription, error) {
@@ -203,6 +438,11 @@ func (q *Queries) UpsertSubscription(ctx context.Context, arg UpsertSubscription
 		arg.LastNotificationCreatedAt,
 		arg.LastPayloadHash,
 		arg.LastTransactionSnapshot,
+		arg.PendingProductID,
+		arg.PendingPlanID,
+		arg.PendingLevel,
+		arg.BillingRetry,
+		arg.ExpirationIntent,
 	)
 	var i AppleSubscription
 	err := row.Scan(
@@ -228,6 +468,11 @@ func (q *Queries) UpsertSubscription(ctx context.Context, arg UpsertSubscription
 		&i.LastTransactionSnapshot,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.PendingProductID,
+		&i.PendingPlanID,
+		&i.PendingLevel,
+		&i.BillingRetry,
+		&i.ExpirationIntent,
 	)
 	return i, err
 }
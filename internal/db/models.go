@@ -16,6 +16,25 @@ type AppleAccountToken struct {
 	UpdatedAt pgtype.Timestamptz
 }
 
+type AppleConsumptionRequest struct {
+	ID                    int64
+	EventID               int64
+	UserID                pgtype.Int8
+	Environment           string
+	OriginalTransactionID string
+	TransactionID         string
+	AppAccountToken       pgtype.UUID
+	Status                string
+	Attempts              int32
+	NextAttemptAt         pgtype.Timestamptz
+	DeadlineAt            pgtype.Timestamptz
+	LastError             string
+	SentPayload           []byte
+	SentAt                pgtype.Timestamptz
+	CreatedAt             pgtype.Timestamptz
+	UpdatedAt             pgtype.Timestamptz
+}
+
 type AppleEvent struct {
 	ID                    int64
 	NotificationUuid      string
@@ -33,6 +52,96 @@ type AppleEvent struct {
 	DecodedPayload        []byte
 	NotificationCreatedAt pgtype.Timestamptz
 	CreatedAt             pgtype.Timestamptz
+	ProductID             string
+	RenewalProductID      string
+	OfferDiscountType     string
+}
+
+type AppleFamilyShare struct {
+	ID                    int64
+	UserID                int64
+	Environment           string
+	OriginalTransactionID string
+	LastTransactionID     string
+	PlanID                string
+	ProviderProductID     string
+	Level                 int32
+	Status                string
+	CurrentPeriodEnd      pgtype.Timestamptz
+	RevokedAt             pgtype.Timestamptz
+	LastEventAt           pgtype.Timestamptz
+	CreatedAt             pgtype.Timestamptz
+	UpdatedAt             pgtype.Timestamptz
+}
+
+type AppleOfferSignature struct {
+	ID              int64
+	UserID          int64
+	OfferID         string
+	OfferType       string
+	ProductID       string
+	AppAccountToken string
+	Nonce           string
+	SignedAt        pgtype.Timestamptz
+	CreatedAt       pgtype.Timestamptz
+}
+
+type ApplePayloadArchive struct {
+	ID                    int64
+	Source                string
+	EventID               pgtype.Int8
+	UserID                pgtype.Int8
+	Environment           string
+	OriginalTransactionID string
+	TransactionID         string
+	KeyID                 string
+	WrappedKey            []byte
+	Nonce                 []byte
+	Ciphertext            []byte
+	CreatedAt             pgtype.Timestamptz
+	ExpiresAt             pgtype.Timestamptz
+}
+
+type AppleProduct struct {
+	ID                  int64
+	PlanID              string
+	ProductID           string
+	Environment         string
+	Type                string
+	Level               int32
+	Credits             int64
+	SubscriptionGroupID string
+	DisplayName         string
+	Description         string
+	SortOrder           int32
+	Active              bool
+	CreatedAt           pgtype.Timestamptz
+	UpdatedAt           pgtype.Timestamptz
+	Localizations       []byte
+	Recommended         bool
+	PriceMilliunits     int64
+	Currency            string
+	BillingPeriod       string
+}
+
+type ApplePurchase struct {
+	ID                    int64
+	UserID                int64
+	Environment           string
+	TransactionID         string
+	OriginalTransactionID string
+	PlanID                string
+	ProviderProductID     string
+	ProductType           string
+	Quantity              int32
+	Level                 int32
+	Credits               int64
+	CreditBucketID        pgtype.Int8
+	Status                string
+	PurchasedAt           pgtype.Timestamptz
+	RevokedAt             pgtype.Timestamptz
+	CreatedAt             pgtype.Timestamptz
+	UpdatedAt             pgtype.Timestamptz
 }
 
 type AppleSubscription struct {
@@ -58,6 +167,19 @@ type AppleSubscription struct {
 	LastTransactionSnapshot   []byte
 	CreatedAt                 pgtype.Timestamptz
 	UpdatedAt                 pgtype.Timestamptz
+	PendingProductID          string
+	PendingPlanID             string
+	PendingLevel              int32
+	BillingRetry              bool
+	ExpirationIntent          int32
+}
+
+type AppleSubscriptionStatusSync struct {
+	SubscriptionID int64
+	SyncedAt       pgtype.Timestamptz
+	AppleStatus    int32
+	LastError      string
+	UpdatedAt      pgtype.Timestamptz
 }
 
 type AuthIdentity struct {
@@ -69,9 +191,235 @@ type AuthIdentity struct {
 	UpdatedAt       pgtype.Timestamptz
 }
 
-type User struct {
+type CompEntitlement struct {
 	ID        int64
-	Name      string
+	UserID    int64
+	PlanID    string
+	Level     int32
+	Source    string
+	Reference string
+	StartsAt  pgtype.Timestamptz
+	EndsAt    pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+	Note      string
+	RevokedAt pgtype.Timestamptz
+}
+
+type CreditBucket struct {
+	ID        int64
+	UserID    int64
+	Source    string
+	Amount    int64
+	Remaining int64
+	ExpiresAt pgtype.Timestamptz
+	Reference string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+}
+
+type CreditLedger struct {
+	ID        int64
+	UserID    int64
+	BucketID  pgtype.Int8
+	EntryType string
+	Delta     int64
+	Reference string
+	CreatedAt pgtype.Timestamptz
+}
+
+type EntitlementWebhookDelivery struct {
+	ID             int64
+	EventID        int64
+	SubscriberID   int64
+	Status         string
+	Attempts       int32
+	NextAttemptAt  pgtype.Timestamptz
+	LastStatusCode int32
+	LastError      string
+	DeliveredAt    pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+}
+
+type EntitlementWebhookEvent struct {
+	ID        int64
+	EventType string
+	UserID    int64
+	Payload   []byte
+	CreatedAt pgtype.Timestamptz
+}
+
+type EntitlementWebhookSubscriber struct {
+	ID         int64
+	Name       string
+	Url        string
+	Secret     string
+	EventTypes []string
+	Active     bool
+	CreatedAt  pgtype.Timestamptz
+	UpdatedAt  pgtype.Timestamptz
+}
+
+type GooglePlayEvent struct {
+	ID               int64
+	MessageID        string
+	NotificationKind string
+	NotificationType int32
+	PackageName      string
+	PurchaseToken    string
+	ProductID        string
+	UserID           pgtype.Int8
+	ProcessingStatus string
+	ProcessingError  string
+	EventTime        pgtype.Timestamptz
+	CreatedAt        pgtype.Timestamptz
+}
+
+type GooglePlayPurchase struct {
+	ID                int64
+	UserID            int64
+	PackageName       string
+	PurchaseToken     string
+	OrderID           string
+	PlanID            string
+	ProviderProductID string
+	ProductType       string
+	Quantity          int32
+	Level             int32
+	Credits           int64
+	CreditBucketID    pgtype.Int8
+	Status            string
+	TestPurchase      bool
+	PurchasedAt       pgtype.Timestamptz
+	RevokedAt         pgtype.Timestamptz
+	CreatedAt         pgtype.Timestamptz
+	UpdatedAt         pgtype.Timestamptz
+}
+
+type GooglePlaySubscription struct {
+	ID                  int64
+	UserID              int64
+	PackageName         string
+	PurchaseToken       string
+	LinkedPurchaseToken string
+	LatestOrderID       string
+	PlanID              string
+	ProviderProductID   string
+	Level               int32
+	Status              string
+	AutoRenewStatus     string
+	TestPurchase        bool
+	CurrentPeriodStart  pgtype.Timestamptz
+	CurrentPeriodEnd    pgtype.Timestamptz
+	LastEventAt         pgtype.Timestamptz
+	CreatedAt           pgtype.Timestamptz
+	UpdatedAt           pgtype.Timestamptz
+}
+
+type PaywallExposure struct {
+	ID             int64
+	ExperimentID   string
+	VariantID      string
+	UserID         int64
+	Platform       string
+	FirstExposedAt pgtype.Timestamptz
+	LastExposedAt  pgtype.Timestamptz
+}
+
+type PromoCode struct {
+	Code           string
+	PlanID         string
+	Level          int32
+	CompDays       int32
+	MaxRedemptions int32
+	RedeemedCount  int32
+	ExpiresAt      pgtype.Timestamptz
+	Active         bool
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+}
+
+type PromoCodeRedemption struct {
+	ID                int64
+	Code              string
+	UserID            int64
+	CompEntitlementID int64
+	CreatedAt         pgtype.Timestamptz
+}
+
+type ReferralCode struct {
+	UserID    int64
+	Code      string
+	CreatedAt pgtype.Timestamptz
+}
+
+type ReferralRedemption struct {
+	ID                    int64
+	InviterUserID         int64
+	InviteeUserID         int64
+	Code                  string
+	DeviceKey             string
+	InviterRewardCredits  int64
+	InviterRewardCompDays int32
+	InviteeRewardCredits  int64
+	InviteeRewardCompDays int32
+	CreatedAt             pgtype.Timestamptz
+}
+
+type StripeCustomer struct {
+	ID         int64
+	UserID     int64
+	CustomerID string
+	CreatedAt  pgtype.Timestamptz
+}
+
+type StripeEvent struct {
+	ID               int64
+	EventID          string
+	EventType        string
+	CustomerID       string
+	SubscriptionID   string
+	UserID           pgtype.Int8
+	ProcessingStatus string
+	ProcessingError  string
+	EventCreatedAt   pgtype.Timestamptz
+	CreatedAt        pgtype.Timestamptz
+}
+
+type StripeSubscription struct {
+	ID                 int64
+	UserID             int64
+	CustomerID         string
+	SubscriptionID     string
+	PriceID            string
+	PlanID             string
+	Level              int32
+	Status             string
+	StripeStatus       string
+	AutoRenewStatus    string
+	CurrentPeriodStart pgtype.Timestamptz
+	CurrentPeriodEnd   pgtype.Timestamptz
+	LastEventAt        pgtype.Timestamptz
+	CreatedAt          pgtype.Timestamptz
+	UpdatedAt          pgtype.Timestamptz
+}
+
+type User struct {
+	ID                int64
+	Name              string
+	CreatedAt         pgtype.Timestamptz
+	UpdatedAt         pgtype.Timestamptz
+	Status            string
+	StatusReason      string
+	StatusUpdatedAt   pgtype.Timestamptz
+	SessionsRevokedAt pgtype.Timestamptz
+	StatusExpiresAt   pgtype.Timestamptz
+}
+
+type UserSetting struct {
+	UserID    int64
+	Key       string
+	Value     []byte
 	CreatedAt pgtype.Timestamptz
 	UpdatedAt pgtype.Timestamptz
 }
@@ -11,19 +11,129 @@ import (
 )
 
 type Querier interface {
+	ClaimDueAppleConsumptionRequests(ctx context.Context, arg ClaimDueAppleConsumptionRequestsParams) ([]AppleConsumptionRequest, error)
+	ClaimDueEntitlementWebhookDeliveries(ctx context.Context, arg ClaimDueEntitlementWebhookDeliveriesParams) ([]ClaimDueEntitlementWebhookDeliveriesRow, error)
+	CountAppleOfferSignatures(ctx context.Context, arg CountAppleOfferSignaturesParams) (int64, error)
+	CountAppleProducts(ctx context.Context) (int64, error)
+	CountReferralRedemptionsByInviter(ctx context.Context, inviterUserID int64) (int64, error)
 	CreateAuthIdentity(ctx context.Context, arg CreateAuthIdentityParams) (CreateAuthIdentityRow, error)
 	CreateUser(ctx context.Context, name string) (CreateUserRow, error)
+	DeactivatePromoCode(ctx context.Context, code string) (PromoCode, error)
+	DeleteExpiredApplePayloadArchives(ctx context.Context, arg DeleteExpiredApplePayloadArchivesParams) (int64, error)
+	DeleteUserSetting(ctx context.Context, arg DeleteUserSettingParams) error
 	GetAppleAccountTokenByToken(ctx context.Context, token pgtype.UUID) (AppleAccountToken, error)
 	GetAppleAccountTokenByUser(ctx context.Context, userID int64) (AppleAccountToken, error)
+	GetAppleConsumptionSnapshot(ctx context.Context, arg GetAppleConsumptionSnapshotParams) (GetAppleConsumptionSnapshotRow, error)
 	GetAppleEventByUUID(ctx context.Context, notificationUuid string) (AppleEvent, error)
+	GetApplePayloadArchiveByEvent(ctx context.Context, eventID pgtype.Int8) (ApplePayloadArchive, error)
+	GetAppleProduct(ctx context.Context, id int64) (AppleProduct, error)
+	GetLatestCompEntitlementEnd(ctx context.Context, userID int64) (pgtype.Timestamptz, error)
+	GetReferralCodeByUser(ctx context.Context, userID int64) (ReferralCode, error)
+	GetReferralRedemptionByInvitee(ctx context.Context, inviteeUserID int64) (ReferralRedemption, error)
+	GetReferralStatsByInviter(ctx context.Context, inviterUserID int64) (GetReferralStatsByInviterRow, error)
+	GetStripeCustomerByCustomerID(ctx context.Context, customerID string) (StripeCustomer, error)
+	GetStripeCustomerByUser(ctx context.Context, userID int64) (StripeCustomer, error)
 	GetSubscriptionByOriginalTx(ctx context.Context, arg GetSubscriptionByOriginalTxParams) (AppleSubscription, error)
+	GetSubscriptionByOriginalTxForUpdate(ctx context.Context, arg GetSubscriptionByOriginalTxForUpdateParams) (AppleSubscription, error)
 	GetUser(ctx context.Context, id int64) (GetUserRow, error)
+	GetUserAccountState(ctx context.Context, id int64) (GetUserAccountStateRow, error)
 	GetUserInfoByAuthIdentity(ctx context.Context, arg GetUserInfoByAuthIdentityParams) (GetUserInfoByAuthIdentityRow, error)
+	GetUserWithAccountState(ctx context.Context, id int64) (User, error)
+	IncrementPromoCodeRedemptions(ctx context.Context, code string) error
 	InsertAppleAccountToken(ctx context.Context, arg InsertAppleAccountTokenParams) (AppleAccountToken, error)
+	InsertAppleConsumptionRequest(ctx context.Context, arg InsertAppleConsumptionRequestParams) error
 	InsertAppleEventIfNotExists(ctx context.Context, arg InsertAppleEventIfNotExistsParams) (int64, error)
+	InsertAppleOfferSignature(ctx context.Context, arg InsertAppleOfferSignatureParams) (AppleOfferSignature, error)
+	InsertApplePayloadArchive(ctx context.Context, arg InsertApplePayloadArchiveParams) error
+	InsertAppleProduct(ctx context.Context, arg InsertAppleProductParams) (AppleProduct, error)
+	InsertAppleProductIfNotExists(ctx context.Context, arg InsertAppleProductIfNotExistsParams) (int64, error)
+	InsertApplePurchaseIfNotExists(ctx context.Context, arg InsertApplePurchaseIfNotExistsParams) (ApplePurchase, error)
+	InsertCompEntitlement(ctx context.Context, arg InsertCompEntitlementParams) (CompEntitlement, error)
+	InsertCreditBucket(ctx context.Context, arg InsertCreditBucketParams) (CreditBucket, error)
+	InsertCreditLedgerEntry(ctx context.Context, arg InsertCreditLedgerEntryParams) (int64, error)
+	InsertEntitlementWebhookEvent(ctx context.Context, arg InsertEntitlementWebhookEventParams) (int64, error)
+	InsertEntitlementWebhookSubscriber(ctx context.Context, arg InsertEntitlementWebhookSubscriberParams) (EntitlementWebhookSubscriber, error)
+	InsertGooglePlayEventIfNotExists(ctx context.Context, arg InsertGooglePlayEventIfNotExistsParams) (int64, error)
+	InsertGooglePlayPurchaseIfNotExists(ctx context.Context, arg InsertGooglePlayPurchaseIfNotExistsParams) (GooglePlayPurchase, error)
+	InsertPromoCode(ctx context.Context, arg InsertPromoCodeParams) (PromoCode, error)
+	InsertPromoCodeRedemption(ctx context.Context, arg InsertPromoCodeRedemptionParams) (PromoCodeRedemption, error)
+	InsertReferralCode(ctx context.Context, arg InsertReferralCodeParams) (ReferralCode, error)
+	InsertReferralRedemption(ctx context.Context, arg InsertReferralRedemptionParams) (ReferralRedemption, error)
+	InsertStripeCustomerIfNotExists(ctx context.Context, arg InsertStripeCustomerIfNotExistsParams) (StripeCustomer, error)
+	InsertStripeEventIfNotExists(ctx context.Context, arg InsertStripeEventIfNotExistsParams) (int64, error)
+	ListActiveAppleProducts(ctx context.Context) ([]AppleProduct, error)
+	ListActiveCreditBuckets(ctx context.Context, arg ListActiveCreditBucketsParams) ([]CreditBucket, error)
+	ListActiveGooglePlayLifetimePurchasesByUser(ctx context.Context, userID int64) ([]GooglePlayPurchase, error)
+	ListActiveLifetimePurchasesByUser(ctx context.Context, arg ListActiveLifetimePurchasesByUserParams) ([]ApplePurchase, error)
+	ListAppleFamilySharesForUserEntitlement(ctx context.Context, arg ListAppleFamilySharesForUserEntitlementParams) ([]AppleFamilyShare, error)
+	ListApplePayloadArchivesByOriginalTx(ctx context.Context, arg ListApplePayloadArchivesByOriginalTxParams) ([]ApplePayloadArchive, error)
+	ListApplePayloadArchivesNotUsingKey(ctx context.Context, arg ListApplePayloadArchivesNotUsingKeyParams) ([]ApplePayloadArchive, error)
+	ListAppleProducts(ctx context.Context) ([]AppleProduct, error)
+	ListApplePurchaseTimelineByUser(ctx context.Context, arg ListApplePurchaseTimelineByUserParams) ([]ListApplePurchaseTimelineByUserRow, error)
+	ListAppleRevenueEvents(ctx context.Context, arg ListAppleRevenueEventsParams) ([]ListAppleRevenueEventsRow, error)
+	ListAppleSubscriptionsDueForStatusSync(ctx context.Context, arg ListAppleSubscriptionsDueForStatusSyncParams) ([]AppleSubscription, error)
+	ListAuthIdentitiesByUser(ctx context.Context, userID int64) ([]AuthIdentity, error)
+	ListCompEntitlementsByUser(ctx context.Context, userID int64) ([]CompEntitlement, error)
+	ListEntitlementWebhookDeliveries(ctx context.Context, arg ListEntitlementWebhookDeliveriesParams) ([]ListEntitlementWebhookDeliveriesRow, error)
+	ListEntitlementWebhookSubscribers(ctx context.Context) ([]EntitlementWebhookSubscriber, error)
+	ListGooglePlaySubscriptionsForUserEntitlement(ctx context.Context, userID int64) ([]GooglePlaySubscription, error)
+	ListPaywallExperimentResults(ctx context.Context, arg ListPaywallExperimentResultsParams) ([]ListPaywallExperimentResultsRow, error)
 	ListPendingAppleEvents(ctx context.Context, arg ListPendingAppleEventsParams) ([]AppleEvent, error)
+	ListPromoCodes(ctx context.Context) ([]PromoCode, error)
+	ListRecentAppleEventsByUser(ctx context.Context, arg ListRecentAppleEventsByUserParams) ([]AppleEvent, error)
+	ListRedrivableAppleEvents(ctx context.Context, arg ListRedrivableAppleEventsParams) ([]AppleEvent, error)
+	ListStripeSubscriptionsForUserEntitlement(ctx context.Context, userID int64) ([]StripeSubscription, error)
+	ListSubscriptionsByUser(ctx context.Context, userID int64) ([]AppleSubscription, error)
 	ListSubscriptionsForUserEntitlement(ctx context.Context, arg ListSubscriptionsForUserEntitlementParams) ([]AppleSubscription, error)
+	ListUnexpiredCompEntitlements(ctx context.Context, arg ListUnexpiredCompEntitlementsParams) ([]CompEntitlement, error)
+	ListUserSettings(ctx context.Context, userID int64) ([]UserSetting, error)
+	LockAppleFamilyShare(ctx context.Context, arg LockAppleFamilyShareParams) (AppleFamilyShare, error)
+	LockAppleOfferSignatureQuota(ctx context.Context, arg LockAppleOfferSignatureQuotaParams) error
+	LockApplePurchaseByTransaction(ctx context.Context, arg LockApplePurchaseByTransactionParams) (ApplePurchase, error)
+	LockCompEntitlementQueue(ctx context.Context, userID int64) error
+	LockCreditBucket(ctx context.Context, id int64) (CreditBucket, error)
+	LockExpiredCreditBuckets(ctx context.Context, arg LockExpiredCreditBucketsParams) ([]CreditBucket, error)
+	LockGooglePlayPurchaseByToken(ctx context.Context, purchaseToken string) (GooglePlayPurchase, error)
+	LockGooglePlaySubscriptionByToken(ctx context.Context, purchaseToken string) (GooglePlaySubscription, error)
+	LockPendingAppleEvent(ctx context.Context, id int64) (AppleEvent, error)
+	LockPromoCode(ctx context.Context, code string) (PromoCode, error)
+	LockReferralCode(ctx context.Context, code string) (ReferralCode, error)
+	LockSpendableCreditBuckets(ctx context.Context, arg LockSpendableCreditBucketsParams) ([]CreditBucket, error)
+	LockStripeSubscriptionBySubscriptionID(ctx context.Context, subscriptionID string) (StripeSubscription, error)
+	LockSubscriptionByID(ctx context.Context, id int64) (AppleSubscription, error)
+	LockSubscriptionOriginalTx(ctx context.Context, arg LockSubscriptionOriginalTxParams) error
+	MarkAppleConsumptionRequestFailed(ctx context.Context, arg MarkAppleConsumptionRequestFailedParams) error
+	MarkAppleConsumptionRequestSent(ctx context.Context, arg MarkAppleConsumptionRequestSentParams) error
+	MarkApplePurchaseRefunded(ctx context.Context, arg MarkApplePurchaseRefundedParams) (ApplePurchase, error)
+	MarkEntitlementWebhookDeliveryDelivered(ctx context.Context, arg MarkEntitlementWebhookDeliveryDeliveredParams) error
+	MarkEntitlementWebhookDeliveryFailed(ctx context.Context, arg MarkEntitlementWebhookDeliveryFailedParams) error
+	MarkGooglePlayPurchaseRefunded(ctx context.Context, arg MarkGooglePlayPurchaseRefundedParams) (GooglePlayPurchase, error)
+	ReassignSubscriptionOwner(ctx context.Context, arg ReassignSubscriptionOwnerParams) (AppleSubscription, error)
+	ReplayDeadEntitlementWebhookDeliveries(ctx context.Context, arg ReplayDeadEntitlementWebhookDeliveriesParams) (int64, error)
+	ReplayEntitlementWebhookDelivery(ctx context.Context, arg ReplayEntitlementWebhookDeliveryParams) (int64, error)
+	RevokeCompEntitlement(ctx context.Context, arg RevokeCompEntitlementParams) (CompEntitlement, error)
+	RevokeGooglePlaySubscription(ctx context.Context, arg RevokeGooglePlaySubscriptionParams) (GooglePlaySubscription, error)
+	RevokeUserSessions(ctx context.Context, id int64) (RevokeUserSessionsRow, error)
+	SearchUsersByAppleAccountToken(ctx context.Context, arg SearchUsersByAppleAccountTokenParams) ([]User, error)
+	SearchUsersByEmail(ctx context.Context, arg SearchUsersByEmailParams) ([]User, error)
+	SearchUsersByID(ctx context.Context, arg SearchUsersByIDParams) ([]User, error)
+	SearchUsersByOriginalTransactionID(ctx context.Context, arg SearchUsersByOriginalTransactionIDParams) ([]User, error)
+	SearchUsersByProviderSubject(ctx context.Context, arg SearchUsersByProviderSubjectParams) ([]User, error)
+	SetApplePurchaseCreditBucket(ctx context.Context, arg SetApplePurchaseCreditBucketParams) error
+	SetCreditBucketRemaining(ctx context.Context, arg SetCreditBucketRemainingParams) error
+	SetGooglePlayPurchaseCreditBucket(ctx context.Context, arg SetGooglePlayPurchaseCreditBucketParams) error
+	SetUserStatus(ctx context.Context, arg SetUserStatusParams) (SetUserStatusRow, error)
+	UpdateAppleEventProcessing(ctx context.Context, arg UpdateAppleEventProcessingParams) error
+	UpdateApplePayloadArchiveKey(ctx context.Context, arg UpdateApplePayloadArchiveKeyParams) error
+	UpdateAppleProduct(ctx context.Context, arg UpdateAppleProductParams) (AppleProduct, error)
+	UpdateEntitlementWebhookSubscriber(ctx context.Context, arg UpdateEntitlementWebhookSubscriberParams) (EntitlementWebhookSubscriber, error)
+	UpsertAppleFamilyShare(ctx context.Context, arg UpsertAppleFamilyShareParams) (AppleFamilyShare, error)
+	UpsertAppleSubscriptionStatusSync(ctx context.Context, arg UpsertAppleSubscriptionStatusSyncParams) error
+	UpsertGooglePlaySubscription(ctx context.Context, arg UpsertGooglePlaySubscriptionParams) (GooglePlaySubscription, error)
+	UpsertPaywallExposure(ctx context.Context, arg UpsertPaywallExposureParams) (PaywallExposure, error)
+	UpsertStripeSubscription(ctx context.Context, arg UpsertStripeSubscriptionParams) (StripeSubscription, error)
 	UpsertSubscription(ctx context.Context, arg UpsertSubscriptionParams) (AppleSubscription, error)
+	UpsertUserSetting(ctx context.Context, arg UpsertUserSettingParams) error
 }
 
 var _ Querier = (*Queries)(nil)
@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createAuthIdentity = `-- name: CreateAuthIdentity :one
@@ -91,12 +93,44 @@ func (q *Queries) GetUser(ctx context.Context, id int64) (GetUserRow, error) {
 	return i, err
 }
 
+const getUserAccountState = `-- name: GetUserAccountState :one
+SELECT id, status, status_reason, status_updated_at, sessions_revoked_at, status_expires_at
+FROM users
+WHERE id = $1
+`
+
+type GetUserAccountStateRow struct {
+	ID                int64
+	Status            string
+	StatusReason      string
+	StatusUpdatedAt   pgtype.Timestamptz
+	SessionsRevokedAt pgtype.Timestamptz
+	StatusExpiresAt   pgtype.Timestamptz
+}
+
+func (q *Queries) GetUserAccountState(ctx context.Context, id int64) (GetUserAccountStateRow, error) {
+	row := q.db.QueryRow(ctx, getUserAccountState, id)
+	var i GetUserAccountStateRow
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusUpdatedAt,
+		&i.SessionsRevokedAt,
+		&i.StatusExpiresAt,
+	)
+	return i, err
+}
+
 const getUserInfoByAuthIdentity = `-- name: GetUserInfoByAuthIdentity :one
 SELECT
     u.id,
     ai.email,
     ai.provider,
-    ai.provider_subject
+    ai.provider_subject,
+    u.status,
+    u.status_reason,
+    u.status_expires_at
 FROM auth_identities ai
 JOIN users u ON u.id = ai.user_id
 WHERE ai.provider = $1
@@ -113,6 +147,9 @@ type GetUserInfoByAuthIdentityRow struct {
 	Email           string
 	Provider        string
 	ProviderSubject string
+	Status          string
+	StatusReason    string
+	StatusExpiresAt pgtype.Timestamptz
 }
 
 func (q *Queries) GetUserInfoByAuthIdentity(ctx context.Context, arg GetUserInfoByAuthIdentityParams) (GetUserInfoByAuthIdentityRow, error) {
@@ -123,6 +160,146 @@ func (q *Queries) GetUserInfoByAuthIdentity(ctx context.Context, arg GetUserInfo
 		&i.Email,
 		&i.Provider,
 		&i.ProviderSubject,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusExpiresAt,
+	)
+	return i, err
+}
+
+const getUserWithAccountState = `-- name: GetUserWithAccountState :one
+SELECT id, name, created_at, updated_at, status, status_reason, status_updated_at, sessions_revoked_at, status_expires_at
+FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserWithAccountState(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRow(ctx, getUserWithAccountState, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusUpdatedAt,
+		&i.SessionsRevokedAt,
+		&i.StatusExpiresAt,
+	)
+	return i, err
+}
+
+const listAuthIdentitiesByUser = `-- name: ListAuthIdentitiesByUser :many
+SELECT provider, provider_subject, user_id, email, created_at, updated_at
+FROM auth_identities
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListAuthIdentitiesByUser(ctx context.Context, userID int64) ([]AuthIdentity, error) {
+	rows, err := q.db.Query(ctx, listAuthIdentitiesByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuthIdentity
+	for rows.Next() {
+		var i AuthIdentity
+		if err := rows.Scan(
+			&i.Provider,
+			&i.ProviderSubject,
+			&i.UserID,
+			&i.Email,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeUserSessions = `-- name: RevokeUserSessions :one
+UPDATE users
+SET sessions_revoked_at = now(),
+    updated_at = now()
+WHERE id = $1
+RETURNING id, status, status_reason, status_updated_at, sessions_revoked_at, status_expires_at
+`
+
+type RevokeUserSessionsRow struct {
+	ID                int64
+	Status            string
+	StatusReason      string
+	StatusUpdatedAt   pgtype.Timestamptz
+	SessionsRevokedAt pgtype.Timestamptz
+	StatusExpiresAt   pgtype.Timestamptz
+}
+
+func (q *Queries) RevokeUserSessions(ctx context.Context, id int64) (RevokeUserSessionsRow, error) {
+	row := q.db.QueryRow(ctx, revokeUserSessions, id)
+	var i RevokeUserSessionsRow
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusUpdatedAt,
+		&i.SessionsRevokedAt,
+		&i.StatusExpiresAt,
+	)
+	return i, err
+}
+
+const setUserStatus = `-- name: SetUserStatus :one
+UPDATE users
+SET status = $1,
+    status_reason = $2,
+    status_expires_at = $3,
+    status_updated_at = now(),
+    sessions_revoked_at = CASE WHEN $4::boolean THEN now() ELSE sessions_revoked_at END,
+    updated_at = now()
+WHERE id = $5
+RETURNING id, status, status_reason, status_updated_at, sessions_revoked_at, status_expires_at
+`
+
+type SetUserStatusParams struct {
+	Status          string
+	StatusReason    string
+	StatusExpiresAt pgtype.Timestamptz
+	RevokeSessions  bool
+	ID              int64
+}
+
+type SetUserStatusRow struct {
+	ID                int64
+	Status            string
+	StatusReason      string
+	StatusUpdatedAt   pgtype.Timestamptz
+	SessionsRevokedAt pgtype.Timestamptz
+	StatusExpiresAt   pgtype.Timestamptz
+}
+
+func (q *Queries) SetUserStatus(ctx context.Context, arg SetUserStatusParams) (SetUserStatusRow, error) {
+	row := q.db.QueryRow(ctx, setUserStatus,
+		arg.Status,
+		arg.StatusReason,
+		arg.StatusExpiresAt,
+		arg.RevokeSessions,
+		arg.ID,
+	)
+	var i SetUserStatusRow
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusUpdatedAt,
+		&i.SessionsRevokedAt,
+		&i.StatusExpiresAt,
 	)
 	return i, err
 }
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// users.status：账号状态。只有 ACTIVE 可以登录与调用 Bearer 接口。
+//
+// SUSPENDED / BANNED 可以带 StatusExpiresAt，到期后无需写库即视为 ACTIVE；DELETED 永不过期。
+const (
+	UserStatusActive    = "ACTIVE"
+	UserStatusSuspended = "SUSPENDED"
+	UserStatusBanned    = "BANNED"
+	UserStatusDeleted   = "DELETED"
+)
+
+// 账号被拒绝访问时返回给客户端的错误码，客户端据此展示对应文案。
+const (
+	AccountErrorSuspended      = "ACCOUNT_SUSPENDED"
+	AccountErrorBanned         = "ACCOUNT_BANNED"
+	AccountErrorDeleted        = "ACCOUNT_DELETED"
+	AccountErrorSessionRevoked = "SESSION_REVOKED"
+)
+
+// ErrAccountBlocked 匹配所有 *AccountBlockedError，便于调用方用 errors.Is 判断。
+var ErrAccountBlocked = errors.New("account blocked")
+
+// ErrAccountNotFound 表示账号不存在，与数据库不可用等读取失败区分开。
+var ErrAccountNotFound = errors.New("account not found")
+
+// AccountBlockedError 表示账号当前处于非 ACTIVE 状态，携带原因与到期时间供接口层透出。
+type AccountBlockedError struct {
+	Status    string
+	Reason    string
+	ExpiresAt *time.Time
+}
+
+func (e *AccountBlockedError) Error() string {
+	return "account " + strings.ToLower(e.Status)
+}
+
+func (e *AccountBlockedError) Is(target error) bool {
+	return target == ErrAccountBlocked
+}
+
+// Code 返回对客户端暴露的错误码。
+func (e *AccountBlockedError) Code() string {
+	switch e.Status {
+	case UserStatusBanned:
+		return AccountErrorBanned
+	case UserStatusDeleted:
+		return AccountErrorDeleted
+	default:
+		return AccountErrorSuspended
+	}
+}
+
+// IsKnownUserStatus 判断 status 是否为 users.status 允许的取值。
+func IsKnownUserStatus(status string) bool {
+	switch status {
+	case UserStatusActive, UserStatusSuspended, UserStatusBanned, UserStatusDeleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// AccountState 是 users 表中与访问控制相关的字段投影。
+//
+// SessionsRevokedAt 非空时，签发时间不晚于该时刻的 access token 一律视为失效（强制下线）。
+type AccountState struct {
+	UserID            int64
+	Status            string
+	StatusReason      string
+	StatusUpdatedAt   *time.Time
+	StatusExpiresAt   *time.Time
+	SessionsRevokedAt *time.Time
+}
+
+// EffectiveStatus 返回 now 时刻实际生效的状态：已到期的 SUSPENDED / BANNED 视为 ACTIVE。
+func (s AccountState) EffectiveStatus(now time.Time) string {
+	if s.Status == UserStatusDeleted || s.StatusExpiresAt == nil {
+		return s.Status
+	}
+	if (s.Status == UserStatusSuspended || s.Status == UserStatusBanned) && !now.Before(*s.StatusExpiresAt) {
+		return UserStatusActive
+	}
+	return s.Status
+}
+
+// BlockedError 在账号 now 时刻不可用时返回 *AccountBlockedError，否则返回 nil。
+// 未知状态按不可用处理。
+func (s AccountState) BlockedError(now time.Time) error {
+	status := s.EffectiveStatus(now)
+	if status == UserStatusActive {
+		return nil
+	}
+	return &AccountBlockedError{
+		Status:    status,
+		Reason:    s.StatusReason,
+		ExpiresAt: s.StatusExpiresAt,
+	}
+}
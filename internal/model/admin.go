@@ -0,0 +1,157 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AdminSearchField 是管理后台用户搜索支持的查询维度。
+type AdminSearchField string
+
+const (
+	AdminSearchByID                    AdminSearchField = "id"
+	AdminSearchByEmail                 AdminSearchField = "email"
+	AdminSearchByProviderSubject       AdminSearchField = "provider_subject"
+	AdminSearchByAppAccountToken       AdminSearchField = "app_account_token"
+	AdminSearchByOriginalTransactionID AdminSearchField = "original_transaction_id"
+)
+
+// AdminUser 是管理后台看到的用户行，包含账号状态。
+type AdminUser struct {
+	ID                int64
+	Name              string
+	Status            string
+	StatusReason      string
+	StatusUpdatedAt   *time.Time
+	StatusExpiresAt   *time.Time
+	SessionsRevokedAt *time.Time
+	CreatedAt         time.Time
+}
+
+// AdminUserDetail 聚合单个用户的身份、订阅与最近的 Apple 通知，供客服排查使用。
+type AdminUserDetail struct {
+	User            AdminUser
+	Identities      []AdminIdentity
+	AppAccountToken string
+	Subscriptions   []Subscription
+	RecentEvents    []AdminAppleEvent
+}
+
+// AdminIdentity 是 auth_identities 行的管理后台投影。
+type AdminIdentity struct {
+	Provider        string
+	ProviderSubject string
+	Email           string
+	CreatedAt       time.Time
+}
+
+// AdminAppleEvent 是 apple_events 行的管理后台投影，不包含 decoded payload。
+type AdminAppleEvent struct {
+	NotificationUUID      string
+	NotificationType      string
+	Subtype               string
+	Environment           AppleEnvironment
+	OriginalTransactionID string
+	TransactionID         string
+	ProcessingStatus      string
+	ProcessingError       string
+	NotificationCreatedAt *time.Time
+	CreatedAt             time.Time
+}
+
+// AdminUserSummaryView 是管理后台 API 返回的用户信息。
+type AdminUserSummaryView struct {
+	ID                string `json:"id" doc:"用户 ID" example:"1"`
+	Name              string `json:"name" doc:"用户显示名称" example:"Ada"`
+	Status            string `json:"status" doc:"账号状态" example:"ACTIVE" enum:"ACTIVE,SUSPENDED,BANNED,DELETED"`
+	StatusReason      string `json:"status_reason" doc:"最近一次状态变更的原因" example:""`
+	StatusUpdatedAt   string `json:"status_updated_at,omitempty" doc:"最近一次状态变更时间（RFC3339）" format:"date-time"`
+	StatusExpiresAt   string `json:"status_expires_at,omitempty" doc:"暂停 / 封禁的到期时间（RFC3339），为空表示不过期" format:"date-time"`
+	SessionsRevokedAt string `json:"sessions_revoked_at,omitempty" doc:"最近一次强制下线时间（RFC3339），早于该时刻签发的 token 均失效" format:"date-time"`
+	CreatedAt         string `json:"created_at" doc:"注册时间（RFC3339）" format:"date-time"`
+}
+
+// AdminUserSearchResponse 是 GET /admin/users 的响应负载。
+type AdminUserSearchResponse struct {
+	Users      []AdminUserSummaryView `json:"users" doc:"命中的用户，按用户 ID 升序"`
+	NextCursor string                 `json:"next_cursor,omitempty" doc:"下一页游标；为空表示没有更多结果"`
+}
+
+// AdminIdentityView 是用户详情中的登录身份。
+type AdminIdentityView struct {
+	Provider        string `json:"provider" doc:"认证提供方" example:"apple"`
+	ProviderSubject string `json:"provider_subject" doc:"提供方内的用户标识"`
+	Email           string `json:"email" doc:"提供方返回的邮箱，可能为空"`
+	CreatedAt       string `json:"created_at" doc:"绑定时间（RFC3339）" format:"date-time"`
+}
+
+// AdminSubscriptionView 是用户详情中的 Apple 订阅行。
+type AdminSubscriptionView struct {
+	Environment           string `json:"environment" doc:"Apple 环境" example:"Production"`
+	OriginalTransactionID string `json:"original_transaction_id" doc:"Apple originalTransactionId"`
+	LastTransactionID     string `json:"last_transaction_id" doc:"最近一次 transactionId"`
+	ProductID             string `json:"product_id" doc:"Apple productId"`
+	PlanID                string `json:"plan_id" doc:"内部 plan 标识"`
+	Level                 int    `json:"level" doc:"订阅等级"`
+	Status                string `json:"status" doc:"内部订阅状态" example:"ACTIVE"`
+	AutoRenewStatus       string `json:"auto_renew_status" doc:"自动续期状态" example:"ON"`
+	CurrentPeriodEnd      string `json:"current_period_end" doc:"当前周期结束时间（RFC3339）" format:"date-time"`
+	LastEventAt           string `json:"last_event_at" doc:"最近一次状态变更时间（RFC3339）" format:"date-time"`
+}
+
+// AdminAppleEventView 是用户详情中的 Apple 通知记录。
+type AdminAppleEventView struct {
+	NotificationUUID      string `json:"notification_uuid" doc:"Apple notificationUUID"`
+	NotificationType      string `json:"notification_type" doc:"通知类型" example:"DID_RENEW"`
+	Subtype               string `json:"subtype" doc:"通知子类型"`
+	Environment           string `json:"environment" doc:"Apple 环境" example:"Production"`
+	OriginalTransactionID string `json:"original_transaction_id" doc:"Apple originalTransactionId"`
+	TransactionID         string `json:"transaction_id" doc:"Apple transactionId"`
+	ProcessingStatus      string `json:"processing_status" doc:"本服务处理结果" example:"PROCESSED"`
+	ProcessingError       string `json:"processing_error" doc:"处理失败原因"`
+	NotificationCreatedAt string `json:"notification_created_at,omitempty" doc:"Apple 签发通知时间（RFC3339）" format:"date-time"`
+	CreatedAt             string `json:"created_at" doc:"本服务接收时间（RFC3339）" format:"date-time"`
+}
+
+// AdminUserDetailResponse 是 GET /admin/users/{id} 的响应负载。
+type AdminUserDetailResponse struct {
+	User            AdminUserSummaryView    `json:"user" doc:"用户基础信息与账号状态"`
+	Identities      []AdminIdentityView     `json:"identities" doc:"已绑定的登录身份"`
+	AppAccountToken string                  `json:"app_account_token" doc:"Apple appAccountToken，未生成时为空"`
+	Subscriptions   []AdminSubscriptionView `json:"subscriptions" doc:"所有环境下的 Apple 订阅"`
+	RecentEvents    []AdminAppleEventView   `json:"recent_events" doc:"最近的 Apple 通知，按接收时间倒序"`
+}
+
+// AdminApplePayloadView 是一条解密后的 Apple 通知 / transaction 归档。
+type AdminApplePayloadView struct {
+	ID                    string          `json:"id" doc:"归档 ID" example:"1"`
+	Source                string          `json:"source" doc:"来源：NOTIFICATION 为 App Store Server Notification，TRANSACTION 为 verify 拉取的交易" enum:"NOTIFICATION,TRANSACTION"`
+	Environment           string          `json:"environment" doc:"Apple 环境" example:"Production"`
+	OriginalTransactionID string          `json:"original_transaction_id" doc:"Apple originalTransactionId"`
+	TransactionID         string          `json:"transaction_id" doc:"Apple transactionId"`
+	UserID                string          `json:"user_id,omitempty" doc:"归档时绑定的用户 ID，未绑定时为空"`
+	Payload               json.RawMessage `json:"payload" doc:"解码后的 Apple 数据（JSON）"`
+	CreatedAt             string          `json:"created_at" doc:"归档时间（RFC3339）" format:"date-time"`
+	ExpiresAt             string          `json:"expires_at" doc:"保留期到期时间（RFC3339），到期后删除" format:"date-time"`
+}
+
+// AdminApplePayloadsResponse 是 GET /admin/apple/payloads 的响应负载。
+type AdminApplePayloadsResponse struct {
+	Payloads []AdminApplePayloadView `json:"payloads" doc:"按归档时间倒序"`
+}
+
+// AdminAccountStateView 是 suspend / ban / unsuspend / force-logout 的响应负载。
+type AdminAccountStateView struct {
+	UserID            string `json:"user_id" doc:"用户 ID" example:"1"`
+	Status            string `json:"status" doc:"账号状态" example:"SUSPENDED" enum:"ACTIVE,SUSPENDED,BANNED,DELETED"`
+	StatusReason      string `json:"status_reason" doc:"最近一次状态变更的原因" example:"chargeback fraud"`
+	StatusUpdatedAt   string `json:"status_updated_at,omitempty" doc:"最近一次状态变更时间（RFC3339）" format:"date-time"`
+	StatusExpiresAt   string `json:"status_expires_at,omitempty" doc:"暂停 / 封禁的到期时间（RFC3339），为空表示不过期" format:"date-time"`
+	SessionsRevokedAt string `json:"sessions_revoked_at,omitempty" doc:"最近一次强制下线时间（RFC3339）" format:"date-time"`
+}
+
+// AdminUserActionRequest 是 suspend / ban / unsuspend / force-logout 的请求体。
+type AdminUserActionRequest struct {
+	Reason    string     `json:"reason,omitempty" doc:"操作原因，记录在账号状态与审计日志中" maxLength:"500" example:"chargeback fraud"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" doc:"仅 suspend / ban 生效：到期时间（RFC3339），到期后账号自动恢复；省略表示不过期"`
+}
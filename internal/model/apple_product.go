@@ -0,0 +1,106 @@
+package model
+
+import "time"
+
+// ProductLocalization 是商品在某一语言下的付费墙文案。
+type ProductLocalization struct {
+	Title       string   `json:"title" minLength:"1" maxLength:"100" doc:"商品标题" example:"Pro 月度会员"`
+	Description string   `json:"description,omitempty" maxLength:"500" doc:"商品描述"`
+	Features    []string `json:"features,omitempty" maxItems:"20" doc:"权益要点，按展示顺序"`
+}
+
+// AppleProduct 是 apple_products 行的领域投影：数据库管理的 Apple IAP 商品。
+//
+// 字段与 APPLE_IAP_PRODUCTS JSON 一致，另带付费墙展示用的元数据与报表用的参考价格；Active=false 的商品不进入运行期 catalog。
+// PriceMilliunits 以 Currency 的千分之一为单位（与 Apple 一致），BillingPeriod 为 ISO 8601 时长（P1W、P1M、P1Y 等）。
+type AppleProduct struct {
+	ID                  int64
+	PlanID              string
+	ProductID           string
+	Environment         AppleEnvironment
+	Type                string
+	Level               int
+	Credits             int64
+	SubscriptionGroupID string
+	DisplayName         string
+	Description         string
+	SortOrder           int
+	Active              bool
+	Localizations       map[string]ProductLocalization
+	Recommended         bool
+	PriceMilliunits     int64
+	Currency            string
+	BillingPeriod       string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// AppleProductInput 是新增 / 更新 apple_products 行的输入。
+type AppleProductInput struct {
+	PlanID              string
+	ProductID           string
+	Environment         AppleEnvironment
+	Type                string
+	Level               int
+	Credits             int64
+	SubscriptionGroupID string
+	DisplayName         string
+	Description         string
+	SortOrder           int
+	Active              bool
+	Localizations       map[string]ProductLocalization
+	Recommended         bool
+	PriceMilliunits     int64
+	Currency            string
+	BillingPeriod       string
+}
+
+// AdminAppleProductRequest 是 POST /admin/apple/products 与 PUT /admin/apple/products/{id} 的请求体。
+type AdminAppleProductRequest struct {
+	PlanID              string `json:"plan_id" minLength:"1" doc:"内部套餐 ID" example:"pro_monthly"`
+	ProductID           string `json:"product_id" minLength:"1" doc:"App Store Connect 中的 productId" example:"com.app.pro.monthly"`
+	Environment         string `json:"environment" enum:"Production,Sandbox" doc:"Apple 环境" example:"Production"`
+	Type                string `json:"type,omitempty" enum:"subscription,consumable,non_consumable" doc:"商品类型，缺省为 subscription" example:"subscription"`
+	Level               int    `json:"level,omitempty" minimum:"0" doc:"权益等级；非消耗型买断为终身权益等级" example:"1"`
+	Credits             int64  `json:"credits,omitempty" minimum:"0" doc:"消耗型商品每份发放的积分；其他类型必须为 0" example:"0"`
+	SubscriptionGroupID string `json:"subscription_group_id,omitempty" doc:"App Store Connect 订阅群组 ID" example:"21000000"`
+	DisplayName         string `json:"display_name,omitempty" maxLength:"100" doc:"付费墙展示名称" example:"Pro 月度会员"`
+	Description         string `json:"description,omitempty" maxLength:"500" doc:"付费墙展示描述"`
+	SortOrder           int    `json:"sort_order,omitempty" doc:"付费墙排序，升序" example:"10"`
+	Active              *bool  `json:"active,omitempty" doc:"是否上架；省略视为 true。下架的商品不再出现在 paywall，已有订阅的续期、到期与退款照常处理"`
+	// Localizations 以 BCP 47 语言标签为 key，如 zh-Hans、en。
+	Localizations map[string]ProductLocalization `json:"localizations,omitempty" doc:"各语言的付费墙文案，key 为 BCP 47 语言标签；缺少的语言回退到 display_name / description"`
+	Recommended   bool                           `json:"recommended,omitempty" doc:"是否在付费墙上作为推荐方案展示"`
+	// 参考价格只用于收入报表（MRR），不影响 App Store 实际售价。
+	PriceMilliunits int64  `json:"price_milliunits,omitempty" minimum:"0" doc:"参考价格，单位为 currency 的千分之一；0 表示未定价，不计入 MRR" example:"9990"`
+	Currency        string `json:"currency,omitempty" doc:"参考价格的 ISO 4217 币种；定价时必填" example:"USD"`
+	BillingPeriod   string `json:"billing_period,omitempty" enum:"P1W,P1M,P2M,P3M,P6M,P1Y" doc:"订阅计费周期（ISO 8601）；定价的订阅必填" example:"P1M"`
+}
+
+// AdminAppleProductView 是一条数据库管理的 Apple 商品。
+type AdminAppleProductView struct {
+	ID                  string                         `json:"id" doc:"商品记录 ID" example:"1"`
+	PlanID              string                         `json:"plan_id" doc:"内部套餐 ID" example:"pro_monthly"`
+	ProductID           string                         `json:"product_id" doc:"App Store Connect 中的 productId" example:"com.app.pro.monthly"`
+	Environment         string                         `json:"environment" doc:"Apple 环境" example:"Production"`
+	Type                string                         `json:"type" doc:"商品类型" enum:"subscription,consumable,non_consumable"`
+	Level               int                            `json:"level" doc:"权益等级" example:"1"`
+	Credits             int64                          `json:"credits" doc:"消耗型商品每份发放的积分" example:"0"`
+	SubscriptionGroupID string                         `json:"subscription_group_id,omitempty" doc:"App Store Connect 订阅群组 ID"`
+	DisplayName         string                         `json:"display_name,omitempty" doc:"付费墙展示名称"`
+	Description         string                         `json:"description,omitempty" doc:"付费墙展示描述"`
+	SortOrder           int                            `json:"sort_order" doc:"付费墙排序" example:"10"`
+	Active              bool                           `json:"active" doc:"是否上架"`
+	Localizations       map[string]ProductLocalization `json:"localizations" doc:"各语言的付费墙文案"`
+	Recommended         bool                           `json:"recommended" doc:"是否在付费墙上作为推荐方案展示"`
+	PriceMilliunits     int64                          `json:"price_milliunits" doc:"参考价格，单位为 currency 的千分之一" example:"9990"`
+	Currency            string                         `json:"currency,omitempty" doc:"参考价格币种" example:"USD"`
+	BillingPeriod       string                         `json:"billing_period,omitempty" doc:"订阅计费周期" example:"P1M"`
+	CreatedAt           string                         `json:"created_at" doc:"创建时间（RFC3339）" format:"date-time"`
+	UpdatedAt           string                         `json:"updated_at" doc:"最近更新时间（RFC3339）" format:"date-time"`
+}
+
+// AdminAppleProductsResponse 是 GET /admin/apple/products 的响应负载。
+type AdminAppleProductsResponse struct {
+	Products []AdminAppleProductView `json:"products" doc:"全部商品（含已下架），按 sort_order、id 升序"`
+}
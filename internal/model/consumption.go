@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// apple_consumption_requests.status：Send Consumption Information 的投递状态。
+const (
+	ConsumptionRequestPending = "PENDING"
+	ConsumptionRequestSent    = "SENT"
+	ConsumptionRequestFailed  = "FAILED"
+)
+
+// AppleConsumptionRequest 是 apple_consumption_requests 行的领域投影：一次 CONSUMPTION_REQUEST 通知及其回复状态。
+//
+// UserID == 0 表示通知到达时无法确定用户（回复中不含账户信息）；DeadlineAt 之后 Apple 不再接受回复。
+type AppleConsumptionRequest struct {
+	ID                    int64
+	EventID               int64
+	UserID                int64
+	Environment           AppleEnvironment
+	OriginalTransactionID string
+	TransactionID         string
+	AppAccountToken       string
+	Status                string
+	Attempts              int
+	NextAttemptAt         time.Time
+	DeadlineAt            time.Time
+	LastError             string
+	SentPayload           []byte
+	SentAt                *time.Time
+	CreatedAt             time.Time
+}
+
+// ConsumptionSnapshot 是计算 consumption 字段所需的本地数据：账户创建时间 / 状态、该交易发放与剩余的积分、
+// 以及本地是否已为该交易交付权益。
+type ConsumptionSnapshot struct {
+	AccountCreatedAt time.Time
+	AccountStatus    string
+	CreditsGranted   int64
+	CreditsRemaining int64
+	Delivered        bool
+}
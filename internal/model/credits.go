@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// credit_buckets.source：积分来源。不同来源的过期策略不同（购买通常不过期，促销 / 订阅赠送有期限）。
+const (
+	CreditSourcePurchase              = "PURCHASE"
+	CreditSourcePromo                 = "PROMO"
+	CreditSourceSubscriptionAllowance = "SUBSCRIPTION_ALLOWANCE"
+	CreditSourceReferral              = "REFERRAL"
+)
+
+// credit_ledger.entry_type：每次余额变动的类型。delta 对 GRANT 为正，对 SPEND / EXPIRE / REVOKE 为负。
+// REVOKE 表示来源被撤销（例如购买退款）时收回 bucket 的剩余额度。
+const (
+	CreditEntryGrant  = "GRANT"
+	CreditEntrySpend  = "SPEND"
+	CreditEntryExpire = "EXPIRE"
+	CreditEntryRevoke = "REVOKE"
+)
+
+// CreditBucket 是 credit_buckets 行的领域投影。
+//
+// Amount 是发放时的额度，Remaining 是当前剩余；ExpiresAt 为 nil 表示永不过期。
+type CreditBucket struct {
+	ID        int64
+	UserID    int64
+	Source    string
+	Amount    int64
+	Remaining int64
+	ExpiresAt *time.Time
+	Reference string
+	CreatedAt time.Time
+}
+
+// CreditGrant 是发放积分时的入参；每次发放都会新建一个独立 bucket 并写一条 GRANT ledger。
+//
+// Reference 用于把 bucket 关联回业务来源（订单号、活动 ID 等），不参与幂等判断。
+type CreditGrant struct {
+	UserID    int64
+	Source    string
+	Amount    int64
+	ExpiresAt *time.Time
+	Reference string
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// EntitlementGrant.Source：权益来源。
+const (
+	EntitlementSourceApple       = "APPLE"
+	EntitlementSourceAppleFamily = "APPLE_FAMILY_SHARING"
+	EntitlementSourceGooglePlay  = "GOOGLE_PLAY"
+	EntitlementSourceStripe      = "STRIPE"
+	EntitlementSourceComp        = "COMP"
+)
+
+// DefaultFeatureSet 是未在 ENTITLEMENT_FEATURE_SETS 中声明的 plan 所归属的 feature set。
+const DefaultFeatureSet = "premium"
+
+// EntitlementGrant 是某一来源（商店订阅、买断、赠送等）给出的一条权益，由 entitlement engine 统一合并。
+//
+// Status 使用对外 API status（ACTIVE / CANCELED / GRACE_PERIOD 有效，BILLING_RETRY / EXPIRED 失效）；ExpiresAt 为 nil 表示终身权益。
+type EntitlementGrant struct {
+	Source    string
+	PlanID    string
+	Level     int
+	Status    string
+	ExpiresAt *time.Time
+	// PendingPlanID / PendingLevel 是下次续期才生效的同组变更，仅 Apple 订阅会填写。
+	PendingPlanID string
+	PendingLevel  int
+	// GracePeriodExpiresAt 仅 GRACE_PERIOD 时有值；ExpirationReason 是失效权益的到期原因（可为空）。
+	GracePeriodExpiresAt *time.Time
+	ExpirationReason     string
+}
+
+// Entitlement 是某个 feature set 当前生效的权益。
+type Entitlement struct {
+	FeatureSet string `json:"feature_set" doc:"权益覆盖的功能集合" example:"premium"`
+	PlanID     string `json:"plan_id" doc:"生效权益对应的 plan" example:"pro_monthly"`
+	Source     string `json:"source" doc:"权益来源" example:"APPLE" enum:"APPLE,APPLE_FAMILY_SHARING,GOOGLE_PLAY,STRIPE,COMP"`
+	Status     string `json:"status" doc:"权益状态；CANCELED 表示已关闭自动续费但仍在有效期内，GRACE_PERIOD 表示扣款失败但仍在宽限期内" example:"ACTIVE" enum:"ACTIVE,CANCELED,GRACE_PERIOD"`
+	Level      int    `json:"level" doc:"权益等级" example:"1" minimum:"1"`
+	ExpiresAt  string `json:"expires_at,omitempty" doc:"到期时间（RFC3339），终身权益省略" example:"2026-02-23T12:00:00Z" format:"date-time"`
+}
+
+// EntitlementSummary 是 entitlement engine 对单个用户的合并结果。
+//
+// Subscription 是跨 feature set 的最佳权益，保持 /users/me subscription_info 的既有语义；
+// Entitlements 每个 feature set 一条，只包含当前有效的权益。
+type EntitlementSummary struct {
+	Subscription SubscriptionInfo
+	Entitlements []Entitlement
+}
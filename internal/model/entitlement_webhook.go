@@ -0,0 +1,154 @@
+package model
+
+import "time"
+
+// entitlement_webhook_events.event_type：出站 webhook 的事件类型，订阅方按类型订阅。
+const (
+	EntitlementEventActivated   = "subscription.activated"
+	EntitlementEventDeactivated = "subscription.deactivated"
+	EntitlementEventUpdated     = "subscription.updated"
+)
+
+// EntitlementEventTypes 是全部可订阅的事件类型。
+var EntitlementEventTypes = []string{EntitlementEventActivated, EntitlementEventDeactivated, EntitlementEventUpdated}
+
+// entitlement_webhook_deliveries.status：单个订阅方的投递状态。
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliveryDelivered = "DELIVERED"
+	WebhookDeliveryDead      = "DEAD"
+)
+
+// EntitlementChange 是一次订阅行变更后的快照，作为 webhook 请求体的 data 原样下发。
+//
+// SubscriptionID 是本服务内部的订阅行 ID（家庭共享为 apple_family_shares 行 ID），与 Source 组合唯一；
+// PreviousStatus 为空表示新建的订阅。一次性购买（含买断）不产生事件。
+type EntitlementChange struct {
+	UserID               int64      `json:"user_id"`
+	Source               string     `json:"source"`
+	SubscriptionID       int64      `json:"subscription_id"`
+	Environment          string     `json:"environment,omitempty"`
+	PlanID               string     `json:"plan_id"`
+	Level                int        `json:"level"`
+	Status               string     `json:"status"`
+	PreviousStatus       string     `json:"previous_status,omitempty"`
+	AutoRenewStatus      string     `json:"auto_renew_status"`
+	CurrentPeriodEnd     time.Time  `json:"current_period_end"`
+	GracePeriodExpiresAt *time.Time `json:"grace_period_expires_at,omitempty"`
+	BillingRetry         bool       `json:"billing_retry,omitempty"`
+	PendingPlanID        string     `json:"pending_plan_id,omitempty"`
+}
+
+// EntitlementWebhookSubscriber 是 entitlement_webhook_subscribers 行的领域投影。
+type EntitlementWebhookSubscriber struct {
+	ID         int64
+	Name       string
+	URL        string
+	Secret     string
+	EventTypes []string
+	Active     bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// EntitlementWebhookSubscriberInput 是新增 / 更新订阅方的入参；更新时 Secret 为空表示保留原密钥。
+type EntitlementWebhookSubscriberInput struct {
+	Name       string
+	URL        string
+	Secret     string
+	EventTypes []string
+	Active     bool
+}
+
+// EntitlementWebhookDispatch 是一条已领取、待发送的投递，带上事件内容与订阅方的 URL / 密钥。
+type EntitlementWebhookDispatch struct {
+	DeliveryID     int64
+	EventID        int64
+	SubscriberID   int64
+	Attempts       int
+	EventType      string
+	UserID         int64
+	Payload        []byte
+	EventCreatedAt time.Time
+	URL            string
+	Secret         string
+}
+
+// EntitlementWebhookDelivery 是投递记录的查询投影。
+type EntitlementWebhookDelivery struct {
+	ID             int64
+	EventID        int64
+	SubscriberID   int64
+	EventType      string
+	UserID         int64
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// EntitlementWebhookDeliveryQuery 是投递记录的分页查询条件；零值字段不过滤，BeforeID 为 0 表示第一页。
+type EntitlementWebhookDeliveryQuery struct {
+	SubscriberID int64
+	Status       string
+	BeforeID     int64
+	Limit        int
+}
+
+// AdminEntitlementWebhookSubscriberRequest 是新增 / 更新订阅方的请求体。
+type AdminEntitlementWebhookSubscriberRequest struct {
+	Name       string   `json:"name" minLength:"1" maxLength:"100" doc:"订阅方名称" example:"billing-sync"`
+	URL        string   `json:"url" minLength:"1" doc:"回调地址；生产环境必须是 https" example:"https://billing.internal.example.com/hooks/entitlement"`
+	Secret     string   `json:"secret,omitempty" doc:"签名密钥，至少 16 个字符。新增时省略则自动生成；更新时省略则保留原密钥"`
+	EventTypes []string `json:"event_types" minItems:"1" uniqueItems:"true" enum:"subscription.activated,subscription.deactivated,subscription.updated" doc:"订阅的事件类型"`
+	Active     *bool    `json:"active,omitempty" doc:"是否启用；省略视为 true。停用后不再产生新投递，待发送的投递暂停"`
+}
+
+// AdminEntitlementWebhookSubscriberView 是一个订阅方；Secret 只在新增时返回一次。
+type AdminEntitlementWebhookSubscriberView struct {
+	ID         string   `json:"id" doc:"订阅方 ID" example:"1"`
+	Name       string   `json:"name" doc:"订阅方名称" example:"billing-sync"`
+	URL        string   `json:"url" doc:"回调地址"`
+	EventTypes []string `json:"event_types" doc:"订阅的事件类型"`
+	Active     bool     `json:"active" doc:"是否启用"`
+	Secret     string   `json:"secret,omitempty" doc:"签名密钥，仅在新增时返回，请妥善保存"`
+	CreatedAt  string   `json:"created_at" doc:"创建时间（RFC3339）" format:"date-time"`
+	UpdatedAt  string   `json:"updated_at" doc:"最近更新时间（RFC3339）" format:"date-time"`
+}
+
+// AdminEntitlementWebhookSubscribersResponse 是 GET /admin/webhooks/subscribers 的响应负载。
+type AdminEntitlementWebhookSubscribersResponse struct {
+	Subscribers []AdminEntitlementWebhookSubscriberView `json:"subscribers" doc:"全部订阅方（含已停用），按 id 升序"`
+}
+
+// AdminEntitlementWebhookDeliveryView 是一条投递记录。
+type AdminEntitlementWebhookDeliveryView struct {
+	ID             string `json:"id" doc:"投递 ID" example:"1"`
+	EventID        string `json:"event_id" doc:"事件 ID，即请求头 X-Entitlement-Webhook-Id" example:"1"`
+	SubscriberID   string `json:"subscriber_id" doc:"订阅方 ID" example:"1"`
+	EventType      string `json:"event_type" doc:"事件类型" example:"subscription.activated"`
+	UserID         string `json:"user_id" doc:"权益变更的用户 ID" example:"42"`
+	Status         string `json:"status" doc:"投递状态" enum:"PENDING,DELIVERED,DEAD"`
+	Attempts       int    `json:"attempts" doc:"已尝试次数" example:"1"`
+	NextAttemptAt  string `json:"next_attempt_at,omitempty" doc:"下次尝试时间（RFC3339），仅 PENDING 时返回" format:"date-time"`
+	LastStatusCode int    `json:"last_status_code,omitempty" doc:"最近一次尝试的 HTTP 状态码；网络错误时为 0" example:"200"`
+	LastError      string `json:"last_error,omitempty" doc:"最近一次失败的原因"`
+	DeliveredAt    string `json:"delivered_at,omitempty" doc:"投递成功时间（RFC3339）" format:"date-time"`
+	CreatedAt      string `json:"created_at" doc:"创建时间（RFC3339）" format:"date-time"`
+	UpdatedAt      string `json:"updated_at" doc:"最近更新时间（RFC3339）" format:"date-time"`
+}
+
+// AdminEntitlementWebhookDeliveriesResponse 是 GET /admin/webhooks/deliveries 的响应负载。
+type AdminEntitlementWebhookDeliveriesResponse struct {
+	Deliveries []AdminEntitlementWebhookDeliveryView `json:"deliveries" doc:"投递记录，按 id 倒序"`
+	NextCursor string                                `json:"next_cursor,omitempty" doc:"下一页游标；为空表示没有更多结果"`
+}
+
+// AdminEntitlementWebhookReplayResponse 是重放接口的响应负载。
+type AdminEntitlementWebhookReplayResponse struct {
+	Replayed int64 `json:"replayed" doc:"重新排入队列的投递数" example:"1"`
+}
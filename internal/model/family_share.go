@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// AppleFamilyShare 是 apple_family_shares 行的领域投影：家庭成员通过 Family Sharing 获得的 Apple 订阅权益。
+//
+// 与购买者的 apple_subscriptions 分开记录：家庭成员的 transaction 有自己的 original_transaction_id，
+// 但不带本服务签发的 appAccountToken，只能由家庭成员自己通过 verify 认领。
+type AppleFamilyShare struct {
+	ID                    int64
+	UserID                int64
+	Environment           AppleEnvironment
+	OriginalTransactionID string
+	LastTransactionID     string
+	PlanID                string
+	ProviderProductID     string
+	Level                 int
+	Status                string
+	CurrentPeriodEnd      time.Time
+	RevokedAt             *time.Time
+	LastEventAt           time.Time
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+}
+
+// AppleFamilyShareUpsert 是写入 / 更新家庭共享权益的入参。Status 复用 SubscriptionStatus* 常量。
+type AppleFamilyShareUpsert struct {
+	UserID                int64
+	Environment           AppleEnvironment
+	OriginalTransactionID string
+	LastTransactionID     string
+	PlanID                string
+	ProviderProductID     string
+	Level                 int
+	Status                string
+	CurrentPeriodEnd      time.Time
+	RevokedAt             *time.Time
+	LastEventAt           time.Time
+}
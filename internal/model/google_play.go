@@ -0,0 +1,106 @@
+package model
+
+import "time"
+
+// google_play_events.notification_kind：RTDN 消息里携带的通知种类。
+const (
+	GooglePlayNotificationSubscription = "subscription"
+	GooglePlayNotificationOneTime      = "one_time_product"
+	GooglePlayNotificationVoided       = "voided_purchase"
+	GooglePlayNotificationTest         = "test"
+)
+
+// GooglePlaySubscription 是 google_play_subscriptions 行的领域投影。
+//
+// Status / AutoRenewStatus 复用 Apple 订阅的内部状态取值，reader 按同一套规则映射对外 status。
+type GooglePlaySubscription struct {
+	ID                  int64
+	UserID              int64
+	PackageName         string
+	PurchaseToken       string
+	LinkedPurchaseToken string
+	LatestOrderID       string
+	PlanID              string
+	ProviderProductID   string
+	Level               int
+	Status              string
+	AutoRenewStatus     string
+	TestPurchase        bool
+	CurrentPeriodStart  time.Time
+	CurrentPeriodEnd    time.Time
+	LastEventAt         time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// GooglePlaySubscriptionUpsert 是写入 google_play_subscriptions 的入参；PurchaseToken 是幂等键。
+type GooglePlaySubscriptionUpsert struct {
+	UserID              int64
+	PackageName         string
+	PurchaseToken       string
+	LinkedPurchaseToken string
+	LatestOrderID       string
+	PlanID              string
+	ProviderProductID   string
+	Level               int
+	Status              string
+	AutoRenewStatus     string
+	TestPurchase        bool
+	CurrentPeriodStart  time.Time
+	CurrentPeriodEnd    time.Time
+	LastEventAt         time.Time
+}
+
+// GooglePlayPurchase 是 google_play_purchases 行的领域投影：一次消耗型 / 非消耗型购买。
+type GooglePlayPurchase struct {
+	ID                int64
+	UserID            int64
+	PackageName       string
+	PurchaseToken     string
+	OrderID           string
+	PlanID            string
+	ProviderProductID string
+	ProductType       string
+	Quantity          int
+	Level             int
+	Credits           int64
+	CreditBucketID    int64
+	Status            string
+	TestPurchase      bool
+	PurchasedAt       time.Time
+	RevokedAt         *time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// GooglePlayPurchaseInsert 是 InsertGooglePlayPurchaseIfNotExists 的入参；PurchaseToken 是幂等键。
+type GooglePlayPurchaseInsert struct {
+	UserID            int64
+	PackageName       string
+	PurchaseToken     string
+	OrderID           string
+	PlanID            string
+	ProviderProductID string
+	ProductType       string
+	Quantity          int
+	Level             int
+	Credits           int64
+	TestPurchase      bool
+	PurchasedAt       time.Time
+}
+
+// GooglePlayEventInsert 是 google_play_events 的入参；MessageID（Pub/Sub messageId）是幂等键。
+//
+// UserID == 0 表示尚未绑定；ProcessingStatus 复用 apple_events 的取值。
+type GooglePlayEventInsert struct {
+	MessageID        string
+	NotificationKind string
+	NotificationType int
+	PackageName      string
+	PurchaseToken    string
+	ProductID        string
+	UserID           int64
+	ProcessingStatus string
+	ProcessingError  string
+	EventTime        *time.Time
+}
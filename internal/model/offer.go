@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Apple 订阅优惠类型：promotional 面向当前或曾经的订阅者，win_back 只面向已流失（当前无有效订阅）的订阅者。
+const (
+	OfferTypePromotional = "promotional"
+	OfferTypeWinBack     = "win_back"
+)
+
+// AppleOfferSignature 是 apple_offer_signatures 行的领域投影：一次签发的 StoreKit 优惠签名，用于审计。
+type AppleOfferSignature struct {
+	ID              int64
+	UserID          int64
+	OfferID         string
+	OfferType       string
+	ProductID       string
+	AppAccountToken string
+	Nonce           string
+	SignedAt        time.Time
+	CreatedAt       time.Time
+}
+
+// AppleOfferSignatureInsert 是记录一次优惠签名的输入。
+type AppleOfferSignatureInsert struct {
+	UserID          int64
+	OfferID         string
+	OfferType       string
+	ProductID       string
+	AppAccountToken string
+	Nonce           string
+	SignedAt        time.Time
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// apple_payload_archives.source：归档内容来自哪条链路。
+const (
+	PayloadSourceNotification = "NOTIFICATION"
+	PayloadSourceTransaction  = "TRANSACTION"
+)
+
+// ApplePayloadArchive 是 apple_payload_archives 行的领域投影：解码后的 Apple 通知 / transaction 的密文。
+//
+// Ciphertext 由每行独立的 data key 加密，data key 由 KeyID 指定的 master key 包裹为 WrappedKey；
+// 轮换 master key 只需改写 KeyID / WrappedKey。EventID 仅 NOTIFICATION 行有值，UserID == 0 表示未绑定用户。
+type ApplePayloadArchive struct {
+	ID                    int64
+	Source                string
+	EventID               int64
+	UserID                int64
+	Environment           AppleEnvironment
+	OriginalTransactionID string
+	TransactionID         string
+	KeyID                 string
+	WrappedKey            []byte
+	Nonce                 []byte
+	Ciphertext            []byte
+	CreatedAt             time.Time
+	ExpiresAt             time.Time
+}
+
+// ApplePayload 是解密后的归档，供客服排查争议使用；Payload 为解码后的 JSON。
+type ApplePayload struct {
+	ID                    int64
+	Source                string
+	EventID               int64
+	UserID                int64
+	Environment           AppleEnvironment
+	OriginalTransactionID string
+	TransactionID         string
+	Payload               []byte
+	CreatedAt             time.Time
+	ExpiresAt             time.Time
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// PaywallExposure 是 paywall_exposures 行的领域投影：用户在某个实验中被分到的组及曝光时间。
+type PaywallExposure struct {
+	ID             int64
+	ExperimentID   string
+	VariantID      string
+	UserID         int64
+	Platform       string
+	FirstExposedAt time.Time
+	LastExposedAt  time.Time
+}
+
+// PaywallExposureInsert 是记录一次付费墙曝光的输入；VariantID 只在首次曝光时写入。
+type PaywallExposureInsert struct {
+	ExperimentID string
+	VariantID    string
+	UserID       int64
+	Platform     string
+	ExposedAt    time.Time
+}
+
+// PaywallVariantStats 是某个实验分组的曝光与转化人数。
+type PaywallVariantStats struct {
+	VariantID   string
+	Exposures   int64
+	Conversions int64
+}
+
+// AdminPaywallVariantResultView 是实验报表中的一个分组。
+type AdminPaywallVariantResultView struct {
+	VariantID      string  `json:"variant_id" doc:"分组 ID" example:"annual_first"`
+	Weight         int     `json:"weight" doc:"当前配置的流量权重；分组已从配置中移除时为 0" example:"50"`
+	Exposures      int64   `json:"exposures" doc:"曝光人数（按用户去重）" example:"1200"`
+	Conversions    int64   `json:"conversions" doc:"曝光后创建了 Apple 订阅的人数" example:"36"`
+	ConversionRate float64 `json:"conversion_rate" doc:"conversions / exposures；没有曝光时为 0" example:"0.03"`
+}
+
+// AdminPaywallExperimentResultsResponse 是 GET /admin/paywall/experiments/{id}/results 的响应负载。
+type AdminPaywallExperimentResultsResponse struct {
+	ExperimentID string                          `json:"experiment_id" doc:"实验 ID" example:"annual_first"`
+	Active       bool                            `json:"active" doc:"实验是否仍在当前配置中"`
+	Variants     []AdminPaywallVariantResultView `json:"variants" doc:"各分组结果：先按配置顺序列出配置中的分组，再列出已移除但仍有曝光的分组"`
+}
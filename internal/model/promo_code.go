@@ -0,0 +1,95 @@
+package model
+
+import "time"
+
+// PromoCode 是 promo_codes 行的领域投影：兑换后赠送 CompDays 天 PlanID / Level 的会员权益。
+type PromoCode struct {
+	Code     string
+	PlanID   string
+	Level    int
+	CompDays int
+	// MaxRedemptions 是全部用户合计的兑换上限，0 表示不限。
+	MaxRedemptions int
+	RedeemedCount  int
+	ExpiresAt      *time.Time
+	Active         bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// PromoCodeInput 是后台创建兑换码时的入参。
+type PromoCodeInput struct {
+	Code           string
+	PlanID         string
+	Level          int
+	CompDays       int
+	MaxRedemptions int
+	ExpiresAt      *time.Time
+}
+
+// AdminPromoCodeRequest 是 POST /admin/promo-codes 的请求体。
+type AdminPromoCodeRequest struct {
+	Code           string     `json:"code" doc:"兑换码（不区分大小写，保存为大写）" minLength:"1" maxLength:"32" example:"SPRING2026"`
+	PlanID         string     `json:"plan_id" doc:"赠送权益对应的内部 plan" minLength:"1" example:"pro"`
+	Level          int        `json:"level" doc:"赠送权益的订阅等级" minimum:"1" example:"1"`
+	CompDays       int        `json:"comp_days" doc:"赠送会员天数" minimum:"1" example:"30"`
+	MaxRedemptions int        `json:"max_redemptions,omitempty" doc:"全部用户合计的兑换上限，0 或省略表示不限" minimum:"0" example:"1000"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" doc:"兑换截止时间（RFC3339）；省略表示长期有效" format:"date-time"`
+}
+
+// AdminPromoCodeView 是后台展示的兑换码。
+type AdminPromoCodeView struct {
+	Code           string `json:"code" doc:"兑换码" example:"SPRING2026"`
+	PlanID         string `json:"plan_id" doc:"赠送权益对应的内部 plan" example:"pro"`
+	Level          int    `json:"level" doc:"赠送权益的订阅等级" example:"1"`
+	CompDays       int    `json:"comp_days" doc:"赠送会员天数" example:"30"`
+	MaxRedemptions int    `json:"max_redemptions" doc:"兑换上限，0 表示不限" example:"1000"`
+	RedeemedCount  int    `json:"redeemed_count" doc:"已兑换次数" example:"12"`
+	ExpiresAt      string `json:"expires_at,omitempty" doc:"兑换截止时间（RFC3339）；长期有效时省略" format:"date-time"`
+	Active         bool   `json:"active" doc:"是否可兑换；停用后不可恢复"`
+	CreatedAt      string `json:"created_at" format:"date-time"`
+	UpdatedAt      string `json:"updated_at" format:"date-time"`
+}
+
+// AdminPromoCodesResponse 是 GET /admin/promo-codes 的响应负载。
+type AdminPromoCodesResponse struct {
+	PromoCodes []AdminPromoCodeView `json:"promo_codes"`
+}
+
+// AdminCompGrantRequest 是 POST /admin/users/{id}/comps 的请求体。
+type AdminCompGrantRequest struct {
+	PlanID   string `json:"plan_id" doc:"赠送权益对应的内部 plan" minLength:"1" example:"pro"`
+	Level    int    `json:"level" doc:"赠送权益的订阅等级" minimum:"1" example:"1"`
+	CompDays int    `json:"comp_days" doc:"赠送会员天数" minimum:"1" maximum:"3650" example:"30"`
+	Note     string `json:"note" doc:"发放原因，写入审计日志并随权益保存" minLength:"1" maxLength:"500" example:"补偿 3 月故障"`
+}
+
+// AdminCompEntitlementView 是后台展示的一段赠送权益。
+type AdminCompEntitlementView struct {
+	ID        string `json:"id" doc:"赠送权益 ID" example:"42"`
+	PlanID    string `json:"plan_id" doc:"内部 plan" example:"pro"`
+	Level     int    `json:"level" doc:"订阅等级" example:"1"`
+	Source    string `json:"source" doc:"来源" enum:"REFERRAL,ADMIN,PROMO_CODE"`
+	Reference string `json:"reference" doc:"来源引用，如 referral:<兑换 ID>、admin:<操作人 ID>、promo:<兑换码>"`
+	Note      string `json:"note,omitempty" doc:"后台发放时填写的原因"`
+	StartsAt  string `json:"starts_at" doc:"开始时间；新权益排在已有赠送权益之后" format:"date-time"`
+	EndsAt    string `json:"ends_at" format:"date-time"`
+	RevokedAt string `json:"revoked_at,omitempty" doc:"撤销时间；未撤销时省略" format:"date-time"`
+}
+
+// AdminCompEntitlementsResponse 是 GET /admin/users/{id}/comps 的响应负载。
+type AdminCompEntitlementsResponse struct {
+	Comps []AdminCompEntitlementView `json:"comps"`
+}
+
+// RedeemPromoCodeRequest 是 POST /users/me/promo-codes/redeem 的请求体。
+type RedeemPromoCodeRequest struct {
+	Code string `json:"code" doc:"兑换码（不区分大小写）" minLength:"1" maxLength:"32" example:"SPRING2026"`
+}
+
+// RedeemPromoCodeResponse 是 POST /users/me/promo-codes/redeem 的响应负载。
+type RedeemPromoCodeResponse struct {
+	PlanID     string `json:"plan_id" doc:"赠送权益对应的内部 plan" example:"pro"`
+	CompDays   int    `json:"comp_days" doc:"本次赠送的会员天数" example:"30"`
+	CompEndsAt string `json:"comp_ends_at" doc:"本次赠送会员的到期时间（RFC3339）" format:"date-time"`
+}
@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+// 商品类型：catalog 中每个产品声明的售卖方式，与 Apple transaction 的 type 一一对应。
+const (
+	ProductTypeSubscription  = "subscription"
+	ProductTypeConsumable    = "consumable"
+	ProductTypeNonConsumable = "non_consumable"
+)
+
+// apple_purchases.status：一次性购买的状态。退款后置为 REFUNDED，不再计入权益。
+const (
+	PurchaseStatusActive   = "ACTIVE"
+	PurchaseStatusRefunded = "REFUNDED"
+)
+
+// Purchase 是 apple_purchases 行的领域投影：一次消耗型 / 非消耗型购买。
+//
+// Credits 是本次购买应发放的积分总数（catalog 单价 × quantity）；CreditBucketID 为 0 表示没有发放积分。
+type Purchase struct {
+	ID                    int64
+	UserID                int64
+	Environment           AppleEnvironment
+	TransactionID         string
+	OriginalTransactionID string
+	PlanID                string
+	ProviderProductID     string
+	ProductType           string
+	Quantity              int
+	Level                 int
+	Credits               int64
+	CreditBucketID        int64
+	Status                string
+	PurchasedAt           time.Time
+	RevokedAt             *time.Time
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+}
+
+// PurchaseInsert 是 InsertPurchaseIfNotExists 的入参；(TransactionID, Environment) 是幂等键。
+type PurchaseInsert struct {
+	UserID                int64
+	Environment           AppleEnvironment
+	TransactionID         string
+	OriginalTransactionID string
+	PlanID                string
+	ProviderProductID     string
+	ProductType           string
+	Quantity              int
+	Level                 int
+	Credits               int64
+	PurchasedAt           time.Time
+}
+
+// PurchaseInfo 是 verify 接口中一次性购买的对外视图。
+type PurchaseInfo struct {
+	TransactionID  string `json:"transaction_id" doc:"Apple transactionId / Google Play orderId" example:"200000123456789"`
+	ProductID      string `json:"product_id" doc:"内部 plan id" example:"credits_100"`
+	ProductType    string `json:"product_type" doc:"商品类型" example:"consumable" enum:"consumable,non_consumable"`
+	Status         string `json:"status" doc:"购买状态" example:"ACTIVE" enum:"ACTIVE,REFUNDED"`
+	CreditsGranted int64  `json:"credits_granted" doc:"本次购买发放的积分（非消耗型为 0）" example:"100" minimum:"0"`
+	PurchasedAt    string `json:"purchased_at" doc:"购买时间（RFC3339）" example:"2026-02-23T12:00:00Z" format:"date-time"`
+}
@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+// 购买记录条目的类型（GET /users/me/purchases 的 items[].type）。
+const (
+	PurchaseHistoryPurchase   = "PURCHASE"
+	PurchaseHistoryRenewal    = "RENEWAL"
+	PurchaseHistoryRefund     = "REFUND"
+	PurchaseHistoryPlanChange = "PLAN_CHANGE"
+	PurchaseHistoryExpiration = "EXPIRATION"
+)
+
+// 购买时间线条目的来源：已处理的通知，或没有对应已处理通知的购买 / 订阅行（只经过 verify、通知尚未到达等）。
+const (
+	PurchaseTimelineSourceEvent        = "EVENT"
+	PurchaseTimelineSourcePurchase     = "PURCHASE"
+	PurchaseTimelineSourceSubscription = "SUBSCRIPTION"
+)
+
+// PurchaseTimelineQuery 是按用户分页读取购买时间线的条件。
+//
+// 结果按 (OccurredAt, Source, ID) 倒序；BeforeAt 为零值表示第一页，否则只返回排在
+// (BeforeAt, BeforeSource, BeforeID) 之后的条目。
+type PurchaseTimelineQuery struct {
+	UserID            int64
+	Environments      []AppleEnvironment
+	NotificationTypes []string
+	BeforeAt          time.Time
+	BeforeSource      string
+	BeforeID          int64
+	Limit             int
+}
+
+// PurchaseTimelineEvent 是时间线中的一条记录。Source 为 EVENT 时是已处理的通知，ProductID 优先取通知自身记录的商品，
+// 旧数据回退到对应的购买 / 订阅行；为 PURCHASE / SUBSCRIPTION 时是没有对应通知的购买 / 订阅行，NotificationType
+// 分别记为 ONE_TIME_CHARGE / SUBSCRIBED，ID 是该行在各自表中的 id。
+type PurchaseTimelineEvent struct {
+	Source           string
+	ID               int64
+	NotificationType string
+	Subtype          string
+	Environment      AppleEnvironment
+	OccurredAt       time.Time
+	ProductID        string
+	RenewalProductID string
+}
+
+// PurchaseHistoryItem 是购买记录中的一条。不包含 transaction id、appAccountToken 等 Apple 标识。
+type PurchaseHistoryItem struct {
+	Type        string `json:"type" enum:"PURCHASE,RENEWAL,REFUND,PLAN_CHANGE,EXPIRATION" doc:"记录类型" example:"RENEWAL"`
+	OccurredAt  string `json:"occurred_at" doc:"发生时间（RFC3339）" example:"2026-02-23T12:00:00Z" format:"date-time"`
+	PlanID      string `json:"plan_id" doc:"业务 plan 标识；商品不在目录中时为空" example:"pro_monthly"`
+	ProductID   string `json:"product_id" doc:"App Store 商品标识" example:"com.app.pro.monthly"`
+	ToPlanID    string `json:"to_plan_id,omitempty" doc:"PLAN_CHANGE 的目标 plan" example:"basic_monthly"`
+	ToProductID string `json:"to_product_id,omitempty" doc:"PLAN_CHANGE 的目标商品" example:"com.app.basic.monthly"`
+	Reason      string `json:"reason,omitempty" doc:"补充说明，如 UPGRADE / DOWNGRADE、VOLUNTARY / BILLING_RETRY" example:"DOWNGRADE"`
+}
+
+// PurchaseHistoryResponse 是 GET /users/me/purchases 的响应。
+type PurchaseHistoryResponse struct {
+	Items      []PurchaseHistoryItem `json:"items" doc:"按时间倒序的购买记录"`
+	NextCursor string                `json:"next_cursor,omitempty" doc:"下一页游标；为空表示没有更多记录"`
+}
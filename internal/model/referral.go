@@ -0,0 +1,92 @@
+package model
+
+import "time"
+
+// comp_entitlements.source：赠送权益的来源。
+const (
+	CompSourceReferral  = "REFERRAL"
+	CompSourceAdmin     = "ADMIN"
+	CompSourcePromoCode = "PROMO_CODE"
+)
+
+// ReferralRewards 描述一次成功邀请给某一方发放的奖励；两项均为 0 表示该方不发奖励。
+type ReferralRewards struct {
+	Credits  int64
+	CompDays int
+}
+
+// ReferralRedemption 是 referral_redemptions 行的领域投影，记录兑换双方与实际发放的奖励。
+type ReferralRedemption struct {
+	ID            int64
+	InviterUserID int64
+	InviteeUserID int64
+	Code          string
+	DeviceKey     string
+	InviterReward ReferralRewards
+	InviteeReward ReferralRewards
+	CreatedAt     time.Time
+}
+
+// ReferralStats 是邀请人视角的邀请统计。
+type ReferralStats struct {
+	InvitedCount   int
+	EarnedCredits  int64
+	EarnedCompDays int
+}
+
+// CompEntitlement 是 comp_entitlements 行的领域投影：一段赠送的订阅权益。
+type CompEntitlement struct {
+	ID        int64
+	UserID    int64
+	PlanID    string
+	Level     int
+	Source    string
+	Reference string
+	Note      string
+	StartsAt  time.Time
+	EndsAt    time.Time
+	RevokedAt *time.Time
+}
+
+// CompGrant 是发放赠送权益时的入参。新权益接在该用户已有赠送权益的末尾，避免重叠浪费。
+type CompGrant struct {
+	UserID    int64
+	PlanID    string
+	Level     int
+	Source    string
+	Reference string
+	Note      string
+	Duration  time.Duration
+}
+
+// ReferralRewardView 是 API 中展示的奖励内容。
+type ReferralRewardView struct {
+	Credits  int64 `json:"credits" doc:"奖励积分" example:"100" minimum:"0"`
+	CompDays int   `json:"comp_days" doc:"赠送会员天数" example:"7" minimum:"0"`
+}
+
+// ReferralInfoResponse 是 GET /users/me/referral 的响应负载。
+type ReferralInfoResponse struct {
+	Code             string             `json:"code" doc:"我的邀请码，长期不变" example:"K7M2Q9XA"`
+	InvitedCount     int                `json:"invited_count" doc:"已成功邀请的人数" example:"3" minimum:"0"`
+	InviteLimit      int                `json:"invite_limit" doc:"可获得奖励的邀请人数上限，0 表示不限" example:"50" minimum:"0"`
+	RemainingInvites int                `json:"remaining_invites" doc:"剩余可邀请人数；invite_limit 为 0 时恒为 -1" example:"47"`
+	EarnedCredits    int64              `json:"earned_credits" doc:"通过邀请累计获得的积分" example:"300" minimum:"0"`
+	EarnedCompDays   int                `json:"earned_comp_days" doc:"通过邀请累计获得的会员天数" example:"0" minimum:"0"`
+	InviterReward    ReferralRewardView `json:"inviter_reward" doc:"每成功邀请一人，邀请人获得的奖励"`
+	InviteeReward    ReferralRewardView `json:"invitee_reward" doc:"被邀请人兑换邀请码获得的奖励"`
+	RedeemWindowDays int                `json:"redeem_window_days" doc:"注册后多少天内可以兑换邀请码，0 表示不限" example:"7" minimum:"0"`
+	RedeemedCode     string             `json:"redeemed_code,omitempty" doc:"我已兑换过的邀请码；未兑换时省略"`
+}
+
+// RedeemReferralRequest 是 POST /users/me/referral/redeem 的请求体。
+type RedeemReferralRequest struct {
+	Code     string `json:"code" doc:"邀请码（不区分大小写）" minLength:"1" maxLength:"32" example:"K7M2Q9XA"`
+	DeviceID string `json:"device_id,omitempty" doc:"客户端设备标识；非游客账号必填，用于限制同一设备只能兑换一次" maxLength:"128"`
+}
+
+// RedeemReferralResponse 是 POST /users/me/referral/redeem 的响应负载。
+type RedeemReferralResponse struct {
+	Reward     ReferralRewardView `json:"reward" doc:"本次发放给我的奖励"`
+	CompEndsAt string             `json:"comp_ends_at,omitempty" doc:"赠送会员的到期时间（RFC3339）；没有赠送会员时省略" format:"date-time"`
+}
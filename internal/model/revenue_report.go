@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// RevenueEventQuery 是按时间顺序分页读取 apple_events 的条件：只返回 Until 之前、排在 (AfterAt, AfterID) 之后的事件。
+type RevenueEventQuery struct {
+	Until   time.Time
+	AfterAt time.Time
+	AfterID int64
+	Limit   int
+}
+
+// RevenueEvent 是收入报表重放的一条 Apple 通知。ProductID 优先取通知自身记录的商品，旧数据回退到订阅行。
+type RevenueEvent struct {
+	ID                    int64
+	NotificationType      string
+	Subtype               string
+	Environment           AppleEnvironment
+	OriginalTransactionID string
+	OccurredAt            time.Time
+	ProductID             string
+	OfferDiscountType     string
+}
+
+// RevenueReportRow 是收入报表中一个周期、环境、plan 的指标。
+type RevenueReportRow struct {
+	PeriodStart         string  `json:"period_start" doc:"周期开始（含，RFC3339，UTC）" format:"date-time"`
+	PeriodEnd           string  `json:"period_end" doc:"周期结束（不含，RFC3339，UTC）" format:"date-time"`
+	Environment         string  `json:"environment" doc:"Apple 环境" example:"Production"`
+	PlanID              string  `json:"plan_id" doc:"内部套餐 ID；不在商品目录中的商品以 productId 代替" example:"pro_monthly"`
+	Active              int64   `json:"active" doc:"周期结束时的有效订阅数（含试用中）" example:"1200"`
+	New                 int64   `json:"new" doc:"首次订阅数（含免费试用开始）" example:"80"`
+	Renewed             int64   `json:"renewed" doc:"续期数（含试用转付费）" example:"900"`
+	Churned             int64   `json:"churned" doc:"到期未续 / 撤销而流失的订阅数" example:"40"`
+	Refunded            int64   `json:"refunded" doc:"退款数" example:"3"`
+	Reactivated         int64   `json:"reactivated" doc:"流失后重新订阅或扣费恢复的订阅数" example:"12"`
+	TrialStarted        int64   `json:"trial_started" doc:"开始免费试用数" example:"50"`
+	TrialConverted      int64   `json:"trial_converted" doc:"免费试用转付费数" example:"20"`
+	TrialExpired        int64   `json:"trial_expired" doc:"免费试用未转付费即结束的数量" example:"25"`
+	TrialConversionRate float64 `json:"trial_conversion_rate" doc:"trial_converted / (trial_converted + trial_expired)；没有结束的试用时为 0" example:"0.44"`
+	MRRMilliunits       int64   `json:"mrr_milliunits" doc:"周期结束时的 MRR，按商品目录参考价格折算为月金额，单位为 currency 的千分之一；试用中的订阅不计入" example:"11988000"`
+	Currency            string  `json:"currency,omitempty" doc:"MRR 币种；该 plan 没有定价时为空" example:"USD"`
+}
+
+// RevenueReportResponse 是 GET /admin/reports/revenue 的 JSON 响应负载。
+type RevenueReportResponse struct {
+	From        string             `json:"from" doc:"报表开始时间（含，RFC3339）" format:"date-time"`
+	To          string             `json:"to" doc:"报表结束时间（不含，RFC3339）" format:"date-time"`
+	Granularity string             `json:"granularity" doc:"周期粒度" enum:"day,week,month" example:"month"`
+	Rows        []RevenueReportRow `json:"rows" doc:"按周期、环境、plan 排列；没有任何数据的组合省略"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "encoding/json"
+
+// UserSettings 是 GET/PUT /users/me/settings 返回的负载：包含所有登记配置项的当前值（未覆盖的为默认值）。
+type UserSettings struct {
+	Settings map[string]any `json:"settings" doc:"配置项 key 到当前值的映射，包含全部服务端登记的 key"`
+}
+
+// UpdateUserSettingsRequest 是 PUT /users/me/settings 的请求体，只需要携带要修改的 key。
+type UpdateUserSettingsRequest struct {
+	Settings map[string]json.RawMessage `json:"settings" doc:"要修改的配置项；未出现的 key 保持不变，值为 null 表示恢复默认值" required:"true"`
+}
@@ -0,0 +1,78 @@
+package model
+
+import "time"
+
+// StripeCustomer 是 stripe_customers 行：本地 user 与 Stripe customer 的一一映射。
+type StripeCustomer struct {
+	ID         int64
+	UserID     int64
+	CustomerID string
+	CreatedAt  time.Time
+}
+
+// StripeSubscription 是 stripe_subscriptions 行的领域投影。
+//
+// Status / AutoRenewStatus 复用 Apple 订阅的内部状态取值；StripeStatus 保留 Stripe 原始 status 便于排查。
+type StripeSubscription struct {
+	ID                 int64
+	UserID             int64
+	CustomerID         string
+	SubscriptionID     string
+	PriceID            string
+	PlanID             string
+	Level              int
+	Status             string
+	StripeStatus       string
+	AutoRenewStatus    string
+	CurrentPeriodStart time.Time
+	CurrentPeriodEnd   time.Time
+	LastEventAt        time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// StripeSubscriptionUpsert 是写入 stripe_subscriptions 的入参；SubscriptionID 是幂等键。
+type StripeSubscriptionUpsert struct {
+	UserID             int64
+	CustomerID         string
+	SubscriptionID     string
+	PriceID            string
+	PlanID             string
+	Level              int
+	Status             string
+	StripeStatus       string
+	AutoRenewStatus    string
+	CurrentPeriodStart time.Time
+	CurrentPeriodEnd   time.Time
+	LastEventAt        time.Time
+}
+
+// StripeEventInsert 是 stripe_events 的入参；EventID（Stripe event id）是幂等键。
+//
+// UserID == 0 表示尚未绑定；ProcessingStatus 复用 apple_events 的取值。
+type StripeEventInsert struct {
+	EventID          string
+	EventType        string
+	CustomerID       string
+	SubscriptionID   string
+	UserID           int64
+	ProcessingStatus string
+	ProcessingError  string
+	EventCreatedAt   *time.Time
+}
+
+// StripeCheckoutRequest 是 POST /payment/stripe/checkout 的请求体。
+type StripeCheckoutRequest struct {
+	PlanID string `json:"plan_id" doc:"内部 plan id（STRIPE_PRODUCTS 中配置）" required:"true" minLength:"1" example:"pro_monthly"`
+}
+
+// StripeCheckoutResponse 是 POST /payment/stripe/checkout 的响应负载。
+type StripeCheckoutResponse struct {
+	SessionID string `json:"session_id" doc:"Stripe Checkout Session id" example:"cs_test_a1b2c3"`
+	URL       string `json:"url" doc:"跳转到 Stripe 托管的支付页面" example:"https://checkout.stripe.com/c/pay/cs_test_a1b2c3"`
+}
+
+// StripePortalResponse 是 POST /payment/stripe/portal 的响应负载。
+type StripePortalResponse struct {
+	URL string `json:"url" doc:"Stripe customer portal 链接，用于管理订阅与付款方式" example:"https://billing.stripe.com/p/session/test_a1b2c3"`
+}
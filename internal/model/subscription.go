@@ -53,6 +53,7 @@ const (
 	EventStatusProcessed          = "PROCESSED"
 	EventStatusIgnoredDuplicate   = "IGNORED_DUPLICATE"
 	EventStatusIgnoredUnknownType = "IGNORED_UNKNOWN_TYPE"
+	EventStatusIgnoredStale       = "IGNORED_STALE"
 	EventStatusPendingUserBinding = "PENDING_USER_BINDING"
 	EventStatusOwnershipConflict  = "OWNERSHIP_CONFLICT"
 	EventStatusPermanentFailure   = "PERMANENT_FAILURE"
@@ -80,8 +81,15 @@ type Subscription struct {
 	LastNotificationCreatedAt *time.Time
 	LastPayloadHash           string
 	LastTransactionSnapshot   []byte
-	CreatedAt                 time.Time
-	UpdatedAt                 time.Time
+	// Pending* 是同组内已排定、下次续期才生效的目标商品（降级 / 平级切换）；PendingProductID 为空表示无待生效变更。
+	PendingProductID string
+	PendingPlanID    string
+	PendingLevel     int
+	// BillingRetry / ExpirationIntent 来自 renewal info：Apple 是否仍在重试扣款，以及到期原因（0 表示未知）。
+	BillingRetry     bool
+	ExpirationIntent int
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
 }
 
 // SubscriptionUpsert 是 webhook reducer / verify 写入 apple_subscriptions 时使用的入参。
@@ -108,6 +116,11 @@ type SubscriptionUpsert struct {
 	LastNotificationCreatedAt *time.Time
 	LastPayloadHash           string
 	LastTransactionSnapshot   []byte
+	PendingProductID          string
+	PendingPlanID             string
+	PendingLevel              int
+	BillingRetry              bool
+	ExpirationIntent          int
 }
 
 // AppleEventInsert 是 InsertAppleEventIfNotExists 入参；NotificationUUID 是幂等键。
@@ -129,4 +142,49 @@ type AppleEventInsert struct {
 	RawJWSSHA256          string
 	DecodedPayload        []byte
 	NotificationCreatedAt *time.Time
+	// ProductID / RenewalProductID 是 transaction 的 productId 与 renewal info 的 autoRenewProductId，供购买记录展示。
+	ProductID        string
+	RenewalProductID string
+	// OfferDiscountType 是 transaction 的 offerDiscountType，收入报表据此识别免费试用。
+	OfferDiscountType string
+}
+
+// AppleEvent 是 apple_events 行的领域投影，供 PENDING_USER_BINDING 事件重放使用。
+//
+// DecodedPayload 是入库时保留的最小重放字段（JSON），重放时不需要再请求 Apple；开启加密归档时该列为空，
+// 重放改为解密该通知的归档，RawJWSSHA256 用于还原 payload hash。
+type AppleEvent struct {
+	ID                    int64
+	NotificationUUID      string
+	NotificationType      string
+	Environment           AppleEnvironment
+	UserID                int64
+	AppAccountToken       string
+	OriginalTransactionID string
+	ProcessingStatus      string
+	ProcessingError       string
+	RawJWSSHA256          string
+	DecodedPayload        []byte
+	NotificationCreatedAt *time.Time
+	CreatedAt             time.Time
+}
+
+// SubscriptionStatusSyncFilter 是状态同步任务挑选 apple_subscriptions 行的条件。
+//
+// 只选 ACTIVE / CANCELED 的行：current_period_end 早于 PeriodEndBefore（临近或已过期），
+// 或 last_event_at 早于 LastEventBefore（长时间没有通知）；且从未同步过或上次同步早于 SyncedBefore。
+type SubscriptionStatusSyncFilter struct {
+	Environments    []AppleEnvironment
+	PeriodEndBefore time.Time
+	LastEventBefore time.Time
+	SyncedBefore    time.Time
+	Limit           int
+}
+
+// SubscriptionStatusSync 记录一次状态同步的结果；AppleStatus 为 Apple 返回的 status（查询失败时为 0）。
+type SubscriptionStatusSync struct {
+	SubscriptionID int64
+	SyncedAt       time.Time
+	AppleStatus    int
+	LastError      string
 }
@@ -41,21 +41,39 @@ type UserSummary struct {
 }
 
 // Credits 用户当前可用的积分余额。
+//
+// Balance 是所有未过期 bucket 剩余额度之和；Breakdown 按来源拆分，NextExpiresAt /
+// NextExpiringAmount 描述最近一批将要过期的积分，没有会过期的积分时两者为空。
 type Credits struct {
-	Balance int64 `json:"balance" doc:"当前可用积分余额" example:"0" minimum:"0"`
+	Balance            int64             `json:"balance" doc:"当前可用积分余额" example:"0" minimum:"0"`
+	Breakdown          []CreditBreakdown `json:"breakdown" doc:"按来源拆分的可用积分；没有积分时为空数组"`
+	NextExpiresAt      string            `json:"next_expires_at,omitempty" doc:"最近一批积分的过期时间（RFC3339），没有会过期的积分时省略" example:"2026-03-01T00:00:00Z" format:"date-time"`
+	NextExpiringAmount int64             `json:"next_expiring_amount" doc:"在 next_expires_at 到期的积分数量" example:"0" minimum:"0"`
+}
+
+// CreditBreakdown 是某个来源下的可用积分汇总。
+type CreditBreakdown struct {
+	Source  string `json:"source" doc:"积分来源" example:"PROMO" enum:"PURCHASE,PROMO,SUBSCRIPTION_ALLOWANCE,REFERRAL"`
+	Balance int64  `json:"balance" doc:"该来源当前可用积分" example:"100" minimum:"0"`
 }
 
 // SubscriptionInfo 用户当前订阅状态。
 type SubscriptionInfo struct {
-	ProductID            string `json:"product_id" doc:"订阅产品标识（例如 App Store productId）" example:"com.picjoy.pro.monthly"`
-	Status               string `json:"status" doc:"订阅状态" example:"ACTIVE" enum:"ACTIVE,EXPIRED,CANCELED,NONE"`
-	SubscribeExpiredTime string `json:"subscribe_expired_time" doc:"订阅过期时间（RFC3339）" example:"2026-02-23T12:00:00Z" format:"date-time"`
-	SubscribeLevel       int    `json:"subscribe_level" doc:"订阅等级（0 = 免费，其他为付费等级）" example:"1" minimum:"0"`
+	ProductID              string `json:"product_id" doc:"订阅产品标识（例如 App Store productId）" example:"com.picjoy.pro.monthly"`
+	Status                 string `json:"status" doc:"订阅状态；GRACE_PERIOD 为扣款失败但仍在宽限期内（保留权益），BILLING_RETRY 为扣款失败且 Apple 仍在重试（无权益），两者都应提示用户更新支付方式" example:"ACTIVE" enum:"ACTIVE,EXPIRED,CANCELED,GRACE_PERIOD,BILLING_RETRY,NONE"`
+	SubscribeExpiredTime   string `json:"subscribe_expired_time" doc:"订阅过期时间（RFC3339）" example:"2026-02-23T12:00:00Z" format:"date-time"`
+	SubscribeLevel         int    `json:"subscribe_level" doc:"订阅等级（0 = 免费，其他为付费等级）" example:"1" minimum:"0"`
+	PendingProductID       string `json:"pending_product_id,omitempty" doc:"已排定在下次续期生效的订阅产品（同组降级 / 平级切换），无待生效变更时省略" example:"com.picjoy.basic.monthly"`
+	PendingLevel           int    `json:"pending_level,omitempty" doc:"待生效产品的订阅等级" example:"1" minimum:"0"`
+	PendingEffectiveTime   string `json:"pending_effective_time,omitempty" doc:"待生效变更的生效时间（RFC3339），即当前周期结束时间" example:"2026-02-23T12:00:00Z" format:"date-time"`
+	GracePeriodExpiredTime string `json:"grace_period_expired_time,omitempty" doc:"宽限期结束时间（RFC3339），仅 GRACE_PERIOD 时返回" example:"2026-03-01T12:00:00Z" format:"date-time"`
+	ExpirationReason       string `json:"expiration_reason,omitempty" doc:"到期原因，仅 EXPIRED / BILLING_RETRY 且 Apple 给出原因时返回" example:"BILLING_ERROR" enum:"CUSTOMER_CANCELED,BILLING_ERROR,PRICE_INCREASE_DECLINED,PRODUCT_UNAVAILABLE,OTHER"`
 }
 
 // MeData 是 GET /users/me 接口返回的负载。
 type MeData struct {
 	Credits          Credits          `json:"credits" doc:"用户积分余额"`
 	SubscriptionInfo SubscriptionInfo `json:"subscription_info" doc:"用户订阅状态"`
+	Entitlements     []Entitlement    `json:"entitlements" doc:"按 feature set 合并后的生效权益，每个 feature set 一条"`
 	User             UserSummary      `json:"user" doc:"用户基础信息"`
 }
@@ -0,0 +1,259 @@
+package admin
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	logpkg "github.com/dundunHa/go-serverhttp-template/pkg/log"
+)
+
+var (
+	ErrNotConfigured = errors.New("admin: not configured")
+	ErrForbidden     = errors.New("admin: caller is not an admin")
+	ErrInvalidQuery  = errors.New("admin: invalid search query")
+	ErrInvalidCursor = errors.New("admin: invalid cursor")
+	ErrInvalidExpiry = errors.New("admin: expires_at must be in the future")
+	ErrUserNotFound  = dao.ErrAdminUserNotFound
+)
+
+const (
+	defaultPageSize  = 20
+	maxPageSize      = 100
+	detailEventLimit = 20
+	payloadLimit     = 50
+	cursorPrefix     = "u:"
+	maxReasonLength  = 500
+)
+
+// SearchPage 是一页搜索结果；NextCursor 为空表示没有更多结果。
+type SearchPage struct {
+	Users      []model.AdminUser
+	NextCursor string
+}
+
+// PayloadReader 解密 Apple 通知 / transaction 的加密归档，由 payment.PayloadArchive 实现。
+type PayloadReader interface {
+	ListPayloads(ctx context.Context, originalTxID string, limit int) ([]model.ApplePayload, error)
+}
+
+// Service 提供客服后台的用户搜索、详情与账号操作。
+//
+// 管理员身份由配置中的用户 ID 白名单决定（AUTH_ADMIN_USER_IDS）；白名单为空时所有调用都返回
+// ErrForbidden，保证未配置的环境不会意外暴露后台能力。所有写操作都会记录操作人审计日志。
+type Service struct {
+	dao      dao.AdminDAO
+	payloads PayloadReader
+	admins   map[int64]struct{}
+	now      func() time.Time
+}
+
+// NewService 构造 admin service。
+func NewService(d dao.AdminDAO, adminUserIDs []int64) *Service {
+	admins := make(map[int64]struct{}, len(adminUserIDs))
+	for _, id := range adminUserIDs {
+		if id > 0 {
+			admins[id] = struct{}{}
+		}
+	}
+	return &Service{dao: d, admins: admins, now: time.Now}
+}
+
+// WithPayloadReader 开启 Apple 加密归档的解密查询；未设置时 ListApplePayloads 返回 ErrNotConfigured。
+func (s *Service) WithPayloadReader(r PayloadReader) *Service {
+	s.payloads = r
+	return s
+}
+
+// IsAdmin 判断 userID 是否在管理员白名单中。
+func (s *Service) IsAdmin(userID int64) bool {
+	if s == nil {
+		return false
+	}
+	_, ok := s.admins[userID]
+	return ok
+}
+
+// SearchUsers 按 field 精确查找用户。cursor 为上一页返回的 NextCursor，首页传空串。
+func (s *Service) SearchUsers(ctx context.Context, actorID int64, field model.AdminSearchField, query, cursor string, limit int) (SearchPage, error) {
+	if err := s.authorize(actorID); err != nil {
+		return SearchPage{}, err
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return SearchPage{}, fmt.Errorf("%w: q is required", ErrInvalidQuery)
+	}
+	if !isSearchField(field) {
+		return SearchPage{}, fmt.Errorf("%w: unsupported field %q", ErrInvalidQuery, field)
+	}
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	limit = min(limit, maxPageSize)
+	afterID, err := decodeCursor(cursor)
+	if err != nil {
+		return SearchPage{}, err
+	}
+
+	// 多取一条用来判断是否还有下一页，避免额外的 COUNT 查询。
+	users, err := s.dao.SearchUsers(ctx, field, query, afterID, limit+1)
+	if err != nil {
+		return SearchPage{}, err
+	}
+	page := SearchPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = encodeCursor(page.Users[limit-1].ID)
+	}
+	return page, nil
+}
+
+// GetUserDetail 返回用户的身份、订阅与最近的 Apple 通知。
+func (s *Service) GetUserDetail(ctx context.Context, actorID, userID int64) (model.AdminUserDetail, error) {
+	if err := s.authorize(actorID); err != nil {
+		return model.AdminUserDetail{}, err
+	}
+	return s.dao.GetUserDetail(ctx, userID, detailEventLimit)
+}
+
+// ListApplePayloads 解密该 originalTransactionId 下最近的 Apple 通知 / transaction 归档，用于争议排查。
+// 每次查询都记录审计日志，因为返回的是明文支付数据。
+func (s *Service) ListApplePayloads(ctx context.Context, actorID int64, originalTxID string) ([]model.ApplePayload, error) {
+	if err := s.authorize(actorID); err != nil {
+		return nil, err
+	}
+	if s.payloads == nil {
+		return nil, ErrNotConfigured
+	}
+	originalTxID = strings.TrimSpace(originalTxID)
+	if originalTxID == "" {
+		return nil, fmt.Errorf("%w: original_transaction_id is required", ErrInvalidQuery)
+	}
+	payloads, err := s.payloads.ListPayloads(ctx, originalTxID, payloadLimit)
+	if err != nil {
+		return nil, err
+	}
+	logpkg.FromContext(ctx).Info("admin apple payload access",
+		"actor_user_id", actorID,
+		"original_transaction_id", originalTxID,
+		"count", len(payloads),
+	)
+	return payloads, nil
+}
+
+// Suspend 把账号置为 SUSPENDED 并强制下线；expiresAt 非空时到期自动恢复。
+func (s *Service) Suspend(ctx context.Context, actorID, userID int64, reason string, expiresAt *time.Time) (model.AccountState, error) {
+	return s.block(ctx, "suspend", model.UserStatusSuspended, actorID, userID, reason, expiresAt)
+}
+
+// Ban 把账号置为 BANNED 并强制下线；expiresAt 非空时到期自动恢复。
+func (s *Service) Ban(ctx context.Context, actorID, userID int64, reason string, expiresAt *time.Time) (model.AccountState, error) {
+	return s.block(ctx, "ban", model.UserStatusBanned, actorID, userID, reason, expiresAt)
+}
+
+func (s *Service) block(ctx context.Context, action, status string, actorID, userID int64, reason string, expiresAt *time.Time) (model.AccountState, error) {
+	if err := s.authorize(actorID); err != nil {
+		return model.AccountState{}, err
+	}
+	if expiresAt != nil && !expiresAt.After(s.now()) {
+		return model.AccountState{}, ErrInvalidExpiry
+	}
+	state, err := s.dao.SetUserStatus(ctx, userID, status, truncateReason(reason), expiresAt, true)
+	if err != nil {
+		return model.AccountState{}, err
+	}
+	s.audit(ctx, action, actorID, userID, reason)
+	return state, nil
+}
+
+// Unsuspend 把账号（SUSPENDED / BANNED）恢复为 ACTIVE；已失效的 token 不会恢复，用户需要重新登录。
+func (s *Service) Unsuspend(ctx context.Context, actorID, userID int64, reason string) (model.AccountState, error) {
+	if err := s.authorize(actorID); err != nil {
+		return model.AccountState{}, err
+	}
+	state, err := s.dao.SetUserStatus(ctx, userID, model.UserStatusActive, truncateReason(reason), nil, false)
+	if err != nil {
+		return model.AccountState{}, err
+	}
+	s.audit(ctx, "unsuspend", actorID, userID, reason)
+	return state, nil
+}
+
+// ForceLogout 使该用户此前签发的所有 access token 失效，不改变账号状态。
+func (s *Service) ForceLogout(ctx context.Context, actorID, userID int64, reason string) (model.AccountState, error) {
+	if err := s.authorize(actorID); err != nil {
+		return model.AccountState{}, err
+	}
+	state, err := s.dao.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return model.AccountState{}, err
+	}
+	s.audit(ctx, "force_logout", actorID, userID, reason)
+	return state, nil
+}
+
+func (s *Service) authorize(actorID int64) error {
+	if s == nil || s.dao == nil {
+		return ErrNotConfigured
+	}
+	if !s.IsAdmin(actorID) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *Service) audit(ctx context.Context, action string, actorID, userID int64, reason string) {
+	logpkg.FromContext(ctx).Info("admin user action",
+		"action", action,
+		"actor_user_id", actorID,
+		"target_user_id", userID,
+		"reason", reason,
+	)
+}
+
+func isSearchField(field model.AdminSearchField) bool {
+	switch field {
+	case model.AdminSearchByID,
+		model.AdminSearchByEmail,
+		model.AdminSearchByProviderSubject,
+		model.AdminSearchByAppAccountToken,
+		model.AdminSearchByOriginalTransactionID:
+		return true
+	default:
+		return false
+	}
+}
+
+func truncateReason(reason string) string {
+	reason = strings.TrimSpace(reason)
+	if r := []rune(reason); len(r) > maxReasonLength {
+		return string(r[:maxReasonLength])
+	}
+	return reason
+}
+
+// 游标对客户端不透明：base64url("u:<last user id>")。
+func encodeCursor(lastID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatInt(lastID, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(string(raw), cursorPrefix), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
@@ -0,0 +1,203 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+type fakeAdminDAO struct {
+	users []model.AdminUser
+
+	lastAfterID int64
+	lastLimit   int
+	statusCalls []statusCall
+	revoked     []int64
+}
+
+type statusCall struct {
+	userID         int64
+	status         string
+	reason         string
+	expiresAt      *time.Time
+	revokeSessions bool
+}
+
+func (d *fakeAdminDAO) SearchUsers(_ context.Context, _ model.AdminSearchField, _ string, afterID int64, limit int) ([]model.AdminUser, error) {
+	d.lastAfterID, d.lastLimit = afterID, limit
+	out := []model.AdminUser{}
+	for _, u := range d.users {
+		if u.ID > afterID && len(out) < limit {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+func (d *fakeAdminDAO) GetUserDetail(_ context.Context, userID int64, _ int) (model.AdminUserDetail, error) {
+	for _, u := range d.users {
+		if u.ID == userID {
+			return model.AdminUserDetail{User: u}, nil
+		}
+	}
+	return model.AdminUserDetail{}, ErrUserNotFound
+}
+
+func (d *fakeAdminDAO) SetUserStatus(_ context.Context, userID int64, status, reason string, expiresAt *time.Time, revokeSessions bool) (model.AccountState, error) {
+	d.statusCalls = append(d.statusCalls, statusCall{userID, status, reason, expiresAt, revokeSessions})
+	return model.AccountState{UserID: userID, Status: status, StatusReason: reason, StatusExpiresAt: expiresAt}, nil
+}
+
+func (d *fakeAdminDAO) RevokeUserSessions(_ context.Context, userID int64) (model.AccountState, error) {
+	d.revoked = append(d.revoked, userID)
+	return model.AccountState{UserID: userID, Status: model.UserStatusActive}, nil
+}
+
+func newFakeDAO(ids ...int64) *fakeAdminDAO {
+	d := &fakeAdminDAO{}
+	for _, id := range ids {
+		d.users = append(d.users, model.AdminUser{ID: id, Status: model.UserStatusActive})
+	}
+	return d
+}
+
+func TestSearchUsersRequiresAdmin(t *testing.T) {
+	svc := NewService(newFakeDAO(1), []int64{99})
+	if _, err := svc.SearchUsers(context.Background(), 1, model.AdminSearchByEmail, "a@example.com", "", 0); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("err = %v, want ErrForbidden", err)
+	}
+	empty := NewService(newFakeDAO(1), nil)
+	if _, err := empty.SearchUsers(context.Background(), 1, model.AdminSearchByEmail, "a@example.com", "", 0); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("empty allowlist err = %v, want ErrForbidden", err)
+	}
+}
+
+func TestSearchUsersPaginatesWithCursor(t *testing.T) {
+	d := newFakeDAO(3, 5, 8)
+	svc := NewService(d, []int64{99})
+	ctx := context.Background()
+
+	first, err := svc.SearchUsers(ctx, 99, model.AdminSearchByEmail, "a@example.com", "", 2)
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	if len(first.Users) != 2 || first.Users[1].ID != 5 || first.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+	if d.lastLimit != 3 {
+		t.Fatalf("dao limit = %d, want limit+1", d.lastLimit)
+	}
+
+	second, err := svc.SearchUsers(ctx, 99, model.AdminSearchByEmail, "a@example.com", first.NextCursor, 2)
+	if err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	if d.lastAfterID != 5 || len(second.Users) != 1 || second.Users[0].ID != 8 || second.NextCursor != "" {
+		t.Fatalf("unexpected second page: %+v (after=%d)", second, d.lastAfterID)
+	}
+}
+
+func TestSearchUsersRejectsBadInput(t *testing.T) {
+	svc := NewService(newFakeDAO(1), []int64{99})
+	ctx := context.Background()
+	if _, err := svc.SearchUsers(ctx, 99, model.AdminSearchByEmail, "a@example.com", "not-a-cursor!", 0); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("cursor err = %v, want ErrInvalidCursor", err)
+	}
+	if _, err := svc.SearchUsers(ctx, 99, model.AdminSearchByEmail, "  ", "", 0); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("empty query err = %v, want ErrInvalidQuery", err)
+	}
+	if _, err := svc.SearchUsers(ctx, 99, "phone", "123", "", 0); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("field err = %v, want ErrInvalidQuery", err)
+	}
+}
+
+func TestSuspendRevokesSessionsAndUnsuspendDoesNot(t *testing.T) {
+	d := newFakeDAO(7)
+	svc := NewService(d, []int64{99})
+	ctx := context.Background()
+
+	state, err := svc.Suspend(ctx, 99, 7, "  chargeback fraud ", nil)
+	if err != nil {
+		t.Fatalf("suspend: %v", err)
+	}
+	if state.Status != model.UserStatusSuspended {
+		t.Fatalf("status = %q, want SUSPENDED", state.Status)
+	}
+	if _, err := svc.Unsuspend(ctx, 99, 7, ""); err != nil {
+		t.Fatalf("unsuspend: %v", err)
+	}
+	want := []statusCall{
+		{7, model.UserStatusSuspended, "chargeback fraud", nil, true},
+		{7, model.UserStatusActive, "", nil, false},
+	}
+	if len(d.statusCalls) != len(want) || d.statusCalls[0] != want[0] || d.statusCalls[1] != want[1] {
+		t.Fatalf("status calls = %+v, want %+v", d.statusCalls, want)
+	}
+}
+
+func TestForceLogoutRevokesSessions(t *testing.T) {
+	d := newFakeDAO(7)
+	svc := NewService(d, []int64{99})
+	if _, err := svc.ForceLogout(context.Background(), 99, 7, "lost device"); err != nil {
+		t.Fatalf("force logout: %v", err)
+	}
+	if len(d.revoked) != 1 || d.revoked[0] != 7 || len(d.statusCalls) != 0 {
+		t.Fatalf("revoked = %v statusCalls = %v", d.revoked, d.statusCalls)
+	}
+}
+
+func TestBanWithExpiry(t *testing.T) {
+	d := newFakeDAO(7)
+	svc := NewService(d, []int64{99})
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	past := now.Add(-time.Minute)
+	if _, err := svc.Ban(ctx, 99, 7, "spam", &past); !errors.Is(err, ErrInvalidExpiry) {
+		t.Fatalf("past expiry err = %v, want ErrInvalidExpiry", err)
+	}
+	until := now.Add(7 * 24 * time.Hour)
+	state, err := svc.Ban(ctx, 99, 7, "spam", &until)
+	if err != nil {
+		t.Fatalf("ban: %v", err)
+	}
+	if state.Status != model.UserStatusBanned || state.StatusExpiresAt == nil || !state.StatusExpiresAt.Equal(until) {
+		t.Fatalf("unexpected state: %+v", state)
+	}
+	if len(d.statusCalls) != 1 || !d.statusCalls[0].revokeSessions {
+		t.Fatalf("ban must revoke sessions: %+v", d.statusCalls)
+	}
+}
+
+type fakePayloadReader struct {
+	lastOriginalTxID string
+}
+
+func (r *fakePayloadReader) ListPayloads(_ context.Context, originalTxID string, _ int) ([]model.ApplePayload, error) {
+	r.lastOriginalTxID = originalTxID
+	return []model.ApplePayload{{ID: 1, OriginalTransactionID: originalTxID, Payload: []byte(`{}`)}}, nil
+}
+
+func TestListApplePayloads(t *testing.T) {
+	svc := NewService(newFakeDAO(1), []int64{99})
+	if _, err := svc.ListApplePayloads(context.Background(), 99, "ot-1"); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("without reader err = %v, want ErrNotConfigured", err)
+	}
+
+	reader := &fakePayloadReader{}
+	svc.WithPayloadReader(reader)
+	if _, err := svc.ListApplePayloads(context.Background(), 1, "ot-1"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("non-admin err = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.ListApplePayloads(context.Background(), 99, "  "); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("blank query err = %v, want ErrInvalidQuery", err)
+	}
+	got, err := svc.ListApplePayloads(context.Background(), 99, " ot-1 ")
+	if err != nil || len(got) != 1 || reader.lastOriginalTxID != "ot-1" {
+		t.Fatalf("list = %+v, %v (query %q)", got, err, reader.lastOriginalTxID)
+	}
+}
@@ -2,6 +2,10 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/dundunHa/go-serverhttp-template/internal/model"
 )
@@ -12,10 +16,19 @@ type Service interface {
 	AuthenticateAccessToken(ctx context.Context, token string) (*model.UserInfo, error)
 }
 
+// AccountStateReader 读取账号状态。AuthService 在校验 access token 时用它拒绝
+// 当前不可用（暂停 / 封禁 / 注销）的账号以及在强制下线之前签发的 token。
+// 账号不存在时应返回 model.ErrAccountNotFound，其它错误视为暂时不可用。
+type AccountStateReader interface {
+	GetAccountState(ctx context.Context, userID int64) (model.AccountState, error)
+}
+
 type AuthService struct {
 	mgr        *ProviderManager
 	identities IdentityResolver
 	tokens     *TokenService
+	accounts   AccountStateReader
+	now        func() time.Time
 }
 
 func NewAuthService(mgr *ProviderManager, identities IdentityResolver, tokens *TokenService) *AuthService {
@@ -23,9 +36,16 @@ func NewAuthService(mgr *ProviderManager, identities IdentityResolver, tokens *T
 		mgr:        mgr,
 		identities: identities,
 		tokens:     tokens,
+		now:        time.Now,
 	}
 }
 
+// WithAccountStates 启用 access token 的账号状态校验；未设置时只校验 JWT 本身。
+func (s *AuthService) WithAccountStates(accounts AccountStateReader) *AuthService {
+	s.accounts = accounts
+	return s
+}
+
 // Verify 统一认证入口
 func (s *AuthService) Verify(ctx context.Context, provider, token string) (*model.UserInfo, error) {
 	p, ok := s.mgr.Get(provider)
@@ -65,6 +85,9 @@ func (s *AuthService) AuthenticateAccessToken(ctx context.Context, token string)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.checkAccountState(ctx, claims); err != nil {
+		return nil, err
+	}
 	return &model.UserInfo{
 		ID:              claims.Subject,
 		Email:           claims.Email,
@@ -72,3 +95,34 @@ func (s *AuthService) AuthenticateAccessToken(ctx context.Context, token string)
 		ProviderSubject: claims.ProviderSubject,
 	}, nil
 }
+
+// checkAccountState 拒绝当前不可用的账号（返回 *model.AccountBlockedError），以及签发时间不晚于
+// sessions_revoked_at 的 token（返回 ErrSessionRevoked）。账号状态读取失败时返回 ErrAccountStateUnavailable。
+//
+// JWT 的 iat 只有秒级精度，因此与强制下线落在同一秒内签发的 token 也会被拒绝，用户重新登录即可。
+func (s *AuthService) checkAccountState(ctx context.Context, claims *TokenClaims) error {
+	if s.accounts == nil {
+		return nil
+	}
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || userID <= 0 {
+		return ErrInvalidToken
+	}
+	state, err := s.accounts.GetAccountState(ctx, userID)
+	if errors.Is(err, model.ErrAccountNotFound) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		// 读库失败不能当作 token 无效，否则一次短暂的数据库故障会把所有用户踢下线。
+		return fmt.Errorf("%w: %w", ErrAccountStateUnavailable, err)
+	}
+	if err := state.BlockedError(s.now()); err != nil {
+		return err
+	}
+	if state.SessionsRevokedAt != nil {
+		if claims.IssuedAt == nil || !claims.IssuedAt.Time.After(*state.SessionsRevokedAt) {
+			return ErrSessionRevoked
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+type stubAccountStates map[int64]model.AccountState
+
+func (s stubAccountStates) GetAccountState(_ context.Context, userID int64) (model.AccountState, error) {
+	state, ok := s[userID]
+	if !ok {
+		return model.AccountState{}, model.ErrAccountNotFound
+	}
+	return state, nil
+}
+
+func newAccountStateTestService(t *testing.T, states stubAccountStates) (*AuthService, string) {
+	t.Helper()
+	tokens, err := NewTokenService(TokenConfig{Secret: "secret", AccessTokenTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("new token service: %v", err)
+	}
+	svc := NewAuthService(NewProviderManager(), nil, tokens).WithAccountStates(states)
+	token, _, err := svc.IssueAccessToken(context.Background(), model.UserInfo{ID: "1", Provider: "guest", ProviderSubject: "device-1"})
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	return svc, token
+}
+
+func TestAuthenticateAccessTokenChecksAccountState(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name  string
+		state model.AccountState
+		want  error
+	}{
+		{"active", model.AccountState{UserID: 1, Status: model.UserStatusActive}, nil},
+		{"revoked before issue", model.AccountState{UserID: 1, Status: model.UserStatusActive, SessionsRevokedAt: &past}, nil},
+		{"revoked after issue", model.AccountState{UserID: 1, Status: model.UserStatusActive, SessionsRevokedAt: &future}, ErrSessionRevoked},
+		{"suspended", model.AccountState{UserID: 1, Status: model.UserStatusSuspended}, ErrAccountBlocked},
+		{"suspension expired", model.AccountState{UserID: 1, Status: model.UserStatusSuspended, StatusExpiresAt: &past}, nil},
+		{"banned until later", model.AccountState{UserID: 1, Status: model.UserStatusBanned, StatusExpiresAt: &future}, ErrAccountBlocked},
+		{"deleted ignores expiry", model.AccountState{UserID: 1, Status: model.UserStatusDeleted, StatusExpiresAt: &past}, ErrAccountBlocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, token := newAccountStateTestService(t, stubAccountStates{1: tt.state})
+			_, err := svc.AuthenticateAccessToken(context.Background(), token)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAccessTokenRejectsUnknownAccount(t *testing.T) {
+	svc, token := newAccountStateTestService(t, stubAccountStates{})
+	if _, err := svc.AuthenticateAccessToken(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("err = %v, want ErrInvalidToken", err)
+	}
+}
+
+type failingAccountStates struct{ err error }
+
+func (s failingAccountStates) GetAccountState(context.Context, int64) (model.AccountState, error) {
+	return model.AccountState{}, s.err
+}
+
+func TestAuthenticateAccessTokenSurfacesAccountStateFailure(t *testing.T) {
+	tokens, err := NewTokenService(TokenConfig{Secret: "secret", AccessTokenTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("new token service: %v", err)
+	}
+	dbErr := errors.New("connection refused")
+	svc := NewAuthService(NewProviderManager(), nil, tokens).WithAccountStates(failingAccountStates{err: dbErr})
+	token, _, err := svc.IssueAccessToken(context.Background(), model.UserInfo{ID: "1", Provider: "guest", ProviderSubject: "device-1"})
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	_, err = svc.AuthenticateAccessToken(context.Background(), token)
+	if !errors.Is(err, ErrAccountStateUnavailable) || !errors.Is(err, dbErr) || errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("err = %v, want wrapped ErrAccountStateUnavailable", err)
+	}
+}
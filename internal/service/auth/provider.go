@@ -18,4 +18,8 @@ var (
 	ErrProviderNotFound    = errors.New("provider not found")
 	ErrIdentityUnavailable = errors.New("identity resolver unavailable")
 	ErrTokenUnavailable    = errors.New("token service unavailable")
+	ErrSessionRevoked      = errors.New("session revoked")
+	// ErrAccountStateUnavailable 表示账号状态读取失败（如数据库不可用），不代表 token 无效。
+	ErrAccountStateUnavailable = errors.New("account state unavailable")
+	ErrAccountBlocked          = model.ErrAccountBlocked
 )
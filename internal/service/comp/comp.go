@@ -0,0 +1,231 @@
+package comp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	logpkg "github.com/dundunHa/go-serverhttp-template/pkg/log"
+)
+
+var (
+	ErrNotConfigured      = errors.New("comp: not configured")
+	ErrInvalidGrant       = errors.New("comp: invalid grant")
+	ErrInvalidPromoCode   = errors.New("comp: invalid promo code")
+	ErrUserNotFound       = dao.ErrCompUserNotFound
+	ErrCompNotFound       = dao.ErrCompEntitlementNotFound
+	ErrPromoCodeNotFound  = dao.ErrPromoCodeNotFound
+	ErrPromoCodeConflict  = dao.ErrPromoCodeConflict
+	ErrAlreadyRedeemed    = dao.ErrPromoCodeAlreadyRedeemed
+	ErrPromoCodeInactive  = errors.New("comp: promo code inactive")
+	ErrPromoCodeExpired   = errors.New("comp: promo code expired")
+	ErrPromoCodeExhausted = errors.New("comp: promo code reached redemption limit")
+)
+
+const (
+	maxCompDays     = 3650
+	maxNoteLength   = 500
+	maxCodeLength   = 32
+	compDayDuration = 24 * time.Hour
+)
+
+// Service 管理邀请以外的两种赠送权益来源：后台直接发放 / 撤销（source ADMIN），以及用户兑换
+// 后台创建的兑换码（source PROMO_CODE）。发放的权益写入 comp_entitlements，由 entitlement.CompSource
+// 合并进订阅视图。调用方的管理员身份由 API 层校验，这里只记录操作人审计日志。
+type Service struct {
+	comps  dao.CompEntitlementDAO
+	promos dao.PromoCodeDAO
+	now    func() time.Time
+}
+
+// NewService 构造赠送权益 service。任一 dao 为 nil 时对应的调用返回 ErrNotConfigured。
+func NewService(comps dao.CompEntitlementDAO, promos dao.PromoCodeDAO) *Service {
+	return &Service{
+		comps:  comps,
+		promos: promos,
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// ListComps 返回用户的全部赠送权益（含已结束与已撤销的）。
+func (s *Service) ListComps(ctx context.Context, userID int64) ([]model.CompEntitlement, error) {
+	if s == nil || s.comps == nil {
+		return nil, ErrNotConfigured
+	}
+	return s.comps.ListCompEntitlements(ctx, userID)
+}
+
+// GrantComp 给用户发放 days 天的赠送权益，排在该用户已有赠送权益之后。note 必填，随权益保存。
+func (s *Service) GrantComp(ctx context.Context, actorID, userID int64, planID string, level, days int, note string) (model.CompEntitlement, error) {
+	if s == nil || s.comps == nil {
+		return model.CompEntitlement{}, ErrNotConfigured
+	}
+	planID, note = strings.TrimSpace(planID), strings.TrimSpace(note)
+	switch {
+	case planID == "":
+		return model.CompEntitlement{}, fmt.Errorf("%w: plan_id is required", ErrInvalidGrant)
+	case level <= 0:
+		return model.CompEntitlement{}, fmt.Errorf("%w: level must be positive", ErrInvalidGrant)
+	case days <= 0 || days > maxCompDays:
+		return model.CompEntitlement{}, fmt.Errorf("%w: comp_days must be between 1 and %d", ErrInvalidGrant, maxCompDays)
+	case note == "":
+		return model.CompEntitlement{}, fmt.Errorf("%w: note is required", ErrInvalidGrant)
+	case len([]rune(note)) > maxNoteLength:
+		return model.CompEntitlement{}, fmt.Errorf("%w: note exceeds %d characters", ErrInvalidGrant, maxNoteLength)
+	}
+	comp, err := s.comps.GrantComp(ctx, model.CompGrant{
+		UserID:    userID,
+		PlanID:    planID,
+		Level:     level,
+		Source:    model.CompSourceAdmin,
+		Reference: "admin:" + strconv.FormatInt(actorID, 10),
+		Note:      note,
+		Duration:  time.Duration(days) * compDayDuration,
+	}, s.now())
+	if err != nil {
+		return model.CompEntitlement{}, err
+	}
+	logpkg.FromContext(ctx).Info("admin comp granted",
+		"actor_user_id", actorID,
+		"user_id", userID,
+		"comp_id", comp.ID,
+		"plan_id", planID,
+		"level", level,
+		"comp_days", days,
+		"note", note,
+	)
+	return comp, nil
+}
+
+// RevokeComp 撤销用户的一段赠送权益（任意来源），撤销后立即不再计入订阅视图。
+func (s *Service) RevokeComp(ctx context.Context, actorID, userID, compID int64) (model.CompEntitlement, error) {
+	if s == nil || s.comps == nil {
+		return model.CompEntitlement{}, ErrNotConfigured
+	}
+	comp, err := s.comps.RevokeComp(ctx, userID, compID, s.now())
+	if err != nil {
+		return model.CompEntitlement{}, err
+	}
+	logpkg.FromContext(ctx).Info("admin comp revoked",
+		"actor_user_id", actorID,
+		"user_id", userID,
+		"comp_id", comp.ID,
+		"source", comp.Source,
+	)
+	return comp, nil
+}
+
+// ListPromoCodes 返回全部兑换码（含已停用的）。
+func (s *Service) ListPromoCodes(ctx context.Context) ([]model.PromoCode, error) {
+	if s == nil || s.promos == nil {
+		return nil, ErrNotConfigured
+	}
+	return s.promos.ListCodes(ctx)
+}
+
+// CreatePromoCode 新增兑换码；code 统一保存为大写。
+func (s *Service) CreatePromoCode(ctx context.Context, actorID int64, in model.PromoCodeInput) (model.PromoCode, error) {
+	if s == nil || s.promos == nil {
+		return model.PromoCode{}, ErrNotConfigured
+	}
+	in.Code, in.PlanID = NormalizeCode(in.Code), strings.TrimSpace(in.PlanID)
+	switch {
+	case in.Code == "" || len(in.Code) > maxCodeLength:
+		return model.PromoCode{}, fmt.Errorf("%w: code must be 1-%d characters", ErrInvalidPromoCode, maxCodeLength)
+	case in.PlanID == "":
+		return model.PromoCode{}, fmt.Errorf("%w: plan_id is required", ErrInvalidPromoCode)
+	case in.Level <= 0:
+		return model.PromoCode{}, fmt.Errorf("%w: level must be positive", ErrInvalidPromoCode)
+	case in.CompDays <= 0 || in.CompDays > maxCompDays:
+		return model.PromoCode{}, fmt.Errorf("%w: comp_days must be between 1 and %d", ErrInvalidPromoCode, maxCompDays)
+	case in.MaxRedemptions < 0:
+		return model.PromoCode{}, fmt.Errorf("%w: max_redemptions must not be negative", ErrInvalidPromoCode)
+	case in.ExpiresAt != nil && !in.ExpiresAt.After(s.now()):
+		return model.PromoCode{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidPromoCode)
+	}
+	code, err := s.promos.CreateCode(ctx, in)
+	if err != nil {
+		return model.PromoCode{}, err
+	}
+	logpkg.FromContext(ctx).Info("admin promo code created",
+		"actor_user_id", actorID,
+		"code", code.Code,
+		"plan_id", code.PlanID,
+		"comp_days", code.CompDays,
+		"max_redemptions", code.MaxRedemptions,
+	)
+	return code, nil
+}
+
+// DeactivatePromoCode 停用兑换码；已兑换的赠送权益不受影响，需要时逐个撤销。
+func (s *Service) DeactivatePromoCode(ctx context.Context, actorID int64, code string) (model.PromoCode, error) {
+	if s == nil || s.promos == nil {
+		return model.PromoCode{}, ErrNotConfigured
+	}
+	row, err := s.promos.DeactivateCode(ctx, NormalizeCode(code))
+	if err != nil {
+		return model.PromoCode{}, err
+	}
+	logpkg.FromContext(ctx).Info("admin promo code deactivated", "actor_user_id", actorID, "code", row.Code)
+	return row, nil
+}
+
+// RedeemPromoCode 兑换兑换码并同事务发放赠送权益。兑换码行加锁，停用、过期与兑换上限都在锁内校验；
+// 每个用户每个兑换码只能兑换一次。
+func (s *Service) RedeemPromoCode(ctx context.Context, userID int64, code string) (model.CompEntitlement, error) {
+	if s == nil || s.promos == nil {
+		return model.CompEntitlement{}, ErrNotConfigured
+	}
+	code = NormalizeCode(code)
+	if code == "" {
+		return model.CompEntitlement{}, ErrPromoCodeNotFound
+	}
+
+	now := s.now()
+	var granted model.CompEntitlement
+	err := s.promos.InTx(ctx, func(tx dao.PromoCodeTx) error {
+		promo, err := tx.LockCode(ctx, code)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !promo.Active:
+			return ErrPromoCodeInactive
+		case promo.ExpiresAt != nil && !promo.ExpiresAt.After(now):
+			return ErrPromoCodeExpired
+		case promo.MaxRedemptions > 0 && promo.RedeemedCount >= promo.MaxRedemptions:
+			return ErrPromoCodeExhausted
+		}
+		comp, err := tx.GrantComp(ctx, model.CompGrant{
+			UserID:    userID,
+			PlanID:    promo.PlanID,
+			Level:     promo.Level,
+			Source:    model.CompSourcePromoCode,
+			Reference: "promo:" + promo.Code,
+			Duration:  time.Duration(promo.CompDays) * compDayDuration,
+		}, now)
+		if err != nil {
+			return err
+		}
+		if err := tx.InsertRedemption(ctx, promo.Code, userID, comp.ID); err != nil {
+			return err
+		}
+		granted = comp
+		return nil
+	})
+	if err != nil {
+		return model.CompEntitlement{}, err
+	}
+	logpkg.FromContext(ctx).Info("promo code redeemed", "user_id", userID, "code", code, "comp_id", granted.ID)
+	return granted, nil
+}
+
+// NormalizeCode 去掉空白并转为大写；兑换码不区分大小写。
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,276 @@
+package comp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+type fakeCompDAO struct {
+	grants  []model.CompGrant
+	revoked []int64
+	err     error
+}
+
+func (d *fakeCompDAO) ListUnexpiredCompEntitlements(context.Context, int64, time.Time) ([]model.CompEntitlement, error) {
+	return nil, nil
+}
+
+func (d *fakeCompDAO) ListCompEntitlements(context.Context, int64) ([]model.CompEntitlement, error) {
+	return nil, nil
+}
+
+func (d *fakeCompDAO) GrantComp(_ context.Context, in model.CompGrant, now time.Time) (model.CompEntitlement, error) {
+	if d.err != nil {
+		return model.CompEntitlement{}, d.err
+	}
+	d.grants = append(d.grants, in)
+	return model.CompEntitlement{ID: int64(len(d.grants)), UserID: in.UserID, PlanID: in.PlanID, Level: in.Level, Source: in.Source, StartsAt: now, EndsAt: now.Add(in.Duration)}, nil
+}
+
+func (d *fakeCompDAO) RevokeComp(_ context.Context, userID, id int64, now time.Time) (model.CompEntitlement, error) {
+	if d.err != nil {
+		return model.CompEntitlement{}, d.err
+	}
+	d.revoked = append(d.revoked, id)
+	return model.CompEntitlement{ID: id, UserID: userID, RevokedAt: &now}, nil
+}
+
+// fakePromoCodeDAO 在内存中模拟 promo_codes；InTx 失败时丢弃本次事务的写入。
+type fakePromoCodeDAO struct {
+	codes       map[string]model.PromoCode
+	redemptions map[promoRedemption]bool
+	grants      []model.CompGrant
+}
+
+type promoRedemption struct {
+	code   string
+	userID int64
+}
+
+func newFakePromoCodeDAO(codes ...model.PromoCode) *fakePromoCodeDAO {
+	d := &fakePromoCodeDAO{codes: map[string]model.PromoCode{}, redemptions: map[promoRedemption]bool{}}
+	for _, c := range codes {
+		d.codes[c.Code] = c
+	}
+	return d
+}
+
+func (d *fakePromoCodeDAO) CreateCode(_ context.Context, in model.PromoCodeInput) (model.PromoCode, error) {
+	if _, ok := d.codes[in.Code]; ok {
+		return model.PromoCode{}, dao.ErrPromoCodeConflict
+	}
+	c := model.PromoCode{Code: in.Code, PlanID: in.PlanID, Level: in.Level, CompDays: in.CompDays, MaxRedemptions: in.MaxRedemptions, ExpiresAt: in.ExpiresAt, Active: true}
+	d.codes[in.Code] = c
+	return c, nil
+}
+
+func (d *fakePromoCodeDAO) ListCodes(context.Context) ([]model.PromoCode, error) {
+	out := make([]model.PromoCode, 0, len(d.codes))
+	for _, c := range d.codes {
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (d *fakePromoCodeDAO) DeactivateCode(_ context.Context, code string) (model.PromoCode, error) {
+	c, ok := d.codes[code]
+	if !ok {
+		return model.PromoCode{}, dao.ErrPromoCodeNotFound
+	}
+	c.Active = false
+	d.codes[code] = c
+	return c, nil
+}
+
+func (d *fakePromoCodeDAO) InTx(_ context.Context, fn func(dao.PromoCodeTx) error) error {
+	tx := &fakePromoCodeTx{d: d}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	for _, r := range tx.redemptions {
+		d.redemptions[r] = true
+		c := d.codes[r.code]
+		c.RedeemedCount++
+		d.codes[r.code] = c
+	}
+	d.grants = append(d.grants, tx.grants...)
+	return nil
+}
+
+type fakePromoCodeTx struct {
+	d           *fakePromoCodeDAO
+	redemptions []promoRedemption
+	grants      []model.CompGrant
+}
+
+func (t *fakePromoCodeTx) LockCode(_ context.Context, code string) (model.PromoCode, error) {
+	c, ok := t.d.codes[code]
+	if !ok {
+		return model.PromoCode{}, dao.ErrPromoCodeNotFound
+	}
+	return c, nil
+}
+
+func (t *fakePromoCodeTx) GrantComp(_ context.Context, in model.CompGrant, now time.Time) (model.CompEntitlement, error) {
+	t.grants = append(t.grants, in)
+	return model.CompEntitlement{ID: int64(len(t.d.grants) + len(t.grants)), UserID: in.UserID, PlanID: in.PlanID, Level: in.Level, Source: in.Source, StartsAt: now, EndsAt: now.Add(in.Duration)}, nil
+}
+
+func (t *fakePromoCodeTx) InsertRedemption(_ context.Context, code string, userID, _ int64) error {
+	key := promoRedemption{code: code, userID: userID}
+	if t.d.redemptions[key] {
+		return dao.ErrPromoCodeAlreadyRedeemed
+	}
+	t.redemptions = append(t.redemptions, key)
+	return nil
+}
+
+func newTestService(comps *fakeCompDAO, promos *fakePromoCodeDAO, now time.Time) *Service {
+	s := NewService(comps, promos)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestGrantComp_RecordsAdminSourceAndNote(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	comps := &fakeCompDAO{}
+	s := newTestService(comps, nil, now)
+
+	got, err := s.GrantComp(context.Background(), 9, 42, " pro ", 2, 30, " 补偿故障 ")
+	if err != nil {
+		t.Fatalf("GrantComp: %v", err)
+	}
+	if len(comps.grants) != 1 {
+		t.Fatalf("grants = %d, want 1", len(comps.grants))
+	}
+	g := comps.grants[0]
+	if g.UserID != 42 || g.PlanID != "pro" || g.Level != 2 || g.Source != model.CompSourceAdmin || g.Reference != "admin:9" || g.Note != "补偿故障" || g.Duration != 30*24*time.Hour {
+		t.Fatalf("unexpected grant: %+v", g)
+	}
+	if !got.EndsAt.Equal(now.Add(30 * 24 * time.Hour)) {
+		t.Fatalf("EndsAt = %s", got.EndsAt)
+	}
+}
+
+func TestGrantComp_RejectsInvalidInput(t *testing.T) {
+	s := newTestService(&fakeCompDAO{}, nil, time.Now())
+	tests := []struct {
+		name   string
+		planID string
+		level  int
+		days   int
+		note   string
+	}{
+		{"missing plan", "", 1, 30, "x"},
+		{"zero level", "pro", 0, 30, "x"},
+		{"zero days", "pro", 1, 0, "x"},
+		{"too many days", "pro", 1, maxCompDays + 1, "x"},
+		{"missing note", "pro", 1, 30, "  "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.GrantComp(context.Background(), 9, 42, tt.planID, tt.level, tt.days, tt.note); !errors.Is(err, ErrInvalidGrant) {
+				t.Fatalf("err = %v, want ErrInvalidGrant", err)
+			}
+		})
+	}
+}
+
+func TestRevokeComp_PassesThroughNotFound(t *testing.T) {
+	s := newTestService(&fakeCompDAO{err: dao.ErrCompEntitlementNotFound}, nil, time.Now())
+	if _, err := s.RevokeComp(context.Background(), 9, 42, 7); !errors.Is(err, ErrCompNotFound) {
+		t.Fatalf("err = %v, want ErrCompNotFound", err)
+	}
+}
+
+func TestCreatePromoCode_NormalizesAndValidates(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	promos := newFakePromoCodeDAO()
+	s := newTestService(nil, promos, now)
+
+	got, err := s.CreatePromoCode(context.Background(), 9, model.PromoCodeInput{Code: " spring ", PlanID: "pro", Level: 1, CompDays: 30})
+	if err != nil {
+		t.Fatalf("CreatePromoCode: %v", err)
+	}
+	if got.Code != "SPRING" {
+		t.Fatalf("Code = %q, want SPRING", got.Code)
+	}
+	if _, err := s.CreatePromoCode(context.Background(), 9, model.PromoCodeInput{Code: "SPRING", PlanID: "pro", Level: 1, CompDays: 30}); !errors.Is(err, ErrPromoCodeConflict) {
+		t.Fatalf("duplicate err = %v, want ErrPromoCodeConflict", err)
+	}
+	past := now.Add(-time.Hour)
+	if _, err := s.CreatePromoCode(context.Background(), 9, model.PromoCodeInput{Code: "OLD", PlanID: "pro", Level: 1, CompDays: 30, ExpiresAt: &past}); !errors.Is(err, ErrInvalidPromoCode) {
+		t.Fatalf("past expiry err = %v, want ErrInvalidPromoCode", err)
+	}
+}
+
+func TestRedeemPromoCode_GrantsCompOncePerUser(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	promos := newFakePromoCodeDAO(model.PromoCode{Code: "SPRING", PlanID: "pro", Level: 2, CompDays: 14, Active: true})
+	s := newTestService(nil, promos, now)
+
+	got, err := s.RedeemPromoCode(context.Background(), 1, " spring ")
+	if err != nil {
+		t.Fatalf("RedeemPromoCode: %v", err)
+	}
+	if got.Source != model.CompSourcePromoCode || got.PlanID != "pro" || got.Level != 2 || !got.EndsAt.Equal(now.Add(14*24*time.Hour)) {
+		t.Fatalf("unexpected comp: %+v", got)
+	}
+	if len(promos.grants) != 1 || promos.grants[0].Reference != "promo:SPRING" {
+		t.Fatalf("unexpected grants: %+v", promos.grants)
+	}
+	if _, err := s.RedeemPromoCode(context.Background(), 1, "SPRING"); !errors.Is(err, ErrAlreadyRedeemed) {
+		t.Fatalf("second redeem err = %v, want ErrAlreadyRedeemed", err)
+	}
+	if len(promos.grants) != 1 {
+		t.Fatalf("rolled back redemption still granted a comp: %+v", promos.grants)
+	}
+	if promos.codes["SPRING"].RedeemedCount != 1 {
+		t.Fatalf("RedeemedCount = %d, want 1", promos.codes["SPRING"].RedeemedCount)
+	}
+}
+
+func TestRedeemPromoCode_RejectsUnavailableCodes(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Minute)
+	promos := newFakePromoCodeDAO(
+		model.PromoCode{Code: "OFF", PlanID: "pro", Level: 1, CompDays: 7},
+		model.PromoCode{Code: "OLD", PlanID: "pro", Level: 1, CompDays: 7, Active: true, ExpiresAt: &expired},
+		model.PromoCode{Code: "FULL", PlanID: "pro", Level: 1, CompDays: 7, Active: true, MaxRedemptions: 3, RedeemedCount: 3},
+	)
+	s := newTestService(nil, promos, now)
+	tests := []struct {
+		code string
+		want error
+	}{
+		{"MISSING", ErrPromoCodeNotFound},
+		{"OFF", ErrPromoCodeInactive},
+		{"OLD", ErrPromoCodeExpired},
+		{"FULL", ErrPromoCodeExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if _, err := s.RedeemPromoCode(context.Background(), 1, tt.code); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if len(promos.grants) != 0 {
+		t.Fatalf("unexpected grants: %+v", promos.grants)
+	}
+}
+
+func TestService_NotConfigured(t *testing.T) {
+	s := NewService(nil, nil)
+	if _, err := s.GrantComp(context.Background(), 9, 42, "pro", 1, 30, "x"); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("GrantComp err = %v", err)
+	}
+	if _, err := s.RedeemPromoCode(context.Background(), 1, "SPRING"); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("RedeemPromoCode err = %v", err)
+	}
+}
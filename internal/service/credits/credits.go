@@ -0,0 +1,191 @@
+package credits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+var (
+	ErrNotConfigured       = errors.New("credits: not configured")
+	ErrInvalidAmount       = errors.New("credits: amount must be positive")
+	ErrUnknownSource       = errors.New("credits: unknown source")
+	ErrInsufficientCredits = dao.ErrInsufficientCredits
+)
+
+// defaultSweepBatchSize 是单个事务内最多过期的 bucket 数量，避免一次 sweep 锁住过多行。
+const defaultSweepBatchSize = 500
+
+// Service 负责积分的发放、FIFO 消费、余额汇总与过期清理。
+//
+// 消费顺序：最早过期的 bucket 最先扣减，永不过期（expires_at 为空）的 bucket 最后扣减；
+// 同一过期时间按发放先后排序。该顺序由 DAO 的 SQL ORDER BY 保证。
+type Service struct {
+	dao            dao.CreditDAO
+	sweepBatchSize int
+	now            func() time.Time
+}
+
+// NewService 构造积分 service。dao 为 nil 时所有调用返回 ErrNotConfigured（LoadCredits 返回零值）。
+func NewService(d dao.CreditDAO, sweepBatchSize int) *Service {
+	if sweepBatchSize <= 0 {
+		sweepBatchSize = defaultSweepBatchSize
+	}
+	return &Service{
+		dao:            d,
+		sweepBatchSize: sweepBatchSize,
+		now:            func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Grant 为 user 新建一个积分 bucket。ExpiresAt 为 nil 表示永不过期；已经过期的时间会被拒绝。
+func (s *Service) Grant(ctx context.Context, in model.CreditGrant) (model.CreditBucket, error) {
+	if s == nil || s.dao == nil {
+		return model.CreditBucket{}, ErrNotConfigured
+	}
+	if in.Amount <= 0 {
+		return model.CreditBucket{}, ErrInvalidAmount
+	}
+	if !IsKnownSource(in.Source) {
+		return model.CreditBucket{}, fmt.Errorf("%w: %q", ErrUnknownSource, in.Source)
+	}
+	if in.ExpiresAt != nil && !in.ExpiresAt.After(s.now()) {
+		return model.CreditBucket{}, errors.New("credits: expires_at must be in the future")
+	}
+
+	var bucket model.CreditBucket
+	err := s.dao.InTx(ctx, func(tx dao.CreditTx) error {
+		b, err := tx.GrantCredits(ctx, in)
+		if err != nil {
+			return err
+		}
+		bucket = b
+		return nil
+	})
+	return bucket, err
+}
+
+// Spend 扣减 amount 积分，按最早过期优先的顺序消耗 bucket。余额不足返回 ErrInsufficientCredits 且不扣减。
+//
+// reference 会写入每条 SPEND ledger，调用方应传入可追溯的业务标识（任务 ID、订单号等）。
+func (s *Service) Spend(ctx context.Context, userID int64, amount int64, reference string) error {
+	if s == nil || s.dao == nil {
+		return ErrNotConfigured
+	}
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	now := s.now()
+	return s.dao.InTx(ctx, func(tx dao.CreditTx) error {
+		return tx.SpendCredits(ctx, userID, amount, reference, now)
+	})
+}
+
+// LoadCredits 组装 /users/me 的积分视图：总余额、按来源拆分、最近一批过期的时间与数量。
+//
+// service 未配置时返回零值 Credits（与 plan 中“无积分即 0”的契约一致）。
+func (s *Service) LoadCredits(ctx context.Context, userID int64) (model.Credits, error) {
+	if s == nil || s.dao == nil || userID <= 0 {
+		return EmptyCredits(), nil
+	}
+	buckets, err := s.dao.ListActiveBuckets(ctx, userID, s.now())
+	if err != nil {
+		return model.Credits{}, err
+	}
+	return summarize(buckets), nil
+}
+
+// SweepExpired 分批过期所有到期 bucket，直到没有剩余或 ctx 结束。返回本次过期的 bucket 总数。
+func (s *Service) SweepExpired(ctx context.Context) (int, error) {
+	if s == nil || s.dao == nil {
+		return 0, ErrNotConfigured
+	}
+	total := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		now := s.now()
+		var n int
+		if err := s.dao.InTx(ctx, func(tx dao.CreditTx) error {
+			expired, err := tx.ExpireDueBuckets(ctx, now, s.sweepBatchSize)
+			n = expired
+			return err
+		}); err != nil {
+			return total, err
+		}
+		total += n
+		if n < s.sweepBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// RunSweeper 执行一轮 SweepExpired 并记录过期数量，作为后台任务的周期回调。
+func (s *Service) RunSweeper(ctx context.Context) error {
+	if s == nil || s.dao == nil {
+		return nil
+	}
+	n, err := s.SweepExpired(ctx)
+	if err != nil {
+		return fmt.Errorf("credit sweep (expired %d): %w", n, err)
+	}
+	if n > 0 {
+		slog.Info("credit sweep expired buckets", "expired", n)
+	}
+	return nil
+}
+
+// IsKnownSource 判定 source 是否是受支持的积分来源。
+func IsKnownSource(source string) bool {
+	switch source {
+	case model.CreditSourcePurchase, model.CreditSourcePromo, model.CreditSourceSubscriptionAllowance, model.CreditSourceReferral:
+		return true
+	default:
+		return false
+	}
+}
+
+// EmptyCredits 返回没有任何积分时的视图；Breakdown 为非 nil 空切片，JSON 中序列化为 []。
+func EmptyCredits() model.Credits {
+	return model.Credits{Breakdown: []model.CreditBreakdown{}}
+}
+
+// summarize 要求 buckets 已按消费顺序排列（DAO 保证），因此第一条带 ExpiresAt 的 bucket 即最近过期批次。
+func summarize(buckets []model.CreditBucket) model.Credits {
+	out := EmptyCredits()
+	bySource := map[string]int64{}
+	var nextExpiry *time.Time
+	for _, b := range buckets {
+		if b.Remaining <= 0 {
+			continue
+		}
+		out.Balance += b.Remaining
+		bySource[b.Source] += b.Remaining
+		if b.ExpiresAt == nil {
+			continue
+		}
+		switch {
+		case nextExpiry == nil || b.ExpiresAt.Before(*nextExpiry):
+			t := *b.ExpiresAt
+			nextExpiry = &t
+			out.NextExpiringAmount = b.Remaining
+		case b.ExpiresAt.Equal(*nextExpiry):
+			out.NextExpiringAmount += b.Remaining
+		}
+	}
+	for source, balance := range bySource {
+		out.Breakdown = append(out.Breakdown, model.CreditBreakdown{Source: source, Balance: balance})
+	}
+	sort.Slice(out.Breakdown, func(i, j int) bool { return out.Breakdown[i].Source < out.Breakdown[j].Source })
+	if nextExpiry != nil {
+		out.NextExpiresAt = nextExpiry.UTC().Format(time.RFC3339)
+	}
+	return out
+}
@@ -0,0 +1,294 @@
+package credits
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+type ledgerEntry struct {
+	bucketID  int64
+	entryType string
+	delta     int64
+}
+
+// fakeCreditDAO 是内存版 CreditDAO，复刻 SQL 的消费顺序与事务回滚语义。
+type fakeCreditDAO struct {
+	buckets []model.CreditBucket
+	ledger  []ledgerEntry
+	nextID  int64
+}
+
+func (d *fakeCreditDAO) ListActiveBuckets(_ context.Context, userID int64, now time.Time) ([]model.CreditBucket, error) {
+	return d.spendable(userID, now), nil
+}
+
+func (d *fakeCreditDAO) InTx(_ context.Context, fn func(dao.CreditTx) error) error {
+	buckets := append([]model.CreditBucket(nil), d.buckets...)
+	ledger := append([]ledgerEntry(nil), d.ledger...)
+	if err := fn(d); err != nil {
+		d.buckets, d.ledger = buckets, ledger
+		return err
+	}
+	return nil
+}
+
+func (d *fakeCreditDAO) GrantCredits(_ context.Context, in model.CreditGrant) (model.CreditBucket, error) {
+	d.nextID++
+	b := model.CreditBucket{
+		ID:        d.nextID,
+		UserID:    in.UserID,
+		Source:    in.Source,
+		Amount:    in.Amount,
+		Remaining: in.Amount,
+		ExpiresAt: in.ExpiresAt,
+		Reference: in.Reference,
+	}
+	d.buckets = append(d.buckets, b)
+	d.ledger = append(d.ledger, ledgerEntry{bucketID: b.ID, entryType: model.CreditEntryGrant, delta: in.Amount})
+	return b, nil
+}
+
+func (d *fakeCreditDAO) SpendCredits(_ context.Context, userID int64, amount int64, _ string, now time.Time) error {
+	candidates := d.spendable(userID, now)
+	var available int64
+	for _, b := range candidates {
+		available += b.Remaining
+	}
+	if available < amount {
+		return dao.ErrInsufficientCredits
+	}
+	left := amount
+	for _, c := range candidates {
+		if left == 0 {
+			break
+		}
+		take := min(c.Remaining, left)
+		d.bucket(c.ID).Remaining -= take
+		d.ledger = append(d.ledger, ledgerEntry{bucketID: c.ID, entryType: model.CreditEntrySpend, delta: -take})
+		left -= take
+	}
+	return nil
+}
+
+func (d *fakeCreditDAO) ExpireDueBuckets(_ context.Context, now time.Time, limit int) (int, error) {
+	n := 0
+	for i := range d.buckets {
+		b := &d.buckets[i]
+		if n == limit {
+			break
+		}
+		if b.Remaining == 0 || b.ExpiresAt == nil || b.ExpiresAt.After(now) {
+			continue
+		}
+		d.ledger = append(d.ledger, ledgerEntry{bucketID: b.ID, entryType: model.CreditEntryExpire, delta: -b.Remaining})
+		b.Remaining = 0
+		n++
+	}
+	return n, nil
+}
+
+func (d *fakeCreditDAO) spendable(userID int64, now time.Time) []model.CreditBucket {
+	var out []model.CreditBucket
+	for _, b := range d.buckets {
+		if b.UserID != userID || b.Remaining == 0 {
+			continue
+		}
+		if b.ExpiresAt != nil && !b.ExpiresAt.After(now) {
+			continue
+		}
+		out = append(out, b)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].ExpiresAt, out[j].ExpiresAt
+		switch {
+		case a == nil && b == nil:
+			return out[i].ID < out[j].ID
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		case a.Equal(*b):
+			return out[i].ID < out[j].ID
+		default:
+			return a.Before(*b)
+		}
+	})
+	return out
+}
+
+func (d *fakeCreditDAO) bucket(id int64) *model.CreditBucket {
+	for i := range d.buckets {
+		if d.buckets[i].ID == id {
+			return &d.buckets[i]
+		}
+	}
+	return nil
+}
+
+func newTestService(t *testing.T, now time.Time, batch int) (*Service, *fakeCreditDAO) {
+	t.Helper()
+	d := &fakeCreditDAO{}
+	s := NewService(d, batch)
+	s.now = func() time.Time { return now }
+	return s, d
+}
+
+func mustGrant(t *testing.T, s *Service, userID int64, source string, amount int64, expiresAt *time.Time) model.CreditBucket {
+	t.Helper()
+	b, err := s.Grant(context.Background(), model.CreditGrant{
+		UserID:    userID,
+		Source:    source,
+		Amount:    amount,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	return b
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func TestService_SpendConsumesEarliestExpiryFirst(t *testing.T) {
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	s, d := newTestService(t, now, 0)
+
+	permanent := mustGrant(t, s, 1, model.CreditSourcePurchase, 100, nil)
+	late := mustGrant(t, s, 1, model.CreditSourcePromo, 50, timePtr(now.Add(72*time.Hour)))
+	early := mustGrant(t, s, 1, model.CreditSourceSubscriptionAllowance, 30, timePtr(now.Add(24*time.Hour)))
+
+	if err := s.Spend(context.Background(), 1, 60, "job-1"); err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+	if got := d.bucket(early.ID).Remaining; got != 0 {
+		t.Fatalf("early bucket remaining = %d, want 0", got)
+	}
+	if got := d.bucket(late.ID).Remaining; got != 20 {
+		t.Fatalf("late bucket remaining = %d, want 20", got)
+	}
+	if got := d.bucket(permanent.ID).Remaining; got != 100 {
+		t.Fatalf("permanent bucket remaining = %d, want 100", got)
+	}
+}
+
+func TestService_SpendInsufficientLeavesBalanceUntouched(t *testing.T) {
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	s, d := newTestService(t, now, 0)
+	mustGrant(t, s, 1, model.CreditSourcePromo, 10, timePtr(now.Add(time.Hour)))
+	ledgerBefore := len(d.ledger)
+
+	err := s.Spend(context.Background(), 1, 11, "job-1")
+	if !errors.Is(err, ErrInsufficientCredits) {
+		t.Fatalf("err = %v, want ErrInsufficientCredits", err)
+	}
+	if d.buckets[0].Remaining != 10 || len(d.ledger) != ledgerBefore {
+		t.Fatalf("state changed after failed spend: %+v ledger=%d", d.buckets[0], len(d.ledger))
+	}
+}
+
+func TestService_GrantValidation(t *testing.T) {
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	s, _ := newTestService(t, now, 0)
+	ctx := context.Background()
+
+	if _, err := s.Grant(ctx, model.CreditGrant{UserID: 1, Source: model.CreditSourcePromo, Amount: 0}); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("zero amount err = %v", err)
+	}
+	if _, err := s.Grant(ctx, model.CreditGrant{UserID: 1, Source: "GIFT", Amount: 1}); !errors.Is(err, ErrUnknownSource) {
+		t.Fatalf("unknown source err = %v", err)
+	}
+	if _, err := s.Grant(ctx, model.CreditGrant{UserID: 1, Source: model.CreditSourcePromo, Amount: 1, ExpiresAt: timePtr(now)}); err == nil {
+		t.Fatalf("expected error for already-expired grant")
+	}
+}
+
+func TestService_LoadCreditsBreakdownAndNextExpiry(t *testing.T) {
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	s, _ := newTestService(t, now, 0)
+	soon := now.Add(24 * time.Hour)
+
+	mustGrant(t, s, 1, model.CreditSourcePurchase, 100, nil)
+	mustGrant(t, s, 1, model.CreditSourcePromo, 20, timePtr(soon))
+	mustGrant(t, s, 1, model.CreditSourceSubscriptionAllowance, 5, timePtr(soon))
+	mustGrant(t, s, 1, model.CreditSourcePromo, 7, timePtr(now.Add(48*time.Hour)))
+	mustGrant(t, s, 2, model.CreditSourcePromo, 999, nil)
+
+	got, err := s.LoadCredits(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Balance != 132 {
+		t.Fatalf("balance = %d, want 132", got.Balance)
+	}
+	want := []model.CreditBreakdown{
+		{Source: model.CreditSourcePromo, Balance: 27},
+		{Source: model.CreditSourcePurchase, Balance: 100},
+		{Source: model.CreditSourceSubscriptionAllowance, Balance: 5},
+	}
+	if len(got.Breakdown) != len(want) {
+		t.Fatalf("breakdown = %+v, want %+v", got.Breakdown, want)
+	}
+	for i := range want {
+		if got.Breakdown[i] != want[i] {
+			t.Fatalf("breakdown[%d] = %+v, want %+v", i, got.Breakdown[i], want[i])
+		}
+	}
+	if got.NextExpiresAt != soon.Format(time.RFC3339) || got.NextExpiringAmount != 25 {
+		t.Fatalf("next expiry = %q / %d, want %q / 25", got.NextExpiresAt, got.NextExpiringAmount, soon.Format(time.RFC3339))
+	}
+}
+
+func TestService_LoadCreditsEmptyWhenNotConfigured(t *testing.T) {
+	var s *Service
+	got, err := s.LoadCredits(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Balance != 0 || got.Breakdown == nil || len(got.Breakdown) != 0 || got.NextExpiresAt != "" {
+		t.Fatalf("unexpected credits: %+v", got)
+	}
+}
+
+func TestService_SweepExpiredDrainsAllBatches(t *testing.T) {
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	s, d := newTestService(t, start, 2)
+	for i := 0; i < 5; i++ {
+		mustGrant(t, s, 1, model.CreditSourcePromo, 10, timePtr(start.Add(time.Hour)))
+	}
+	mustGrant(t, s, 1, model.CreditSourcePurchase, 10, nil)
+
+	s.now = func() time.Time { return start.Add(2 * time.Hour) }
+	n, err := s.SweepExpired(context.Background())
+	if err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	if n != 5 {
+		t.Fatalf("expired = %d, want 5", n)
+	}
+	var expireEntries int
+	for _, e := range d.ledger {
+		if e.entryType == model.CreditEntryExpire {
+			expireEntries++
+			if e.delta != -10 {
+				t.Fatalf("expire delta = %d, want -10", e.delta)
+			}
+		}
+	}
+	if expireEntries != 5 {
+		t.Fatalf("expire ledger entries = %d, want 5", expireEntries)
+	}
+
+	got, err := s.LoadCredits(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Balance != 10 || got.NextExpiresAt != "" {
+		t.Fatalf("unexpected credits after sweep: %+v", got)
+	}
+}
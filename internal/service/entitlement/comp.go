@@ -0,0 +1,70 @@
+package entitlement
+
+import (
+	"context"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// CompSource 把赠送权益（邀请奖励、后台赠送等）作为权益来源。
+type CompSource struct {
+	dao dao.CompEntitlementDAO
+}
+
+// NewCompSource 构造赠送权益来源；dao 为 nil 时返回 nil，NewEngine 会忽略它。
+func NewCompSource(d dao.CompEntitlementDAO) Source {
+	if d == nil {
+		return nil
+	}
+	return &CompSource{dao: d}
+}
+
+// Grants 只返回 now 时刻生效的那段赠送权益；已结束的赠送不计入 EXPIRED 判定。
+func (s *CompSource) Grants(ctx context.Context, userID int64, now time.Time) ([]model.EntitlementGrant, error) {
+	comps, err := s.dao.ListUnexpiredCompEntitlements(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	comp, ok := activeComp(comps, now)
+	if !ok {
+		return nil, nil
+	}
+	endsAt := comp.EndsAt.UTC()
+	return []model.EntitlementGrant{{
+		Source:    model.EntitlementSourceComp,
+		PlanID:    comp.PlanID,
+		Level:     comp.Level,
+		Status:    "ACTIVE",
+		ExpiresAt: &endsAt,
+	}}, nil
+}
+
+// activeComp 返回 now 时刻生效的赠送权益：等级取当前覆盖 now 的最高等级，到期时间沿首尾相接的
+// 排队权益一直延伸到链条末端。comps 须按 starts_at 升序排列。
+func activeComp(comps []model.CompEntitlement, now time.Time) (model.CompEntitlement, bool) {
+	var (
+		out   model.CompEntitlement
+		found bool
+	)
+	for _, c := range comps {
+		if !found {
+			if c.StartsAt.After(now) || !c.EndsAt.After(now) {
+				continue
+			}
+			out, found = c, true
+			continue
+		}
+		if c.StartsAt.After(out.EndsAt) {
+			break
+		}
+		if !c.StartsAt.After(now) && c.Level > out.Level {
+			out.Level, out.PlanID = c.Level, c.PlanID
+		}
+		if c.EndsAt.After(out.EndsAt) {
+			out.EndsAt = c.EndsAt
+		}
+	}
+	return out, found
+}
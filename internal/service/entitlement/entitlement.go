@@ -0,0 +1,202 @@
+// Package entitlement 把各来源（Apple、Google Play、Stripe、赠送权益等）的权益合并成
+// 每个 feature set 一条的生效权益。/users/me 以及其他需要判断会员身份的服务都应读这里，
+// 而不是直接读各商店的表。
+package entitlement
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// Source 是一个权益来源。Grants 返回该来源下 user 的全部权益，包括已失效的（Status=EXPIRED），
+// engine 据此区分“曾经有过权益”（EXPIRED）与“从未有过”（NONE）。
+type Source interface {
+	Grants(ctx context.Context, userID int64, now time.Time) ([]model.EntitlementGrant, error)
+}
+
+// Engine 合并所有 Source 的权益。
+//
+// 同一 feature set 内的重叠权益按等级最高、到期最晚（终身权益视为最晚）选出一条；
+// 两者都相同时保留先注册的 Source 给出的那条。
+type Engine struct {
+	sources     []Source
+	featureSets map[string]string
+	now         func() time.Time
+}
+
+// NewEngine 构造 engine。featureSets 为 plan_id → feature set，未声明的 plan 归入 model.DefaultFeatureSet。
+func NewEngine(featureSets map[string]string, sources ...Source) *Engine {
+	out := make([]Source, 0, len(sources))
+	for _, s := range sources {
+		if s != nil {
+			out = append(out, s)
+		}
+	}
+	return &Engine{
+		sources:     out,
+		featureSets: featureSets,
+		now:         func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// ParseFeatureSets 解析 ENTITLEMENT_FEATURE_SETS；空串表示所有 plan 都属于默认 feature set。
+func ParseFeatureSets(raw string) (map[string]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var out map[string]string
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		return nil, fmt.Errorf("entitlement: parse feature sets: %w", err)
+	}
+	for planID, set := range out {
+		if planID == "" || strings.TrimSpace(set) == "" {
+			return nil, fmt.Errorf("entitlement: empty plan id or feature set in %q", planID)
+		}
+	}
+	return out, nil
+}
+
+// LoadEntitlements 返回 user 当前的合并权益。
+func (e *Engine) LoadEntitlements(ctx context.Context, userID int64) (model.EntitlementSummary, error) {
+	if e == nil || userID <= 0 {
+		return model.EntitlementSummary{Subscription: model.SubscriptionInfo{Status: "NONE"}, Entitlements: []model.Entitlement{}}, nil
+	}
+	now := e.now()
+	var grants []model.EntitlementGrant
+	for _, s := range e.sources {
+		g, err := s.Grants(ctx, userID, now)
+		if err != nil {
+			return model.EntitlementSummary{}, err
+		}
+		grants = append(grants, g...)
+	}
+	return Resolve(grants, e.featureSets), nil
+}
+
+// LoadSubscriptionInfo 只返回跨 feature set 的最佳权益，供只关心单一订阅视图的调用方使用。
+func (e *Engine) LoadSubscriptionInfo(ctx context.Context, userID int64) (model.SubscriptionInfo, error) {
+	summary, err := e.LoadEntitlements(ctx, userID)
+	if err != nil {
+		return model.SubscriptionInfo{}, err
+	}
+	return summary.Subscription, nil
+}
+
+// Resolve 是 engine 的纯函数部分：
+//  1. 有效权益（ACTIVE / CANCELED / GRACE_PERIOD）按 feature set 分组，每组取最佳一条；
+//  2. Subscription 取所有有效权益中的最佳一条；
+//  3. 没有有效权益但有失效权益时，Subscription 为到期最晚那条的 EXPIRED（扣款重试中为 BILLING_RETRY）视图（等级 0）；
+//  4. 完全没有权益时为 NONE。
+func Resolve(grants []model.EntitlementGrant, featureSets map[string]string) model.EntitlementSummary {
+	var (
+		best       *model.EntitlementGrant
+		lastExpiry *model.EntitlementGrant
+		bySet      = map[string]model.EntitlementGrant{}
+	)
+	for i := range grants {
+		g := grants[i]
+		if !effective(g.Status) {
+			if lastExpiry == nil || laterExpiry(g.ExpiresAt, lastExpiry.ExpiresAt) {
+				lastExpiry = &grants[i]
+			}
+			continue
+		}
+		if best == nil || better(g, *best) {
+			best = &grants[i]
+		}
+		set := featureSetFor(featureSets, g.PlanID)
+		if cur, ok := bySet[set]; !ok || better(g, cur) {
+			bySet[set] = g
+		}
+	}
+
+	out := model.EntitlementSummary{Entitlements: make([]model.Entitlement, 0, len(bySet))}
+	for set, g := range bySet {
+		out.Entitlements = append(out.Entitlements, model.Entitlement{
+			FeatureSet: set,
+			PlanID:     g.PlanID,
+			Source:     g.Source,
+			Status:     g.Status,
+			Level:      g.Level,
+			ExpiresAt:  formatExpiry(g.ExpiresAt),
+		})
+	}
+	sort.Slice(out.Entitlements, func(i, j int) bool {
+		return out.Entitlements[i].FeatureSet < out.Entitlements[j].FeatureSet
+	})
+
+	switch {
+	case best != nil:
+		out.Subscription = model.SubscriptionInfo{
+			ProductID:            best.PlanID,
+			Status:               best.Status,
+			SubscribeExpiredTime: formatExpiry(best.ExpiresAt),
+			SubscribeLevel:       best.Level,
+		}
+		if best.PendingPlanID != "" {
+			out.Subscription.PendingProductID = best.PendingPlanID
+			out.Subscription.PendingLevel = best.PendingLevel
+			out.Subscription.PendingEffectiveTime = formatExpiry(best.ExpiresAt)
+		}
+		out.Subscription.GracePeriodExpiredTime = formatExpiry(best.GracePeriodExpiresAt)
+	case lastExpiry != nil:
+		status := "EXPIRED"
+		if lastExpiry.Status == "BILLING_RETRY" {
+			status = lastExpiry.Status
+		}
+		out.Subscription = model.SubscriptionInfo{
+			ProductID:            lastExpiry.PlanID,
+			Status:               status,
+			SubscribeExpiredTime: formatExpiry(lastExpiry.ExpiresAt),
+			ExpirationReason:     lastExpiry.ExpirationReason,
+		}
+	default:
+		out.Subscription = model.SubscriptionInfo{Status: "NONE"}
+	}
+	return out
+}
+
+// effective 判断对外 status 是否仍享有权益；宽限期内保留权益。
+func effective(status string) bool {
+	return status == "ACTIVE" || status == "CANCELED" || status == "GRACE_PERIOD"
+}
+
+// better 判断 a 是否严格优于 b：先比等级，再比到期时间。
+func better(a, b model.EntitlementGrant) bool {
+	if a.Level != b.Level {
+		return a.Level > b.Level
+	}
+	return laterExpiry(a.ExpiresAt, b.ExpiresAt)
+}
+
+// laterExpiry 判断 a 是否严格晚于 b；nil 表示终身，视为最晚。
+func laterExpiry(a, b *time.Time) bool {
+	switch {
+	case a == nil:
+		return b != nil
+	case b == nil:
+		return false
+	default:
+		return a.After(*b)
+	}
+}
+
+func featureSetFor(featureSets map[string]string, planID string) string {
+	if set, ok := featureSets[planID]; ok {
+		return set
+	}
+	return model.DefaultFeatureSet
+}
+
+func formatExpiry(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
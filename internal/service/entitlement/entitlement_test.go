@@ -0,0 +1,261 @@
+package entitlement
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+type staticSource struct {
+	grants []model.EntitlementGrant
+	err    error
+}
+
+func (s staticSource) Grants(context.Context, int64, time.Time) ([]model.EntitlementGrant, error) {
+	return s.grants, s.err
+}
+
+type fakeCompDAO struct {
+	comps []model.CompEntitlement
+}
+
+func (d *fakeCompDAO) ListUnexpiredCompEntitlements(_ context.Context, _ int64, _ time.Time) ([]model.CompEntitlement, error) {
+	return d.comps, nil
+}
+
+func (d *fakeCompDAO) ListCompEntitlements(_ context.Context, _ int64) ([]model.CompEntitlement, error) {
+	return d.comps, nil
+}
+
+func (d *fakeCompDAO) GrantComp(_ context.Context, _ model.CompGrant, _ time.Time) (model.CompEntitlement, error) {
+	return model.CompEntitlement{}, errors.New("not implemented")
+}
+
+func (d *fakeCompDAO) RevokeComp(_ context.Context, _, _ int64, _ time.Time) (model.CompEntitlement, error) {
+	return model.CompEntitlement{}, errors.New("not implemented")
+}
+
+func at(t time.Time) *time.Time { return &t }
+
+func TestEngine_NoneWhenNilOrEmpty(t *testing.T) {
+	var e *Engine
+	got, err := e.LoadEntitlements(context.Background(), 1)
+	if err != nil || got.Subscription.Status != "NONE" || len(got.Entitlements) != 0 {
+		t.Fatalf("nil engine = %+v, err = %v", got, err)
+	}
+	info, err := NewEngine(nil, nil, staticSource{}).LoadSubscriptionInfo(context.Background(), 1)
+	if err != nil || info.Status != "NONE" {
+		t.Fatalf("empty engine = %+v, err = %v", info, err)
+	}
+}
+
+func TestEngine_PropagatesSourceError(t *testing.T) {
+	boom := errors.New("db down")
+	if _, err := NewEngine(nil, staticSource{err: boom}).LoadEntitlements(context.Background(), 1); !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+}
+
+func TestResolve_HighestLevelThenLatestExpiry(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	cases := []struct {
+		name   string
+		grants []model.EntitlementGrant
+		want   string
+	}{
+		{
+			name: "higher level wins over later expiry",
+			grants: []model.EntitlementGrant{
+				{Source: model.EntitlementSourceApple, PlanID: "basic", Level: 1, Status: "ACTIVE", ExpiresAt: at(now.Add(72 * time.Hour))},
+				{Source: model.EntitlementSourceStripe, PlanID: "pro", Level: 2, Status: "ACTIVE", ExpiresAt: at(now.Add(24 * time.Hour))},
+			},
+			want: "pro",
+		},
+		{
+			name: "equal level later expiry wins",
+			grants: []model.EntitlementGrant{
+				{Source: model.EntitlementSourceApple, PlanID: "pro_monthly", Level: 1, Status: "ACTIVE", ExpiresAt: at(now.Add(48 * time.Hour))},
+				{Source: model.EntitlementSourceComp, PlanID: "referral", Level: 1, Status: "ACTIVE", ExpiresAt: at(now.Add(240 * time.Hour))},
+			},
+			want: "referral",
+		},
+		{
+			name: "lifetime beats dated grant",
+			grants: []model.EntitlementGrant{
+				{Source: model.EntitlementSourceGooglePlay, PlanID: "pro_yearly", Level: 1, Status: "CANCELED", ExpiresAt: at(now.Add(300 * 24 * time.Hour))},
+				{Source: model.EntitlementSourceApple, PlanID: "lifetime", Level: 1, Status: "ACTIVE"},
+			},
+			want: "lifetime",
+		},
+		{
+			name: "full tie keeps first source",
+			grants: []model.EntitlementGrant{
+				{Source: model.EntitlementSourceApple, PlanID: "apple_pro", Level: 1, Status: "ACTIVE", ExpiresAt: at(now.Add(time.Hour))},
+				{Source: model.EntitlementSourceStripe, PlanID: "stripe_pro", Level: 1, Status: "ACTIVE", ExpiresAt: at(now.Add(time.Hour))},
+			},
+			want: "apple_pro",
+		},
+		{
+			name: "expired grant never wins",
+			grants: []model.EntitlementGrant{
+				{Source: model.EntitlementSourceApple, PlanID: "pro", Level: 5, Status: "EXPIRED", ExpiresAt: at(now.Add(-time.Hour))},
+				{Source: model.EntitlementSourceComp, PlanID: "referral", Level: 1, Status: "ACTIVE", ExpiresAt: at(now.Add(time.Hour))},
+			},
+			want: "referral",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Resolve(tc.grants, nil)
+			if got.Subscription.ProductID != tc.want {
+				t.Fatalf("winner = %+v, want %s", got.Subscription, tc.want)
+			}
+			if len(got.Entitlements) != 1 || got.Entitlements[0].FeatureSet != model.DefaultFeatureSet || got.Entitlements[0].PlanID != tc.want {
+				t.Fatalf("entitlements = %+v", got.Entitlements)
+			}
+		})
+	}
+}
+
+func TestResolve_OnePerFeatureSet(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	sets := map[string]string{"pro_monthly": "pro", "storage_plus": "storage"}
+	got := Resolve([]model.EntitlementGrant{
+		{Source: model.EntitlementSourceApple, PlanID: "pro_monthly", Level: 2, Status: "ACTIVE", ExpiresAt: at(now.Add(24 * time.Hour))},
+		{Source: model.EntitlementSourceStripe, PlanID: "storage_plus", Level: 1, Status: "CANCELED", ExpiresAt: at(now.Add(48 * time.Hour))},
+		{Source: model.EntitlementSourceComp, PlanID: "referral", Level: 1, Status: "ACTIVE", ExpiresAt: at(now.Add(time.Hour))},
+	}, sets)
+	if len(got.Entitlements) != 3 {
+		t.Fatalf("entitlements = %+v", got.Entitlements)
+	}
+	want := []struct{ set, plan, source, status string }{
+		{model.DefaultFeatureSet, "referral", model.EntitlementSourceComp, "ACTIVE"},
+		{"pro", "pro_monthly", model.EntitlementSourceApple, "ACTIVE"},
+		{"storage", "storage_plus", model.EntitlementSourceStripe, "CANCELED"},
+	}
+	for i, w := range want {
+		e := got.Entitlements[i]
+		if e.FeatureSet != w.set || e.PlanID != w.plan || e.Source != w.source || e.Status != w.status || e.ExpiresAt == "" {
+			t.Fatalf("entitlements[%d] = %+v, want %+v", i, e, w)
+		}
+	}
+	if got.Subscription.ProductID != "pro_monthly" || got.Subscription.SubscribeLevel != 2 {
+		t.Fatalf("subscription = %+v", got.Subscription)
+	}
+}
+
+func TestResolve_ExposesPendingChange(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	end := now.Add(24 * time.Hour)
+	got := Resolve([]model.EntitlementGrant{
+		{Source: model.EntitlementSourceApple, PlanID: "pro_monthly", Level: 2, Status: "ACTIVE", ExpiresAt: at(end), PendingPlanID: "basic_monthly", PendingLevel: 1},
+	}, nil)
+	sub := got.Subscription
+	if sub.SubscribeLevel != 2 || sub.PendingProductID != "basic_monthly" || sub.PendingLevel != 1 || sub.PendingEffectiveTime != end.Format(time.RFC3339) {
+		t.Fatalf("subscription = %+v", sub)
+	}
+}
+
+func TestResolve_GracePeriodAndBillingRetry(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	grace := now.Add(3 * 24 * time.Hour)
+	got := Resolve([]model.EntitlementGrant{
+		{Source: model.EntitlementSourceApple, PlanID: "pro_monthly", Level: 1, Status: "GRACE_PERIOD", ExpiresAt: at(now.Add(-time.Hour)), GracePeriodExpiresAt: &grace},
+	}, nil)
+	if got.Subscription.Status != "GRACE_PERIOD" || got.Subscription.SubscribeLevel != 1 || got.Subscription.GracePeriodExpiredTime != grace.Format(time.RFC3339) || len(got.Entitlements) != 1 {
+		t.Fatalf("grace period must keep the entitlement: %+v", got)
+	}
+
+	got = Resolve([]model.EntitlementGrant{
+		{Source: model.EntitlementSourceApple, PlanID: "pro_monthly", Level: 1, Status: "BILLING_RETRY", ExpiresAt: at(now.Add(-time.Hour)), ExpirationReason: "BILLING_ERROR"},
+	}, nil)
+	if got.Subscription.Status != "BILLING_RETRY" || got.Subscription.SubscribeLevel != 0 || got.Subscription.ExpirationReason != "BILLING_ERROR" || len(got.Entitlements) != 0 {
+		t.Fatalf("billing retry must not grant access: %+v", got)
+	}
+}
+
+func TestResolve_ExpiredOnlyReportsLatest(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	got := Resolve([]model.EntitlementGrant{
+		{Source: model.EntitlementSourceApple, PlanID: "old", Level: 2, Status: "EXPIRED", ExpiresAt: at(now.Add(-48 * time.Hour))},
+		{Source: model.EntitlementSourceStripe, PlanID: "recent", Level: 1, Status: "EXPIRED", ExpiresAt: at(now.Add(-time.Hour))},
+	}, nil)
+	want := now.Add(-time.Hour).Format(time.RFC3339)
+	if got.Subscription.Status != "EXPIRED" || got.Subscription.ProductID != "recent" || got.Subscription.SubscribeLevel != 0 || got.Subscription.SubscribeExpiredTime != want {
+		t.Fatalf("subscription = %+v", got.Subscription)
+	}
+	if len(got.Entitlements) != 0 {
+		t.Fatalf("expired grants must not appear in entitlements: %+v", got.Entitlements)
+	}
+}
+
+func TestParseFeatureSets(t *testing.T) {
+	if got, err := ParseFeatureSets(" "); err != nil || got != nil {
+		t.Fatalf("empty = %v, %v", got, err)
+	}
+	got, err := ParseFeatureSets(`{"pro_monthly":"pro"}`)
+	if err != nil || got["pro_monthly"] != "pro" {
+		t.Fatalf("parse = %v, %v", got, err)
+	}
+	for _, raw := range []string{`[`, `{"pro_monthly":""}`, `{"":"pro"}`} {
+		if _, err := ParseFeatureSets(raw); err == nil {
+			t.Fatalf("ParseFeatureSets(%q) should fail", raw)
+		}
+	}
+}
+
+func TestCompSource_ChainedComps(t *testing.T) {
+	now := time.Now().UTC()
+	comps := &fakeCompDAO{comps: []model.CompEntitlement{
+		{PlanID: "referral", Level: 1, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(24 * time.Hour)},
+		{PlanID: "referral", Level: 1, StartsAt: now.Add(24 * time.Hour), EndsAt: now.Add(72 * time.Hour)},
+	}}
+	e := NewEngine(nil, NewCompSource(comps))
+	e.now = func() time.Time { return now }
+	info, err := e.LoadSubscriptionInfo(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := now.Add(72 * time.Hour).Format(time.RFC3339)
+	if info.Status != "ACTIVE" || info.ProductID != "referral" || info.SubscribeLevel != 1 || info.SubscribeExpiredTime != want {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+}
+
+func TestCompSource_ShorterCompLosesToPaid(t *testing.T) {
+	now := time.Now().UTC()
+	paid := staticSource{grants: []model.EntitlementGrant{
+		{Source: model.EntitlementSourceApple, PlanID: "pro_monthly", Level: 1, Status: "ACTIVE", ExpiresAt: at(now.Add(48 * time.Hour))},
+	}}
+	comps := &fakeCompDAO{comps: []model.CompEntitlement{
+		{PlanID: "referral", Level: 1, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(24 * time.Hour)},
+	}}
+	e := NewEngine(nil, paid, NewCompSource(comps))
+	e.now = func() time.Time { return now }
+	info, err := e.LoadSubscriptionInfo(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.ProductID != "pro_monthly" {
+		t.Fatalf("paid subscription should win, got %+v", info)
+	}
+}
+
+func TestActiveComp_IgnoresFutureAndGaps(t *testing.T) {
+	now := time.Now().UTC()
+	if _, ok := activeComp([]model.CompEntitlement{
+		{Level: 1, StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)},
+	}, now); ok {
+		t.Fatal("future comp must not be active")
+	}
+	got, ok := activeComp([]model.CompEntitlement{
+		{Level: 1, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)},
+		{Level: 2, StartsAt: now.Add(2 * time.Hour), EndsAt: now.Add(5 * time.Hour)},
+	}, now)
+	if !ok || got.Level != 1 || !got.EndsAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("chain must stop at gap, got %+v ok=%v", got, ok)
+	}
+}
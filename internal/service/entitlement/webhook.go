@@ -0,0 +1,459 @@
+package entitlement
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/pkg/job"
+	logpkg "github.com/dundunHa/go-serverhttp-template/pkg/log"
+)
+
+// 出站请求头：事件 ID 供下游去重，签名格式与 Stripe-Signature 相同。
+const (
+	WebhookIDHeader        = "X-Entitlement-Webhook-Id"
+	WebhookSignatureHeader = "X-Entitlement-Webhook-Signature"
+)
+
+var (
+	ErrWebhookNotConfigured      = errors.New("entitlement webhook: not configured")
+	ErrInvalidWebhookSubscriber  = errors.New("entitlement webhook: invalid subscriber")
+	ErrInvalidWebhookCursor      = errors.New("entitlement webhook: invalid cursor")
+	ErrInvalidWebhookSignature   = errors.New("entitlement webhook: invalid signature")
+	ErrWebhookSubscriberNotFound = dao.ErrWebhookSubscriberNotFound
+	ErrWebhookDeliveryNotFound   = dao.ErrWebhookDeliveryNotFound
+)
+
+const (
+	defaultWebhookPageSize = 20
+	maxWebhookPageSize     = 100
+	webhookCursorPrefix    = "d:"
+	minWebhookSecretLength = 16
+	// webhookErrorBodyLimit 是失败时记录到 last_error 的响应体长度上限。
+	webhookErrorBodyLimit = 256
+)
+
+// WebhookConfig 是出站权益 webhook 投递任务的参数，含义见 config.EntitlementWebhookConfig。
+// RequireHTTPS 为 true（生产环境）时订阅方 URL 必须是 https。
+type WebhookConfig struct {
+	BatchSize    int
+	MaxAttempts  int
+	RetryBackoff time.Duration
+	// MaxRetryBackoff 是单次重试间隔的上限；<= 0 时取 1h。
+	MaxRetryBackoff time.Duration
+	Timeout         time.Duration
+	RequireHTTPS    bool
+}
+
+// WebhookService 把订阅状态变更推送给下游服务：各渠道的订阅写入在同一事务内记录事件并扇出投递，
+// 本 service 定时领取到期投递、签名后 POST 给订阅方，失败时指数退避，次数用尽后记为 DEAD 等待管理员重放。
+//
+// 投递语义是至少一次：下游应按 X-Entitlement-Webhook-Id 去重，并以 data 中的状态为准而不是依赖到达顺序。
+type WebhookService struct {
+	dao    dao.EntitlementWebhookDAO
+	client *http.Client
+	cfg    WebhookConfig
+	now    func() time.Time
+}
+
+// NewWebhookService 构造投递任务与订阅方管理；webhookDAO 为 nil 时各方法返回 ErrWebhookNotConfigured。
+func NewWebhookService(webhookDAO dao.EntitlementWebhookDAO, cfg WebhookConfig) *WebhookService {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 30 * time.Second
+	}
+	if cfg.MaxRetryBackoff <= 0 {
+		cfg.MaxRetryBackoff = time.Hour
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &WebhookService{
+		dao: webhookDAO,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// 重定向视为失败，避免把签名请求转发到订阅方未登记的地址。
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		cfg: cfg,
+		now: func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// WebhookSendResult 是单次 SendDue 的统计。
+type WebhookSendResult struct {
+	Delivered int
+	Retried   int
+	Dead      int
+}
+
+// Run 执行一轮 SendDue 并记录结果，作为后台任务的周期回调。
+func (s *WebhookService) Run(ctx context.Context) error {
+	res, err := s.SendDue(ctx)
+	if err != nil {
+		return err
+	}
+	if res.Delivered+res.Retried+res.Dead > 0 {
+		slog.Info("entitlement webhook delivery done", "delivered", res.Delivered, "retried", res.Retried, "dead", res.Dead)
+	}
+	return nil
+}
+
+// SendDue 领取一批到期的投递逐条发送。领取时按 job.BatchLease 加租约。
+func (s *WebhookService) SendDue(ctx context.Context) (*WebhookSendResult, error) {
+	if s == nil || s.dao == nil {
+		return nil, ErrWebhookNotConfigured
+	}
+	now := s.now()
+	rows, err := s.dao.ClaimDueDeliveries(ctx, now, now.Add(job.BatchLease(s.cfg.Timeout, s.cfg.BatchSize)), s.cfg.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("entitlement webhook: claim due deliveries: %w", err)
+	}
+	out := &WebhookSendResult{}
+	for _, d := range rows {
+		if ctx.Err() != nil {
+			break
+		}
+		status, err := s.sendOne(ctx, d)
+		if err != nil {
+			return out, err
+		}
+		switch status {
+		case model.WebhookDeliveryDelivered:
+			out.Delivered++
+		case model.WebhookDeliveryPending:
+			out.Retried++
+		default:
+			out.Dead++
+		}
+	}
+	return out, ctx.Err()
+}
+
+// sendOne 发送单条投递并写回结果，返回该行的新状态；只有写回本身失败时返回 error。
+func (s *WebhookService) sendOne(ctx context.Context, d model.EntitlementWebhookDispatch) (string, error) {
+	statusCode, err := s.post(ctx, d)
+	now := s.now()
+	if err == nil {
+		if err := s.dao.MarkDeliveryDelivered(ctx, d.DeliveryID, statusCode, now); err != nil {
+			return "", fmt.Errorf("entitlement webhook: mark delivered: %w", err)
+		}
+		return model.WebhookDeliveryDelivered, nil
+	}
+	if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		return "", err
+	}
+
+	attempts := d.Attempts + 1
+	slog.Warn("entitlement webhook attempt failed", "delivery_id", d.DeliveryID, "subscriber_id", d.SubscriberID,
+		"event_id", d.EventID, "attempts", attempts, "status_code", statusCode, "err", err)
+	status, next := model.WebhookDeliveryPending, now.Add(job.Backoff(s.cfg.RetryBackoff, attempts, s.cfg.MaxRetryBackoff))
+	if attempts >= s.cfg.MaxAttempts {
+		status, next = model.WebhookDeliveryDead, now
+	}
+	if err := s.dao.MarkDeliveryFailed(ctx, d.DeliveryID, status, statusCode, err.Error(), next); err != nil {
+		return "", fmt.Errorf("entitlement webhook: mark failed: %w", err)
+	}
+	return status, nil
+}
+
+// webhookEnvelope 是请求体；同一事件的每次重试 body 完全相同。
+type webhookEnvelope struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// post 发送签名请求；2xx 视为成功，其余状态码与网络错误都返回 error。
+func (s *WebhookService) post(ctx context.Context, d model.EntitlementWebhookDispatch) (int, error) {
+	eventID := strconv.FormatInt(d.EventID, 10)
+	body, err := json.Marshal(webhookEnvelope{
+		ID:        eventID,
+		Type:      d.EventType,
+		CreatedAt: d.EventCreatedAt.UTC(),
+		Data:      d.Payload,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("encode body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, eventID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(d.Secret, s.now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, webhookErrorBodyLimit))
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhook 返回签名头的值：t=<unix 秒>,v1=<hex(HMAC-SHA256(secret, "<t>.<body>"))>。
+func SignWebhook(secret string, at time.Time, body []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(webhookMAC(secret, ts, body))
+}
+
+// VerifyWebhookSignature 校验签名头，供 Go 编写的下游服务直接使用；时间戳与 now 相差超过 tolerance 时拒绝，防止重放。
+func VerifyWebhookSignature(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var (
+		ts         string
+		signatures []string
+	)
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return fmt.Errorf("%w: missing timestamp or v1 signature", ErrInvalidWebhookSignature)
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > tolerance || skew < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidWebhookSignature)
+	}
+	expected := webhookMAC(secret, ts, body)
+	for _, sig := range signatures {
+		got, err := hex.DecodeString(sig)
+		if err == nil && hmac.Equal(got, expected) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no matching v1 signature", ErrInvalidWebhookSignature)
+}
+
+func webhookMAC(secret, ts string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// ───────── 订阅方与投递管理 ─────────
+
+// ListSubscribers 返回全部订阅方（含已停用）。
+func (s *WebhookService) ListSubscribers(ctx context.Context) ([]model.EntitlementWebhookSubscriber, error) {
+	if s == nil || s.dao == nil {
+		return nil, ErrWebhookNotConfigured
+	}
+	return s.dao.ListSubscribers(ctx)
+}
+
+// CreateSubscriber 新增订阅方；in.Secret 为空时生成随机密钥。返回值带有明文密钥，调用方只应展示这一次。
+func (s *WebhookService) CreateSubscriber(ctx context.Context, actorID int64, in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriber, error) {
+	if s == nil || s.dao == nil {
+		return model.EntitlementWebhookSubscriber{}, ErrWebhookNotConfigured
+	}
+	in, err := s.normalizeSubscriberInput(in)
+	if err != nil {
+		return model.EntitlementWebhookSubscriber{}, err
+	}
+	if in.Secret == "" {
+		if in.Secret, err = newWebhookSecret(); err != nil {
+			return model.EntitlementWebhookSubscriber{}, err
+		}
+	}
+	row, err := s.dao.CreateSubscriber(ctx, in)
+	if err != nil {
+		return model.EntitlementWebhookSubscriber{}, err
+	}
+	s.auditSubscriber(ctx, "create", actorID, row, true)
+	return row, nil
+}
+
+// UpdateSubscriber 整体覆盖订阅方配置；in.Secret 为空时保留原密钥。
+func (s *WebhookService) UpdateSubscriber(ctx context.Context, actorID, id int64, in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriber, error) {
+	if s == nil || s.dao == nil {
+		return model.EntitlementWebhookSubscriber{}, ErrWebhookNotConfigured
+	}
+	in, err := s.normalizeSubscriberInput(in)
+	if err != nil {
+		return model.EntitlementWebhookSubscriber{}, err
+	}
+	row, err := s.dao.UpdateSubscriber(ctx, id, in)
+	if err != nil {
+		return model.EntitlementWebhookSubscriber{}, err
+	}
+	s.auditSubscriber(ctx, "update", actorID, row, in.Secret != "")
+	return row, nil
+}
+
+func (s *WebhookService) auditSubscriber(ctx context.Context, action string, actorID int64, row model.EntitlementWebhookSubscriber, secretChanged bool) {
+	logpkg.FromContext(ctx).Info("admin entitlement webhook subscriber change",
+		"action", action,
+		"actor_user_id", actorID,
+		"id", row.ID,
+		"name", row.Name,
+		"url", row.URL,
+		"event_types", row.EventTypes,
+		"active", row.Active,
+		"secret_changed", secretChanged,
+	)
+}
+
+// normalizeSubscriberInput 校验并规整订阅方配置；不合法时返回 wrap ErrInvalidWebhookSubscriber 的错误。
+func (s *WebhookService) normalizeSubscriberInput(in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriberInput, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	in.URL = strings.TrimSpace(in.URL)
+	if in.Name == "" {
+		return in, fmt.Errorf("%w: name required", ErrInvalidWebhookSubscriber)
+	}
+	u, err := url.Parse(in.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return in, fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhookSubscriber)
+	}
+	if s.cfg.RequireHTTPS && u.Scheme != "https" {
+		return in, fmt.Errorf("%w: url must use https in production", ErrInvalidWebhookSubscriber)
+	}
+	if in.Secret != "" && len(in.Secret) < minWebhookSecretLength {
+		return in, fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidWebhookSubscriber, minWebhookSecretLength)
+	}
+	types := make([]string, 0, len(in.EventTypes))
+	for _, t := range in.EventTypes {
+		if !slices.Contains(model.EntitlementEventTypes, t) {
+			return in, fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhookSubscriber, t)
+		}
+		if !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		return in, fmt.Errorf("%w: at least one event type required", ErrInvalidWebhookSubscriber)
+	}
+	in.EventTypes = types
+	return in, nil
+}
+
+func newWebhookSecret() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("entitlement webhook: generate secret: %w", err)
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// WebhookDeliveryFilter 是投递记录的过滤条件；零值字段不过滤。
+type WebhookDeliveryFilter struct {
+	SubscriberID int64
+	Status       string
+}
+
+// WebhookDeliveryPage 是一页投递记录；NextCursor 为空表示没有更多结果。
+type WebhookDeliveryPage struct {
+	Deliveries []model.EntitlementWebhookDelivery
+	NextCursor string
+}
+
+// ListDeliveries 按 id 倒序分页返回投递记录。cursor 为上一页返回的 NextCursor，首页传空串。
+func (s *WebhookService) ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter, cursor string, limit int) (WebhookDeliveryPage, error) {
+	if s == nil || s.dao == nil {
+		return WebhookDeliveryPage{}, ErrWebhookNotConfigured
+	}
+	if limit <= 0 {
+		limit = defaultWebhookPageSize
+	}
+	limit = min(limit, maxWebhookPageSize)
+	beforeID, err := decodeWebhookCursor(cursor)
+	if err != nil {
+		return WebhookDeliveryPage{}, err
+	}
+	rows, err := s.dao.ListDeliveries(ctx, model.EntitlementWebhookDeliveryQuery{
+		SubscriberID: filter.SubscriberID,
+		Status:       filter.Status,
+		BeforeID:     beforeID,
+		Limit:        limit + 1,
+	})
+	if err != nil {
+		return WebhookDeliveryPage{}, err
+	}
+	page := WebhookDeliveryPage{Deliveries: rows}
+	if len(rows) > limit {
+		page.Deliveries = rows[:limit]
+		page.NextCursor = encodeWebhookCursor(rows[limit-1].ID)
+	}
+	return page, nil
+}
+
+// ReplayDelivery 把一条投递（任意状态）重新排入队列并清零尝试次数，下一轮任务立即发送。
+func (s *WebhookService) ReplayDelivery(ctx context.Context, actorID, id int64) error {
+	if s == nil || s.dao == nil {
+		return ErrWebhookNotConfigured
+	}
+	if err := s.dao.ReplayDelivery(ctx, id, s.now()); err != nil {
+		return err
+	}
+	logpkg.FromContext(ctx).Info("admin entitlement webhook replay", "actor_user_id", actorID, "delivery_id", id)
+	return nil
+}
+
+// ReplayDeadDeliveries 把订阅方的全部 DEAD 投递重新排入队列，返回重放的条数。
+func (s *WebhookService) ReplayDeadDeliveries(ctx context.Context, actorID, subscriberID int64) (int64, error) {
+	if s == nil || s.dao == nil {
+		return 0, ErrWebhookNotConfigured
+	}
+	n, err := s.dao.ReplayDeadDeliveries(ctx, subscriberID, s.now())
+	if err != nil {
+		return 0, err
+	}
+	logpkg.FromContext(ctx).Info("admin entitlement webhook replay dead", "actor_user_id", actorID, "subscriber_id", subscriberID, "replayed", n)
+	return n, nil
+}
+
+func encodeWebhookCursor(lastID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(webhookCursorPrefix + strconv.FormatInt(lastID, 10)))
+}
+
+func decodeWebhookCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), webhookCursorPrefix) {
+		return 0, ErrInvalidWebhookCursor
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(string(raw), webhookCursorPrefix), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidWebhookCursor
+	}
+	return id, nil
+}
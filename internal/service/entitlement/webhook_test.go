@@ -0,0 +1,260 @@
+package entitlement
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+type fakeWebhookDAO struct {
+	due       []model.EntitlementWebhookDispatch
+	delivered map[int64]int
+	failed    map[int64]failedAttempt
+	created   []model.EntitlementWebhookSubscriberInput
+	updated   []model.EntitlementWebhookSubscriberInput
+	query     model.EntitlementWebhookDeliveryQuery
+	rows      []model.EntitlementWebhookDelivery
+	leaseTill time.Time
+}
+
+type failedAttempt struct {
+	status     string
+	statusCode int
+	lastError  string
+	next       time.Time
+}
+
+func (d *fakeWebhookDAO) ListSubscribers(context.Context) ([]model.EntitlementWebhookSubscriber, error) {
+	return nil, nil
+}
+
+func (d *fakeWebhookDAO) CreateSubscriber(_ context.Context, in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriber, error) {
+	d.created = append(d.created, in)
+	return model.EntitlementWebhookSubscriber{ID: 1, Name: in.Name, URL: in.URL, Secret: in.Secret, EventTypes: in.EventTypes, Active: in.Active}, nil
+}
+
+func (d *fakeWebhookDAO) UpdateSubscriber(_ context.Context, id int64, in model.EntitlementWebhookSubscriberInput) (model.EntitlementWebhookSubscriber, error) {
+	if id != 1 {
+		return model.EntitlementWebhookSubscriber{}, dao.ErrWebhookSubscriberNotFound
+	}
+	d.updated = append(d.updated, in)
+	return model.EntitlementWebhookSubscriber{ID: id, Name: in.Name, URL: in.URL, EventTypes: in.EventTypes, Active: in.Active}, nil
+}
+
+func (d *fakeWebhookDAO) ClaimDueDeliveries(_ context.Context, _, leaseUntil time.Time, limit int) ([]model.EntitlementWebhookDispatch, error) {
+	d.leaseTill = leaseUntil
+	out := d.due[:min(limit, len(d.due))]
+	d.due = d.due[len(out):]
+	return out, nil
+}
+
+func (d *fakeWebhookDAO) MarkDeliveryDelivered(_ context.Context, id int64, statusCode int, _ time.Time) error {
+	if d.delivered == nil {
+		d.delivered = map[int64]int{}
+	}
+	d.delivered[id] = statusCode
+	return nil
+}
+
+func (d *fakeWebhookDAO) MarkDeliveryFailed(_ context.Context, id int64, status string, statusCode int, lastError string, next time.Time) error {
+	if d.failed == nil {
+		d.failed = map[int64]failedAttempt{}
+	}
+	d.failed[id] = failedAttempt{status: status, statusCode: statusCode, lastError: lastError, next: next}
+	return nil
+}
+
+func (d *fakeWebhookDAO) ListDeliveries(_ context.Context, q model.EntitlementWebhookDeliveryQuery) ([]model.EntitlementWebhookDelivery, error) {
+	d.query = q
+	return d.rows[:min(q.Limit, len(d.rows))], nil
+}
+
+func (d *fakeWebhookDAO) ReplayDelivery(context.Context, int64, time.Time) error { return nil }
+
+func (d *fakeWebhookDAO) ReplayDeadDeliveries(context.Context, int64, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newTestWebhookService(d dao.EntitlementWebhookDAO, cfg WebhookConfig, now time.Time) *WebhookService {
+	s := NewWebhookService(d, cfg)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestWebhookService_SendDueSignsAndDelivers(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	var (
+		gotBody   []byte
+		gotHeader http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeader = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := &fakeWebhookDAO{due: []model.EntitlementWebhookDispatch{{
+		DeliveryID: 7, EventID: 42, SubscriberID: 1, EventType: model.EntitlementEventActivated, UserID: 9,
+		Payload:        []byte(`{"user_id":9,"source":"STRIPE","status":"ACTIVE"}`),
+		EventCreatedAt: now.Add(-time.Minute), URL: srv.URL, Secret: "whsec_test_secret_value",
+	}}}
+	s := newTestWebhookService(d, WebhookConfig{BatchSize: 10, Timeout: 5 * time.Second}, now)
+	res, err := s.SendDue(context.Background())
+	if err != nil || res.Delivered != 1 || d.delivered[7] != http.StatusNoContent {
+		t.Fatalf("res=%+v err=%v delivered=%v", res, err, d.delivered)
+	}
+	if !d.leaseTill.After(now.Add(10 * 5 * time.Second)) {
+		t.Fatalf("lease %v does not cover the batch", d.leaseTill)
+	}
+	if gotHeader.Get(WebhookIDHeader) != "42" || gotHeader.Get("Content-Type") != "application/json" {
+		t.Fatalf("headers = %v", gotHeader)
+	}
+	if err := VerifyWebhookSignature("whsec_test_secret_value", gotHeader.Get(WebhookSignatureHeader), gotBody, now, 5*time.Minute); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if err := VerifyWebhookSignature("other-secret", gotHeader.Get(WebhookSignatureHeader), gotBody, now, 5*time.Minute); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Fatalf("wrong secret err = %v", err)
+	}
+	if err := VerifyWebhookSignature("whsec_test_secret_value", gotHeader.Get(WebhookSignatureHeader), gotBody, now.Add(time.Hour), 5*time.Minute); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Fatalf("stale timestamp err = %v", err)
+	}
+	var env struct {
+		ID        string          `json:"id"`
+		Type      string          `json:"type"`
+		CreatedAt time.Time       `json:"created_at"`
+		Data      json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(gotBody, &env); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if env.ID != "42" || env.Type != model.EntitlementEventActivated || !env.CreatedAt.Equal(now.Add(-time.Minute)) ||
+		string(env.Data) != `{"user_id":9,"source":"STRIPE","status":"ACTIVE"}` {
+		t.Fatalf("body = %s", gotBody)
+	}
+}
+
+func TestWebhookService_SendDueBacksOffThenDeadLetters(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(srv.URL, http.StatusFound))
+	defer redirect.Close()
+
+	d := &fakeWebhookDAO{due: []model.EntitlementWebhookDispatch{
+		{DeliveryID: 1, EventID: 1, Attempts: 0, Payload: []byte(`{}`), URL: srv.URL, Secret: "s"},
+		{DeliveryID: 2, EventID: 2, Attempts: 2, Payload: []byte(`{}`), URL: redirect.URL, Secret: "s"},
+		{DeliveryID: 3, EventID: 3, Attempts: 3, Payload: []byte(`{}`), URL: srv.URL, Secret: "s"},
+	}}
+	s := newTestWebhookService(d, WebhookConfig{MaxAttempts: 4, RetryBackoff: time.Minute}, now)
+	res, err := s.SendDue(context.Background())
+	if err != nil || res.Retried != 2 || res.Dead != 1 || len(d.delivered) != 0 {
+		t.Fatalf("res=%+v err=%v", res, err)
+	}
+	if f := d.failed[1]; f.status != model.WebhookDeliveryPending || !f.next.Equal(now.Add(time.Minute)) ||
+		f.statusCode != http.StatusServiceUnavailable || !strings.Contains(f.lastError, "maintenance") {
+		t.Fatalf("first attempt = %+v", f)
+	}
+	// 重定向不跟随，视为失败；第 3 次失败退避 4 倍。
+	if f := d.failed[2]; f.status != model.WebhookDeliveryPending || !f.next.Equal(now.Add(4*time.Minute)) || f.statusCode != http.StatusFound {
+		t.Fatalf("redirect attempt = %+v", f)
+	}
+	if f := d.failed[3]; f.status != model.WebhookDeliveryDead {
+		t.Fatalf("last attempt = %+v, want DEAD", f)
+	}
+}
+
+func TestWebhookService_SendDueCapsBackoff(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	// 退避间隔不能随次数无限翻倍，更不能移位溢出后变成立即重试。
+	d := &fakeWebhookDAO{due: []model.EntitlementWebhookDispatch{
+		{DeliveryID: 1, EventID: 1, Attempts: 70, Payload: []byte(`{}`), URL: srv.URL, Secret: "s"},
+	}}
+	s := newTestWebhookService(d, WebhookConfig{MaxAttempts: 100, RetryBackoff: time.Minute, MaxRetryBackoff: 2 * time.Hour}, now)
+	if _, err := s.SendDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if f := d.failed[1]; f.status != model.WebhookDeliveryPending || !f.next.Equal(now.Add(2*time.Hour)) {
+		t.Fatalf("attempt = %+v, want PENDING at +2h", f)
+	}
+}
+
+func TestWebhookService_SubscriberValidation(t *testing.T) {
+	d := &fakeWebhookDAO{}
+	s := NewWebhookService(d, WebhookConfig{RequireHTTPS: true})
+	ctx := context.Background()
+	valid := model.EntitlementWebhookSubscriberInput{
+		Name:       " billing ",
+		URL:        "https://billing.example.com/hooks",
+		EventTypes: []string{model.EntitlementEventActivated, model.EntitlementEventActivated, model.EntitlementEventDeactivated},
+		Active:     true,
+	}
+	for name, mutate := range map[string]func(*model.EntitlementWebhookSubscriberInput){
+		"plain http in prod": func(in *model.EntitlementWebhookSubscriberInput) { in.URL = "http://billing.example.com/hooks" },
+		"relative url":       func(in *model.EntitlementWebhookSubscriberInput) { in.URL = "/hooks" },
+		"blank name":         func(in *model.EntitlementWebhookSubscriberInput) { in.Name = " " },
+		"unknown event":      func(in *model.EntitlementWebhookSubscriberInput) { in.EventTypes = []string{"subscription.created"} },
+		"no events":          func(in *model.EntitlementWebhookSubscriberInput) { in.EventTypes = nil },
+		"short secret":       func(in *model.EntitlementWebhookSubscriberInput) { in.Secret = "short" },
+	} {
+		in := valid
+		mutate(&in)
+		if _, err := s.CreateSubscriber(ctx, 1, in); !errors.Is(err, ErrInvalidWebhookSubscriber) {
+			t.Fatalf("%s: err = %v, want ErrInvalidWebhookSubscriber", name, err)
+		}
+	}
+
+	sub, err := s.CreateSubscriber(ctx, 1, valid)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if sub.Name != "billing" || len(sub.EventTypes) != 2 || !strings.HasPrefix(sub.Secret, "whsec_") || len(sub.Secret) < minWebhookSecretLength {
+		t.Fatalf("created = %+v", sub)
+	}
+	// 更新时省略 secret 原样传给 DAO（保留原密钥），不重新生成。
+	if _, err := s.UpdateSubscriber(ctx, 1, 1, valid); err != nil || d.updated[0].Secret != "" {
+		t.Fatalf("update: %v %+v", err, d.updated)
+	}
+	if _, err := s.UpdateSubscriber(ctx, 1, 2, valid); !errors.Is(err, ErrWebhookSubscriberNotFound) {
+		t.Fatalf("update missing err = %v", err)
+	}
+}
+
+func TestWebhookService_ListDeliveriesPaginates(t *testing.T) {
+	d := &fakeWebhookDAO{rows: []model.EntitlementWebhookDelivery{{ID: 9}, {ID: 8}, {ID: 7}}}
+	s := NewWebhookService(d, WebhookConfig{})
+	ctx := context.Background()
+
+	page, err := s.ListDeliveries(ctx, WebhookDeliveryFilter{SubscriberID: 3, Status: model.WebhookDeliveryDead}, "", 2)
+	if err != nil || len(page.Deliveries) != 2 || page.NextCursor == "" {
+		t.Fatalf("first page: %+v %v", page, err)
+	}
+	if d.query.SubscriberID != 3 || d.query.Status != model.WebhookDeliveryDead || d.query.Limit != 3 || d.query.BeforeID != 0 {
+		t.Fatalf("query = %+v", d.query)
+	}
+	if _, err := s.ListDeliveries(ctx, WebhookDeliveryFilter{}, page.NextCursor, 2); err != nil || d.query.BeforeID != 8 {
+		t.Fatalf("second page query = %+v err=%v", d.query, err)
+	}
+	if _, err := s.ListDeliveries(ctx, WebhookDeliveryFilter{}, "bogus", 2); !errors.Is(err, ErrInvalidWebhookCursor) {
+		t.Fatalf("bad cursor err = %v", err)
+	}
+	if _, err := (*WebhookService)(nil).ListDeliveries(ctx, WebhookDeliveryFilter{}, "", 2); !errors.Is(err, ErrWebhookNotConfigured) {
+		t.Fatalf("nil service err = %v", err)
+	}
+}
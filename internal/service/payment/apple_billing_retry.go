@@ -0,0 +1,30 @@
+package payment
+
+import "github.com/dundunHa/go-serverhttp-template/internal/model"
+
+// applyRenewalFailure 按 renewal info 记录 Apple 是否仍在重试扣款及到期原因；ri 为 nil 时保留已有值。
+func applyRenewalFailure(upsert *model.SubscriptionUpsert, ri *AppleRenewalInfo) {
+	if ri == nil {
+		return
+	}
+	upsert.BillingRetry = ri.IsInBillingRetryPeriod
+	upsert.ExpirationIntent = ri.ExpirationIntent
+}
+
+// expirationReason 把 Apple expirationIntent 映射为对外的到期原因；0 / 未知取值返回空串。
+func expirationReason(intent int) string {
+	switch intent {
+	case 1:
+		return "CUSTOMER_CANCELED"
+	case 2:
+		return "BILLING_ERROR"
+	case 3:
+		return "PRICE_INCREASE_DECLINED"
+	case 4:
+		return "PRODUCT_UNAVAILABLE"
+	case 5:
+		return "OTHER"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,54 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func TestAppleWebhookService_DidFailToRenewEntersBillingRetry(t *testing.T) {
+	now := time.Now().UTC()
+	tok := "00000000-0000-4000-8000-000000000093"
+	tx := &AppleTransaction{
+		TransactionID: "tx", OriginalTransactionID: "ot-retry",
+		AppAccountToken: tok, BundleID: "com.app.example",
+		Environment: EnvProduction, ProductID: "com.app.pro.monthly",
+		Type:         "Auto-Renewable Subscription",
+		PurchaseDate: now.Add(-31 * 24 * time.Hour), ExpiresDate: now.Add(-24 * time.Hour),
+	}
+	event := makeEvent("DID_FAIL_TO_RENEW", "", tx)
+	event.RenewalInfo = &AppleRenewalInfo{AutoRenewStatus: 1, ExpirationIntent: 2}
+	d := &fakeIAPDAO{}
+	svc := NewAppleWebhookService(newProdCatalog(t), &fakeWebhookVerifier{event: event}, newTokensWithFakeDAO(93, tok), d)
+	if err := svc.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("did_fail_to_renew: %v", err)
+	}
+	u := d.upserts[0]
+	if !u.BillingRetry || u.ExpirationIntent != 2 {
+		t.Fatalf("billing retry not recorded: %+v", u)
+	}
+}
+
+func TestSubscriptionInfoFromRow_RenewalFailure(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	past := now.Add(-24 * time.Hour)
+	grace := now.Add(5 * 24 * time.Hour)
+
+	info := subscriptionInfoFromRow(model.Subscription{
+		PlanID: "pro_monthly", Level: 1, Status: model.SubscriptionStatusActive,
+		CurrentPeriodEnd: past, GracePeriodExpiresAt: &grace, BillingRetry: true, ExpirationIntent: 2,
+	}, now)
+	if info.Status != "GRACE_PERIOD" || info.SubscribeLevel != 1 || info.GracePeriodExpiredTime != grace.Format(time.RFC3339) || info.ExpirationReason != "" {
+		t.Fatalf("grace period info = %+v", info)
+	}
+
+	info = subscriptionInfoFromRow(model.Subscription{
+		PlanID: "pro_monthly", Level: 1, Status: model.SubscriptionStatusActive,
+		CurrentPeriodEnd: past, BillingRetry: true, ExpirationIntent: 2,
+	}, now)
+	if info.Status != "BILLING_RETRY" || info.SubscribeLevel != 0 || info.ExpirationReason != "BILLING_ERROR" {
+		t.Fatalf("billing retry info = %+v", info)
+	}
+}
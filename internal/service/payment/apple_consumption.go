@@ -0,0 +1,364 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/pkg/job"
+)
+
+// appleConsumptionResponseWindow 是 Apple 接受 Send Consumption Information 的期限：通知发出后 12 小时。
+const appleConsumptionResponseWindow = 12 * time.Hour
+
+// Send Consumption Information 中使用到的枚举值，完整取值见 Apple 文档。
+const (
+	consumptionPlatformApple     = 1
+	consumptionDeliveredOK       = 0
+	consumptionDeliveryOther     = 5
+	consumptionNotConsumed       = 1
+	consumptionPartiallyConsumed = 2
+	consumptionFullyConsumed     = 3
+	consumptionUserActive        = 1
+	consumptionUserSuspended     = 2
+	consumptionUserTerminated    = 3
+)
+
+// AppleConsumptionConfig 是 CONSUMPTION_REQUEST 回复任务的参数，含义见 config.AppleConsumptionConfig。
+type AppleConsumptionConfig struct {
+	// Timeout 是单次调用 Apple 的超时，用于计算领取请求时的租约；<= 0 时取 10s。
+	Timeout      time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	RetryBackoff time.Duration
+	// MaxRetryBackoff 是单次重试间隔的上限；<= 0 时取 1h。
+	MaxRetryBackoff   time.Duration
+	CustomerConsented bool
+	RefundPreference  int
+}
+
+// ConsumptionUsage 是业务侧对某次购买的使用情况。
+type ConsumptionUsage struct {
+	// PlayTime 是用户在 app 内的使用时长，按 Apple playTime 区间上报；0 表示未知。
+	PlayTime time.Duration
+	// SampleContentProvided 表示购买前是否提供过试用 / 样例内容。
+	SampleContentProvided bool
+}
+
+// ConsumptionUsageProvider 由接入方实现，提供播放 / 使用时长等本服务无法得知的指标。
+// 未设置时 playTime 上报为未声明。
+type ConsumptionUsageProvider interface {
+	ConsumptionUsage(ctx context.Context, req model.AppleConsumptionRequest) (ConsumptionUsage, error)
+}
+
+// AppleConsumptionService 回复 Apple 的 CONSUMPTION_REQUEST：webhook 在事务内记录请求，
+// 本 service 定时计算 consumption 字段并调用 Send Consumption Information，失败时指数退避重试，
+// 直到成功、次数用尽或超过 Apple 的回复期限。
+type AppleConsumptionService struct {
+	dao    dao.AppleConsumptionDAO
+	sender AppleConsumptionSender
+	usage  ConsumptionUsageProvider
+	cfg    AppleConsumptionConfig
+	now    func() time.Time
+}
+
+// NewAppleConsumptionService 构造回复任务；任一依赖为 nil 时 SendDue 返回 ErrNotConfigured。
+func NewAppleConsumptionService(consumptionDAO dao.AppleConsumptionDAO, sender AppleConsumptionSender, cfg AppleConsumptionConfig) *AppleConsumptionService {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Minute
+	}
+	if cfg.MaxRetryBackoff <= 0 {
+		cfg.MaxRetryBackoff = time.Hour
+	}
+	return &AppleConsumptionService{
+		dao:    consumptionDAO,
+		sender: sender,
+		cfg:    cfg,
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// WithUsageProvider 设置使用时长的来源；provider 为 nil 时 playTime 上报为未声明。返回 s 便于链式调用。
+func (s *AppleConsumptionService) WithUsageProvider(provider ConsumptionUsageProvider) *AppleConsumptionService {
+	s.usage = provider
+	return s
+}
+
+// ConsumptionSendResult 是单次 SendDue 的统计。
+type ConsumptionSendResult struct {
+	Sent    int
+	Retried int
+	Failed  int
+}
+
+// Run 执行一轮 SendDue 并记录结果，作为后台任务的周期回调。
+func (s *AppleConsumptionService) Run(ctx context.Context) error {
+	res, err := s.SendDue(ctx)
+	if err != nil {
+		return err
+	}
+	if res.Sent+res.Retried+res.Failed > 0 {
+		slog.Info("apple consumption response done", "sent", res.Sent, "retried", res.Retried, "failed", res.Failed)
+	}
+	return nil
+}
+
+// SendDue 领取一批到期的 PENDING 请求逐条回复。单条失败只影响该行的重试计划。领取时按 job.BatchLease 加租约。
+func (s *AppleConsumptionService) SendDue(ctx context.Context) (*ConsumptionSendResult, error) {
+	if s == nil || s.dao == nil || s.sender == nil {
+		return nil, ErrNotConfigured
+	}
+	now := s.now()
+	rows, err := s.dao.ClaimDueConsumptionRequests(ctx, now, now.Add(job.BatchLease(s.cfg.Timeout, s.cfg.BatchSize)), s.cfg.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("apple consumption: claim due requests: %w", err)
+	}
+	out := &ConsumptionSendResult{}
+	for _, req := range rows {
+		if ctx.Err() != nil {
+			break
+		}
+		status, err := s.sendOne(ctx, req)
+		if err != nil {
+			return out, err
+		}
+		switch status {
+		case model.ConsumptionRequestSent:
+			out.Sent++
+		case model.ConsumptionRequestPending:
+			out.Retried++
+		default:
+			out.Failed++
+		}
+	}
+	return out, ctx.Err()
+}
+
+// sendOne 回复单条请求并写回结果，返回该行的新状态；只有写回本身失败时返回 error。
+func (s *AppleConsumptionService) sendOne(ctx context.Context, req model.AppleConsumptionRequest) (string, error) {
+	now := s.now()
+	if !req.DeadlineAt.IsZero() && now.After(req.DeadlineAt) {
+		return s.markFailed(ctx, req, model.ConsumptionRequestFailed, "response deadline passed", now)
+	}
+	if !s.cfg.CustomerConsented {
+		return s.markFailed(ctx, req, model.ConsumptionRequestFailed, "customer consent not obtained", now)
+	}
+
+	info, err := s.buildConsumptionInfo(ctx, req, now)
+	if err == nil {
+		err = s.sender.SendConsumptionInformation(ctx, req.TransactionID, req.Environment, info)
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return "", err
+		}
+		slog.Warn("apple consumption request failed", "request_id", req.ID, "transaction_id", req.TransactionID, "attempts", req.Attempts+1, "err", err)
+		if errors.Is(err, ErrAppleTransactionNotFound) {
+			return s.markFailed(ctx, req, model.ConsumptionRequestFailed, err.Error(), now)
+		}
+		return s.scheduleRetry(ctx, req, err.Error(), now)
+	}
+
+	payload, err := json.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("apple consumption: encode payload: %w", err)
+	}
+	if err := s.dao.MarkConsumptionRequestSent(ctx, req.ID, payload, now); err != nil {
+		return "", fmt.Errorf("apple consumption: mark sent: %w", err)
+	}
+	return model.ConsumptionRequestSent, nil
+}
+
+// scheduleRetry 按 RetryBackoff * 2^(attempts-1)（不超过 MaxRetryBackoff）安排下一次尝试；次数用尽或下一次已超过回复期限时记为 FAILED。
+func (s *AppleConsumptionService) scheduleRetry(ctx context.Context, req model.AppleConsumptionRequest, lastError string, now time.Time) (string, error) {
+	attempts := req.Attempts + 1
+	if attempts >= s.cfg.MaxAttempts {
+		return s.markFailed(ctx, req, model.ConsumptionRequestFailed, lastError, now)
+	}
+	next := now.Add(job.Backoff(s.cfg.RetryBackoff, attempts, s.cfg.MaxRetryBackoff))
+	if !req.DeadlineAt.IsZero() && next.After(req.DeadlineAt) {
+		return s.markFailed(ctx, req, model.ConsumptionRequestFailed, lastError, now)
+	}
+	return s.markFailed(ctx, req, model.ConsumptionRequestPending, lastError, next)
+}
+
+func (s *AppleConsumptionService) markFailed(ctx context.Context, req model.AppleConsumptionRequest, status, lastError string, nextAttemptAt time.Time) (string, error) {
+	if err := s.dao.MarkConsumptionRequestFailed(ctx, req.ID, status, lastError, nextAttemptAt); err != nil {
+		return "", fmt.Errorf("apple consumption: mark failed: %w", err)
+	}
+	return status, nil
+}
+
+// buildConsumptionInfo 用本地账户 / 积分 / 交付数据与 usage provider 计算回复字段。
+//
+// 无法确定用户时账户相关字段保持未声明，deliveryStatus 记为"其他原因未交付"；lifetimeDollars* 不统计，上报为未声明。
+func (s *AppleConsumptionService) buildConsumptionInfo(ctx context.Context, req model.AppleConsumptionRequest, now time.Time) (AppleConsumptionInfo, error) {
+	info := AppleConsumptionInfo{
+		AppAccountToken:   req.AppAccountToken,
+		CustomerConsented: true,
+		DeliveryStatus:    consumptionDeliveryOther,
+		Platform:          consumptionPlatformApple,
+		RefundPreference:  s.cfg.RefundPreference,
+	}
+	if req.UserID <= 0 {
+		return info, nil
+	}
+	snap, err := s.dao.GetConsumptionSnapshot(ctx, req)
+	if err != nil {
+		if errors.Is(err, dao.ErrConsumptionUserNotFound) {
+			return info, nil
+		}
+		return AppleConsumptionInfo{}, err
+	}
+	info.AccountTenure = accountTenure(now.Sub(snap.AccountCreatedAt))
+	info.UserStatus = consumptionUserStatus(snap.AccountStatus)
+	info.ConsumptionStatus = consumptionStatus(snap)
+	if snap.Delivered {
+		info.DeliveryStatus = consumptionDeliveredOK
+	}
+	if s.usage != nil {
+		usage, err := s.usage.ConsumptionUsage(ctx, req)
+		if err != nil {
+			return AppleConsumptionInfo{}, fmt.Errorf("usage provider: %w", err)
+		}
+		info.PlayTime = playTime(usage.PlayTime)
+		info.SampleContentProvided = usage.SampleContentProvided
+	}
+	return info, nil
+}
+
+// accountTenure 把账户年龄映射为 Apple accountTenure 区间（1: 0–3 天 … 7: 超过 365 天）。
+func accountTenure(age time.Duration) int {
+	const day = 24 * time.Hour
+	switch {
+	case age < 0:
+		return 0
+	case age < 3*day:
+		return 1
+	case age < 10*day:
+		return 2
+	case age < 30*day:
+		return 3
+	case age < 90*day:
+		return 4
+	case age < 180*day:
+		return 5
+	case age < 365*day:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// playTime 把使用时长映射为 Apple playTime 区间（1: 0–5 分钟 … 7: 超过 16 天）；0 表示未声明。
+func playTime(d time.Duration) int {
+	const day = 24 * time.Hour
+	switch {
+	case d <= 0:
+		return 0
+	case d < 5*time.Minute:
+		return 1
+	case d < time.Hour:
+		return 2
+	case d < 6*time.Hour:
+		return 3
+	case d < day:
+		return 4
+	case d < 4*day:
+		return 5
+	case d < 16*day:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// consumptionStatus 按该交易发放积分的剩余量判断消耗程度；没有积分发放（如订阅）时未声明。
+func consumptionStatus(snap model.ConsumptionSnapshot) int {
+	switch {
+	case snap.CreditsGranted <= 0:
+		return 0
+	case snap.CreditsRemaining >= snap.CreditsGranted:
+		return consumptionNotConsumed
+	case snap.CreditsRemaining <= 0:
+		return consumptionFullyConsumed
+	default:
+		return consumptionPartiallyConsumed
+	}
+}
+
+func consumptionUserStatus(status string) int {
+	switch status {
+	case model.UserStatusActive:
+		return consumptionUserActive
+	case model.UserStatusSuspended:
+		return consumptionUserSuspended
+	case model.UserStatusBanned, model.UserStatusDeleted:
+		return consumptionUserTerminated
+	default:
+		return 0
+	}
+}
+
+// classifyConsumptionRequest 把 CONSUMPTION_REQUEST 记为待回复请求。用户优先按 appAccountToken 解析，
+// 没有 token 时按已入账的购买 / 订阅查找；仍找不到时照常记录，回复中不含账户信息。
+func (s *AppleWebhookService) classifyConsumptionRequest(ctx context.Context, qtx dao.SubscriptionTx, event *AppleWebhookEvent) eventClassification {
+	tx := event.Transaction
+	if tx == nil || tx.TransactionID == "" {
+		return eventClassification{status: model.EventStatusPermanentFailure, errorMessage: "consumption request without transaction"}
+	}
+	env := tx.Environment
+	if env == "" {
+		env = event.Environment
+	}
+
+	var userID int64
+	if token := strings.TrimSpace(tx.AppAccountToken); token != "" {
+		mapped, err := s.tokens.ResolveUserByToken(ctx, token)
+		switch {
+		case err == nil:
+			userID = mapped.UserID
+		case !errors.Is(err, ErrAccountTokenNotFound):
+			return eventClassification{status: model.EventStatusPermanentFailure, errorMessage: err.Error()}
+		}
+	}
+	if userID == 0 {
+		if p, err := qtx.GetPurchaseByTransaction(ctx, tx.TransactionID, env); err == nil {
+			userID = p.UserID
+		} else if sub, err := qtx.GetSubscriptionByOriginalTx(ctx, tx.OriginalTransactionID, env); err == nil {
+			userID = sub.UserID
+		}
+	}
+
+	deadline := event.NotificationCreatedAt
+	if deadline.IsZero() {
+		deadline = s.now()
+	}
+	return eventClassification{
+		status: model.EventStatusProcessed,
+		userID: userID,
+		consumption: &model.AppleConsumptionRequest{
+			UserID:                userID,
+			Environment:           env,
+			OriginalTransactionID: tx.OriginalTransactionID,
+			TransactionID:         tx.TransactionID,
+			AppAccountToken:       tx.AppAccountToken,
+			DeadlineAt:            deadline.Add(appleConsumptionResponseWindow),
+		},
+	}
+}
@@ -0,0 +1,201 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+type fakeConsumptionDAO struct {
+	due       []model.AppleConsumptionRequest
+	snapshot  model.ConsumptionSnapshot
+	snapErr   error
+	leaseTill time.Time
+
+	sent   map[int64][]byte
+	failed map[int64]string
+	next   map[int64]time.Time
+}
+
+func (d *fakeConsumptionDAO) ClaimDueConsumptionRequests(_ context.Context, _, leaseUntil time.Time, _ int) ([]model.AppleConsumptionRequest, error) {
+	d.leaseTill = leaseUntil
+	return d.due, nil
+}
+
+func (d *fakeConsumptionDAO) GetConsumptionSnapshot(_ context.Context, _ model.AppleConsumptionRequest) (model.ConsumptionSnapshot, error) {
+	return d.snapshot, d.snapErr
+}
+
+func (d *fakeConsumptionDAO) MarkConsumptionRequestSent(_ context.Context, id int64, payload []byte, _ time.Time) error {
+	if d.sent == nil {
+		d.sent = map[int64][]byte{}
+	}
+	d.sent[id] = payload
+	return nil
+}
+
+func (d *fakeConsumptionDAO) MarkConsumptionRequestFailed(_ context.Context, id int64, status, _ string, nextAttemptAt time.Time) error {
+	if d.failed == nil {
+		d.failed, d.next = map[int64]string{}, map[int64]time.Time{}
+	}
+	d.failed[id] = status
+	d.next[id] = nextAttemptAt
+	return nil
+}
+
+type fakeConsumptionSender struct {
+	err   error
+	calls []AppleConsumptionInfo
+}
+
+func (f *fakeConsumptionSender) SendConsumptionInformation(_ context.Context, _ string, _ Environment, info AppleConsumptionInfo) error {
+	f.calls = append(f.calls, info)
+	return f.err
+}
+
+type fixedUsage time.Duration
+
+func (u fixedUsage) ConsumptionUsage(_ context.Context, _ model.AppleConsumptionRequest) (ConsumptionUsage, error) {
+	return ConsumptionUsage{PlayTime: time.Duration(u)}, nil
+}
+
+func TestAppleWebhookService_RecordsConsumptionRequest(t *testing.T) {
+	created := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	tok := "00000000-0000-4000-8000-000000000093"
+	tx := &AppleTransaction{
+		TransactionID: "tx-coins", OriginalTransactionID: "tx-coins",
+		AppAccountToken: tok, BundleID: "com.app.example",
+		Environment: EnvProduction, ProductID: "com.app.coins.100", Type: "Consumable",
+		PurchaseDate: created.Add(-time.Hour),
+	}
+	event := makeEvent("CONSUMPTION_REQUEST", "", tx)
+	event.NotificationCreatedAt = created
+	d := &fakeIAPDAO{}
+	svc := NewAppleWebhookService(newProdCatalog(t), &fakeWebhookVerifier{event: event}, newTokensWithFakeDAO(93, tok), d)
+	if err := svc.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("consumption request: %v", err)
+	}
+	if len(d.events) != 1 || d.events[0].ProcessingStatus != model.EventStatusProcessed {
+		t.Fatalf("event must be recorded as processed: %+v", d.events)
+	}
+	if len(d.consumptions) != 1 || len(d.upserts) != 0 {
+		t.Fatalf("expected one consumption request and no upsert, got %d / %d", len(d.consumptions), len(d.upserts))
+	}
+	req := d.consumptions[0]
+	if req.EventID != 1 || req.UserID != 93 || req.TransactionID != "tx-coins" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+	if !req.DeadlineAt.Equal(created.Add(12 * time.Hour)) {
+		t.Fatalf("deadline = %s, want 12h after notification", req.DeadlineAt)
+	}
+}
+
+func TestAppleConsumptionService_SendDue(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	base := model.AppleConsumptionRequest{
+		ID: 7, UserID: 93, Environment: EnvProduction, TransactionID: "tx-coins",
+		AppAccountToken: "00000000-0000-4000-8000-000000000093",
+		Status:          model.ConsumptionRequestPending, DeadlineAt: now.Add(10 * time.Hour),
+	}
+	snapshot := model.ConsumptionSnapshot{
+		AccountCreatedAt: now.Add(-40 * 24 * time.Hour), AccountStatus: model.UserStatusActive,
+		CreditsGranted: 100, CreditsRemaining: 30, Delivered: true,
+	}
+	newSvc := func(d *fakeConsumptionDAO, sender *fakeConsumptionSender, consented bool) *AppleConsumptionService {
+		svc := NewAppleConsumptionService(d, sender, AppleConsumptionConfig{
+			MaxAttempts: 3, RetryBackoff: time.Minute, CustomerConsented: consented,
+		}).WithUsageProvider(fixedUsage(2 * time.Hour))
+		svc.now = func() time.Time { return now }
+		return svc
+	}
+
+	t.Run("sends computed fields", func(t *testing.T) {
+		d := &fakeConsumptionDAO{due: []model.AppleConsumptionRequest{base}, snapshot: snapshot}
+		sender := &fakeConsumptionSender{}
+		res, err := newSvc(d, sender, true).SendDue(context.Background())
+		if err != nil || res.Sent != 1 {
+			t.Fatalf("res=%+v err=%v", res, err)
+		}
+		want := AppleConsumptionInfo{
+			AccountTenure: 4, AppAccountToken: base.AppAccountToken, ConsumptionStatus: consumptionPartiallyConsumed,
+			CustomerConsented: true, DeliveryStatus: consumptionDeliveredOK, Platform: consumptionPlatformApple,
+			PlayTime: 3, UserStatus: consumptionUserActive,
+		}
+		if len(sender.calls) != 1 || sender.calls[0] != want {
+			t.Fatalf("sent %+v, want %+v", sender.calls, want)
+		}
+		if !d.leaseTill.After(now.Add(100 * 10 * time.Second)) {
+			t.Fatalf("lease %v does not cover the batch", d.leaseTill)
+		}
+		var stored AppleConsumptionInfo
+		if err := json.Unmarshal(d.sent[7], &stored); err != nil || stored != want {
+			t.Fatalf("stored payload %s: %v", d.sent[7], err)
+		}
+	})
+
+	t.Run("failure backs off", func(t *testing.T) {
+		req := base
+		req.Attempts = 1
+		d := &fakeConsumptionDAO{due: []model.AppleConsumptionRequest{req}, snapshot: snapshot}
+		res, err := newSvc(d, &fakeConsumptionSender{err: errors.New("http.stauts_code = 500")}, true).SendDue(context.Background())
+		if err != nil || res.Retried != 1 {
+			t.Fatalf("res=%+v err=%v", res, err)
+		}
+		if d.failed[7] != model.ConsumptionRequestPending || !d.next[7].Equal(now.Add(2*time.Minute)) {
+			t.Fatalf("status=%s next=%s, want PENDING at +2m", d.failed[7], d.next[7])
+		}
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		req := base
+		req.Attempts = 2
+		d := &fakeConsumptionDAO{due: []model.AppleConsumptionRequest{req}, snapshot: snapshot}
+		res, _ := newSvc(d, &fakeConsumptionSender{err: errors.New("timeout")}, true).SendDue(context.Background())
+		if res.Failed != 1 || d.failed[7] != model.ConsumptionRequestFailed {
+			t.Fatalf("res=%+v status=%s", res, d.failed[7])
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		deadline  time.Time
+		consented bool
+	}{
+		"deadline passed": {deadline: now.Add(-time.Minute), consented: true},
+		"no consent":      {deadline: now.Add(time.Hour)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := base
+			req.DeadlineAt = tc.deadline
+			d := &fakeConsumptionDAO{due: []model.AppleConsumptionRequest{req}, snapshot: snapshot}
+			sender := &fakeConsumptionSender{}
+			if _, err := newSvc(d, sender, tc.consented).SendDue(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if len(sender.calls) != 0 || d.failed[7] != model.ConsumptionRequestFailed {
+				t.Fatalf("calls=%d status=%s, want no call and FAILED", len(sender.calls), d.failed[7])
+			}
+		})
+	}
+}
+
+func TestConsumptionStatus(t *testing.T) {
+	cases := []struct {
+		granted, remaining int64
+		want               int
+	}{
+		{0, 0, 0},
+		{100, 100, consumptionNotConsumed},
+		{100, 40, consumptionPartiallyConsumed},
+		{100, 0, consumptionFullyConsumed},
+	}
+	for _, tc := range cases {
+		got := consumptionStatus(model.ConsumptionSnapshot{CreditsGranted: tc.granted, CreditsRemaining: tc.remaining})
+		if got != tc.want {
+			t.Fatalf("granted=%d remaining=%d: got %d, want %d", tc.granted, tc.remaining, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// pendingRedriveBatchSize 是单次重放最多处理的 PENDING_USER_BINDING 事件数。
+const pendingRedriveBatchSize = 100
+
+// RedriveResult 是一次 PENDING_USER_BINDING 重放的统计。Redriven 为离开 PENDING_USER_BINDING 的事件数
+// （包括重放后判定为 PERMANENT_FAILURE 等终态的），StillPending 为仍无法绑定的事件数。
+type RedriveResult struct {
+	Redriven     int
+	StillPending int
+	Failed       int
+}
+
+// RedrivePendingEvents 重新处理已能绑定到 user 的 PENDING_USER_BINDING 事件。
+//
+// token 非空时只处理该 appAccountToken 的事件，否则处理所有 token 已有映射的事件。事件用入库时保留的
+// 最小重放字段或该通知的加密归档重新走 classifyEvent，不再请求 Apple；事件行被锁住后才处理，并发重放不会重复入账。
+func (s *AppleWebhookService) RedrivePendingEvents(ctx context.Context, token string) (*RedriveResult, error) {
+	if s == nil || s.catalog == nil || s.tokens == nil || s.dao == nil {
+		return nil, ErrNotConfigured
+	}
+	var events []model.AppleEvent
+	if err := s.dao.InTx(ctx, func(qtx dao.SubscriptionTx) error {
+		var err error
+		events, err = qtx.ListRedrivableEvents(ctx, token, pendingRedriveBatchSize)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("apple iap redrive: list pending events: %w", err)
+	}
+
+	out := &RedriveResult{}
+	for _, ev := range events {
+		status, err := s.redriveEvent(ctx, ev.ID)
+		switch {
+		case err != nil:
+			out.Failed++
+			slog.Warn("apple pending event redrive failed", "event_id", ev.ID, "notification_uuid", ev.NotificationUUID, "err", err)
+		case status == model.EventStatusPendingUserBinding:
+			out.StillPending++
+		case status != "":
+			out.Redriven++
+		}
+	}
+	return out, nil
+}
+
+// RedrivePendingForToken 是 TokenService 的绑定回调：token 建立映射或 verify 成功后立即重放该 token
+// 下搁置的事件。失败只记录日志，不影响调用方的请求。
+func (s *AppleWebhookService) RedrivePendingForToken(ctx context.Context, token string) {
+	if strings.TrimSpace(token) == "" {
+		return
+	}
+	res, err := s.RedrivePendingEvents(ctx, token)
+	if err != nil {
+		slog.Warn("apple pending event redrive failed", "err", err)
+		return
+	}
+	if res.Redriven > 0 || res.Failed > 0 {
+		slog.Info("apple pending events redriven", "redriven", res.Redriven, "failed", res.Failed)
+	}
+}
+
+// RunPendingRedrive 重放一批 PENDING_USER_BINDING 事件，作为后台任务的周期回调，兜底绑定回调没有覆盖到的情况。
+func (s *AppleWebhookService) RunPendingRedrive(ctx context.Context) error {
+	res, err := s.RedrivePendingEvents(ctx, "")
+	if err != nil {
+		return err
+	}
+	if res.Redriven > 0 || res.Failed > 0 {
+		slog.Info("apple pending event sweep done", "redriven", res.Redriven, "still_pending", res.StillPending, "failed", res.Failed)
+	}
+	return nil
+}
+
+// redriveEvent 在事务内锁住事件并重新分类，返回新的 processing_status；事件已被处理时返回空字符串。
+func (s *AppleWebhookService) redriveEvent(ctx context.Context, eventID int64) (string, error) {
+	var status string
+	err := s.dao.InTx(ctx, func(qtx dao.SubscriptionTx) error {
+		row, err := qtx.LockPendingEvent(ctx, eventID)
+		if err != nil {
+			if errors.Is(err, dao.ErrEventNotPending) {
+				return nil
+			}
+			return err
+		}
+		var event *AppleWebhookEvent
+		if len(row.DecodedPayload) == 0 {
+			// 加密归档无法打开（如 master key 已移除）时事件保持 PENDING_USER_BINDING，记为失败。
+			if event, err = s.archive.openNotification(ctx, qtx, row); err != nil {
+				return err
+			}
+		} else if event, err = decodeRetainedEvent(row.DecodedPayload); err != nil {
+			status = model.EventStatusPermanentFailure
+			return qtx.UpdateEventProcessing(ctx, row.ID, status, 0, "decode retained payload: "+err.Error())
+		}
+
+		classification := s.classifyEvent(ctx, qtx, event)
+		status = classification.status
+		if status == model.EventStatusPendingUserBinding {
+			return nil
+		}
+		if err := qtx.UpdateEventProcessing(ctx, row.ID, status, classification.userID, classification.errorMessage); err != nil {
+			return err
+		}
+		return s.applyClassification(ctx, qtx, row.ID, event, classification)
+	})
+	if err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+// retainedEvent 是 PENDING_USER_BINDING 事件写入 apple_events.decoded_payload 的最小字段集：只保留
+// classifyEvent 重新分类所需的标识、类型、时间与已映射的 transaction / renewal info，不含 bundle id
+// 与原始通知内容。字段名与旧版本保留的 AppleWebhookEvent JSON 一致，旧行仍可重放。
+// 开启加密归档时不写该列；事件进入终态时 UpdateEventProcessing 清空该列。
+type retainedEvent struct {
+	NotificationUUID      string
+	NotificationType      string
+	Subtype               string
+	Environment           Environment
+	NotificationCreatedAt time.Time
+	SignedPayloadSHA256   string
+	Transaction           *AppleTransaction
+	RenewalInfo           *AppleRenewalInfo
+}
+
+// encodeRetainedEvent 把解码后的通知投影为 retainedEvent 并序列化，供之后重放。
+func encodeRetainedEvent(event *AppleWebhookEvent) ([]byte, error) {
+	retained := retainedEvent{
+		NotificationUUID:      event.NotificationUUID,
+		NotificationType:      event.NotificationType,
+		Subtype:               event.Subtype,
+		Environment:           event.Environment,
+		NotificationCreatedAt: event.NotificationCreatedAt,
+		SignedPayloadSHA256:   event.SignedPayloadSHA256,
+		RenewalInfo:           event.RenewalInfo,
+	}
+	if event.Transaction != nil {
+		tx := *event.Transaction
+		tx.BundleID = ""
+		retained.Transaction = &tx
+	}
+	return json.Marshal(retained)
+}
+
+func decodeRetainedEvent(payload []byte) (*AppleWebhookEvent, error) {
+	var retained retainedEvent
+	if err := json.Unmarshal(payload, &retained); err != nil {
+		return nil, err
+	}
+	return &AppleWebhookEvent{
+		NotificationUUID:      retained.NotificationUUID,
+		NotificationType:      retained.NotificationType,
+		Subtype:               retained.Subtype,
+		Environment:           retained.Environment,
+		NotificationCreatedAt: retained.NotificationCreatedAt,
+		SignedPayloadSHA256:   retained.SignedPayloadSHA256,
+		Transaction:           retained.Transaction,
+		RenewalInfo:           retained.RenewalInfo,
+	}, nil
+}
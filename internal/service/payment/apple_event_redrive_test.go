@@ -0,0 +1,162 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	gopayApple "github.com/go-pay/gopay/apple"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+const redriveToken = "00000000-0000-4000-8000-000000000777"
+
+func parkPendingEvent(t *testing.T, svc *AppleWebhookService, d *fakeIAPDAO, verifier *fakeWebhookVerifier) {
+	t.Helper()
+	now := time.Now().UTC()
+	verifier.event = makeEvent("SUBSCRIBED", "", &AppleTransaction{
+		TransactionID: "tx-redrive", OriginalTransactionID: "ot-redrive",
+		AppAccountToken: redriveToken, BundleID: "com.app.example",
+		Environment: EnvProduction, ProductID: "com.app.pro.monthly",
+		Type:         "Auto-Renewable Subscription",
+		PurchaseDate: now, ExpiresDate: now.Add(30 * 24 * time.Hour),
+	})
+	if err := svc.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("webhook: %v", err)
+	}
+	if len(d.events) != 1 || d.events[0].ProcessingStatus != model.EventStatusPendingUserBinding || len(d.events[0].DecodedPayload) == 0 {
+		t.Fatalf("pending event must retain decoded payload: %+v", d.events)
+	}
+	if retained := string(d.events[0].DecodedPayload); strings.Contains(retained, "com.app.example") || strings.Contains(retained, "DecodedPayload") {
+		t.Fatalf("retained payload must only keep re-drive fields: %s", retained)
+	}
+	d.pending = append(d.pending, model.AppleEvent{
+		ID:               7,
+		AppAccountToken:  redriveToken,
+		ProcessingStatus: model.EventStatusPendingUserBinding,
+		DecodedPayload:   d.events[0].DecodedPayload,
+	})
+}
+
+func TestAppleWebhookService_RedriveOnTokenBinding(t *testing.T) {
+	tokenDAO := newFakeTokenDAO()
+	tokens := NewTokenService(tokenDAO)
+	verifier := &fakeWebhookVerifier{}
+	d := &fakeIAPDAO{}
+	svc := NewAppleWebhookService(newProdCatalog(t), verifier, tokens, d)
+	redriven := make(chan struct{})
+	tokens.WithBindingHook(func(ctx context.Context, token string) {
+		svc.RedrivePendingForToken(ctx, token)
+		close(redriven)
+	})
+	parkPendingEvent(t, svc, d, verifier)
+
+	tokenDAO.nextToken = redriveToken
+	if _, err := tokens.EnsureAccountToken(context.Background(), 55); err != nil {
+		t.Fatalf("ensure token: %v", err)
+	}
+	select {
+	case <-redriven:
+	case <-time.After(time.Second):
+		t.Fatal("binding hook not called")
+	}
+	if d.eventUpdates[7] != model.EventStatusProcessed || len(d.pending[0].DecodedPayload) != 0 {
+		t.Fatalf("event after binding = %q %+v, want PROCESSED without retained payload", d.eventUpdates[7], d.pending[0])
+	}
+	if len(d.upserts) != 1 || d.upserts[0].UserID != 55 || d.upserts[0].OriginalTransactionID != "ot-redrive" {
+		t.Fatalf("unexpected upserts: %+v", d.upserts)
+	}
+
+	res, err := svc.RedrivePendingEvents(context.Background(), "")
+	if err != nil {
+		t.Fatalf("second redrive: %v", err)
+	}
+	if res.Redriven != 0 || len(d.upserts) != 1 {
+		t.Fatalf("processed event must not be redriven twice: %+v upserts=%d", res, len(d.upserts))
+	}
+}
+
+func TestAppleWebhookService_RedriveKeepsUnboundEventsPending(t *testing.T) {
+	verifier := &fakeWebhookVerifier{}
+	d := &fakeIAPDAO{}
+	svc := NewAppleWebhookService(newProdCatalog(t), verifier, NewTokenService(newFakeTokenDAO()), d)
+	parkPendingEvent(t, svc, d, verifier)
+
+	res, err := svc.RedrivePendingEvents(context.Background(), "")
+	if err != nil {
+		t.Fatalf("redrive: %v", err)
+	}
+	if res.StillPending != 1 || res.Redriven != 0 || len(d.eventUpdates) != 0 || len(d.upserts) != 0 {
+		t.Fatalf("unbound event must stay pending: %+v updates=%v", res, d.eventUpdates)
+	}
+}
+
+func TestAppleWebhookService_RedriveCorruptPayloadFailsPermanently(t *testing.T) {
+	d := &fakeIAPDAO{pending: []model.AppleEvent{{ID: 9, AppAccountToken: redriveToken, DecodedPayload: []byte("{")}}}
+	svc := NewAppleWebhookService(newProdCatalog(t), &fakeWebhookVerifier{}, newTokensWithFakeDAO(55, redriveToken), d)
+
+	res, err := svc.RedrivePendingEvents(context.Background(), redriveToken)
+	if err != nil {
+		t.Fatalf("redrive: %v", err)
+	}
+	if d.eventUpdates[9] != model.EventStatusPermanentFailure || res.Redriven != 1 {
+		t.Fatalf("corrupt payload: result=%+v updates=%v", res, d.eventUpdates)
+	}
+}
+
+func TestAppleWebhookService_RedriveFromEncryptedArchive(t *testing.T) {
+	now := time.Now().UTC()
+	notification := decodedNotification{
+		Notification: &gopayApple.NotificationV2Payload{
+			NotificationType: "SUBSCRIBED",
+			NotificationUUID: "notif-archived",
+			Data:             &gopayApple.Data{BundleID: "com.app.example", Environment: "Production"},
+		},
+		TransactionInfo: &gopayApple.TransactionInfo{
+			TransactionId: "tx-archived", OriginalTransactionId: "ot-archived",
+			AppAccountToken: redriveToken, BundleId: "com.app.example",
+			Environment: "Production", ProductId: "com.app.pro.monthly",
+			Type:         "Auto-Renewable Subscription",
+			PurchaseDate: now.UnixMilli(), ExpiresDate: now.Add(30 * 24 * time.Hour).UnixMilli(),
+		},
+	}
+	raw, err := json.Marshal(notification)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	event := notification.webhookEvent()
+	event.SignedPayloadSHA256 = "deadbeef"
+	event.DecodedPayload = raw
+
+	tokenDAO := newFakeTokenDAO()
+	d := &fakeIAPDAO{}
+	archive := NewPayloadArchive(mustKeyring(t, "k1:"+archiveKey(1), ""), &fakeArchiveDAO{}, PayloadArchiveConfig{Retention: time.Hour})
+	svc := NewAppleWebhookService(newProdCatalog(t), &fakeWebhookVerifier{event: event}, NewTokenService(tokenDAO), d).WithPayloadArchive(archive)
+	if err := svc.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("webhook: %v", err)
+	}
+	if len(d.events) != 1 || d.events[0].ProcessingStatus != model.EventStatusPendingUserBinding || len(d.events[0].DecodedPayload) != 0 || len(d.archives) != 1 {
+		t.Fatalf("pending event must keep its re-drive copy only in the encrypted archive: %+v archives=%d", d.events, len(d.archives))
+	}
+	d.pending = append(d.pending, model.AppleEvent{
+		ID:               d.archives[0].EventID,
+		AppAccountToken:  redriveToken,
+		ProcessingStatus: model.EventStatusPendingUserBinding,
+		RawJWSSHA256:     "deadbeef",
+	})
+
+	tokenDAO.tokens[55] = redriveToken
+	res, err := svc.RedrivePendingEvents(context.Background(), redriveToken)
+	if err != nil {
+		t.Fatalf("redrive: %v", err)
+	}
+	if res.Redriven != 1 || d.eventUpdates[d.archives[0].EventID] != model.EventStatusProcessed {
+		t.Fatalf("redrive result = %+v updates=%v", res, d.eventUpdates)
+	}
+	if len(d.upserts) != 1 || d.upserts[0].UserID != 55 || d.upserts[0].OriginalTransactionID != "ot-archived" || d.upserts[0].LastPayloadHash != "deadbeef" {
+		t.Fatalf("unexpected upserts: %+v", d.upserts)
+	}
+}
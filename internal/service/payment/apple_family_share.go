@@ -0,0 +1,160 @@
+package payment
+
+import (
+	"context"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// FamilyShareProof 证明提交者确实持有该家庭共享订阅：设备上 StoreKit 返回的 signed transaction
+// （Transaction.jwsRepresentation）与本机 identifierForVendor。
+type FamilyShareProof struct {
+	SignedTransaction string
+	DeviceID          string
+}
+
+// verify 校验 signed transaction 是苹果签发的同一笔交易，且其 deviceVerification 由 DeviceID 生成：
+// base64(SHA-384(deviceVerificationNonce + identifierForVendor))，两者均取小写 UUID 字符串。
+// 只拿到 transactionId（如截图、客服工单泄露）的人无法伪造。
+func (p FamilyShareProof) verify(transactionID, bundleID string, decode func(string, string) (deviceSignedTransaction, error)) error {
+	if strings.TrimSpace(p.SignedTransaction) == "" || strings.TrimSpace(p.DeviceID) == "" {
+		return ErrFamilyShareProofRequired
+	}
+	signed, err := decode(p.SignedTransaction, bundleID)
+	if err != nil {
+		return err
+	}
+	if signed.TransactionID != strings.TrimSpace(transactionID) || signed.DeviceVerification == "" || signed.DeviceVerificationNonce == "" {
+		return ErrFamilyShareProofMismatch
+	}
+	sum := sha512.Sum384([]byte(strings.ToLower(signed.DeviceVerificationNonce) + strings.ToLower(strings.TrimSpace(p.DeviceID))))
+	want := base64.StdEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(want), []byte(signed.DeviceVerification)) != 1 {
+		return ErrFamilyShareProofMismatch
+	}
+	return nil
+}
+
+// verifyFamilyShare 让家庭成员认领通过 Family Sharing 获得的订阅。
+//
+// 家庭成员的 transaction 有自己的 original_transaction_id 但不带 appAccountToken，无法像购买者那样
+// 通过 token 反查 user；提交者必须附带 FamilyShareProof 证明交易来自自己的设备，否则任何拿到
+// transactionId 的登录用户都能抢先认领。认领后其他用户提交返回 409。
+// 权益写入 apple_family_shares，与购买者的 apple_subscriptions 分开。
+func (s *AppleIAPService) verifyFamilyShare(ctx context.Context, userID int64, transactionID string, tx *AppleTransaction, proof FamilyShareProof) (*VerifyResult, error) {
+	if tx.IsRevoked() {
+		return nil, ErrTransactionRevoked
+	}
+	if err := proof.verify(transactionID, s.catalog.BundleID(), s.decodeDeviceSigned); err != nil {
+		return nil, err
+	}
+	if tx.OriginalTransactionID == "" || tx.Environment == "" {
+		return nil, errors.New("apple iap: missing original_transaction_id or environment in transaction")
+	}
+	product, err := s.catalog.Lookup(tx.ProductID, tx.Environment)
+	if err != nil {
+		return nil, err
+	}
+	if product.Type != model.ProductTypeSubscription {
+		return nil, ErrUnsupportedProductType
+	}
+
+	now := s.now()
+	var share model.AppleFamilyShare
+	if err := s.dao.InTx(ctx, func(qtx dao.SubscriptionTx) error {
+		row, e := qtx.UpsertFamilyShareWithOwnershipCheck(ctx, model.AppleFamilyShareUpsert{
+			UserID:                userID,
+			Environment:           tx.Environment,
+			OriginalTransactionID: tx.OriginalTransactionID,
+			LastTransactionID:     tx.TransactionID,
+			PlanID:                product.PlanID,
+			ProviderProductID:     product.ProductID,
+			Level:                 product.Level,
+			Status:                model.SubscriptionStatusActive,
+			CurrentPeriodEnd:      tx.ExpiresDate,
+			LastEventAt:           now,
+		})
+		if e != nil {
+			return e
+		}
+		share = row
+		return s.archive.archiveTransaction(ctx, qtx, userID, tx)
+	}); err != nil {
+		return nil, err
+	}
+
+	info := subscriptionInfoFromRow(familyShareView(share), now)
+	return &VerifyResult{Subscription: &info}, nil
+}
+
+// familyShareView 把家庭共享权益投影成 model.Subscription，复用订阅的 API status 映射规则。
+func familyShareView(share model.AppleFamilyShare) model.Subscription {
+	return model.Subscription{
+		ID:                    share.ID,
+		UserID:                share.UserID,
+		Environment:           share.Environment,
+		OriginalTransactionID: share.OriginalTransactionID,
+		LastTransactionID:     share.LastTransactionID,
+		PlanID:                share.PlanID,
+		ProviderProductID:     share.ProviderProductID,
+		Level:                 share.Level,
+		Status:                share.Status,
+		CurrentPeriodEnd:      share.CurrentPeriodEnd,
+		LastEventAt:           share.LastEventAt,
+	}
+}
+
+// classifyFamilyShareEvent 处理家庭成员 transaction 的通知。
+//
+// 家庭成员尚未通过 verify 认领时记为 PENDING_USER_BINDING；认领时 verify 会直接拉取最新 transaction，
+// 不依赖这些事件。已认领的按购买者同样的 reducer 规则更新状态，REVOKE（购买者停止共享或成员离开家庭）
+// 把权益标记为 REVOKED。
+func (s *AppleWebhookService) classifyFamilyShareEvent(ctx context.Context, qtx dao.SubscriptionTx, event *AppleWebhookEvent) eventClassification {
+	tx := event.Transaction
+	existing, err := qtx.GetFamilyShare(ctx, tx.OriginalTransactionID, tx.Environment)
+	if err != nil {
+		if errors.Is(err, dao.ErrFamilyShareNotFound) {
+			return eventClassification{status: model.EventStatusPendingUserBinding, errorMessage: "family share not claimed"}
+		}
+		return eventClassification{status: model.EventStatusPermanentFailure, errorMessage: err.Error()}
+	}
+	product, err := s.catalog.Lookup(tx.ProductID, tx.Environment)
+	if err != nil {
+		if errors.Is(err, ErrUnknownProduct) {
+			return eventClassification{status: model.EventStatusIgnoredUnknownType, userID: existing.UserID, errorMessage: "product not in catalog"}
+		}
+		return eventClassification{status: model.EventStatusPermanentFailure, userID: existing.UserID, errorMessage: err.Error()}
+	}
+	if !isKnownNotificationType(event.NotificationType) {
+		return eventClassification{status: model.EventStatusIgnoredUnknownType, userID: existing.UserID}
+	}
+
+	up := buildWebhookUpsert(existing.UserID, event, product, nil, s.now())
+	share := model.AppleFamilyShareUpsert{
+		UserID:                existing.UserID,
+		Environment:           tx.Environment,
+		OriginalTransactionID: tx.OriginalTransactionID,
+		LastTransactionID:     tx.TransactionID,
+		PlanID:                product.PlanID,
+		ProviderProductID:     product.ProductID,
+		Level:                 product.Level,
+		Status:                up.Status,
+		CurrentPeriodEnd:      up.CurrentPeriodEnd,
+		LastEventAt:           up.LastEventAt,
+	}
+	if share.Status == model.SubscriptionStatusRevoked {
+		// 停止共享时 Apple 不一定带 revocationDate，此时以通知时间为准。
+		revokedAt := up.LastEventAt
+		if !tx.IsRevoked() && !event.NotificationCreatedAt.IsZero() {
+			revokedAt = event.NotificationCreatedAt.UTC()
+		}
+		share.RevokedAt = &revokedAt
+	}
+	return eventClassification{status: model.EventStatusProcessed, userID: existing.UserID, familyShare: &share}
+}
@@ -0,0 +1,187 @@
+package payment
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func familySharedTx(now time.Time) *AppleTransaction {
+	return &AppleTransaction{
+		TransactionID:         "tx-family",
+		OriginalTransactionID: "ot-family",
+		BundleID:              "com.app.test",
+		Environment:           EnvProduction,
+		ProductID:             "com.app.pro.monthly",
+		Type:                  "Auto-Renewable Subscription",
+		InAppOwnershipType:    "FAMILY_SHARED",
+		PurchaseDate:          now,
+		ExpiresDate:           now.Add(30 * 24 * time.Hour),
+	}
+}
+
+const familyDeviceID = "9B1DEB4D-3B7D-4BAD-9BDD-2B0D7B3DCB6D"
+
+// withFamilyDevice 让 svc 把 signed transaction "jws-<deviceID>" 解码为由该设备生成的 deviceVerification。
+func withFamilyDevice(svc *AppleIAPService) *AppleIAPService {
+	svc.decodeDeviceSigned = func(signed, _ string) (deviceSignedTransaction, error) {
+		const nonce = "3f8a1c2e-5b7d-4e9f-a1b3-c5d7e9f1a3b5"
+		sum := sha512.Sum384([]byte(nonce + "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d"))
+		if signed != "jws-"+familyDeviceID {
+			return deviceSignedTransaction{}, ErrInvalidSignedTransaction
+		}
+		return deviceSignedTransaction{
+			TransactionID:           "tx-family",
+			DeviceVerification:      base64.StdEncoding.EncodeToString(sum[:]),
+			DeviceVerificationNonce: nonce,
+		}, nil
+	}
+	return svc
+}
+
+var familyProof = FamilyShareProof{SignedTransaction: "jws-" + familyDeviceID, DeviceID: familyDeviceID}
+
+func TestAppleIAPService_VerifyFamilyShareClaims(t *testing.T) {
+	now := time.Now().UTC()
+	d := &fakeIAPDAO{}
+	svc := withFamilyDevice(NewAppleIAPService(newProdCatalog(t), &fakeVerifier{tx: familySharedTx(now)}, NewTokenService(newFakeTokenDAO()), d))
+
+	res, err := svc.VerifyTransactionWithProof(context.Background(), 7, "tx-family", familyProof)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if res.Subscription == nil || res.Subscription.Status != "ACTIVE" || res.Subscription.ProductID != "pro_monthly" {
+		t.Fatalf("unexpected result: %+v", res.Subscription)
+	}
+	if len(d.upserts) != 0 {
+		t.Fatalf("family share must not touch apple_subscriptions, got %d upserts", len(d.upserts))
+	}
+	share := d.familyShares["ot-family"]
+	if share.UserID != 7 || share.Status != model.SubscriptionStatusActive {
+		t.Fatalf("unexpected family share: %+v", share)
+	}
+
+	// 同一用户重复提交是幂等的；其他用户提交同一 transaction 返回冲突。
+	if _, err := svc.VerifyTransactionWithProof(context.Background(), 7, "tx-family", familyProof); err != nil {
+		t.Fatalf("re-verify: %v", err)
+	}
+	if _, err := svc.VerifyTransactionWithProof(context.Background(), 8, "tx-family", familyProof); !errors.Is(err, ErrFamilyShareOwnershipConflict) {
+		t.Fatalf("other user err = %v, want ErrFamilyShareOwnershipConflict", err)
+	}
+}
+
+func TestAppleIAPService_VerifyFamilyShareRequiresDeviceProof(t *testing.T) {
+	now := time.Now().UTC()
+	d := &fakeIAPDAO{}
+	svc := withFamilyDevice(NewAppleIAPService(newProdCatalog(t), &fakeVerifier{tx: familySharedTx(now)}, NewTokenService(newFakeTokenDAO()), d))
+	ctx := context.Background()
+
+	// 只知道 transactionId 的其他用户不能抢先认领。
+	if _, err := svc.VerifyTransaction(ctx, 8, "tx-family"); !errors.Is(err, ErrFamilyShareProofRequired) {
+		t.Fatalf("no proof err = %v, want ErrFamilyShareProofRequired", err)
+	}
+	otherDevice := FamilyShareProof{SignedTransaction: familyProof.SignedTransaction, DeviceID: "11111111-2222-3333-4444-555555555555"}
+	if _, err := svc.VerifyTransactionWithProof(ctx, 8, "tx-family", otherDevice); !errors.Is(err, ErrFamilyShareProofMismatch) {
+		t.Fatalf("other device err = %v, want ErrFamilyShareProofMismatch", err)
+	}
+	forged := FamilyShareProof{SignedTransaction: "forged", DeviceID: familyDeviceID}
+	if _, err := svc.VerifyTransactionWithProof(ctx, 8, "tx-family", forged); !errors.Is(err, ErrInvalidSignedTransaction) {
+		t.Fatalf("forged jws err = %v, want ErrInvalidSignedTransaction", err)
+	}
+	if len(d.familyShares) != 0 {
+		t.Fatalf("unproven claims must not write shares: %+v", d.familyShares)
+	}
+
+	// 真正的家庭成员仍可认领；之后冒用者即使换设备也无法接管。
+	if _, err := svc.VerifyTransactionWithProof(ctx, 7, "tx-family", familyProof); err != nil {
+		t.Fatalf("member claim: %v", err)
+	}
+	if _, err := svc.VerifyTransactionWithProof(ctx, 8, "tx-family", otherDevice); !errors.Is(err, ErrFamilyShareProofMismatch) {
+		t.Fatalf("takeover err = %v, want ErrFamilyShareProofMismatch", err)
+	}
+	if share := d.familyShares["ot-family"]; share.UserID != 7 {
+		t.Fatalf("family share owner = %d, want 7", share.UserID)
+	}
+}
+
+func TestAppleIAPService_VerifyFamilyShareRevoked(t *testing.T) {
+	now := time.Now().UTC()
+	tx := familySharedTx(now)
+	tx.RevocationDate = timePtr(now.Add(-time.Hour))
+	d := &fakeIAPDAO{}
+	svc := withFamilyDevice(NewAppleIAPService(newProdCatalog(t), &fakeVerifier{tx: tx}, NewTokenService(newFakeTokenDAO()), d))
+	if _, err := svc.VerifyTransactionWithProof(context.Background(), 7, "tx-family", familyProof); !errors.Is(err, ErrTransactionRevoked) {
+		t.Fatalf("err = %v, want ErrTransactionRevoked", err)
+	}
+	if len(d.familyShares) != 0 {
+		t.Fatalf("revoked transaction must not be claimed: %+v", d.familyShares)
+	}
+}
+
+func TestAppleWebhookService_FamilyShareUnclaimedIsPending(t *testing.T) {
+	now := time.Now().UTC()
+	d := &fakeIAPDAO{}
+	verifier := &fakeWebhookVerifier{event: makeEvent("DID_RENEW", "", familySharedTx(now))}
+	svc := NewAppleWebhookService(newProdCatalog(t), verifier, NewTokenService(newFakeTokenDAO()), d)
+	if err := svc.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("unclaimed family share should ack, got %v", err)
+	}
+	if len(d.familyShares) != 0 || len(d.upserts) != 0 {
+		t.Fatalf("unclaimed family share must not write rows: shares=%+v upserts=%d", d.familyShares, len(d.upserts))
+	}
+}
+
+func TestAppleWebhookService_FamilyShareRevoke(t *testing.T) {
+	now := time.Now().UTC()
+	d := &fakeIAPDAO{familyShares: map[string]model.AppleFamilyShare{
+		"ot-family": {
+			ID: 1, UserID: 7, Environment: model.AppleEnvProduction, OriginalTransactionID: "ot-family",
+			PlanID: "pro_monthly", Level: 1, Status: model.SubscriptionStatusActive, CurrentPeriodEnd: now.Add(24 * time.Hour),
+		},
+	}}
+	event := makeEvent("REVOKE", "", familySharedTx(now))
+	event.NotificationCreatedAt = now.Truncate(time.Second)
+	svc := NewAppleWebhookService(newProdCatalog(t), &fakeWebhookVerifier{event: event}, NewTokenService(newFakeTokenDAO()), d)
+	if err := svc.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	share := d.familyShares["ot-family"]
+	if share.UserID != 7 || share.Status != model.SubscriptionStatusRevoked {
+		t.Fatalf("family share should be revoked for the claimer: %+v", share)
+	}
+	if share.RevokedAt == nil || !share.RevokedAt.Equal(event.NotificationCreatedAt) {
+		t.Fatalf("revoked_at = %v, want notification time %v", share.RevokedAt, event.NotificationCreatedAt)
+	}
+	if len(d.upserts) != 0 {
+		t.Fatalf("family share revoke must not touch purchaser subscription, got %d upserts", len(d.upserts))
+	}
+}
+
+func TestAppleEntitlementSource_IncludesFamilyShares(t *testing.T) {
+	c := newProdCatalog(t)
+	now := time.Now().UTC()
+	d := &readerDAO{shares: []model.AppleFamilyShare{{
+		Environment:      model.AppleEnvProduction,
+		PlanID:           "pro_monthly",
+		Level:            1,
+		Status:           model.SubscriptionStatusActive,
+		CurrentPeriodEnd: now.Add(48 * time.Hour),
+	}}}
+	grants, err := NewAppleEntitlementSource(d, c).Grants(context.Background(), 1, now)
+	if err != nil {
+		t.Fatalf("grants: %v", err)
+	}
+	if len(grants) != 1 || grants[0].Source != model.EntitlementSourceAppleFamily || grants[0].Status != "ACTIVE" {
+		t.Fatalf("unexpected grants: %+v", grants)
+	}
+
+	d.shares[0].Status = model.SubscriptionStatusRevoked
+	if info := resolveInfo(t, 1, now, NewAppleEntitlementSource(d, c)); info.Status != "EXPIRED" {
+		t.Fatalf("revoked family share must not entitle, got %+v", info)
+	}
+}
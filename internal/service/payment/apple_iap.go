@@ -15,15 +15,27 @@ type AppleIAPDAO interface {
 	InTx(ctx context.Context, fn func(dao.SubscriptionTx) error) error
 }
 
+// VerifyResult 是 verify 的结果。自动续期订阅只填 Subscription；一次性购买填 Purchase，
+// 其中非消耗型同时给出买断后的终身权益视图。
+type VerifyResult struct {
+	Subscription *model.SubscriptionInfo
+	Purchase     *model.PurchaseInfo
+}
+
 // AppleIAPService 实现 POST /payment/apple/verify 的业务流。
 //
 // 校验顺序与 plan 一致：fetch -> 类型/撤销/token 校验 -> token 映射 -> 事务 upsert。
+// 消耗型 / 非消耗型商品走 recordPurchase，同一 transaction 无论提交多少次只入账一次。
+// Family Sharing 获得的订阅没有 appAccountToken，走 verifyFamilyShare 由家庭成员凭设备证明认领。
 type AppleIAPService struct {
 	catalog  *Catalog
 	verifier AppleTransactionVerifier
 	tokens   *TokenService
 	dao      AppleIAPDAO
+	archive  *PayloadArchive
 	now      func() time.Time
+
+	decodeDeviceSigned func(signedTransaction, bundleID string) (deviceSignedTransaction, error)
 }
 
 // NewAppleIAPService 构造 service；任一关键依赖为 nil 时 service 调用都返回 ErrNotConfigured。
@@ -34,11 +46,25 @@ func NewAppleIAPService(catalog *Catalog, verifier AppleTransactionVerifier, tok
 		tokens:   tokens,
 		dao:      dao,
 		now:      func() time.Time { return time.Now().UTC() },
+
+		decodeDeviceSigned: decodeDeviceSignedTransaction,
 	}
 }
 
-// VerifyTransaction 是 verify endpoint 的服务层入口；返回 SubscriptionInfo 或 typed error。
-func (s *AppleIAPService) VerifyTransaction(ctx context.Context, userID int64, transactionID string) (*model.SubscriptionInfo, error) {
+// WithPayloadArchive 开启 verify 拉取到的 transaction 的加密归档；archive 为 nil 时不归档。返回 s 便于链式调用。
+func (s *AppleIAPService) WithPayloadArchive(archive *PayloadArchive) *AppleIAPService {
+	s.archive = archive
+	return s
+}
+
+// VerifyTransaction 校验不带家庭共享证明的 transaction；家庭共享订阅会返回 ErrFamilyShareProofRequired。
+func (s *AppleIAPService) VerifyTransaction(ctx context.Context, userID int64, transactionID string) (*VerifyResult, error) {
+	return s.VerifyTransactionWithProof(ctx, userID, transactionID, FamilyShareProof{})
+}
+
+// VerifyTransactionWithProof 是 verify endpoint 的服务层入口；返回 VerifyResult 或 typed error。
+// proof 只在认领家庭共享订阅时使用。
+func (s *AppleIAPService) VerifyTransactionWithProof(ctx context.Context, userID int64, transactionID string, proof FamilyShareProof) (*VerifyResult, error) {
 	if s == nil || s.catalog == nil || s.verifier == nil || s.tokens == nil || s.dao == nil {
 		return nil, ErrNotConfigured
 	}
@@ -56,6 +82,9 @@ func (s *AppleIAPService) VerifyTransaction(ctx context.Context, userID int64, t
 	if tx == nil {
 		return nil, ErrAppleTransactionNotFound
 	}
+	if tx.IsFamilyShared() && tx.ProductType() == model.ProductTypeSubscription {
+		return s.verifyFamilyShare(ctx, userID, transactionID, tx, proof)
+	}
 
 	if err := s.validateTransaction(tx); err != nil {
 		return nil, err
@@ -65,6 +94,9 @@ func (s *AppleIAPService) VerifyTransaction(ctx context.Context, userID int64, t
 	if err != nil {
 		return nil, err
 	}
+	if product.Type != tx.ProductType() {
+		return nil, ErrUnsupportedProductType
+	}
 
 	mapped, err := s.tokens.ResolveUserByToken(ctx, tx.AppAccountToken)
 	if err != nil {
@@ -77,29 +109,72 @@ func (s *AppleIAPService) VerifyTransaction(ctx context.Context, userID int64, t
 		return nil, ErrAppAccountTokenMismatch
 	}
 
+	if isOneTimeProduct(product) {
+		res, err := s.verifyPurchase(ctx, userID, tx, product)
+		if err == nil {
+			s.tokens.notifyBound(ctx, tx.AppAccountToken)
+		}
+		return res, err
+	}
+
 	upsertParams := buildVerifyUpsert(userID, tx, product, s.now())
 
 	var upserted model.Subscription
 	if err := s.dao.InTx(ctx, func(qtx dao.SubscriptionTx) error {
+		if existing, e := qtx.GetSubscriptionByOriginalTx(ctx, tx.OriginalTransactionID, tx.Environment); e == nil {
+			carryRenewalState(&upsertParams, existing)
+			applyRenewalChange(&upsertParams, "", nil, s.catalog)
+		} else if !errors.Is(e, dao.ErrSubscriptionNotFound) {
+			return e
+		}
 		sub, e := qtx.UpsertSubscriptionWithOwnershipCheck(ctx, upsertParams)
+		if errors.Is(e, dao.ErrStaleSubscriptionUpdate) {
+			// 提交的是比现有状态更旧的 transaction（如恢复购买拿到的历史交易），返回现有行。
+			sub, e = qtx.GetSubscriptionByOriginalTx(ctx, tx.OriginalTransactionID, tx.Environment)
+		}
 		if e != nil {
 			return e
 		}
 		upserted = sub
-		return nil
+		return s.archive.archiveTransaction(ctx, qtx, userID, tx)
 	}); err != nil {
 		return nil, err
 	}
 
+	s.tokens.notifyBound(ctx, tx.AppAccountToken)
+
 	info := subscriptionInfoFromRow(upserted, s.now())
-	return &info, nil
+	return &VerifyResult{Subscription: &info}, nil
+}
+
+func (s *AppleIAPService) verifyPurchase(ctx context.Context, userID int64, tx *AppleTransaction, product Product) (*VerifyResult, error) {
+	var recorded model.Purchase
+	if err := s.dao.InTx(ctx, func(qtx dao.SubscriptionTx) error {
+		p, e := recordPurchase(ctx, qtx, userID, tx, product)
+		if e != nil {
+			return e
+		}
+		recorded = p
+		return s.archive.archiveTransaction(ctx, qtx, userID, tx)
+	}); err != nil {
+		return nil, err
+	}
+
+	purchase := purchaseInfoFromRow(recorded)
+	out := &VerifyResult{Purchase: &purchase}
+	if recorded.ProductType == model.ProductTypeNonConsumable && recorded.Status == model.PurchaseStatusActive {
+		info := lifetimeInfo(recorded)
+		out.Subscription = &info
+	}
+	return out, nil
 }
 
 func (s *AppleIAPService) validateTransaction(tx *AppleTransaction) error {
-	if !tx.IsAutoRenewableSubscription() {
+	if tx.ProductType() == "" {
 		return ErrUnsupportedProductType
 	}
-	if tx.IsRevoked() {
+	// 被升级替代的旧 transaction 同样带 revocationDate，但不是退款：按替代关闭处理，返回当前订阅。
+	if tx.IsRevoked() && !tx.IsUpgraded {
 		return ErrTransactionRevoked
 	}
 	if strings.TrimSpace(tx.AppAccountToken) == "" {
@@ -119,7 +194,7 @@ func buildVerifyUpsert(userID int64, tx *AppleTransaction, product Product, now
 	if tx.IsRevoked() {
 		status = model.SubscriptionStatusRevoked
 	}
-	return model.SubscriptionUpsert{
+	upsert := model.SubscriptionUpsert{
 		UserID:                userID,
 		AppAccountToken:       tx.AppAccountToken,
 		Environment:           tx.Environment,
@@ -136,6 +211,10 @@ func buildVerifyUpsert(userID int64, tx *AppleTransaction, product Product, now
 		CurrentPeriodEnd:      tx.ExpiresDate,
 		LastEventAt:           now,
 	}
+	if tx.IsUpgraded {
+		closeSupersededTransaction(&upsert, tx)
+	}
+	return upsert
 }
 
 // subscriptionInfoFromRow 把单条 subscription 行映射为 /users/me 用的 SubscriptionInfo。
@@ -150,8 +229,17 @@ func subscriptionInfoFromRow(sub model.Subscription, now time.Time) model.Subscr
 		info.SubscribeExpiredTime = sub.CurrentPeriodEnd.UTC().Format(time.RFC3339)
 	}
 	info.Status = APIStatusForSubscription(sub, now)
-	if info.Status == "EXPIRED" {
+	switch info.Status {
+	case "EXPIRED", "BILLING_RETRY":
 		info.SubscribeLevel = 0
+		info.ExpirationReason = expirationReason(sub.ExpirationIntent)
+	case "GRACE_PERIOD":
+		info.GracePeriodExpiredTime = sub.GracePeriodExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if info.SubscribeLevel > 0 && sub.PendingPlanID != "" {
+		info.PendingProductID = sub.PendingPlanID
+		info.PendingLevel = sub.PendingLevel
+		info.PendingEffectiveTime = info.SubscribeExpiredTime
 	}
 	return info
 }
@@ -161,24 +249,20 @@ func subscriptionInfoFromRow(sub model.Subscription, now time.Time) model.Subscr
 // 规则按 plan "## Entitlement Model" 表：
 //   - ACTIVE 且仍在有效期 -> "ACTIVE"
 //   - CANCELED 但仍在有效期 -> "CANCELED"
-//   - ACTIVE / CANCELED 已过期 -> "EXPIRED"
+//   - ACTIVE / CANCELED 已过 current_period_end 但仍在 grace_period_expires_at 之前 -> "GRACE_PERIOD"（仍有权益）
+//   - ACTIVE / CANCELED 已过期且 Apple 仍在重试扣款 -> "BILLING_RETRY"（无权益，客户端应提示更新支付方式）
+//   - 其余已过期 -> "EXPIRED"
 //   - EXPIRED / REVOKED -> "EXPIRED"
-//
-// effective_end = max(current_period_end, grace_period_expires_at)。
 func APIStatusForSubscription(sub model.Subscription, now time.Time) string {
-	end := sub.CurrentPeriodEnd
-	if sub.GracePeriodExpiresAt != nil && sub.GracePeriodExpiresAt.After(end) {
-		end = *sub.GracePeriodExpiresAt
-	}
 	switch sub.Status {
-	case model.SubscriptionStatusActive:
-		if end.After(now) {
-			return "ACTIVE"
-		}
-		return "EXPIRED"
-	case model.SubscriptionStatusCanceled:
-		if end.After(now) {
-			return "CANCELED"
+	case model.SubscriptionStatusActive, model.SubscriptionStatusCanceled:
+		switch {
+		case sub.CurrentPeriodEnd.After(now):
+			return sub.Status
+		case sub.GracePeriodExpiresAt != nil && sub.GracePeriodExpiresAt.After(now):
+			return "GRACE_PERIOD"
+		case sub.BillingRetry:
+			return "BILLING_RETRY"
 		}
 		return "EXPIRED"
 	case model.SubscriptionStatusExpired, model.SubscriptionStatusRevoked:
@@ -24,12 +24,30 @@ func (f *fakeVerifier) FetchTransaction(_ context.Context, _ string, _ Environme
 	return f.tx, nil
 }
 
-// fakeIAPDAO simulates dao.SubscriptionDAO.InTx with an in-memory tx that records every upsert.
+// fakeIAPDAO simulates dao.SubscriptionDAO.InTx with an in-memory tx that records every upsert,
+// one-time purchase, credit grant, revoked bucket and family share.
 type fakeIAPDAO struct {
 	mu            sync.Mutex
 	upserts       []model.SubscriptionUpsert
 	forceConflict bool
 	commitFn      func(model.SubscriptionUpsert) (model.Subscription, error)
+
+	purchases map[string]model.Purchase
+	grants    []model.CreditGrant
+	revoked   []int64
+
+	familyShares map[string]model.AppleFamilyShare
+
+	subscriptions map[string]model.Subscription
+	reassigned    []int64
+
+	events       []model.AppleEventInsert
+	pending      []model.AppleEvent
+	eventUpdates map[int64]string
+
+	archives []model.ApplePayloadArchive
+
+	consumptions []model.AppleConsumptionRequest
 }
 
 func (f *fakeIAPDAO) InTx(ctx context.Context, fn func(dao.SubscriptionTx) error) error {
@@ -44,7 +62,10 @@ type fakeIAPTx struct {
 	owner *fakeIAPDAO
 }
 
-func (t *fakeIAPTx) InsertAppleEventIfNotExists(_ context.Context, _ model.AppleEventInsert) (bool, int64, error) {
+func (t *fakeIAPTx) InsertAppleEventIfNotExists(_ context.Context, in model.AppleEventInsert) (bool, int64, error) {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	t.owner.events = append(t.owner.events, in)
 	return true, 1, nil
 }
 
@@ -77,10 +98,143 @@ func (t *fakeIAPTx) UpsertSubscriptionWithOwnershipCheck(_ context.Context, in m
 	}, nil
 }
 
-func (t *fakeIAPTx) GetSubscriptionByOriginalTx(_ context.Context, _ string, _ model.AppleEnvironment) (model.Subscription, error) {
+func (t *fakeIAPTx) GetSubscriptionByOriginalTx(_ context.Context, originalTransactionID string, _ model.AppleEnvironment) (model.Subscription, error) {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	sub, ok := t.owner.subscriptions[originalTransactionID]
+	if !ok {
+		return model.Subscription{}, dao.ErrSubscriptionNotFound
+	}
+	return sub, nil
+}
+
+func (t *fakeIAPTx) ReassignSubscriptionOwner(_ context.Context, subscriptionID, userID int64, appAccountToken string) (model.Subscription, error) {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	for k, sub := range t.owner.subscriptions {
+		if sub.ID == subscriptionID {
+			sub.UserID = userID
+			sub.AppAccountToken = appAccountToken
+			t.owner.subscriptions[k] = sub
+			t.owner.reassigned = append(t.owner.reassigned, subscriptionID)
+			return sub, nil
+		}
+	}
 	return model.Subscription{}, dao.ErrSubscriptionNotFound
 }
 
+func (t *fakeIAPTx) InsertPurchaseIfNotExists(_ context.Context, in model.PurchaseInsert) (model.Purchase, bool, error) {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	if t.owner.purchases == nil {
+		t.owner.purchases = map[string]model.Purchase{}
+	}
+	if existing, ok := t.owner.purchases[in.TransactionID]; ok {
+		if existing.UserID != in.UserID {
+			return model.Purchase{}, false, dao.ErrPurchaseOwnershipConflict
+		}
+		return existing, false, nil
+	}
+	p := model.Purchase{
+		ID:                    int64(len(t.owner.purchases) + 1),
+		UserID:                in.UserID,
+		Environment:           in.Environment,
+		TransactionID:         in.TransactionID,
+		OriginalTransactionID: in.OriginalTransactionID,
+		PlanID:                in.PlanID,
+		ProviderProductID:     in.ProviderProductID,
+		ProductType:           in.ProductType,
+		Quantity:              in.Quantity,
+		Level:                 in.Level,
+		Credits:               in.Credits,
+		Status:                model.PurchaseStatusActive,
+		PurchasedAt:           in.PurchasedAt,
+	}
+	t.owner.purchases[in.TransactionID] = p
+	return p, true, nil
+}
+
+func (t *fakeIAPTx) GetPurchaseByTransaction(_ context.Context, transactionID string, _ model.AppleEnvironment) (model.Purchase, error) {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	p, ok := t.owner.purchases[transactionID]
+	if !ok {
+		return model.Purchase{}, dao.ErrPurchaseNotFound
+	}
+	return p, nil
+}
+
+func (t *fakeIAPTx) GrantPurchaseCredits(_ context.Context, purchaseID int64, in model.CreditGrant) (model.CreditBucket, error) {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	t.owner.grants = append(t.owner.grants, in)
+	bucketID := int64(len(t.owner.grants))
+	for k, p := range t.owner.purchases {
+		if p.ID == purchaseID {
+			p.CreditBucketID = bucketID
+			t.owner.purchases[k] = p
+		}
+	}
+	return model.CreditBucket{ID: bucketID, UserID: in.UserID, Amount: in.Amount, Remaining: in.Amount}, nil
+}
+
+func (t *fakeIAPTx) RefundPurchase(_ context.Context, p model.Purchase, revokedAt time.Time) (model.Purchase, error) {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	cur := t.owner.purchases[p.TransactionID]
+	if cur.Status == model.PurchaseStatusRefunded {
+		return cur, nil
+	}
+	cur.Status = model.PurchaseStatusRefunded
+	cur.RevokedAt = &revokedAt
+	if cur.CreditBucketID != 0 {
+		t.owner.revoked = append(t.owner.revoked, cur.CreditBucketID)
+	}
+	t.owner.purchases[p.TransactionID] = cur
+	return cur, nil
+}
+
+func (t *fakeIAPTx) GetFamilyShare(_ context.Context, originalTransactionID string, _ model.AppleEnvironment) (model.AppleFamilyShare, error) {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	share, ok := t.owner.familyShares[originalTransactionID]
+	if !ok {
+		return model.AppleFamilyShare{}, dao.ErrFamilyShareNotFound
+	}
+	return share, nil
+}
+
+func (t *fakeIAPTx) UpsertFamilyShareWithOwnershipCheck(_ context.Context, in model.AppleFamilyShareUpsert) (model.AppleFamilyShare, error) {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	if t.owner.familyShares == nil {
+		t.owner.familyShares = map[string]model.AppleFamilyShare{}
+	}
+	existing, ok := t.owner.familyShares[in.OriginalTransactionID]
+	if ok && existing.UserID != in.UserID {
+		return model.AppleFamilyShare{}, dao.ErrFamilyShareOwnershipConflict
+	}
+	share := model.AppleFamilyShare{
+		ID:                    int64(len(t.owner.familyShares) + 1),
+		UserID:                in.UserID,
+		Environment:           in.Environment,
+		OriginalTransactionID: in.OriginalTransactionID,
+		LastTransactionID:     in.LastTransactionID,
+		PlanID:                in.PlanID,
+		ProviderProductID:     in.ProviderProductID,
+		Level:                 in.Level,
+		Status:                in.Status,
+		CurrentPeriodEnd:      in.CurrentPeriodEnd,
+		RevokedAt:             in.RevokedAt,
+		LastEventAt:           in.LastEventAt,
+	}
+	if ok {
+		share.ID = existing.ID
+	}
+	t.owner.familyShares[in.OriginalTransactionID] = share
+	return share, nil
+}
+
 func newProdCatalog(t testing.TB) *Catalog {
 	t.Helper()
 	cfg := validProdConfig()
@@ -118,10 +272,14 @@ func TestAppleIAPService_VerifyTransaction_Happy(t *testing.T) {
 	dao := &fakeIAPDAO{}
 	svc := NewAppleIAPService(catalog, verifier, tokens, dao)
 
-	info, err := svc.VerifyTransaction(context.Background(), userID, "tx-1")
+	res, err := svc.VerifyTransaction(context.Background(), userID, "tx-1")
 	if err != nil {
 		t.Fatalf("verify: %v", err)
 	}
+	if res.Purchase != nil || res.Subscription == nil {
+		t.Fatalf("subscription verify should only return subscription info: %+v", res)
+	}
+	info := res.Subscription
 	if info.Status != "ACTIVE" {
 		t.Fatalf("status = %q, want ACTIVE", info.Status)
 	}
@@ -136,6 +294,43 @@ func TestAppleIAPService_VerifyTransaction_Happy(t *testing.T) {
 	}
 }
 
+func TestAppleIAPService_VerifyTransaction_StaleReturnsCurrent(t *testing.T) {
+	const userID int64 = 42
+	tok := "00000000-0000-4000-8000-000000000042"
+	now := time.Now().UTC()
+	verifier := &fakeVerifier{tx: &AppleTransaction{
+		TransactionID:         "tx-old",
+		OriginalTransactionID: "ot-1",
+		AppAccountToken:       tok,
+		BundleID:              "com.app.test",
+		Environment:           EnvProduction,
+		ProductID:             "com.app.pro.monthly",
+		Type:                  "Auto-Renewable Subscription",
+		PurchaseDate:          now.Add(-60 * 24 * time.Hour),
+		ExpiresDate:           now.Add(-30 * 24 * time.Hour),
+	}}
+	d := &fakeIAPDAO{
+		subscriptions: map[string]model.Subscription{"ot-1": {
+			ID: 3, UserID: userID, AppAccountToken: tok, Environment: model.AppleEnvProduction,
+			OriginalTransactionID: "ot-1", LastTransactionID: "tx-new", PlanID: "pro_monthly",
+			ProviderProductID: "com.app.pro.monthly", Level: 1, Status: model.SubscriptionStatusActive,
+			AutoRenewStatus: model.AutoRenewStatusOn, CurrentPeriodStart: now, CurrentPeriodEnd: now.Add(30 * 24 * time.Hour),
+		}},
+		commitFn: func(model.SubscriptionUpsert) (model.Subscription, error) {
+			return model.Subscription{}, dao.ErrStaleSubscriptionUpdate
+		},
+	}
+	svc := NewAppleIAPService(newProdCatalog(t), verifier, newTokensWithFakeDAO(userID, tok), d)
+
+	res, err := svc.VerifyTransaction(context.Background(), userID, "tx-old")
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if res.Subscription == nil || res.Subscription.Status != "ACTIVE" {
+		t.Fatalf("stale verify should return current row: %+v", res.Subscription)
+	}
+}
+
 func TestAppleIAPService_VerifyTransaction_Errors(t *testing.T) {
 	catalog := newProdCatalog(t)
 	const userID int64 = 42
@@ -283,7 +478,10 @@ func TestAPIStatusForSubscription(t *testing.T) {
 		{"canceled_past_is_expired", model.Subscription{Status: model.SubscriptionStatusCanceled, CurrentPeriodEnd: past}, "EXPIRED"},
 		{"expired_db_status", model.Subscription{Status: model.SubscriptionStatusExpired, CurrentPeriodEnd: future}, "EXPIRED"},
 		{"revoked_db_status", model.Subscription{Status: model.SubscriptionStatusRevoked, CurrentPeriodEnd: future}, "EXPIRED"},
-		{"grace_extends_active", model.Subscription{Status: model.SubscriptionStatusActive, CurrentPeriodEnd: past, GracePeriodExpiresAt: &future}, "ACTIVE"},
+		{"grace_period_after_period_end", model.Subscription{Status: model.SubscriptionStatusActive, CurrentPeriodEnd: past, GracePeriodExpiresAt: &future}, "GRACE_PERIOD"},
+		{"billing_retry_after_grace", model.Subscription{Status: model.SubscriptionStatusActive, CurrentPeriodEnd: past, GracePeriodExpiresAt: &past, BillingRetry: true}, "BILLING_RETRY"},
+		{"billing_retry_flag_within_period", model.Subscription{Status: model.SubscriptionStatusActive, CurrentPeriodEnd: future, BillingRetry: true}, "ACTIVE"},
+		{"expired_ignores_billing_retry", model.Subscription{Status: model.SubscriptionStatusExpired, CurrentPeriodEnd: past, BillingRetry: true}, "EXPIRED"},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -295,3 +493,69 @@ func TestAPIStatusForSubscription(t *testing.T) {
 }
 
 func timePtr(t time.Time) *time.Time { return &t }
+
+func (t *fakeIAPTx) ListRedrivableEvents(_ context.Context, token string, _ int) ([]model.AppleEvent, error) {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	var out []model.AppleEvent
+	for _, ev := range t.owner.pending {
+		if token == "" || ev.AppAccountToken == token {
+			out = append(out, ev)
+		}
+	}
+	return out, nil
+}
+
+func (t *fakeIAPTx) LockPendingEvent(_ context.Context, eventID int64) (model.AppleEvent, error) {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	if _, done := t.owner.eventUpdates[eventID]; done {
+		return model.AppleEvent{}, dao.ErrEventNotPending
+	}
+	for _, ev := range t.owner.pending {
+		if ev.ID == eventID {
+			return ev, nil
+		}
+	}
+	return model.AppleEvent{}, dao.ErrEventNotPending
+}
+
+func (t *fakeIAPTx) UpdateEventProcessing(_ context.Context, eventID int64, status string, _ int64, _ string) error {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	if t.owner.eventUpdates == nil {
+		t.owner.eventUpdates = map[int64]string{}
+	}
+	t.owner.eventUpdates[eventID] = status
+	for i := range t.owner.pending {
+		if t.owner.pending[i].ID == eventID {
+			t.owner.pending[i].DecodedPayload = nil
+		}
+	}
+	return nil
+}
+
+func (t *fakeIAPTx) InsertPayloadArchive(_ context.Context, in model.ApplePayloadArchive) error {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	t.owner.archives = append(t.owner.archives, in)
+	return nil
+}
+
+func (t *fakeIAPTx) GetPayloadArchiveByEvent(_ context.Context, eventID int64) (model.ApplePayloadArchive, error) {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	for _, a := range t.owner.archives {
+		if a.EventID == eventID && a.Source == model.PayloadSourceNotification {
+			return a, nil
+		}
+	}
+	return model.ApplePayloadArchive{}, dao.ErrPayloadArchiveNotFound
+}
+
+func (t *fakeIAPTx) InsertConsumptionRequest(_ context.Context, in model.AppleConsumptionRequest) error {
+	t.owner.mu.Lock()
+	defer t.owner.mu.Unlock()
+	t.owner.consumptions = append(t.owner.consumptions, in)
+	return nil
+}
@@ -1,6 +1,17 @@
 package payment
 
-import "time"
+import (
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// Apple transaction 的 type 字段取值（Apple 写法包含空格与连字符）。
+const (
+	appleTypeAutoRenewable = "Auto-Renewable Subscription"
+	appleTypeConsumable    = "Consumable"
+	appleTypeNonConsumable = "Non-Consumable"
+)
 
 // AppleTransaction 是从 Apple App Store Server API 拿到的交易在领域内的投影。
 //
@@ -21,11 +32,30 @@ type AppleTransaction struct {
 	WebOrderLineItemID    string
 	InAppOwnershipType    string
 	IsUpgraded            bool
+	Quantity              int
+	// OfferDiscountType 是该交易使用的优惠折扣类型（FREE_TRIAL / PAY_AS_YOU_GO / PAY_UP_FRONT），没有优惠时为空。
+	OfferDiscountType string
+	// DecodedPayload 是 verify 拉取到的解码后 transaction（JSON），仅用于加密归档，不随 PENDING 事件保留。
+	DecodedPayload []byte `json:"-"`
 }
 
-// IsAutoRenewableSubscription 判定 Apple 的 type 字段是否为自动续期订阅。Apple 写法包含空格。
+// IsAutoRenewableSubscription 判定 Apple 的 type 字段是否为自动续期订阅。
 func (t *AppleTransaction) IsAutoRenewableSubscription() bool {
-	return t.Type == "Auto-Renewable Subscription"
+	return t.Type == appleTypeAutoRenewable
+}
+
+// ProductType 把 Apple 的 type 字段映射为 catalog 商品类型；不支持的类型（如非续期订阅）返回空串。
+func (t *AppleTransaction) ProductType() string {
+	switch t.Type {
+	case appleTypeAutoRenewable:
+		return model.ProductTypeSubscription
+	case appleTypeConsumable:
+		return model.ProductTypeConsumable
+	case appleTypeNonConsumable:
+		return model.ProductTypeNonConsumable
+	default:
+		return ""
+	}
 }
 
 // IsRevoked 表示 Apple 标记此 transaction 为已退款 / 撤销。
@@ -72,6 +102,23 @@ type AppleSubscriptionStatus struct {
 	RenewalInfo           *AppleRenewalInfo
 }
 
+// AppleConsumptionInfo 是 Send Consumption Information 的请求 body，字段取值均为 Apple 文档中的枚举，0 表示未声明。
+// JSON 形式即发送给 Apple 的内容，原样记录在 apple_consumption_requests.sent_payload。
+type AppleConsumptionInfo struct {
+	AccountTenure            int    `json:"accountTenure"`
+	AppAccountToken          string `json:"appAccountToken"`
+	ConsumptionStatus        int    `json:"consumptionStatus"`
+	CustomerConsented        bool   `json:"customerConsented"`
+	DeliveryStatus           int    `json:"deliveryStatus"`
+	LifetimeDollarsPurchased int    `json:"lifetimeDollarsPurchased"`
+	LifetimeDollarsRefunded  int    `json:"lifetimeDollarsRefunded"`
+	Platform                 int    `json:"platform"`
+	PlayTime                 int    `json:"playTime"`
+	RefundPreference         int    `json:"refundPreference"`
+	SampleContentProvided    bool   `json:"sampleContentProvided"`
+	UserStatus               int    `json:"userStatus"`
+}
+
 // NotificationHistoryRequest 是 reconciler GetNotificationHistory 的查询参数。
 //
 // StartDate / EndDate 至少其中一个必须给定，符合 Apple Notification History 文档。
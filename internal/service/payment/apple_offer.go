@@ -0,0 +1,203 @@
+package payment
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// offerPayloadSeparator 是 Apple 规定的签名字段分隔符（U+2063 INVISIBLE SEPARATOR）。
+const offerPayloadSeparator = "\u2063"
+
+// OfferSignature 是 StoreKit 兑换促销 / 挽回优惠所需的服务端签名参数。
+type OfferSignature struct {
+	ProductID       string
+	OfferID         string
+	KeyID           string
+	AppAccountToken string
+	Nonce           string
+	Timestamp       int64
+	Signature       string
+}
+
+// AppleOfferService 为 StoreKit 订阅优惠生成签名。
+//
+// 签名使用 catalog 中的 App Store key；签发前按 APPLE_IAP_OFFERS 校验用户资格，每次签发都写入
+// apple_offer_signatures 用于审计与按用户限次。
+type AppleOfferService struct {
+	catalog *Catalog
+	tokens  *TokenService
+	subs    dao.SubscriptionDAO
+	log     dao.AppleOfferDAO
+	key     *ecdsa.PrivateKey
+	now     func() time.Time
+}
+
+// NewAppleOfferService 构造优惠签名 service；catalog 中的私钥不是 P-256 PKCS#8 PEM 时返回 wrap ErrInvalidConfig 的错误。
+func NewAppleOfferService(catalog *Catalog, tokens *TokenService, subs dao.SubscriptionDAO, log dao.AppleOfferDAO) (*AppleOfferService, error) {
+	if catalog == nil {
+		return nil, ErrNotConfigured
+	}
+	key, err := parseECPrivateKey(catalog.PrivateKeyPEM())
+	if err != nil {
+		return nil, err
+	}
+	return &AppleOfferService{
+		catalog: catalog,
+		tokens:  tokens,
+		subs:    subs,
+		log:     log,
+		key:     key,
+		now:     func() time.Time { return time.Now().UTC() },
+	}, nil
+}
+
+// SignOffer 校验用户对 (productID, offerID) 的资格并返回签名。
+//
+// 资格规则：promotional 要求用户当前或曾经订阅过同一订阅组；win_back 要求曾经订阅过且当前没有有效订阅。
+// 配置了 max_per_user 时，已签发次数达到上限也视为不符合资格；计数与写入在同一事务内原子完成。
+func (s *AppleOfferService) SignOffer(ctx context.Context, userID int64, productID, offerID string) (*OfferSignature, error) {
+	if s == nil || s.catalog == nil || s.key == nil || s.subs == nil || s.log == nil {
+		return nil, ErrNotConfigured
+	}
+	productID, offerID = strings.TrimSpace(productID), strings.TrimSpace(offerID)
+	offer, product, err := s.catalog.LookupOffer(productID, offerID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	if err := s.checkEligibility(ctx, userID, offer, product, now); err != nil {
+		return nil, err
+	}
+
+	token, err := s.tokens.EnsureAccountToken(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, fmt.Errorf("apple offer: nonce: %w", err)
+	}
+	out := &OfferSignature{
+		ProductID:       offer.ProductID,
+		OfferID:         offer.OfferID,
+		KeyID:           s.catalog.KeyID(),
+		AppAccountToken: strings.ToLower(token),
+		Nonce:           nonce,
+		Timestamp:       now.UnixMilli(),
+	}
+	sig, err := signOffer(s.key, s.catalog.BundleID(), out)
+	if err != nil {
+		return nil, err
+	}
+	out.Signature = sig
+
+	if _, err := s.log.InsertOfferSignature(ctx, model.AppleOfferSignatureInsert{
+		UserID:          userID,
+		OfferID:         offer.OfferID,
+		OfferType:       offer.Type,
+		ProductID:       offer.ProductID,
+		AppAccountToken: out.AppAccountToken,
+		Nonce:           out.Nonce,
+		SignedAt:        now,
+	}, offer.MaxPerUser); err != nil {
+		if errors.Is(err, dao.ErrOfferSignatureLimitReached) {
+			return nil, fmt.Errorf("offer %s reached max_per_user %d: %w", offer.OfferID, offer.MaxPerUser, ErrOfferNotEligible)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *AppleOfferService) checkEligibility(ctx context.Context, userID int64, offer Offer, product Product, now time.Time) error {
+	rows, err := s.subs.ListSubscriptionsForUserEntitlement(ctx, userID, s.catalog.AllowedEntitlementEnvironments())
+	if err != nil {
+		return err
+	}
+	var subscribed, active bool
+	for _, row := range rows {
+		if !sameSubscriptionGroup(row, product) {
+			continue
+		}
+		subscribed = true
+		if status := APIStatusForSubscription(row, now); status == "ACTIVE" || status == "CANCELED" || status == "GRACE_PERIOD" {
+			active = true
+		}
+	}
+	switch {
+	case !subscribed:
+		return fmt.Errorf("offer %s requires an existing subscriber: %w", offer.OfferID, ErrOfferNotEligible)
+	case offer.Type == model.OfferTypeWinBack && active:
+		return fmt.Errorf("win-back offer %s requires a lapsed subscriber: %w", offer.OfferID, ErrOfferNotEligible)
+	}
+	return nil
+}
+
+// sameSubscriptionGroup 判断订阅行与优惠商品是否属于同一订阅组；商品未配置订阅组时按商品 ID 比较。
+func sameSubscriptionGroup(row model.Subscription, product Product) bool {
+	if product.SubscriptionGroupID != "" {
+		return row.SubscriptionGroupID == product.SubscriptionGroupID
+	}
+	return row.ProviderProductID == product.ProductID
+}
+
+// signOffer 按 Apple 的规则对
+// bundleID, keyID, productID, offerID, appAccountToken, nonce, timestamp（以 U+2063 连接）
+// 做 ECDSA P-256 / SHA-256 签名，返回 base64 编码的 DER 签名。
+func signOffer(key *ecdsa.PrivateKey, bundleID string, o *OfferSignature) (string, error) {
+	payload := strings.Join([]string{
+		bundleID,
+		o.KeyID,
+		o.ProductID,
+		o.OfferID,
+		o.AppAccountToken,
+		o.Nonce,
+		strconv.FormatInt(o.Timestamp, 10),
+	}, offerPayloadSeparator)
+	digest := sha256.Sum256([]byte(payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("apple offer: sign: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+func parseECPrivateKey(pemText string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemText))
+	if block == nil {
+		return nil, fmt.Errorf("apple offer: private key is not PEM: %w", ErrInvalidConfig)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("apple offer: parse private key: %w", joinInvalidConfig(err))
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("apple offer: private key is not ECDSA: %w", ErrInvalidConfig)
+	}
+	return key, nil
+}
+
+// newNonce 生成小写 UUID v4，Apple 要求签名中的 nonce 为小写。
+func newNonce() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,136 @@
+package payment
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+type fakeOfferDAO struct {
+	signed []model.AppleOfferSignatureInsert
+}
+
+func (d *fakeOfferDAO) InsertOfferSignature(_ context.Context, in model.AppleOfferSignatureInsert, maxPerUser int) (model.AppleOfferSignature, error) {
+	n := 0
+	for _, s := range d.signed {
+		if s.UserID == in.UserID && s.OfferID == in.OfferID {
+			n++
+		}
+	}
+	if maxPerUser > 0 && n >= maxPerUser {
+		return model.AppleOfferSignature{}, dao.ErrOfferSignatureLimitReached
+	}
+	d.signed = append(d.signed, in)
+	return model.AppleOfferSignature{ID: int64(len(d.signed)), UserID: in.UserID, OfferID: in.OfferID, Nonce: in.Nonce}, nil
+}
+
+func newOfferTestService(t *testing.T, subs *readerDAO, log *fakeOfferDAO) (*AppleOfferService, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	cfg := validProdConfig()
+	cfg.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	cfg.Offers = `[{"offer_id":"promo_3m","product_id":"com.app.pro.monthly","max_per_user":1},{"offer_id":"winback_50","product_id":"com.app.pro.monthly","type":"win_back"}]`
+	catalog, err := NewCatalog(cfg, "dev")
+	if err != nil {
+		t.Fatalf("catalog: %v", err)
+	}
+	svc, err := NewAppleOfferService(catalog, newTokensWithFakeDAO(42, "00000000-0000-4000-8000-0000000000AB"), subs, log)
+	if err != nil {
+		t.Fatalf("service: %v", err)
+	}
+	return svc, key
+}
+
+func offerSubscription(now time.Time, end time.Duration) model.Subscription {
+	return model.Subscription{
+		Environment:         model.AppleEnvProduction,
+		PlanID:              "pro_monthly",
+		ProviderProductID:   "com.app.pro.monthly",
+		SubscriptionGroupID: "21456789",
+		Level:               1,
+		Status:              model.SubscriptionStatusActive,
+		CurrentPeriodEnd:    now.Add(end),
+	}
+}
+
+func TestAppleOfferService_SignOffer(t *testing.T) {
+	now := time.Now().UTC()
+	log := &fakeOfferDAO{}
+	svc, key := newOfferTestService(t, &readerDAO{rows: []model.Subscription{offerSubscription(now, 24*time.Hour)}}, log)
+
+	sig, err := svc.SignOffer(context.Background(), 42, "com.app.pro.monthly", "promo_3m")
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if sig.KeyID != "ABC123" || sig.AppAccountToken != "00000000-0000-4000-8000-0000000000ab" || sig.Nonce != strings.ToLower(sig.Nonce) {
+		t.Fatalf("unexpected signature params: %+v", sig)
+	}
+	payload := strings.Join([]string{"com.app.example", "ABC123", "com.app.pro.monthly", "promo_3m", sig.AppAccountToken, sig.Nonce, strconv.FormatInt(sig.Timestamp, 10)}, "\u2063")
+	digest := sha256.Sum256([]byte(payload))
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || !ecdsa.VerifyASN1(&key.PublicKey, digest[:], raw) {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+	if len(log.signed) != 1 || log.signed[0].Nonce != sig.Nonce || log.signed[0].OfferType != model.OfferTypePromotional {
+		t.Fatalf("signature not logged: %+v", log.signed)
+	}
+
+	if _, err := svc.SignOffer(context.Background(), 42, "com.app.pro.monthly", "promo_3m"); !errors.Is(err, ErrOfferNotEligible) {
+		t.Fatalf("second signature err = %v, want ErrOfferNotEligible (max_per_user)", err)
+	}
+}
+
+func TestAppleOfferService_Eligibility(t *testing.T) {
+	now := time.Now().UTC()
+	cases := []struct {
+		name    string
+		rows    []model.Subscription
+		offerID string
+		wantErr error
+	}{
+		{"never subscribed", nil, "promo_3m", ErrOfferNotEligible},
+		{"win-back for active subscriber", []model.Subscription{offerSubscription(now, 24*time.Hour)}, "winback_50", ErrOfferNotEligible},
+		{"win-back for lapsed subscriber", []model.Subscription{offerSubscription(now, -24*time.Hour)}, "winback_50", nil},
+		{"unknown offer", []model.Subscription{offerSubscription(now, 24*time.Hour)}, "nope", ErrUnknownOffer},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			log := &fakeOfferDAO{}
+			svc, _ := newOfferTestService(t, &readerDAO{rows: tc.rows}, log)
+			_, err := svc.SignOffer(context.Background(), 42, "com.app.pro.monthly", tc.offerID)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil && len(log.signed) != 0 {
+				t.Fatalf("rejected offer must not be logged: %+v", log.signed)
+			}
+		})
+	}
+}
+
+func TestNewAppleOfferService_RejectsNonECKey(t *testing.T) {
+	c := newProdCatalog(t)
+	if _, err := NewAppleOfferService(c, nil, &readerDAO{}, &fakeOfferDAO{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("err = %v, want ErrInvalidConfig", err)
+	}
+}
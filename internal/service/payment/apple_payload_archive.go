@@ -0,0 +1,233 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/config"
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/pkg/envelope"
+)
+
+const (
+	defaultPayloadRetention = 90 * 24 * time.Hour
+	defaultPayloadBatchSize = 500
+)
+
+// PayloadArchiveConfig 是加密归档的保留期与批量参数，含义见 config.PayloadArchiveConfig。
+type PayloadArchiveConfig struct {
+	Retention time.Duration
+	BatchSize int
+}
+
+// PayloadArchive 把解码后的 Apple 通知 / transaction 以信封加密写入 apple_payload_archives，
+// 并负责保留期清理、master key 轮换后的重新包裹以及客服解密查询。
+//
+// 明文只存在于内存中：数据库里只有密文和被 master key 包裹的 data key。nil *PayloadArchive 表示未开启归档，
+// 写入为 no-op，查询返回 ErrNotConfigured。
+type PayloadArchive struct {
+	keyring  *envelope.Keyring
+	archives dao.ApplePayloadArchiveDAO
+	cfg      PayloadArchiveConfig
+	now      func() time.Time
+}
+
+// NewPayloadKeyring 按配置构造 master keyring；未配置 Keys 时返回 ErrNotConfigured，格式错误时 wrap ErrInvalidConfig。
+// 错误信息不包含 key 材料。
+func NewPayloadKeyring(cfg config.PayloadArchiveConfig) (*envelope.Keyring, error) {
+	if strings.TrimSpace(cfg.Keys) == "" {
+		return nil, ErrNotConfigured
+	}
+	keyring, err := envelope.ParseKeyring(cfg.Keys, strings.TrimSpace(cfg.ActiveKeyID))
+	if err != nil {
+		return nil, fmt.Errorf("payload archive keys: %v: %w", err, ErrInvalidConfig)
+	}
+	return keyring, nil
+}
+
+// NewPayloadArchive 构造加密归档；keyring 或 archives 为 nil 时返回 nil（不归档）。
+func NewPayloadArchive(keyring *envelope.Keyring, archives dao.ApplePayloadArchiveDAO, cfg PayloadArchiveConfig) *PayloadArchive {
+	if keyring == nil || archives == nil {
+		return nil
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaultPayloadRetention
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultPayloadBatchSize
+	}
+	return &PayloadArchive{
+		keyring:  keyring,
+		archives: archives,
+		cfg:      cfg,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// archiveNotification 在 webhook 事务内归档新入库通知的解码内容。
+func (a *PayloadArchive) archiveNotification(ctx context.Context, qtx dao.SubscriptionTx, eventID, userID int64, event *AppleWebhookEvent) error {
+	if a == nil || len(event.DecodedPayload) == 0 {
+		return nil
+	}
+	row := model.ApplePayloadArchive{
+		Source:      model.PayloadSourceNotification,
+		EventID:     eventID,
+		UserID:      userID,
+		Environment: event.Environment,
+	}
+	if event.Transaction != nil {
+		row.OriginalTransactionID = event.Transaction.OriginalTransactionID
+		row.TransactionID = event.Transaction.TransactionID
+	}
+	return a.insert(ctx, qtx, row, event.DecodedPayload)
+}
+
+// openNotification 解密 PENDING_USER_BINDING 事件的 NOTIFICATION 归档并还原为 AppleWebhookEvent，供重放使用。
+// 归档已过保留期被清理时返回 dao.ErrPayloadArchiveNotFound。
+func (a *PayloadArchive) openNotification(ctx context.Context, qtx dao.SubscriptionTx, row model.AppleEvent) (*AppleWebhookEvent, error) {
+	if a == nil {
+		return nil, ErrNotConfigured
+	}
+	archive, err := qtx.GetPayloadArchiveByEvent(ctx, row.ID)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := a.keyring.Open(sealedFromRow(archive), payloadAAD(archive))
+	if err != nil {
+		return nil, fmt.Errorf("payload archive: open archive %d: %w", archive.ID, err)
+	}
+	var decoded decodedNotification
+	if err := json.Unmarshal(plaintext, &decoded); err != nil {
+		return nil, fmt.Errorf("payload archive: decode archive %d: %w", archive.ID, err)
+	}
+	if decoded.Notification == nil {
+		return nil, fmt.Errorf("payload archive: archive %d has no notification", archive.ID)
+	}
+	event := decoded.webhookEvent()
+	event.SignedPayloadSHA256 = row.RawJWSSHA256
+	return event, nil
+}
+
+// archiveTransaction 在 verify 事务内归档 Apple 返回的解码后 transaction；同一 transaction 只归档一次。
+func (a *PayloadArchive) archiveTransaction(ctx context.Context, qtx dao.SubscriptionTx, userID int64, tx *AppleTransaction) error {
+	if a == nil || len(tx.DecodedPayload) == 0 {
+		return nil
+	}
+	return a.insert(ctx, qtx, model.ApplePayloadArchive{
+		Source:                model.PayloadSourceTransaction,
+		UserID:                userID,
+		Environment:           tx.Environment,
+		OriginalTransactionID: tx.OriginalTransactionID,
+		TransactionID:         tx.TransactionID,
+	}, tx.DecodedPayload)
+}
+
+func (a *PayloadArchive) insert(ctx context.Context, qtx dao.SubscriptionTx, row model.ApplePayloadArchive, plaintext []byte) error {
+	sealed, err := a.keyring.Seal(plaintext, payloadAAD(row))
+	if err != nil {
+		return fmt.Errorf("payload archive: seal: %w", err)
+	}
+	row.KeyID = sealed.KeyID
+	row.WrappedKey = sealed.WrappedKey
+	row.Nonce = sealed.Nonce
+	row.Ciphertext = sealed.Ciphertext
+	row.ExpiresAt = a.now().Add(a.cfg.Retention)
+	return qtx.InsertPayloadArchive(ctx, row)
+}
+
+// ListPayloads 解密该 originalTransactionId 下最近 limit 条归档，供客服排查争议。
+// 无法解密的归档（如 master key 已被移除）返回错误，而不是静默跳过。
+func (a *PayloadArchive) ListPayloads(ctx context.Context, originalTxID string, limit int) ([]model.ApplePayload, error) {
+	if a == nil {
+		return nil, ErrNotConfigured
+	}
+	rows, err := a.archives.ListArchivesByOriginalTx(ctx, originalTxID, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]model.ApplePayload, 0, len(rows))
+	for _, r := range rows {
+		plaintext, err := a.keyring.Open(sealedFromRow(r), payloadAAD(r))
+		if err != nil {
+			return nil, fmt.Errorf("payload archive: open archive %d: %w", r.ID, err)
+		}
+		out = append(out, model.ApplePayload{
+			ID:                    r.ID,
+			Source:                r.Source,
+			EventID:               r.EventID,
+			UserID:                r.UserID,
+			Environment:           r.Environment,
+			OriginalTransactionID: r.OriginalTransactionID,
+			TransactionID:         r.TransactionID,
+			Payload:               plaintext,
+			CreatedAt:             r.CreatedAt,
+			ExpiresAt:             r.ExpiresAt,
+		})
+	}
+	return out, nil
+}
+
+// PayloadMaintenanceResult 是单次 MaintainOnce 的统计。
+type PayloadMaintenanceResult struct {
+	Purged    int64
+	Rewrapped int
+	Failed    int
+}
+
+// MaintainOnce 删除一批已过保留期的归档，并把一批仍由旧 master key 包裹的归档重新包裹到 active key。
+// 重新包裹失败（例如旧 key 已从配置移除）的行记入 Failed，不影响其他行。
+func (a *PayloadArchive) MaintainOnce(ctx context.Context) (*PayloadMaintenanceResult, error) {
+	if a == nil {
+		return nil, ErrNotConfigured
+	}
+	out := &PayloadMaintenanceResult{}
+	purged, err := a.archives.DeleteExpiredArchives(ctx, a.now(), a.cfg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	out.Purged = purged
+
+	rows, err := a.archives.ListArchivesNotUsingKey(ctx, a.keyring.ActiveKeyID(), a.cfg.BatchSize)
+	if err != nil {
+		return out, err
+	}
+	for _, r := range rows {
+		sealed, err := a.keyring.Rewrap(sealedFromRow(r))
+		if err == nil {
+			err = a.archives.UpdateArchiveKey(ctx, r.ID, r.KeyID, sealed.KeyID, sealed.WrappedKey)
+		}
+		if err != nil {
+			out.Failed++
+			slog.Warn("apple payload archive rewrap failed", "archive_id", r.ID, "key_id", r.KeyID, "err", err)
+			continue
+		}
+		out.Rewrapped++
+	}
+	return out, nil
+}
+
+// RunMaintenance 执行一轮 MaintainOnce 并记录结果，作为后台任务的周期回调。
+func (a *PayloadArchive) RunMaintenance(ctx context.Context) error {
+	res, err := a.MaintainOnce(ctx)
+	if err != nil {
+		return err
+	}
+	if res.Purged > 0 || res.Rewrapped > 0 || res.Failed > 0 {
+		slog.Info("apple payload archive maintenance done", "purged", res.Purged, "rewrapped", res.Rewrapped, "failed", res.Failed)
+	}
+	return nil
+}
+
+// payloadAAD 把密文绑定到归档行的来源与交易标识，防止把密文挪到另一行后被当作该交易的内容解密。
+func payloadAAD(r model.ApplePayloadArchive) []byte {
+	return []byte(strings.Join([]string{r.Source, string(r.Environment), r.OriginalTransactionID, r.TransactionID}, "\x00"))
+}
+
+func sealedFromRow(r model.ApplePayloadArchive) envelope.Sealed {
+	return envelope.Sealed{KeyID: r.KeyID, WrappedKey: r.WrappedKey, Nonce: r.Nonce, Ciphertext: r.Ciphertext}
+}
@@ -0,0 +1,183 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/config"
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/pkg/envelope"
+)
+
+type fakeArchiveDAO struct {
+	rows   []model.ApplePayloadArchive
+	purged time.Time
+}
+
+func (d *fakeArchiveDAO) ListArchivesByOriginalTx(_ context.Context, originalTxID string, _ int) ([]model.ApplePayloadArchive, error) {
+	var out []model.ApplePayloadArchive
+	for _, r := range d.rows {
+		if r.OriginalTransactionID == originalTxID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (d *fakeArchiveDAO) DeleteExpiredArchives(_ context.Context, before time.Time, _ int) (int64, error) {
+	d.purged = before
+	kept := d.rows[:0]
+	var n int64
+	for _, r := range d.rows {
+		if r.ExpiresAt.After(before) {
+			kept = append(kept, r)
+			continue
+		}
+		n++
+	}
+	d.rows = kept
+	return n, nil
+}
+
+func (d *fakeArchiveDAO) ListArchivesNotUsingKey(_ context.Context, keyID string, _ int) ([]model.ApplePayloadArchive, error) {
+	var out []model.ApplePayloadArchive
+	for _, r := range d.rows {
+		if r.KeyID != keyID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (d *fakeArchiveDAO) UpdateArchiveKey(_ context.Context, id int64, previousKeyID, keyID string, wrappedKey []byte) error {
+	for i := range d.rows {
+		if d.rows[i].ID == id && d.rows[i].KeyID == previousKeyID {
+			d.rows[i].KeyID, d.rows[i].WrappedKey = keyID, wrappedKey
+		}
+	}
+	return nil
+}
+
+func archiveKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func mustKeyring(t *testing.T, keys, active string) *envelope.Keyring {
+	t.Helper()
+	k, err := NewPayloadKeyring(config.PayloadArchiveConfig{Keys: keys, ActiveKeyID: active})
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	return k
+}
+
+func TestNewPayloadKeyring(t *testing.T) {
+	if _, err := NewPayloadKeyring(config.PayloadArchiveConfig{}); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("empty keys err = %v, want ErrNotConfigured", err)
+	}
+	if _, err := NewPayloadKeyring(config.PayloadArchiveConfig{Keys: "k1:not-base64"}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("bad keys err = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestAppleWebhookService_ArchivesEncryptedPayload(t *testing.T) {
+	now := time.Now().UTC()
+	tok := "00000000-0000-4000-8000-000000000079"
+	event := makeEvent("DID_RENEW", "", &AppleTransaction{
+		TransactionID: "tx-archive", OriginalTransactionID: "ot-archive",
+		AppAccountToken: tok, BundleID: "com.app.example",
+		Environment: EnvProduction, ProductID: "com.app.pro.monthly",
+		Type:         "Auto-Renewable Subscription",
+		PurchaseDate: now, ExpiresDate: now.Add(30 * 24 * time.Hour),
+	})
+	event.DecodedPayload = []byte(`{"notification":{"notificationType":"DID_RENEW"}}`)
+	d := &fakeIAPDAO{}
+	archives := &fakeArchiveDAO{}
+	archive := NewPayloadArchive(mustKeyring(t, "k1:"+archiveKey(1), ""), archives, PayloadArchiveConfig{Retention: time.Hour})
+	svc := NewAppleWebhookService(newProdCatalog(t), &fakeWebhookVerifier{event: event}, newTokensWithFakeDAO(79, tok), d).WithPayloadArchive(archive)
+
+	if err := svc.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("webhook: %v", err)
+	}
+	if len(d.archives) != 1 {
+		t.Fatalf("archives = %d, want 1", len(d.archives))
+	}
+	row := d.archives[0]
+	if row.Source != model.PayloadSourceNotification || row.EventID != 1 || row.UserID != 79 || row.KeyID != "k1" {
+		t.Fatalf("unexpected archive row: %+v", row)
+	}
+	if bytes.Contains(row.Ciphertext, []byte("DID_RENEW")) {
+		t.Fatalf("archive must not store plaintext")
+	}
+	if len(d.events) != 1 || len(d.events[0].DecodedPayload) != 0 {
+		t.Fatalf("processed event must not keep plaintext payload: %+v", d.events)
+	}
+
+	row.ID = 1
+	archives.rows = append(archives.rows, row)
+	got, err := archive.ListPayloads(context.Background(), "ot-archive", 10)
+	if err != nil {
+		t.Fatalf("list payloads: %v", err)
+	}
+	if len(got) != 1 || string(got[0].Payload) != string(event.DecodedPayload) {
+		t.Fatalf("decrypted payloads = %+v", got)
+	}
+}
+
+func TestPayloadArchive_MaintainPurgesAndRewraps(t *testing.T) {
+	oldRing := mustKeyring(t, "k1:"+archiveKey(1), "")
+	archives := &fakeArchiveDAO{}
+	old := NewPayloadArchive(oldRing, archives, PayloadArchiveConfig{})
+	now := time.Now().UTC()
+	old.now = func() time.Time { return now }
+
+	for i, tx := range []string{"tx-a", "tx-b"} {
+		d := &fakeIAPDAO{}
+		if err := d.InTx(context.Background(), func(qtx dao.SubscriptionTx) error {
+			return old.archiveTransaction(context.Background(), qtx, 5, &AppleTransaction{
+				TransactionID: tx, OriginalTransactionID: "ot-rotate", Environment: EnvProduction,
+				DecodedPayload: []byte(`{"transactionId":"` + tx + `"}`),
+			})
+		}); err != nil {
+			t.Fatalf("archive: %v", err)
+		}
+		row := d.archives[0]
+		row.ID = int64(i + 1)
+		archives.rows = append(archives.rows, row)
+	}
+	archives.rows[0].ExpiresAt = now.Add(-time.Minute)
+
+	rotated := NewPayloadArchive(mustKeyring(t, "k1:"+archiveKey(1)+",k2:"+archiveKey(2), "k2"), archives, PayloadArchiveConfig{})
+	rotated.now = func() time.Time { return now }
+	res, err := rotated.MaintainOnce(context.Background())
+	if err != nil {
+		t.Fatalf("maintain: %v", err)
+	}
+	if res.Purged != 1 || res.Rewrapped != 1 || res.Failed != 0 {
+		t.Fatalf("maintain result = %+v", res)
+	}
+
+	retired := NewPayloadArchive(mustKeyring(t, "k2:"+archiveKey(2), ""), archives, PayloadArchiveConfig{})
+	got, err := retired.ListPayloads(context.Background(), "ot-rotate", 10)
+	if err != nil {
+		t.Fatalf("list after retiring k1: %v", err)
+	}
+	if len(got) != 1 || got[0].TransactionID != "tx-b" || string(got[0].Payload) != `{"transactionId":"tx-b"}` {
+		t.Fatalf("payloads after rotation = %+v", got)
+	}
+}
+
+func TestPayloadArchive_NilIsDisabled(t *testing.T) {
+	var archive *PayloadArchive
+	if err := archive.archiveNotification(context.Background(), nil, 1, 0, &AppleWebhookEvent{DecodedPayload: []byte("{}")}); err != nil {
+		t.Fatalf("nil archive must be a no-op: %v", err)
+	}
+	if _, err := archive.ListPayloads(context.Background(), "ot", 1); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("nil archive list err = %v, want ErrNotConfigured", err)
+	}
+}
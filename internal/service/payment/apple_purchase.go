@@ -0,0 +1,84 @@
+package payment
+
+import (
+	"context"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// isOneTimeProduct 判断 catalog 商品是否为一次性购买（消耗型 / 非消耗型）。
+func isOneTimeProduct(p Product) bool {
+	return p.Type == model.ProductTypeConsumable || p.Type == model.ProductTypeNonConsumable
+}
+
+// recordPurchase 是 verify 与 webhook 共用的一次性购买 reducer，必须在 SubscriptionDAO.InTx 内调用。
+//
+//  1. 以 (transaction_id, environment) 幂等写入 apple_purchases；
+//  2. 仅在首次写入、且 transaction 未被撤销时按 catalog 发放积分（消耗型），因此 verify 与 webhook
+//     先后到达、或任一方重试都只会入账一次；
+//  3. transaction 已撤销（REFUND / REVOKE）时把购买标记为 REFUNDED 并收回对应 bucket 的剩余积分。
+func recordPurchase(ctx context.Context, qtx dao.SubscriptionTx, userID int64, tx *AppleTransaction, product Product) (model.Purchase, error) {
+	quantity := max(tx.Quantity, 1)
+	p, created, err := qtx.InsertPurchaseIfNotExists(ctx, model.PurchaseInsert{
+		UserID:                userID,
+		Environment:           tx.Environment,
+		TransactionID:         tx.TransactionID,
+		OriginalTransactionID: tx.OriginalTransactionID,
+		PlanID:                product.PlanID,
+		ProviderProductID:     product.ProductID,
+		ProductType:           product.Type,
+		Quantity:              quantity,
+		Level:                 product.Level,
+		Credits:               product.Credits * int64(quantity),
+		PurchasedAt:           tx.PurchaseDate,
+	})
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	if created && !tx.IsRevoked() && p.ProductType == model.ProductTypeConsumable && p.Credits > 0 {
+		bucket, err := qtx.GrantPurchaseCredits(ctx, p.ID, model.CreditGrant{
+			UserID:    userID,
+			Source:    model.CreditSourcePurchase,
+			Amount:    p.Credits,
+			Reference: "apple:" + p.TransactionID,
+		})
+		if err != nil {
+			return model.Purchase{}, err
+		}
+		p.CreditBucketID = bucket.ID
+	}
+
+	if tx.IsRevoked() && p.Status != model.PurchaseStatusRefunded {
+		return qtx.RefundPurchase(ctx, p, *tx.RevocationDate)
+	}
+	return p, nil
+}
+
+// purchaseInfoFromRow 把 apple_purchases 行映射为 verify 响应里的 PurchaseInfo。
+func purchaseInfoFromRow(p model.Purchase) model.PurchaseInfo {
+	info := model.PurchaseInfo{
+		TransactionID: p.TransactionID,
+		ProductID:     p.PlanID,
+		ProductType:   p.ProductType,
+		Status:        p.Status,
+	}
+	if p.CreditBucketID != 0 {
+		info.CreditsGranted = p.Credits
+	}
+	if !p.PurchasedAt.IsZero() {
+		info.PurchasedAt = p.PurchasedAt.UTC().Format(time.RFC3339)
+	}
+	return info
+}
+
+// lifetimeInfo 返回非消耗型买断对应的权益视图：永久 ACTIVE，没有到期时间。
+func lifetimeInfo(p model.Purchase) model.SubscriptionInfo {
+	return model.SubscriptionInfo{
+		ProductID:      p.PlanID,
+		Status:         "ACTIVE",
+		SubscribeLevel: p.Level,
+	}
+}
@@ -0,0 +1,164 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+const oneTimeProductsJSON = `[
+	{"plan_id":"pro_monthly","product_id":"com.app.pro.monthly","level":1,"environment":"Production"},
+	{"plan_id":"credits_100","product_id":"com.app.credits.100","type":"consumable","credits":100,"environment":"Production"},
+	{"plan_id":"lifetime","product_id":"com.app.lifetime","type":"non_consumable","level":2,"environment":"Production"}
+]`
+
+func newOneTimeCatalog(t testing.TB) *Catalog {
+	t.Helper()
+	cfg := validProdConfig()
+	cfg.Products = oneTimeProductsJSON
+	c, err := NewCatalog(cfg, "dev")
+	if err != nil {
+		t.Fatalf("catalog: %v", err)
+	}
+	return c
+}
+
+func oneTimeTx(token, txID, productID, appleType string) *AppleTransaction {
+	return &AppleTransaction{
+		TransactionID:         txID,
+		OriginalTransactionID: txID,
+		AppAccountToken:       token,
+		BundleID:              "com.app.example",
+		Environment:           EnvProduction,
+		ProductID:             productID,
+		Type:                  appleType,
+		PurchaseDate:          time.Now().UTC(),
+	}
+}
+
+func TestAppleIAPService_VerifyConsumableCreditsExactlyOnce(t *testing.T) {
+	const userID int64 = 7
+	tok := "00000000-0000-4000-8000-000000000007"
+	tx := oneTimeTx(tok, "tx-credits", "com.app.credits.100", "Consumable")
+	tx.Quantity = 2
+	d := &fakeIAPDAO{}
+	svc := NewAppleIAPService(newOneTimeCatalog(t), &fakeVerifier{tx: tx}, newTokensWithFakeDAO(userID, tok), d)
+
+	for i := 0; i < 2; i++ {
+		res, err := svc.VerifyTransaction(context.Background(), userID, "tx-credits")
+		if err != nil {
+			t.Fatalf("verify #%d: %v", i, err)
+		}
+		if res.Subscription != nil || res.Purchase == nil {
+			t.Fatalf("consumable should only return purchase info: %+v", res)
+		}
+		if res.Purchase.ProductType != model.ProductTypeConsumable || res.Purchase.CreditsGranted != 200 || res.Purchase.Status != model.PurchaseStatusActive {
+			t.Fatalf("unexpected purchase info: %+v", res.Purchase)
+		}
+	}
+	if len(d.grants) != 1 {
+		t.Fatalf("credits must be granted exactly once, got %d grants", len(d.grants))
+	}
+	if g := d.grants[0]; g.UserID != userID || g.Amount != 200 || g.Source != model.CreditSourcePurchase || g.Reference != "apple:tx-credits" {
+		t.Fatalf("unexpected grant: %+v", g)
+	}
+	if len(d.upserts) != 0 {
+		t.Fatalf("one-time purchase must not touch apple_subscriptions, got %d upserts", len(d.upserts))
+	}
+}
+
+func TestAppleIAPService_VerifyNonConsumableReturnsLifetime(t *testing.T) {
+	const userID int64 = 8
+	tok := "00000000-0000-4000-8000-000000000008"
+	tx := oneTimeTx(tok, "tx-lifetime", "com.app.lifetime", "Non-Consumable")
+	d := &fakeIAPDAO{}
+	svc := NewAppleIAPService(newOneTimeCatalog(t), &fakeVerifier{tx: tx}, newTokensWithFakeDAO(userID, tok), d)
+
+	res, err := svc.VerifyTransaction(context.Background(), userID, "tx-lifetime")
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if res.Subscription == nil || res.Subscription.Status != "ACTIVE" || res.Subscription.SubscribeLevel != 2 || res.Subscription.SubscribeExpiredTime != "" {
+		t.Fatalf("unexpected lifetime info: %+v", res.Subscription)
+	}
+	if res.Purchase == nil || res.Purchase.CreditsGranted != 0 {
+		t.Fatalf("unexpected purchase info: %+v", res.Purchase)
+	}
+	if len(d.grants) != 0 {
+		t.Fatalf("non-consumable must not grant credits, got %d", len(d.grants))
+	}
+}
+
+func TestAppleIAPService_VerifyTypeMismatchRejected(t *testing.T) {
+	const userID int64 = 9
+	tok := "00000000-0000-4000-8000-000000000009"
+	tx := oneTimeTx(tok, "tx-mismatch", "com.app.credits.100", "Non-Consumable")
+	svc := NewAppleIAPService(newOneTimeCatalog(t), &fakeVerifier{tx: tx}, newTokensWithFakeDAO(userID, tok), &fakeIAPDAO{})
+	if _, err := svc.VerifyTransaction(context.Background(), userID, "tx-mismatch"); !errors.Is(err, ErrUnsupportedProductType) {
+		t.Fatalf("got %v, want ErrUnsupportedProductType", err)
+	}
+}
+
+func TestAppleWebhookService_OneTimeChargeThenRefundReversesCredits(t *testing.T) {
+	const userID int64 = 11
+	tok := "00000000-0000-4000-8000-000000000011"
+	tx := oneTimeTx(tok, "tx-refund", "com.app.credits.100", "Consumable")
+	d := &fakeIAPDAO{}
+	tokens := newTokensWithFakeDAO(userID, tok)
+	catalog := newOneTimeCatalog(t)
+
+	charge := NewAppleWebhookService(catalog, &fakeWebhookVerifier{event: makeEvent("ONE_TIME_CHARGE", "", tx)}, tokens, d)
+	if err := charge.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("one_time_charge: %v", err)
+	}
+	// 客户端随后 verify 同一 transaction：不能重复入账。
+	verify := NewAppleIAPService(catalog, &fakeVerifier{tx: tx}, tokens, d)
+	if _, err := verify.VerifyTransaction(context.Background(), userID, "tx-refund"); err != nil {
+		t.Fatalf("verify after webhook: %v", err)
+	}
+	if len(d.grants) != 1 {
+		t.Fatalf("expected 1 grant across webhook + verify, got %d", len(d.grants))
+	}
+
+	refunded := *tx
+	refunded.RevocationDate = timePtr(time.Now().UTC())
+	refund := NewAppleWebhookService(catalog, &fakeWebhookVerifier{event: makeEvent("REFUND", "", &refunded)}, tokens, d)
+	if err := refund.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	p := d.purchases["tx-refund"]
+	if p.Status != model.PurchaseStatusRefunded || p.RevokedAt == nil {
+		t.Fatalf("purchase should be refunded: %+v", p)
+	}
+	if len(d.revoked) != 1 || d.revoked[0] != p.CreditBucketID {
+		t.Fatalf("refund should revoke the purchase bucket, got %v", d.revoked)
+	}
+	if len(d.grants) != 1 {
+		t.Fatalf("refund must not grant credits, got %d grants", len(d.grants))
+	}
+}
+
+func TestAppleWebhookService_OneTimeUnknownNotificationIgnored(t *testing.T) {
+	const userID int64 = 12
+	tok := "00000000-0000-4000-8000-000000000012"
+	tx := oneTimeTx(tok, "tx-ignored", "com.app.credits.100", "Consumable")
+	d := &fakeIAPDAO{}
+	svc := NewAppleWebhookService(newOneTimeCatalog(t), &fakeWebhookVerifier{event: makeEvent("DID_RENEW", "", tx)}, newTokensWithFakeDAO(userID, tok), d)
+	if err := svc.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("ignored event should ack, got %v", err)
+	}
+	if len(d.purchases) != 0 || len(d.grants) != 0 {
+		t.Fatalf("ignored event must not record purchase: %+v %+v", d.purchases, d.grants)
+	}
+}
+
+func TestAppleEntitlementSource_LifetimePurchase(t *testing.T) {
+	d := &readerDAO{lifetimes: []model.Purchase{{PlanID: "lifetime", Level: 2, ProductType: model.ProductTypeNonConsumable, Status: model.PurchaseStatusActive}}}
+	info := resolveInfo(t, 1, time.Now().UTC(), NewAppleEntitlementSource(d, newOneTimeCatalog(t)))
+	if info.Status != "ACTIVE" || info.ProductID != "lifetime" || info.SubscribeLevel != 2 || info.SubscribeExpiredTime != "" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+}
@@ -3,6 +3,7 @@ package payment
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 )
@@ -11,6 +12,10 @@ type fakeReconciler struct {
 	events []AppleWebhookEvent
 	next   string
 	err    error
+
+	mu       sync.Mutex
+	statuses map[string]*AppleSubscriptionStatus
+	queried  []string
 }
 
 func (f *fakeReconciler) GetNotificationHistory(_ context.Context, _ NotificationHistoryRequest) ([]AppleWebhookEvent, string, error) {
@@ -20,8 +25,15 @@ func (f *fakeReconciler) GetNotificationHistory(_ context.Context, _ Notificatio
 	return f.events, f.next, nil
 }
 
-func (f *fakeReconciler) GetSubscriptionStatus(_ context.Context, _ string, _ Environment) (*AppleSubscriptionStatus, error) {
-	return nil, errors.New("not implemented")
+func (f *fakeReconciler) GetSubscriptionStatus(_ context.Context, originalTransactionID string, _ Environment) (*AppleSubscriptionStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queried = append(f.queried, originalTransactionID)
+	st, ok := f.statuses[originalTransactionID]
+	if !ok {
+		return nil, ErrAppleTransactionNotFound
+	}
+	return st, nil
 }
 
 func TestAppleReconcileService_NotConfigured(t *testing.T) {
@@ -0,0 +1,73 @@
+package payment
+
+import (
+	"strings"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// 同一订阅组内切换商品（升级 / 降级 / 平级切换）的处理：
+//   - 升级立即生效：Apple 当即签发目标商品的新 transaction，被替代的旧 transaction 带 isUpgraded 与 revocationDate；
+//   - 降级与平级切换在当前周期结束后生效：当前 transaction 不变，renewal info 的 autoRenewProductId 指向目标商品，
+//     本地记为 pending_*，直到目标商品的续期 transaction 到达。
+
+// carryRenewalState 把现有行中来自 renewal info 的字段（待生效变更、扣款重试、到期原因）带入 upsert；
+// upsert 会整体覆盖 DB 中的值，没有 renewal info 的写入（如 verify、不带 renewal info 的通知）需要先保留原值。
+func carryRenewalState(upsert *model.SubscriptionUpsert, existing model.Subscription) {
+	upsert.PendingProductID = existing.PendingProductID
+	upsert.PendingPlanID = existing.PendingPlanID
+	upsert.PendingLevel = existing.PendingLevel
+	upsert.BillingRetry = existing.BillingRetry
+	upsert.ExpirationIntent = existing.ExpirationIntent
+}
+
+// applyRenewalChange 按 renewal info 的 autoRenewProductId 更新 upsert 的待生效变更。
+//
+// 目标商品与当前商品相同、不在 catalog 中或不属于同一订阅组时清空 pending。subtype 为 UPGRADE 且目标等级更高时
+// 直接切换到目标商品（通知携带的仍是旧 transaction 时也不让用户等到周期结束）。ri 为 nil 时保留已有 pending，
+// 但当前商品已是 pending 商品（续期后已生效）时清空。
+func applyRenewalChange(upsert *model.SubscriptionUpsert, subtype string, ri *AppleRenewalInfo, catalog *Catalog) {
+	if ri == nil {
+		if upsert.PendingProductID != "" && upsert.PendingProductID == upsert.ProviderProductID {
+			clearPendingRenewal(upsert)
+		}
+		return
+	}
+	clearPendingRenewal(upsert)
+	if ri.AutoRenewProductID == "" || ri.AutoRenewProductID == upsert.ProviderProductID {
+		return
+	}
+	target, err := catalog.Lookup(ri.AutoRenewProductID, upsert.Environment)
+	if err != nil || target.Type != model.ProductTypeSubscription {
+		return
+	}
+	if upsert.SubscriptionGroupID != "" && target.SubscriptionGroupID != "" && target.SubscriptionGroupID != upsert.SubscriptionGroupID {
+		return
+	}
+	if strings.EqualFold(subtype, "UPGRADE") && target.Level > upsert.Level {
+		upsert.PlanID = target.PlanID
+		upsert.ProviderProductID = target.ProductID
+		upsert.SubscriptionGroupID = target.SubscriptionGroupID
+		upsert.Level = target.Level
+		return
+	}
+	upsert.PendingProductID = target.ProductID
+	upsert.PendingPlanID = target.PlanID
+	upsert.PendingLevel = target.Level
+}
+
+func clearPendingRenewal(upsert *model.SubscriptionUpsert) {
+	upsert.PendingProductID = ""
+	upsert.PendingPlanID = ""
+	upsert.PendingLevel = 0
+}
+
+// closeSupersededTransaction 把因升级被替代的 transaction 记为 EXPIRED（而非退款语义的 REVOKED），
+// 有效期截止到 Apple 的替代时间。新 transaction 已写入时，这次写入的 current_period_start 更早，
+// 会被 DAO 作为过期更新拒绝，不会覆盖升级后的状态。
+func closeSupersededTransaction(upsert *model.SubscriptionUpsert, tx *AppleTransaction) {
+	upsert.Status = model.SubscriptionStatusExpired
+	if tx.RevocationDate != nil && !tx.RevocationDate.IsZero() {
+		upsert.CurrentPeriodEnd = *tx.RevocationDate
+	}
+}
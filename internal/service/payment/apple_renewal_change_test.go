@@ -0,0 +1,131 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func newTieredCatalog(t testing.TB) *Catalog {
+	t.Helper()
+	cfg := validProdConfig()
+	cfg.Products = `[
+		{"plan_id":"basic_monthly","product_id":"com.app.basic.monthly","level":1,"environment":"Production","subscription_group_id":"21456789"},
+		{"plan_id":"pro_monthly","product_id":"com.app.pro.monthly","level":2,"environment":"Production","subscription_group_id":"21456789"},
+		{"plan_id":"pro_yearly","product_id":"com.app.pro.yearly","level":2,"environment":"Production","subscription_group_id":"21456789"},
+		{"plan_id":"other_monthly","product_id":"com.app.other.monthly","level":1,"environment":"Production","subscription_group_id":"99999999"}
+	]`
+	c, err := NewCatalog(cfg, "dev")
+	if err != nil {
+		t.Fatalf("catalog: %v", err)
+	}
+	return c
+}
+
+func TestApplyRenewalChange(t *testing.T) {
+	catalog := newTieredCatalog(t)
+	pro := model.SubscriptionUpsert{
+		Environment: EnvProduction, PlanID: "pro_monthly", ProviderProductID: "com.app.pro.monthly",
+		SubscriptionGroupID: "21456789", Level: 2,
+	}
+	withPending := pro
+	withPending.PendingProductID, withPending.PendingPlanID, withPending.PendingLevel = "com.app.basic.monthly", "basic_monthly", 1
+	reached := withPending
+	reached.PlanID, reached.ProviderProductID, reached.Level = "basic_monthly", "com.app.basic.monthly", 1
+
+	cases := map[string]struct {
+		in          model.SubscriptionUpsert
+		subtype     string
+		autoRenew   string
+		nilInfo     bool
+		wantLevel   int
+		wantPending string
+	}{
+		"downgrade waits for period end":  {in: pro, subtype: "DOWNGRADE", autoRenew: "com.app.basic.monthly", wantLevel: 2, wantPending: "com.app.basic.monthly"},
+		"crossgrade waits for period end": {in: pro, autoRenew: "com.app.pro.yearly", wantLevel: 2, wantPending: "com.app.pro.yearly"},
+		"downgrade reverted":              {in: withPending, autoRenew: "com.app.pro.monthly", wantLevel: 2},
+		"other group ignored":             {in: pro, autoRenew: "com.app.other.monthly", wantLevel: 2},
+		"unknown product ignored":         {in: pro, autoRenew: "com.app.unknown", wantLevel: 2},
+		"no renewal info keeps pending":   {in: withPending, nilInfo: true, wantLevel: 2, wantPending: "com.app.basic.monthly"},
+		"pending reached is cleared":      {in: reached, nilInfo: true, wantLevel: 1},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			u := tc.in
+			var ri *AppleRenewalInfo
+			if !tc.nilInfo {
+				ri = &AppleRenewalInfo{AutoRenewStatus: 1, AutoRenewProductID: tc.autoRenew}
+			}
+			applyRenewalChange(&u, tc.subtype, ri, catalog)
+			if u.Level != tc.wantLevel || u.PendingProductID != tc.wantPending {
+				t.Fatalf("level=%d pending=%q, want level=%d pending=%q", u.Level, u.PendingProductID, tc.wantLevel, tc.wantPending)
+			}
+		})
+	}
+
+	t.Run("upgrade applies immediately", func(t *testing.T) {
+		u := pro
+		u.PlanID, u.ProviderProductID, u.Level = "basic_monthly", "com.app.basic.monthly", 1
+		applyRenewalChange(&u, "UPGRADE", &AppleRenewalInfo{AutoRenewStatus: 1, AutoRenewProductID: "com.app.pro.monthly"}, catalog)
+		if u.Level != 2 || u.PlanID != "pro_monthly" || u.PendingProductID != "" {
+			t.Fatalf("upgrade must switch now without pending: %+v", u)
+		}
+	})
+}
+
+func TestAppleWebhookService_DowngradeScheduledForPeriodEnd(t *testing.T) {
+	now := time.Now().UTC()
+	tok := "00000000-0000-4000-8000-000000000091"
+	tx := &AppleTransaction{
+		TransactionID: "tx-pro", OriginalTransactionID: "ot-downgrade",
+		AppAccountToken: tok, BundleID: "com.app.example",
+		Environment: EnvProduction, ProductID: "com.app.pro.monthly",
+		Type:         "Auto-Renewable Subscription",
+		PurchaseDate: now.Add(-24 * time.Hour), ExpiresDate: now.Add(29 * 24 * time.Hour),
+	}
+	event := makeEvent("DID_CHANGE_RENEWAL_PREF", "DOWNGRADE", tx)
+	event.RenewalInfo = &AppleRenewalInfo{AutoRenewStatus: 1, AutoRenewProductID: "com.app.basic.monthly"}
+	d := &fakeIAPDAO{}
+	svc := NewAppleWebhookService(newTieredCatalog(t), &fakeWebhookVerifier{event: event}, newTokensWithFakeDAO(91, tok), d)
+	if err := svc.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("downgrade: %v", err)
+	}
+	if len(d.upserts) != 1 {
+		t.Fatalf("expected one upsert, got %d", len(d.upserts))
+	}
+	u := d.upserts[0]
+	if u.Level != 2 || u.PlanID != "pro_monthly" || u.Status != model.SubscriptionStatusActive {
+		t.Fatalf("current level must stay until period end: %+v", u)
+	}
+	if u.PendingPlanID != "basic_monthly" || u.PendingLevel != 1 || u.AutoRenewStatus != model.AutoRenewStatusOn {
+		t.Fatalf("pending downgrade not recorded: %+v", u)
+	}
+	if e := d.events[0]; e.ProductID != "com.app.pro.monthly" || e.RenewalProductID != "com.app.basic.monthly" {
+		t.Fatalf("event products not recorded: %q -> %q", e.ProductID, e.RenewalProductID)
+	}
+}
+
+func TestAppleWebhookService_SupersededTransactionClosed(t *testing.T) {
+	now := time.Now().UTC()
+	tok := "00000000-0000-4000-8000-000000000092"
+	upgradedAt := now.Add(-time.Hour)
+	tx := &AppleTransaction{
+		TransactionID: "tx-basic", OriginalTransactionID: "ot-upgrade",
+		AppAccountToken: tok, BundleID: "com.app.example",
+		Environment: EnvProduction, ProductID: "com.app.basic.monthly",
+		Type:         "Auto-Renewable Subscription",
+		PurchaseDate: now.Add(-10 * 24 * time.Hour), ExpiresDate: now.Add(20 * 24 * time.Hour),
+		RevocationDate: &upgradedAt, IsUpgraded: true,
+	}
+	d := &fakeIAPDAO{}
+	svc := NewAppleWebhookService(newTieredCatalog(t), &fakeWebhookVerifier{event: makeEvent("DID_RENEW", "", tx)}, newTokensWithFakeDAO(92, tok), d)
+	if err := svc.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("superseded: %v", err)
+	}
+	u := d.upserts[0]
+	if u.Status != model.SubscriptionStatusExpired || !u.CurrentPeriodEnd.Equal(upgradedAt) {
+		t.Fatalf("superseded transaction must be closed at upgrade time, got %s until %s", u.Status, u.CurrentPeriodEnd)
+	}
+}
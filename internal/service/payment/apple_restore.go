@@ -0,0 +1,298 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// 恢复购买单项结果（POST /payment/apple/restore 的 items[].status）。
+const (
+	RestoreStatusRestored = "RESTORED"
+	RestoreStatusConflict = "CONFLICT"
+	RestoreStatusSkipped  = "SKIPPED"
+	RestoreStatusNotFound = "NOT_FOUND"
+)
+
+// 恢复购买单项结果的原因（items[].reason）。
+const (
+	RestoreReasonRebound                = "REBOUND"
+	RestoreReasonOwnedByAnotherAccount  = "OWNED_BY_ANOTHER_ACCOUNT"
+	RestoreReasonUnknownAccountToken    = "UNKNOWN_APP_ACCOUNT_TOKEN"
+	RestoreReasonMissingAppAccountToken = "MISSING_APP_ACCOUNT_TOKEN"
+	RestoreReasonFamilyShared           = "FAMILY_SHARED"
+	RestoreReasonConsumable             = "CONSUMABLE"
+	RestoreReasonUnknownProduct         = "UNKNOWN_PRODUCT"
+	RestoreReasonDuplicate              = "DUPLICATE"
+)
+
+// RestoreItem 是一条提交的交易的恢复结果。Subscription / Purchase 仅在 RESTORED 时填写，含义同 VerifyResult。
+type RestoreItem struct {
+	TransactionID string
+	Status        string
+	Reason        string
+	Subscription  *model.SubscriptionInfo
+	Purchase      *model.PurchaseInfo
+}
+
+// RestoreResult 按提交顺序列出每条交易的恢复结果：先 transaction id，后 signed transaction。
+type RestoreResult struct {
+	Items []RestoreItem
+}
+
+// AppleRestoreService 实现 POST /payment/apple/restore：客户端换设备 / 重装后提交本机最新的交易，
+// 服务端按 App Store Server API 的结果重建订阅与非消耗型买断。
+//
+// 归属以 Apple 返回的最新 transaction 的 appAccountToken 为准：
+//   - token 属于当前用户：写入订阅；本地行属于其他用户时改绑到当前用户（同一 Apple ID 在新账号下重新订阅的情况）；
+//   - token 属于其他用户：不改动任何数据，返回 CONFLICT；
+//   - 没有 token 或 token 未知：返回 SKIPPED。
+//
+// 一次性购买的 token 随交易固定，不存在改绑；消耗型商品已入账或已消耗，不参与恢复。
+type AppleRestoreService struct {
+	catalog    *Catalog
+	verifier   AppleTransactionVerifier
+	reconciler AppleReconciler
+	tokens     *TokenService
+	dao        AppleIAPDAO
+	archive    *PayloadArchive
+	now        func() time.Time
+
+	decodeSigned func(signedTransaction, bundleID string) (string, error)
+}
+
+// NewAppleRestoreService 构造恢复购买 service；任一依赖为 nil 时 RestorePurchases 返回 ErrNotConfigured。
+func NewAppleRestoreService(catalog *Catalog, verifier AppleTransactionVerifier, reconciler AppleReconciler, tokens *TokenService, dao AppleIAPDAO) *AppleRestoreService {
+	return &AppleRestoreService{
+		catalog:      catalog,
+		verifier:     verifier,
+		reconciler:   reconciler,
+		tokens:       tokens,
+		dao:          dao,
+		now:          func() time.Time { return time.Now().UTC() },
+		decodeSigned: decodeSignedTransactionID,
+	}
+}
+
+// WithPayloadArchive 开启恢复时拉取到的 transaction 的加密归档；archive 为 nil 时不归档。返回 s 便于链式调用。
+func (s *AppleRestoreService) WithPayloadArchive(archive *PayloadArchive) *AppleRestoreService {
+	s.archive = archive
+	return s
+}
+
+// RestorePurchases 逐条恢复 transactionIDs 与 signedTransactions 对应的购买。
+//
+// 单条交易的归属冲突、商品未知等都记录在对应的 RestoreItem 中；signed transaction 校验失败返回
+// ErrInvalidSignedTransaction，Apple / DB 的瞬态错误直接返回，客户端可整体重试（恢复是幂等的）。
+func (s *AppleRestoreService) RestorePurchases(ctx context.Context, userID int64, transactionIDs, signedTransactions []string) (*RestoreResult, error) {
+	if s == nil || s.catalog == nil || s.verifier == nil || s.reconciler == nil || s.tokens == nil || s.dao == nil {
+		return nil, ErrNotConfigured
+	}
+	if userID <= 0 {
+		return nil, errors.New("apple restore: invalid user id")
+	}
+
+	ids := make([]string, 0, len(transactionIDs)+len(signedTransactions))
+	for _, id := range transactionIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	for _, signed := range signedTransactions {
+		id, err := s.decodeSigned(signed, s.catalog.BundleID())
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("apple restore: transaction required")
+	}
+
+	out := &RestoreResult{Items: make([]RestoreItem, 0, len(ids))}
+	seen := map[string]struct{}{}
+	for _, id := range ids {
+		item, err := s.restoreOne(ctx, userID, id, seen)
+		if err != nil {
+			return nil, err
+		}
+		out.Items = append(out.Items, item)
+	}
+	return out, nil
+}
+
+func (s *AppleRestoreService) restoreOne(ctx context.Context, userID int64, transactionID string, seen map[string]struct{}) (RestoreItem, error) {
+	item := RestoreItem{TransactionID: transactionID}
+	tx, err := s.verifier.FetchTransaction(ctx, transactionID, EnvProduction)
+	if errors.Is(err, ErrAppleTransactionNotFound) || (err == nil && tx == nil) {
+		item.Status = RestoreStatusNotFound
+		return item, nil
+	}
+	if err != nil {
+		return item, err
+	}
+
+	// 同一订阅的多条交易（如多次续期）只恢复一次。
+	key := string(tx.Environment) + ":" + tx.OriginalTransactionID
+	if _, ok := seen[key]; ok {
+		item.Status, item.Reason = RestoreStatusSkipped, RestoreReasonDuplicate
+		return item, nil
+	}
+	seen[key] = struct{}{}
+
+	switch tx.ProductType() {
+	case model.ProductTypeSubscription:
+		if tx.IsFamilyShared() {
+			// 家庭共享订阅没有 appAccountToken，由家庭成员通过 verify 认领。
+			item.Status, item.Reason = RestoreStatusSkipped, RestoreReasonFamilyShared
+			return item, nil
+		}
+		return s.restoreSubscription(ctx, userID, tx, item)
+	case model.ProductTypeNonConsumable:
+		return s.restorePurchase(ctx, userID, tx, item)
+	case model.ProductTypeConsumable:
+		item.Status, item.Reason = RestoreStatusSkipped, RestoreReasonConsumable
+		return item, nil
+	default:
+		item.Status, item.Reason = RestoreStatusSkipped, RestoreReasonUnknownProduct
+		return item, nil
+	}
+}
+
+// restoreSubscription 查询 original transaction 的最新状态，以最新 transaction 决定归属后写入订阅。
+func (s *AppleRestoreService) restoreSubscription(ctx context.Context, userID int64, tx *AppleTransaction, item RestoreItem) (RestoreItem, error) {
+	status, err := s.reconciler.GetSubscriptionStatus(ctx, tx.OriginalTransactionID, tx.Environment)
+	if errors.Is(err, ErrAppleTransactionNotFound) {
+		item.Status = RestoreStatusNotFound
+		return item, nil
+	}
+	if err != nil {
+		return item, err
+	}
+	latest := tx
+	if status.LastTransaction != nil {
+		latest = status.LastTransaction
+	}
+	if latest.Environment == "" {
+		latest.Environment = tx.Environment
+	}
+
+	product, err := s.catalog.Lookup(latest.ProductID, latest.Environment)
+	if err != nil || product.Type != model.ProductTypeSubscription {
+		item.Status, item.Reason = RestoreStatusSkipped, RestoreReasonUnknownProduct
+		return item, nil
+	}
+	if reason, err := s.checkOwner(ctx, userID, latest.AppAccountToken); err != nil || reason != "" {
+		item.Status, item.Reason = restoreStatusForReason(reason), reason
+		return item, err
+	}
+
+	var (
+		restored model.Subscription
+		rebound  bool
+	)
+	if err := s.dao.InTx(ctx, func(qtx dao.SubscriptionTx) error {
+		current, e := qtx.GetSubscriptionByOriginalTx(ctx, latest.OriginalTransactionID, latest.Environment)
+		switch {
+		case e == nil && current.UserID != userID:
+			slog.Info("apple restore rebinding subscription", "subscription_id", current.ID, "from_user_id", current.UserID, "to_user_id", userID)
+			current, e = qtx.ReassignSubscriptionOwner(ctx, current.ID, userID, latest.AppAccountToken)
+			rebound = true
+		case errors.Is(e, dao.ErrSubscriptionNotFound):
+			current, e = qtx.UpsertSubscriptionWithOwnershipCheck(ctx, buildVerifyUpsert(userID, latest, product, s.now()))
+		}
+		if e != nil {
+			return e
+		}
+		restored = current
+		if upsert := buildStatusSyncUpsert(current, status, s.catalog); statusSyncChanged(current, upsert) {
+			sub, e := qtx.UpsertSubscriptionWithOwnershipCheck(ctx, upsert)
+			if e != nil && !errors.Is(e, dao.ErrStaleSubscriptionUpdate) {
+				return e
+			}
+			if e == nil {
+				restored = sub
+			}
+		}
+		return s.archive.archiveTransaction(ctx, qtx, userID, latest)
+	}); err != nil {
+		return item, err
+	}
+	s.tokens.notifyBound(ctx, latest.AppAccountToken)
+
+	info := subscriptionInfoFromRow(restored, s.now())
+	item.Status, item.Subscription = RestoreStatusRestored, &info
+	if rebound {
+		item.Reason = RestoreReasonRebound
+	}
+	return item, nil
+}
+
+// restorePurchase 重新入账非消耗型买断；recordPurchase 幂等，已入账时返回现有行。
+func (s *AppleRestoreService) restorePurchase(ctx context.Context, userID int64, tx *AppleTransaction, item RestoreItem) (RestoreItem, error) {
+	product, err := s.catalog.Lookup(tx.ProductID, tx.Environment)
+	if err != nil || product.Type != model.ProductTypeNonConsumable {
+		item.Status, item.Reason = RestoreStatusSkipped, RestoreReasonUnknownProduct
+		return item, nil
+	}
+	if reason, err := s.checkOwner(ctx, userID, tx.AppAccountToken); err != nil || reason != "" {
+		item.Status, item.Reason = restoreStatusForReason(reason), reason
+		return item, err
+	}
+
+	var recorded model.Purchase
+	err = s.dao.InTx(ctx, func(qtx dao.SubscriptionTx) error {
+		p, e := recordPurchase(ctx, qtx, userID, tx, product)
+		if e != nil {
+			return e
+		}
+		recorded = p
+		return s.archive.archiveTransaction(ctx, qtx, userID, tx)
+	})
+	if errors.Is(err, ErrPurchaseOwnershipConflict) {
+		item.Status, item.Reason = RestoreStatusConflict, RestoreReasonOwnedByAnotherAccount
+		return item, nil
+	}
+	if err != nil {
+		return item, err
+	}
+	s.tokens.notifyBound(ctx, tx.AppAccountToken)
+
+	purchase := purchaseInfoFromRow(recorded)
+	item.Status, item.Purchase = RestoreStatusRestored, &purchase
+	if recorded.Status == model.PurchaseStatusActive {
+		info := lifetimeInfo(recorded)
+		item.Subscription = &info
+	}
+	return item, nil
+}
+
+// checkOwner 判断 appAccountToken 是否属于 userID；不属于时返回原因，属于时返回空串。
+func (s *AppleRestoreService) checkOwner(ctx context.Context, userID int64, token string) (string, error) {
+	if strings.TrimSpace(token) == "" {
+		return RestoreReasonMissingAppAccountToken, nil
+	}
+	mapped, err := s.tokens.ResolveUserByToken(ctx, token)
+	if errors.Is(err, ErrAccountTokenNotFound) {
+		return RestoreReasonUnknownAccountToken, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if mapped.UserID != userID {
+		return RestoreReasonOwnedByAnotherAccount, nil
+	}
+	return "", nil
+}
+
+func restoreStatusForReason(reason string) string {
+	if reason == RestoreReasonOwnedByAnotherAccount {
+		return RestoreStatusConflict
+	}
+	return RestoreStatusSkipped
+}
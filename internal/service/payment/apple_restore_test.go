@@ -0,0 +1,156 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// restoreVerifier returns the canned transaction for each transaction id.
+type restoreVerifier map[string]*AppleTransaction
+
+func (v restoreVerifier) FetchTransaction(_ context.Context, txID string, _ Environment) (*AppleTransaction, error) {
+	tx, ok := v[txID]
+	if !ok {
+		return nil, ErrAppleTransactionNotFound
+	}
+	return tx, nil
+}
+
+func subscriptionTx(token, txID, originalTxID string, purchased time.Time) *AppleTransaction {
+	return &AppleTransaction{
+		TransactionID:         txID,
+		OriginalTransactionID: originalTxID,
+		AppAccountToken:       token,
+		BundleID:              "com.app.example",
+		Environment:           EnvProduction,
+		ProductID:             "com.app.pro.monthly",
+		Type:                  appleTypeAutoRenewable,
+		PurchaseDate:          purchased,
+		ExpiresDate:           purchased.Add(30 * 24 * time.Hour),
+	}
+}
+
+func TestAppleRestoreService_RestorePurchases(t *testing.T) {
+	const userID, otherID int64 = 42, 7
+	mine := "00000000-0000-4000-8000-000000000042"
+	theirs := "00000000-0000-4000-8000-000000000007"
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tokenDAO := newFakeTokenDAO()
+	tokenDAO.tokens[userID] = mine
+	tokenDAO.tokens[otherID] = theirs
+
+	// ot-rebind 原本记在 otherID 名下，但 Apple 返回的最新续期交易携带当前用户的 token。
+	oldTx := subscriptionTx(theirs, "tx-rebind-1", "ot-rebind", now.Add(-60*24*time.Hour))
+	latest := subscriptionTx(mine, "tx-rebind-2", "ot-rebind", now.Add(-24*time.Hour))
+	conflict := subscriptionTx(theirs, "tx-conflict", "ot-conflict", now.Add(-24*time.Hour))
+	fresh := subscriptionTx(mine, "tx-fresh", "ot-fresh", now.Add(-24*time.Hour))
+	noToken := subscriptionTx("", "tx-no-token", "ot-no-token", now.Add(-24*time.Hour))
+	family := subscriptionTx("", "tx-family", "ot-family", now.Add(-24*time.Hour))
+	family.InAppOwnershipType = "FAMILY_SHARED"
+	verifier := restoreVerifier{
+		"tx-rebind-1": oldTx,
+		"tx-rebind-2": latest,
+		"tx-conflict": conflict,
+		"tx-fresh":    fresh,
+		"tx-no-token": noToken,
+		"tx-family":   family,
+		"tx-credits":  oneTimeTx(mine, "tx-credits", "com.app.credits.100", appleTypeConsumable),
+		"tx-lifetime": oneTimeTx(mine, "tx-lifetime", "com.app.lifetime", appleTypeNonConsumable),
+	}
+	active := func(tx *AppleTransaction) *AppleSubscriptionStatus {
+		return &AppleSubscriptionStatus{
+			OriginalTransactionID: tx.OriginalTransactionID,
+			Status:                appleSubscriptionStatusActive,
+			LastTransaction:       tx,
+			RenewalInfo:           &AppleRenewalInfo{AutoRenewStatus: 1},
+		}
+	}
+	reconciler := &fakeReconciler{statuses: map[string]*AppleSubscriptionStatus{
+		"ot-rebind":   active(latest),
+		"ot-conflict": active(conflict),
+		"ot-fresh":    active(fresh),
+		"ot-no-token": active(noToken),
+	}}
+
+	subs := &fakeIAPDAO{subscriptions: map[string]model.Subscription{
+		"ot-rebind": {
+			ID: 5, UserID: otherID, AppAccountToken: theirs, Environment: model.AppleEnvProduction,
+			OriginalTransactionID: "ot-rebind", LastTransactionID: "tx-rebind-1",
+			PlanID: "pro_monthly", ProviderProductID: "com.app.pro.monthly", Level: 1,
+			Status: model.SubscriptionStatusExpired, AutoRenewStatus: model.AutoRenewStatusOff,
+			CurrentPeriodStart: oldTx.PurchaseDate, CurrentPeriodEnd: oldTx.ExpiresDate,
+		},
+	}}
+	svc := NewAppleRestoreService(newOneTimeCatalog(t), verifier, reconciler, NewTokenService(tokenDAO), subs)
+	svc.now = func() time.Time { return now }
+	svc.decodeSigned = func(signed, _ string) (string, error) {
+		if signed == "bad" {
+			return "", ErrInvalidSignedTransaction
+		}
+		return signed, nil
+	}
+
+	res, err := svc.RestorePurchases(context.Background(), userID,
+		[]string{"tx-rebind-1", "tx-rebind-2", "tx-conflict", "tx-fresh", "tx-no-token", "tx-family", "tx-credits", "tx-missing"},
+		[]string{"tx-lifetime"})
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	want := []struct{ id, status, reason string }{
+		{"tx-rebind-1", RestoreStatusRestored, RestoreReasonRebound},
+		{"tx-rebind-2", RestoreStatusSkipped, RestoreReasonDuplicate},
+		{"tx-conflict", RestoreStatusConflict, RestoreReasonOwnedByAnotherAccount},
+		{"tx-fresh", RestoreStatusRestored, ""},
+		{"tx-no-token", RestoreStatusSkipped, RestoreReasonMissingAppAccountToken},
+		{"tx-family", RestoreStatusSkipped, RestoreReasonFamilyShared},
+		{"tx-credits", RestoreStatusSkipped, RestoreReasonConsumable},
+		{"tx-missing", RestoreStatusNotFound, ""},
+		{"tx-lifetime", RestoreStatusRestored, ""},
+	}
+	if len(res.Items) != len(want) {
+		t.Fatalf("got %d items, want %d: %+v", len(res.Items), len(want), res.Items)
+	}
+	for i, w := range want {
+		got := res.Items[i]
+		if got.TransactionID != w.id || got.Status != w.status || got.Reason != w.reason {
+			t.Fatalf("item %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	if len(subs.reassigned) != 1 || subs.reassigned[0] != 5 {
+		t.Fatalf("ot-rebind must be reassigned once, got %v", subs.reassigned)
+	}
+	if info := res.Items[0].Subscription; info == nil || info.Status != model.SubscriptionStatusActive || info.SubscribeLevel != 1 {
+		t.Fatalf("rebound subscription must be active: %+v", info)
+	}
+	for _, u := range subs.upserts {
+		if u.OriginalTransactionID == "ot-conflict" || u.UserID != userID {
+			t.Fatalf("conflicting subscription must not be written: %+v", u)
+		}
+		if u.OriginalTransactionID == "ot-rebind" && u.LastTransactionID != "tx-rebind-2" {
+			t.Fatalf("rebound subscription must follow the latest transaction: %+v", u)
+		}
+	}
+	if len(subs.purchases) != 1 || subs.purchases["tx-lifetime"].UserID != userID {
+		t.Fatalf("only the non-consumable must be recorded: %+v", subs.purchases)
+	}
+	if info := res.Items[8].Subscription; info == nil || info.SubscribeLevel != 2 {
+		t.Fatalf("non-consumable must restore lifetime entitlement: %+v", info)
+	}
+
+	if _, err := svc.RestorePurchases(context.Background(), userID, nil, []string{"bad"}); !errors.Is(err, ErrInvalidSignedTransaction) {
+		t.Fatalf("bad signed transaction err = %v", err)
+	}
+}
+
+func TestAppleRestoreService_NotConfigured(t *testing.T) {
+	svc := NewAppleRestoreService(newProdCatalog(t), restoreVerifier{}, nil, newTokensWithFakeDAO(1, "t"), &fakeIAPDAO{})
+	if _, err := svc.RestorePurchases(context.Background(), 1, []string{"tx"}, nil); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("err = %v, want ErrNotConfigured", err)
+	}
+}
@@ -0,0 +1,284 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// Apple Get All Subscription Statuses 返回的 status 取值。
+const (
+	appleSubscriptionStatusActive       = 1
+	appleSubscriptionStatusExpired      = 2
+	appleSubscriptionStatusBillingRetry = 3
+	appleSubscriptionStatusGracePeriod  = 4
+	appleSubscriptionStatusRevoked      = 5
+)
+
+// AppleStatusSyncConfig 是订阅状态同步任务的参数，含义见 config.AppleStatusSyncConfig。
+type AppleStatusSyncConfig struct {
+	ExpiryWindow  time.Duration
+	StaleAfter    time.Duration
+	ResyncAfter   time.Duration
+	BatchSize     int
+	Concurrency   int
+	RatePerSecond int
+}
+
+// AppleStatusSyncService 定时用 Apple Get All Subscription Statuses 校正 apple_subscriptions，
+// 补齐漏投的 webhook，不需要运维手动跑 reconcile CLI。
+//
+// 同步只修正 status / auto_renew_status / 当前周期（以及随之变化的 plan），不改 last_event_at 与
+// 最近一次通知的快照；每次查询的时间与结果记录在 apple_subscription_status_syncs。
+type AppleStatusSyncService struct {
+	catalog    *Catalog
+	reconciler AppleReconciler
+	dao        AppleIAPDAO
+	syncs      dao.AppleStatusSyncDAO
+	cfg        AppleStatusSyncConfig
+	now        func() time.Time
+}
+
+// NewAppleStatusSyncService 构造状态同步 service；任一依赖为 nil 时 SyncOnce 返回 ErrNotConfigured。
+func NewAppleStatusSyncService(catalog *Catalog, reconciler AppleReconciler, subs AppleIAPDAO, syncs dao.AppleStatusSyncDAO, cfg AppleStatusSyncConfig) *AppleStatusSyncService {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 200
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	return &AppleStatusSyncService{
+		catalog:    catalog,
+		reconciler: reconciler,
+		dao:        subs,
+		syncs:      syncs,
+		cfg:        cfg,
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// StatusSyncResult 是单次 SyncOnce 的统计。
+type StatusSyncResult struct {
+	Checked int
+	Updated int
+	Failed  int
+}
+
+// RunSync 执行一轮 SyncOnce 并记录结果，作为后台任务的周期回调。
+func (s *AppleStatusSyncService) RunSync(ctx context.Context) error {
+	res, err := s.SyncOnce(ctx)
+	if err != nil {
+		return err
+	}
+	if res.Checked > 0 {
+		slog.Info("apple subscription status sync done", "checked", res.Checked, "updated", res.Updated, "failed", res.Failed)
+	}
+	return nil
+}
+
+// SyncOnce 挑选一批到期附近或长时间没有通知的订阅，逐条向 Apple 查询并修正本地状态。
+//
+// 同时在途的 Apple 请求不超过 Concurrency，发起速率不超过 RatePerSecond。单条失败记入 Failed
+// 与 apple_subscription_status_syncs.last_error，不影响其他行。
+func (s *AppleStatusSyncService) SyncOnce(ctx context.Context) (*StatusSyncResult, error) {
+	if s == nil || s.catalog == nil || s.reconciler == nil || s.dao == nil || s.syncs == nil {
+		return nil, ErrNotConfigured
+	}
+	now := s.now()
+	rows, err := s.syncs.ListSubscriptionsDueForStatusSync(ctx, model.SubscriptionStatusSyncFilter{
+		Environments:    s.catalog.AllowedEntitlementEnvironments(),
+		PeriodEndBefore: now.Add(s.cfg.ExpiryWindow),
+		LastEventBefore: now.Add(-s.cfg.StaleAfter),
+		SyncedBefore:    now.Add(-s.cfg.ResyncAfter),
+		Limit:           s.cfg.BatchSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("apple status sync: list due subscriptions: %w", err)
+	}
+
+	var throttle <-chan time.Time
+	if s.cfg.RatePerSecond > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(s.cfg.RatePerSecond))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	out := &StatusSyncResult{}
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, s.cfg.Concurrency)
+	)
+	for i, row := range rows {
+		if throttle != nil && i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-throttle:
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(row model.Subscription) {
+			defer func() { <-sem; wg.Done() }()
+			updated, err := s.syncOne(ctx, row)
+			mu.Lock()
+			defer mu.Unlock()
+			out.Checked++
+			switch {
+			case err != nil:
+				out.Failed++
+				slog.Warn("apple subscription status sync row failed", "subscription_id", row.ID, "original_transaction_id", row.OriginalTransactionID, "err", err)
+			case updated:
+				out.Updated++
+			}
+		}(row)
+	}
+	wg.Wait()
+	return out, ctx.Err()
+}
+
+// syncOne 查询单条订阅并在状态有变化时写回；无论成败都记录一次同步。
+func (s *AppleStatusSyncService) syncOne(ctx context.Context, row model.Subscription) (bool, error) {
+	status, err := s.reconciler.GetSubscriptionStatus(ctx, row.OriginalTransactionID, row.Environment)
+	updated := false
+	if err == nil {
+		err = s.dao.InTx(ctx, func(qtx dao.SubscriptionTx) error {
+			current, err := qtx.GetSubscriptionByOriginalTx(ctx, row.OriginalTransactionID, row.Environment)
+			if err != nil {
+				return err
+			}
+			upsert := buildStatusSyncUpsert(current, status, s.catalog)
+			if !statusSyncChanged(current, upsert) {
+				return nil
+			}
+			if _, err := qtx.UpsertSubscriptionWithOwnershipCheck(ctx, upsert); err != nil {
+				if errors.Is(err, dao.ErrStaleSubscriptionUpdate) {
+					return nil
+				}
+				return err
+			}
+			updated = true
+			return nil
+		})
+	}
+
+	mark := model.SubscriptionStatusSync{SubscriptionID: row.ID, SyncedAt: s.now()}
+	if status != nil {
+		mark.AppleStatus = status.Status
+	}
+	if err != nil {
+		mark.LastError = err.Error()
+	}
+	if merr := s.syncs.MarkSubscriptionStatusSynced(ctx, mark); merr != nil && err == nil {
+		err = merr
+	}
+	return updated, err
+}
+
+// buildStatusSyncUpsert 以现有行为基础，叠加 Apple 返回的最新 transaction / renewal info / status。
+//
+// status 映射：1 活跃（auto-renew 关闭时为 CANCELED）、3 billing retry 与 4 grace period 按 1 处理，
+// 由 current_period_end / grace_period_expires_at 决定对外是否仍有效；2 过期为 EXPIRED，5 撤销为 REVOKED。
+// transaction 的商品不在 catalog 中时保留现有 plan；renewal info 的 autoRenewProductId 同步待生效的降级 / 平级切换。
+func buildStatusSyncUpsert(row model.Subscription, st *AppleSubscriptionStatus, catalog *Catalog) model.SubscriptionUpsert {
+	upsert := model.SubscriptionUpsert{
+		UserID:                    row.UserID,
+		AppAccountToken:           row.AppAccountToken,
+		Environment:               row.Environment,
+		OriginalTransactionID:     row.OriginalTransactionID,
+		LastTransactionID:         row.LastTransactionID,
+		WebOrderLineItemID:        row.WebOrderLineItemID,
+		PlanID:                    row.PlanID,
+		ProviderProductID:         row.ProviderProductID,
+		SubscriptionGroupID:       row.SubscriptionGroupID,
+		Level:                     row.Level,
+		Status:                    row.Status,
+		AutoRenewStatus:           row.AutoRenewStatus,
+		CurrentPeriodStart:        row.CurrentPeriodStart,
+		CurrentPeriodEnd:          row.CurrentPeriodEnd,
+		GracePeriodExpiresAt:      row.GracePeriodExpiresAt,
+		LastEventAt:               row.LastEventAt,
+		LastNotificationCreatedAt: row.LastNotificationCreatedAt,
+		LastPayloadHash:           row.LastPayloadHash,
+		LastTransactionSnapshot:   row.LastTransactionSnapshot,
+	}
+	carryRenewalState(&upsert, row)
+	if tx := st.LastTransaction; tx != nil {
+		if tx.TransactionID != "" {
+			upsert.LastTransactionID = tx.TransactionID
+		}
+		if tx.WebOrderLineItemID != "" {
+			upsert.WebOrderLineItemID = tx.WebOrderLineItemID
+		}
+		if !tx.PurchaseDate.IsZero() {
+			upsert.CurrentPeriodStart = tx.PurchaseDate
+		}
+		if !tx.ExpiresDate.IsZero() {
+			upsert.CurrentPeriodEnd = tx.ExpiresDate
+		}
+		if product, err := catalog.Lookup(tx.ProductID, row.Environment); err == nil && product.Type == model.ProductTypeSubscription {
+			upsert.PlanID = product.PlanID
+			upsert.ProviderProductID = product.ProductID
+			upsert.SubscriptionGroupID = product.SubscriptionGroupID
+			upsert.Level = product.Level
+		}
+	}
+	if ri := st.RenewalInfo; ri != nil {
+		switch ri.AutoRenewStatus {
+		case 0:
+			upsert.AutoRenewStatus = model.AutoRenewStatusOff
+		case 1:
+			upsert.AutoRenewStatus = model.AutoRenewStatusOn
+		}
+		upsert.GracePeriodExpiresAt = ri.GracePeriodExpiresDate
+	}
+	applyRenewalFailure(&upsert, st.RenewalInfo)
+	applyRenewalChange(&upsert, "", st.RenewalInfo, catalog)
+
+	switch st.Status {
+	case appleSubscriptionStatusActive, appleSubscriptionStatusBillingRetry, appleSubscriptionStatusGracePeriod:
+		upsert.Status = model.SubscriptionStatusActive
+		if st.Status == appleSubscriptionStatusBillingRetry {
+			upsert.BillingRetry = true
+		}
+		if upsert.AutoRenewStatus == model.AutoRenewStatusOff {
+			upsert.Status = model.SubscriptionStatusCanceled
+		}
+	case appleSubscriptionStatusExpired:
+		upsert.Status = model.SubscriptionStatusExpired
+	case appleSubscriptionStatusRevoked:
+		upsert.Status = model.SubscriptionStatusRevoked
+	}
+	return upsert
+}
+
+// statusSyncChanged 判断同步结果是否与现有行不同，避免无变化时刷新 updated_at。
+func statusSyncChanged(row model.Subscription, u model.SubscriptionUpsert) bool {
+	return row.Status != u.Status ||
+		row.AutoRenewStatus != u.AutoRenewStatus ||
+		row.LastTransactionID != u.LastTransactionID ||
+		row.PlanID != u.PlanID ||
+		row.Level != u.Level ||
+		row.PendingProductID != u.PendingProductID ||
+		row.BillingRetry != u.BillingRetry ||
+		row.ExpirationIntent != u.ExpirationIntent ||
+		!row.CurrentPeriodStart.Equal(u.CurrentPeriodStart) ||
+		!row.CurrentPeriodEnd.Equal(u.CurrentPeriodEnd) ||
+		!equalTimePtr(row.GracePeriodExpiresAt, u.GracePeriodExpiresAt)
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
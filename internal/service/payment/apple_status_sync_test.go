@@ -0,0 +1,174 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+type fakeStatusSyncDAO struct {
+	mu     sync.Mutex
+	due    []model.Subscription
+	filter model.SubscriptionStatusSyncFilter
+	marks  []model.SubscriptionStatusSync
+}
+
+func (d *fakeStatusSyncDAO) ListSubscriptionsDueForStatusSync(_ context.Context, in model.SubscriptionStatusSyncFilter) ([]model.Subscription, error) {
+	d.filter = in
+	return d.due, nil
+}
+
+func (d *fakeStatusSyncDAO) MarkSubscriptionStatusSynced(_ context.Context, in model.SubscriptionStatusSync) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.marks = append(d.marks, in)
+	return nil
+}
+
+func statusSyncRow(id int64, otID string, now time.Time) model.Subscription {
+	return model.Subscription{
+		ID:                    id,
+		UserID:                42,
+		AppAccountToken:       "00000000-0000-4000-8000-000000000042",
+		Environment:           model.AppleEnvProduction,
+		OriginalTransactionID: otID,
+		LastTransactionID:     otID,
+		PlanID:                "pro_monthly",
+		ProviderProductID:     "com.app.pro.monthly",
+		SubscriptionGroupID:   "21456789",
+		Level:                 1,
+		Status:                model.SubscriptionStatusActive,
+		AutoRenewStatus:       model.AutoRenewStatusOn,
+		CurrentPeriodStart:    now.Add(-30 * 24 * time.Hour),
+		CurrentPeriodEnd:      now.Add(-time.Hour),
+		LastEventAt:           now.Add(-30 * 24 * time.Hour),
+	}
+}
+
+func TestAppleStatusSyncService_SyncOnce(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	renewed := statusSyncRow(1, "ot-renewed", now)
+	revoked := statusSyncRow(2, "ot-revoked", now)
+	missing := statusSyncRow(3, "ot-missing", now)
+	unchanged := statusSyncRow(4, "ot-unchanged", now)
+	unchanged.CurrentPeriodEnd = now.Add(12 * time.Hour)
+
+	subs := &fakeIAPDAO{subscriptions: map[string]model.Subscription{}}
+	for _, row := range []model.Subscription{renewed, revoked, missing, unchanged} {
+		subs.subscriptions[row.OriginalTransactionID] = row
+	}
+	reconciler := &fakeReconciler{statuses: map[string]*AppleSubscriptionStatus{
+		"ot-renewed": {
+			Status: appleSubscriptionStatusActive,
+			LastTransaction: &AppleTransaction{
+				TransactionID:         "tx-renewed-2",
+				OriginalTransactionID: "ot-renewed",
+				ProductID:             "com.app.pro.monthly",
+				PurchaseDate:          now.Add(-time.Hour),
+				ExpiresDate:           now.Add(30 * 24 * time.Hour),
+			},
+			RenewalInfo: &AppleRenewalInfo{AutoRenewStatus: 1},
+		},
+		"ot-revoked": {Status: appleSubscriptionStatusRevoked},
+		"ot-unchanged": {
+			Status:      appleSubscriptionStatusActive,
+			RenewalInfo: &AppleRenewalInfo{AutoRenewStatus: 1},
+		},
+	}}
+	syncs := &fakeStatusSyncDAO{due: []model.Subscription{renewed, revoked, missing, unchanged}}
+	svc := NewAppleStatusSyncService(newProdCatalog(t), reconciler, subs, syncs, AppleStatusSyncConfig{
+		ExpiryWindow: 24 * time.Hour,
+		StaleAfter:   7 * 24 * time.Hour,
+		ResyncAfter:  6 * time.Hour,
+		BatchSize:    50,
+		Concurrency:  2,
+	})
+	svc.now = func() time.Time { return now }
+
+	res, err := svc.SyncOnce(context.Background())
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if res.Checked != 4 || res.Updated != 2 || res.Failed != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if got := syncs.filter; !got.PeriodEndBefore.Equal(now.Add(24*time.Hour)) || !got.LastEventBefore.Equal(now.Add(-7*24*time.Hour)) ||
+		!got.SyncedBefore.Equal(now.Add(-6*time.Hour)) || got.Limit != 50 || len(got.Environments) != 1 {
+		t.Fatalf("unexpected filter: %+v", got)
+	}
+
+	byOT := map[string]model.SubscriptionUpsert{}
+	for _, u := range subs.upserts {
+		byOT[u.OriginalTransactionID] = u
+	}
+	if len(byOT) != 2 {
+		t.Fatalf("want 2 upserts, got %+v", subs.upserts)
+	}
+	if u := byOT["ot-renewed"]; u.Status != model.SubscriptionStatusActive || u.LastTransactionID != "tx-renewed-2" ||
+		!u.CurrentPeriodEnd.Equal(now.Add(30*24*time.Hour)) || !u.LastEventAt.Equal(renewed.LastEventAt) {
+		t.Fatalf("unexpected renewed upsert: %+v", u)
+	}
+	if u := byOT["ot-revoked"]; u.Status != model.SubscriptionStatusRevoked {
+		t.Fatalf("unexpected revoked upsert: %+v", u)
+	}
+
+	if len(syncs.marks) != 4 {
+		t.Fatalf("every row must be marked synced, got %+v", syncs.marks)
+	}
+	for _, m := range syncs.marks {
+		if m.SubscriptionID == missing.ID && m.LastError == "" {
+			t.Fatalf("failed row must record the error: %+v", m)
+		}
+		if m.SubscriptionID != missing.ID && (m.LastError != "" || m.AppleStatus == 0) {
+			t.Fatalf("unexpected mark: %+v", m)
+		}
+	}
+}
+
+func TestBuildStatusSyncUpsert_StatusMapping(t *testing.T) {
+	now := time.Now().UTC()
+	grace := now.Add(6 * 24 * time.Hour)
+	cases := []struct {
+		name       string
+		status     *AppleSubscriptionStatus
+		wantStatus string
+		wantRenew  string
+		wantGrace  bool
+		wantRetry  bool
+	}{
+		{"active auto-renew off", &AppleSubscriptionStatus{Status: appleSubscriptionStatusActive, RenewalInfo: &AppleRenewalInfo{AutoRenewStatus: 0}}, model.SubscriptionStatusCanceled, model.AutoRenewStatusOff, false, false},
+		{"expired", &AppleSubscriptionStatus{Status: appleSubscriptionStatusExpired}, model.SubscriptionStatusExpired, model.AutoRenewStatusOn, false, false},
+		{"billing retry", &AppleSubscriptionStatus{Status: appleSubscriptionStatusBillingRetry, RenewalInfo: &AppleRenewalInfo{AutoRenewStatus: 1, IsInBillingRetryPeriod: true, ExpirationIntent: 2}}, model.SubscriptionStatusActive, model.AutoRenewStatusOn, false, true},
+		{"grace period", &AppleSubscriptionStatus{Status: appleSubscriptionStatusGracePeriod, RenewalInfo: &AppleRenewalInfo{AutoRenewStatus: 1, GracePeriodExpiresDate: &grace}}, model.SubscriptionStatusActive, model.AutoRenewStatusOn, true, false},
+		{"revoked", &AppleSubscriptionStatus{Status: appleSubscriptionStatusRevoked}, model.SubscriptionStatusRevoked, model.AutoRenewStatusOn, false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			row := statusSyncRow(1, "ot-1", now)
+			u := buildStatusSyncUpsert(row, tc.status, newProdCatalog(t))
+			if u.Status != tc.wantStatus || u.AutoRenewStatus != tc.wantRenew {
+				t.Fatalf("status/auto-renew = %s/%s, want %s/%s", u.Status, u.AutoRenewStatus, tc.wantStatus, tc.wantRenew)
+			}
+			if (u.GracePeriodExpiresAt != nil) != tc.wantGrace {
+				t.Fatalf("grace = %v, want set=%v", u.GracePeriodExpiresAt, tc.wantGrace)
+			}
+			if u.BillingRetry != tc.wantRetry {
+				t.Fatalf("billing retry = %v, want %v", u.BillingRetry, tc.wantRetry)
+			}
+			if u.PlanID != row.PlanID || !u.CurrentPeriodEnd.Equal(row.CurrentPeriodEnd) || !u.LastEventAt.Equal(row.LastEventAt) {
+				t.Fatalf("fields without Apple data must be kept: %+v", u)
+			}
+		})
+	}
+}
+
+func TestAppleStatusSyncService_NotConfigured(t *testing.T) {
+	svc := NewAppleStatusSyncService(nil, nil, nil, nil, AppleStatusSyncConfig{})
+	if _, err := svc.SyncOnce(context.Background()); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("err = %v, want ErrNotConfigured", err)
+	}
+}
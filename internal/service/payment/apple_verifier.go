@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -28,6 +29,11 @@ type AppleReconciler interface {
 	GetSubscriptionStatus(ctx context.Context, originalTransactionID string, env Environment) (*AppleSubscriptionStatus, error)
 }
 
+// AppleConsumptionSender 调用 Send Consumption Information 回复 CONSUMPTION_REQUEST。
+type AppleConsumptionSender interface {
+	SendConsumptionInformation(ctx context.Context, transactionID string, env Environment, info AppleConsumptionInfo) error
+}
+
 // ErrAppleTransactionNotFound 表示 Apple 返回 4040010 等 not-found 错误码。
 var ErrAppleTransactionNotFound = errors.New("apple iap: transaction not found")
 
@@ -116,6 +122,19 @@ func NewAppleReconciler(c *Catalog) (AppleReconciler, error) {
 	}, nil
 }
 
+// NewAppleConsumptionSender 构造生产用 consumption sender；与 verifier 共享 prod/sandbox 客户端。
+func NewAppleConsumptionSender(c *Catalog) (AppleConsumptionSender, error) {
+	prod, sandbox, err := NewGopayAppleClients(c)
+	if err != nil {
+		return nil, err
+	}
+	return &gopayVerifier{
+		prod:     prod,
+		sandbox:  sandbox,
+		bundleID: c.BundleID(),
+	}, nil
+}
+
 // FetchTransaction 调用 Apple GetTransactionInfo，并按 fallback 策略尝试 sandbox。
 func (v *gopayVerifier) FetchTransaction(ctx context.Context, txID string, env Environment) (*AppleTransaction, error) {
 	if v == nil || v.prod == nil {
@@ -160,6 +179,9 @@ func (v *gopayVerifier) fetchOnce(ctx context.Context, txID string, useProd bool
 	if tx.BundleID != "" && v.bundleID != "" && !strings.EqualFold(tx.BundleID, v.bundleID) {
 		return nil, fmt.Errorf("apple iap: bundle mismatch: got %s, want %s", tx.BundleID, v.bundleID)
 	}
+	if raw, err := json.Marshal(decoded); err == nil {
+		tx.DecodedPayload = raw
+	}
 	return &tx, nil
 }
 
@@ -191,15 +213,91 @@ func (v *gopayVerifier) GetNotificationHistory(ctx context.Context, req Notifica
 	return out, rsp.PaginationToken, nil
 }
 
-// GetSubscriptionStatus 是 reconcile 的逃生口；此 stub 返回 NotConfigured 直到接入 Apple GetAllSubscriptionStatuses。
+// GetSubscriptionStatus 调用 Apple Get All Subscription Statuses，返回 originalTransactionID 所在订阅的最新状态。
 //
-// 暂未实现的原因：gopay v1.5.118 没有 GetAllSubscriptionStatuses；plan 接受用 raw HTTP 兜底，
-// 留到 follow-up 任务，service 此前先依赖 Notification History replay。
+// 只查询 env 对应的环境，不做 sandbox 回退：调用方从 apple_subscriptions 取出的行已经带有确定的环境。
+// 响应中找不到该 original_transaction_id 时返回 ErrAppleTransactionNotFound。
 func (v *gopayVerifier) GetSubscriptionStatus(ctx context.Context, originalTransactionID string, env Environment) (*AppleSubscriptionStatus, error) {
-	_ = ctx
-	_ = originalTransactionID
-	_ = env
-	return nil, errors.New("apple iap: GetSubscriptionStatus not implemented; use GetNotificationHistory replay")
+	if v == nil || v.prod == nil {
+		return nil, ErrNotConfigured
+	}
+	if strings.TrimSpace(originalTransactionID) == "" {
+		return nil, errors.New("apple iap: original transaction id required")
+	}
+	client := v.prod
+	if env == EnvSandbox {
+		if v.sandbox == nil {
+			return nil, ErrSandboxFallbackDisabled
+		}
+		client = v.sandbox
+	}
+	rsp, err := client.GetAllSubscriptionStatuses(ctx, originalTransactionID)
+	if err != nil {
+		return nil, classifyAppleError(err)
+	}
+	if rsp == nil {
+		return nil, ErrAppleTransactionNotFound
+	}
+	for _, group := range rsp.Data {
+		if group == nil {
+			continue
+		}
+		for _, item := range group.LastTransactions {
+			if item == nil || item.OriginalTransactionId != originalTransactionID {
+				continue
+			}
+			out := &AppleSubscriptionStatus{
+				OriginalTransactionID: item.OriginalTransactionId,
+				Environment:           env,
+				Status:                int(item.Status),
+			}
+			if rsp.Environment != "" {
+				out.Environment = Environment(rsp.Environment)
+			}
+			if item.SignedTransactionInfo != "" {
+				tx, err := item.DecodeTransactionInfo()
+				if err != nil {
+					return nil, fmt.Errorf("apple iap: decode status transaction: %w", err)
+				}
+				mapped := mapAppleTransaction(tx)
+				if mapped.BundleID != "" && v.bundleID != "" && !strings.EqualFold(mapped.BundleID, v.bundleID) {
+					return nil, fmt.Errorf("apple iap: bundle mismatch: got %s, want %s", mapped.BundleID, v.bundleID)
+				}
+				out.LastTransaction = &mapped
+			}
+			if item.SignedRenewalInfo != "" {
+				ri, err := item.DecodeRenewalInfo()
+				if err != nil {
+					return nil, fmt.Errorf("apple iap: decode status renewal info: %w", err)
+				}
+				out.RenewalInfo = mapRenewalInfo(ri)
+			}
+			return out, nil
+		}
+	}
+	return nil, ErrAppleTransactionNotFound
+}
+
+// SendConsumptionInformation 调用 Apple Send Consumption Information。与 GetSubscriptionStatus 一样只发往 env
+// 对应的环境：请求来自该环境的通知，不做 sandbox 回退。
+func (v *gopayVerifier) SendConsumptionInformation(ctx context.Context, transactionID string, env Environment, info AppleConsumptionInfo) error {
+	if v == nil || v.prod == nil {
+		return ErrNotConfigured
+	}
+	if strings.TrimSpace(transactionID) == "" {
+		return errors.New("apple iap: transaction id required")
+	}
+	client := v.prod
+	if env == EnvSandbox {
+		if v.sandbox == nil {
+			return ErrSandboxFallbackDisabled
+		}
+		client = v.sandbox
+	}
+	if err := client.SendConsumptionInformation(ctx, transactionID, buildConsumptionBodyMap(info)); err != nil {
+		return classifyAppleError(err)
+	}
+	return nil
 }
 
 // gopayWebhookVerifier 是 AppleWebhookVerifier 的生产实现。
@@ -237,42 +335,118 @@ func decodeWebhookEvent(signedPayload, expectBundle string) (*AppleWebhookEvent,
 	if payload == nil || payload.NotificationUUID == "" {
 		return nil, errors.New("apple iap: missing notification uuid")
 	}
-	ev := &AppleWebhookEvent{
-		NotificationUUID:    payload.NotificationUUID,
-		NotificationType:    payload.NotificationType,
-		Subtype:             payload.Subtype,
-		SignedPayloadSHA256: sha256Hex(signedPayload),
+	archived := decodedNotification{Notification: payload}
+	if tx, err := payload.DecodeTransactionInfo(); err == nil && tx != nil {
+		archived.TransactionInfo = tx
 	}
-	if payload.Data != nil {
-		ev.BundleID = payload.Data.BundleID
-		ev.Environment = Environment(payload.Data.Environment)
+	if ri, err := payload.DecodeRenewalInfo(); err == nil && ri != nil {
+		archived.RenewalInfo = ri
 	}
+	ev := archived.webhookEvent()
+	ev.SignedPayloadSHA256 = sha256Hex(signedPayload)
 	if expectBundle != "" && ev.BundleID != "" && !strings.EqualFold(ev.BundleID, expectBundle) {
 		return nil, fmt.Errorf("apple iap: bundle mismatch in webhook: got %s, want %s", ev.BundleID, expectBundle)
 	}
+	if raw, err := json.Marshal(archived); err == nil {
+		ev.DecodedPayload = raw
+	}
+	return ev, nil
+}
+
+// decodedNotification 是写入加密归档的通知内容：通知本身加上已解码的 transaction / renewal info 明文。
+type decodedNotification struct {
+	Notification    *gopayApple.NotificationV2Payload `json:"notification"`
+	TransactionInfo *gopayApple.TransactionInfo       `json:"transactionInfo,omitempty"`
+	RenewalInfo     *gopayApple.RenewalInfo           `json:"renewalInfo,omitempty"`
+}
 
+// webhookEvent 把解码后的通知映射为 AppleWebhookEvent；webhook 入库与从加密归档重放共用。
+// 不设置 SignedPayloadSHA256 / DecodedPayload。
+func (n decodedNotification) webhookEvent() *AppleWebhookEvent {
+	payload := n.Notification
+	ev := &AppleWebhookEvent{
+		NotificationUUID: payload.NotificationUUID,
+		NotificationType: payload.NotificationType,
+		Subtype:          payload.Subtype,
+	}
+	if payload.Data != nil {
+		ev.BundleID = payload.Data.BundleID
+		ev.Environment = Environment(payload.Data.Environment)
+	}
 	if payload.IssuedAt != 0 {
 		ev.NotificationCreatedAt = time.Unix(payload.IssuedAt, 0)
 	}
-
-	if tx, err := payload.DecodeTransactionInfo(); err == nil && tx != nil {
-		mapped := mapAppleTransaction(tx)
+	if n.TransactionInfo != nil {
+		mapped := mapAppleTransaction(n.TransactionInfo)
 		ev.Transaction = &mapped
 	}
-	if ri, err := payload.DecodeRenewalInfo(); err == nil && ri != nil {
-		ev.RenewalInfo = &AppleRenewalInfo{
-			AutoRenewStatus:        int(ri.AutoRenewStatus),
-			OriginalTransactionID:  ri.OriginalTransactionId,
-			AutoRenewProductID:     ri.AutoRenewProductId,
-			ExpirationIntent:       int(ri.ExpirationIntent),
-			IsInBillingRetryPeriod: ri.IsInBillingRetryPeriod,
-		}
-		if ri.GracePeriodExpiresDate > 0 {
-			t := time.UnixMilli(ri.GracePeriodExpiresDate)
-			ev.RenewalInfo.GracePeriodExpiresDate = &t
-		}
+	if n.RenewalInfo != nil {
+		ev.RenewalInfo = mapRenewalInfo(n.RenewalInfo)
 	}
-	return ev, nil
+	return ev
+}
+
+func mapRenewalInfo(ri *gopayApple.RenewalInfo) *AppleRenewalInfo {
+	out := &AppleRenewalInfo{
+		AutoRenewStatus:        int(ri.AutoRenewStatus),
+		OriginalTransactionID:  ri.OriginalTransactionId,
+		AutoRenewProductID:     ri.AutoRenewProductId,
+		ExpirationIntent:       int(ri.ExpirationIntent),
+		IsInBillingRetryPeriod: ri.IsInBillingRetryPeriod,
+	}
+	if ri.GracePeriodExpiresDate > 0 {
+		t := time.UnixMilli(ri.GracePeriodExpiresDate)
+		out.GracePeriodExpiresDate = &t
+	}
+	return out
+}
+
+// decodeSignedTransactionID 校验客户端提交的 StoreKit signed transaction（x5c 证书链 + 苹果根证 + bundle id），
+// 只取出其中的 transactionId：交易内容仍以 App Store Server API 拉取的结果为准。
+func decodeSignedTransactionID(signedTransaction, expectBundle string) (string, error) {
+	tx, err := decodeDeviceSignedTransaction(signedTransaction, expectBundle)
+	if err != nil {
+		return "", err
+	}
+	return tx.TransactionID, nil
+}
+
+// deviceSignedTransaction 是设备端 signed transaction 中用于证明持有关系的字段。
+type deviceSignedTransaction struct {
+	TransactionID           string
+	DeviceVerification      string
+	DeviceVerificationNonce string
+}
+
+// signedTransactionClaims 补充 gopay 未解析的 deviceVerification 字段；这两个字段只出现在设备端
+// StoreKit 返回的 transaction 里，App Store Server API 返回的 transaction 不带。
+type signedTransactionClaims struct {
+	gopayApple.JWSTransactionDecodedPayload
+	DeviceVerification      string `json:"deviceVerification"`
+	DeviceVerificationNonce string `json:"deviceVerificationNonce"`
+}
+
+// decodeDeviceSignedTransaction 按 decodeSignedTransactionID 同样的规则校验 signed transaction，
+// 额外取出 deviceVerification / deviceVerificationNonce。
+func decodeDeviceSignedTransaction(signedTransaction, expectBundle string) (deviceSignedTransaction, error) {
+	if !looksLikeCompactJWS(signedTransaction) {
+		return deviceSignedTransaction{}, ErrInvalidSignedTransaction
+	}
+	tx := &signedTransactionClaims{}
+	if err := gopayApple.ExtractClaims(signedTransaction, tx); err != nil {
+		return deviceSignedTransaction{}, fmt.Errorf("%w: %v", ErrInvalidSignedTransaction, err)
+	}
+	if tx.TransactionId == "" {
+		return deviceSignedTransaction{}, ErrInvalidSignedTransaction
+	}
+	if expectBundle != "" && tx.BundleId != "" && !strings.EqualFold(tx.BundleId, expectBundle) {
+		return deviceSignedTransaction{}, fmt.Errorf("%w: bundle mismatch: got %s, want %s", ErrInvalidSignedTransaction, tx.BundleId, expectBundle)
+	}
+	return deviceSignedTransaction{
+		TransactionID:           tx.TransactionId,
+		DeviceVerification:      tx.DeviceVerification,
+		DeviceVerificationNonce: tx.DeviceVerificationNonce,
+	}, nil
 }
 
 func mapAppleTransaction(tx *gopayApple.JWSTransactionDecodedPayload) AppleTransaction {
@@ -288,6 +462,8 @@ func mapAppleTransaction(tx *gopayApple.JWSTransactionDecodedPayload) AppleTrans
 		WebOrderLineItemID:    tx.WebOrderLineItemId,
 		InAppOwnershipType:    tx.InAppOwnershipType,
 		IsUpgraded:            tx.IsUpgraded,
+		Quantity:              int(tx.Quantity),
+		OfferDiscountType:     tx.OfferDiscountType,
 	}
 	if tx.PurchaseDate > 0 {
 		out.PurchaseDate = time.UnixMilli(tx.PurchaseDate)
@@ -381,3 +557,20 @@ func buildHistoryBodyMap(req NotificationHistoryRequest) map[string]any {
 	}
 	return m
 }
+
+func buildConsumptionBodyMap(info AppleConsumptionInfo) map[string]any {
+	return map[string]any{
+		"accountTenure":            info.AccountTenure,
+		"appAccountToken":          info.AppAccountToken,
+		"consumptionStatus":        info.ConsumptionStatus,
+		"customerConsented":        info.CustomerConsented,
+		"deliveryStatus":           info.DeliveryStatus,
+		"lifetimeDollarsPurchased": info.LifetimeDollarsPurchased,
+		"lifetimeDollarsRefunded":  info.LifetimeDollarsRefunded,
+		"platform":                 info.Platform,
+		"playTime":                 info.PlayTime,
+		"refundPreference":         info.RefundPreference,
+		"sampleContentProvided":    info.SampleContentProvided,
+		"userStatus":               info.UserStatus,
+	}
+}
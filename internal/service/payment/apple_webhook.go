@@ -17,6 +17,7 @@ type AppleWebhookService struct {
 	verifier AppleWebhookVerifier
 	tokens   *TokenService
 	dao      AppleIAPDAO
+	archive  *PayloadArchive
 	now      func() time.Time
 }
 
@@ -31,6 +32,12 @@ func NewAppleWebhookService(catalog *Catalog, verifier AppleWebhookVerifier, tok
 	}
 }
 
+// WithPayloadArchive 开启通知解码内容的加密归档；archive 为 nil 时不归档。返回 s 便于链式调用。
+func (s *AppleWebhookService) WithPayloadArchive(archive *PayloadArchive) *AppleWebhookService {
+	s.archive = archive
+	return s
+}
+
 // WebhookMaxBodyBytes 暴露 catalog 配置的最大 webhook body 大小，供路由层做 size guard。
 func (s *AppleWebhookService) WebhookMaxBodyBytes() int {
 	if s == nil || s.catalog == nil {
@@ -90,26 +97,68 @@ func (s *AppleWebhookService) processInTx(ctx context.Context, qtx dao.Subscript
 		insert.OriginalTransactionID = event.Transaction.OriginalTransactionID
 		insert.TransactionID = event.Transaction.TransactionID
 		insert.WebOrderLineItemID = event.Transaction.WebOrderLineItemID
+		insert.ProductID = event.Transaction.ProductID
+		insert.OfferDiscountType = event.Transaction.OfferDiscountType
+	}
+	if event.RenewalInfo != nil {
+		insert.RenewalProductID = event.RenewalInfo.AutoRenewProductID
 	}
 
 	classification := s.classifyEvent(ctx, qtx, event)
 	insert.ProcessingStatus = classification.status
 	insert.UserID = classification.userID
 	insert.ProcessingError = classification.errorMessage
+	// 开启加密归档时不写明文副本，重放改为解密该通知的归档。
+	if classification.status == model.EventStatusPendingUserBinding && (s.archive == nil || len(event.DecodedPayload) == 0) {
+		payload, err := encodeRetainedEvent(event)
+		if err != nil {
+			return fmt.Errorf("apple iap webhook: encode pending event: %w", err)
+		}
+		insert.DecodedPayload = payload
+	}
 
-	created, _, err := qtx.InsertAppleEventIfNotExists(ctx, insert)
+	created, eventID, err := qtx.InsertAppleEventIfNotExists(ctx, insert)
 	if err != nil {
 		return fmt.Errorf("apple iap webhook: insert event: %w", err)
 	}
 	if !created {
 		return nil
 	}
+	if err := s.archive.archiveNotification(ctx, qtx, eventID, classification.userID, event); err != nil {
+		return fmt.Errorf("apple iap webhook: archive payload: %w", err)
+	}
+	return s.applyClassification(ctx, qtx, eventID, event, classification)
+}
 
+// applyClassification 执行分类结果对应的业务写入；仅 PROCESSED 才会落订阅 / 家庭共享 / 一次性购买。
+//
+// 订阅 upsert 因乱序被 DAO 拒绝（ErrStaleSubscriptionUpdate）时不回滚，而是把事件改记为 IGNORED_STALE。
+func (s *AppleWebhookService) applyClassification(ctx context.Context, qtx dao.SubscriptionTx, eventID int64, event *AppleWebhookEvent, classification eventClassification) error {
 	if classification.status == model.EventStatusProcessed && classification.upsert != nil {
 		if _, err := qtx.UpsertSubscriptionWithOwnershipCheck(ctx, *classification.upsert); err != nil {
+			if errors.Is(err, dao.ErrStaleSubscriptionUpdate) {
+				return qtx.UpdateEventProcessing(ctx, eventID, model.EventStatusIgnoredStale, classification.userID, "older than current subscription state")
+			}
 			return fmt.Errorf("apple iap webhook: upsert subscription: %w", err)
 		}
 	}
+	if classification.status == model.EventStatusProcessed && classification.familyShare != nil {
+		if _, err := qtx.UpsertFamilyShareWithOwnershipCheck(ctx, *classification.familyShare); err != nil {
+			return fmt.Errorf("apple iap webhook: update family share: %w", err)
+		}
+	}
+	if classification.status == model.EventStatusProcessed && classification.purchase != nil {
+		if _, err := recordPurchase(ctx, qtx, classification.userID, event.Transaction, *classification.purchase); err != nil {
+			return fmt.Errorf("apple iap webhook: record purchase: %w", err)
+		}
+	}
+	if classification.status == model.EventStatusProcessed && classification.consumption != nil {
+		req := *classification.consumption
+		req.EventID = eventID
+		if err := qtx.InsertConsumptionRequest(ctx, req); err != nil {
+			return fmt.Errorf("apple iap webhook: record consumption request: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -119,9 +168,18 @@ type eventClassification struct {
 	userID       int64
 	errorMessage string
 	upsert       *model.SubscriptionUpsert
+	// purchase 非 nil 表示一次性购买事件，由 recordPurchase 入账 / 退款。
+	purchase *Product
+	// familyShare 非 nil 表示已认领的家庭共享订阅事件。
+	familyShare *model.AppleFamilyShareUpsert
+	// consumption 非 nil 表示 CONSUMPTION_REQUEST，记录后由 AppleConsumptionService 回复。
+	consumption *model.AppleConsumptionRequest
 }
 
 func (s *AppleWebhookService) classifyEvent(ctx context.Context, qtx dao.SubscriptionTx, event *AppleWebhookEvent) eventClassification {
+	if strings.EqualFold(event.NotificationType, "CONSUMPTION_REQUEST") {
+		return s.classifyConsumptionRequest(ctx, qtx, event)
+	}
 	tx := event.Transaction
 	if tx == nil {
 		if isKnownNotificationType(event.NotificationType) {
@@ -129,8 +187,11 @@ func (s *AppleWebhookService) classifyEvent(ctx context.Context, qtx dao.Subscri
 		}
 		return eventClassification{status: model.EventStatusIgnoredUnknownType}
 	}
-	if !tx.IsAutoRenewableSubscription() {
-		return eventClassification{status: model.EventStatusIgnoredUnknownType, errorMessage: "non-subscription transaction"}
+	if tx.ProductType() == "" {
+		return eventClassification{status: model.EventStatusIgnoredUnknownType, errorMessage: "unsupported transaction type"}
+	}
+	if tx.IsFamilyShared() && tx.ProductType() == model.ProductTypeSubscription {
+		return s.classifyFamilyShareEvent(ctx, qtx, event)
 	}
 	if strings.TrimSpace(tx.AppAccountToken) == "" {
 		return eventClassification{status: model.EventStatusPendingUserBinding, errorMessage: "missing appAccountToken"}
@@ -151,8 +212,15 @@ func (s *AppleWebhookService) classifyEvent(ctx context.Context, qtx dao.Subscri
 		}
 		return eventClassification{status: model.EventStatusPermanentFailure, userID: mapped.UserID, errorMessage: err.Error()}
 	}
+	if product.Type != tx.ProductType() {
+		return eventClassification{status: model.EventStatusIgnoredUnknownType, userID: mapped.UserID, errorMessage: "product type mismatch"}
+	}
+	if isOneTimeProduct(product) {
+		return s.classifyPurchaseEvent(ctx, qtx, event, mapped.UserID, product)
+	}
 
-	if existing, err := qtx.GetSubscriptionByOriginalTx(ctx, tx.OriginalTransactionID, tx.Environment); err == nil {
+	existing, err := qtx.GetSubscriptionByOriginalTx(ctx, tx.OriginalTransactionID, tx.Environment)
+	if err == nil {
 		if existing.UserID != mapped.UserID {
 			return eventClassification{status: model.EventStatusOwnershipConflict, userID: existing.UserID, errorMessage: "original_transaction_id owned by another user"}
 		}
@@ -164,10 +232,38 @@ func (s *AppleWebhookService) classifyEvent(ctx context.Context, qtx dao.Subscri
 		return eventClassification{status: model.EventStatusIgnoredUnknownType, userID: mapped.UserID}
 	}
 
-	upsert := buildWebhookUpsert(mapped.UserID, event, product, s.now())
+	var current *model.Subscription
+	if err == nil {
+		current = &existing
+	}
+	upsert := buildWebhookUpsert(mapped.UserID, event, product, current, s.now())
+	applyRenewalChange(&upsert, event.Subtype, event.RenewalInfo, s.catalog)
 	return eventClassification{status: model.EventStatusProcessed, userID: mapped.UserID, upsert: &upsert}
 }
 
+// classifyPurchaseEvent 处理一次性购买的通知：ONE_TIME_CHARGE 入账，REFUND / REVOKE 退款，其余忽略。
+func (s *AppleWebhookService) classifyPurchaseEvent(ctx context.Context, qtx dao.SubscriptionTx, event *AppleWebhookEvent, userID int64, product Product) eventClassification {
+	tx := event.Transaction
+	if existing, err := qtx.GetPurchaseByTransaction(ctx, tx.TransactionID, tx.Environment); err == nil {
+		if existing.UserID != userID {
+			return eventClassification{status: model.EventStatusOwnershipConflict, userID: existing.UserID, errorMessage: "transaction_id owned by another user"}
+		}
+	} else if !errors.Is(err, dao.ErrPurchaseNotFound) {
+		return eventClassification{status: model.EventStatusPermanentFailure, userID: userID, errorMessage: err.Error()}
+	}
+
+	switch strings.ToUpper(event.NotificationType) {
+	case "ONE_TIME_CHARGE":
+	case "REFUND", "REVOKE":
+		if !tx.IsRevoked() {
+			return eventClassification{status: model.EventStatusPermanentFailure, userID: userID, errorMessage: "refund without revocation date"}
+		}
+	default:
+		return eventClassification{status: model.EventStatusIgnoredUnknownType, userID: userID}
+	}
+	return eventClassification{status: model.EventStatusProcessed, userID: userID, purchase: &product}
+}
+
 func isKnownNotificationType(t string) bool {
 	switch strings.ToUpper(t) {
 	case "SUBSCRIBED", "DID_RENEW", "DID_CHANGE_RENEWAL_STATUS",
@@ -182,7 +278,8 @@ func isKnownNotificationType(t string) bool {
 }
 
 // buildWebhookUpsert 把通知 + 当前 transaction 转换成 SubscriptionUpsert，按通知类型决定 status / auto_renew。
-func buildWebhookUpsert(userID int64, event *AppleWebhookEvent, product Product, now time.Time) model.SubscriptionUpsert {
+// existing 为现有行（没有时为 nil），通知未携带 renewal info 时沿用其中的 renewal 相关字段。
+func buildWebhookUpsert(userID int64, event *AppleWebhookEvent, product Product, existing *model.Subscription, now time.Time) model.SubscriptionUpsert {
 	tx := event.Transaction
 	upsert := model.SubscriptionUpsert{
 		UserID:                userID,
@@ -206,7 +303,13 @@ func buildWebhookUpsert(userID int64, event *AppleWebhookEvent, product Product,
 		t := event.NotificationCreatedAt
 		upsert.LastNotificationCreatedAt = &t
 	}
-	if tx.IsRevoked() {
+	if existing != nil {
+		carryRenewalState(&upsert, *existing)
+	}
+	applyRenewalFailure(&upsert, event.RenewalInfo)
+	if tx.IsUpgraded {
+		closeSupersededTransaction(&upsert, tx)
+	} else if tx.IsRevoked() {
 		upsert.Status = model.SubscriptionStatusRevoked
 		if tx.RevocationDate != nil {
 			upsert.LastEventAt = *tx.RevocationDate
@@ -218,6 +321,7 @@ func buildWebhookUpsert(userID int64, event *AppleWebhookEvent, product Product,
 		upsert.Status = model.SubscriptionStatusRevoked
 	case "EXPIRED", "GRACE_PERIOD_EXPIRED":
 		upsert.Status = model.SubscriptionStatusExpired
+		upsert.BillingRetry = false
 	case "DID_CHANGE_RENEWAL_STATUS":
 		if event.RenewalInfo != nil && event.RenewalInfo.AutoRenewStatus == 0 {
 			upsert.Status = model.SubscriptionStatusCanceled
@@ -226,11 +330,12 @@ func buildWebhookUpsert(userID int64, event *AppleWebhookEvent, product Product,
 			upsert.AutoRenewStatus = model.AutoRenewStatusOn
 		}
 	case "DID_FAIL_TO_RENEW":
+		upsert.BillingRetry = true
 		if event.RenewalInfo != nil && event.RenewalInfo.GracePeriodExpiresDate != nil {
 			grace := *event.RenewalInfo.GracePeriodExpiresDate
 			upsert.GracePeriodExpiresAt = &grace
 		}
-	case "SUBSCRIBED", "DID_RENEW", "RENEWAL_EXTENDED":
+	case "SUBSCRIBED", "DID_RENEW", "RENEWAL_EXTENDED", "DID_CHANGE_RENEWAL_PREF":
 		// fall through with ACTIVE / ON
 		if event.RenewalInfo != nil {
 			if event.RenewalInfo.AutoRenewStatus == 0 {
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
 	"github.com/dundunHa/go-serverhttp-template/internal/model"
 )
 
@@ -147,6 +148,62 @@ func TestAppleWebhookService_RefundMarksRevoked(t *testing.T) {
 	}
 }
 
+func TestAppleWebhookService_DelistedProductStillRenews(t *testing.T) {
+	catalog := newProdCatalog(t)
+	productDAO := &fakeAppleProductDAO{rows: []model.AppleProduct{{
+		ID: 1, PlanID: "pro_monthly", ProductID: "com.app.pro.monthly", Environment: EnvProduction,
+		Type: model.ProductTypeSubscription, Level: 1, SubscriptionGroupID: "21456789", Active: false,
+	}}}
+	if err := NewProductCatalogService(catalog, productDAO).Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	now := time.Now().UTC()
+	tok := "00000000-0000-4000-8000-000000000079"
+	tx := &AppleTransaction{
+		TransactionID: "tx-renew", OriginalTransactionID: "ot-delisted",
+		AppAccountToken: tok, BundleID: "com.app.example",
+		Environment: EnvProduction, ProductID: "com.app.pro.monthly",
+		Type:         "Auto-Renewable Subscription",
+		PurchaseDate: now, ExpiresDate: now.Add(30 * 24 * time.Hour),
+	}
+	d := &fakeIAPDAO{}
+	svc := NewAppleWebhookService(catalog, &fakeWebhookVerifier{event: makeEvent("DID_RENEW", "", tx)}, newTokensWithFakeDAO(79, tok), d)
+	if err := svc.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("renew: %v", err)
+	}
+	if len(d.events) != 1 || d.events[0].ProcessingStatus != model.EventStatusProcessed {
+		t.Fatalf("delisted renewal must not be ignored: %+v", d.events)
+	}
+	if len(d.upserts) != 1 || d.upserts[0].PlanID != "pro_monthly" || !d.upserts[0].CurrentPeriodEnd.Equal(tx.ExpiresDate) ||
+		d.upserts[0].Status != model.SubscriptionStatusActive {
+		t.Fatalf("delisted renewal must extend the subscription: %+v", d.upserts)
+	}
+}
+
+func TestAppleWebhookService_StaleEventIgnored(t *testing.T) {
+	now := time.Now().UTC()
+	tok := "00000000-0000-4000-8000-000000000078"
+	tx := &AppleTransaction{
+		TransactionID: "tx-old", OriginalTransactionID: "ot-stale",
+		AppAccountToken: tok, BundleID: "com.app.example",
+		Environment: EnvProduction, ProductID: "com.app.pro.monthly",
+		Type:         "Auto-Renewable Subscription",
+		PurchaseDate: now.Add(-60 * 24 * time.Hour), ExpiresDate: now.Add(-30 * 24 * time.Hour),
+	}
+	verifier := &fakeWebhookVerifier{event: makeEvent("DID_RENEW", "", tx)}
+	d := &fakeIAPDAO{commitFn: func(model.SubscriptionUpsert) (model.Subscription, error) {
+		return model.Subscription{}, dao.ErrStaleSubscriptionUpdate
+	}}
+	svc := NewAppleWebhookService(newProdCatalog(t), verifier, newTokensWithFakeDAO(78, tok), d)
+	if err := svc.HandleSignedPayload(context.Background(), "abc.def.ghi"); err != nil {
+		t.Fatalf("stale event must be acked: %v", err)
+	}
+	if d.eventUpdates[1] != model.EventStatusIgnoredStale {
+		t.Fatalf("event status = %q, want IGNORED_STALE", d.eventUpdates[1])
+	}
+}
+
 func TestAppleWebhookService_DidChangeRenewalOff(t *testing.T) {
 	now := time.Now().UTC()
 	tok := "00000000-0000-4000-8000-000000000088"
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/dundunHa/go-serverhttp-template/internal/config"
@@ -25,12 +26,68 @@ const (
 	EnvSandbox    = model.AppleEnvSandbox
 )
 
+// Product 是 catalog 中的一个可售商品。
+//
+// Type 缺省为 subscription（兼容只配置订阅的旧 catalog）；consumable 必须配置 Credits（每份发放的积分），
+// non_consumable 的 Level 表示买断后获得的终身权益等级。
+// DisplayName / Description / SortOrder / Localizations / Recommended 只用于付费墙展示；Localizations 只读，不要修改。
+// PriceMilliunits / Currency / BillingPeriod 是收入报表用的参考价格（单位为 currency 的千分之一），不影响实际售价。
 type Product struct {
 	PlanID              string      `json:"plan_id"`
 	ProductID           string      `json:"product_id"`
+	Type                string      `json:"type,omitempty"`
 	Level               int         `json:"level"`
+	Credits             int64       `json:"credits,omitempty"`
 	Environment         Environment `json:"environment"`
 	SubscriptionGroupID string      `json:"subscription_group_id,omitempty"`
+	DisplayName         string      `json:"display_name,omitempty"`
+	Description         string      `json:"description,omitempty"`
+	SortOrder           int         `json:"sort_order,omitempty"`
+	// Localizations 以 BCP 47 语言标签为 key。
+	Localizations map[string]model.ProductLocalization `json:"localizations,omitempty"`
+	Recommended   bool                                 `json:"recommended,omitempty"`
+
+	PriceMilliunits int64  `json:"price_milliunits,omitempty"`
+	Currency        string `json:"currency,omitempty"`
+	BillingPeriod   string `json:"billing_period,omitempty"`
+
+	// Delisted 表示商品已在 apple_products 中下架：仍可解析已有订阅与购买，但不再出现在 paywall。
+	Delisted bool `json:"-"`
+}
+
+// billingPeriodMonths 是支持的订阅计费周期（ISO 8601）对应的月数。
+var billingPeriodMonths = map[string]float64{
+	"P1W": 12.0 / 52,
+	"P1M": 1,
+	"P2M": 2,
+	"P3M": 3,
+	"P6M": 6,
+	"P1Y": 12,
+}
+
+// MonthlyPriceMilliunits 把参考价格折算为每月金额（currency 的千分之一），用于 MRR；未定价或非订阅商品返回 0。
+func (p Product) MonthlyPriceMilliunits() float64 {
+	months := billingPeriodMonths[p.BillingPeriod]
+	if p.PriceMilliunits <= 0 || months == 0 {
+		return 0
+	}
+	return float64(p.PriceMilliunits) / months
+}
+
+// Offer 是一条 StoreKit 订阅优惠规则（APPLE_IAP_OFFERS）。
+//
+// OfferID 是 App Store Connect 中配置的优惠 identifier；Type 缺省为 promotional。
+// MaxPerUser 限制同一用户可获得的签名次数，0 表示不限。
+type Offer struct {
+	OfferID    string `json:"offer_id"`
+	ProductID  string `json:"product_id"`
+	Type       string `json:"type,omitempty"`
+	MaxPerUser int    `json:"max_per_user,omitempty"`
+}
+
+type offerKey struct {
+	ProductID string
+	OfferID   string
 }
 
 type catalogKey struct {
@@ -38,13 +95,22 @@ type catalogKey struct {
 	Environment Environment
 }
 
-// Catalog 是 Apple IAP 配置的不可变运行期视图。
-// 构造完成后所有字段只读；通过 NewCatalog 校验配置并填充。
+// productSet 是一份校验过的商品列表及其索引；构造后只读，整体替换。
+type productSet struct {
+	products       []Product
+	byProductIDEnv map[catalogKey]Product
+}
+
+// Catalog 是 Apple IAP 配置的运行期视图，通过 NewCatalog 校验配置并填充。
+//
+// 除商品列表外所有字段构造后只读。商品列表可由 ReplaceProducts 整体原子替换（数据库管理的 catalog
+// 热加载），读方总是看到某一份完整的商品列表；APPLE_IAP_PRODUCTS 解析出的商品作为种子与回退。
 //
 // 不要在 catalog 之外读取环境变量；catalog 是唯一的事实源。
 type Catalog struct {
-	products              []Product
-	byProductIDEnv        map[catalogKey]Product
+	seed                  *productSet
+	current               atomic.Pointer[productSet]
+	offers                map[offerKey]Offer
 	allowedEntitlement    map[Environment]struct{}
 	entitlementOrder      []Environment
 	enableSandboxFallback bool
@@ -119,14 +185,19 @@ func NewCatalog(cfg config.AppleIAPConfig, appEnv string) (*Catalog, error) {
 		return nil, ErrNotConfigured
 	}
 
+	offers, err := parseOffers(cfg.Offers, products)
+	if err != nil {
+		return nil, err
+	}
+
 	pem, err := resolvePrivateKey(cfg, appEnv)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Catalog{
-		products:              products,
-		byProductIDEnv:        byKey,
+	c := &Catalog{
+		seed:                  &productSet{products: products, byProductIDEnv: byKey},
+		offers:                offers,
 		allowedEntitlement:    allowed,
 		entitlementOrder:      order,
 		enableSandboxFallback: cfg.EnableSandboxFallback,
@@ -137,7 +208,9 @@ func NewCatalog(cfg config.AppleIAPConfig, appEnv string) (*Catalog, error) {
 		issuerID:              cfg.IssuerID,
 		keyID:                 cfg.KeyID,
 		privateKeyPEM:         pem,
-	}, nil
+	}
+	c.current.Store(c.seed)
+	return c, nil
 }
 
 func parseEntitlementEnvironments(raw string) (map[Environment]struct{}, []Environment, error) {
@@ -177,21 +250,144 @@ func parseProducts(raw string) ([]Product, map[catalogKey]Product, error) {
 		return nil, nil, fmt.Errorf("parse APPLE_IAP_PRODUCTS json: %w", joinInvalidConfig(err))
 	}
 
+	byKey, err := indexProducts("APPLE_IAP_PRODUCTS", products)
+	if err != nil {
+		return nil, nil, err
+	}
+	return products, byKey, nil
+}
+
+// indexProducts 校验 products 并建立 (product_id, environment) 索引；products 中缺省的 type 会被原地补齐。
+// source 只用于错误信息。
+func indexProducts(source string, products []Product) (map[catalogKey]Product, error) {
 	byKey := make(map[catalogKey]Product, len(products))
 	for i, p := range products {
-		if p.PlanID == "" || p.ProductID == "" || p.Environment == "" {
-			return nil, nil, fmt.Errorf("APPLE_IAP_PRODUCTS[%d]: plan_id, product_id, environment required: %w", i, ErrInvalidConfig)
-		}
-		if p.Environment != EnvProduction && p.Environment != EnvSandbox {
-			return nil, nil, fmt.Errorf("APPLE_IAP_PRODUCTS[%d]: unknown environment %q: %w", i, p.Environment, ErrInvalidConfig)
+		if err := validateProduct(&p); err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", source, i, err)
 		}
+		products[i] = p
 		key := catalogKey{ProductID: p.ProductID, Environment: p.Environment}
 		if _, dup := byKey[key]; dup {
-			return nil, nil, fmt.Errorf("APPLE_IAP_PRODUCTS[%d]: duplicate %s/%s: %w", i, p.ProductID, p.Environment, errors.Join(ErrDuplicateProduct, ErrInvalidConfig))
+			return nil, fmt.Errorf("%s[%d]: duplicate %s/%s: %w", source, i, p.ProductID, p.Environment, errors.Join(ErrDuplicateProduct, ErrInvalidConfig))
 		}
 		byKey[key] = p
 	}
-	return products, byKey, nil
+	return byKey, nil
+}
+
+// validateProduct 校验单个 Apple 商品的必填字段、环境与类型组合。
+func validateProduct(p *Product) error {
+	if p.PlanID == "" || p.ProductID == "" || p.Environment == "" {
+		return fmt.Errorf("plan_id, product_id, environment required: %w", ErrInvalidConfig)
+	}
+	if p.Environment != EnvProduction && p.Environment != EnvSandbox {
+		return fmt.Errorf("unknown environment %q: %w", p.Environment, ErrInvalidConfig)
+	}
+	if p.Level < 0 {
+		return fmt.Errorf("negative level: %w", ErrInvalidConfig)
+	}
+	for locale, l := range p.Localizations {
+		if strings.TrimSpace(locale) == "" || strings.TrimSpace(l.Title) == "" {
+			return fmt.Errorf("localizations %q: locale and title required: %w", locale, ErrInvalidConfig)
+		}
+	}
+	if err := normalizeProductType(p); err != nil {
+		return err
+	}
+	return validateProductPrice(p)
+}
+
+// validateProductPrice 校验参考价格：定价时必须给出三位币种代码，订阅还必须给出计费周期。
+func validateProductPrice(p *Product) error {
+	p.Currency = strings.ToUpper(strings.TrimSpace(p.Currency))
+	p.BillingPeriod = strings.ToUpper(strings.TrimSpace(p.BillingPeriod))
+	if p.PriceMilliunits < 0 {
+		return fmt.Errorf("negative price_milliunits: %w", ErrInvalidConfig)
+	}
+	if p.BillingPeriod != "" {
+		if p.Type != model.ProductTypeSubscription {
+			return fmt.Errorf("billing_period is only valid for subscriptions: %w", ErrInvalidConfig)
+		}
+		if _, ok := billingPeriodMonths[p.BillingPeriod]; !ok {
+			return fmt.Errorf("unknown billing_period %q: %w", p.BillingPeriod, ErrInvalidConfig)
+		}
+	}
+	if p.PriceMilliunits == 0 {
+		return nil
+	}
+	if len(p.Currency) != 3 {
+		return fmt.Errorf("priced product requires a 3-letter currency: %w", ErrInvalidConfig)
+	}
+	if p.Type == model.ProductTypeSubscription && p.BillingPeriod == "" {
+		return fmt.Errorf("priced subscription requires billing_period: %w", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// parseOffers 解析 APPLE_IAP_OFFERS；每条优惠必须指向 catalog 中的订阅商品。
+func parseOffers(raw string, products []Product) (map[offerKey]Offer, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return nil, nil
+	}
+	var offers []Offer
+	dec := json.NewDecoder(strings.NewReader(trimmed))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&offers); err != nil {
+		return nil, fmt.Errorf("parse APPLE_IAP_OFFERS json: %w", joinInvalidConfig(err))
+	}
+
+	subscriptions := make(map[string]struct{}, len(products))
+	for _, p := range products {
+		if p.Type == model.ProductTypeSubscription {
+			subscriptions[p.ProductID] = struct{}{}
+		}
+	}
+	out := make(map[offerKey]Offer, len(offers))
+	for i, o := range offers {
+		if o.OfferID == "" || o.ProductID == "" {
+			return nil, fmt.Errorf("APPLE_IAP_OFFERS[%d]: offer_id, product_id required: %w", i, ErrInvalidConfig)
+		}
+		if o.Type == "" {
+			o.Type = model.OfferTypePromotional
+		}
+		if o.Type != model.OfferTypePromotional && o.Type != model.OfferTypeWinBack {
+			return nil, fmt.Errorf("APPLE_IAP_OFFERS[%d]: unknown type %q: %w", i, o.Type, ErrInvalidConfig)
+		}
+		if o.MaxPerUser < 0 {
+			return nil, fmt.Errorf("APPLE_IAP_OFFERS[%d]: negative max_per_user: %w", i, ErrInvalidConfig)
+		}
+		if _, ok := subscriptions[o.ProductID]; !ok {
+			return nil, fmt.Errorf("APPLE_IAP_OFFERS[%d]: %s is not a subscription in APPLE_IAP_PRODUCTS: %w", i, o.ProductID, ErrInvalidConfig)
+		}
+		key := offerKey{ProductID: o.ProductID, OfferID: o.OfferID}
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("APPLE_IAP_OFFERS[%d]: duplicate %s/%s: %w", i, o.ProductID, o.OfferID, ErrInvalidConfig)
+		}
+		out[key] = o
+	}
+	return out, nil
+}
+
+// normalizeProductType 补齐缺省的商品类型（subscription）并校验 type 与 credits 的组合；
+// Apple 与 Google Play 的 catalog 共用同一套规则。
+func normalizeProductType(p *Product) error {
+	if p.Type == "" {
+		p.Type = model.ProductTypeSubscription
+	}
+	switch p.Type {
+	case model.ProductTypeSubscription, model.ProductTypeNonConsumable:
+		if p.Credits != 0 {
+			return fmt.Errorf("credits only allowed for consumable products: %w", ErrInvalidConfig)
+		}
+	case model.ProductTypeConsumable:
+		if p.Credits <= 0 {
+			return fmt.Errorf("consumable requires positive credits: %w", ErrInvalidConfig)
+		}
+	default:
+		return fmt.Errorf("unknown type %q: %w", p.Type, ErrInvalidConfig)
+	}
+	return nil
 }
 
 func resolvePrivateKey(cfg config.AppleIAPConfig, appEnv string) (string, error) {
@@ -225,13 +421,32 @@ func (c *Catalog) Lookup(productID string, env Environment) (Product, error) {
 	if c == nil {
 		return Product{}, ErrNotConfigured
 	}
-	p, ok := c.byProductIDEnv[catalogKey{ProductID: productID, Environment: env}]
+	p, ok := c.current.Load().byProductIDEnv[catalogKey{ProductID: productID, Environment: env}]
 	if !ok {
 		return Product{}, ErrUnknownProduct
 	}
 	return p, nil
 }
 
+// LookupOffer 按 (productID, offerID) 查找优惠规则及其订阅商品；商品按授权环境的声明顺序取第一个匹配项。
+// 未配置该优惠返回 ErrUnknownOffer。
+func (c *Catalog) LookupOffer(productID, offerID string) (Offer, Product, error) {
+	if c == nil {
+		return Offer{}, Product{}, ErrNotConfigured
+	}
+	o, ok := c.offers[offerKey{ProductID: productID, OfferID: offerID}]
+	if !ok {
+		return Offer{}, Product{}, ErrUnknownOffer
+	}
+	set := c.current.Load()
+	for _, env := range c.entitlementOrder {
+		if p, ok := set.byProductIDEnv[catalogKey{ProductID: productID, Environment: env}]; ok {
+			return o, p, nil
+		}
+	}
+	return Offer{}, Product{}, ErrUnknownOffer
+}
+
 // IsEntitlementEnvironment 检查 env 是否在允许授权的白名单内。
 func (c *Catalog) IsEntitlementEnvironment(env Environment) bool {
 	if c == nil {
@@ -252,14 +467,50 @@ func (c *Catalog) StoreRawPayloads() bool         { return c.storeRawPayloads }
 
 // Products 返回 catalog 内产品的拷贝，调用方可自由修改返回切片而不影响内部状态。
 func (c *Catalog) Products() []Product {
-	if c == nil || len(c.products) == 0 {
+	if c == nil {
+		return nil
+	}
+	set := c.current.Load()
+	if len(set.products) == 0 {
+		return nil
+	}
+	out := make([]Product, len(set.products))
+	copy(out, set.products)
+	return out
+}
+
+// SeedProducts 返回 APPLE_IAP_PRODUCTS 解析出的商品拷贝，用于初始化数据库 catalog。
+func (c *Catalog) SeedProducts() []Product {
+	if c == nil || len(c.seed.products) == 0 {
 		return nil
 	}
-	out := make([]Product, len(c.products))
-	copy(out, c.products)
+	out := make([]Product, len(c.seed.products))
+	copy(out, c.seed.products)
 	return out
 }
 
+// ReplaceProducts 校验 products 后原子替换当前商品列表；校验失败时保持原列表并返回 wrap ErrInvalidConfig 的错误。
+// products 为空时回退到 APPLE_IAP_PRODUCTS 种子，保证 catalog 永远不会为空。
+//
+// 正在进行的 Lookup 看到的是替换前或替换后的完整列表，不会看到中间状态。
+func (c *Catalog) ReplaceProducts(products []Product) error {
+	if c == nil {
+		return ErrNotConfigured
+	}
+	if len(products) == 0 {
+		c.current.Store(c.seed)
+		return nil
+	}
+	next := make([]Product, len(products))
+	copy(next, products)
+	byKey, err := indexProducts("apple_products", next)
+	if err != nil {
+		return err
+	}
+	c.current.Store(&productSet{products: next, byProductIDEnv: byKey})
+	return nil
+}
+
 // AllowedEntitlementEnvironments 返回声明顺序保留的允许授权环境列表的拷贝。
 func (c *Catalog) AllowedEntitlementEnvironments() []Environment {
 	if c == nil || len(c.entitlementOrder) == 0 {
@@ -159,6 +159,48 @@ func TestNewCatalog_FailingMatrix(t *testing.T) {
 			},
 			wantErr: ErrInvalidConfig,
 		},
+		{
+			name: "consumable without credits returns ErrInvalidConfig",
+			mutate: func(c *config.AppleIAPConfig) {
+				c.Products = `[{"plan_id":"credits_100","product_id":"com.app.credits.100","type":"consumable","environment":"Production"}]`
+			},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name: "credits on subscription returns ErrInvalidConfig",
+			mutate: func(c *config.AppleIAPConfig) {
+				c.Products = `[{"plan_id":"pro_monthly","product_id":"com.app.pro.monthly","level":1,"credits":100,"environment":"Production"}]`
+			},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name: "unknown product type returns ErrInvalidConfig",
+			mutate: func(c *config.AppleIAPConfig) {
+				c.Products = `[{"plan_id":"pro_monthly","product_id":"com.app.pro.monthly","type":"non_renewing","environment":"Production"}]`
+			},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name: "priced subscription without billing period returns ErrInvalidConfig",
+			mutate: func(c *config.AppleIAPConfig) {
+				c.Products = `[{"plan_id":"pro_monthly","product_id":"com.app.pro.monthly","level":1,"price_milliunits":9990,"currency":"USD","environment":"Production"}]`
+			},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name: "price without currency returns ErrInvalidConfig",
+			mutate: func(c *config.AppleIAPConfig) {
+				c.Products = `[{"plan_id":"pro_monthly","product_id":"com.app.pro.monthly","level":1,"price_milliunits":9990,"billing_period":"P1M","environment":"Production"}]`
+			},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name: "billing period on consumable returns ErrInvalidConfig",
+			mutate: func(c *config.AppleIAPConfig) {
+				c.Products = `[{"plan_id":"credits_100","product_id":"com.app.credits.100","type":"consumable","credits":100,"billing_period":"P1M","environment":"Production"}]`
+			},
+			wantErr: ErrInvalidConfig,
+		},
 		{
 			name:   "production with P8Path and no PrivateKey returns ErrInvalidConfig",
 			appEnv: AppEnvProd,
@@ -168,6 +210,27 @@ func TestNewCatalog_FailingMatrix(t *testing.T) {
 			},
 			wantErr: ErrInvalidConfig,
 		},
+		{
+			name: "malformed offers json returns ErrInvalidConfig",
+			mutate: func(c *config.AppleIAPConfig) {
+				c.Offers = `[{`
+			},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name: "offer for product outside catalog returns ErrInvalidConfig",
+			mutate: func(c *config.AppleIAPConfig) {
+				c.Offers = `[{"offer_id":"winback_50","product_id":"com.unknown.sku"}]`
+			},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name: "offer with unknown type returns ErrInvalidConfig",
+			mutate: func(c *config.AppleIAPConfig) {
+				c.Offers = `[{"offer_id":"winback_50","product_id":"com.app.pro.monthly","type":"intro"}]`
+			},
+			wantErr: ErrInvalidConfig,
+		},
 	}
 
 	for _, tc := range cases {
@@ -399,3 +462,44 @@ func TestNewCatalog_FullyEmptyReturnsNotConfigured(t *testing.T) {
 		t.Fatalf("expected ErrNotConfigured, got %v", err)
 	}
 }
+
+func TestCatalog_ReplaceProducts(t *testing.T) {
+	t.Parallel()
+
+	cat, err := NewCatalog(validProdConfig(), AppEnvProd)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	next := []Product{
+		{PlanID: "pro_yearly", ProductID: "com.app.pro.yearly", Level: 2, Environment: EnvProduction, DisplayName: "Pro 年度"},
+	}
+	if err := cat.ReplaceProducts(next); err != nil {
+		t.Fatalf("ReplaceProducts: %v", err)
+	}
+	got, err := cat.Lookup("com.app.pro.yearly", EnvProduction)
+	if err != nil || got.Type != "subscription" || got.DisplayName != "Pro 年度" {
+		t.Fatalf("Lookup replaced product = %#v, err=%v", got, err)
+	}
+	if _, err := cat.Lookup("com.app.pro.monthly", EnvProduction); !errors.Is(err, ErrUnknownProduct) {
+		t.Fatalf("replaced-out product must be unknown, err=%v", err)
+	}
+
+	invalid := []Product{
+		{PlanID: "a", ProductID: "com.app.a", Environment: EnvProduction},
+		{PlanID: "b", ProductID: "com.app.a", Environment: EnvProduction},
+	}
+	if err := cat.ReplaceProducts(invalid); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("duplicate products err = %v, want ErrInvalidConfig", err)
+	}
+	if _, err := cat.Lookup("com.app.pro.yearly", EnvProduction); err != nil {
+		t.Fatalf("invalid replacement must keep the current products, err=%v", err)
+	}
+
+	if err := cat.ReplaceProducts(nil); err != nil {
+		t.Fatalf("ReplaceProducts(nil): %v", err)
+	}
+	if _, err := cat.Lookup("com.app.pro.monthly", EnvProduction); err != nil {
+		t.Fatalf("empty replacement must fall back to APPLE_IAP_PRODUCTS, err=%v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package payment
+
+import (
+	"context"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/entitlement"
+)
+
+// AppleEntitlementSource 把 Apple 自动续期订阅与非消耗型买断作为 entitlement 来源。
+//
+// 仅读取 catalog.AllowedEntitlementEnvironments 中的环境；catalog 为 nil 时没有任何权益。
+type AppleEntitlementSource struct {
+	dao     dao.SubscriptionDAO
+	catalog *Catalog
+}
+
+// NewAppleEntitlementSource 构造 Apple 来源；dao 或 catalog 为 nil 时返回 nil，engine 会忽略它。
+func NewAppleEntitlementSource(d dao.SubscriptionDAO, catalog *Catalog) entitlement.Source {
+	if d == nil || catalog == nil {
+		return nil
+	}
+	return &AppleEntitlementSource{dao: d, catalog: catalog}
+}
+
+// Grants 返回该用户的全部 Apple 订阅行（含已终止的）、未退款的买断，以及认领的家庭共享订阅。
+func (s *AppleEntitlementSource) Grants(ctx context.Context, userID int64, now time.Time) ([]model.EntitlementGrant, error) {
+	envs := s.catalog.AllowedEntitlementEnvironments()
+	if len(envs) == 0 {
+		return nil, nil
+	}
+	rows, err := s.dao.ListSubscriptionsForUserEntitlement(ctx, userID, envs)
+	if err != nil {
+		return nil, err
+	}
+	grants := subscriptionGrants(model.EntitlementSourceApple, rows, now)
+	purchases, err := s.dao.ListActiveLifetimePurchases(ctx, userID, envs)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range purchases {
+		grants = append(grants, lifetimeGrant(model.EntitlementSourceApple, p.PlanID, p.Level))
+	}
+	shares, err := s.dao.ListFamilySharesForUserEntitlement(ctx, userID, envs)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]model.Subscription, 0, len(shares))
+	for _, share := range shares {
+		views = append(views, familyShareView(share))
+	}
+	return append(grants, subscriptionGrants(model.EntitlementSourceAppleFamily, views, now)...), nil
+}
+
+// GooglePlayEntitlementSource 把 Google Play 订阅与买断作为 entitlement 来源。
+type GooglePlayEntitlementSource struct {
+	dao dao.GooglePlayDAO
+}
+
+// NewGooglePlayEntitlementSource 构造 Google Play 来源；dao 为 nil 时返回 nil。
+func NewGooglePlayEntitlementSource(d dao.GooglePlayDAO) entitlement.Source {
+	if d == nil {
+		return nil
+	}
+	return &GooglePlayEntitlementSource{dao: d}
+}
+
+func (s *GooglePlayEntitlementSource) Grants(ctx context.Context, userID int64, now time.Time) ([]model.EntitlementGrant, error) {
+	rows, err := s.dao.ListSubscriptionsForUserEntitlement(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]model.Subscription, 0, len(rows))
+	for _, row := range rows {
+		views = append(views, googlePlaySubscriptionView(row))
+	}
+	grants := subscriptionGrants(model.EntitlementSourceGooglePlay, views, now)
+	purchases, err := s.dao.ListActiveLifetimePurchases(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range purchases {
+		grants = append(grants, lifetimeGrant(model.EntitlementSourceGooglePlay, p.PlanID, p.Level))
+	}
+	return grants, nil
+}
+
+// StripeEntitlementSource 把 Stripe web 订阅作为 entitlement 来源。
+type StripeEntitlementSource struct {
+	dao dao.StripeDAO
+}
+
+// NewStripeEntitlementSource 构造 Stripe 来源；dao 为 nil 时返回 nil。
+func NewStripeEntitlementSource(d dao.StripeDAO) entitlement.Source {
+	if d == nil {
+		return nil
+	}
+	return &StripeEntitlementSource{dao: d}
+}
+
+func (s *StripeEntitlementSource) Grants(ctx context.Context, userID int64, now time.Time) ([]model.EntitlementGrant, error) {
+	rows, err := s.dao.ListSubscriptionsForUserEntitlement(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]model.Subscription, 0, len(rows))
+	for _, row := range rows {
+		views = append(views, stripeSubscriptionView(row))
+	}
+	return subscriptionGrants(model.EntitlementSourceStripe, views, now), nil
+}
+
+// subscriptionGrants 按 APIStatusForSubscription 把订阅行映射为权益；终止行保留为 EXPIRED，
+// 供 engine 区分 EXPIRED 与 NONE。
+func subscriptionGrants(source string, rows []model.Subscription, now time.Time) []model.EntitlementGrant {
+	out := make([]model.EntitlementGrant, 0, len(rows))
+	for _, sub := range rows {
+		g := model.EntitlementGrant{
+			Source:        source,
+			PlanID:        sub.PlanID,
+			Level:         sub.Level,
+			Status:        APIStatusForSubscription(sub, now),
+			PendingPlanID: sub.PendingPlanID,
+			PendingLevel:  sub.PendingLevel,
+		}
+		if !sub.CurrentPeriodEnd.IsZero() {
+			end := sub.CurrentPeriodEnd.UTC()
+			g.ExpiresAt = &end
+		}
+		switch g.Status {
+		case "GRACE_PERIOD":
+			grace := sub.GracePeriodExpiresAt.UTC()
+			g.GracePeriodExpiresAt = &grace
+		case "EXPIRED", "BILLING_RETRY":
+			g.ExpirationReason = expirationReason(sub.ExpirationIntent)
+		}
+		out = append(out, g)
+	}
+	return out
+}
+
+func lifetimeGrant(source, planID string, level int) model.EntitlementGrant {
+	return model.EntitlementGrant{Source: source, PlanID: planID, Level: level, Status: "ACTIVE"}
+}
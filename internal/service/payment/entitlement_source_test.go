@@ -8,15 +8,18 @@ import (
 
 	"github.com/dundunHa/go-serverhttp-template/internal/dao"
 	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	"github.com/dundunHa/go-serverhttp-template/internal/service/entitlement"
 )
 
 type readerDAO struct {
-	rows    []model.Subscription
-	listErr error
+	rows      []model.Subscription
+	lifetimes []model.Purchase
+	shares    []model.AppleFamilyShare
+	listErr   error
 }
 
-func (d *readerDAO) GetOrCreateAccountToken(_ context.Context, _ int64) (string, error) {
-	return "", nil
+func (d *readerDAO) GetOrCreateAccountToken(_ context.Context, _ int64) (string, bool, error) {
+	return "", false, nil
 }
 func (d *readerDAO) GetAccountTokenByToken(_ context.Context, _ string) (model.AppleAccountToken, error) {
 	return model.AppleAccountToken{}, nil
@@ -30,35 +33,43 @@ func (d *readerDAO) ListSubscriptionsForUserEntitlement(_ context.Context, _ int
 	}
 	return d.rows, nil
 }
+func (d *readerDAO) ListActiveLifetimePurchases(_ context.Context, _ int64, _ []model.AppleEnvironment) ([]model.Purchase, error) {
+	return d.lifetimes, nil
+}
+func (d *readerDAO) ListFamilySharesForUserEntitlement(_ context.Context, _ int64, _ []model.AppleEnvironment) ([]model.AppleFamilyShare, error) {
+	return d.shares, nil
+}
 func (d *readerDAO) InTx(_ context.Context, _ func(dao.SubscriptionTx) error) error {
 	return errors.New("not used in reader tests")
 }
 
-func TestSubscriptionReader_NoneWhenReaderNil(t *testing.T) {
-	var r *SubscriptionReader
-	info, err := r.LoadSubscriptionInfo(context.Background(), 1)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if info.Status != "NONE" {
-		t.Fatalf("status = %q, want NONE", info.Status)
+// resolveInfo 按 engine 的方式汇总来源的权益并返回 /users/me 的订阅视图。
+func resolveInfo(t testing.TB, userID int64, now time.Time, sources ...entitlement.Source) model.SubscriptionInfo {
+	t.Helper()
+	var grants []model.EntitlementGrant
+	for _, s := range sources {
+		if s == nil {
+			continue
+		}
+		g, err := s.Grants(context.Background(), userID, now)
+		if err != nil {
+			t.Fatalf("grants: %v", err)
+		}
+		grants = append(grants, g...)
 	}
+	return entitlement.Resolve(grants, nil).Subscription
 }
 
-func TestSubscriptionReader_NoneWhenDAOEmpty(t *testing.T) {
+func TestAppleEntitlementSource_NoneWhenDAOEmpty(t *testing.T) {
 	c := newProdCatalog(t)
 	d := &readerDAO{}
-	r := NewSubscriptionReader(d, c)
-	info, err := r.LoadSubscriptionInfo(context.Background(), 1)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
+	info := resolveInfo(t, 1, time.Now().UTC(), NewAppleEntitlementSource(d, c))
 	if info.Status != "NONE" {
 		t.Fatalf("status = %q, want NONE", info.Status)
 	}
 }
 
-func TestSubscriptionReader_StatusActive(t *testing.T) {
+func TestAppleEntitlementSource_StatusActive(t *testing.T) {
 	c := newProdCatalog(t)
 	now := time.Now().UTC()
 	d := &readerDAO{rows: []model.Subscription{{
@@ -69,18 +80,13 @@ func TestSubscriptionReader_StatusActive(t *testing.T) {
 		CurrentPeriodEnd: now.Add(48 * time.Hour),
 		LastEventAt:      now,
 	}}}
-	r := NewSubscriptionReader(d, c)
-	r.now = func() time.Time { return now }
-	info, err := r.LoadSubscriptionInfo(context.Background(), 1)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
+	info := resolveInfo(t, 1, now, NewAppleEntitlementSource(d, c))
 	if info.Status != "ACTIVE" || info.ProductID != "pro_monthly" || info.SubscribeLevel != 1 {
 		t.Fatalf("unexpected info: %+v", info)
 	}
 }
 
-func TestSubscriptionReader_StatusCanceledStillEntitled(t *testing.T) {
+func TestAppleEntitlementSource_StatusCanceledStillEntitled(t *testing.T) {
 	c := newProdCatalog(t)
 	now := time.Now().UTC()
 	d := &readerDAO{rows: []model.Subscription{{
@@ -91,15 +97,13 @@ func TestSubscriptionReader_StatusCanceledStillEntitled(t *testing.T) {
 		CurrentPeriodEnd: now.Add(48 * time.Hour),
 		LastEventAt:      now,
 	}}}
-	r := NewSubscriptionReader(d, c)
-	r.now = func() time.Time { return now }
-	info, _ := r.LoadSubscriptionInfo(context.Background(), 1)
+	info := resolveInfo(t, 1, now, NewAppleEntitlementSource(d, c))
 	if info.Status != "CANCELED" || info.SubscribeLevel != 1 {
 		t.Fatalf("unexpected info: %+v", info)
 	}
 }
 
-func TestSubscriptionReader_StatusExpiredZeroLevel(t *testing.T) {
+func TestAppleEntitlementSource_StatusExpiredZeroLevel(t *testing.T) {
 	c := newProdCatalog(t)
 	now := time.Now().UTC()
 	d := &readerDAO{rows: []model.Subscription{{
@@ -110,9 +114,7 @@ func TestSubscriptionReader_StatusExpiredZeroLevel(t *testing.T) {
 		CurrentPeriodEnd: now.Add(-48 * time.Hour),
 		LastEventAt:      now.Add(-72 * time.Hour),
 	}}}
-	r := NewSubscriptionReader(d, c)
-	r.now = func() time.Time { return now }
-	info, _ := r.LoadSubscriptionInfo(context.Background(), 1)
+	info := resolveInfo(t, 1, now, NewAppleEntitlementSource(d, c))
 	if info.Status != "EXPIRED" {
 		t.Fatalf("status = %q, want EXPIRED", info.Status)
 	}
@@ -121,7 +123,7 @@ func TestSubscriptionReader_StatusExpiredZeroLevel(t *testing.T) {
 	}
 }
 
-func TestSubscriptionReader_StatusRevokedMapsToExpired(t *testing.T) {
+func TestAppleEntitlementSource_StatusRevokedMapsToExpired(t *testing.T) {
 	c := newProdCatalog(t)
 	now := time.Now().UTC()
 	d := &readerDAO{rows: []model.Subscription{{
@@ -131,25 +133,21 @@ func TestSubscriptionReader_StatusRevokedMapsToExpired(t *testing.T) {
 		Level:            1,
 		CurrentPeriodEnd: now.Add(48 * time.Hour),
 	}}}
-	r := NewSubscriptionReader(d, c)
-	r.now = func() time.Time { return now }
-	info, _ := r.LoadSubscriptionInfo(context.Background(), 1)
+	info := resolveInfo(t, 1, now, NewAppleEntitlementSource(d, c))
 	if info.Status != "EXPIRED" {
 		t.Fatalf("revoked must map to EXPIRED, got %s", info.Status)
 	}
 }
 
-func TestSubscriptionReader_PicksFirstActiveFromOrderedRows(t *testing.T) {
+func TestAppleEntitlementSource_PicksHighestLevel(t *testing.T) {
 	c := newProdCatalog(t)
 	now := time.Now().UTC()
 	d := &readerDAO{rows: []model.Subscription{
 		{Status: model.SubscriptionStatusActive, Environment: model.AppleEnvProduction, PlanID: "pro_monthly", Level: 5, CurrentPeriodEnd: now.Add(24 * time.Hour)},
 		{Status: model.SubscriptionStatusActive, Environment: model.AppleEnvProduction, PlanID: "basic_monthly", Level: 1, CurrentPeriodEnd: now.Add(72 * time.Hour)},
 	}}
-	r := NewSubscriptionReader(d, c)
-	r.now = func() time.Time { return now }
-	info, _ := r.LoadSubscriptionInfo(context.Background(), 1)
+	info := resolveInfo(t, 1, now, NewAppleEntitlementSource(d, c))
 	if info.SubscribeLevel != 5 || info.ProductID != "pro_monthly" {
-		t.Fatalf("expected highest-level row first, got %+v", info)
+		t.Fatalf("expected highest-level row, got %+v", info)
 	}
 }
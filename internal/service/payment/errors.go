@@ -7,17 +7,56 @@ import (
 )
 
 var (
-	ErrNotConfigured           = errors.New("apple iap: not configured")
-	ErrInvalidConfig           = errors.New("apple iap: invalid configuration")
-	ErrDuplicateProduct        = errors.New("apple iap: duplicate (product_id, environment) in catalog")
-	ErrUnknownProduct          = errors.New("apple iap: product not in catalog")
-	ErrEnvironmentNotEntitled  = errors.New("apple iap: transaction environment not allowed for entitlement")
-	ErrSandboxFallbackDisabled = errors.New("apple iap: sandbox fallback disabled")
-	ErrEmptyAppAccountToken    = errors.New("apple iap: appAccountToken missing")
-	ErrAppAccountTokenMismatch = errors.New("apple iap: appAccountToken does not match authenticated user")
-	ErrTransactionRevoked      = errors.New("apple iap: transaction revoked")
-	ErrUnsupportedProductType  = errors.New("apple iap: only auto-renewable subscriptions supported")
+	ErrNotConfigured             = errors.New("apple iap: not configured")
+	ErrInvalidConfig             = errors.New("apple iap: invalid configuration")
+	ErrDuplicateProduct          = errors.New("apple iap: duplicate (product_id, environment) in catalog")
+	ErrUnknownProduct            = errors.New("apple iap: product not in catalog")
+	ErrEnvironmentNotEntitled    = errors.New("apple iap: transaction environment not allowed for entitlement")
+	ErrSandboxFallbackDisabled   = errors.New("apple iap: sandbox fallback disabled")
+	ErrEmptyAppAccountToken      = errors.New("apple iap: appAccountToken missing")
+	ErrAppAccountTokenMismatch   = errors.New("apple iap: appAccountToken does not match authenticated user")
+	ErrTransactionRevoked        = errors.New("apple iap: transaction revoked")
+	ErrUnsupportedProductType    = errors.New("apple iap: unsupported product type")
+	ErrUnknownOffer              = errors.New("apple iap: offer not configured")
+	ErrOfferNotEligible          = errors.New("apple iap: user not eligible for offer")
+	ErrInvalidHistoryCursor      = errors.New("apple iap: invalid purchase history cursor")
+	ErrInvalidSignedTransaction  = errors.New("apple iap: invalid signed transaction")
+	ErrFamilyShareProofRequired  = errors.New("apple iap: family shared transaction requires device signed transaction and device id")
+	ErrFamilyShareProofMismatch  = errors.New("apple iap: family share proof does not match transaction or device")
+	ErrInvalidProduct            = errors.New("apple iap: invalid product")
+	ErrUnknownPlatform           = errors.New("payment: unknown paywall platform")
+	ErrPaywallExperimentNotFound = errors.New("payment: paywall experiment not found")
 
 	ErrSubscriptionOwnershipConflict = dao.ErrSubscriptionOwnershipConflict
 	ErrSubscriptionNotFound          = dao.ErrSubscriptionNotFound
+	ErrPurchaseOwnershipConflict     = dao.ErrPurchaseOwnershipConflict
+	ErrFamilyShareOwnershipConflict  = dao.ErrFamilyShareOwnershipConflict
+	ErrAppleProductNotFound          = dao.ErrAppleProductNotFound
+	ErrAppleProductConflict          = dao.ErrDuplicateAppleProduct
+)
+
+// Google Play Billing 的业务错误；配置类错误与 Apple 共用 ErrNotConfigured / ErrInvalidConfig。
+var (
+	ErrGooglePlayUnknownProduct      = errors.New("google play: product not in catalog")
+	ErrGooglePlayProductMismatch     = errors.New("google play: purchase does not contain the requested product")
+	ErrGooglePlayPurchasePending     = errors.New("google play: purchase is pending")
+	ErrGooglePlayPurchaseCanceled    = errors.New("google play: purchase canceled")
+	ErrGooglePlayTestPurchase        = errors.New("google play: test purchases are not allowed")
+	ErrGooglePlayAccountMismatch     = errors.New("google play: obfuscated account id does not match authenticated user")
+	ErrGooglePlayAccountMissing      = errors.New("google play: purchase has no obfuscated account id")
+	ErrGooglePlayWebhookUnauthorized = errors.New("google play: webhook token mismatch")
+	ErrGooglePlayInvalidNotification = errors.New("google play: invalid developer notification")
+
+	ErrGooglePlayOwnershipConflict = dao.ErrGooglePlayOwnershipConflict
+)
+
+// Stripe web billing 的业务错误；配置类错误同样复用 ErrNotConfigured / ErrInvalidConfig。
+var (
+	ErrStripeUnknownPlan       = errors.New("stripe: plan not in catalog")
+	ErrStripeNoCustomer        = errors.New("stripe: user has no stripe customer")
+	ErrStripeInvalidSignature  = errors.New("stripe: invalid webhook signature")
+	ErrStripeInvalidEvent      = errors.New("stripe: invalid webhook event")
+	ErrStripeRequestRejected   = errors.New("stripe: api request rejected")
+	ErrStripeAuthRejected      = errors.New("stripe: api auth rejected")
+	ErrStripeOwnershipConflict = dao.ErrStripeOwnershipConflict
 )
@@ -0,0 +1,591 @@
+package payment
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	logpkg "github.com/dundunHa/go-serverhttp-template/pkg/log"
+)
+
+// GooglePlayStoreDAO 是 GooglePlayService 用到的 dao 子集。
+type GooglePlayStoreDAO interface {
+	InTx(ctx context.Context, fn func(dao.GooglePlayTx) error) error
+}
+
+// GooglePlayService 实现 Google Play 的 verify 与 Real-Time Developer Notifications（RTDN）处理。
+//
+// 用户绑定：Android 客户端发起购买时把 /payment/apple/account-token 返回的同一个 UUID 作为
+// BillingFlowParams 的 obfuscatedAccountId 提交。verify 时该值必须存在且指向当前用户；
+// RTDN 依次按已有记录、linkedPurchaseToken 指向的旧订阅、obfuscatedAccountId 反查 user。
+//
+// 所有状态都以 Android Publisher API 的查询结果为准，RTDN 只当作“某个 token 有变化”的信号。
+// 写库成功后对尚未确认的购买调用 acknowledge，避免 Google 三天后自动退款。
+type GooglePlayService struct {
+	catalog   *GooglePlayCatalog
+	publisher GooglePlayPublisher
+	tokens    *TokenService
+	dao       GooglePlayStoreDAO
+	now       func() time.Time
+}
+
+// NewGooglePlayService 构造 service；任一关键依赖为 nil 时调用都返回 ErrNotConfigured。
+func NewGooglePlayService(catalog *GooglePlayCatalog, publisher GooglePlayPublisher, tokens *TokenService, dao GooglePlayStoreDAO) *GooglePlayService {
+	return &GooglePlayService{
+		catalog:   catalog,
+		publisher: publisher,
+		tokens:    tokens,
+		dao:       dao,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+func (s *GooglePlayService) configured() bool {
+	return s != nil && s.catalog != nil && s.publisher != nil && s.tokens != nil && s.dao != nil
+}
+
+// WebhookMaxBodyBytes 暴露 RTDN 消息体上限，供路由层做 size guard。
+func (s *GooglePlayService) WebhookMaxBodyBytes() int {
+	if s == nil || s.catalog == nil {
+		return 0
+	}
+	return s.catalog.WebhookMaxBodyBytes()
+}
+
+// VerifyPurchase 是 POST /payment/google-play/verify 的服务层入口。
+func (s *GooglePlayService) VerifyPurchase(ctx context.Context, userID int64, productID, purchaseToken string) (*VerifyResult, error) {
+	if !s.configured() {
+		return nil, ErrNotConfigured
+	}
+	if userID <= 0 {
+		return nil, errors.New("google play: invalid user id")
+	}
+	if strings.TrimSpace(productID) == "" || strings.TrimSpace(purchaseToken) == "" {
+		return nil, errors.New("google play: product id and purchase token required")
+	}
+
+	product, err := s.catalog.Lookup(productID)
+	if err != nil {
+		return nil, err
+	}
+	if isOneTimeProduct(product) {
+		return s.verifyProduct(ctx, userID, product, purchaseToken)
+	}
+	return s.verifySubscription(ctx, userID, product, purchaseToken)
+}
+
+func (s *GooglePlayService) verifySubscription(ctx context.Context, userID int64, product Product, purchaseToken string) (*VerifyResult, error) {
+	sp, err := s.publisher.GetSubscription(ctx, purchaseToken)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := sp.LineItem(product.ProductID); !ok {
+		return nil, ErrGooglePlayProductMismatch
+	}
+	if sp.TestPurchase && !s.catalog.AllowTestPurchases() {
+		return nil, ErrGooglePlayTestPurchase
+	}
+	if _, ok := sp.Status(); !ok {
+		return nil, ErrGooglePlayPurchasePending
+	}
+	if err := s.checkAccount(ctx, userID, sp.ObfuscatedAccountID); err != nil {
+		return nil, err
+	}
+
+	upsert := s.buildSubscriptionUpsert(userID, sp, product)
+	var saved model.GooglePlaySubscription
+	if err := s.dao.InTx(ctx, func(qtx dao.GooglePlayTx) error {
+		sub, e := qtx.UpsertSubscriptionWithOwnershipCheck(ctx, upsert)
+		if e != nil {
+			return e
+		}
+		saved = sub
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if !sp.Acknowledged {
+		if err := s.publisher.AcknowledgeSubscription(ctx, product.ProductID, purchaseToken); err != nil {
+			logpkg.FromContext(ctx).WarnContext(ctx, "google play acknowledge subscription failed; will retry on next notification", "err", err)
+		}
+	}
+	info := subscriptionInfoFromRow(googlePlaySubscriptionView(saved), s.now())
+	return &VerifyResult{Subscription: &info}, nil
+}
+
+func (s *GooglePlayService) verifyProduct(ctx context.Context, userID int64, product Product, purchaseToken string) (*VerifyResult, error) {
+	pp, err := s.publisher.GetProductPurchase(ctx, product.ProductID, purchaseToken)
+	if err != nil {
+		return nil, err
+	}
+	if pp.TestPurchase && !s.catalog.AllowTestPurchases() {
+		return nil, ErrGooglePlayTestPurchase
+	}
+	switch pp.PurchaseState {
+	case googleProductStatePurchased:
+	case googleProductStatePending:
+		return nil, ErrGooglePlayPurchasePending
+	default:
+		return nil, ErrGooglePlayPurchaseCanceled
+	}
+	if err := s.checkAccount(ctx, userID, pp.ObfuscatedAccountID); err != nil {
+		return nil, err
+	}
+
+	var recorded model.GooglePlayPurchase
+	if err := s.dao.InTx(ctx, func(qtx dao.GooglePlayTx) error {
+		p, e := recordGooglePlayPurchase(ctx, qtx, userID, s.catalog.PackageName(), pp, product)
+		if e != nil {
+			return e
+		}
+		recorded = p
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if !pp.Acknowledged {
+		if err := s.publisher.AcknowledgeProduct(ctx, product.ProductID, purchaseToken); err != nil {
+			logpkg.FromContext(ctx).WarnContext(ctx, "google play acknowledge product failed; will retry on next notification", "err", err)
+		}
+	}
+	purchase := googlePlayPurchaseInfo(recorded)
+	out := &VerifyResult{Purchase: &purchase}
+	if recorded.ProductType == model.ProductTypeNonConsumable && recorded.Status == model.PurchaseStatusActive {
+		info := googlePlayLifetimeInfo(recorded)
+		out.Subscription = &info
+	}
+	return out, nil
+}
+
+// checkAccount 校验购买携带的 obfuscatedAccountId 指向当前用户。未携带时拒绝：否则任何拿到
+// purchaseToken 的登录用户都能抢先把购买绑定到自己名下。
+func (s *GooglePlayService) checkAccount(ctx context.Context, userID int64, obfuscatedAccountID string) error {
+	if obfuscatedAccountID == "" {
+		return ErrGooglePlayAccountMissing
+	}
+	mapped, err := s.tokens.ResolveUserByToken(ctx, obfuscatedAccountID)
+	if err != nil {
+		if errors.Is(err, ErrAccountTokenNotFound) {
+			return ErrGooglePlayAccountMismatch
+		}
+		return err
+	}
+	if mapped.UserID != userID {
+		return ErrGooglePlayAccountMismatch
+	}
+	return nil
+}
+
+// HandlePushMessage 是 POST /webhooks/google-play 的服务层入口。返回 nil 表示 ack（200），
+// 其余错误让 Pub/Sub 按退避策略重投。
+//
+// 流程：校验共享 token → 解码 DeveloperNotification → 向 Android Publisher 拉取最新状态（事务外）→
+// 事务内幂等写 google_play_events 并 reduce 订阅 / 购买 → 提交后 acknowledge。
+// acknowledge 失败返回 error：重投时事件已存在不会重复入账，但会再次尝试 acknowledge。
+func (s *GooglePlayService) HandlePushMessage(ctx context.Context, token string, msg GooglePlayPushMessage) error {
+	if !s.configured() || s.catalog.WebhookToken() == "" {
+		return ErrNotConfigured
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.catalog.WebhookToken())) != 1 {
+		return ErrGooglePlayWebhookUnauthorized
+	}
+	if msg.MessageID == "" {
+		return fmt.Errorf("%w: missing message id", ErrGooglePlayInvalidNotification)
+	}
+	data, err := base64.StdEncoding.DecodeString(msg.Data)
+	if err != nil {
+		return fmt.Errorf("%w: decode data: %v", ErrGooglePlayInvalidNotification, err)
+	}
+	var n googleDeveloperNotification
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("%w: decode notification: %v", ErrGooglePlayInvalidNotification, err)
+	}
+	if n.PackageName != s.catalog.PackageName() {
+		return fmt.Errorf("%w: package mismatch", ErrGooglePlayInvalidNotification)
+	}
+
+	event := model.GooglePlayEventInsert{MessageID: msg.MessageID, PackageName: n.PackageName}
+	if ms, err := strconv.ParseInt(n.EventTimeMillis, 10, 64); err == nil && ms > 0 {
+		t := time.UnixMilli(ms).UTC()
+		event.EventTime = &t
+	}
+
+	switch {
+	case n.SubscriptionNotification != nil:
+		event.NotificationKind = model.GooglePlayNotificationSubscription
+		event.NotificationType = n.SubscriptionNotification.NotificationType
+		event.PurchaseToken = n.SubscriptionNotification.PurchaseToken
+		event.ProductID = n.SubscriptionNotification.SubscriptionID
+		return s.handleSubscriptionNotification(ctx, event)
+	case n.OneTimeProductNotification != nil:
+		event.NotificationKind = model.GooglePlayNotificationOneTime
+		event.NotificationType = n.OneTimeProductNotification.NotificationType
+		event.PurchaseToken = n.OneTimeProductNotification.PurchaseToken
+		event.ProductID = n.OneTimeProductNotification.SKU
+		return s.handleOneTimeNotification(ctx, event)
+	case n.VoidedPurchaseNotification != nil:
+		event.NotificationKind = model.GooglePlayNotificationVoided
+		event.NotificationType = n.VoidedPurchaseNotification.ProductType
+		event.PurchaseToken = n.VoidedPurchaseNotification.PurchaseToken
+		return s.handleVoidedNotification(ctx, event)
+	case n.TestNotification != nil:
+		event.NotificationKind = model.GooglePlayNotificationTest
+		return s.recordEvent(ctx, event, googlePlayClassification{status: model.EventStatusProcessed})
+	default:
+		return fmt.Errorf("%w: no notification payload", ErrGooglePlayInvalidNotification)
+	}
+}
+
+// googlePlayClassification 记录写 google_play_events 之前做出的决策。
+type googlePlayClassification struct {
+	status       string
+	userID       int64
+	errorMessage string
+}
+
+func (s *GooglePlayService) recordEvent(ctx context.Context, event model.GooglePlayEventInsert, c googlePlayClassification) error {
+	event.ProcessingStatus, event.UserID, event.ProcessingError = c.status, c.userID, c.errorMessage
+	return s.dao.InTx(ctx, func(qtx dao.GooglePlayTx) error {
+		_, err := qtx.InsertEventIfNotExists(ctx, event)
+		return err
+	})
+}
+
+func (s *GooglePlayService) handleSubscriptionNotification(ctx context.Context, event model.GooglePlayEventInsert) error {
+	sp, err := s.publisher.GetSubscription(ctx, event.PurchaseToken)
+	if err != nil {
+		if errors.Is(err, ErrGooglePlayPurchaseNotFound) {
+			return s.recordEvent(ctx, event, googlePlayClassification{status: model.EventStatusPermanentFailure, errorMessage: "purchase token not found"})
+		}
+		return err
+	}
+	product, found := s.subscriptionProduct(sp)
+
+	var (
+		classification googlePlayClassification
+		ackProductID   string
+	)
+	if err := s.dao.InTx(ctx, func(qtx dao.GooglePlayTx) error {
+		classification = s.classifySubscription(ctx, qtx, sp, product, found)
+		event.ProcessingStatus, event.UserID, event.ProcessingError = classification.status, classification.userID, classification.errorMessage
+		created, err := qtx.InsertEventIfNotExists(ctx, event)
+		if err != nil {
+			return fmt.Errorf("google play webhook: insert event: %w", err)
+		}
+		if classification.status != model.EventStatusProcessed {
+			return nil
+		}
+		ackProductID = product.ProductID
+		if !created {
+			return nil
+		}
+		if _, err := qtx.UpsertSubscriptionWithOwnershipCheck(ctx, s.buildSubscriptionUpsert(classification.userID, sp, product)); err != nil {
+			return fmt.Errorf("google play webhook: upsert subscription: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if ackProductID != "" && !sp.Acknowledged {
+		if err := s.publisher.AcknowledgeSubscription(ctx, ackProductID, sp.PurchaseToken); err != nil {
+			return fmt.Errorf("google play webhook: acknowledge subscription: %w", err)
+		}
+	}
+	return nil
+}
+
+// subscriptionProduct 返回订阅 line items 中第一个在 catalog 内的商品。
+func (s *GooglePlayService) subscriptionProduct(sp *GooglePlaySubscriptionPurchase) (Product, bool) {
+	for _, item := range sp.LineItems {
+		if p, err := s.catalog.Lookup(item.ProductID); err == nil && p.Type == model.ProductTypeSubscription {
+			return p, true
+		}
+	}
+	return Product{}, false
+}
+
+func (s *GooglePlayService) classifySubscription(ctx context.Context, qtx dao.GooglePlayTx, sp *GooglePlaySubscriptionPurchase, product Product, found bool) googlePlayClassification {
+	userID, c, ok := s.resolveSubscriptionUser(ctx, qtx, sp)
+	if !ok {
+		return c
+	}
+	if !found {
+		return googlePlayClassification{status: model.EventStatusIgnoredUnknownType, userID: userID, errorMessage: "product not in catalog"}
+	}
+	if sp.TestPurchase && !s.catalog.AllowTestPurchases() {
+		return googlePlayClassification{status: model.EventStatusIgnoredUnknownType, userID: userID, errorMessage: "test purchase not allowed"}
+	}
+	if _, ok := sp.Status(); !ok {
+		return googlePlayClassification{status: model.EventStatusIgnoredUnknownType, userID: userID, errorMessage: "subscription not active yet"}
+	}
+	return googlePlayClassification{status: model.EventStatusProcessed, userID: userID}
+}
+
+// resolveSubscriptionUser 依次按已有订阅行、linkedPurchaseToken 指向的旧订阅、obfuscatedAccountId 反查 user。
+func (s *GooglePlayService) resolveSubscriptionUser(ctx context.Context, qtx dao.GooglePlayTx, sp *GooglePlaySubscriptionPurchase) (int64, googlePlayClassification, bool) {
+	for _, token := range []string{sp.PurchaseToken, sp.LinkedPurchaseToken} {
+		if token == "" {
+			continue
+		}
+		existing, err := qtx.GetSubscriptionByToken(ctx, token)
+		if err == nil {
+			return existing.UserID, googlePlayClassification{}, true
+		}
+		if !errors.Is(err, dao.ErrGooglePlaySubscriptionNotFound) {
+			return 0, googlePlayClassification{status: model.EventStatusPermanentFailure, errorMessage: err.Error()}, false
+		}
+	}
+	return s.resolveAccountUser(ctx, sp.ObfuscatedAccountID)
+}
+
+func (s *GooglePlayService) resolveAccountUser(ctx context.Context, obfuscatedAccountID string) (int64, googlePlayClassification, bool) {
+	if obfuscatedAccountID == "" {
+		return 0, googlePlayClassification{status: model.EventStatusPendingUserBinding, errorMessage: "missing obfuscatedAccountId"}, false
+	}
+	mapped, err := s.tokens.ResolveUserByToken(ctx, obfuscatedAccountID)
+	if err != nil {
+		if errors.Is(err, ErrAccountTokenNotFound) {
+			return 0, googlePlayClassification{status: model.EventStatusPendingUserBinding, errorMessage: "no user binding for obfuscatedAccountId"}, false
+		}
+		return 0, googlePlayClassification{status: model.EventStatusPermanentFailure, errorMessage: err.Error()}, false
+	}
+	return mapped.UserID, googlePlayClassification{}, true
+}
+
+func (s *GooglePlayService) handleOneTimeNotification(ctx context.Context, event model.GooglePlayEventInsert) error {
+	if event.NotificationType != googleOneTimeProductPurchased {
+		return s.recordEvent(ctx, event, googlePlayClassification{status: model.EventStatusIgnoredUnknownType})
+	}
+	product, err := s.catalog.Lookup(event.ProductID)
+	if err != nil || !isOneTimeProduct(product) {
+		return s.recordEvent(ctx, event, googlePlayClassification{status: model.EventStatusIgnoredUnknownType, errorMessage: "product not in catalog"})
+	}
+	pp, err := s.publisher.GetProductPurchase(ctx, product.ProductID, event.PurchaseToken)
+	if err != nil {
+		if errors.Is(err, ErrGooglePlayPurchaseNotFound) {
+			return s.recordEvent(ctx, event, googlePlayClassification{status: model.EventStatusPermanentFailure, errorMessage: "purchase token not found"})
+		}
+		return err
+	}
+
+	processed := false
+	if err := s.dao.InTx(ctx, func(qtx dao.GooglePlayTx) error {
+		c := s.classifyProduct(ctx, qtx, pp)
+		event.ProcessingStatus, event.UserID, event.ProcessingError = c.status, c.userID, c.errorMessage
+		created, err := qtx.InsertEventIfNotExists(ctx, event)
+		if err != nil {
+			return fmt.Errorf("google play webhook: insert event: %w", err)
+		}
+		if c.status != model.EventStatusProcessed {
+			return nil
+		}
+		processed = true
+		if !created {
+			return nil
+		}
+		if _, err := recordGooglePlayPurchase(ctx, qtx, c.userID, s.catalog.PackageName(), pp, product); err != nil {
+			return fmt.Errorf("google play webhook: record purchase: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if processed && !pp.Acknowledged {
+		if err := s.publisher.AcknowledgeProduct(ctx, product.ProductID, pp.PurchaseToken); err != nil {
+			return fmt.Errorf("google play webhook: acknowledge product: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *GooglePlayService) classifyProduct(ctx context.Context, qtx dao.GooglePlayTx, pp *GooglePlayProductPurchase) googlePlayClassification {
+	var userID int64
+	if existing, err := qtx.GetPurchaseByToken(ctx, pp.PurchaseToken); err == nil {
+		userID = existing.UserID
+	} else if !errors.Is(err, dao.ErrGooglePlayPurchaseNotFound) {
+		return googlePlayClassification{status: model.EventStatusPermanentFailure, errorMessage: err.Error()}
+	} else {
+		id, c, ok := s.resolveAccountUser(ctx, pp.ObfuscatedAccountID)
+		if !ok {
+			return c
+		}
+		userID = id
+	}
+	if pp.TestPurchase && !s.catalog.AllowTestPurchases() {
+		return googlePlayClassification{status: model.EventStatusIgnoredUnknownType, userID: userID, errorMessage: "test purchase not allowed"}
+	}
+	if pp.PurchaseState != googleProductStatePurchased {
+		return googlePlayClassification{status: model.EventStatusIgnoredUnknownType, userID: userID, errorMessage: "purchase not completed"}
+	}
+	return googlePlayClassification{status: model.EventStatusProcessed, userID: userID}
+}
+
+// handleVoidedNotification 处理退款 / 拒付：订阅置为 REVOKED，一次性购买标记 REFUNDED 并收回剩余积分。
+func (s *GooglePlayService) handleVoidedNotification(ctx context.Context, event model.GooglePlayEventInsert) error {
+	revokedAt := s.now()
+	if event.EventTime != nil {
+		revokedAt = *event.EventTime
+	}
+	return s.dao.InTx(ctx, func(qtx dao.GooglePlayTx) error {
+		c := googlePlayClassification{status: model.EventStatusProcessed}
+		var apply func() error
+		switch event.NotificationType {
+		case googleVoidedProductSubscription:
+			sub, err := qtx.GetSubscriptionByToken(ctx, event.PurchaseToken)
+			switch {
+			case err == nil:
+				c.userID = sub.UserID
+				apply = func() error {
+					_, err := qtx.RevokeSubscription(ctx, sub, revokedAt)
+					return err
+				}
+			case errors.Is(err, dao.ErrGooglePlaySubscriptionNotFound):
+				c = googlePlayClassification{status: model.EventStatusIgnoredUnknownType, errorMessage: "unknown purchase token"}
+			default:
+				return err
+			}
+		case googleVoidedProductOneTime:
+			p, err := qtx.GetPurchaseByToken(ctx, event.PurchaseToken)
+			switch {
+			case err == nil:
+				c.userID = p.UserID
+				event.ProductID = p.ProviderProductID
+				apply = func() error {
+					_, err := qtx.RefundPurchase(ctx, p, revokedAt)
+					return err
+				}
+			case errors.Is(err, dao.ErrGooglePlayPurchaseNotFound):
+				c = googlePlayClassification{status: model.EventStatusIgnoredUnknownType, errorMessage: "unknown purchase token"}
+			default:
+				return err
+			}
+		default:
+			c = googlePlayClassification{status: model.EventStatusIgnoredUnknownType, errorMessage: "unknown voided product type"}
+		}
+
+		event.ProcessingStatus, event.UserID, event.ProcessingError = c.status, c.userID, c.errorMessage
+		created, err := qtx.InsertEventIfNotExists(ctx, event)
+		if err != nil {
+			return fmt.Errorf("google play webhook: insert event: %w", err)
+		}
+		if !created || apply == nil {
+			return nil
+		}
+		if err := apply(); err != nil {
+			return fmt.Errorf("google play webhook: void purchase: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *GooglePlayService) buildSubscriptionUpsert(userID int64, sp *GooglePlaySubscriptionPurchase, product Product) model.GooglePlaySubscriptionUpsert {
+	status, _ := sp.Status()
+	item, _ := sp.LineItem(product.ProductID)
+	autoRenew := model.AutoRenewStatusOff
+	if item.AutoRenewing {
+		autoRenew = model.AutoRenewStatusOn
+	}
+	return model.GooglePlaySubscriptionUpsert{
+		UserID:              userID,
+		PackageName:         s.catalog.PackageName(),
+		PurchaseToken:       sp.PurchaseToken,
+		LinkedPurchaseToken: sp.LinkedPurchaseToken,
+		LatestOrderID:       sp.LatestOrderID,
+		PlanID:              product.PlanID,
+		ProviderProductID:   product.ProductID,
+		Level:               product.Level,
+		Status:              status,
+		AutoRenewStatus:     autoRenew,
+		TestPurchase:        sp.TestPurchase,
+		CurrentPeriodStart:  sp.StartTime,
+		CurrentPeriodEnd:    item.ExpiryTime,
+		LastEventAt:         s.now(),
+	}
+}
+
+// recordGooglePlayPurchase 与 Apple 的 recordPurchase 对称：以 purchase_token 幂等写入，仅首次写入时为消耗型发放积分。
+func recordGooglePlayPurchase(ctx context.Context, qtx dao.GooglePlayTx, userID int64, packageName string, pp *GooglePlayProductPurchase, product Product) (model.GooglePlayPurchase, error) {
+	quantity := max(pp.Quantity, 1)
+	p, created, err := qtx.InsertPurchaseIfNotExists(ctx, model.GooglePlayPurchaseInsert{
+		UserID:            userID,
+		PackageName:       packageName,
+		PurchaseToken:     pp.PurchaseToken,
+		OrderID:           pp.OrderID,
+		PlanID:            product.PlanID,
+		ProviderProductID: product.ProductID,
+		ProductType:       product.Type,
+		Quantity:          quantity,
+		Level:             product.Level,
+		Credits:           product.Credits * int64(quantity),
+		TestPurchase:      pp.TestPurchase,
+		PurchasedAt:       pp.PurchaseTime,
+	})
+	if err != nil {
+		return model.GooglePlayPurchase{}, err
+	}
+	if created && p.ProductType == model.ProductTypeConsumable && p.Credits > 0 {
+		bucket, err := qtx.GrantPurchaseCredits(ctx, p.ID, model.CreditGrant{
+			UserID:    userID,
+			Source:    model.CreditSourcePurchase,
+			Amount:    p.Credits,
+			Reference: "google-play:" + p.OrderID,
+		})
+		if err != nil {
+			return model.GooglePlayPurchase{}, err
+		}
+		p.CreditBucketID = bucket.ID
+	}
+	return p, nil
+}
+
+// googlePlaySubscriptionView 把 Google Play 订阅行投影成 model.Subscription，复用 Apple 的 API status 映射规则。
+func googlePlaySubscriptionView(sub model.GooglePlaySubscription) model.Subscription {
+	return model.Subscription{
+		ID:                 sub.ID,
+		UserID:             sub.UserID,
+		PlanID:             sub.PlanID,
+		ProviderProductID:  sub.ProviderProductID,
+		Level:              sub.Level,
+		Status:             sub.Status,
+		AutoRenewStatus:    sub.AutoRenewStatus,
+		CurrentPeriodStart: sub.CurrentPeriodStart,
+		CurrentPeriodEnd:   sub.CurrentPeriodEnd,
+		LastEventAt:        sub.LastEventAt,
+	}
+}
+
+func googlePlayPurchaseInfo(p model.GooglePlayPurchase) model.PurchaseInfo {
+	info := model.PurchaseInfo{
+		TransactionID: p.OrderID,
+		ProductID:     p.PlanID,
+		ProductType:   p.ProductType,
+		Status:        p.Status,
+	}
+	if p.CreditBucketID != 0 {
+		info.CreditsGranted = p.Credits
+	}
+	if !p.PurchasedAt.IsZero() {
+		info.PurchasedAt = p.PurchasedAt.UTC().Format(time.RFC3339)
+	}
+	return info
+}
+
+func googlePlayLifetimeInfo(p model.GooglePlayPurchase) model.SubscriptionInfo {
+	return model.SubscriptionInfo{
+		ProductID:      p.PlanID,
+		Status:         "ACTIVE",
+		SubscribeLevel: p.Level,
+	}
+}
@@ -0,0 +1,191 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/config"
+)
+
+const (
+	defaultGooglePlayAPIBaseURL = "https://androidpublisher.googleapis.com"
+	defaultGoogleTokenURL       = "https://oauth2.googleapis.com/token"
+)
+
+// googleServiceAccount 是 service account JSON key 中 OAuth 签名用到的字段。
+type googleServiceAccount struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// googlePlayProductConfig 是 GOOGLE_PLAY_PRODUCTS 中单个商品的配置形状。
+// Google Play 没有 Apple 的 Production / Sandbox 之分（测试购买由 AllowTestPurchases 控制），因此不含 environment。
+type googlePlayProductConfig struct {
+	PlanID    string `json:"plan_id"`
+	ProductID string `json:"product_id"`
+	Type      string `json:"type,omitempty"`
+	Level     int    `json:"level"`
+	Credits   int64  `json:"credits,omitempty"`
+}
+
+// GooglePlayCatalog 是 Google Play Billing 配置的不可变运行期视图，语义与 Apple 的 Catalog 一致：
+// 构造完成后所有字段只读，调用方不得日志输出 service account 与 webhook token。
+type GooglePlayCatalog struct {
+	packageName         string
+	products            []Product
+	byProductID         map[string]Product
+	serviceAccount      googleServiceAccount
+	apiBaseURL          string
+	tokenURL            string
+	apiTimeout          time.Duration
+	webhookToken        string
+	webhookMaxBodyBytes int
+	allowTestPurchases  bool
+}
+
+// NewGooglePlayCatalog 验证 Google Play 配置并构造只读 catalog。
+//
+// 行为：
+//   - PackageName / ServiceAccountJSON / Products 任一缺失 → ErrNotConfigured（verify 路由 503）。
+//   - products JSON、service account JSON 或 URL 不合法 → wrap ErrInvalidConfig。
+//   - 生产环境（appEnv == "prod"）要求 API / token 地址必须是 https，避免误把 fake server 配进线上。
+//
+// WebhookToken 为空不影响 verify，只是 RTDN webhook 不可用。
+func NewGooglePlayCatalog(cfg config.GooglePlayConfig, appEnv string) (*GooglePlayCatalog, error) {
+	if cfg.PackageName == "" || cfg.ServiceAccountJSON == "" || strings.TrimSpace(cfg.Products) == "" {
+		return nil, ErrNotConfigured
+	}
+
+	account, err := parseGoogleServiceAccount(cfg.ServiceAccountJSON)
+	if err != nil {
+		return nil, err
+	}
+	products, byID, err := parseGooglePlayProducts(cfg.Products)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenURL := cfg.TokenURL
+	if tokenURL == "" {
+		tokenURL = account.TokenURI
+	}
+	if tokenURL == "" {
+		tokenURL = defaultGoogleTokenURL
+	}
+	apiBaseURL := cfg.APIBaseURL
+	if apiBaseURL == "" {
+		apiBaseURL = defaultGooglePlayAPIBaseURL
+	}
+	if err := validateProviderURL("GOOGLE_PLAY_API_BASE_URL", apiBaseURL, appEnv); err != nil {
+		return nil, err
+	}
+	if err := validateProviderURL("GOOGLE_PLAY_TOKEN_URL", tokenURL, appEnv); err != nil {
+		return nil, err
+	}
+
+	return &GooglePlayCatalog{
+		packageName:         cfg.PackageName,
+		products:            products,
+		byProductID:         byID,
+		serviceAccount:      account,
+		apiBaseURL:          strings.TrimRight(apiBaseURL, "/"),
+		tokenURL:            tokenURL,
+		apiTimeout:          cfg.APITimeout,
+		webhookToken:        cfg.WebhookToken,
+		webhookMaxBodyBytes: cfg.WebhookMaxBodyBytes,
+		allowTestPurchases:  cfg.AllowTestPurchases,
+	}, nil
+}
+
+func validateProviderURL(name, raw, appEnv string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("%s must be an absolute http(s) url: %w", name, ErrInvalidConfig)
+	}
+	if appEnv == AppEnvProd && u.Scheme != "https" {
+		return fmt.Errorf("%s must use https in production: %w", name, ErrInvalidConfig)
+	}
+	return nil
+}
+
+func parseGoogleServiceAccount(raw string) (googleServiceAccount, error) {
+	var account googleServiceAccount
+	if err := json.Unmarshal([]byte(raw), &account); err != nil {
+		return googleServiceAccount{}, fmt.Errorf("parse GOOGLE_PLAY_SERVICE_ACCOUNT_JSON: %w", joinInvalidConfig(err))
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return googleServiceAccount{}, fmt.Errorf("GOOGLE_PLAY_SERVICE_ACCOUNT_JSON: client_email and private_key required: %w", ErrInvalidConfig)
+	}
+	if _, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey)); err != nil {
+		return googleServiceAccount{}, fmt.Errorf("GOOGLE_PLAY_SERVICE_ACCOUNT_JSON: parse private_key: %w", joinInvalidConfig(err))
+	}
+	return account, nil
+}
+
+func parseGooglePlayProducts(raw string) ([]Product, map[string]Product, error) {
+	var configs []googlePlayProductConfig
+	dec := json.NewDecoder(strings.NewReader(strings.TrimSpace(raw)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&configs); err != nil {
+		return nil, nil, fmt.Errorf("parse GOOGLE_PLAY_PRODUCTS json: %w", joinInvalidConfig(err))
+	}
+	if len(configs) == 0 {
+		return nil, nil, ErrNotConfigured
+	}
+
+	products := make([]Product, 0, len(configs))
+	byID := make(map[string]Product, len(configs))
+	for i, c := range configs {
+		if c.PlanID == "" || c.ProductID == "" {
+			return nil, nil, fmt.Errorf("GOOGLE_PLAY_PRODUCTS[%d]: plan_id, product_id required: %w", i, ErrInvalidConfig)
+		}
+		p := Product{PlanID: c.PlanID, ProductID: c.ProductID, Type: c.Type, Level: c.Level, Credits: c.Credits}
+		if err := normalizeProductType(&p); err != nil {
+			return nil, nil, fmt.Errorf("GOOGLE_PLAY_PRODUCTS[%d]: %w", i, err)
+		}
+		if _, dup := byID[p.ProductID]; dup {
+			return nil, nil, fmt.Errorf("GOOGLE_PLAY_PRODUCTS[%d]: duplicate %s: %w", i, p.ProductID, errors.Join(ErrDuplicateProduct, ErrInvalidConfig))
+		}
+		byID[p.ProductID] = p
+		products = append(products, p)
+	}
+	return products, byID, nil
+}
+
+// Lookup 按 Play Console 中的 productId 查找商品；未命中返回 ErrGooglePlayUnknownProduct。
+func (c *GooglePlayCatalog) Lookup(productID string) (Product, error) {
+	if c == nil {
+		return Product{}, ErrNotConfigured
+	}
+	p, ok := c.byProductID[productID]
+	if !ok {
+		return Product{}, ErrGooglePlayUnknownProduct
+	}
+	return p, nil
+}
+
+// Products 返回 catalog 内产品的拷贝。
+func (c *GooglePlayCatalog) Products() []Product {
+	if c == nil || len(c.products) == 0 {
+		return nil
+	}
+	out := make([]Product, len(c.products))
+	copy(out, c.products)
+	return out
+}
+
+func (c *GooglePlayCatalog) PackageName() string       { return c.packageName }
+func (c *GooglePlayCatalog) APIBaseURL() string        { return c.apiBaseURL }
+func (c *GooglePlayCatalog) TokenURL() string          { return c.tokenURL }
+func (c *GooglePlayCatalog) APITimeout() time.Duration { return c.apiTimeout }
+func (c *GooglePlayCatalog) WebhookToken() string      { return c.webhookToken }
+func (c *GooglePlayCatalog) WebhookMaxBodyBytes() int  { return c.webhookMaxBodyBytes }
+func (c *GooglePlayCatalog) AllowTestPurchases() bool  { return c.allowTestPurchases }
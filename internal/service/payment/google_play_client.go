@@ -0,0 +1,296 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const googlePlayAPIScope = "https://www.googleapis.com/auth/androidpublisher"
+
+// GooglePlayPublisher 封装本服务用到的 Android Publisher API 子集。
+type GooglePlayPublisher interface {
+	GetSubscription(ctx context.Context, purchaseToken string) (*GooglePlaySubscriptionPurchase, error)
+	GetProductPurchase(ctx context.Context, productID, purchaseToken string) (*GooglePlayProductPurchase, error)
+	AcknowledgeSubscription(ctx context.Context, productID, purchaseToken string) error
+	AcknowledgeProduct(ctx context.Context, productID, purchaseToken string) error
+}
+
+// ErrGooglePlayPurchaseNotFound 表示 Google 返回 404 / 410：purchase token 不存在或已过期太久。
+var ErrGooglePlayPurchaseNotFound = errors.New("google play: purchase token not found")
+
+// ErrGooglePlayAuthRejected 表示 OAuth 换 token 失败或 API 返回 401/403（service account 未授权等）。
+var ErrGooglePlayAuthRejected = errors.New("google play: api auth rejected")
+
+// googlePlayClient 是 GooglePlayPublisher 的生产实现：直接调用 REST API，
+// 用 service account JWT 换取 OAuth access token 并缓存到过期前一分钟。
+type googlePlayClient struct {
+	packageName string
+	baseURL     string
+	tokenURL    string
+	clientEmail string
+	keyID       string
+	key         *rsa.PrivateKey
+	http        *http.Client
+	now         func() time.Time
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewGooglePlayPublisher 用 catalog 构造 Android Publisher 客户端；catalog 为 nil 返回 ErrNotConfigured。
+func NewGooglePlayPublisher(c *GooglePlayCatalog) (GooglePlayPublisher, error) {
+	if c == nil {
+		return nil, ErrNotConfigured
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(c.serviceAccount.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("google play: parse service account key: %w", joinInvalidConfig(err))
+	}
+	timeout := c.APITimeout()
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &googlePlayClient{
+		packageName: c.PackageName(),
+		baseURL:     c.APIBaseURL(),
+		tokenURL:    c.TokenURL(),
+		clientEmail: c.serviceAccount.ClientEmail,
+		keyID:       c.serviceAccount.PrivateKeyID,
+		key:         key,
+		http:        &http.Client{Timeout: timeout},
+		now:         time.Now,
+	}, nil
+}
+
+type googleSubscriptionV2 struct {
+	StartTime            string `json:"startTime"`
+	SubscriptionState    string `json:"subscriptionState"`
+	LatestOrderID        string `json:"latestOrderId"`
+	LinkedPurchaseToken  string `json:"linkedPurchaseToken"`
+	AcknowledgementState string `json:"acknowledgementState"`
+	LineItems            []struct {
+		ProductID        string `json:"productId"`
+		ExpiryTime       string `json:"expiryTime"`
+		AutoRenewingPlan *struct {
+			AutoRenewEnabled bool `json:"autoRenewEnabled"`
+		} `json:"autoRenewingPlan"`
+	} `json:"lineItems"`
+	ExternalAccountIdentifiers *struct {
+		ObfuscatedExternalAccountID string `json:"obfuscatedExternalAccountId"`
+	} `json:"externalAccountIdentifiers"`
+	TestPurchase *struct{} `json:"testPurchase"`
+}
+
+type googleProductPurchase struct {
+	PurchaseTimeMillis          string `json:"purchaseTimeMillis"`
+	PurchaseState               int    `json:"purchaseState"`
+	OrderID                     string `json:"orderId"`
+	AcknowledgementState        int    `json:"acknowledgementState"`
+	ObfuscatedExternalAccountID string `json:"obfuscatedExternalAccountId"`
+	PurchaseType                *int   `json:"purchaseType"`
+	Quantity                    int    `json:"quantity"`
+}
+
+// GetSubscription 调用 purchases.subscriptionsv2.get。
+func (c *googlePlayClient) GetSubscription(ctx context.Context, purchaseToken string) (*GooglePlaySubscriptionPurchase, error) {
+	var raw googleSubscriptionV2
+	path := fmt.Sprintf("/androidpublisher/v3/applications/%s/purchases/subscriptionsv2/tokens/%s",
+		url.PathEscape(c.packageName), url.PathEscape(purchaseToken))
+	if err := c.do(ctx, http.MethodGet, path, &raw); err != nil {
+		return nil, err
+	}
+
+	out := &GooglePlaySubscriptionPurchase{
+		PurchaseToken:       purchaseToken,
+		LinkedPurchaseToken: raw.LinkedPurchaseToken,
+		LatestOrderID:       raw.LatestOrderID,
+		State:               raw.SubscriptionState,
+		StartTime:           parseGoogleTime(raw.StartTime),
+		Acknowledged:        raw.AcknowledgementState == "ACKNOWLEDGEMENT_STATE_ACKNOWLEDGED",
+		TestPurchase:        raw.TestPurchase != nil,
+	}
+	if raw.ExternalAccountIdentifiers != nil {
+		out.ObfuscatedAccountID = raw.ExternalAccountIdentifiers.ObfuscatedExternalAccountID
+	}
+	for _, item := range raw.LineItems {
+		li := GooglePlayLineItem{ProductID: item.ProductID, ExpiryTime: parseGoogleTime(item.ExpiryTime)}
+		if item.AutoRenewingPlan != nil {
+			li.AutoRenewing = item.AutoRenewingPlan.AutoRenewEnabled
+		}
+		out.LineItems = append(out.LineItems, li)
+	}
+	return out, nil
+}
+
+// GetProductPurchase 调用 purchases.products.get。
+func (c *googlePlayClient) GetProductPurchase(ctx context.Context, productID, purchaseToken string) (*GooglePlayProductPurchase, error) {
+	var raw googleProductPurchase
+	path := fmt.Sprintf("/androidpublisher/v3/applications/%s/purchases/products/%s/tokens/%s",
+		url.PathEscape(c.packageName), url.PathEscape(productID), url.PathEscape(purchaseToken))
+	if err := c.do(ctx, http.MethodGet, path, &raw); err != nil {
+		return nil, err
+	}
+
+	out := &GooglePlayProductPurchase{
+		PurchaseToken:       purchaseToken,
+		ProductID:           productID,
+		OrderID:             raw.OrderID,
+		PurchaseState:       raw.PurchaseState,
+		Quantity:            raw.Quantity,
+		Acknowledged:        raw.AcknowledgementState == 1,
+		ObfuscatedAccountID: raw.ObfuscatedExternalAccountID,
+		TestPurchase:        raw.PurchaseType != nil && *raw.PurchaseType == 0,
+	}
+	if ms, err := strconv.ParseInt(raw.PurchaseTimeMillis, 10, 64); err == nil && ms > 0 {
+		out.PurchaseTime = time.UnixMilli(ms)
+	}
+	return out, nil
+}
+
+// AcknowledgeSubscription 调用 purchases.subscriptions.acknowledge；Google 要求 3 天内确认，否则自动退款。
+func (c *googlePlayClient) AcknowledgeSubscription(ctx context.Context, productID, purchaseToken string) error {
+	path := fmt.Sprintf("/androidpublisher/v3/applications/%s/purchases/subscriptions/%s/tokens/%s:acknowledge",
+		url.PathEscape(c.packageName), url.PathEscape(productID), url.PathEscape(purchaseToken))
+	return c.do(ctx, http.MethodPost, path, nil)
+}
+
+// AcknowledgeProduct 调用 purchases.products.acknowledge。
+func (c *googlePlayClient) AcknowledgeProduct(ctx context.Context, productID, purchaseToken string) error {
+	path := fmt.Sprintf("/androidpublisher/v3/applications/%s/purchases/products/%s/tokens/%s:acknowledge",
+		url.PathEscape(c.packageName), url.PathEscape(productID), url.PathEscape(purchaseToken))
+	return c.do(ctx, http.MethodPost, path, nil)
+}
+
+func (c *googlePlayClient) do(ctx context.Context, method, path string, out any) error {
+	token, err := c.token(ctx)
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if method == http.MethodPost {
+		body = bytes.NewReader([]byte("{}"))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("google play: build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("google play: %s %s: %w", method, path, err)
+	}
+	defer rsp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(rsp.Body, 1<<20))
+
+	switch {
+	case rsp.StatusCode == http.StatusNotFound || rsp.StatusCode == http.StatusGone:
+		return ErrGooglePlayPurchaseNotFound
+	case rsp.StatusCode == http.StatusUnauthorized || rsp.StatusCode == http.StatusForbidden:
+		c.resetToken()
+		return fmt.Errorf("%w: status %d", ErrGooglePlayAuthRejected, rsp.StatusCode)
+	case rsp.StatusCode < 200 || rsp.StatusCode >= 300:
+		return fmt.Errorf("google play: %s %s: unexpected status %d", method, path, rsp.StatusCode)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("google play: decode response: %w", err)
+	}
+	return nil
+}
+
+// token 返回缓存的 access token，过期前一分钟重新走 JWT bearer grant。
+func (c *googlePlayClient) token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if c.accessToken != "" && now.Before(c.expiresAt.Add(-time.Minute)) {
+		return c.accessToken, nil
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   c.clientEmail,
+		"scope": googlePlayAPIScope,
+		"aud":   c.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+	assertion := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if c.keyID != "" {
+		assertion.Header["kid"] = c.keyID
+	}
+	signed, err := assertion.SignedString(c.key)
+	if err != nil {
+		return "", fmt.Errorf("google play: sign oauth assertion: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {signed},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("google play: build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("google play: token request: %w", err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode == http.StatusBadRequest || rsp.StatusCode == http.StatusUnauthorized || rsp.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("%w: token endpoint status %d", ErrGooglePlayAuthRejected, rsp.StatusCode)
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("google play: token endpoint status %d", rsp.StatusCode)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(rsp.Body, 1<<20)).Decode(&tok); err != nil {
+		return "", fmt.Errorf("google play: decode token response: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", fmt.Errorf("%w: empty access token", ErrGooglePlayAuthRejected)
+	}
+	c.accessToken = tok.AccessToken
+	c.expiresAt = now.Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return c.accessToken, nil
+}
+
+func (c *googlePlayClient) resetToken() {
+	c.mu.Lock()
+	c.accessToken = ""
+	c.mu.Unlock()
+}
+
+func parseGoogleTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
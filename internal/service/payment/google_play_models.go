@@ -0,0 +1,128 @@
+package payment
+
+import (
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// subscriptionsv2 的 subscriptionState 取值。
+const (
+	googleSubStateActive                  = "SUBSCRIPTION_STATE_ACTIVE"
+	googleSubStateCanceled                = "SUBSCRIPTION_STATE_CANCELED"
+	googleSubStateInGracePeriod           = "SUBSCRIPTION_STATE_IN_GRACE_PERIOD"
+	googleSubStateOnHold                  = "SUBSCRIPTION_STATE_ON_HOLD"
+	googleSubStatePaused                  = "SUBSCRIPTION_STATE_PAUSED"
+	googleSubStateExpired                 = "SUBSCRIPTION_STATE_EXPIRED"
+	googleSubStatePending                 = "SUBSCRIPTION_STATE_PENDING"
+	googleSubStatePendingPurchaseCanceled = "SUBSCRIPTION_STATE_PENDING_PURCHASE_CANCELED"
+)
+
+// purchases.products 的 purchaseState 取值。
+const (
+	googleProductStatePurchased = 0
+	googleProductStateCanceled  = 1
+	googleProductStatePending   = 2
+)
+
+// RTDN 中 oneTimeProductNotification.notificationType / voidedPurchaseNotification.productType 的取值。
+const (
+	googleOneTimeProductPurchased = 1
+	googleOneTimeProductCanceled  = 2
+
+	googleVoidedProductSubscription = 1
+	googleVoidedProductOneTime      = 2
+)
+
+// GooglePlayLineItem 是订阅购买里单个 base plan 的投影。
+type GooglePlayLineItem struct {
+	ProductID    string
+	ExpiryTime   time.Time
+	AutoRenewing bool
+}
+
+// GooglePlaySubscriptionPurchase 是 purchases.subscriptionsv2.get 结果在领域内的投影。
+//
+// 与 AppleTransaction 一样，service / reducer 只依赖此结构，不感知 Android Publisher 的 JSON 形状。
+type GooglePlaySubscriptionPurchase struct {
+	PurchaseToken       string
+	LinkedPurchaseToken string
+	LatestOrderID       string
+	State               string
+	StartTime           time.Time
+	LineItems           []GooglePlayLineItem
+	Acknowledged        bool
+	ObfuscatedAccountID string
+	TestPurchase        bool
+}
+
+// LineItem 返回指定 productId 的 line item。
+func (p *GooglePlaySubscriptionPurchase) LineItem(productID string) (GooglePlayLineItem, bool) {
+	for _, item := range p.LineItems {
+		if item.ProductID == productID {
+			return item, true
+		}
+	}
+	return GooglePlayLineItem{}, false
+}
+
+// Status 把 subscriptionState 映射为订阅内部状态；ok == false 表示尚未生效（PENDING 等），不应写入权益。
+//
+// 宽限期内仍视为 ACTIVE；ON_HOLD / PAUSED 期间用户没有权益，按 EXPIRED 处理，恢复后 Google 会再推送通知。
+func (p *GooglePlaySubscriptionPurchase) Status() (string, bool) {
+	switch p.State {
+	case googleSubStateActive, googleSubStateInGracePeriod:
+		return model.SubscriptionStatusActive, true
+	case googleSubStateCanceled:
+		return model.SubscriptionStatusCanceled, true
+	case googleSubStateOnHold, googleSubStatePaused, googleSubStateExpired:
+		return model.SubscriptionStatusExpired, true
+	default:
+		return "", false
+	}
+}
+
+// GooglePlayProductPurchase 是 purchases.products.get 结果在领域内的投影。
+type GooglePlayProductPurchase struct {
+	PurchaseToken       string
+	ProductID           string
+	OrderID             string
+	PurchaseState       int
+	PurchaseTime        time.Time
+	Quantity            int
+	Acknowledged        bool
+	ObfuscatedAccountID string
+	TestPurchase        bool
+}
+
+// GooglePlayPushMessage 是 Pub/Sub push 请求里的 message 字段；Data 为 base64 编码的 DeveloperNotification。
+type GooglePlayPushMessage struct {
+	MessageID string
+	Data      string
+}
+
+// googleDeveloperNotification 是 RTDN 的 JSON 形状（base64 解码后）。
+type googleDeveloperNotification struct {
+	Version         string `json:"version"`
+	PackageName     string `json:"packageName"`
+	EventTimeMillis string `json:"eventTimeMillis"`
+
+	SubscriptionNotification *struct {
+		NotificationType int    `json:"notificationType"`
+		PurchaseToken    string `json:"purchaseToken"`
+		SubscriptionID   string `json:"subscriptionId"`
+	} `json:"subscriptionNotification"`
+	OneTimeProductNotification *struct {
+		NotificationType int    `json:"notificationType"`
+		PurchaseToken    string `json:"purchaseToken"`
+		SKU              string `json:"sku"`
+	} `json:"oneTimeProductNotification"`
+	VoidedPurchaseNotification *struct {
+		PurchaseToken string `json:"purchaseToken"`
+		OrderID       string `json:"orderId"`
+		ProductType   int    `json:"productType"`
+	} `json:"voidedPurchaseNotification"`
+	TestNotification *struct {
+		Version string `json:"version"`
+	} `json:"testNotification"`
+}
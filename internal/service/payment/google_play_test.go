@@ -0,0 +1,528 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/config"
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+const (
+	testGooglePackage      = "com.app.example"
+	testGoogleWebhookToken = "rtdn-secret"
+	testGoogleProducts     = `[{"plan_id":"pro_monthly","product_id":"pro.monthly","level":1},` +
+		`{"plan_id":"credits_100","product_id":"credits.100","type":"consumable","credits":100}]`
+)
+
+func testServiceAccountJSON(t testing.TB) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	raw, _ := json.Marshal(map[string]string{
+		"client_email":   "play@example.iam.gserviceaccount.com",
+		"private_key":    string(pemKey),
+		"private_key_id": "kid-1",
+	})
+	return string(raw)
+}
+
+func validGooglePlayConfig(t testing.TB, baseURL string) config.GooglePlayConfig {
+	return config.GooglePlayConfig{
+		PackageName:         testGooglePackage,
+		ServiceAccountJSON:  testServiceAccountJSON(t),
+		Products:            testGoogleProducts,
+		WebhookToken:        testGoogleWebhookToken,
+		APIBaseURL:          baseURL,
+		TokenURL:            baseURL + "/token",
+		APITimeout:          5 * time.Second,
+		WebhookMaxBodyBytes: 65536,
+	}
+}
+
+func TestNewGooglePlayCatalog_Validation(t *testing.T) {
+	t.Parallel()
+
+	base := validGooglePlayConfig(t, "http://127.0.0.1:1")
+	cases := []struct {
+		name    string
+		appEnv  string
+		mutate  func(*config.GooglePlayConfig)
+		wantErr error
+	}{
+		{name: "missing package", mutate: func(c *config.GooglePlayConfig) { c.PackageName = "" }, wantErr: ErrNotConfigured},
+		{name: "missing service account", mutate: func(c *config.GooglePlayConfig) { c.ServiceAccountJSON = "" }, wantErr: ErrNotConfigured},
+		{name: "missing products", mutate: func(c *config.GooglePlayConfig) { c.Products = " " }, wantErr: ErrNotConfigured},
+		{name: "bad service account key", mutate: func(c *config.GooglePlayConfig) {
+			c.ServiceAccountJSON = `{"client_email":"a@b","private_key":"nope"}`
+		}, wantErr: ErrInvalidConfig},
+		{name: "consumable without credits", mutate: func(c *config.GooglePlayConfig) {
+			c.Products = `[{"plan_id":"c","product_id":"c","type":"consumable"}]`
+		}, wantErr: ErrInvalidConfig},
+		{name: "duplicate product", mutate: func(c *config.GooglePlayConfig) {
+			c.Products = `[{"plan_id":"a","product_id":"x"},{"plan_id":"b","product_id":"x"}]`
+		}, wantErr: ErrDuplicateProduct},
+		{name: "http api url in prod", appEnv: AppEnvProd, wantErr: ErrInvalidConfig},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := base
+			if tc.mutate != nil {
+				tc.mutate(&cfg)
+			}
+			appEnv := tc.appEnv
+			if appEnv == "" {
+				appEnv = "dev"
+			}
+			if _, err := NewGooglePlayCatalog(cfg, appEnv); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	c, err := NewGooglePlayCatalog(base, "dev")
+	if err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+	if p, err := c.Lookup("credits.100"); err != nil || p.Type != model.ProductTypeConsumable || p.Credits != 100 {
+		t.Fatalf("lookup consumable = %+v, %v", p, err)
+	}
+	if _, err := c.Lookup("missing"); !errors.Is(err, ErrGooglePlayUnknownProduct) {
+		t.Fatalf("lookup missing err = %v", err)
+	}
+}
+
+// fakeGooglePlayAPI 模拟 OAuth token endpoint 与 Android Publisher API，按 purchase token 返回预置 JSON。
+type fakeGooglePlayAPI struct {
+	mu            sync.Mutex
+	subscriptions map[string]map[string]any
+	products      map[string]map[string]any
+	acks          []string
+	tokenCalls    int
+}
+
+func newFakeGooglePlayAPI(t *testing.T) (*fakeGooglePlayAPI, *httptest.Server) {
+	t.Helper()
+	f := &fakeGooglePlayAPI{subscriptions: map[string]map[string]any{}, products: map[string]map[string]any{}}
+	srv := httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeGooglePlayAPI) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("assertion") == "" {
+			http.Error(w, "bad assertion", http.StatusBadRequest)
+			return
+		}
+		f.tokenCalls++
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "access-1", "expires_in": 3600})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer access-1" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	prefix := "/androidpublisher/v3/applications/" + testGooglePackage + "/purchases/"
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	if strings.HasSuffix(path, ":acknowledge") {
+		f.acks = append(f.acks, strings.TrimSuffix(path, ":acknowledge"))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	parts := strings.Split(path, "/")
+	var body map[string]any
+	switch {
+	case len(parts) == 3 && parts[0] == "subscriptionsv2":
+		body = f.subscriptions[parts[2]]
+	case len(parts) == 4 && parts[0] == "products":
+		body = f.products[parts[3]]
+	}
+	if body == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (f *fakeGooglePlayAPI) setSubscription(token string, body map[string]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscriptions[token] = body
+}
+
+func (f *fakeGooglePlayAPI) setProduct(token string, body map[string]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.products[token] = body
+}
+
+func (f *fakeGooglePlayAPI) ackCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.acks)
+}
+
+// fakeGooglePlayDAO 是 dao.GooglePlayDAO 的内存实现；InTx 不回滚，测试只覆盖成功路径的幂等。
+type fakeGooglePlayDAO struct {
+	mu            sync.Mutex
+	events        map[string]model.GooglePlayEventInsert
+	subscriptions map[string]model.GooglePlaySubscription
+	purchases     map[string]model.GooglePlayPurchase
+	grants        []model.CreditGrant
+	refunded      []string
+	nextID        int64
+}
+
+func newFakeGooglePlayDAO() *fakeGooglePlayDAO {
+	return &fakeGooglePlayDAO{
+		events:        map[string]model.GooglePlayEventInsert{},
+		subscriptions: map[string]model.GooglePlaySubscription{},
+		purchases:     map[string]model.GooglePlayPurchase{},
+	}
+}
+
+func (d *fakeGooglePlayDAO) ListSubscriptionsForUserEntitlement(_ context.Context, userID int64) ([]model.GooglePlaySubscription, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []model.GooglePlaySubscription
+	for _, s := range d.subscriptions {
+		if s.UserID == userID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (d *fakeGooglePlayDAO) ListActiveLifetimePurchases(_ context.Context, userID int64) ([]model.GooglePlayPurchase, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []model.GooglePlayPurchase
+	for _, p := range d.purchases {
+		if p.UserID == userID && p.ProductType == model.ProductTypeNonConsumable && p.Status == model.PurchaseStatusActive {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (d *fakeGooglePlayDAO) InTx(_ context.Context, fn func(dao.GooglePlayTx) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return fn(d)
+}
+
+func (d *fakeGooglePlayDAO) InsertEventIfNotExists(_ context.Context, in model.GooglePlayEventInsert) (bool, error) {
+	if _, ok := d.events[in.MessageID]; ok {
+		return false, nil
+	}
+	d.events[in.MessageID] = in
+	return true, nil
+}
+
+func (d *fakeGooglePlayDAO) GetSubscriptionByToken(_ context.Context, token string) (model.GooglePlaySubscription, error) {
+	s, ok := d.subscriptions[token]
+	if !ok {
+		return model.GooglePlaySubscription{}, dao.ErrGooglePlaySubscriptionNotFound
+	}
+	return s, nil
+}
+
+func (d *fakeGooglePlayDAO) UpsertSubscriptionWithOwnershipCheck(_ context.Context, in model.GooglePlaySubscriptionUpsert) (model.GooglePlaySubscription, error) {
+	cur, ok := d.subscriptions[in.PurchaseToken]
+	if ok && cur.UserID != in.UserID {
+		return model.GooglePlaySubscription{}, dao.ErrGooglePlayOwnershipConflict
+	}
+	if !ok {
+		d.nextID++
+		cur.ID = d.nextID
+	}
+	cur.UserID, cur.PurchaseToken, cur.PlanID, cur.ProviderProductID = in.UserID, in.PurchaseToken, in.PlanID, in.ProviderProductID
+	cur.Level, cur.Status, cur.AutoRenewStatus = in.Level, in.Status, in.AutoRenewStatus
+	cur.CurrentPeriodStart, cur.CurrentPeriodEnd, cur.LastEventAt = in.CurrentPeriodStart, in.CurrentPeriodEnd, in.LastEventAt
+	d.subscriptions[in.PurchaseToken] = cur
+	return cur, nil
+}
+
+func (d *fakeGooglePlayDAO) RevokeSubscription(_ context.Context, sub model.GooglePlaySubscription, revokedAt time.Time) (model.GooglePlaySubscription, error) {
+	sub.Status, sub.LastEventAt = model.SubscriptionStatusRevoked, revokedAt
+	d.subscriptions[sub.PurchaseToken] = sub
+	return sub, nil
+}
+
+func (d *fakeGooglePlayDAO) InsertPurchaseIfNotExists(_ context.Context, in model.GooglePlayPurchaseInsert) (model.GooglePlayPurchase, bool, error) {
+	if cur, ok := d.purchases[in.PurchaseToken]; ok {
+		if cur.UserID != in.UserID {
+			return model.GooglePlayPurchase{}, false, dao.ErrGooglePlayOwnershipConflict
+		}
+		return cur, false, nil
+	}
+	d.nextID++
+	p := model.GooglePlayPurchase{
+		ID: d.nextID, UserID: in.UserID, PurchaseToken: in.PurchaseToken, OrderID: in.OrderID,
+		PlanID: in.PlanID, ProviderProductID: in.ProviderProductID, ProductType: in.ProductType,
+		Quantity: in.Quantity, Level: in.Level, Credits: in.Credits, Status: model.PurchaseStatusActive,
+		PurchasedAt: in.PurchasedAt,
+	}
+	d.purchases[in.PurchaseToken] = p
+	return p, true, nil
+}
+
+func (d *fakeGooglePlayDAO) GetPurchaseByToken(_ context.Context, token string) (model.GooglePlayPurchase, error) {
+	p, ok := d.purchases[token]
+	if !ok {
+		return model.GooglePlayPurchase{}, dao.ErrGooglePlayPurchaseNotFound
+	}
+	return p, nil
+}
+
+func (d *fakeGooglePlayDAO) GrantPurchaseCredits(_ context.Context, purchaseID int64, in model.CreditGrant) (model.CreditBucket, error) {
+	d.grants = append(d.grants, in)
+	d.nextID++
+	for token, p := range d.purchases {
+		if p.ID == purchaseID {
+			p.CreditBucketID = d.nextID
+			d.purchases[token] = p
+		}
+	}
+	return model.CreditBucket{ID: d.nextID}, nil
+}
+
+func (d *fakeGooglePlayDAO) RefundPurchase(_ context.Context, p model.GooglePlayPurchase, revokedAt time.Time) (model.GooglePlayPurchase, error) {
+	if p.Status == model.PurchaseStatusRefunded {
+		return p, nil
+	}
+	p.Status, p.RevokedAt = model.PurchaseStatusRefunded, &revokedAt
+	d.purchases[p.PurchaseToken] = p
+	d.refunded = append(d.refunded, p.OrderID)
+	return p, nil
+}
+
+type googlePlayFixture struct {
+	api     *fakeGooglePlayAPI
+	dao     *fakeGooglePlayDAO
+	service *GooglePlayService
+}
+
+const (
+	testGoogleUserID       int64 = 42
+	testGoogleAccountToken       = "00000000-0000-4000-8000-000000000042"
+)
+
+func newGooglePlayFixture(t *testing.T) *googlePlayFixture {
+	t.Helper()
+	api, srv := newFakeGooglePlayAPI(t)
+	catalog, err := NewGooglePlayCatalog(validGooglePlayConfig(t, srv.URL), "dev")
+	if err != nil {
+		t.Fatalf("catalog: %v", err)
+	}
+	publisher, err := NewGooglePlayPublisher(catalog)
+	if err != nil {
+		t.Fatalf("publisher: %v", err)
+	}
+	d := newFakeGooglePlayDAO()
+	tokens := newTokensWithFakeDAO(testGoogleUserID, testGoogleAccountToken)
+	return &googlePlayFixture{api: api, dao: d, service: NewGooglePlayService(catalog, publisher, tokens, d)}
+}
+
+func activeSubscriptionJSON(expiry time.Time, acknowledged bool) map[string]any {
+	ack := "ACKNOWLEDGEMENT_STATE_PENDING"
+	if acknowledged {
+		ack = "ACKNOWLEDGEMENT_STATE_ACKNOWLEDGED"
+	}
+	return map[string]any{
+		"startTime":            expiry.Add(-30 * 24 * time.Hour).Format(time.RFC3339),
+		"subscriptionState":    "SUBSCRIPTION_STATE_ACTIVE",
+		"latestOrderId":        "GPA.1234-5678",
+		"acknowledgementState": ack,
+		"lineItems": []any{map[string]any{
+			"productId":        "pro.monthly",
+			"expiryTime":       expiry.Format(time.RFC3339),
+			"autoRenewingPlan": map[string]any{"autoRenewEnabled": true},
+		}},
+		"externalAccountIdentifiers": map[string]any{"obfuscatedExternalAccountId": testGoogleAccountToken},
+	}
+}
+
+func pushMessage(t *testing.T, messageID string, notification map[string]any) GooglePlayPushMessage {
+	t.Helper()
+	notification["version"] = "1.0"
+	notification["packageName"] = testGooglePackage
+	notification["eventTimeMillis"] = "1760000000000"
+	raw, err := json.Marshal(notification)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return GooglePlayPushMessage{MessageID: messageID, Data: base64.StdEncoding.EncodeToString(raw)}
+}
+
+func TestGooglePlayService_VerifySubscription(t *testing.T) {
+	f := newGooglePlayFixture(t)
+	expiry := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+	f.api.setSubscription("sub-token", activeSubscriptionJSON(expiry, false))
+
+	res, err := f.service.VerifyPurchase(context.Background(), testGoogleUserID, "pro.monthly", "sub-token")
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if res.Subscription == nil || res.Subscription.Status != "ACTIVE" || res.Subscription.ProductID != "pro_monthly" {
+		t.Fatalf("unexpected result: %+v", res.Subscription)
+	}
+	if got := f.dao.subscriptions["sub-token"]; got.UserID != testGoogleUserID || !got.CurrentPeriodEnd.Equal(expiry) {
+		t.Fatalf("unexpected row: %+v", got)
+	}
+	if f.api.ackCount() != 1 {
+		t.Fatalf("acks = %d, want 1", f.api.ackCount())
+	}
+
+	if _, err := f.service.VerifyPurchase(context.Background(), 7, "pro.monthly", "sub-token"); !errors.Is(err, ErrGooglePlayAccountMismatch) {
+		t.Fatalf("other user err = %v, want ErrGooglePlayAccountMismatch", err)
+	}
+	if _, err := f.service.VerifyPurchase(context.Background(), testGoogleUserID, "pro.monthly", "missing"); !errors.Is(err, ErrGooglePlayPurchaseNotFound) {
+		t.Fatalf("missing token err = %v", err)
+	}
+
+	info := resolveInfo(t, testGoogleUserID, time.Now().UTC(), NewGooglePlayEntitlementSource(f.dao))
+	if info.Status != "ACTIVE" || info.SubscribeLevel != 1 {
+		t.Fatalf("entitlement info = %+v", info)
+	}
+}
+
+func TestGooglePlayService_VerifyConsumableGrantsOnce(t *testing.T) {
+	f := newGooglePlayFixture(t)
+	f.api.setProduct("iap-token", map[string]any{
+		"purchaseTimeMillis":          "1760000000000",
+		"purchaseState":               0,
+		"orderId":                     "GPA.9999",
+		"obfuscatedExternalAccountId": testGoogleAccountToken,
+		"quantity":                    2,
+	})
+
+	for i := 0; i < 2; i++ {
+		res, err := f.service.VerifyPurchase(context.Background(), testGoogleUserID, "credits.100", "iap-token")
+		if err != nil {
+			t.Fatalf("verify #%d: %v", i, err)
+		}
+		if res.Purchase == nil || res.Purchase.TransactionID != "GPA.9999" || res.Purchase.CreditsGranted != 200 {
+			t.Fatalf("unexpected purchase: %+v", res.Purchase)
+		}
+	}
+	if len(f.dao.grants) != 1 || f.dao.grants[0].Reference != "google-play:GPA.9999" {
+		t.Fatalf("grants = %+v, want exactly one", f.dao.grants)
+	}
+
+	f.api.setProduct("anon-token", map[string]any{"purchaseState": 0, "orderId": "GPA.1"})
+	if _, err := f.service.VerifyPurchase(context.Background(), testGoogleUserID, "credits.100", "anon-token"); !errors.Is(err, ErrGooglePlayAccountMissing) {
+		t.Fatalf("missing account err = %v, want ErrGooglePlayAccountMissing", err)
+	}
+	if _, ok := f.dao.purchases["anon-token"]; ok {
+		t.Fatalf("purchase without obfuscatedAccountId should not be recorded")
+	}
+
+	f.api.setProduct("pending-token", map[string]any{"purchaseState": 2, "orderId": "GPA.0"})
+	if _, err := f.service.VerifyPurchase(context.Background(), testGoogleUserID, "credits.100", "pending-token"); !errors.Is(err, ErrGooglePlayPurchasePending) {
+		t.Fatalf("pending err = %v", err)
+	}
+}
+
+func TestGooglePlayService_HandlePushMessage(t *testing.T) {
+	f := newGooglePlayFixture(t)
+	ctx := context.Background()
+	expiry := time.Now().Add(30 * 24 * time.Hour).UTC()
+	f.api.setSubscription("rtdn-token", activeSubscriptionJSON(expiry, false))
+
+	msg := pushMessage(t, "m-1", map[string]any{"subscriptionNotification": map[string]any{
+		"notificationType": 4, "purchaseToken": "rtdn-token", "subscriptionId": "pro.monthly",
+	}})
+	if err := f.service.HandlePushMessage(ctx, "wrong", msg); !errors.Is(err, ErrGooglePlayWebhookUnauthorized) {
+		t.Fatalf("bad token err = %v", err)
+	}
+	if err := f.service.HandlePushMessage(ctx, testGoogleWebhookToken, msg); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if got := f.dao.subscriptions["rtdn-token"]; got.UserID != testGoogleUserID || got.Status != model.SubscriptionStatusActive {
+		t.Fatalf("unexpected row: %+v", got)
+	}
+	if err := f.service.HandlePushMessage(ctx, testGoogleWebhookToken, msg); err != nil {
+		t.Fatalf("duplicate push: %v", err)
+	}
+	if len(f.dao.events) != 1 || f.dao.events["m-1"].ProcessingStatus != model.EventStatusProcessed {
+		t.Fatalf("events = %+v", f.dao.events)
+	}
+
+	voided := pushMessage(t, "m-2", map[string]any{"voidedPurchaseNotification": map[string]any{
+		"purchaseToken": "rtdn-token", "orderId": "GPA.1234-5678", "productType": 1,
+	}})
+	if err := f.service.HandlePushMessage(ctx, testGoogleWebhookToken, voided); err != nil {
+		t.Fatalf("voided: %v", err)
+	}
+	if got := f.dao.subscriptions["rtdn-token"]; got.Status != model.SubscriptionStatusRevoked {
+		t.Fatalf("status = %q, want REVOKED", got.Status)
+	}
+
+	unbound := activeSubscriptionJSON(expiry, true)
+	unbound["externalAccountIdentifiers"] = map[string]any{"obfuscatedExternalAccountId": "unknown"}
+	f.api.setSubscription("unbound-token", unbound)
+	msg = pushMessage(t, "m-3", map[string]any{"subscriptionNotification": map[string]any{
+		"notificationType": 4, "purchaseToken": "unbound-token", "subscriptionId": "pro.monthly",
+	}})
+	if err := f.service.HandlePushMessage(ctx, testGoogleWebhookToken, msg); err != nil {
+		t.Fatalf("unbound push: %v", err)
+	}
+	if got := f.dao.events["m-3"].ProcessingStatus; got != model.EventStatusPendingUserBinding {
+		t.Fatalf("status = %q, want PENDING_USER_BINDING", got)
+	}
+	if _, ok := f.dao.subscriptions["unbound-token"]; ok {
+		t.Fatal("unbound subscription must not be written")
+	}
+}
+
+func TestGooglePlayService_VoidedOneTimeRefundsCredits(t *testing.T) {
+	f := newGooglePlayFixture(t)
+	ctx := context.Background()
+	f.api.setProduct("iap-token", map[string]any{
+		"purchaseTimeMillis": "1760000000000", "purchaseState": 0, "orderId": "GPA.7777",
+		"obfuscatedExternalAccountId": testGoogleAccountToken,
+	})
+	msg := pushMessage(t, "p-1", map[string]any{"oneTimeProductNotification": map[string]any{
+		"notificationType": 1, "purchaseToken": "iap-token", "sku": "credits.100",
+	}})
+	if err := f.service.HandlePushMessage(ctx, testGoogleWebhookToken, msg); err != nil {
+		t.Fatalf("purchase push: %v", err)
+	}
+	if len(f.dao.grants) != 1 || f.api.ackCount() != 1 {
+		t.Fatalf("grants = %d, acks = %d", len(f.dao.grants), f.api.ackCount())
+	}
+
+	voided := pushMessage(t, "p-2", map[string]any{"voidedPurchaseNotification": map[string]any{
+		"purchaseToken": "iap-token", "orderId": "GPA.7777", "productType": 2,
+	}})
+	for i := 0; i < 2; i++ {
+		if err := f.service.HandlePushMessage(ctx, testGoogleWebhookToken, voided); err != nil {
+			t.Fatalf("voided push #%d: %v", i, err)
+		}
+	}
+	if len(f.dao.refunded) != 1 || f.dao.purchases["iap-token"].Status != model.PurchaseStatusRefunded {
+		t.Fatalf("refunded = %v, purchase = %+v", f.dao.refunded, f.dao.purchases["iap-token"])
+	}
+}
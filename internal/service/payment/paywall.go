@@ -0,0 +1,224 @@
+package payment
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// 付费墙的客户端平台，决定返回哪个商店的商品 ID。
+const (
+	PaywallPlatformIOS     = "ios"
+	PaywallPlatformAndroid = "android"
+	PaywallPlatformWeb     = "web"
+)
+
+// PaywallPlan 是付费墙上的一个方案（plan）。
+//
+// ProductIDs 是该平台商店中售卖此 plan 的商品 ID：iOS 为 App Store productId，Android 为 Google Play 商品 ID，
+// Web 为 Stripe price id。文案来自 Apple 商品目录中同一 plan 的商品。
+type PaywallPlan struct {
+	PlanID      string
+	Type        string
+	Level       int
+	ProductIDs  []string
+	Title       string
+	Description string
+	Features    []string
+	Recommended bool
+}
+
+// Paywall 是某个平台、语言下的付费墙。Locale 是实际采用的语言标签，为空表示使用默认文案；
+// ExperimentID / VariantID 为调用方所在的实验分组，不在实验中时为空。
+type Paywall struct {
+	Platform     string
+	Locale       string
+	ExperimentID string
+	VariantID    string
+	Plans        []PaywallPlan
+}
+
+// PaywallService 从商品目录生成客户端付费墙。
+//
+// 商品与文案都以 Apple 商品目录（apple_products，热加载）为准：iOS 直接列出上架的 Apple 商品；
+// Android / Web 列出对应商店 catalog 中的 plan，文案、排序与推荐标记按 plan_id 取自 Apple 商品目录，
+// Apple 目录中没有的 plan 以 plan_id 作为标题并排在最后。配置了实验时，登录用户看到所在分组的方案组合。
+type PaywallService struct {
+	apple       *Catalog
+	googlePlay  *GooglePlayCatalog
+	stripe      *StripeCatalog
+	experiments *PaywallExperimentService
+}
+
+// NewPaywallService 构造付费墙 service；任一 catalog 可为 nil，对应平台返回 ErrNotConfigured。
+func NewPaywallService(apple *Catalog, googlePlay *GooglePlayCatalog, stripe *StripeCatalog) *PaywallService {
+	return &PaywallService{apple: apple, googlePlay: googlePlay, stripe: stripe}
+}
+
+// WithExperiments 开启付费墙实验；experiments 为 nil 时所有用户看到同一付费墙。返回 s 便于链式调用。
+func (s *PaywallService) WithExperiments(experiments *PaywallExperimentService) *PaywallService {
+	s.experiments = experiments
+	return s
+}
+
+// paywallMeta 是 Apple 目录中某个 plan 用于展示的商品及其排序位置。
+type paywallMeta struct {
+	product Product
+	rank    int
+}
+
+// Paywall 返回 platform 的付费墙；locales 为按偏好排序的语言标签（如 Accept-Language 解析结果）。
+// userID 为 0 表示未登录，不参与实验。实验分组失败（如数据库不可用）时记录日志并返回默认付费墙。
+func (s *PaywallService) Paywall(ctx context.Context, userID int64, platform string, locales []string) (Paywall, error) {
+	if s == nil {
+		return Paywall{}, ErrNotConfigured
+	}
+	meta := s.appleMeta()
+
+	var products []Product
+	switch platform {
+	case PaywallPlatformIOS:
+		if s.apple == nil {
+			return Paywall{}, ErrNotConfigured
+		}
+		products = s.appleProducts()
+	case PaywallPlatformAndroid:
+		if s.googlePlay == nil {
+			return Paywall{}, ErrNotConfigured
+		}
+		products = s.googlePlay.Products()
+	case PaywallPlatformWeb:
+		if s.stripe == nil {
+			return Paywall{}, ErrNotConfigured
+		}
+		products = s.stripe.Products()
+	default:
+		return Paywall{}, ErrUnknownPlatform
+	}
+
+	plans := make([]PaywallPlan, 0, len(products))
+	ranks := make(map[string]int, len(products))
+	index := make(map[string]int, len(products))
+	for i, p := range products {
+		if at, ok := index[p.PlanID]; ok {
+			if !slices.Contains(plans[at].ProductIDs, p.ProductID) {
+				plans[at].ProductIDs = append(plans[at].ProductIDs, p.ProductID)
+			}
+			continue
+		}
+		plan := PaywallPlan{PlanID: p.PlanID, Type: p.Type, Level: p.Level, ProductIDs: []string{p.ProductID}}
+		ranks[p.PlanID] = len(meta) + i
+		if m, ok := meta[p.PlanID]; ok {
+			plan.Recommended = m.product.Recommended
+			ranks[p.PlanID] = m.rank
+		}
+		index[p.PlanID] = len(plans)
+		plans = append(plans, plan)
+	}
+	sort.SliceStable(plans, func(i, j int) bool { return ranks[plans[i].PlanID] < ranks[plans[j].PlanID] })
+
+	out := Paywall{Platform: platform, Locale: resolvePaywallLocale(locales, meta)}
+	assignment, err := s.experiments.Assign(ctx, userID, platform)
+	if err != nil {
+		slog.Warn("paywall experiment assignment failed; serving default paywall", "user_id", userID, "platform", platform, "err", err)
+	}
+	if assignment != nil {
+		out.ExperimentID, out.VariantID = assignment.ExperimentID, assignment.Variant.ID
+		plans = applyPaywallVariant(plans, assignment.Variant)
+	}
+	out.Plans = plans
+	for i := range out.Plans {
+		localizePlan(&out.Plans[i], meta[out.Plans[i].PlanID].product, out.Locale)
+	}
+	return out, nil
+}
+
+// appleProducts 返回授权环境内未下架的 Apple 商品，按 sort_order 与目录顺序排列。
+func (s *PaywallService) appleProducts() []Product {
+	if s.apple == nil {
+		return nil
+	}
+	products := s.apple.Products()
+	out := products[:0]
+	for _, p := range products {
+		if !p.Delisted && s.apple.IsEntitlementEnvironment(p.Environment) {
+			out = append(out, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].SortOrder < out[j].SortOrder })
+	return out
+}
+
+// appleMeta 按 plan_id 取 Apple 目录中排序最靠前的商品作为该 plan 的展示信息。
+func (s *PaywallService) appleMeta() map[string]paywallMeta {
+	products := s.appleProducts()
+	meta := make(map[string]paywallMeta, len(products))
+	for i, p := range products {
+		if _, ok := meta[p.PlanID]; !ok {
+			meta[p.PlanID] = paywallMeta{product: p, rank: i}
+		}
+	}
+	return meta
+}
+
+// localizePlan 按 locale 填充文案；该语言没有文案时回退到 display_name / description，标题最终回退到 plan_id。
+func localizePlan(plan *PaywallPlan, p Product, locale string) {
+	if l, ok := lookupLocalization(p.Localizations, locale); ok {
+		plan.Title, plan.Description = l.Title, l.Description
+		plan.Features = append([]string(nil), l.Features...)
+		return
+	}
+	plan.Title, plan.Description = p.DisplayName, p.Description
+	if plan.Title == "" {
+		plan.Title = plan.PlanID
+	}
+}
+
+// resolvePaywallLocale 在目录已有的语言中为 locales 选择最匹配的一个：依次尝试每个候选标签本身及其逐级去掉
+// 子标签的形式（zh-Hans-CN → zh-Hans → zh），大小写与 “_” / “-” 不敏感。没有匹配时返回空串。
+func resolvePaywallLocale(locales []string, meta map[string]paywallMeta) string {
+	available := map[string]string{}
+	for _, m := range meta {
+		for locale := range m.product.Localizations {
+			available[normalizeLocale(locale)] = locale
+		}
+	}
+	if len(available) == 0 {
+		return ""
+	}
+	for _, candidate := range locales {
+		tag := normalizeLocale(candidate)
+		for tag != "" {
+			if locale, ok := available[tag]; ok {
+				return locale
+			}
+			cut := strings.LastIndexByte(tag, '-')
+			if cut < 0 {
+				break
+			}
+			tag = tag[:cut]
+		}
+	}
+	return ""
+}
+
+func lookupLocalization(localizations map[string]model.ProductLocalization, locale string) (model.ProductLocalization, bool) {
+	if locale == "" {
+		return model.ProductLocalization{}, false
+	}
+	locale = normalizeLocale(locale)
+	for key, l := range localizations {
+		if normalizeLocale(key) == locale {
+			return l, true
+		}
+	}
+	return model.ProductLocalization{}, false
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -0,0 +1,271 @@
+package payment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// PaywallVariant 是付费墙实验的一个分组。
+//
+// PlanIDs 为空表示对照组，原样展示商品目录；否则付费墙只展示这些 plan 并按此顺序排列（当前平台没有的 plan 忽略），
+// 可以用来测试不同的方案组合或不同定价的商品（定价不同的商品配置为不同的 plan）。RecommendedPlanID 非空时
+// 覆盖目录中的推荐标记，只推荐该 plan。
+type PaywallVariant struct {
+	ID                string   `json:"id"`
+	Weight            int      `json:"weight"`
+	PlanIDs           []string `json:"plan_ids,omitempty"`
+	RecommendedPlanID string   `json:"recommended_plan_id,omitempty"`
+}
+
+// PaywallExperiment 是一个付费墙实验。Platform 为空表示对所有平台生效；同一平台同时只能有一个实验。
+type PaywallExperiment struct {
+	ID       string           `json:"id"`
+	Platform string           `json:"platform,omitempty"`
+	Variants []PaywallVariant `json:"variants"`
+}
+
+// PaywallAssignment 是用户在实验中被分到的组。
+type PaywallAssignment struct {
+	ExperimentID string
+	Variant      PaywallVariant
+}
+
+// PaywallVariantResult 是实验某个分组的结果。Weight 为当前配置的权重，分组已从配置中移除时为 0。
+type PaywallVariantResult struct {
+	VariantID   string
+	Weight      int
+	Exposures   int64
+	Conversions int64
+}
+
+// PaywallExperimentResults 是实验的曝光与转化报表；Active 表示实验仍在当前配置中。
+type PaywallExperimentResults struct {
+	ExperimentID string
+	Active       bool
+	Variants     []PaywallVariantResult
+}
+
+// ParsePaywallExperiments 解析 PAYWALL_EXPERIMENTS；空串表示没有实验。
+func ParsePaywallExperiments(raw string) ([]PaywallExperiment, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var out []PaywallExperiment
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		return nil, fmt.Errorf("paywall experiments: parse: %w", err)
+	}
+	ids := map[string]struct{}{}
+	platforms := map[string]string{}
+	for _, exp := range out {
+		if strings.TrimSpace(exp.ID) == "" {
+			return nil, errors.New("paywall experiments: empty experiment id")
+		}
+		if _, dup := ids[exp.ID]; dup {
+			return nil, fmt.Errorf("paywall experiments: duplicate experiment %q", exp.ID)
+		}
+		ids[exp.ID] = struct{}{}
+		if err := validatePaywallExperiment(exp); err != nil {
+			return nil, err
+		}
+		// 平台为空的实验覆盖所有平台，与任何其他实验都冲突。
+		targets := []string{exp.Platform}
+		if exp.Platform == "" {
+			targets = []string{PaywallPlatformIOS, PaywallPlatformAndroid, PaywallPlatformWeb}
+		}
+		for _, p := range targets {
+			if other, ok := platforms[p]; ok {
+				return nil, fmt.Errorf("paywall experiments: %q and %q both target platform %s", other, exp.ID, p)
+			}
+			platforms[p] = exp.ID
+		}
+	}
+	return out, nil
+}
+
+func validatePaywallExperiment(exp PaywallExperiment) error {
+	switch exp.Platform {
+	case "", PaywallPlatformIOS, PaywallPlatformAndroid, PaywallPlatformWeb:
+	default:
+		return fmt.Errorf("paywall experiments: %q has unknown platform %q", exp.ID, exp.Platform)
+	}
+	if len(exp.Variants) == 0 {
+		return fmt.Errorf("paywall experiments: %q has no variants", exp.ID)
+	}
+	variants := map[string]struct{}{}
+	for _, v := range exp.Variants {
+		if strings.TrimSpace(v.ID) == "" {
+			return fmt.Errorf("paywall experiments: %q has a variant without id", exp.ID)
+		}
+		if _, dup := variants[v.ID]; dup {
+			return fmt.Errorf("paywall experiments: %q has duplicate variant %q", exp.ID, v.ID)
+		}
+		variants[v.ID] = struct{}{}
+		if v.Weight <= 0 {
+			return fmt.Errorf("paywall experiments: %q/%q weight must be positive", exp.ID, v.ID)
+		}
+		for _, planID := range v.PlanIDs {
+			if strings.TrimSpace(planID) == "" {
+				return fmt.Errorf("paywall experiments: %q/%q has an empty plan id", exp.ID, v.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// PaywallExperimentService 为登录用户分配付费墙实验分组并统计转化。
+//
+// 分组由 hash(experiment_id, user_id) 按权重确定，同一用户在任何实例上都得到同一分组；首次曝光写入
+// paywall_exposures 后以库中分组为准，调整权重只影响尚未曝光的用户。转化以曝光后创建的 apple_subscriptions 计，
+// 只统计授权环境（APPLE_IAP_ENTITLEMENT_ENVIRONMENTS）内的订阅，沙盒测试购买不计入生产实验。
+type PaywallExperimentService struct {
+	catalog     *Catalog
+	experiments []PaywallExperiment
+	dao         dao.PaywallExperimentDAO
+	now         func() time.Time
+}
+
+// NewPaywallExperimentService 构造实验 service；experiments 来自 ParsePaywallExperiments，
+// catalog 提供计入转化的订阅环境。
+func NewPaywallExperimentService(catalog *Catalog, experiments []PaywallExperiment, experimentDAO dao.PaywallExperimentDAO) *PaywallExperimentService {
+	return &PaywallExperimentService{
+		catalog:     catalog,
+		experiments: experiments,
+		dao:         experimentDAO,
+		now:         func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Assign 返回 userID 在 platform 上的实验分组并记录曝光；没有进行中的实验或 userID 无效（未登录）时返回 nil。
+// 库中记录的分组已从配置中移除时同样返回 nil，付费墙按对照组展示。
+func (s *PaywallExperimentService) Assign(ctx context.Context, userID int64, platform string) (*PaywallAssignment, error) {
+	if s == nil || s.dao == nil || userID <= 0 {
+		return nil, nil
+	}
+	exp, ok := s.experimentFor(platform)
+	if !ok {
+		return nil, nil
+	}
+	exposure, err := s.dao.RecordExposure(ctx, model.PaywallExposureInsert{
+		ExperimentID: exp.ID,
+		VariantID:    assignPaywallVariant(exp, userID).ID,
+		UserID:       userID,
+		Platform:     platform,
+		ExposedAt:    s.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range exp.Variants {
+		if v.ID == exposure.VariantID {
+			return &PaywallAssignment{ExperimentID: exp.ID, Variant: v}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Results 返回实验各分组的曝光与转化人数：先按配置顺序列出配置中的分组，再列出已移除但仍有曝光的分组。
+// 实验既不在配置中也没有曝光记录时返回 ErrPaywallExperimentNotFound。
+func (s *PaywallExperimentService) Results(ctx context.Context, experimentID string) (PaywallExperimentResults, error) {
+	if s == nil || s.dao == nil {
+		return PaywallExperimentResults{}, ErrNotConfigured
+	}
+	stats, err := s.dao.ListVariantStats(ctx, experimentID, s.catalog.AllowedEntitlementEnvironments())
+	if err != nil {
+		return PaywallExperimentResults{}, err
+	}
+	byVariant := make(map[string]model.PaywallVariantStats, len(stats))
+	for _, st := range stats {
+		byVariant[st.VariantID] = st
+	}
+
+	out := PaywallExperimentResults{ExperimentID: experimentID}
+	for _, exp := range s.experiments {
+		if exp.ID != experimentID {
+			continue
+		}
+		out.Active = true
+		for _, v := range exp.Variants {
+			st := byVariant[v.ID]
+			out.Variants = append(out.Variants, PaywallVariantResult{
+				VariantID: v.ID, Weight: v.Weight, Exposures: st.Exposures, Conversions: st.Conversions,
+			})
+			delete(byVariant, v.ID)
+		}
+	}
+	if !out.Active && len(stats) == 0 {
+		return PaywallExperimentResults{}, ErrPaywallExperimentNotFound
+	}
+	for _, st := range stats {
+		if _, removed := byVariant[st.VariantID]; removed {
+			out.Variants = append(out.Variants, PaywallVariantResult{
+				VariantID: st.VariantID, Exposures: st.Exposures, Conversions: st.Conversions,
+			})
+		}
+	}
+	return out, nil
+}
+
+func (s *PaywallExperimentService) experimentFor(platform string) (PaywallExperiment, bool) {
+	for _, exp := range s.experiments {
+		if exp.Platform == "" || exp.Platform == platform {
+			return exp, true
+		}
+	}
+	return PaywallExperiment{}, false
+}
+
+// assignPaywallVariant 取 SHA-256(experiment_id:user_id) 的前 8 字节对总权重取模，落在哪个分组的权重区间即分到该组。
+// 以 experiment_id 参与哈希，同一用户在不同实验中的分组互相独立。
+func assignPaywallVariant(exp PaywallExperiment, userID int64) PaywallVariant {
+	var total uint64
+	for _, v := range exp.Variants {
+		total += uint64(v.Weight)
+	}
+	sum := sha256.Sum256([]byte(exp.ID + ":" + strconv.FormatInt(userID, 10)))
+	bucket := binary.BigEndian.Uint64(sum[:8]) % total
+	for _, v := range exp.Variants {
+		if bucket < uint64(v.Weight) {
+			return v
+		}
+		bucket -= uint64(v.Weight)
+	}
+	return exp.Variants[len(exp.Variants)-1]
+}
+
+// applyPaywallVariant 按分组筛选、排序 plans 并覆盖推荐标记；对照组原样返回。
+// 分组的 plan 在当前平台一个都没有时原样返回，避免展示空的付费墙。
+func applyPaywallVariant(plans []PaywallPlan, v PaywallVariant) []PaywallPlan {
+	out := plans
+	if len(v.PlanIDs) > 0 {
+		byID := make(map[string]PaywallPlan, len(plans))
+		for _, p := range plans {
+			byID[p.PlanID] = p
+		}
+		filtered := make([]PaywallPlan, 0, len(v.PlanIDs))
+		for _, planID := range v.PlanIDs {
+			if p, ok := byID[planID]; ok {
+				filtered = append(filtered, p)
+				delete(byID, planID)
+			}
+		}
+		if len(filtered) > 0 {
+			out = filtered
+		}
+	}
+	if v.RecommendedPlanID != "" {
+		for i := range out {
+			out[i].Recommended = out[i].PlanID == v.RecommendedPlanID
+		}
+	}
+	return out
+}
@@ -0,0 +1,185 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+type paywallExposureKey struct {
+	experimentID string
+	userID       int64
+}
+
+// fakePaywallExperimentDAO keeps the first variant recorded for each (experiment, user), like the upsert.
+type fakePaywallExperimentDAO struct {
+	exposures map[paywallExposureKey]model.PaywallExposure
+	stats     []model.PaywallVariantStats
+	envs      []model.AppleEnvironment
+	err       error
+}
+
+func (f *fakePaywallExperimentDAO) RecordExposure(_ context.Context, in model.PaywallExposureInsert) (model.PaywallExposure, error) {
+	if f.err != nil {
+		return model.PaywallExposure{}, f.err
+	}
+	if f.exposures == nil {
+		f.exposures = map[paywallExposureKey]model.PaywallExposure{}
+	}
+	key := paywallExposureKey{in.ExperimentID, in.UserID}
+	e, ok := f.exposures[key]
+	if !ok {
+		e = model.PaywallExposure{ExperimentID: in.ExperimentID, VariantID: in.VariantID, UserID: in.UserID, Platform: in.Platform, FirstExposedAt: in.ExposedAt}
+	}
+	e.LastExposedAt = in.ExposedAt
+	f.exposures[key] = e
+	return e, nil
+}
+
+func (f *fakePaywallExperimentDAO) ListVariantStats(_ context.Context, _ string, envs []model.AppleEnvironment) ([]model.PaywallVariantStats, error) {
+	f.envs = envs
+	return f.stats, f.err
+}
+
+const testPaywallExperiments = `[{"id":"annual_first","platform":"ios","variants":[
+	{"id":"control","weight":1},
+	{"id":"monthly_only","weight":3,"plan_ids":["pro_monthly","unknown_plan"],"recommended_plan_id":"pro_monthly"}
+]}]`
+
+func TestParsePaywallExperiments(t *testing.T) {
+	if exps, err := ParsePaywallExperiments(" "); err != nil || exps != nil {
+		t.Fatalf("empty config = %+v, %v", exps, err)
+	}
+	exps, err := ParsePaywallExperiments(testPaywallExperiments)
+	if err != nil || len(exps) != 1 || len(exps[0].Variants) != 2 || exps[0].Variants[1].PlanIDs[0] != "pro_monthly" {
+		t.Fatalf("parse = %+v, %v", exps, err)
+	}
+	for name, raw := range map[string]string{
+		"bad json":          `{`,
+		"empty id":          `[{"variants":[{"id":"a","weight":1}]}]`,
+		"duplicate id":      `[{"id":"x","platform":"ios","variants":[{"id":"a","weight":1}]},{"id":"x","platform":"web","variants":[{"id":"a","weight":1}]}]`,
+		"unknown platform":  `[{"id":"x","platform":"tv","variants":[{"id":"a","weight":1}]}]`,
+		"no variants":       `[{"id":"x","variants":[]}]`,
+		"zero weight":       `[{"id":"x","variants":[{"id":"a","weight":0}]}]`,
+		"duplicate variant": `[{"id":"x","variants":[{"id":"a","weight":1},{"id":"a","weight":1}]}]`,
+		"empty plan id":     `[{"id":"x","variants":[{"id":"a","weight":1,"plan_ids":[""]}]}]`,
+		"platform overlap":  `[{"id":"x","platform":"ios","variants":[{"id":"a","weight":1}]},{"id":"y","variants":[{"id":"a","weight":1}]}]`,
+	} {
+		if _, err := ParsePaywallExperiments(raw); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestAssignPaywallVariant_DeterministicAndWeighted(t *testing.T) {
+	exps, err := ParsePaywallExperiments(testPaywallExperiments)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	counts := map[string]int{}
+	for userID := int64(1); userID <= 4000; userID++ {
+		v := assignPaywallVariant(exps[0], userID)
+		if again := assignPaywallVariant(exps[0], userID); again.ID != v.ID {
+			t.Fatalf("user %d assigned %s then %s", userID, v.ID, again.ID)
+		}
+		counts[v.ID]++
+	}
+	// 权重 1:3，允许 ±5% 的抽样误差。
+	if share := float64(counts["monthly_only"]) / 4000; share < 0.70 || share > 0.80 {
+		t.Fatalf("monthly_only share = %.3f, want ~0.75 (%v)", share, counts)
+	}
+}
+
+func TestPaywallService_Experiment(t *testing.T) {
+	exps, err := ParsePaywallExperiments(testPaywallExperiments)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	// 找一个落在 monthly_only 分组的用户。
+	var userID int64 = 1
+	for assignPaywallVariant(exps[0], userID).ID != "monthly_only" {
+		userID++
+	}
+	store := &fakePaywallExperimentDAO{}
+	experiments := NewPaywallExperimentService(newPaywallCatalog(t), exps, store)
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	experiments.now = func() time.Time { return now }
+	svc := NewPaywallService(newPaywallCatalog(t), nil, nil).WithExperiments(experiments)
+	ctx := context.Background()
+
+	got, err := svc.Paywall(ctx, userID, PaywallPlatformIOS, nil)
+	if err != nil {
+		t.Fatalf("paywall: %v", err)
+	}
+	if got.ExperimentID != "annual_first" || got.VariantID != "monthly_only" {
+		t.Fatalf("assignment = %s/%s", got.ExperimentID, got.VariantID)
+	}
+	if len(got.Plans) != 1 || got.Plans[0].PlanID != "pro_monthly" || !got.Plans[0].Recommended {
+		t.Fatalf("variant not applied: %+v", got.Plans)
+	}
+
+	// 已曝光的用户保持原分组，即使配置改为全部进入对照组。
+	exps[0].Variants[0].Weight = 1000
+	now = now.Add(time.Hour)
+	if got, _ := svc.Paywall(ctx, userID, PaywallPlatformIOS, nil); got.VariantID != "monthly_only" {
+		t.Fatalf("assignment must be sticky, got %s", got.VariantID)
+	}
+	if len(store.exposures) != 1 {
+		t.Fatalf("exposures = %+v", store.exposures)
+	}
+	for _, e := range store.exposures {
+		if !e.LastExposedAt.After(e.FirstExposedAt) {
+			t.Fatalf("repeat exposure must refresh last_exposed_at: %+v", e)
+		}
+	}
+
+	// 未登录或分组失败时返回默认付费墙。
+	store.err = errors.New("db down")
+	for _, uid := range []int64{0, userID} {
+		got, err := svc.Paywall(ctx, uid, PaywallPlatformIOS, nil)
+		if err != nil || got.ExperimentID != "" || len(got.Plans) != 3 || got.Plans[0].PlanID != "pro_yearly" || !got.Plans[0].Recommended {
+			t.Fatalf("default paywall for user %d = %+v, %v", uid, got, err)
+		}
+	}
+}
+
+func TestPaywallExperimentService_Results(t *testing.T) {
+	exps, err := ParsePaywallExperiments(testPaywallExperiments)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	store := &fakePaywallExperimentDAO{stats: []model.PaywallVariantStats{
+		{VariantID: "control", Exposures: 100, Conversions: 3},
+		{VariantID: "legacy", Exposures: 10, Conversions: 1},
+	}}
+	svc := NewPaywallExperimentService(newPaywallCatalog(t), exps, store)
+
+	res, err := svc.Results(context.Background(), "annual_first")
+	if err != nil || !res.Active {
+		t.Fatalf("results = %+v, %v", res, err)
+	}
+	if len(store.envs) != 1 || store.envs[0] != EnvProduction {
+		t.Fatalf("conversions must be limited to entitlement environments, got %v", store.envs)
+	}
+	want := []PaywallVariantResult{
+		{VariantID: "control", Weight: 1, Exposures: 100, Conversions: 3},
+		{VariantID: "monthly_only", Weight: 3},
+		{VariantID: "legacy", Exposures: 10, Conversions: 1},
+	}
+	if len(res.Variants) != len(want) {
+		t.Fatalf("variants = %+v, want %+v", res.Variants, want)
+	}
+	for i := range want {
+		if res.Variants[i] != want[i] {
+			t.Fatalf("variant %d = %+v, want %+v", i, res.Variants[i], want[i])
+		}
+	}
+
+	store.stats = nil
+	if _, err := svc.Results(context.Background(), "missing"); !errors.Is(err, ErrPaywallExperimentNotFound) {
+		t.Fatalf("missing experiment err = %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+func newPaywallCatalog(t testing.TB) *Catalog {
+	t.Helper()
+	cfg := validProdConfig()
+	cfg.Products = `[
+		{"plan_id":"pro_monthly","product_id":"com.app.pro.monthly","level":1,"environment":"Production","sort_order":20,"display_name":"Pro Monthly",
+		 "localizations":{"zh-Hans":{"title":"Pro 月度","description":"按月订阅","features":["无限导出","去除广告"]},"en":{"title":"Pro Monthly"}}},
+		{"plan_id":"pro_yearly","product_id":"com.app.pro.yearly","level":1,"environment":"Production","sort_order":10,"recommended":true,
+		 "localizations":{"zh-Hans":{"title":"Pro 年度"}}},
+		{"plan_id":"pro_yearly","product_id":"com.app.pro.yearly","level":1,"environment":"Sandbox","sort_order":10},
+		{"plan_id":"credits_100","product_id":"com.app.credits.100","type":"consumable","credits":100,"environment":"Production","sort_order":30}
+	]`
+	c, err := NewCatalog(cfg, "dev")
+	if err != nil {
+		t.Fatalf("catalog: %v", err)
+	}
+	return c
+}
+
+func TestPaywallService_IOS(t *testing.T) {
+	svc := NewPaywallService(newPaywallCatalog(t), nil, nil)
+
+	got, err := svc.Paywall(context.Background(), 0, PaywallPlatformIOS, []string{"zh-Hans-CN", "en"})
+	if err != nil {
+		t.Fatalf("paywall: %v", err)
+	}
+	if got.Locale != "zh-Hans" {
+		t.Fatalf("locale = %q, want zh-Hans", got.Locale)
+	}
+	var ids []string
+	for _, p := range got.Plans {
+		ids = append(ids, p.PlanID)
+	}
+	// 只列出授权环境（Production）内的商品，按 sort_order 排序。
+	if !slices.Equal(ids, []string{"pro_yearly", "pro_monthly", "credits_100"}) {
+		t.Fatalf("plans = %v", ids)
+	}
+	yearly, monthly, credits := got.Plans[0], got.Plans[1], got.Plans[2]
+	if !yearly.Recommended || yearly.Title != "Pro 年度" || !slices.Equal(yearly.ProductIDs, []string{"com.app.pro.yearly"}) {
+		t.Fatalf("yearly = %+v", yearly)
+	}
+	if monthly.Title != "Pro 月度" || monthly.Description != "按月订阅" || len(monthly.Features) != 2 {
+		t.Fatalf("monthly = %+v", monthly)
+	}
+	if credits.Title != "credits_100" || credits.Type != model.ProductTypeConsumable {
+		t.Fatalf("untranslated plan must fall back to plan_id: %+v", credits)
+	}
+
+	// 没有匹配的语言时使用 display_name。
+	got, err = svc.Paywall(context.Background(), 0, PaywallPlatformIOS, []string{"fr"})
+	if err != nil || got.Locale != "" || got.Plans[1].Title != "Pro Monthly" || got.Plans[1].Features != nil {
+		t.Fatalf("fallback paywall = %+v, err=%v", got, err)
+	}
+}
+
+func TestPaywallService_OtherStores(t *testing.T) {
+	stripe, err := NewStripeCatalog(validStripeConfig("http://127.0.0.1:1"), "dev")
+	if err != nil {
+		t.Fatalf("stripe catalog: %v", err)
+	}
+	svc := NewPaywallService(newPaywallCatalog(t), nil, stripe)
+
+	got, err := svc.Paywall(context.Background(), 0, PaywallPlatformWeb, []string{"en-US"})
+	if err != nil {
+		t.Fatalf("paywall: %v", err)
+	}
+	if len(got.Plans) != 2 {
+		t.Fatalf("plans = %+v", got.Plans)
+	}
+	pro, maxPlan := got.Plans[0], got.Plans[1]
+	if pro.PlanID != "pro_monthly" || pro.Title != "Pro Monthly" || !slices.Equal(pro.ProductIDs, []string{"price_pro"}) {
+		t.Fatalf("stripe plan must reuse the apple copy: %+v", pro)
+	}
+	if maxPlan.PlanID != "max_monthly" || maxPlan.Title != "max_monthly" || maxPlan.Level != 2 {
+		t.Fatalf("plan missing from the apple catalog = %+v", maxPlan)
+	}
+
+	if _, err := svc.Paywall(context.Background(), 0, PaywallPlatformAndroid, nil); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("android err = %v, want ErrNotConfigured", err)
+	}
+	if _, err := svc.Paywall(context.Background(), 0, "tv", nil); !errors.Is(err, ErrUnknownPlatform) {
+		t.Fatalf("unknown platform err = %v, want ErrUnknownPlatform", err)
+	}
+}
+
+func TestPaywallService_FollowsCatalogReload(t *testing.T) {
+	catalog := newPaywallCatalog(t)
+	svc := NewPaywallService(catalog, nil, nil)
+
+	if err := catalog.ReplaceProducts([]Product{
+		{PlanID: "max_monthly", ProductID: "com.app.max.monthly", Level: 2, Environment: EnvProduction, DisplayName: "Max"},
+		{PlanID: "pro_monthly", ProductID: "com.app.pro.monthly", Level: 1, Environment: EnvProduction, Delisted: true},
+	}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	got, err := svc.Paywall(context.Background(), 0, PaywallPlatformIOS, nil)
+	if err != nil || len(got.Plans) != 1 || got.Plans[0].Title != "Max" {
+		t.Fatalf("paywall after reload = %+v, err=%v", got, err)
+	}
+}
@@ -0,0 +1,225 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	logpkg "github.com/dundunHa/go-serverhttp-template/pkg/log"
+)
+
+// ProductCatalogService 把 apple_products 表同步到运行期 Catalog，并提供管理后台的商品增改。
+//
+// 每个实例周期性地加载全部商品并原子替换 catalog 的商品列表，后台修改在所有实例上于一个刷新周期内生效，
+// 无需重启。表为空时用 APPLE_IAP_PRODUCTS 初始化；表仍为空或加载失败时 catalog 保持回退 / 上一份列表。
+//
+// 商品只能下架（active=false）不能删除：已下架商品仍留在 catalog 中（Product.Delisted），
+// verify 与通知照常按 product_id 解析已有订阅与购买，只有 paywall 不再展示。
+type ProductCatalogService struct {
+	catalog *Catalog
+	dao     dao.AppleProductDAO
+
+	// refreshMu 串行化 Refresh，避免较早读出的列表覆盖较新的列表。
+	refreshMu sync.Mutex
+}
+
+// NewProductCatalogService 构造数据库 catalog 同步 service。
+func NewProductCatalogService(catalog *Catalog, productDAO dao.AppleProductDAO) *ProductCatalogService {
+	return &ProductCatalogService{catalog: catalog, dao: productDAO}
+}
+
+// Seed 在 apple_products 为空时写入 APPLE_IAP_PRODUCTS 中的商品；表非空时不做任何改动。
+// 多个实例同时启动时由 (product_id, environment) 唯一约束去重。
+func (s *ProductCatalogService) Seed(ctx context.Context) error {
+	if s == nil || s.catalog == nil || s.dao == nil {
+		return ErrNotConfigured
+	}
+	n, err := s.dao.CountProducts(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	seed := s.catalog.SeedProducts()
+	in := make([]model.AppleProductInput, 0, len(seed))
+	for i, p := range seed {
+		in = append(in, model.AppleProductInput{
+			PlanID:              p.PlanID,
+			ProductID:           p.ProductID,
+			Environment:         p.Environment,
+			Type:                p.Type,
+			Level:               p.Level,
+			Credits:             p.Credits,
+			SubscriptionGroupID: p.SubscriptionGroupID,
+			DisplayName:         p.DisplayName,
+			Description:         p.Description,
+			SortOrder:           seedSortOrder(p, i),
+			Active:              true,
+			Localizations:       p.Localizations,
+			Recommended:         p.Recommended,
+			PriceMilliunits:     p.PriceMilliunits,
+			Currency:            p.Currency,
+			BillingPeriod:       p.BillingPeriod,
+		})
+	}
+	inserted, err := s.dao.SeedProducts(ctx, in)
+	if err != nil {
+		return err
+	}
+	if inserted > 0 {
+		slog.Info("apple product catalog seeded from APPLE_IAP_PRODUCTS", "count", inserted)
+	}
+	return nil
+}
+
+// Refresh 加载全部商品（含已下架）并替换 catalog 的商品列表。DB 错误或数据不合法时保持当前列表并返回错误。
+func (s *ProductCatalogService) Refresh(ctx context.Context) error {
+	if s == nil || s.catalog == nil || s.dao == nil {
+		return ErrNotConfigured
+	}
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	rows, err := s.dao.ListProducts(ctx, false)
+	if err != nil {
+		return err
+	}
+	products := make([]Product, 0, len(rows))
+	for _, row := range rows {
+		products = append(products, productFromRow(row))
+	}
+	return s.catalog.ReplaceProducts(products)
+}
+
+// Run 执行一次 Refresh，作为后台任务的周期回调。失败时 catalog 保持上一份列表。
+func (s *ProductCatalogService) Run(ctx context.Context) error {
+	return s.Refresh(ctx)
+}
+
+// ListProducts 返回全部商品（含已下架），按 sort_order、id 升序。
+func (s *ProductCatalogService) ListProducts(ctx context.Context) ([]model.AppleProduct, error) {
+	if s == nil || s.dao == nil {
+		return nil, ErrNotConfigured
+	}
+	return s.dao.ListProducts(ctx, false)
+}
+
+// CreateProduct 新增一条商品并立即刷新本实例的 catalog；其他实例在下一个刷新周期生效。
+func (s *ProductCatalogService) CreateProduct(ctx context.Context, actorID int64, in model.AppleProductInput) (model.AppleProduct, error) {
+	if s == nil || s.catalog == nil || s.dao == nil {
+		return model.AppleProduct{}, ErrNotConfigured
+	}
+	in, err := normalizeProductInput(in)
+	if err != nil {
+		return model.AppleProduct{}, err
+	}
+	row, err := s.dao.CreateProduct(ctx, in)
+	if err != nil {
+		return model.AppleProduct{}, err
+	}
+	s.afterWrite(ctx, "create", actorID, row)
+	return row, nil
+}
+
+// UpdateProduct 覆盖 id 对应商品的可变字段（下架即 Active=false）并立即刷新本实例的 catalog。
+//
+// product_id、environment 与 type 决定已有订阅 / 购买如何解析，创建后不可修改；与现有值不同时返回 ErrInvalidProduct。
+func (s *ProductCatalogService) UpdateProduct(ctx context.Context, actorID, id int64, in model.AppleProductInput) (model.AppleProduct, error) {
+	if s == nil || s.catalog == nil || s.dao == nil {
+		return model.AppleProduct{}, ErrNotConfigured
+	}
+	in, err := normalizeProductInput(in)
+	if err != nil {
+		return model.AppleProduct{}, err
+	}
+	existing, err := s.dao.GetProduct(ctx, id)
+	if err != nil {
+		return model.AppleProduct{}, err
+	}
+	if in.ProductID != existing.ProductID || in.Environment != existing.Environment || in.Type != existing.Type {
+		return model.AppleProduct{}, fmt.Errorf("%w: product_id、environment、type 不可修改", ErrInvalidProduct)
+	}
+	row, err := s.dao.UpdateProduct(ctx, id, in)
+	if err != nil {
+		return model.AppleProduct{}, err
+	}
+	s.afterWrite(ctx, "update", actorID, row)
+	return row, nil
+}
+
+// afterWrite 记录审计日志并刷新 catalog；刷新失败不影响已提交的修改，由周期任务补齐。
+func (s *ProductCatalogService) afterWrite(ctx context.Context, action string, actorID int64, row model.AppleProduct) {
+	logpkg.FromContext(ctx).Info("admin apple product change",
+		"action", action,
+		"actor_user_id", actorID,
+		"id", row.ID,
+		"plan_id", row.PlanID,
+		"product_id", row.ProductID,
+		"environment", row.Environment,
+		"active", row.Active,
+	)
+	if err := s.Refresh(ctx); err != nil {
+		slog.Warn("apple product catalog refresh after admin change failed", "err", err)
+	}
+}
+
+// normalizeProductInput 按 catalog 的规则校验并补齐商品；不合法时返回 wrap ErrInvalidProduct 的错误。
+func normalizeProductInput(in model.AppleProductInput) (model.AppleProductInput, error) {
+	p := Product{
+		PlanID:      strings.TrimSpace(in.PlanID),
+		ProductID:   strings.TrimSpace(in.ProductID),
+		Type:        strings.TrimSpace(in.Type),
+		Level:       in.Level,
+		Credits:     in.Credits,
+		Environment: in.Environment,
+
+		Localizations: in.Localizations,
+
+		PriceMilliunits: in.PriceMilliunits,
+		Currency:        in.Currency,
+		BillingPeriod:   in.BillingPeriod,
+	}
+	if err := validateProduct(&p); err != nil {
+		return in, fmt.Errorf("%w: %v", ErrInvalidProduct, err)
+	}
+	in.PlanID, in.ProductID, in.Type = p.PlanID, p.ProductID, p.Type
+	in.Currency, in.BillingPeriod = p.Currency, p.BillingPeriod
+	in.SubscriptionGroupID = strings.TrimSpace(in.SubscriptionGroupID)
+	in.DisplayName = strings.TrimSpace(in.DisplayName)
+	in.Description = strings.TrimSpace(in.Description)
+	return in, nil
+}
+
+func productFromRow(row model.AppleProduct) Product {
+	return Product{
+		PlanID:              row.PlanID,
+		ProductID:           row.ProductID,
+		Type:                row.Type,
+		Level:               row.Level,
+		Credits:             row.Credits,
+		Environment:         row.Environment,
+		SubscriptionGroupID: row.SubscriptionGroupID,
+		DisplayName:         row.DisplayName,
+		Description:         row.Description,
+		SortOrder:           row.SortOrder,
+		Localizations:       row.Localizations,
+		Recommended:         row.Recommended,
+		PriceMilliunits:     row.PriceMilliunits,
+		Currency:            row.Currency,
+		BillingPeriod:       row.BillingPeriod,
+		Delisted:            !row.Active,
+	}
+}
+
+// seedSortOrder 为未配置 sort_order 的种子商品按 APPLE_IAP_PRODUCTS 中的顺序编号，保持原有展示顺序。
+func seedSortOrder(p Product, index int) int {
+	if p.SortOrder != 0 {
+		return p.SortOrder
+	}
+	return (index + 1) * 10
+}
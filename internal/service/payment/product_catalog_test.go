@@ -0,0 +1,180 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+type fakeAppleProductDAO struct {
+	rows    []model.AppleProduct
+	listErr error
+}
+
+func (d *fakeAppleProductDAO) ListProducts(_ context.Context, activeOnly bool) ([]model.AppleProduct, error) {
+	if d.listErr != nil {
+		return nil, d.listErr
+	}
+	out := []model.AppleProduct{}
+	for _, r := range d.rows {
+		if r.Active || !activeOnly {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (d *fakeAppleProductDAO) CountProducts(context.Context) (int64, error) {
+	return int64(len(d.rows)), nil
+}
+
+func (d *fakeAppleProductDAO) SeedProducts(ctx context.Context, in []model.AppleProductInput) (int64, error) {
+	for _, p := range in {
+		if _, err := d.CreateProduct(ctx, p); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(in)), nil
+}
+
+func (d *fakeAppleProductDAO) CreateProduct(_ context.Context, in model.AppleProductInput) (model.AppleProduct, error) {
+	for _, r := range d.rows {
+		if r.ProductID == in.ProductID && r.Environment == in.Environment {
+			return model.AppleProduct{}, dao.ErrDuplicateAppleProduct
+		}
+	}
+	row := appleProductRow(int64(len(d.rows)+1), in)
+	d.rows = append(d.rows, row)
+	return row, nil
+}
+
+func (d *fakeAppleProductDAO) GetProduct(_ context.Context, id int64) (model.AppleProduct, error) {
+	for _, r := range d.rows {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return model.AppleProduct{}, dao.ErrAppleProductNotFound
+}
+
+func (d *fakeAppleProductDAO) UpdateProduct(_ context.Context, id int64, in model.AppleProductInput) (model.AppleProduct, error) {
+	for i, r := range d.rows {
+		if r.ID == id {
+			in.ProductID, in.Environment, in.Type = r.ProductID, r.Environment, r.Type
+			d.rows[i] = appleProductRow(id, in)
+			return d.rows[i], nil
+		}
+	}
+	return model.AppleProduct{}, dao.ErrAppleProductNotFound
+}
+
+func appleProductRow(id int64, in model.AppleProductInput) model.AppleProduct {
+	return model.AppleProduct{
+		ID: id, PlanID: in.PlanID, ProductID: in.ProductID, Environment: in.Environment, Type: in.Type,
+		Level: in.Level, Credits: in.Credits, SubscriptionGroupID: in.SubscriptionGroupID,
+		DisplayName: in.DisplayName, Description: in.Description, SortOrder: in.SortOrder, Active: in.Active,
+	}
+}
+
+func TestProductCatalogService_SeedAndRefresh(t *testing.T) {
+	cat := newOneTimeCatalog(t)
+	productDAO := &fakeAppleProductDAO{}
+	svc := NewProductCatalogService(cat, productDAO)
+	ctx := context.Background()
+
+	if err := svc.Seed(ctx); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if len(productDAO.rows) != len(cat.SeedProducts()) {
+		t.Fatalf("seeded %d rows, want %d", len(productDAO.rows), len(cat.SeedProducts()))
+	}
+	if productDAO.rows[0].SortOrder != 10 || productDAO.rows[1].SortOrder != 20 {
+		t.Fatalf("seed must keep APPLE_IAP_PRODUCTS order: %+v", productDAO.rows)
+	}
+	// 表非空时再次 Seed 不写入。
+	if err := svc.Seed(ctx); err != nil || len(productDAO.rows) != len(cat.SeedProducts()) {
+		t.Fatalf("second seed must be a no-op: rows=%d err=%v", len(productDAO.rows), err)
+	}
+
+	created, err := svc.CreateProduct(ctx, 1, model.AppleProductInput{
+		PlanID: "pro_yearly", ProductID: " com.app.pro.yearly ", Environment: EnvProduction, Level: 1, Active: true,
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.ProductID != "com.app.pro.yearly" || created.Type != model.ProductTypeSubscription {
+		t.Fatalf("create must normalize input: %+v", created)
+	}
+	if _, err := cat.Lookup("com.app.pro.yearly", EnvProduction); err != nil {
+		t.Fatalf("created product must be live without restart, err=%v", err)
+	}
+
+	// 下架后仍可解析（已有订阅的续期、退款照常处理），只标记为 Delisted；其余商品不受影响。
+	in := model.AppleProductInput{PlanID: "pro_yearly", ProductID: "com.app.pro.yearly", Environment: EnvProduction, Level: 1}
+	if _, err := svc.UpdateProduct(ctx, 1, created.ID, in); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	if p, err := cat.Lookup("com.app.pro.yearly", EnvProduction); err != nil || !p.Delisted {
+		t.Fatalf("deactivated product must stay resolvable as delisted: %+v err=%v", p, err)
+	}
+	if _, err := cat.Lookup("com.app.pro.monthly", EnvProduction); err != nil {
+		t.Fatalf("other products must stay live, err=%v", err)
+	}
+
+	// DB 不可用时保持当前列表。
+	productDAO.listErr = errors.New("db down")
+	if err := svc.Refresh(ctx); err == nil {
+		t.Fatal("refresh must surface the DB error")
+	}
+	if _, err := cat.Lookup("com.app.pro.monthly", EnvProduction); err != nil {
+		t.Fatalf("failed refresh must keep the current products, err=%v", err)
+	}
+}
+
+func TestProductCatalogService_WriteErrors(t *testing.T) {
+	svc := NewProductCatalogService(newOneTimeCatalog(t), &fakeAppleProductDAO{})
+	ctx := context.Background()
+
+	if _, err := svc.CreateProduct(ctx, 1, model.AppleProductInput{PlanID: "x", ProductID: "com.app.x", Environment: EnvProduction, Type: model.ProductTypeConsumable}); !errors.Is(err, ErrInvalidProduct) {
+		t.Fatalf("consumable without credits err = %v, want ErrInvalidProduct", err)
+	}
+	if _, err := svc.CreateProduct(ctx, 1, model.AppleProductInput{PlanID: "x", ProductID: "com.app.x", Environment: "Lunar"}); !errors.Is(err, ErrInvalidProduct) {
+		t.Fatalf("unknown environment err = %v, want ErrInvalidProduct", err)
+	}
+	untitled := map[string]model.ProductLocalization{"en": {Description: "no title"}}
+	if _, err := svc.CreateProduct(ctx, 1, model.AppleProductInput{PlanID: "x", ProductID: "com.app.x", Environment: EnvProduction, Localizations: untitled}); !errors.Is(err, ErrInvalidProduct) {
+		t.Fatalf("localization without title err = %v, want ErrInvalidProduct", err)
+	}
+	in := model.AppleProductInput{PlanID: "x", ProductID: "com.app.x", Environment: EnvProduction, Active: true}
+	if _, err := svc.CreateProduct(ctx, 1, in); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.CreateProduct(ctx, 1, in); !errors.Is(err, ErrAppleProductConflict) {
+		t.Fatalf("duplicate err = %v, want ErrAppleProductConflict", err)
+	}
+	if _, err := svc.UpdateProduct(ctx, 1, 99, in); !errors.Is(err, ErrAppleProductNotFound) {
+		t.Fatalf("missing id err = %v, want ErrAppleProductNotFound", err)
+	}
+	// product_id / environment / type 创建后不可修改。
+	for name, change := range map[string]func(*model.AppleProductInput){
+		"product_id":  func(p *model.AppleProductInput) { p.ProductID = "com.app.y" },
+		"environment": func(p *model.AppleProductInput) { p.Environment = EnvSandbox },
+		"type":        func(p *model.AppleProductInput) { p.Type = model.ProductTypeNonConsumable },
+	} {
+		changed := in
+		change(&changed)
+		if _, err := svc.UpdateProduct(ctx, 1, 1, changed); !errors.Is(err, ErrInvalidProduct) {
+			t.Fatalf("changing %s err = %v, want ErrInvalidProduct", name, err)
+		}
+	}
+	in.DisplayName = "X"
+	if row, err := svc.UpdateProduct(ctx, 1, 1, in); err != nil || row.DisplayName != "X" {
+		t.Fatalf("update mutable field: %+v err=%v", row, err)
+	}
+	if _, err := NewProductCatalogService(nil, nil).ListProducts(ctx); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("nil dao err = %v, want ErrNotConfigured", err)
+	}
+}
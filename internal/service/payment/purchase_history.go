@@ -0,0 +1,161 @@
+package payment
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// historyNotificationTypes 是会出现在购买记录中的通知类型，其余通知（续订开关、价格变更等）不展示。
+var historyNotificationTypes = []string{
+	"SUBSCRIBED", "ONE_TIME_CHARGE", "DID_RENEW",
+	"REFUND", "REVOKE", "DID_CHANGE_RENEWAL_PREF",
+	"EXPIRED", "GRACE_PERIOD_EXPIRED",
+}
+
+const historyCursorPrefix = "h:"
+
+// PurchaseHistoryEntry 是购买记录中的一条，字段含义见 model.PurchaseHistoryItem。
+type PurchaseHistoryEntry struct {
+	Type        string
+	OccurredAt  time.Time
+	PlanID      string
+	ProductID   string
+	ToPlanID    string
+	ToProductID string
+	Reason      string
+}
+
+// PurchaseHistoryPage 是一页购买记录；NextCursor 为空表示没有更多记录。
+type PurchaseHistoryPage struct {
+	Entries    []PurchaseHistoryEntry
+	NextCursor string
+}
+
+// PurchaseHistoryService 把 apple_events 中已处理的通知，以及没有对应通知的购买 / 订阅行整理成用户可读的购买记录。
+type PurchaseHistoryService struct {
+	catalog *Catalog
+	dao     dao.PurchaseHistoryDAO
+}
+
+// NewPurchaseHistoryService 构造购买记录 service；任一依赖为 nil 时 ListPurchaseHistory 返回 ErrNotConfigured。
+func NewPurchaseHistoryService(catalog *Catalog, historyDAO dao.PurchaseHistoryDAO) *PurchaseHistoryService {
+	return &PurchaseHistoryService{catalog: catalog, dao: historyDAO}
+}
+
+// ListPurchaseHistory 按时间倒序返回 userID 在权益环境内的购买、续期、退款、换档与到期记录。
+// cursor 为上一页返回的 NextCursor，首页传空串；无法解析时返回 ErrInvalidHistoryCursor。
+func (s *PurchaseHistoryService) ListPurchaseHistory(ctx context.Context, userID int64, cursor string, limit int) (PurchaseHistoryPage, error) {
+	if s == nil || s.catalog == nil || s.dao == nil {
+		return PurchaseHistoryPage{}, ErrNotConfigured
+	}
+	beforeAt, beforeSource, beforeID, err := decodeHistoryCursor(cursor)
+	if err != nil {
+		return PurchaseHistoryPage{}, err
+	}
+	events, err := s.dao.ListPurchaseTimeline(ctx, model.PurchaseTimelineQuery{
+		UserID:            userID,
+		Environments:      s.catalog.AllowedEntitlementEnvironments(),
+		NotificationTypes: historyNotificationTypes,
+		BeforeAt:          beforeAt,
+		BeforeSource:      beforeSource,
+		BeforeID:          beforeID,
+		Limit:             limit + 1,
+	})
+	if err != nil {
+		return PurchaseHistoryPage{}, fmt.Errorf("purchase history: %w", err)
+	}
+
+	page := PurchaseHistoryPage{Entries: make([]PurchaseHistoryEntry, 0, min(len(events), limit))}
+	for i, e := range events {
+		if i == limit {
+			last := events[limit-1]
+			page.NextCursor = encodeHistoryCursor(last.OccurredAt, last.Source, last.ID)
+			break
+		}
+		page.Entries = append(page.Entries, s.historyEntry(e))
+	}
+	return page, nil
+}
+
+// historyEntry 把一条通知映射为购买记录。Reason 取通知 subtype（如 UPGRADE、BILLING_RETRY），
+// 宽限期结束的到期记为 GRACE_PERIOD。
+func (s *PurchaseHistoryService) historyEntry(e model.PurchaseTimelineEvent) PurchaseHistoryEntry {
+	out := PurchaseHistoryEntry{
+		OccurredAt: e.OccurredAt,
+		ProductID:  e.ProductID,
+		PlanID:     s.planID(e.ProductID, e.Environment),
+		Reason:     strings.ToUpper(e.Subtype),
+	}
+	switch strings.ToUpper(e.NotificationType) {
+	case "SUBSCRIBED", "ONE_TIME_CHARGE":
+		out.Type = model.PurchaseHistoryPurchase
+	case "DID_RENEW":
+		out.Type = model.PurchaseHistoryRenewal
+	case "REFUND", "REVOKE":
+		out.Type = model.PurchaseHistoryRefund
+	case "DID_CHANGE_RENEWAL_PREF":
+		out.Type = model.PurchaseHistoryPlanChange
+		out.ToProductID = e.RenewalProductID
+		out.ToPlanID = s.planID(e.RenewalProductID, e.Environment)
+	case "GRACE_PERIOD_EXPIRED":
+		out.Type = model.PurchaseHistoryExpiration
+		out.Reason = "GRACE_PERIOD"
+	default:
+		out.Type = model.PurchaseHistoryExpiration
+	}
+	return out
+}
+
+// planID 在 catalog 中查找商品对应的 plan；商品不在目录中或为空时返回空串。
+func (s *PurchaseHistoryService) planID(productID string, env Environment) string {
+	if productID == "" {
+		return ""
+	}
+	p, err := s.catalog.Lookup(productID, env)
+	if err != nil {
+		return ""
+	}
+	return p.PlanID
+}
+
+func encodeHistoryCursor(at time.Time, source string, id int64) string {
+	raw := historyCursorPrefix + strconv.FormatInt(at.UnixMicro(), 10) + ":" + source + ":" + strconv.FormatInt(id, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeHistoryCursor 解析 "h:<微秒>:<来源>:<id>"。
+func decodeHistoryCursor(cursor string) (time.Time, string, int64, error) {
+	if cursor == "" {
+		return time.Time{}, "", 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), historyCursorPrefix) {
+		return time.Time{}, "", 0, ErrInvalidHistoryCursor
+	}
+	parts := strings.Split(strings.TrimPrefix(string(raw), historyCursorPrefix), ":")
+	if len(parts) != 3 {
+		return time.Time{}, "", 0, ErrInvalidHistoryCursor
+	}
+	source := parts[1]
+	switch source {
+	case model.PurchaseTimelineSourceEvent, model.PurchaseTimelineSourcePurchase, model.PurchaseTimelineSourceSubscription:
+	default:
+		return time.Time{}, "", 0, ErrInvalidHistoryCursor
+	}
+	micros, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, "", 0, ErrInvalidHistoryCursor
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || id <= 0 {
+		return time.Time{}, "", 0, ErrInvalidHistoryCursor
+	}
+	return time.UnixMicro(micros).UTC(), source, id, nil
+}
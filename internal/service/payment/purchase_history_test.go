@@ -0,0 +1,108 @@
+package payment
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+type fakeHistoryDAO struct {
+	events []model.PurchaseTimelineEvent
+	last   model.PurchaseTimelineQuery
+}
+
+// ListPurchaseTimeline 模拟 SQL 的倒序 + 游标过滤；events 需已按 (时间, 来源, id) 倒序排列。
+func (d *fakeHistoryDAO) ListPurchaseTimeline(_ context.Context, in model.PurchaseTimelineQuery) ([]model.PurchaseTimelineEvent, error) {
+	d.last = in
+	var out []model.PurchaseTimelineEvent
+	for _, e := range d.events {
+		if !in.BeforeAt.IsZero() {
+			c := e.OccurredAt.Compare(in.BeforeAt)
+			if c == 0 {
+				c = strings.Compare(e.Source, in.BeforeSource)
+			}
+			if c == 0 {
+				c = cmp.Compare(e.ID, in.BeforeID)
+			}
+			if c >= 0 {
+				continue
+			}
+		}
+		if len(out) == in.Limit {
+			break
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func TestPurchaseHistoryService_MapsAndPaginates(t *testing.T) {
+	base := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	d := &fakeHistoryDAO{events: []model.PurchaseTimelineEvent{
+		{Source: model.PurchaseTimelineSourceEvent, ID: 5, NotificationType: "EXPIRED", Subtype: "VOLUNTARY", Environment: EnvProduction, OccurredAt: base.Add(4 * time.Hour), ProductID: "com.app.basic.monthly"},
+		{Source: model.PurchaseTimelineSourceEvent, ID: 4, NotificationType: "DID_CHANGE_RENEWAL_PREF", Subtype: "DOWNGRADE", Environment: EnvProduction, OccurredAt: base.Add(3 * time.Hour), ProductID: "com.app.pro.monthly", RenewalProductID: "com.app.basic.monthly"},
+		{Source: model.PurchaseTimelineSourcePurchase, ID: 9, NotificationType: "ONE_TIME_CHARGE", Environment: EnvProduction, OccurredAt: base.Add(2 * time.Hour), ProductID: "com.app.coins.100"},
+		{Source: model.PurchaseTimelineSourceEvent, ID: 3, NotificationType: "DID_RENEW", Environment: EnvProduction, OccurredAt: base.Add(2 * time.Hour), ProductID: "com.app.pro.monthly"},
+		{Source: model.PurchaseTimelineSourceEvent, ID: 2, NotificationType: "REFUND", Environment: EnvProduction, OccurredAt: base.Add(time.Hour), ProductID: "com.app.retired"},
+		{Source: model.PurchaseTimelineSourceSubscription, ID: 1, NotificationType: "SUBSCRIBED", Environment: EnvProduction, OccurredAt: base, ProductID: "com.app.pro.monthly"},
+	}}
+	svc := NewPurchaseHistoryService(newTieredCatalog(t), d)
+	ctx := context.Background()
+
+	first, err := svc.ListPurchaseHistory(ctx, 7, "", 3)
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	if d.last.UserID != 7 || len(d.last.NotificationTypes) == 0 || d.last.Limit != 4 {
+		t.Fatalf("unexpected query: %+v", d.last)
+	}
+	if len(first.Entries) != 3 || first.NextCursor == "" {
+		t.Fatalf("first page: %+v", first)
+	}
+	if e := first.Entries[0]; e.Type != model.PurchaseHistoryExpiration || e.PlanID != "basic_monthly" || e.Reason != "VOLUNTARY" {
+		t.Fatalf("expiration entry: %+v", e)
+	}
+	if e := first.Entries[1]; e.Type != model.PurchaseHistoryPlanChange || e.PlanID != "pro_monthly" || e.ToPlanID != "basic_monthly" || e.Reason != "DOWNGRADE" {
+		t.Fatalf("plan change entry: %+v", e)
+	}
+	if e := first.Entries[2]; e.Type != model.PurchaseHistoryPurchase || e.ProductID != "com.app.coins.100" {
+		t.Fatalf("verified-only purchase entry: %+v", e)
+	}
+
+	second, err := svc.ListPurchaseHistory(ctx, 7, first.NextCursor, 3)
+	if err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	if d.last.BeforeSource != model.PurchaseTimelineSourcePurchase || d.last.BeforeID != 9 {
+		t.Fatalf("cursor must carry the source: %+v", d.last)
+	}
+	if len(second.Entries) != 3 || second.NextCursor != "" {
+		t.Fatalf("second page: %+v", second)
+	}
+	if e := second.Entries[0]; e.Type != model.PurchaseHistoryRenewal {
+		t.Fatalf("renewal entry: %+v", e)
+	}
+	if e := second.Entries[1]; e.Type != model.PurchaseHistoryRefund || e.ProductID != "com.app.retired" || e.PlanID != "" {
+		t.Fatalf("refund of retired product: %+v", e)
+	}
+	if e := second.Entries[2]; e.Type != model.PurchaseHistoryPurchase || !e.OccurredAt.Equal(base) {
+		t.Fatalf("purchase entry: %+v", e)
+	}
+}
+
+func TestPurchaseHistoryService_InvalidCursor(t *testing.T) {
+	svc := NewPurchaseHistoryService(newTieredCatalog(t), &fakeHistoryDAO{})
+	for _, cursor := range []string{"not base64!", encodeHistoryCursor(time.Now(), model.PurchaseTimelineSourceEvent, 0), encodeHistoryCursor(time.Now(), "OTHER", 1), "dTox"} {
+		if _, err := svc.ListPurchaseHistory(context.Background(), 7, cursor, 10); !errors.Is(err, ErrInvalidHistoryCursor) {
+			t.Fatalf("cursor %q: got %v, want ErrInvalidHistoryCursor", cursor, err)
+		}
+	}
+	if _, err := NewPurchaseHistoryService(nil, nil).ListPurchaseHistory(context.Background(), 7, "", 10); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("got %v, want ErrNotConfigured", err)
+	}
+}
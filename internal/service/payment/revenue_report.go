@@ -0,0 +1,444 @@
+package payment
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// 收入报表的周期粒度。周期按 UTC 切分，周从周一开始。
+const (
+	RevenueGranularityDay   = "day"
+	RevenueGranularityWeek  = "week"
+	RevenueGranularityMonth = "month"
+)
+
+// maxRevenuePeriods 限制单次报表的周期数，避免误传的时间范围把整张表按天展开。
+const maxRevenuePeriods = 1000
+
+const revenueEventBatchSize = 5000
+
+// Apple offerDiscountType 中表示免费试用的取值。
+const appleOfferDiscountFreeTrial = "FREE_TRIAL"
+
+// ErrInvalidReportRange 表示报表的时间范围或粒度不合法。
+var ErrInvalidReportRange = errors.New("revenue report: invalid range")
+
+// RevenueReportRequest 是一次收入报表的参数：[From, To) 按 Granularity 切分为周期；Environments 为空表示全部环境。
+type RevenueReportRequest struct {
+	From         time.Time
+	To           time.Time
+	Granularity  string
+	Environments []Environment
+}
+
+// RevenueRow 是一个周期、环境、plan 的指标。Active 与 MRRMilliunits 是周期结束时的快照，其余为周期内的计数。
+type RevenueRow struct {
+	PeriodStart    time.Time
+	PeriodEnd      time.Time
+	Environment    Environment
+	PlanID         string
+	Active         int64
+	New            int64
+	Renewed        int64
+	Churned        int64
+	Refunded       int64
+	Reactivated    int64
+	TrialStarted   int64
+	TrialConverted int64
+	TrialExpired   int64
+	MRRMilliunits  int64
+	Currency       string
+}
+
+// TrialConversionRate 返回本周期结束的试用中转为付费的比例；没有结束的试用时返回 0。
+func (r RevenueRow) TrialConversionRate() float64 {
+	ended := r.TrialConverted + r.TrialExpired
+	if ended == 0 {
+		return 0
+	}
+	return float64(r.TrialConverted) / float64(ended)
+}
+
+func (r RevenueRow) empty() bool {
+	return r.Active == 0 && r.New == 0 && r.Renewed == 0 && r.Churned == 0 && r.Refunded == 0 && r.Reactivated == 0 &&
+		r.TrialStarted == 0 && r.TrialConverted == 0 && r.TrialExpired == 0
+}
+
+// RevenueReport 是收入报表结果，Rows 按周期、环境、plan 排序，省略没有任何数据的组合。
+type RevenueReport struct {
+	From        time.Time
+	To          time.Time
+	Granularity string
+	Rows        []RevenueRow
+}
+
+// RevenueReportService 通过按时间顺序重放 apple_events 计算订阅收入与流失指标。
+//
+// 指标只依赖通知历史（而不是 apple_subscriptions 的当前状态），同一时间范围重复计算结果一致。每个订阅
+// （original transaction + 环境）按通知推进状态：SUBSCRIBED 记为新订阅（RESUBSCRIBE 或曾经订阅过则记为
+// 重新激活），DID_RENEW 记为续期（试用中则同时记为试用转付费），EXPIRED / GRACE_PERIOD_EXPIRED / REVOKE
+// 记为流失，REFUND 记为退款。商品按 apple_products 全表（含已下架商品）映射到 plan，MRR 按其中的参考价格折算，
+// 表中没有的商品回退到 catalog；消耗型与非消耗型商品不参与。
+type RevenueReportService struct {
+	catalog   *Catalog
+	dao       dao.RevenueReportDAO
+	products  dao.AppleProductDAO
+	batchSize int
+}
+
+// NewRevenueReportService 构造收入报表 service；catalog 或 dao 为 nil 时 Report 返回 ErrNotConfigured。
+func NewRevenueReportService(catalog *Catalog, reportDAO dao.RevenueReportDAO) *RevenueReportService {
+	return &RevenueReportService{catalog: catalog, dao: reportDAO, batchSize: revenueEventBatchSize}
+}
+
+// WithProducts 让报表按 apple_products 全表定价；未设置时只使用 catalog。返回 s 便于链式调用。
+func (s *RevenueReportService) WithProducts(productDAO dao.AppleProductDAO) *RevenueReportService {
+	s.products = productDAO
+	return s
+}
+
+// revenueSubscription 是重放过程中一个订阅的状态。
+type revenueSubscription struct {
+	env       Environment
+	productID string
+	seen      bool
+	active    bool
+	trial     bool
+}
+
+type revenueRowKey struct {
+	period int
+	env    Environment
+	planID string
+}
+
+// revenueReplay 持有一次报表的重放状态。
+type revenueReplay struct {
+	catalog *Catalog
+	// products 是 apple_products 全表（含已下架），优先于 catalog。
+	products map[catalogKey]Product
+	bounds   []time.Time
+	envs     map[Environment]struct{}
+	subs     map[string]*revenueSubscription
+	rows     map[revenueRowKey]*RevenueRow
+	mrr      map[revenueRowKey]float64
+}
+
+// Report 计算 req 对应的报表。From 之前的通知只用于还原周期开始时的订阅状态，不计入任何周期。
+func (s *RevenueReportService) Report(ctx context.Context, req RevenueReportRequest) (RevenueReport, error) {
+	if s == nil || s.catalog == nil || s.dao == nil {
+		return RevenueReport{}, ErrNotConfigured
+	}
+	bounds, err := revenuePeriods(req.From.UTC(), req.To.UTC(), req.Granularity)
+	if err != nil {
+		return RevenueReport{}, err
+	}
+	products, err := s.allProducts(ctx)
+	if err != nil {
+		return RevenueReport{}, err
+	}
+	r := &revenueReplay{
+		catalog:  s.catalog,
+		products: products,
+		bounds:   bounds,
+		subs:     map[string]*revenueSubscription{},
+		rows:     map[revenueRowKey]*RevenueRow{},
+		mrr:      map[revenueRowKey]float64{},
+	}
+	if len(req.Environments) > 0 {
+		r.envs = make(map[Environment]struct{}, len(req.Environments))
+		for _, env := range req.Environments {
+			r.envs[env] = struct{}{}
+		}
+	}
+
+	period := -1
+	query := model.RevenueEventQuery{Until: bounds[len(bounds)-1], Limit: s.batchSize}
+	for {
+		events, err := s.dao.ListRevenueEvents(ctx, query)
+		if err != nil {
+			return RevenueReport{}, err
+		}
+		for _, ev := range events {
+			for period+1 < len(bounds) && !ev.OccurredAt.Before(bounds[period+1]) {
+				if period >= 0 {
+					if err := r.snapshot(period); err != nil {
+						return RevenueReport{}, err
+					}
+				}
+				period++
+			}
+			r.apply(ev, period)
+		}
+		if len(events) < query.Limit {
+			break
+		}
+		last := events[len(events)-1]
+		query.AfterAt, query.AfterID = last.OccurredAt, last.ID
+	}
+	for ; period < len(bounds)-1; period++ {
+		if period >= 0 {
+			if err := r.snapshot(period); err != nil {
+				return RevenueReport{}, err
+			}
+		}
+	}
+
+	out := RevenueReport{From: bounds[0], To: bounds[len(bounds)-1], Granularity: req.Granularity}
+	for key, row := range r.rows {
+		row.MRRMilliunits = int64(math.Round(r.mrr[key]))
+		if !row.empty() {
+			out.Rows = append(out.Rows, *row)
+		}
+	}
+	sort.Slice(out.Rows, func(i, j int) bool {
+		a, b := out.Rows[i], out.Rows[j]
+		if !a.PeriodStart.Equal(b.PeriodStart) {
+			return a.PeriodStart.Before(b.PeriodStart)
+		}
+		if a.Environment != b.Environment {
+			return a.Environment < b.Environment
+		}
+		return a.PlanID < b.PlanID
+	})
+	return out, nil
+}
+
+// allProducts 按 (product_id, environment) 索引 apple_products 的全部商品；未设置 products 时返回 nil。
+func (s *RevenueReportService) allProducts(ctx context.Context) (map[catalogKey]Product, error) {
+	if s.products == nil {
+		return nil, nil
+	}
+	rows, err := s.products.ListProducts(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[catalogKey]Product, len(rows))
+	for _, row := range rows {
+		out[catalogKey{ProductID: row.ProductID, Environment: row.Environment}] = productFromRow(row)
+	}
+	return out, nil
+}
+
+// apply 按一条通知推进订阅状态；period < 0 表示通知早于报表开始，只更新状态不计数。
+func (r *revenueReplay) apply(ev model.RevenueEvent, period int) {
+	if r.envs != nil {
+		if _, ok := r.envs[ev.Environment]; !ok {
+			return
+		}
+	}
+	key := string(ev.Environment) + ":" + ev.OriginalTransactionID
+	sub := r.subs[key]
+	if sub == nil {
+		sub = &revenueSubscription{env: ev.Environment}
+	}
+	if ev.ProductID != "" {
+		sub.productID = ev.ProductID
+	}
+	product, ok := r.product(sub)
+	if !ok {
+		return
+	}
+	r.subs[key] = sub
+
+	row := &RevenueRow{}
+	if period >= 0 {
+		row = r.row(period, sub.env, product.PlanID)
+	}
+	switch ev.NotificationType {
+	case "SUBSCRIBED", "OFFER_REDEEMED":
+		if ev.NotificationType == "OFFER_REDEEMED" && ev.Subtype != "INITIAL_BUY" && ev.Subtype != "RESUBSCRIBE" {
+			break
+		}
+		if ev.Subtype == "RESUBSCRIBE" || sub.seen {
+			if !sub.active {
+				row.Reactivated++
+			}
+		} else {
+			row.New++
+		}
+		sub.trial = ev.OfferDiscountType == appleOfferDiscountFreeTrial
+		if sub.trial {
+			row.TrialStarted++
+		}
+		sub.active = true
+	case "DID_RENEW":
+		if sub.trial {
+			row.TrialConverted++
+			sub.trial = false
+		}
+		if sub.seen && !sub.active {
+			row.Reactivated++
+		}
+		row.Renewed++
+		sub.active = true
+	case "EXPIRED", "GRACE_PERIOD_EXPIRED", "REVOKE":
+		if sub.active {
+			row.Churned++
+			if sub.trial {
+				row.TrialExpired++
+			}
+		}
+		sub.active, sub.trial = false, false
+	case "REFUND":
+		row.Refunded++
+		sub.active, sub.trial = false, false
+	default:
+		// 其他通知（续订偏好变更、扣费失败等）不改变是否有效，只更新商品。
+		if !sub.seen {
+			sub.active = true
+		}
+	}
+	sub.seen = true
+}
+
+// product 返回订阅当前商品的定义，先查 apple_products 全表再查 catalog；都没有的商品以 productId 作为 plan，
+// 非订阅商品返回 false。
+func (r *revenueReplay) product(sub *revenueSubscription) (Product, bool) {
+	if sub.productID == "" {
+		return Product{}, false
+	}
+	if p, ok := r.products[catalogKey{ProductID: sub.productID, Environment: sub.env}]; ok {
+		return p, p.Type == model.ProductTypeSubscription
+	}
+	p, err := r.catalog.Lookup(sub.productID, sub.env)
+	if err != nil {
+		return Product{PlanID: sub.productID, ProductID: sub.productID, Type: model.ProductTypeSubscription, Environment: sub.env}, true
+	}
+	return p, p.Type == model.ProductTypeSubscription
+}
+
+// snapshot 记录周期结束时各 plan 的有效订阅数与 MRR。
+func (r *revenueReplay) snapshot(period int) error {
+	for _, sub := range r.subs {
+		if !sub.active {
+			continue
+		}
+		product, ok := r.product(sub)
+		if !ok {
+			continue
+		}
+		key := revenueRowKey{period: period, env: sub.env, planID: product.PlanID}
+		row := r.row(period, sub.env, product.PlanID)
+		row.Active++
+		if sub.trial || product.MonthlyPriceMilliunits() == 0 {
+			continue
+		}
+		if row.Currency != "" && row.Currency != product.Currency {
+			return fmt.Errorf("revenue report: plan %s is priced in both %s and %s: %w", product.PlanID, row.Currency, product.Currency, ErrInvalidConfig)
+		}
+		row.Currency = product.Currency
+		r.mrr[key] += product.MonthlyPriceMilliunits()
+	}
+	return nil
+}
+
+func (r *revenueReplay) row(period int, env Environment, planID string) *RevenueRow {
+	key := revenueRowKey{period: period, env: env, planID: planID}
+	row, ok := r.rows[key]
+	if !ok {
+		row = &RevenueRow{
+			PeriodStart: r.bounds[period],
+			PeriodEnd:   r.bounds[period+1],
+			Environment: env,
+			PlanID:      planID,
+		}
+		r.rows[key] = row
+	}
+	return row
+}
+
+// revenuePeriods 把 [from, to) 切分为周期边界：from 向下对齐到周期开始，to 向上对齐到周期结束。
+func revenuePeriods(from, to time.Time, granularity string) ([]time.Time, error) {
+	if from.IsZero() || to.IsZero() || !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidReportRange)
+	}
+	var next func(time.Time) time.Time
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case RevenueGranularityDay:
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case RevenueGranularityWeek:
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+	case RevenueGranularityMonth:
+		start = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	default:
+		return nil, fmt.Errorf("%w: unknown granularity %q", ErrInvalidReportRange, granularity)
+	}
+	bounds := []time.Time{start}
+	for t := start; t.Before(to); {
+		t = next(t)
+		bounds = append(bounds, t)
+		if len(bounds) > maxRevenuePeriods+1 {
+			return nil, fmt.Errorf("%w: more than %d periods", ErrInvalidReportRange, maxRevenuePeriods)
+		}
+	}
+	return bounds, nil
+}
+
+// Response 把报表转换为 JSON 响应负载。
+func (r RevenueReport) Response() model.RevenueReportResponse {
+	out := model.RevenueReportResponse{
+		From:        r.From.Format(time.RFC3339),
+		To:          r.To.Format(time.RFC3339),
+		Granularity: r.Granularity,
+		Rows:        make([]model.RevenueReportRow, 0, len(r.Rows)),
+	}
+	for _, row := range r.Rows {
+		out.Rows = append(out.Rows, model.RevenueReportRow{
+			PeriodStart:         row.PeriodStart.Format(time.RFC3339),
+			PeriodEnd:           row.PeriodEnd.Format(time.RFC3339),
+			Environment:         string(row.Environment),
+			PlanID:              row.PlanID,
+			Active:              row.Active,
+			New:                 row.New,
+			Renewed:             row.Renewed,
+			Churned:             row.Churned,
+			Refunded:            row.Refunded,
+			Reactivated:         row.Reactivated,
+			TrialStarted:        row.TrialStarted,
+			TrialConverted:      row.TrialConverted,
+			TrialExpired:        row.TrialExpired,
+			TrialConversionRate: row.TrialConversionRate(),
+			MRRMilliunits:       row.MRRMilliunits,
+			Currency:            row.Currency,
+		})
+	}
+	return out
+}
+
+// WriteCSV 以 CSV 输出报表，首行为表头，列与 JSON 字段一致。
+func (r RevenueReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"period_start", "period_end", "environment", "plan_id", "active", "new", "renewed", "churned", "refunded",
+		"reactivated", "trial_started", "trial_converted", "trial_expired", "trial_conversion_rate", "mrr_milliunits", "currency",
+	}); err != nil {
+		return err
+	}
+	itoa := func(n int64) string { return strconv.FormatInt(n, 10) }
+	for _, row := range r.Response().Rows {
+		if err := cw.Write([]string{
+			row.PeriodStart, row.PeriodEnd, row.Environment, row.PlanID,
+			itoa(row.Active), itoa(row.New), itoa(row.Renewed), itoa(row.Churned), itoa(row.Refunded), itoa(row.Reactivated),
+			itoa(row.TrialStarted), itoa(row.TrialConverted), itoa(row.TrialExpired),
+			strconv.FormatFloat(row.TrialConversionRate, 'f', 4, 64), itoa(row.MRRMilliunits), row.Currency,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
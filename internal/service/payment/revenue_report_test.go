@@ -0,0 +1,209 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// fakeRevenueReportDAO pages through events the same way ListRevenueEvents does; events must be sorted.
+type fakeRevenueReportDAO struct {
+	events []model.RevenueEvent
+	calls  int
+}
+
+func (f *fakeRevenueReportDAO) ListRevenueEvents(_ context.Context, q model.RevenueEventQuery) ([]model.RevenueEvent, error) {
+	f.calls++
+	var out []model.RevenueEvent
+	for _, ev := range f.events {
+		if !ev.OccurredAt.Before(q.Until) {
+			continue
+		}
+		if ev.OccurredAt.Before(q.AfterAt) || (ev.OccurredAt.Equal(q.AfterAt) && ev.ID <= q.AfterID) {
+			continue
+		}
+		if len(out) == q.Limit {
+			break
+		}
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+func newRevenueCatalog(t testing.TB) *Catalog {
+	t.Helper()
+	cfg := validProdConfig()
+	cfg.Products = `[
+		{"plan_id":"pro_monthly","product_id":"com.app.pro.monthly","level":1,"environment":"Production","price_milliunits":9990,"currency":"usd","billing_period":"P1M"},
+		{"plan_id":"pro_yearly","product_id":"com.app.pro.yearly","level":1,"environment":"Production","price_milliunits":99990,"currency":"USD","billing_period":"P1Y"},
+		{"plan_id":"pro_monthly","product_id":"com.app.pro.monthly","level":1,"environment":"Sandbox"},
+		{"plan_id":"credits_100","product_id":"com.app.credits.100","type":"consumable","credits":100,"environment":"Production"}
+	]`
+	c, err := NewCatalog(cfg, "dev")
+	if err != nil {
+		t.Fatalf("catalog: %v", err)
+	}
+	return c
+}
+
+func revenueEvents() []model.RevenueEvent {
+	at := func(month time.Month, day int) time.Time { return time.Date(2026, month, day, 12, 0, 0, 0, time.UTC) }
+	prod, sandbox := model.AppleEnvironment("Production"), model.AppleEnvironment("Sandbox")
+	monthly, yearly := "com.app.pro.monthly", "com.app.pro.yearly"
+	events := []model.RevenueEvent{
+		{NotificationType: "SUBSCRIBED", Subtype: "INITIAL_BUY", Environment: prod, OriginalTransactionID: "A", OccurredAt: at(9, 20), ProductID: monthly},
+		{NotificationType: "SUBSCRIBED", Subtype: "INITIAL_BUY", Environment: sandbox, OriginalTransactionID: "E", OccurredAt: at(10, 1), ProductID: monthly},
+		{NotificationType: "SUBSCRIBED", Subtype: "INITIAL_BUY", Environment: prod, OriginalTransactionID: "B", OccurredAt: at(10, 5), ProductID: yearly, OfferDiscountType: "FREE_TRIAL"},
+		{NotificationType: "SUBSCRIBED", Subtype: "INITIAL_BUY", Environment: prod, OriginalTransactionID: "C", OccurredAt: at(10, 6), ProductID: monthly, OfferDiscountType: "FREE_TRIAL"},
+		{NotificationType: "REFUND", Environment: prod, OriginalTransactionID: "D", OccurredAt: at(10, 7), ProductID: "com.app.credits.100"},
+		{NotificationType: "DID_RENEW", Environment: prod, OriginalTransactionID: "B", OccurredAt: at(10, 12), ProductID: yearly},
+		{NotificationType: "EXPIRED", Subtype: "VOLUNTARY", Environment: prod, OriginalTransactionID: "C", OccurredAt: at(10, 13), ProductID: monthly},
+		{NotificationType: "DID_RENEW", Environment: prod, OriginalTransactionID: "A", OccurredAt: at(10, 20), ProductID: monthly},
+		{NotificationType: "EXPIRED", Subtype: "VOLUNTARY", Environment: prod, OriginalTransactionID: "A", OccurredAt: at(11, 3), ProductID: monthly},
+		{NotificationType: "SUBSCRIBED", Subtype: "RESUBSCRIBE", Environment: prod, OriginalTransactionID: "A", OccurredAt: at(11, 10), ProductID: monthly},
+		{NotificationType: "REFUND", Environment: prod, OriginalTransactionID: "B", OccurredAt: at(11, 15), ProductID: yearly},
+		{NotificationType: "DID_RENEW", Environment: prod, OriginalTransactionID: "A", OccurredAt: at(12, 10), ProductID: monthly},
+	}
+	for i := range events {
+		events[i].ID = int64(i + 1)
+	}
+	return events
+}
+
+func TestRevenueReportService_Report(t *testing.T) {
+	store := &fakeRevenueReportDAO{events: revenueEvents()}
+	svc := NewRevenueReportService(newRevenueCatalog(t), store)
+	svc.batchSize = 3
+
+	report, err := svc.Report(context.Background(), RevenueReportRequest{
+		From:         time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC),
+		To:           time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+		Granularity:  RevenueGranularityMonth,
+		Environments: []Environment{"Production"},
+	})
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if !report.From.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("from must align to month start, got %s", report.From)
+	}
+	oct, nov := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	want := []RevenueRow{
+		{PeriodStart: oct, PeriodEnd: nov, Environment: "Production", PlanID: "pro_monthly",
+			Active: 1, New: 1, Renewed: 1, Churned: 1, TrialStarted: 1, TrialExpired: 1, MRRMilliunits: 9990, Currency: "USD"},
+		{PeriodStart: oct, PeriodEnd: nov, Environment: "Production", PlanID: "pro_yearly",
+			Active: 1, New: 1, Renewed: 1, TrialStarted: 1, TrialConverted: 1, MRRMilliunits: 8333, Currency: "USD"},
+		{PeriodStart: nov, PeriodEnd: nov.AddDate(0, 1, 0), Environment: "Production", PlanID: "pro_monthly",
+			Active: 1, Churned: 1, Reactivated: 1, MRRMilliunits: 9990, Currency: "USD"},
+		{PeriodStart: nov, PeriodEnd: nov.AddDate(0, 1, 0), Environment: "Production", PlanID: "pro_yearly",
+			Refunded: 1},
+	}
+	if len(report.Rows) != len(want) {
+		t.Fatalf("rows = %+v", report.Rows)
+	}
+	for i := range want {
+		if report.Rows[i] != want[i] {
+			t.Fatalf("row %d = %+v, want %+v", i, report.Rows[i], want[i])
+		}
+	}
+	if report.Rows[1].TrialConversionRate() != 1 || report.Rows[0].TrialConversionRate() != 0 {
+		t.Fatalf("trial conversion = %v / %v", report.Rows[1].TrialConversionRate(), report.Rows[0].TrialConversionRate())
+	}
+	// 12 月的事件不在范围内；分页需要多次读取。
+	if store.calls < 4 {
+		t.Fatalf("expected paged reads, got %d calls", store.calls)
+	}
+
+	// 同一范围重复计算结果一致，CSV 每行一个组合。
+	again, err := svc.Report(context.Background(), RevenueReportRequest{
+		From: report.From, To: report.To, Granularity: RevenueGranularityMonth, Environments: []Environment{"Production"},
+	})
+	if err != nil || len(again.Rows) != len(report.Rows) || again.Rows[2] != report.Rows[2] {
+		t.Fatalf("report must be reproducible: %+v, %v", again.Rows, err)
+	}
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "period_start,period_end,environment,plan_id,active,") ||
+		lines[2] != "2026-10-01T00:00:00Z,2026-11-01T00:00:00Z,Production,pro_yearly,1,1,1,0,0,0,1,1,0,1.0000,8333,USD" {
+		t.Fatalf("csv = %q", buf.String())
+	}
+}
+
+func TestRevenueReportService_AllEnvironments(t *testing.T) {
+	svc := NewRevenueReportService(newRevenueCatalog(t), &fakeRevenueReportDAO{events: revenueEvents()})
+	report, err := svc.Report(context.Background(), RevenueReportRequest{
+		From:        time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC),
+		Granularity: RevenueGranularityDay,
+	})
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	// 10-01 当天：Production 的 A 仍然有效，Sandbox 的 E 新订阅（未定价，不计 MRR）。
+	if len(report.Rows) != 2 || report.Rows[0].Environment != "Production" || report.Rows[0].Active != 1 ||
+		report.Rows[1].Environment != "Sandbox" || report.Rows[1].New != 1 || report.Rows[1].MRRMilliunits != 0 || report.Rows[1].Currency != "" {
+		t.Fatalf("rows = %+v", report.Rows)
+	}
+}
+
+func TestRevenueReportService_PricesDelistedProducts(t *testing.T) {
+	// 已下架的商品不在 APPLE_IAP_PRODUCTS 中，只能从 apple_products 全表取到 plan 与价格。
+	products := &fakeAppleProductDAO{rows: []model.AppleProduct{{
+		ID: 1, PlanID: "legacy_monthly", ProductID: "com.app.legacy.monthly", Environment: EnvProduction,
+		Type: model.ProductTypeSubscription, Level: 1, Active: false,
+		PriceMilliunits: 4990, Currency: "USD", BillingPeriod: "P1M",
+	}}}
+	events := []model.RevenueEvent{{
+		ID: 1, NotificationType: "SUBSCRIBED", Subtype: "INITIAL_BUY", Environment: EnvProduction, OriginalTransactionID: "L",
+		OccurredAt: time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC), ProductID: "com.app.legacy.monthly",
+	}}
+	svc := NewRevenueReportService(newRevenueCatalog(t), &fakeRevenueReportDAO{events: events}).WithProducts(products)
+	report, err := svc.Report(context.Background(), RevenueReportRequest{
+		From:        time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		Granularity: RevenueGranularityMonth,
+	})
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if len(report.Rows) != 1 || report.Rows[0].PlanID != "legacy_monthly" || report.Rows[0].New != 1 ||
+		report.Rows[0].MRRMilliunits != 4990 || report.Rows[0].Currency != "USD" {
+		t.Fatalf("rows = %+v", report.Rows)
+	}
+
+	products.listErr = errors.New("db down")
+	if _, err := svc.Report(context.Background(), RevenueReportRequest{
+		From: report.From, To: report.To, Granularity: RevenueGranularityMonth,
+	}); err == nil {
+		t.Fatal("report must surface the product load error")
+	}
+}
+
+func TestRevenuePeriods(t *testing.T) {
+	// 2026-10-15 是周四，周从周一开始。
+	bounds, err := revenuePeriods(time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), RevenueGranularityWeek)
+	if err != nil || len(bounds) != 2 || !bounds[0].Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)) || !bounds[1].Equal(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("week bounds = %v, %v", bounds, err)
+	}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, tc := range map[string]struct {
+		to          time.Time
+		granularity string
+	}{
+		"empty range":         {from, RevenueGranularityDay},
+		"unknown granularity": {from.AddDate(0, 1, 0), "quarter"},
+		"too many periods":    {from.AddDate(5, 0, 0), RevenueGranularityDay},
+	} {
+		if _, err := revenuePeriods(from, tc.to, tc.granularity); !errors.Is(err, ErrInvalidReportRange) {
+			t.Fatalf("%s: err = %v", name, err)
+		}
+	}
+}
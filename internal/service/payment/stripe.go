@@ -0,0 +1,267 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	logpkg "github.com/dundunHa/go-serverhttp-template/pkg/log"
+)
+
+// StripeStoreDAO 是 StripeService 用到的 dao 子集。
+type StripeStoreDAO interface {
+	GetCustomerByUser(ctx context.Context, userID int64) (model.StripeCustomer, error)
+	BindCustomer(ctx context.Context, userID int64, customerID string) (model.StripeCustomer, error)
+	InTx(ctx context.Context, fn func(dao.StripeTx) error) error
+}
+
+// StripeService 实现 Stripe web billing：Checkout Session、customer portal 与订阅生命周期 webhook。
+//
+// 用户绑定：首次 checkout 时为 user 创建 Stripe customer 并写入 stripe_customers，webhook 通过事件中的
+// customer id 反查 user；映射缺失时回退到 checkout 写入的 subscription metadata.user_id。
+// 订阅状态直接取自事件携带的 subscription 对象，stripe_events 以 event id 保证同一事件只 reduce 一次。
+type StripeService struct {
+	catalog *StripeCatalog
+	api     StripeAPI
+	dao     StripeStoreDAO
+	now     func() time.Time
+}
+
+// NewStripeService 构造 service；任一依赖为 nil 时调用都返回 ErrNotConfigured。
+func NewStripeService(catalog *StripeCatalog, api StripeAPI, dao StripeStoreDAO) *StripeService {
+	return &StripeService{
+		catalog: catalog,
+		api:     api,
+		dao:     dao,
+		now:     func() time.Time { return time.Now().UTC() },
+	}
+}
+
+func (s *StripeService) configured() bool {
+	return s != nil && s.catalog != nil && s.api != nil && s.dao != nil
+}
+
+// WebhookMaxBodyBytes 暴露 webhook 请求体上限，供路由层做 size guard。
+func (s *StripeService) WebhookMaxBodyBytes() int {
+	if s == nil || s.catalog == nil {
+		return 0
+	}
+	return s.catalog.WebhookMaxBodyBytes()
+}
+
+// CreateCheckoutSession 为当前用户创建指定 plan 的订阅 Checkout Session。
+func (s *StripeService) CreateCheckoutSession(ctx context.Context, userID int64, planID string) (model.StripeCheckoutResponse, error) {
+	if !s.configured() {
+		return model.StripeCheckoutResponse{}, ErrNotConfigured
+	}
+	if userID <= 0 {
+		return model.StripeCheckoutResponse{}, errors.New("stripe: invalid user id")
+	}
+	product, err := s.catalog.LookupPlan(planID)
+	if err != nil {
+		return model.StripeCheckoutResponse{}, err
+	}
+	customerID, err := s.ensureCustomer(ctx, userID)
+	if err != nil {
+		return model.StripeCheckoutResponse{}, err
+	}
+	session, err := s.api.CreateCheckoutSession(ctx, StripeCheckoutParams{
+		UserID:     userID,
+		CustomerID: customerID,
+		PriceID:    product.ProductID,
+		SuccessURL: s.catalog.SuccessURL(),
+		CancelURL:  s.catalog.CancelURL(),
+	})
+	if err != nil {
+		return model.StripeCheckoutResponse{}, err
+	}
+	return model.StripeCheckoutResponse{SessionID: session.ID, URL: session.URL}, nil
+}
+
+// CreatePortalSession 返回当前用户的 customer portal 链接；从未发起过 checkout 的用户返回 ErrStripeNoCustomer。
+func (s *StripeService) CreatePortalSession(ctx context.Context, userID int64) (model.StripePortalResponse, error) {
+	if !s.configured() {
+		return model.StripePortalResponse{}, ErrNotConfigured
+	}
+	customer, err := s.dao.GetCustomerByUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, dao.ErrStripeCustomerNotFound) {
+			return model.StripePortalResponse{}, ErrStripeNoCustomer
+		}
+		return model.StripePortalResponse{}, err
+	}
+	url, err := s.api.CreatePortalSession(ctx, customer.CustomerID, s.catalog.PortalReturnURL())
+	if err != nil {
+		return model.StripePortalResponse{}, err
+	}
+	return model.StripePortalResponse{URL: url}, nil
+}
+
+// ensureCustomer 返回 user 的 Stripe customer id，不存在时创建并写入映射。
+func (s *StripeService) ensureCustomer(ctx context.Context, userID int64) (string, error) {
+	customer, err := s.dao.GetCustomerByUser(ctx, userID)
+	if err == nil {
+		return customer.CustomerID, nil
+	}
+	if !errors.Is(err, dao.ErrStripeCustomerNotFound) {
+		return "", err
+	}
+	customerID, err := s.api.CreateCustomer(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	customer, err = s.dao.BindCustomer(ctx, userID, customerID)
+	if err != nil {
+		return "", err
+	}
+	return customer.CustomerID, nil
+}
+
+// HandleWebhook 是 POST /webhooks/stripe 的服务层入口。返回 nil 表示 2xx ack，其余错误让 Stripe 重试。
+//
+// 流程：校验 Stripe-Signature → 解码事件 → 事务内幂等写 stripe_events 并 reduce stripe_subscriptions。
+// 非订阅生命周期事件只记录为 IGNORED_UNKNOWN_TYPE。
+func (s *StripeService) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	if !s.configured() || s.catalog.WebhookSecret() == "" {
+		return ErrNotConfigured
+	}
+	if err := verifyStripeSignature(payload, signature, s.catalog.WebhookSecret(), s.catalog.WebhookTolerance(), s.now()); err != nil {
+		return err
+	}
+	var event stripeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("%w: decode event: %v", ErrStripeInvalidEvent, err)
+	}
+	if event.ID == "" || event.Type == "" {
+		return fmt.Errorf("%w: missing id or type", ErrStripeInvalidEvent)
+	}
+
+	in := model.StripeEventInsert{EventID: event.ID, EventType: event.Type}
+	// eventAt 写入 last_event_at，用于丢弃乱序到达的旧事件；缺少 created 时退化为接收时间。
+	eventAt := s.now()
+	if event.Created > 0 {
+		eventAt = time.Unix(event.Created, 0).UTC()
+		in.EventCreatedAt = &eventAt
+	}
+
+	switch event.Type {
+	case stripeEventSubscriptionCreated, stripeEventSubscriptionUpdated, stripeEventSubscriptionDeleted:
+	default:
+		in.ProcessingStatus = model.EventStatusIgnoredUnknownType
+		return s.dao.InTx(ctx, func(qtx dao.StripeTx) error {
+			_, err := qtx.InsertEventIfNotExists(ctx, in)
+			return err
+		})
+	}
+
+	var sub stripeSubscriptionObject
+	if err := json.Unmarshal(event.Data.Object, &sub); err != nil || sub.ID == "" {
+		return fmt.Errorf("%w: decode subscription object", ErrStripeInvalidEvent)
+	}
+	in.CustomerID, in.SubscriptionID = sub.Customer, sub.ID
+
+	return s.dao.InTx(ctx, func(qtx dao.StripeTx) error {
+		c, upsert := s.classifySubscription(ctx, qtx, &sub, eventAt)
+		in.ProcessingStatus, in.UserID, in.ProcessingError = c.status, c.userID, c.errorMessage
+		created, err := qtx.InsertEventIfNotExists(ctx, in)
+		if err != nil {
+			return fmt.Errorf("stripe webhook: insert event: %w", err)
+		}
+		if !created || c.status != model.EventStatusProcessed {
+			return nil
+		}
+		if _, err := qtx.UpsertSubscriptionWithOwnershipCheck(ctx, upsert); err != nil {
+			return fmt.Errorf("stripe webhook: upsert subscription: %w", err)
+		}
+		logpkg.FromContext(ctx).InfoContext(ctx, "stripe subscription updated",
+			"user_id", c.userID, "subscription_id", sub.ID, "status", upsert.Status)
+		return nil
+	})
+}
+
+// stripeClassification 记录写 stripe_events 之前做出的决策。
+type stripeClassification struct {
+	status       string
+	userID       int64
+	errorMessage string
+}
+
+func (s *StripeService) classifySubscription(ctx context.Context, qtx dao.StripeTx, sub *stripeSubscriptionObject, eventAt time.Time) (stripeClassification, model.StripeSubscriptionUpsert) {
+	userID, c, ok := s.resolveUser(ctx, qtx, sub)
+	if !ok {
+		return c, model.StripeSubscriptionUpsert{}
+	}
+	product, found := s.catalog.LookupPrice(sub.PriceID())
+	if !found {
+		return stripeClassification{status: model.EventStatusIgnoredUnknownType, userID: userID, errorMessage: "price not in catalog"}, model.StripeSubscriptionUpsert{}
+	}
+	status, ok := sub.InternalStatus()
+	if !ok {
+		return stripeClassification{status: model.EventStatusIgnoredUnknownType, userID: userID, errorMessage: "subscription not active yet"}, model.StripeSubscriptionUpsert{}
+	}
+
+	autoRenew := model.AutoRenewStatusOn
+	if sub.CancelAtPeriodEnd || status == model.SubscriptionStatusExpired {
+		autoRenew = model.AutoRenewStatusOff
+	}
+	start, end := sub.Period()
+	return stripeClassification{status: model.EventStatusProcessed, userID: userID}, model.StripeSubscriptionUpsert{
+		UserID:             userID,
+		CustomerID:         sub.Customer,
+		SubscriptionID:     sub.ID,
+		PriceID:            product.ProductID,
+		PlanID:             product.PlanID,
+		Level:              product.Level,
+		Status:             status,
+		StripeStatus:       sub.Status,
+		AutoRenewStatus:    autoRenew,
+		CurrentPeriodStart: start,
+		CurrentPeriodEnd:   end,
+		LastEventAt:        eventAt,
+	}
+}
+
+// resolveUser 依次按已有订阅行、customer 映射、subscription metadata.user_id 反查 user。
+func (s *StripeService) resolveUser(ctx context.Context, qtx dao.StripeTx, sub *stripeSubscriptionObject) (int64, stripeClassification, bool) {
+	existing, err := qtx.GetSubscription(ctx, sub.ID)
+	if err == nil {
+		return existing.UserID, stripeClassification{}, true
+	}
+	if !errors.Is(err, dao.ErrStripeSubscriptionNotFound) {
+		return 0, stripeClassification{status: model.EventStatusPermanentFailure, errorMessage: err.Error()}, false
+	}
+	if sub.Customer != "" {
+		customer, err := qtx.GetCustomerByCustomerID(ctx, sub.Customer)
+		if err == nil {
+			return customer.UserID, stripeClassification{}, true
+		}
+		if !errors.Is(err, dao.ErrStripeCustomerNotFound) {
+			return 0, stripeClassification{status: model.EventStatusPermanentFailure, errorMessage: err.Error()}, false
+		}
+	}
+	if id, err := strconv.ParseInt(sub.Metadata["user_id"], 10, 64); err == nil && id > 0 {
+		return id, stripeClassification{}, true
+	}
+	return 0, stripeClassification{status: model.EventStatusPendingUserBinding, errorMessage: "no user binding for customer"}, false
+}
+
+// stripeSubscriptionView 把 Stripe 订阅行投影成 model.Subscription，复用 Apple 的 API status 映射规则。
+func stripeSubscriptionView(sub model.StripeSubscription) model.Subscription {
+	return model.Subscription{
+		ID:                 sub.ID,
+		UserID:             sub.UserID,
+		PlanID:             sub.PlanID,
+		ProviderProductID:  sub.PriceID,
+		Level:              sub.Level,
+		Status:             sub.Status,
+		AutoRenewStatus:    sub.AutoRenewStatus,
+		CurrentPeriodStart: sub.CurrentPeriodStart,
+		CurrentPeriodEnd:   sub.CurrentPeriodEnd,
+		LastEventAt:        sub.LastEventAt,
+	}
+}
@@ -0,0 +1,165 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/config"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+const defaultStripeAPIBaseURL = "https://api.stripe.com"
+
+// stripeProductConfig 是 STRIPE_PRODUCTS 中单个订阅价格的配置形状。
+type stripeProductConfig struct {
+	PlanID  string `json:"plan_id"`
+	PriceID string `json:"price_id"`
+	Level   int    `json:"level"`
+}
+
+// StripeCatalog 是 Stripe 配置的不可变运行期视图；调用方不得日志输出 secret key 与 webhook secret。
+//
+// Product.ProductID 存放 Stripe price id，只支持自动续费订阅。
+type StripeCatalog struct {
+	secretKey           string
+	webhookSecret       string
+	products            []Product
+	byPriceID           map[string]Product
+	byPlanID            map[string]Product
+	apiBaseURL          string
+	apiTimeout          time.Duration
+	webhookTolerance    time.Duration
+	webhookMaxBodyBytes int
+	successURL          string
+	cancelURL           string
+	portalReturnURL     string
+}
+
+// NewStripeCatalog 验证 Stripe 配置并构造只读 catalog。
+//
+// 行为：
+//   - SecretKey / Products 任一缺失 → ErrNotConfigured（checkout / portal 路由 503）。
+//   - products JSON 或 URL 不合法、SuccessURL / CancelURL 缺失 → wrap ErrInvalidConfig。
+//   - 生产环境要求所有 URL 必须是 https。
+//
+// WebhookSecret 为空不影响 checkout，只是 webhook 不可用。
+func NewStripeCatalog(cfg config.StripeConfig, appEnv string) (*StripeCatalog, error) {
+	if cfg.SecretKey == "" || strings.TrimSpace(cfg.Products) == "" {
+		return nil, ErrNotConfigured
+	}
+	products, byPrice, byPlan, err := parseStripeProducts(cfg.Products)
+	if err != nil {
+		return nil, err
+	}
+
+	apiBaseURL := cfg.APIBaseURL
+	if apiBaseURL == "" {
+		apiBaseURL = defaultStripeAPIBaseURL
+	}
+	if cfg.SuccessURL == "" || cfg.CancelURL == "" {
+		return nil, fmt.Errorf("STRIPE_SUCCESS_URL and STRIPE_CANCEL_URL required: %w", ErrInvalidConfig)
+	}
+	portalReturnURL := cfg.PortalReturnURL
+	if portalReturnURL == "" {
+		portalReturnURL = cfg.SuccessURL
+	}
+	for _, u := range []struct{ name, value string }{
+		{"STRIPE_API_BASE_URL", apiBaseURL},
+		{"STRIPE_SUCCESS_URL", cfg.SuccessURL},
+		{"STRIPE_CANCEL_URL", cfg.CancelURL},
+		{"STRIPE_PORTAL_RETURN_URL", portalReturnURL},
+	} {
+		if err := validateProviderURL(u.name, u.value, appEnv); err != nil {
+			return nil, err
+		}
+	}
+
+	return &StripeCatalog{
+		secretKey:           cfg.SecretKey,
+		webhookSecret:       cfg.WebhookSecret,
+		products:            products,
+		byPriceID:           byPrice,
+		byPlanID:            byPlan,
+		apiBaseURL:          strings.TrimRight(apiBaseURL, "/"),
+		apiTimeout:          cfg.APITimeout,
+		webhookTolerance:    cfg.WebhookTolerance,
+		webhookMaxBodyBytes: cfg.WebhookMaxBodyBytes,
+		successURL:          cfg.SuccessURL,
+		cancelURL:           cfg.CancelURL,
+		portalReturnURL:     portalReturnURL,
+	}, nil
+}
+
+func parseStripeProducts(raw string) ([]Product, map[string]Product, map[string]Product, error) {
+	var configs []stripeProductConfig
+	dec := json.NewDecoder(strings.NewReader(strings.TrimSpace(raw)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&configs); err != nil {
+		return nil, nil, nil, fmt.Errorf("parse STRIPE_PRODUCTS json: %w", joinInvalidConfig(err))
+	}
+	if len(configs) == 0 {
+		return nil, nil, nil, ErrNotConfigured
+	}
+
+	products := make([]Product, 0, len(configs))
+	byPrice := make(map[string]Product, len(configs))
+	byPlan := make(map[string]Product, len(configs))
+	for i, c := range configs {
+		if c.PlanID == "" || c.PriceID == "" {
+			return nil, nil, nil, fmt.Errorf("STRIPE_PRODUCTS[%d]: plan_id, price_id required: %w", i, ErrInvalidConfig)
+		}
+		_, dupPrice := byPrice[c.PriceID]
+		_, dupPlan := byPlan[c.PlanID]
+		if dupPrice || dupPlan {
+			return nil, nil, nil, fmt.Errorf("STRIPE_PRODUCTS[%d]: duplicate %s / %s: %w", i, c.PlanID, c.PriceID, errors.Join(ErrDuplicateProduct, ErrInvalidConfig))
+		}
+		p := Product{PlanID: c.PlanID, ProductID: c.PriceID, Type: model.ProductTypeSubscription, Level: c.Level}
+		byPrice[p.ProductID] = p
+		byPlan[p.PlanID] = p
+		products = append(products, p)
+	}
+	return products, byPrice, byPlan, nil
+}
+
+// LookupPlan 按内部 plan id 查找价格；未命中返回 ErrStripeUnknownPlan。
+func (c *StripeCatalog) LookupPlan(planID string) (Product, error) {
+	if c == nil {
+		return Product{}, ErrNotConfigured
+	}
+	p, ok := c.byPlanID[planID]
+	if !ok {
+		return Product{}, ErrStripeUnknownPlan
+	}
+	return p, nil
+}
+
+// LookupPrice 按 Stripe price id 查找商品。
+func (c *StripeCatalog) LookupPrice(priceID string) (Product, bool) {
+	if c == nil {
+		return Product{}, false
+	}
+	p, ok := c.byPriceID[priceID]
+	return p, ok
+}
+
+// Products 返回 catalog 内产品的拷贝。
+func (c *StripeCatalog) Products() []Product {
+	if c == nil || len(c.products) == 0 {
+		return nil
+	}
+	out := make([]Product, len(c.products))
+	copy(out, c.products)
+	return out
+}
+
+func (c *StripeCatalog) APIBaseURL() string              { return c.apiBaseURL }
+func (c *StripeCatalog) APITimeout() time.Duration       { return c.apiTimeout }
+func (c *StripeCatalog) WebhookSecret() string           { return c.webhookSecret }
+func (c *StripeCatalog) WebhookTolerance() time.Duration { return c.webhookTolerance }
+func (c *StripeCatalog) WebhookMaxBodyBytes() int        { return c.webhookMaxBodyBytes }
+func (c *StripeCatalog) SuccessURL() string              { return c.successURL }
+func (c *StripeCatalog) CancelURL() string               { return c.cancelURL }
+func (c *StripeCatalog) PortalReturnURL() string         { return c.portalReturnURL }
@@ -0,0 +1,163 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StripeAPI 封装本服务用到的 Stripe REST API 子集。
+type StripeAPI interface {
+	CreateCustomer(ctx context.Context, userID int64) (string, error)
+	CreateCheckoutSession(ctx context.Context, in StripeCheckoutParams) (StripeCheckoutSession, error)
+	CreatePortalSession(ctx context.Context, customerID, returnURL string) (string, error)
+}
+
+// StripeCheckoutParams 是创建 subscription 模式 Checkout Session 的入参。
+type StripeCheckoutParams struct {
+	UserID     int64
+	CustomerID string
+	PriceID    string
+	SuccessURL string
+	CancelURL  string
+}
+
+// StripeCheckoutSession 是 Checkout Session 创建结果中客户端需要的字段。
+type StripeCheckoutSession struct {
+	ID  string
+	URL string
+}
+
+// stripeClient 是 StripeAPI 的生产实现：form 编码请求 + secret key Bearer 认证。
+type stripeClient struct {
+	baseURL   string
+	secretKey string
+	http      *http.Client
+}
+
+// NewStripeAPI 用 catalog 构造 Stripe 客户端；catalog 为 nil 返回 ErrNotConfigured。
+func NewStripeAPI(c *StripeCatalog) (StripeAPI, error) {
+	if c == nil {
+		return nil, ErrNotConfigured
+	}
+	timeout := c.APITimeout()
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &stripeClient{
+		baseURL:   c.APIBaseURL(),
+		secretKey: c.secretKey,
+		http:      &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// CreateCustomer 创建 Stripe customer 并在 metadata 中记录 user id。
+//
+// Idempotency-Key 按 user 固定，并发的首次 checkout 在 Stripe 侧只会创建一个 customer。
+func (c *stripeClient) CreateCustomer(ctx context.Context, userID int64) (string, error) {
+	form := url.Values{"metadata[user_id]": {strconv.FormatInt(userID, 10)}}
+	var out struct {
+		ID string `json:"id"`
+	}
+	if err := c.post(ctx, "/v1/customers", form, "customer-user-"+strconv.FormatInt(userID, 10), &out); err != nil {
+		return "", err
+	}
+	if out.ID == "" {
+		return "", fmt.Errorf("stripe: create customer: empty id")
+	}
+	return out.ID, nil
+}
+
+// CreateCheckoutSession 创建 mode=subscription 的 Checkout Session。
+//
+// client_reference_id 与 subscription metadata 都写入 user id，webhook 在 customer 映射缺失时可以据此兜底。
+func (c *stripeClient) CreateCheckoutSession(ctx context.Context, in StripeCheckoutParams) (StripeCheckoutSession, error) {
+	userID := strconv.FormatInt(in.UserID, 10)
+	form := url.Values{
+		"mode":                                 {"subscription"},
+		"customer":                             {in.CustomerID},
+		"client_reference_id":                  {userID},
+		"line_items[0][price]":                 {in.PriceID},
+		"line_items[0][quantity]":              {"1"},
+		"success_url":                          {in.SuccessURL},
+		"cancel_url":                           {in.CancelURL},
+		"subscription_data[metadata][user_id]": {userID},
+	}
+	var out struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	if err := c.post(ctx, "/v1/checkout/sessions", form, "", &out); err != nil {
+		return StripeCheckoutSession{}, err
+	}
+	if out.URL == "" {
+		return StripeCheckoutSession{}, fmt.Errorf("stripe: create checkout session: empty url")
+	}
+	return StripeCheckoutSession{ID: out.ID, URL: out.URL}, nil
+}
+
+// CreatePortalSession 创建 customer portal session 并返回跳转链接。
+func (c *stripeClient) CreatePortalSession(ctx context.Context, customerID, returnURL string) (string, error) {
+	form := url.Values{"customer": {customerID}, "return_url": {returnURL}}
+	var out struct {
+		URL string `json:"url"`
+	}
+	if err := c.post(ctx, "/v1/billing_portal/sessions", form, "", &out); err != nil {
+		return "", err
+	}
+	if out.URL == "" {
+		return "", fmt.Errorf("stripe: create portal session: empty url")
+	}
+	return out.URL, nil
+}
+
+func (c *stripeClient) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("stripe: build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.secretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("stripe: POST %s: %w", path, err)
+	}
+	defer rsp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(rsp.Body, 1<<20))
+
+	switch {
+	case rsp.StatusCode == http.StatusUnauthorized || rsp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: status %d", ErrStripeAuthRejected, rsp.StatusCode)
+	case rsp.StatusCode == http.StatusBadRequest || rsp.StatusCode == http.StatusNotFound || rsp.StatusCode == http.StatusPaymentRequired:
+		return fmt.Errorf("%w: POST %s: status %d: %s", ErrStripeRequestRejected, path, rsp.StatusCode, stripeErrorMessage(data))
+	case rsp.StatusCode < 200 || rsp.StatusCode >= 300:
+		return fmt.Errorf("stripe: POST %s: unexpected status %d", path, rsp.StatusCode)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("stripe: decode response: %w", err)
+	}
+	return nil
+}
+
+// stripeErrorMessage 提取 Stripe 错误响应中的 error.message，便于排查配置问题（如 price 不存在）。
+func stripeErrorMessage(data []byte) string {
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return ""
+	}
+	return body.Error.Message
+}
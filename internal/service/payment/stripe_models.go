@@ -0,0 +1,137 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// 本服务处理的 Stripe webhook 事件类型。
+const (
+	stripeEventSubscriptionCreated = "customer.subscription.created"
+	stripeEventSubscriptionUpdated = "customer.subscription.updated"
+	stripeEventSubscriptionDeleted = "customer.subscription.deleted"
+)
+
+const defaultStripeWebhookTolerance = 5 * time.Minute
+
+// stripeEvent 是 webhook 事件的 JSON 形状；data.object 按事件类型再解码。
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// stripeSubscriptionObject 是 subscription 对象中本服务关心的字段。
+//
+// 新版 API 把 current_period_* 移到了 subscription item 上，这里两处都读，优先顶层字段。
+type stripeSubscriptionObject struct {
+	ID                 string            `json:"id"`
+	Customer           string            `json:"customer"`
+	Status             string            `json:"status"`
+	CancelAtPeriodEnd  bool              `json:"cancel_at_period_end"`
+	CurrentPeriodStart int64             `json:"current_period_start"`
+	CurrentPeriodEnd   int64             `json:"current_period_end"`
+	Metadata           map[string]string `json:"metadata"`
+	Items              struct {
+		Data []struct {
+			Price struct {
+				ID string `json:"id"`
+			} `json:"price"`
+			CurrentPeriodStart int64 `json:"current_period_start"`
+			CurrentPeriodEnd   int64 `json:"current_period_end"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// PriceID 返回第一个 subscription item 的 price id。
+func (s *stripeSubscriptionObject) PriceID() string {
+	if len(s.Items.Data) == 0 {
+		return ""
+	}
+	return s.Items.Data[0].Price.ID
+}
+
+// Period 返回当前计费周期。
+func (s *stripeSubscriptionObject) Period() (time.Time, time.Time) {
+	start, end := s.CurrentPeriodStart, s.CurrentPeriodEnd
+	if (start == 0 || end == 0) && len(s.Items.Data) > 0 {
+		start, end = s.Items.Data[0].CurrentPeriodStart, s.Items.Data[0].CurrentPeriodEnd
+	}
+	return time.Unix(start, 0).UTC(), time.Unix(end, 0).UTC()
+}
+
+// InternalStatus 把 Stripe subscription status 映射为订阅内部状态；ok == false 表示尚未生效（incomplete），不写入权益。
+//
+// past_due 期间 Stripe 仍在重试扣款，保留权益；cancel_at_period_end 的有效订阅按 CANCELED（到期前仍有权益）处理。
+func (s *stripeSubscriptionObject) InternalStatus() (string, bool) {
+	switch s.Status {
+	case "active", "trialing", "past_due":
+		if s.CancelAtPeriodEnd {
+			return model.SubscriptionStatusCanceled, true
+		}
+		return model.SubscriptionStatusActive, true
+	case "canceled", "unpaid", "incomplete_expired", "paused":
+		return model.SubscriptionStatusExpired, true
+	default:
+		return "", false
+	}
+}
+
+// verifyStripeSignature 按 Stripe 规范校验 Stripe-Signature：
+// HMAC-SHA256(secret, "<t>.<payload>") 与任一 v1 签名相等，且 t 与 now 的偏差不超过 tolerance。
+func verifyStripeSignature(payload []byte, header, secret string, tolerance time.Duration, now time.Time) error {
+	var (
+		timestamp  int64
+		signatures []string
+	)
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: bad timestamp", ErrStripeInvalidSignature)
+			}
+			timestamp = ts
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return fmt.Errorf("%w: missing timestamp or v1 signature", ErrStripeInvalidSignature)
+	}
+	if tolerance <= 0 {
+		tolerance = defaultStripeWebhookTolerance
+	}
+	skew := now.Sub(time.Unix(timestamp, 0))
+	if skew > tolerance || skew < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrStripeInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+	for _, sig := range signatures {
+		got, err := hex.DecodeString(sig)
+		if err == nil && hmac.Equal(got, expected) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no matching v1 signature", ErrStripeInvalidSignature)
+}
@@ -0,0 +1,443 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/config"
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+const testStripeWebhookSecret = "whsec_test"
+
+func validStripeConfig(baseURL string) config.StripeConfig {
+	return config.StripeConfig{
+		SecretKey:           "sk_test_123",
+		WebhookSecret:       testStripeWebhookSecret,
+		Products:            `[{"plan_id":"pro_monthly","price_id":"price_pro","level":1},{"plan_id":"max_monthly","price_id":"price_max","level":2}]`,
+		SuccessURL:          "https://app.example.com/billing/success",
+		CancelURL:           "https://app.example.com/billing/cancel",
+		APIBaseURL:          baseURL,
+		APITimeout:          5 * time.Second,
+		WebhookTolerance:    5 * time.Minute,
+		WebhookMaxBodyBytes: 262144,
+	}
+}
+
+func TestNewStripeCatalog_Validation(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		appEnv  string
+		mutate  func(*config.StripeConfig)
+		wantErr error
+	}{
+		{name: "missing secret key", mutate: func(c *config.StripeConfig) { c.SecretKey = "" }, wantErr: ErrNotConfigured},
+		{name: "missing products", mutate: func(c *config.StripeConfig) { c.Products = "" }, wantErr: ErrNotConfigured},
+		{name: "missing success url", mutate: func(c *config.StripeConfig) { c.SuccessURL = "" }, wantErr: ErrInvalidConfig},
+		{name: "duplicate plan", mutate: func(c *config.StripeConfig) {
+			c.Products = `[{"plan_id":"a","price_id":"p1"},{"plan_id":"a","price_id":"p2"}]`
+		}, wantErr: ErrDuplicateProduct},
+		{name: "unknown field", mutate: func(c *config.StripeConfig) {
+			c.Products = `[{"plan_id":"a","price_id":"p1","credits":1}]`
+		}, wantErr: ErrInvalidConfig},
+		{name: "http api url in prod", appEnv: AppEnvProd, wantErr: ErrInvalidConfig},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validStripeConfig("http://127.0.0.1:1")
+			if tc.mutate != nil {
+				tc.mutate(&cfg)
+			}
+			appEnv := tc.appEnv
+			if appEnv == "" {
+				appEnv = "dev"
+			}
+			if _, err := NewStripeCatalog(cfg, appEnv); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	c, err := NewStripeCatalog(validStripeConfig("http://127.0.0.1:1"), "dev")
+	if err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+	if c.PortalReturnURL() != "https://app.example.com/billing/success" {
+		t.Fatalf("portal return url should default to success url, got %q", c.PortalReturnURL())
+	}
+}
+
+func signStripePayload(payload []byte, ts time.Time, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", ts.Unix())
+	mac.Write(payload)
+	return fmt.Sprintf("t=%d,v1=%s", ts.Unix(), hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestVerifyStripeSignature(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	payload := []byte(`{"id":"evt_1"}`)
+	valid := signStripePayload(payload, now, testStripeWebhookSecret)
+
+	if err := verifyStripeSignature(payload, valid, testStripeWebhookSecret, time.Minute, now); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	rotated := "t=1760000000,v0=deadbeef,v1=00ff," + strings.TrimPrefix(valid, "t=1760000000,")
+	if err := verifyStripeSignature(payload, rotated, testStripeWebhookSecret, time.Minute, now); err != nil {
+		t.Fatalf("any matching v1 should be accepted: %v", err)
+	}
+	cases := map[string]struct {
+		header string
+		now    time.Time
+	}{
+		"wrong secret":     {signStripePayload(payload, now, "other"), now},
+		"stale timestamp":  {valid, now.Add(2 * time.Minute)},
+		"future timestamp": {valid, now.Add(-2 * time.Minute)},
+		"missing v1":       {"t=1760000000", now},
+		"garbage":          {"nope", now},
+	}
+	for name, tc := range cases {
+		if err := verifyStripeSignature(payload, tc.header, testStripeWebhookSecret, time.Minute, tc.now); !errors.Is(err, ErrStripeInvalidSignature) {
+			t.Fatalf("%s: err = %v, want ErrStripeInvalidSignature", name, err)
+		}
+	}
+	if err := verifyStripeSignature([]byte(`{"id":"evt_2"}`), valid, testStripeWebhookSecret, time.Minute, now); !errors.Is(err, ErrStripeInvalidSignature) {
+		t.Fatalf("tampered payload err = %v", err)
+	}
+}
+
+// fakeStripeAPI 是 Stripe REST API 的本地 stand-in，记录收到的 form 参数。
+type fakeStripeAPI struct {
+	mu        sync.Mutex
+	customers int
+	requests  map[string][]map[string]string
+}
+
+func newFakeStripeServer(t *testing.T) (*fakeStripeAPI, *httptest.Server) {
+	t.Helper()
+	f := &fakeStripeAPI{requests: map[string][]map[string]string{}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer sk_test_123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		form := map[string]string{}
+		for k := range r.PostForm {
+			form[k] = r.PostForm.Get(k)
+		}
+		f.requests[r.URL.Path] = append(f.requests[r.URL.Path], form)
+
+		switch r.URL.Path {
+		case "/v1/customers":
+			f.customers++
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "cus_" + strconv.Itoa(f.customers)})
+		case "/v1/checkout/sessions":
+			if form["line_items[0][price]"] == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":{"message":"No such price"}}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "cs_test_1", "url": "https://checkout.stripe.test/cs_test_1"})
+		case "/v1/billing_portal/sessions":
+			_ = json.NewEncoder(w).Encode(map[string]any{"url": "https://billing.stripe.test/p/" + form["customer"]})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+// fakeStripeDAO 是 dao.StripeDAO 的内存实现。
+type fakeStripeDAO struct {
+	mu            sync.Mutex
+	customers     map[int64]string
+	events        map[string]model.StripeEventInsert
+	subscriptions map[string]model.StripeSubscription
+	nextID        int64
+}
+
+func newFakeStripeDAO() *fakeStripeDAO {
+	return &fakeStripeDAO{
+		customers:     map[int64]string{},
+		events:        map[string]model.StripeEventInsert{},
+		subscriptions: map[string]model.StripeSubscription{},
+	}
+}
+
+func (d *fakeStripeDAO) GetCustomerByUser(_ context.Context, userID int64) (model.StripeCustomer, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id, ok := d.customers[userID]
+	if !ok {
+		return model.StripeCustomer{}, dao.ErrStripeCustomerNotFound
+	}
+	return model.StripeCustomer{UserID: userID, CustomerID: id}, nil
+}
+
+func (d *fakeStripeDAO) BindCustomer(_ context.Context, userID int64, customerID string) (model.StripeCustomer, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if id, ok := d.customers[userID]; ok {
+		return model.StripeCustomer{UserID: userID, CustomerID: id}, nil
+	}
+	d.customers[userID] = customerID
+	return model.StripeCustomer{UserID: userID, CustomerID: customerID}, nil
+}
+
+func (d *fakeStripeDAO) ListSubscriptionsForUserEntitlement(_ context.Context, userID int64) ([]model.StripeSubscription, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []model.StripeSubscription
+	for _, s := range d.subscriptions {
+		if s.UserID == userID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (d *fakeStripeDAO) InTx(_ context.Context, fn func(dao.StripeTx) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return fn(d)
+}
+
+func (d *fakeStripeDAO) InsertEventIfNotExists(_ context.Context, in model.StripeEventInsert) (bool, error) {
+	if _, ok := d.events[in.EventID]; ok {
+		return false, nil
+	}
+	d.events[in.EventID] = in
+	return true, nil
+}
+
+func (d *fakeStripeDAO) GetCustomerByCustomerID(_ context.Context, customerID string) (model.StripeCustomer, error) {
+	for userID, id := range d.customers {
+		if id == customerID {
+			return model.StripeCustomer{UserID: userID, CustomerID: id}, nil
+		}
+	}
+	return model.StripeCustomer{}, dao.ErrStripeCustomerNotFound
+}
+
+func (d *fakeStripeDAO) GetSubscription(_ context.Context, subscriptionID string) (model.StripeSubscription, error) {
+	s, ok := d.subscriptions[subscriptionID]
+	if !ok {
+		return model.StripeSubscription{}, dao.ErrStripeSubscriptionNotFound
+	}
+	return s, nil
+}
+
+func (d *fakeStripeDAO) UpsertSubscriptionWithOwnershipCheck(_ context.Context, in model.StripeSubscriptionUpsert) (model.StripeSubscription, error) {
+	cur, ok := d.subscriptions[in.SubscriptionID]
+	if ok && cur.UserID != in.UserID {
+		return model.StripeSubscription{}, dao.ErrStripeOwnershipConflict
+	}
+	if ok && in.LastEventAt.Before(cur.LastEventAt) {
+		return cur, nil
+	}
+	if !ok {
+		d.nextID++
+		cur.ID = d.nextID
+	}
+	cur.UserID, cur.CustomerID, cur.SubscriptionID, cur.PriceID, cur.PlanID = in.UserID, in.CustomerID, in.SubscriptionID, in.PriceID, in.PlanID
+	cur.Level, cur.Status, cur.StripeStatus, cur.AutoRenewStatus = in.Level, in.Status, in.StripeStatus, in.AutoRenewStatus
+	cur.CurrentPeriodStart, cur.CurrentPeriodEnd, cur.LastEventAt = in.CurrentPeriodStart, in.CurrentPeriodEnd, in.LastEventAt
+	d.subscriptions[in.SubscriptionID] = cur
+	return cur, nil
+}
+
+func newStripeFixture(t *testing.T) (*StripeService, *fakeStripeAPI, *fakeStripeDAO) {
+	t.Helper()
+	api, srv := newFakeStripeServer(t)
+	catalog, err := NewStripeCatalog(validStripeConfig(srv.URL), "dev")
+	if err != nil {
+		t.Fatalf("catalog: %v", err)
+	}
+	client, err := NewStripeAPI(catalog)
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	d := newFakeStripeDAO()
+	return NewStripeService(catalog, client, d), api, d
+}
+
+func TestStripeService_CheckoutCreatesCustomerOnce(t *testing.T) {
+	svc, api, d := newStripeFixture(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		out, err := svc.CreateCheckoutSession(ctx, 42, "max_monthly")
+		if err != nil {
+			t.Fatalf("checkout #%d: %v", i, err)
+		}
+		if out.SessionID != "cs_test_1" || out.URL == "" {
+			t.Fatalf("unexpected session: %+v", out)
+		}
+	}
+	if api.customers != 1 || d.customers[42] != "cus_1" {
+		t.Fatalf("customers created = %d, mapping = %q", api.customers, d.customers[42])
+	}
+	form := api.requests["/v1/checkout/sessions"][0]
+	if form["mode"] != "subscription" || form["customer"] != "cus_1" || form["line_items[0][price]"] != "price_max" ||
+		form["client_reference_id"] != "42" || form["subscription_data[metadata][user_id]"] != "42" {
+		t.Fatalf("unexpected checkout form: %v", form)
+	}
+
+	if _, err := svc.CreateCheckoutSession(ctx, 42, "missing"); !errors.Is(err, ErrStripeUnknownPlan) {
+		t.Fatalf("unknown plan err = %v", err)
+	}
+
+	portal, err := svc.CreatePortalSession(ctx, 42)
+	if err != nil || portal.URL != "https://billing.stripe.test/p/cus_1" {
+		t.Fatalf("portal = %+v, err = %v", portal, err)
+	}
+	if _, err := svc.CreatePortalSession(ctx, 7); !errors.Is(err, ErrStripeNoCustomer) {
+		t.Fatalf("portal without customer err = %v", err)
+	}
+}
+
+func stripeSubscriptionEvent(id, typ, status string, cancelAtPeriodEnd bool, periodEnd time.Time) []byte {
+	raw, _ := json.Marshal(map[string]any{
+		"id":      id,
+		"type":    typ,
+		"created": periodEnd.Add(-30 * 24 * time.Hour).Unix(),
+		"data": map[string]any{"object": map[string]any{
+			"id":                   "sub_1",
+			"customer":             "cus_1",
+			"status":               status,
+			"cancel_at_period_end": cancelAtPeriodEnd,
+			"items": map[string]any{"data": []any{map[string]any{
+				"price":                map[string]any{"id": "price_pro"},
+				"current_period_start": periodEnd.Add(-30 * 24 * time.Hour).Unix(),
+				"current_period_end":   periodEnd.Unix(),
+			}}},
+		}},
+	})
+	return raw
+}
+
+func TestStripeService_HandleWebhookLifecycle(t *testing.T) {
+	svc, _, d := newStripeFixture(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	svc.now = func() time.Time { return now }
+	d.customers[42] = "cus_1"
+	periodEnd := now.Add(30 * 24 * time.Hour)
+
+	deliver := func(payload []byte) error {
+		return svc.HandleWebhook(ctx, payload, signStripePayload(payload, now, testStripeWebhookSecret))
+	}
+
+	created := stripeSubscriptionEvent("evt_1", stripeEventSubscriptionCreated, "active", false, periodEnd)
+	if err := svc.HandleWebhook(ctx, created, signStripePayload(created, now, "wrong")); !errors.Is(err, ErrStripeInvalidSignature) {
+		t.Fatalf("bad signature err = %v", err)
+	}
+	if err := deliver(created); err != nil {
+		t.Fatalf("created: %v", err)
+	}
+	got := d.subscriptions["sub_1"]
+	if got.UserID != 42 || got.Status != model.SubscriptionStatusActive || got.PlanID != "pro_monthly" || !got.CurrentPeriodEnd.Equal(periodEnd) {
+		t.Fatalf("unexpected row after create: %+v", got)
+	}
+
+	if info := resolveInfo(t, 42, now, NewStripeEntitlementSource(d)); info.Status != "ACTIVE" || info.ProductID != "pro_monthly" {
+		t.Fatalf("entitlement info = %+v", info)
+	}
+
+	canceled := stripeSubscriptionEvent("evt_2", stripeEventSubscriptionUpdated, "active", true, periodEnd)
+	if err := deliver(canceled); err != nil {
+		t.Fatalf("updated: %v", err)
+	}
+	if got := d.subscriptions["sub_1"]; got.Status != model.SubscriptionStatusCanceled || got.AutoRenewStatus != model.AutoRenewStatusOff {
+		t.Fatalf("unexpected row after cancel: %+v", got)
+	}
+
+	deleted := stripeSubscriptionEvent("evt_3", stripeEventSubscriptionDeleted, "canceled", false, periodEnd)
+	if err := deliver(deleted); err != nil {
+		t.Fatalf("deleted: %v", err)
+	}
+	// 重放旧事件不会把订阅改回 ACTIVE。
+	if err := deliver(created); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if got := d.subscriptions["sub_1"]; got.Status != model.SubscriptionStatusExpired {
+		t.Fatalf("status = %q, want EXPIRED", got.Status)
+	}
+	if len(d.events) != 3 {
+		t.Fatalf("events = %d, want 3", len(d.events))
+	}
+
+	other, _ := json.Marshal(map[string]any{"id": "evt_4", "type": "invoice.paid", "data": map[string]any{"object": map[string]any{}}})
+	if err := deliver(other); err != nil {
+		t.Fatalf("other event: %v", err)
+	}
+	if d.events["evt_4"].ProcessingStatus != model.EventStatusIgnoredUnknownType {
+		t.Fatalf("unexpected status: %+v", d.events["evt_4"])
+	}
+}
+
+func TestStripeService_HandleWebhookOutOfOrder(t *testing.T) {
+	svc, _, d := newStripeFixture(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	svc.now = func() time.Time { return now.Add(time.Hour) }
+	d.customers[42] = "cus_1"
+	periodEnd := now.Add(30 * 24 * time.Hour)
+
+	deliver := func(payload []byte) {
+		t.Helper()
+		if err := svc.HandleWebhook(ctx, payload, signStripePayload(payload, svc.now(), testStripeWebhookSecret)); err != nil {
+			t.Fatalf("webhook: %v", err)
+		}
+	}
+	// 较新的取消事件先到，created 更早的续订事件后到，不得把订阅改回自动续订。
+	deliver(stripeSubscriptionEvent("evt_new", stripeEventSubscriptionUpdated, "active", true, periodEnd))
+	deliver(stripeSubscriptionEvent("evt_old", stripeEventSubscriptionUpdated, "active", false, periodEnd.Add(-time.Hour)))
+
+	got := d.subscriptions["sub_1"]
+	if got.Status != model.SubscriptionStatusCanceled || got.AutoRenewStatus != model.AutoRenewStatusOff || !got.CurrentPeriodEnd.Equal(periodEnd) {
+		t.Fatalf("stale event overwrote subscription: %+v", got)
+	}
+	if !got.LastEventAt.Equal(now) {
+		t.Fatalf("last_event_at = %v, want event created %v", got.LastEventAt, now)
+	}
+	if len(d.events) != 2 {
+		t.Fatalf("events = %d, want 2", len(d.events))
+	}
+}
+
+func TestStripeService_HandleWebhookUnboundCustomer(t *testing.T) {
+	svc, _, d := newStripeFixture(t)
+	now := time.Now().UTC()
+	svc.now = func() time.Time { return now }
+
+	payload := stripeSubscriptionEvent("evt_1", stripeEventSubscriptionCreated, "active", false, now.Add(time.Hour))
+	if err := svc.HandleWebhook(context.Background(), payload, signStripePayload(payload, now, testStripeWebhookSecret)); err != nil {
+		t.Fatalf("webhook: %v", err)
+	}
+	if d.events["evt_1"].ProcessingStatus != model.EventStatusPendingUserBinding || len(d.subscriptions) != 0 {
+		t.Fatalf("events = %+v, subscriptions = %+v", d.events, d.subscriptions)
+	}
+}
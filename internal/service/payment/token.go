@@ -13,7 +13,7 @@ var ErrAccountTokenNotFound = dao.ErrAccountTokenNotFound
 
 // TokenDAO 是 TokenService 的持久化依赖。具体实现由 internal/dao 提供。
 type TokenDAO interface {
-	GetOrCreateAccountToken(ctx context.Context, userID int64) (string, error)
+	GetOrCreateAccountToken(ctx context.Context, userID int64) (string, bool, error)
 	GetAccountTokenByToken(ctx context.Context, token string) (model.AppleAccountToken, error)
 }
 
@@ -22,7 +22,8 @@ type TokenDAO interface {
 // 该 token 不是认证凭证；它只是本服务与 Apple transaction 之间的关联键。
 // 对应 API endpoint 仍然要求调用方携带 Bearer access token。
 type TokenService struct {
-	dao TokenDAO
+	dao     TokenDAO
+	onBound func(ctx context.Context, token string)
 }
 
 // NewTokenService 构造 TokenService。dao 为 nil 时 service 调用都会返回 not-configured 错误。
@@ -30,12 +31,34 @@ func NewTokenService(d TokenDAO) *TokenService {
 	return &TokenService{dao: d}
 }
 
+// WithBindingHook 注册 token 绑定回调：EnsureAccountToken 首次建立映射或 verify / restore 成功后异步调用，
+// 用于重放在绑定前到达、被搁置为 PENDING_USER_BINDING 的通知。需在服务启动前设置。
+func (s *TokenService) WithBindingHook(fn func(ctx context.Context, token string)) *TokenService {
+	s.onBound = fn
+	return s
+}
+
 // EnsureAccountToken 返回 userID 对应的 appAccountToken UUID，首调时持久化生成。
 func (s *TokenService) EnsureAccountToken(ctx context.Context, userID int64) (string, error) {
 	if s == nil || s.dao == nil {
 		return "", errors.New("payment: token service not configured")
 	}
-	return s.dao.GetOrCreateAccountToken(ctx, userID)
+	token, created, err := s.dao.GetOrCreateAccountToken(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if created {
+		s.notifyBound(ctx, token)
+	}
+	return token, nil
+}
+
+// notifyBound 在独立 goroutine 里执行绑定回调，重放耗时不计入请求延迟，也不随请求取消而中断。
+func (s *TokenService) notifyBound(ctx context.Context, token string) {
+	if s == nil || s.onBound == nil || token == "" {
+		return
+	}
+	go s.onBound(context.WithoutCancel(ctx), token)
 }
 
 // ResolveUserByToken 通过 token 反查映射，未命中返回 ErrAccountTokenNotFound。
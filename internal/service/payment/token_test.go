@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/dundunHa/go-serverhttp-template/internal/dao"
 	"github.com/dundunHa/go-serverhttp-template/internal/model"
@@ -19,26 +20,31 @@ type fakeTokenDAO struct {
 	callsGetOrCreate int
 	callsLookup      int
 	nextSeed         int64
+	nextToken        string
 }
 
 func newFakeTokenDAO() *fakeTokenDAO {
 	return &fakeTokenDAO{tokens: map[int64]string{}}
 }
 
-func (f *fakeTokenDAO) GetOrCreateAccountToken(_ context.Context, userID int64) (string, error) {
+func (f *fakeTokenDAO) GetOrCreateAccountToken(_ context.Context, userID int64) (string, bool, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	f.callsGetOrCreate++
 	if f.errOnGet != nil {
-		return "", f.errOnGet
+		return "", false, f.errOnGet
 	}
 	if t, ok := f.tokens[userID]; ok {
-		return t, nil
+		return t, false, nil
 	}
-	f.nextSeed++
-	t := fmt.Sprintf("00000000-0000-4000-8000-%012d", f.nextSeed)
+	t := f.nextToken
+	if t == "" {
+		f.nextSeed++
+		t = fmt.Sprintf("00000000-0000-4000-8000-%012d", f.nextSeed)
+	}
+	f.nextToken = ""
 	f.tokens[userID] = t
-	return t, nil
+	return t, true, nil
 }
 
 func (f *fakeTokenDAO) GetAccountTokenByToken(_ context.Context, token string) (model.AppleAccountToken, error) {
@@ -112,6 +118,40 @@ func TestTokenService_EnsureAccountToken_PropagatesDAOError(t *testing.T) {
 	}
 }
 
+func TestTokenService_EnsureAccountToken_HookOnlyOnCreate(t *testing.T) {
+	svc := NewTokenService(newFakeTokenDAO())
+	bound := make(chan string, 2)
+	svc.WithBindingHook(func(ctx context.Context, token string) {
+		if ctx.Err() != nil {
+			t.Errorf("hook ctx must outlive the request: %v", ctx.Err())
+		}
+		bound <- token
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first, err := svc.EnsureAccountToken(ctx, 42)
+	cancel()
+	if err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if _, err := svc.EnsureAccountToken(context.Background(), 42); err != nil {
+		t.Fatalf("second call: %v", err)
+	}
+	select {
+	case got := <-bound:
+		if got != first {
+			t.Fatalf("hook token = %s, want %s", got, first)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("hook not called on first creation")
+	}
+	select {
+	case got := <-bound:
+		t.Fatalf("hook must not fire for an existing mapping, got %s", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
 func TestTokenService_ResolveUserByToken_NotFound(t *testing.T) {
 	svc := NewTokenService(newFakeTokenDAO())
 	_, err := svc.ResolveUserByToken(context.Background(), "00000000-0000-4000-8000-000000000001")
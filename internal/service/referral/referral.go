@@ -0,0 +1,254 @@
+package referral
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+	logpkg "github.com/dundunHa/go-serverhttp-template/pkg/log"
+)
+
+var (
+	ErrNotConfigured       = errors.New("referral: not configured")
+	ErrInvalidCode         = errors.New("referral: invalid code")
+	ErrCodeNotFound        = dao.ErrReferralCodeNotFound
+	ErrSelfReferral        = errors.New("referral: cannot redeem own code")
+	ErrAlreadyRedeemed     = dao.ErrReferralAlreadyRedeemed
+	ErrDeviceUsed          = dao.ErrReferralDeviceUsed
+	ErrDeviceRequired      = errors.New("referral: device id required")
+	ErrRedeemWindowClosed  = errors.New("referral: redeem window closed")
+	ErrInviterLimitReached = errors.New("referral: inviter reached invite limit")
+)
+
+const guestProvider = "guest"
+
+// Config 描述兑换规则与双方奖励。
+type Config struct {
+	// RedeemWindow 是注册后允许兑换的时长；<= 0 表示不限制。
+	RedeemWindow time.Duration
+	// InviterCap 是每个邀请人最多可被兑换的次数；<= 0 表示不限制。
+	InviterCap    int
+	InviterReward model.ReferralRewards
+	InviteeReward model.ReferralRewards
+	// CreditsTTL 是奖励积分的有效期；<= 0 表示永不过期。
+	CreditsTTL time.Duration
+	// CompPlanID / CompLevel 是赠送会员权益对应的内部 plan 与订阅等级。
+	CompPlanID string
+	CompLevel  int
+}
+
+// RedeemInput 是一次兑换请求。Provider / ProviderSubject 取自 access token，用于识别游客设备。
+type RedeemInput struct {
+	UserID          int64
+	Provider        string
+	ProviderSubject string
+	Code            string
+	DeviceID        string
+}
+
+// RedeemResult 是兑换成功后发放给被邀请人的奖励。
+type RedeemResult struct {
+	Reward     model.ReferralRewards
+	CompEndsAt *time.Time
+}
+
+// Info 是 GET /users/me/referral 的领域视图。
+type Info struct {
+	Code         string
+	Stats        model.ReferralStats
+	RedeemedCode string
+	Config       Config
+}
+
+// Service 管理邀请码与兑换。
+//
+// 反作弊规则（全部在同一事务内校验，邀请码行加锁保证邀请上限计数准确）：
+//   - 不能兑换自己的邀请码，也不能用邀请人自己的游客设备兑换；
+//   - 每个用户只能兑换一次，每个设备 / 游客身份只能参与一次兑换；
+//   - 只能在注册后 RedeemWindow 内兑换；
+//   - 每个邀请人最多被兑换 InviterCap 次。
+type Service struct {
+	dao dao.ReferralDAO
+	cfg Config
+	now func() time.Time
+}
+
+// NewService 构造邀请 service。dao 为 nil 时所有调用返回 ErrNotConfigured。
+func NewService(d dao.ReferralDAO, cfg Config) *Service {
+	if cfg.CompLevel <= 0 {
+		cfg.CompLevel = 1
+	}
+	if cfg.CompPlanID == "" {
+		cfg.CompPlanID = "referral"
+	}
+	return &Service{
+		dao: d,
+		cfg: cfg,
+		now: func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// GetInfo 返回用户的邀请码（首次访问时生成）、邀请统计以及自己兑换过的邀请码。
+func (s *Service) GetInfo(ctx context.Context, userID int64) (Info, error) {
+	if s == nil || s.dao == nil {
+		return Info{}, ErrNotConfigured
+	}
+	code, err := s.dao.GetOrCreateCode(ctx, userID)
+	if err != nil {
+		return Info{}, err
+	}
+	stats, err := s.dao.GetStats(ctx, userID)
+	if err != nil {
+		return Info{}, err
+	}
+	info := Info{Code: code, Stats: stats, Config: s.cfg}
+	redemption, err := s.dao.GetRedemptionByInvitee(ctx, userID)
+	switch {
+	case err == nil:
+		info.RedeemedCode = redemption.Code
+	case !errors.Is(err, pgx.ErrNoRows):
+		return Info{}, err
+	}
+	return info, nil
+}
+
+// Redeem 兑换邀请码并同事务给双方发放奖励。
+func (s *Service) Redeem(ctx context.Context, in RedeemInput) (RedeemResult, error) {
+	if s == nil || s.dao == nil {
+		return RedeemResult{}, ErrNotConfigured
+	}
+	code := NormalizeCode(in.Code)
+	if code == "" {
+		return RedeemResult{}, ErrInvalidCode
+	}
+	deviceKey := deviceKeyFor(in)
+	if deviceKey == "" {
+		return RedeemResult{}, ErrDeviceRequired
+	}
+
+	now := s.now()
+	var result RedeemResult
+	err := s.dao.InTx(ctx, func(tx dao.ReferralTx) error {
+		inviterID, err := tx.LockCode(ctx, code)
+		if err != nil {
+			return err
+		}
+		if inviterID == in.UserID {
+			return ErrSelfReferral
+		}
+		if s.cfg.RedeemWindow > 0 {
+			signup, err := tx.GetUserCreatedAt(ctx, in.UserID)
+			if err != nil {
+				return err
+			}
+			if now.Sub(signup) > s.cfg.RedeemWindow {
+				return ErrRedeemWindowClosed
+			}
+		}
+		redeemed, err := tx.HasRedeemed(ctx, in.UserID)
+		if err != nil {
+			return err
+		}
+		if redeemed {
+			return ErrAlreadyRedeemed
+		}
+		inviterDevices, err := tx.ListGuestSubjects(ctx, inviterID)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(inviterDevices, deviceKey) {
+			return ErrSelfReferral
+		}
+		if s.cfg.InviterCap > 0 {
+			n, err := tx.CountRedemptions(ctx, inviterID)
+			if err != nil {
+				return err
+			}
+			if n >= s.cfg.InviterCap {
+				return ErrInviterLimitReached
+			}
+		}
+
+		redemption, err := tx.InsertRedemption(ctx, model.ReferralRedemption{
+			InviterUserID: inviterID,
+			InviteeUserID: in.UserID,
+			Code:          code,
+			DeviceKey:     deviceKey,
+			InviterReward: s.cfg.InviterReward,
+			InviteeReward: s.cfg.InviteeReward,
+		})
+		if err != nil {
+			return err
+		}
+		reference := fmt.Sprintf("referral:%d", redemption.ID)
+		if _, err := s.grant(ctx, tx, inviterID, s.cfg.InviterReward, reference, now); err != nil {
+			return err
+		}
+		compEndsAt, err := s.grant(ctx, tx, in.UserID, s.cfg.InviteeReward, reference, now)
+		if err != nil {
+			return err
+		}
+		result = RedeemResult{Reward: s.cfg.InviteeReward, CompEndsAt: compEndsAt}
+		return nil
+	})
+	if err != nil {
+		return RedeemResult{}, err
+	}
+	logpkg.FromContext(ctx).Info("referral redeemed", "invitee_user_id", in.UserID, "code", code)
+	return result, nil
+}
+
+// grant 给一方发放奖励，返回赠送权益的到期时间（没有赠送权益时为 nil）。
+func (s *Service) grant(ctx context.Context, tx dao.ReferralTx, userID int64, reward model.ReferralRewards, reference string, now time.Time) (*time.Time, error) {
+	if reward.Credits > 0 {
+		grant := model.CreditGrant{
+			UserID:    userID,
+			Source:    model.CreditSourceReferral,
+			Amount:    reward.Credits,
+			Reference: reference,
+		}
+		if s.cfg.CreditsTTL > 0 {
+			expiresAt := now.Add(s.cfg.CreditsTTL)
+			grant.ExpiresAt = &expiresAt
+		}
+		if _, err := tx.GrantCredits(ctx, grant); err != nil {
+			return nil, err
+		}
+	}
+	if reward.CompDays <= 0 {
+		return nil, nil
+	}
+	comp, err := tx.GrantComp(ctx, model.CompGrant{
+		UserID:    userID,
+		PlanID:    s.cfg.CompPlanID,
+		Level:     s.cfg.CompLevel,
+		Source:    model.CompSourceReferral,
+		Reference: reference,
+		Duration:  time.Duration(reward.CompDays) * 24 * time.Hour,
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+	return &comp.EndsAt, nil
+}
+
+// NormalizeCode 去掉空白并转为大写；邀请码不区分大小写。
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// deviceKeyFor 返回用于“一台设备只能兑换一次”的标识：游客账号直接使用游客 subject（即设备 ID），
+// 其它账号使用客户端上报的 device_id。
+func deviceKeyFor(in RedeemInput) string {
+	if in.Provider == guestProvider && in.ProviderSubject != "" {
+		return in.ProviderSubject
+	}
+	return strings.TrimSpace(in.DeviceID)
+}
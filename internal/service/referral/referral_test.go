@@ -0,0 +1,289 @@
+package referral
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	"github.com/dundunHa/go-serverhttp-template/internal/model"
+)
+
+// fakeReferralDAO 在内存中模拟 referral 表；InTx 失败时丢弃本次事务的写入。
+type fakeReferralDAO struct {
+	codes       map[int64]string
+	signups     map[int64]time.Time
+	guests      map[int64][]string
+	redemptions []model.ReferralRedemption
+	credits     []model.CreditGrant
+	comps       []model.CompGrant
+}
+
+func newFakeReferralDAO() *fakeReferralDAO {
+	return &fakeReferralDAO{
+		codes:   map[int64]string{},
+		signups: map[int64]time.Time{},
+		guests:  map[int64][]string{},
+	}
+}
+
+func (d *fakeReferralDAO) GetOrCreateCode(_ context.Context, userID int64) (string, error) {
+	if code, ok := d.codes[userID]; ok {
+		return code, nil
+	}
+	code := "CODE" + string(rune('A'+userID))
+	d.codes[userID] = code
+	return code, nil
+}
+
+func (d *fakeReferralDAO) GetStats(_ context.Context, inviterUserID int64) (model.ReferralStats, error) {
+	var st model.ReferralStats
+	for _, r := range d.redemptions {
+		if r.InviterUserID == inviterUserID {
+			st.InvitedCount++
+			st.EarnedCredits += r.InviterReward.Credits
+			st.EarnedCompDays += r.InviterReward.CompDays
+		}
+	}
+	return st, nil
+}
+
+func (d *fakeReferralDAO) GetRedemptionByInvitee(_ context.Context, inviteeUserID int64) (model.ReferralRedemption, error) {
+	for _, r := range d.redemptions {
+		if r.InviteeUserID == inviteeUserID {
+			return r, nil
+		}
+	}
+	return model.ReferralRedemption{}, pgx.ErrNoRows
+}
+
+func (d *fakeReferralDAO) InTx(_ context.Context, fn func(dao.ReferralTx) error) error {
+	tx := &fakeReferralTx{d: d, redemptions: d.redemptions, credits: d.credits, comps: d.comps}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	d.redemptions, d.credits, d.comps = tx.redemptions, tx.credits, tx.comps
+	return nil
+}
+
+type fakeReferralTx struct {
+	d           *fakeReferralDAO
+	redemptions []model.ReferralRedemption
+	credits     []model.CreditGrant
+	comps       []model.CompGrant
+}
+
+func (t *fakeReferralTx) LockCode(_ context.Context, code string) (int64, error) {
+	for id, c := range t.d.codes {
+		if c == code {
+			return id, nil
+		}
+	}
+	return 0, dao.ErrReferralCodeNotFound
+}
+
+func (t *fakeReferralTx) GetUserCreatedAt(_ context.Context, userID int64) (time.Time, error) {
+	return t.d.signups[userID], nil
+}
+
+func (t *fakeReferralTx) HasRedeemed(_ context.Context, inviteeUserID int64) (bool, error) {
+	for _, r := range t.redemptions {
+		if r.InviteeUserID == inviteeUserID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (t *fakeReferralTx) ListGuestSubjects(_ context.Context, userID int64) ([]string, error) {
+	return t.d.guests[userID], nil
+}
+
+func (t *fakeReferralTx) CountRedemptions(_ context.Context, inviterUserID int64) (int, error) {
+	n := 0
+	for _, r := range t.redemptions {
+		if r.InviterUserID == inviterUserID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (t *fakeReferralTx) InsertRedemption(_ context.Context, in model.ReferralRedemption) (model.ReferralRedemption, error) {
+	for _, r := range t.redemptions {
+		if r.DeviceKey == in.DeviceKey {
+			return model.ReferralRedemption{}, dao.ErrReferralDeviceUsed
+		}
+	}
+	in.ID = int64(len(t.redemptions) + 1)
+	t.redemptions = append(t.redemptions, in)
+	return in, nil
+}
+
+func (t *fakeReferralTx) GrantCredits(_ context.Context, in model.CreditGrant) (model.CreditBucket, error) {
+	t.credits = append(t.credits, in)
+	return model.CreditBucket{UserID: in.UserID, Amount: in.Amount}, nil
+}
+
+func (t *fakeReferralTx) GrantComp(_ context.Context, in model.CompGrant, now time.Time) (model.CompEntitlement, error) {
+	t.comps = append(t.comps, in)
+	return model.CompEntitlement{UserID: in.UserID, StartsAt: now, EndsAt: now.Add(in.Duration)}, nil
+}
+
+var testNow = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(d *fakeReferralDAO, cfg Config) *Service {
+	svc := NewService(d, cfg)
+	svc.now = func() time.Time { return testNow }
+	return svc
+}
+
+func defaultTestConfig() Config {
+	return Config{
+		RedeemWindow:  7 * 24 * time.Hour,
+		InviterCap:    2,
+		InviterReward: model.ReferralRewards{Credits: 100},
+		InviteeReward: model.ReferralRewards{Credits: 50, CompDays: 7},
+	}
+}
+
+// seed 创建邀请人 1（邀请码 CODEB）以及若干刚注册的被邀请人。
+func seed(t *testing.T, d *fakeReferralDAO, invitees ...int64) string {
+	t.Helper()
+	code, _ := d.GetOrCreateCode(context.Background(), 1)
+	d.signups[1] = testNow.Add(-30 * 24 * time.Hour)
+	for _, id := range invitees {
+		d.signups[id] = testNow.Add(-time.Hour)
+	}
+	return code
+}
+
+func TestRedeemGrantsBothSides(t *testing.T) {
+	d := newFakeReferralDAO()
+	code := seed(t, d, 2)
+	svc := newTestService(d, defaultTestConfig())
+
+	res, err := svc.Redeem(context.Background(), RedeemInput{UserID: 2, Provider: "guest", ProviderSubject: "device-2", Code: " " + code + " "})
+	if err != nil {
+		t.Fatalf("redeem: %v", err)
+	}
+	if res.Reward.Credits != 50 || res.CompEndsAt == nil || !res.CompEndsAt.Equal(testNow.Add(7*24*time.Hour)) {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if len(d.credits) != 2 || d.credits[0].UserID != 1 || d.credits[0].Amount != 100 || d.credits[1].UserID != 2 || d.credits[1].Amount != 50 {
+		t.Fatalf("unexpected credit grants: %+v", d.credits)
+	}
+	if d.credits[0].Source != model.CreditSourceReferral || d.credits[0].Reference != "referral:1" {
+		t.Fatalf("unexpected grant metadata: %+v", d.credits[0])
+	}
+	if len(d.comps) != 1 || d.comps[0].UserID != 2 || d.comps[0].Level != 1 || d.comps[0].PlanID != "referral" {
+		t.Fatalf("unexpected comp grants: %+v", d.comps)
+	}
+	if d.redemptions[0].DeviceKey != "device-2" {
+		t.Fatalf("guest subject should be the device key: %+v", d.redemptions[0])
+	}
+}
+
+func TestRedeemAntiFraud(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(d *fakeReferralDAO)
+		in      RedeemInput
+		want    error
+	}{
+		{
+			name: "self referral",
+			in:   RedeemInput{UserID: 1, Provider: "guest", ProviderSubject: "device-1"},
+			want: ErrSelfReferral,
+		},
+		{
+			name:    "inviter's own device",
+			prepare: func(d *fakeReferralDAO) { d.guests[1] = []string{"shared-device"} },
+			in:      RedeemInput{UserID: 2, Provider: "apple", ProviderSubject: "apple-2", DeviceID: "shared-device"},
+			want:    ErrSelfReferral,
+		},
+		{
+			name: "device required for non guest",
+			in:   RedeemInput{UserID: 2, Provider: "gmail", ProviderSubject: "gmail-2"},
+			want: ErrDeviceRequired,
+		},
+		{
+			name:    "signup too old",
+			prepare: func(d *fakeReferralDAO) { d.signups[2] = testNow.Add(-8 * 24 * time.Hour) },
+			in:      RedeemInput{UserID: 2, Provider: "guest", ProviderSubject: "device-2"},
+			want:    ErrRedeemWindowClosed,
+		},
+		{
+			name: "unknown code",
+			in:   RedeemInput{UserID: 2, Provider: "guest", ProviderSubject: "device-2", Code: "NOPE"},
+			want: ErrCodeNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeReferralDAO()
+			code := seed(t, d, 2)
+			if tt.prepare != nil {
+				tt.prepare(d)
+			}
+			if tt.in.Code == "" {
+				tt.in.Code = code
+			}
+			_, err := newTestService(d, defaultTestConfig()).Redeem(context.Background(), tt.in)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if len(d.redemptions) != 0 || len(d.credits) != 0 {
+				t.Fatalf("rejected redemption must not write: %+v %+v", d.redemptions, d.credits)
+			}
+		})
+	}
+}
+
+func TestRedeemOncePerUserDeviceAndInviterCap(t *testing.T) {
+	d := newFakeReferralDAO()
+	code := seed(t, d, 2, 3, 4, 5)
+	svc := newTestService(d, defaultTestConfig())
+	ctx := context.Background()
+
+	if _, err := svc.Redeem(ctx, RedeemInput{UserID: 2, Provider: "guest", ProviderSubject: "device-2", Code: code}); err != nil {
+		t.Fatalf("first redeem: %v", err)
+	}
+	if _, err := svc.Redeem(ctx, RedeemInput{UserID: 2, Provider: "guest", ProviderSubject: "device-2", Code: code}); !errors.Is(err, ErrAlreadyRedeemed) {
+		t.Fatalf("second redeem by same user err = %v, want ErrAlreadyRedeemed", err)
+	}
+	if _, err := svc.Redeem(ctx, RedeemInput{UserID: 3, Provider: "apple", ProviderSubject: "apple-3", Code: code, DeviceID: "device-2"}); !errors.Is(err, ErrDeviceUsed) {
+		t.Fatalf("same device err = %v, want ErrDeviceUsed", err)
+	}
+	if _, err := svc.Redeem(ctx, RedeemInput{UserID: 4, Provider: "guest", ProviderSubject: "device-4", Code: code}); err != nil {
+		t.Fatalf("second invitee: %v", err)
+	}
+	if _, err := svc.Redeem(ctx, RedeemInput{UserID: 5, Provider: "guest", ProviderSubject: "device-5", Code: code}); !errors.Is(err, ErrInviterLimitReached) {
+		t.Fatalf("over cap err = %v, want ErrInviterLimitReached", err)
+	}
+
+	info, err := svc.GetInfo(ctx, 1)
+	if err != nil {
+		t.Fatalf("get info: %v", err)
+	}
+	if info.Code != code || info.Stats.InvitedCount != 2 || info.Stats.EarnedCredits != 200 {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	invitee, err := svc.GetInfo(ctx, 2)
+	if err != nil {
+		t.Fatalf("get invitee info: %v", err)
+	}
+	if invitee.RedeemedCode != code {
+		t.Fatalf("redeemed code = %q, want %q", invitee.RedeemedCode, code)
+	}
+}
+
+func TestServiceNotConfigured(t *testing.T) {
+	svc := NewService(nil, Config{})
+	if _, err := svc.GetInfo(context.Background(), 1); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("err = %v, want ErrNotConfigured", err)
+	}
+}
@@ -0,0 +1,39 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/pkg/cache"
+)
+
+// ErrCacheMiss 表示缓存中没有该 key，调用方应回源数据库。
+var ErrCacheMiss = errors.New("settings: cache miss")
+
+// Cache 是 Service 依赖的最小缓存接口，值为用户的覆盖项（key -> 原始 JSON）。
+type Cache interface {
+	Get(ctx context.Context, key string) (map[string]json.RawMessage, error)
+	Set(ctx context.Context, key string, val map[string]json.RawMessage, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+}
+
+// RedisCache 通过 pkg/cache 的全局 Default 实例读写缓存。
+type RedisCache struct{}
+
+func (RedisCache) Get(ctx context.Context, key string) (map[string]json.RawMessage, error) {
+	v, err := cache.Get[map[string]json.RawMessage](ctx, key)
+	if cache.IsMiss(err) {
+		return nil, ErrCacheMiss
+	}
+	return v, err
+}
+
+func (RedisCache) Set(ctx context.Context, key string, val map[string]json.RawMessage, ttl time.Duration) error {
+	return cache.Set(ctx, key, val, ttl)
+}
+
+func (RedisCache) Del(ctx context.Context, key string) error {
+	return cache.Del(ctx, key)
+}
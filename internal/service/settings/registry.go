@@ -0,0 +1,153 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// ValueType 是配置项允许的取值类型。
+type ValueType string
+
+const (
+	TypeBool   ValueType = "bool"
+	TypeInt    ValueType = "int"
+	TypeString ValueType = "string"
+	TypeEnum   ValueType = "enum"
+)
+
+// Definition 描述一个可由用户修改的配置项。
+//
+// Default 的 Go 类型必须与 Type 对应：bool / int64 / string（Enum 也用 string）。
+// Min / Max 仅对 TypeInt 生效；MaxLength 仅对 TypeString 生效，0 表示不限制；
+// Values 是 TypeEnum 的合法取值。
+type Definition struct {
+	Key       string
+	Type      ValueType
+	Default   any
+	Min       int64
+	Max       int64
+	MaxLength int
+	Values    []string
+}
+
+// Registry 是服务端维护的配置项 schema：只有登记过的 key 才能读写。
+//
+// 新增配置项只需要在 DefaultRegistry 中追加 Definition，不需要数据库迁移。
+type Registry struct {
+	defs map[string]Definition
+	keys []string
+}
+
+// NewRegistry 校验并构造 registry；key 重复或默认值不满足自身约束时返回错误。
+func NewRegistry(defs ...Definition) (*Registry, error) {
+	r := &Registry{defs: make(map[string]Definition, len(defs))}
+	for _, d := range defs {
+		if d.Key == "" {
+			return nil, fmt.Errorf("settings: empty key")
+		}
+		if _, dup := r.defs[d.Key]; dup {
+			return nil, fmt.Errorf("settings: duplicate key %q", d.Key)
+		}
+		raw, err := json.Marshal(d.Default)
+		if err != nil {
+			return nil, fmt.Errorf("settings: marshal default of %q: %w", d.Key, err)
+		}
+		if _, err := d.decode(raw); err != nil {
+			return nil, fmt.Errorf("settings: default of %q: %w", d.Key, err)
+		}
+		r.defs[d.Key] = d
+		r.keys = append(r.keys, d.Key)
+	}
+	sort.Strings(r.keys)
+	return r, nil
+}
+
+// DefaultRegistry 返回本服务当前支持的配置项。
+func DefaultRegistry() *Registry {
+	r, err := NewRegistry(
+		Definition{Key: "notifications.push_enabled", Type: TypeBool, Default: true},
+		Definition{Key: "notifications.email_enabled", Type: TypeBool, Default: false},
+		Definition{Key: "notifications.marketing_enabled", Type: TypeBool, Default: false},
+		Definition{Key: "content.filter_level", Type: TypeEnum, Default: "moderate", Values: []string{"off", "moderate", "strict"}},
+	)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Keys 返回所有登记的 key，按字典序排列。
+func (r *Registry) Keys() []string {
+	return slices.Clone(r.keys)
+}
+
+// Lookup 按 key 查找定义。
+func (r *Registry) Lookup(key string) (Definition, bool) {
+	d, ok := r.defs[key]
+	return d, ok
+}
+
+// Decode 把客户端提交的原始 JSON 解析为 key 对应类型的值；key 未登记返回 ErrUnknownKey，
+// 类型或取值不合法返回 ErrInvalidValue。
+func (r *Registry) Decode(key string, raw json.RawMessage) (any, error) {
+	d, ok := r.defs[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	}
+	v, err := d.decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidValue, key, err)
+	}
+	return v, nil
+}
+
+func (d Definition) decode(raw json.RawMessage) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("malformed json")
+	}
+
+	switch d.Type {
+	case TypeBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected boolean")
+		}
+		return b, nil
+	case TypeInt:
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("expected integer")
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("expected integer")
+		}
+		if i < d.Min || i > d.Max {
+			return nil, fmt.Errorf("must be between %d and %d", d.Min, d.Max)
+		}
+		return i, nil
+	case TypeString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string")
+		}
+		if d.MaxLength > 0 && len([]rune(s)) > d.MaxLength {
+			return nil, fmt.Errorf("must be at most %d characters", d.MaxLength)
+		}
+		return s, nil
+	case TypeEnum:
+		s, ok := v.(string)
+		if !ok || !slices.Contains(d.Values, s) {
+			return nil, fmt.Errorf("must be one of %v", d.Values)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %q", d.Type)
+	}
+}
@@ -0,0 +1,170 @@
+package settings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dundunHa/go-serverhttp-template/internal/dao"
+	logpkg "github.com/dundunHa/go-serverhttp-template/pkg/log"
+)
+
+var (
+	ErrNotConfigured = errors.New("settings: not configured")
+	ErrUnknownKey    = errors.New("settings: unknown key")
+	ErrInvalidValue  = errors.New("settings: invalid value")
+	ErrEmptyPatch    = errors.New("settings: empty patch")
+)
+
+const defaultCacheTTL = 10 * time.Minute
+
+// Service 读写用户偏好设置。
+//
+// 存储层只保存与默认值不同的覆盖项；读取时用 registry 的默认值补全，所以新增配置项
+// 或调整默认值对所有未覆盖的用户立即生效。缓存中存的也是覆盖项而非合并结果，原因相同。
+type Service struct {
+	dao      dao.SettingsDAO
+	registry *Registry
+	cache    Cache
+	cacheTTL time.Duration
+}
+
+// NewService 构造 settings service。registry 为 nil 时使用 DefaultRegistry；cache 为 nil 时不缓存。
+func NewService(d dao.SettingsDAO, registry *Registry, cache Cache, cacheTTL time.Duration) *Service {
+	if registry == nil {
+		registry = DefaultRegistry()
+	}
+	if cacheTTL <= 0 {
+		cacheTTL = defaultCacheTTL
+	}
+	return &Service{
+		dao:      d,
+		registry: registry,
+		cache:    cache,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Get 返回 user 全部配置项的当前值（覆盖值优先，否则为默认值）。
+func (s *Service) Get(ctx context.Context, userID int64) (map[string]any, error) {
+	if s == nil || s.dao == nil {
+		return nil, ErrNotConfigured
+	}
+	overrides, err := s.loadOverrides(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolve(ctx, overrides), nil
+}
+
+// Update 对 patch 中出现的 key 做部分更新，未出现的 key 保持不变；值为 JSON null 表示恢复默认值。
+//
+// 所有 key 先全部校验，任何一个不合法都不会写入（全有或全无）。成功后返回更新后的完整配置。
+func (s *Service) Update(ctx context.Context, userID int64, patch map[string]json.RawMessage) (map[string]any, error) {
+	if s == nil || s.dao == nil {
+		return nil, ErrNotConfigured
+	}
+	if len(patch) == 0 {
+		return nil, ErrEmptyPatch
+	}
+
+	keys := make([]string, 0, len(patch))
+	for k := range patch {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	set := make(map[string]json.RawMessage, len(patch))
+	var reset []string
+	for _, key := range keys {
+		raw := patch[key]
+		if isJSONNull(raw) {
+			if _, ok := s.registry.Lookup(key); !ok {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownKey, key)
+			}
+			reset = append(reset, key)
+			continue
+		}
+		v, err := s.registry.Decode(key, raw)
+		if err != nil {
+			return nil, err
+		}
+		normalized, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("settings: encode %s: %w", key, err)
+		}
+		set[key] = normalized
+	}
+
+	if err := s.dao.UpdateSettings(ctx, userID, set, reset); err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, userID)
+	return s.Get(ctx, userID)
+}
+
+// loadOverrides 优先读缓存；缓存未命中或出错时回源数据库并回填。缓存故障只记日志，不影响请求。
+func (s *Service) loadOverrides(ctx context.Context, userID int64) (map[string]json.RawMessage, error) {
+	key := cacheKey(userID)
+	if s.cache != nil {
+		cached, err := s.cache.Get(ctx, key)
+		if err == nil {
+			return cached, nil
+		}
+		if !errors.Is(err, ErrCacheMiss) {
+			logpkg.FromContext(ctx).Warn("settings cache read failed", "err", err, "user_id", userID)
+		}
+	}
+
+	overrides, err := s.dao.ListSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache != nil {
+		if err := s.cache.Set(ctx, key, overrides, s.cacheTTL); err != nil {
+			logpkg.FromContext(ctx).Warn("settings cache write failed", "err", err, "user_id", userID)
+		}
+	}
+	return overrides, nil
+}
+
+func (s *Service) invalidate(ctx context.Context, userID int64) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Del(ctx, cacheKey(userID)); err != nil {
+		logpkg.FromContext(ctx).Warn("settings cache invalidate failed", "err", err, "user_id", userID)
+	}
+}
+
+// resolve 用默认值补全覆盖项。已下线的 key 直接忽略；存量值不再满足当前 schema 时回落到默认值。
+func (s *Service) resolve(ctx context.Context, overrides map[string]json.RawMessage) map[string]any {
+	out := make(map[string]any, len(s.registry.keys))
+	for _, key := range s.registry.keys {
+		def := s.registry.defs[key]
+		out[key] = def.Default
+		raw, ok := overrides[key]
+		if !ok {
+			continue
+		}
+		v, err := s.registry.Decode(key, raw)
+		if err != nil {
+			logpkg.FromContext(ctx).Warn("stored setting no longer valid; using default", "key", key, "err", err)
+			continue
+		}
+		out[key] = v
+	}
+	return out
+}
+
+func cacheKey(userID int64) string {
+	return fmt.Sprintf("user_settings:%d", userID)
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
@@ -0,0 +1,227 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeSettingsDAO struct {
+	rows      map[int64]map[string]json.RawMessage
+	listCalls int
+}
+
+func newFakeSettingsDAO() *fakeSettingsDAO {
+	return &fakeSettingsDAO{rows: map[int64]map[string]json.RawMessage{}}
+}
+
+func (d *fakeSettingsDAO) ListSettings(_ context.Context, userID int64) (map[string]json.RawMessage, error) {
+	d.listCalls++
+	out := map[string]json.RawMessage{}
+	for k, v := range d.rows[userID] {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (d *fakeSettingsDAO) UpdateSettings(_ context.Context, userID int64, set map[string]json.RawMessage, reset []string) error {
+	if d.rows[userID] == nil {
+		d.rows[userID] = map[string]json.RawMessage{}
+	}
+	for k, v := range set {
+		d.rows[userID][k] = v
+	}
+	for _, k := range reset {
+		delete(d.rows[userID], k)
+	}
+	return nil
+}
+
+type memoryCache struct {
+	items map[string]map[string]json.RawMessage
+	err   error
+}
+
+func (c *memoryCache) Get(_ context.Context, key string) (map[string]json.RawMessage, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	v, ok := c.items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return v, nil
+}
+
+func (c *memoryCache) Set(_ context.Context, key string, val map[string]json.RawMessage, _ time.Duration) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.items[key] = val
+	return nil
+}
+
+func (c *memoryCache) Del(_ context.Context, key string) error {
+	delete(c.items, key)
+	return nil
+}
+
+func TestService_GetReturnsDefaults(t *testing.T) {
+	s := NewService(newFakeSettingsDAO(), nil, nil, 0)
+	got, err := s.Get(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got["notifications.push_enabled"] != true || got["content.filter_level"] != "moderate" {
+		t.Fatalf("unexpected defaults: %+v", got)
+	}
+	if len(got) != len(DefaultRegistry().Keys()) {
+		t.Fatalf("got %d keys, want every registered key", len(got))
+	}
+}
+
+func TestService_UpdatePartialAndReset(t *testing.T) {
+	d := newFakeSettingsDAO()
+	s := NewService(d, nil, nil, 0)
+	ctx := context.Background()
+
+	got, err := s.Update(ctx, 1, map[string]json.RawMessage{
+		"notifications.push_enabled": json.RawMessage(`false`),
+		"content.filter_level":       json.RawMessage(`"strict"`),
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got["notifications.push_enabled"] != false || got["content.filter_level"] != "strict" {
+		t.Fatalf("unexpected settings after update: %+v", got)
+	}
+	if got["notifications.email_enabled"] != false {
+		t.Fatalf("untouched key changed: %+v", got)
+	}
+
+	got, err = s.Update(ctx, 1, map[string]json.RawMessage{
+		"content.filter_level": json.RawMessage(`null`),
+	})
+	if err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if got["content.filter_level"] != "moderate" || got["notifications.push_enabled"] != false {
+		t.Fatalf("unexpected settings after reset: %+v", got)
+	}
+	if _, ok := d.rows[1]["content.filter_level"]; ok {
+		t.Fatalf("reset key should be deleted from storage")
+	}
+}
+
+func TestService_UpdateRejectsUnknownOrInvalidWithoutWriting(t *testing.T) {
+	d := newFakeSettingsDAO()
+	s := NewService(d, nil, nil, 0)
+	ctx := context.Background()
+
+	cases := []struct {
+		name  string
+		patch map[string]json.RawMessage
+		want  error
+	}{
+		{"unknown key", map[string]json.RawMessage{"notifications.push_enabled": json.RawMessage(`false`), "theme": json.RawMessage(`"dark"`)}, ErrUnknownKey},
+		{"unknown key reset", map[string]json.RawMessage{"theme": json.RawMessage(`null`)}, ErrUnknownKey},
+		{"wrong type", map[string]json.RawMessage{"notifications.push_enabled": json.RawMessage(`"yes"`)}, ErrInvalidValue},
+		{"bad enum", map[string]json.RawMessage{"content.filter_level": json.RawMessage(`"extreme"`)}, ErrInvalidValue},
+		{"empty", map[string]json.RawMessage{}, ErrEmptyPatch},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := s.Update(ctx, 1, tc.patch); !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+			if len(d.rows[1]) != 0 {
+				t.Fatalf("rejected patch must not write: %+v", d.rows[1])
+			}
+		})
+	}
+}
+
+func TestService_ReadsAreCachedAndInvalidatedOnUpdate(t *testing.T) {
+	d := newFakeSettingsDAO()
+	c := &memoryCache{items: map[string]map[string]json.RawMessage{}}
+	s := NewService(d, nil, c, time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.Get(ctx, 1); err != nil {
+			t.Fatalf("get: %v", err)
+		}
+	}
+	if d.listCalls != 1 {
+		t.Fatalf("list calls = %d, want 1 (cached)", d.listCalls)
+	}
+
+	if _, err := s.Update(ctx, 1, map[string]json.RawMessage{"notifications.email_enabled": json.RawMessage(`true`)}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, err := s.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got["notifications.email_enabled"] != true {
+		t.Fatalf("stale read after update: %+v", got)
+	}
+}
+
+func TestService_CacheFailureFallsBackToDAO(t *testing.T) {
+	d := newFakeSettingsDAO()
+	d.rows[1] = map[string]json.RawMessage{"notifications.push_enabled": json.RawMessage(`false`)}
+	s := NewService(d, nil, &memoryCache{err: errors.New("redis down")}, time.Minute)
+
+	got, err := s.Get(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got["notifications.push_enabled"] != false {
+		t.Fatalf("unexpected settings: %+v", got)
+	}
+}
+
+func TestService_InvalidStoredValueFallsBackToDefault(t *testing.T) {
+	d := newFakeSettingsDAO()
+	d.rows[1] = map[string]json.RawMessage{
+		"content.filter_level": json.RawMessage(`"retired-level"`),
+		"retired.key":          json.RawMessage(`true`),
+	}
+	s := NewService(d, nil, nil, 0)
+
+	got, err := s.Get(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got["content.filter_level"] != "moderate" {
+		t.Fatalf("invalid stored value should fall back to default: %+v", got)
+	}
+	if _, ok := got["retired.key"]; ok {
+		t.Fatalf("unregistered key leaked: %+v", got)
+	}
+}
+
+func TestNewRegistryRejectsInvalidDefinitions(t *testing.T) {
+	if _, err := NewRegistry(
+		Definition{Key: "a", Type: TypeBool, Default: true},
+		Definition{Key: "a", Type: TypeBool, Default: false},
+	); err == nil {
+		t.Fatalf("expected duplicate key error")
+	}
+	if _, err := NewRegistry(Definition{Key: "n", Type: TypeInt, Default: 5, Min: 0, Max: 3}); err == nil {
+		t.Fatalf("expected out-of-range default error")
+	}
+	r, err := NewRegistry(Definition{Key: "n", Type: TypeInt, Default: int64(1), Min: 0, Max: 3})
+	if err != nil {
+		t.Fatalf("new registry: %v", err)
+	}
+	if _, err := r.Decode("n", json.RawMessage(`1.5`)); !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("fractional int err = %v", err)
+	}
+	if v, err := r.Decode("n", json.RawMessage(`2`)); err != nil || v != int64(2) {
+		t.Fatalf("decode = %v, %v", v, err)
+	}
+}
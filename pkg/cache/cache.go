@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/go-redis/redis"
@@ -17,6 +18,14 @@ type Cache struct {
 // Default 全局默认实例，Init 后可直接使用下面的函数
 var Default *Cache
 
+// ErrNotInitialized 在 Init 之前调用包级函数时返回，避免对 nil Default 解引用。
+var ErrNotInitialized = errors.New("cache: not initialized")
+
+// IsMiss 判断 Get 返回的错误是否表示 key 不存在
+func IsMiss(err error) bool {
+	return errors.Is(err, redis.Nil)
+}
+
 // Init 用配置初始化 Default
 func Init(cfg Config) {
 	uopt := &redis.UniversalOptions{
@@ -34,6 +43,9 @@ func Init(cfg Config) {
 
 // Set 将任意对象 JSON 编码后存入
 func Set(ctx context.Context, key string, val interface{}, ttl time.Duration) error {
+	if Default == nil {
+		return ErrNotInitialized
+	}
 	buf, err := json.Marshal(val)
 	if err != nil {
 		return err
@@ -44,6 +56,9 @@ func Set(ctx context.Context, key string, val interface{}, ttl time.Duration) er
 // Get[T] 从缓存取出并 JSON 解码到 T
 func Get[T any](ctx context.Context, key string) (T, error) {
 	var zero T
+	if Default == nil {
+		return zero, ErrNotInitialized
+	}
 	buf, err := Default.client.Get(key).Bytes()
 	if err != nil {
 		return zero, err
@@ -56,5 +71,8 @@ func Get[T any](ctx context.Context, key string) (T, error) {
 
 // Del 删除 key
 func Del(ctx context.Context, key string) error {
+	if Default == nil {
+		return ErrNotInitialized
+	}
 	return Default.client.Del(key).Err()
 }
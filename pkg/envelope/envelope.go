@@ -0,0 +1,202 @@
+// Package envelope 提供基于 AES-256-GCM 的信封加密：每条数据使用随机 data key 加密，
+// data key 再由 Keyring 中的 master key 包裹。轮换 master key 只需重新包裹 data key（Rewrap），
+// 不需要重新加密数据本身。
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const keySize = 32
+
+var (
+	ErrInvalidKey = errors.New("envelope: invalid key")
+	ErrUnknownKey = errors.New("envelope: unknown key id")
+	ErrDecrypt    = errors.New("envelope: decrypt failed")
+)
+
+// Sealed 是一条加密结果。WrappedKey 为 master key 包裹后的 data key（nonce || ciphertext）。
+type Sealed struct {
+	KeyID      string
+	WrappedKey []byte
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// Keyring 持有一组 master key：Active 用于新数据加密，其余 key 只用于解密与 Rewrap。
+type Keyring struct {
+	keys   map[string]cipher.AEAD
+	active string
+}
+
+// ParseKeyring 解析 "<key id>:<base64 key>" 的逗号分隔列表，key 必须是 32 字节。
+// activeID 为空且只有一把 key 时使用该 key。
+func ParseKeyring(raw, activeID string) (*Keyring, error) {
+	keys := map[string][]byte{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(part, ":")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("%w: entry must be <key id>:<base64 key>", ErrInvalidKey)
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("%w: duplicate key id %q", ErrInvalidKey, id)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q is not base64", ErrInvalidKey, id)
+		}
+		keys[id] = key
+	}
+	if activeID == "" && len(keys) == 1 {
+		for id := range keys {
+			activeID = id
+		}
+	}
+	return NewKeyring(keys, activeID)
+}
+
+// NewKeyring 用 key id -> 32 字节 key 构造 Keyring；activeID 必须在 keys 中。
+func NewKeyring(keys map[string][]byte, activeID string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no keys", ErrInvalidKey)
+	}
+	k := &Keyring{keys: make(map[string]cipher.AEAD, len(keys)), active: activeID}
+	for id, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("%w: key %q must be %d bytes", ErrInvalidKey, id, keySize)
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+	}
+	if _, ok := k.keys[activeID]; !ok {
+		return nil, fmt.Errorf("%w: active key %q not in keyring", ErrInvalidKey, activeID)
+	}
+	return k, nil
+}
+
+// ActiveKeyID 返回新数据使用的 master key id。
+func (k *Keyring) ActiveKeyID() string { return k.active }
+
+// Seal 用随机 data key 加密 plaintext，并用 active master key 包裹 data key。aad 参与认证但不加密，
+// 解密时必须提供相同的 aad。
+func (k *Keyring) Seal(plaintext, aad []byte) (Sealed, error) {
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return Sealed{}, fmt.Errorf("envelope: generate data key: %w", err)
+	}
+	data, err := newGCM(dek)
+	if err != nil {
+		return Sealed{}, err
+	}
+	nonce, err := randomNonce(data)
+	if err != nil {
+		return Sealed{}, err
+	}
+	wrapped, err := k.wrap(k.active, dek)
+	if err != nil {
+		return Sealed{}, err
+	}
+	return Sealed{
+		KeyID:      k.active,
+		WrappedKey: wrapped,
+		Nonce:      nonce,
+		Ciphertext: data.Seal(nil, nonce, plaintext, aad),
+	}, nil
+}
+
+// Open 解包 data key 并解密；master key 不在 keyring 中时返回 ErrUnknownKey，认证失败返回 ErrDecrypt。
+func (k *Keyring) Open(s Sealed, aad []byte) ([]byte, error) {
+	dek, err := k.unwrap(s.KeyID, s.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Nonce) != data.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := data.Open(nil, s.Nonce, s.Ciphertext, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// Rewrap 用 active master key 重新包裹 data key，Nonce / Ciphertext 不变。已使用 active key 时原样返回。
+func (k *Keyring) Rewrap(s Sealed) (Sealed, error) {
+	if s.KeyID == k.active {
+		return s, nil
+	}
+	dek, err := k.unwrap(s.KeyID, s.WrappedKey)
+	if err != nil {
+		return Sealed{}, err
+	}
+	wrapped, err := k.wrap(k.active, dek)
+	if err != nil {
+		return Sealed{}, err
+	}
+	s.KeyID = k.active
+	s.WrappedKey = wrapped
+	return s, nil
+}
+
+// master key 包裹 data key 时以 key id 作为 aad，防止把包裹结果挪到另一把 key 名下。
+func (k *Keyring) wrap(keyID string, dek []byte) ([]byte, error) {
+	master, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	nonce, err := randomNonce(master)
+	if err != nil {
+		return nil, err
+	}
+	return master.Seal(nonce, nonce, dek, []byte(keyID)), nil
+}
+
+func (k *Keyring) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	master, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	n := master.NonceSize()
+	if len(wrapped) < n {
+		return nil, ErrDecrypt
+	}
+	dek, err := master.Open(nil, wrapped[:n], wrapped[n:], []byte(keyID))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return dek, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func randomNonce(aead cipher.AEAD) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("envelope: generate nonce: %w", err)
+	}
+	return nonce, nil
+}
@@ -0,0 +1,96 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, keySize))
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	k, err := ParseKeyring("k1:"+testKey(1), "")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	s, err := k.Seal([]byte(`{"hello":"world"}`), []byte("ot-1"))
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if s.KeyID != "k1" || bytes.Contains(s.Ciphertext, []byte("hello")) {
+		t.Fatalf("unexpected sealed value: %+v", s)
+	}
+	got, err := k.Open(s, []byte("ot-1"))
+	if err != nil || string(got) != `{"hello":"world"}` {
+		t.Fatalf("open = %q, %v", got, err)
+	}
+	if _, err := k.Open(s, []byte("ot-2")); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("open with wrong aad err = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestRewrapAfterRotation(t *testing.T) {
+	old, err := ParseKeyring("k1:"+testKey(1), "k1")
+	if err != nil {
+		t.Fatalf("parse old: %v", err)
+	}
+	s, err := old.Seal([]byte("payload"), nil)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+
+	rotated, err := ParseKeyring("k1:"+testKey(1)+",k2:"+testKey(2), "k2")
+	if err != nil {
+		t.Fatalf("parse rotated: %v", err)
+	}
+	if got, err := rotated.Open(s, nil); err != nil || string(got) != "payload" {
+		t.Fatalf("old key must still decrypt: %q, %v", got, err)
+	}
+	re, err := rotated.Rewrap(s)
+	if err != nil {
+		t.Fatalf("rewrap: %v", err)
+	}
+	if re.KeyID != "k2" || !bytes.Equal(re.Ciphertext, s.Ciphertext) {
+		t.Fatalf("rewrap must only change the wrapped key: %+v", re)
+	}
+
+	retired, err := ParseKeyring("k2:"+testKey(2), "")
+	if err != nil {
+		t.Fatalf("parse retired: %v", err)
+	}
+	if got, err := retired.Open(re, nil); err != nil || string(got) != "payload" {
+		t.Fatalf("rewrapped value after retiring k1: %q, %v", got, err)
+	}
+	if _, err := retired.Open(s, nil); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("open with retired key err = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestParseKeyringErrors(t *testing.T) {
+	cases := map[string]struct {
+		raw, active string
+	}{
+		"empty":          {"", ""},
+		"missing id":     {":" + testKey(1), ""},
+		"not base64":     {"k1:???", ""},
+		"short key":      {"k1:" + base64.StdEncoding.EncodeToString([]byte("short")), ""},
+		"duplicate":      {"k1:" + testKey(1) + ",k1:" + testKey(2), "k1"},
+		"ambiguous":      {"k1:" + testKey(1) + ",k2:" + testKey(2), ""},
+		"unknown active": {"k1:" + testKey(1), "k9"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseKeyring(tc.raw, tc.active)
+			if !errors.Is(err, ErrInvalidKey) {
+				t.Fatalf("err = %v, want ErrInvalidKey", err)
+			}
+			if err != nil && strings.Contains(err.Error(), testKey(1)) {
+				t.Fatalf("error must not leak key material: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+// Package job 提供后台批处理任务共用的小工具。
+package job
+
+import "time"
+
+// leaseSlack 是批量租约在逐条处理耗时之外预留的余量，覆盖领取与写回结果的数据库往返。
+const leaseSlack = time.Minute
+
+// BatchLease 返回领取一批任务时加的租约时长：batchSize 条逐条处理、每条最长 timeout 的总耗时再加余量。
+// 进程在写回结果前退出时，租约到期后任务会被重新领取。
+func BatchLease(timeout time.Duration, batchSize int) time.Duration {
+	return timeout*time.Duration(max(batchSize, 1)) + leaseSlack
+}
+
+// Backoff 返回第 attempt 次失败后的重试间隔：base * 2^(attempt-1)，不超过 maxDelay。
+// 逐次翻倍并在达到上限后停止，attempt 很大时也不会溢出成负数或零。
+func Backoff(base time.Duration, attempt int, maxDelay time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxDelay)
+}
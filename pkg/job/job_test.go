@@ -0,0 +1,33 @@
+package job
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBatchLease(t *testing.T) {
+	if got := BatchLease(10*time.Second, 50); got != 500*time.Second+time.Minute {
+		t.Fatalf("BatchLease = %s", got)
+	}
+	if got := BatchLease(10*time.Second, 0); got != 10*time.Second+time.Minute {
+		t.Fatalf("BatchLease with empty batch = %s", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{3, 4 * time.Minute},
+		{7, time.Hour},
+		{100, time.Hour},
+	}
+	for _, c := range cases {
+		if got := Backoff(time.Minute, c.attempt, time.Hour); got != c.want {
+			t.Fatalf("Backoff(attempt=%d) = %s, want %s", c.attempt, got, c.want)
+		}
+	}
+}